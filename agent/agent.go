@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
@@ -43,6 +44,7 @@ type NewAgentConfig struct {
 	Tools        *ToolRegistry
 	Context      *ContextBuilder
 	Model        string
+	FallbackModel string // 主模型不可用时的备用模型
 	Workspace    string
 	MaxIteration int
 	Temperature  float64 // 0 表示使用 provider 默认
@@ -53,9 +55,22 @@ type NewAgentConfig struct {
 	ContextWindowTokens int // 来自配置 agents.defaults.context_tokens 或 profile
 	ReserveTokens       int // 保留 token 数，默认 4096
 	MaxHistoryTurns     int // 最多保留的 user 轮次，0 表示不限制
+	SummarizerContextTokens int // 摘要模型上下文窗口，0 表示与 ContextWindowTokens 相同
+	ToolResultTruncation ToolResultTruncation // 超长 tool 结果截断方式
 
 	// 同一会话内两次调用模型的最小间隔（秒），0 表示不限制；用于缓解 406/限流
 	ModelRequestIntervalSeconds int
+
+	// 工具报错策略（agents.defaults.tool_error_policy / tool_error_max_consecutive）
+	ToolErrorPolicy         string
+	ToolErrorMaxConsecutive int
+
+	// 单次工具调用的执行超时（秒，tools.timeout_seconds），0 表示默认
+	ToolTimeoutSeconds int
+
+	// system_prompt 模板变量：Agent 显示名称与用户时区（agents.defaults.user_timezone）
+	AgentName    string
+	UserTimezone string
 }
 
 // NewAgent creates a new agent
@@ -90,6 +105,7 @@ func NewAgent(cfg *NewAgentConfig) (*Agent, error) {
 
 	loopConfig := &LoopConfig{
 		Model:                   state.Model,
+		FallbackModel:           strings.TrimSpace(cfg.FallbackModel),
 		Provider:                cfg.Provider,
 		SessionMgr:              cfg.SessionMgr,
 		MaxIterations:           cfg.MaxIteration,
@@ -98,7 +114,17 @@ func NewAgent(cfg *NewAgentConfig) (*Agent, error) {
 		ContextWindowTokens:     cfg.ContextWindowTokens,
 		ReserveTokens:            cfg.ReserveTokens,
 		MaxHistoryTurns:         cfg.MaxHistoryTurns,
+		SummarizerContextTokens: cfg.SummarizerContextTokens,
+		ToolResultTruncation:    cfg.ToolResultTruncation,
 		ModelRequestInterval:     time.Duration(cfg.ModelRequestIntervalSeconds) * time.Second,
+		ToolErrorPolicy:         cfg.ToolErrorPolicy,
+		ToolErrorMaxConsecutive: cfg.ToolErrorMaxConsecutive,
+		ToolTimeout:             time.Duration(cfg.ToolTimeoutSeconds) * time.Second,
+		SystemPromptVars: config.SystemPromptVars{
+			AgentName:    cfg.AgentName,
+			Workspace:    cfg.Workspace,
+			UserTimezone: cfg.UserTimezone,
+		},
 		ConvertToLLM:            defaultConvertToLLM,
 		TransformContext:        nil,
 		Skills:                  skills,
@@ -344,7 +370,7 @@ func (a *Agent) updateSession(sess *session.Session, messages []AgentMessage) {
 					})
 				}
 			}
-			if reasoning, ok := msg.Metadata["reasoning_content"].(string); ok && strings.TrimSpace(reasoning) != "" {
+			if reasoning := messageReasoning(msg); reasoning != "" {
 				if sessMsg.Metadata == nil {
 					sessMsg.Metadata = make(map[string]interface{})
 				}
@@ -491,11 +517,13 @@ func (a *Agent) GetState() *AgentState {
 }
 
 // SetSystemPrompt updates the system prompt
+// prompt 可包含 {{.Date}} 等模板变量，每次运行时展开
 func (a *Agent) SetSystemPrompt(prompt string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	a.state.SystemPrompt = prompt
+	a.loopConfig.SystemPrompt = prompt
 }
 
 // SetTools updates the available tools
@@ -521,6 +549,14 @@ func (a *Agent) GetOrchestrator() *Orchestrator {
 	return a.orchestrator
 }
 
+// SetSkills 更新 agent 的可用技能列表，下一次 Run 起生效；已加载但不再可用的技能被移除
+func (a *Agent) SetSkills(skills []*Skill) {
+	a.loopConfig.SetSkills(skills)
+	a.mu.Lock()
+	a.state.LoadedSkills = filterLoadedSkills(a.state.LoadedSkills, skills)
+	a.mu.Unlock()
+}
+
 // CreateOrchestratorForRun 为本次 Run 创建独立的 Orchestrator，避免多 agent/多会话共用一个 eventChan 导致流式事件串台。
 // 调用方负责在 Run 结束后不再使用返回的 orchestrator（无需 Close，由 GC 回收）。
 func (a *Agent) CreateOrchestratorForRun(sessionKey string) *Orchestrator {
@@ -531,6 +567,22 @@ func (a *Agent) CreateOrchestratorForRun(sessionKey string) *Orchestrator {
 	return NewOrchestrator(a.loopConfig, runState)
 }
 
+// FitHistoryToContext 按本 Agent 的上下文窗口（扣除保留 token，tool 结果按发送时的规则截断后估算）预先裁掉最早的轮次，
+// 返回裁剪后的消息与丢弃的轮次数
+func (a *Agent) FitHistoryToContext(messages []AgentMessage) ([]AgentMessage, int) {
+	window := a.loopConfig.ContextWindowTokens
+	if window <= 0 {
+		window = DefaultContextWindowTokens
+	}
+	budget := window - EffectiveReserveTokens(a.loopConfig.ReserveTokens)
+	estimated := CopyMessagesWithTruncatedToolResults(messages, window, a.loopConfig.ToolResultTruncation)
+	fitted, dropped := FitHistoryToBudget(estimated, budget)
+	if dropped == 0 {
+		return messages, 0
+	}
+	return messages[len(messages)-len(fitted):], dropped
+}
+
 // Helper functions
 
 // defaultConvertToLLM converts agent messages to provider messages
@@ -546,9 +598,7 @@ func defaultConvertToLLM(messages []AgentMessage) ([]providers.Message, error) {
 		providerMsg := providers.Message{
 			Role: string(msg.Role),
 		}
-		if reasoning, ok := msg.Metadata["reasoning_content"].(string); ok {
-			providerMsg.ReasoningContent = reasoning
-		}
+		providerMsg.ReasoningContent = messageReasoning(msg)
 
 		// Extract content
 		for _, block := range msg.Content {
@@ -565,10 +615,6 @@ func defaultConvertToLLM(messages []AgentMessage) ([]providers.Message, error) {
 				} else if b.URL != "" {
 					providerMsg.Images = append(providerMsg.Images, b.URL)
 				}
-			case ThinkingContent:
-				if strings.TrimSpace(providerMsg.ReasoningContent) == "" {
-					providerMsg.ReasoningContent = b.Thinking
-				}
 			}
 		}
 
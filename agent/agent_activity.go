@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+// isSupervisorAgent agents.list 中 supervisor 为 true 的 agent 可查询其他 agent 的活动
+func (m *AgentManager) isSupervisorAgent(agentID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cfg == nil {
+		return false
+	}
+	for _, a := range m.cfg.Agents.List {
+		if a.ID == agentID {
+			return a.Supervisor
+		}
+	}
+	return false
+}
+
+// summarizeAgentActivity 实现 agent_activity 工具：汇总 agent 的会话与分身运行；非主管 agent 只能查询自身
+func (m *AgentManager) summarizeAgentActivity(ctx context.Context, requesterSessionKey, agentID string, limit int) (map[string]interface{}, error) {
+	requesterID, _, _ := session.ParseAgentSessionKey(requesterSessionKey)
+	if requesterID != agentID && !m.isSupervisorAgent(requesterID) {
+		return nil, fmt.Errorf("agent %q is not allowed to query other agents (set supervisor: true in agents.list)", requesterID)
+	}
+	if _, ok := m.GetAgent(agentID); !ok {
+		return nil, fmt.Errorf("agent not found: %s", agentID)
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+
+	// 会话：与 sessions.list 相同的数据来源，按 agent:<id>: 前缀过滤
+	keys, err := m.sessionMgr.List()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	prefix := "agent:" + agentID + ":"
+	type sessionRow struct {
+		key       string
+		messages  int
+		updatedAt time.Time
+		lastTask  string
+	}
+	rows := make([]sessionRow, 0)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		sess, err := m.sessionMgr.Lookup(key)
+		if err != nil {
+			continue
+		}
+		history := sess.GetHistory(0)
+		row := sessionRow{key: key, messages: len(history), updatedAt: sess.UpdatedAt}
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Role == "user" {
+				row.lastTask = truncateString(history[i].Content, 120)
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].updatedAt.After(rows[j].updatedAt) })
+
+	var lastActivity time.Time
+	if len(rows) > 0 {
+		lastActivity = rows[0].updatedAt
+	}
+	recentSessions := make([]map[string]interface{}, 0, limit)
+	for i, row := range rows {
+		if i >= limit {
+			break
+		}
+		recentSessions = append(recentSessions, map[string]interface{}{
+			"key":          row.key,
+			"messageCount": row.messages,
+			"updatedAt":    row.updatedAt.UnixMilli(),
+			"lastTask":     row.lastTask,
+		})
+	}
+
+	// 分身运行：该 agent 发起或执行的运行
+	runCounts := map[string]int{}
+	recentRuns := make([]map[string]interface{}, 0, limit)
+	if m.subagentRegistry != nil {
+		runs := make([]*SubagentRunRecord, 0)
+		for _, r := range m.subagentRegistry.ListRuns() {
+			if strings.HasPrefix(r.RequesterSessionKey, prefix) || strings.HasPrefix(r.ChildSessionKey, prefix) {
+				runs = append(runs, r)
+			}
+		}
+		sort.Slice(runs, func(i, j int) bool { return runs[i].CreatedAt > runs[j].CreatedAt })
+		for i, r := range runs {
+			status := subagentRunStatus(r)
+			runCounts[status]++
+			if i < limit {
+				recentRuns = append(recentRuns, map[string]interface{}{
+					"runId":     r.RunID,
+					"task":      truncateString(r.Task, 120),
+					"label":     r.Label,
+					"status":    status,
+					"createdAt": r.CreatedAt,
+				})
+			}
+		}
+	}
+
+	summary := map[string]interface{}{
+		"agentId":        agentID,
+		"sessionCount":   len(rows),
+		"recentSessions": recentSessions,
+		"runCounts":      runCounts,
+		"recentRuns":     recentRuns,
+	}
+	if !lastActivity.IsZero() {
+		summary["lastActivity"] = lastActivity.UnixMilli()
+	}
+	return summary, nil
+}
+
+// subagentRunStatus 运行状态：pending / running，结束后为 outcome.status
+func subagentRunStatus(r *SubagentRunRecord) string {
+	if r.EndedAt == nil {
+		if r.StartedAt == nil {
+			return "pending"
+		}
+		return "running"
+	}
+	if r.Outcome != nil && r.Outcome.Status != "" {
+		return r.Outcome.Status
+	}
+	return "unknown"
+}
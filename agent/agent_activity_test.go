@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestAgentActivityToolSummarizesKnownAgent(t *testing.T) {
+	sessionMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	for _, key := range []string{"agent:worker:main", "agent:worker:telegram:42"} {
+		sess, _ := sessionMgr.GetOrCreate(key)
+		sess.AddMessage(session.Message{Role: "user", Content: "translate report for " + key, Timestamp: time.Now()})
+		sess.AddMessage(session.Message{Role: "assistant", Content: "done", Timestamp: time.Now()})
+		if err := sessionMgr.Save(sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other, _ := sessionMgr.GetOrCreate("agent:boss:main")
+	_ = sessionMgr.Save(other)
+
+	registry := NewSubagentRegistry(t.TempDir())
+	_ = registry.RegisterRun(&SubagentRunParams{RunID: "run-1", ChildSessionKey: "agent:worker:subagent:abc", RequesterSessionKey: "agent:worker:main", Task: "crawl docs"})
+
+	m := &AgentManager{
+		agents:           map[string]*Agent{"worker": {id: "worker"}, "boss": {id: "boss"}, "intern": {id: "intern"}},
+		sessionMgr:       sessionMgr,
+		subagentRegistry: registry,
+		cfg: &config.Config{Agents: config.AgentsConfig{List: []config.AgentConfig{
+			{ID: "boss", Supervisor: true},
+			{ID: "worker"},
+			{ID: "intern"},
+		}}},
+	}
+	tool := tools.NewAgentActivityTool(m.summarizeAgentActivity)
+
+	ctx := context.WithValue(context.Background(), "session_key", "agent:boss:main")
+	out, err := tool.Execute(ctx, map[string]interface{}{"agentId": "worker", "limit": float64(10)})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var summary struct {
+		AgentID        string                   `json:"agentId"`
+		SessionCount   int                      `json:"sessionCount"`
+		LastActivity   int64                    `json:"lastActivity"`
+		RecentSessions []map[string]interface{} `json:"recentSessions"`
+		RunCounts      map[string]int           `json:"runCounts"`
+		RecentRuns     []map[string]interface{} `json:"recentRuns"`
+	}
+	if err := json.Unmarshal([]byte(out), &summary); err != nil {
+		t.Fatalf("unmarshal summary: %v (%s)", err, out)
+	}
+	if summary.AgentID != "worker" || summary.SessionCount != 2 || summary.LastActivity == 0 {
+		t.Errorf("unexpected summary header: %s", out)
+	}
+	if len(summary.RecentSessions) != 2 || !strings.HasPrefix(summary.RecentSessions[0]["lastTask"].(string), "translate report") {
+		t.Errorf("unexpected recent sessions: %v", summary.RecentSessions)
+	}
+	if summary.RunCounts["running"] != 1 || len(summary.RecentRuns) != 1 || summary.RecentRuns[0]["task"] != "crawl docs" {
+		t.Errorf("unexpected runs: %v %v", summary.RunCounts, summary.RecentRuns)
+	}
+
+	// 非主管 agent 不能查询其他 agent
+	internCtx := context.WithValue(context.Background(), "session_key", "agent:intern:main")
+	if _, err := tool.Execute(internCtx, map[string]interface{}{"agentId": "worker"}); err == nil {
+		t.Error("non-supervisor agent should not query other agents")
+	}
+}
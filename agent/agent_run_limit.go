@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// agentRunSlots 单个 Agent 的 Run 并发计数与等待队列
+type agentRunSlots struct {
+	active  int
+	waiters []chan struct{} // 按到达顺序排队；close 表示已分配到名额
+}
+
+// agentRunLimiter 按 Agent 限制同时执行的 Run 数（agents.list[].max_concurrent_runs），避免单个繁忙 Agent 占满执行资源；零值可用
+type agentRunLimiter struct {
+	mu    sync.Mutex
+	slots map[string]*agentRunSlots
+}
+
+// AgentRunStats 单个 Agent 的 Run 并发情况（status 中展示）
+type AgentRunStats struct {
+	AgentID string `json:"agentId"`
+	Active  int    `json:"active"`
+	Queued  int    `json:"queued"`
+	Limit   int    `json:"limit"` // 0 表示不限制
+}
+
+func (l *agentRunLimiter) get(agentID string) *agentRunSlots {
+	if l.slots == nil {
+		l.slots = make(map[string]*agentRunSlots)
+	}
+	s := l.slots[agentID]
+	if s == nil {
+		s = &agentRunSlots{}
+		l.slots[agentID] = s
+	}
+	return s
+}
+
+// acquire 占用一个名额；已达上限时排队，直到有 Run 结束或 ctx 取消。limit <= 0 表示不限制
+func (l *agentRunLimiter) acquire(ctx context.Context, agentID string, limit int) error {
+	l.mu.Lock()
+	s := l.get(agentID)
+	if limit <= 0 || (s.active < limit && len(s.waiters) == 0) {
+		s.active++
+		l.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	s.waiters = append(s.waiters, ch)
+	l.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		for i, w := range s.waiters {
+			if w == ch {
+				s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+				l.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		l.mu.Unlock()
+		// 取消与分配同时发生：名额已转交给本次调用，归还后再返回
+		l.release(agentID, limit)
+		return ctx.Err()
+	}
+}
+
+// release 归还名额，并按当前上限依次唤醒排队的 Run
+func (l *agentRunLimiter) release(agentID string, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.get(agentID)
+	if s.active > 0 {
+		s.active--
+	}
+	for len(s.waiters) > 0 && (limit <= 0 || s.active < limit) {
+		s.active++
+		close(s.waiters[0])
+		s.waiters = s.waiters[1:]
+	}
+}
+
+// snapshot 返回各 Agent 的活跃与排队 Run 数（按 agentId 排序），limits 为当前配置的上限
+func (l *agentRunLimiter) snapshot(limits map[string]int) []AgentRunStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ids := make(map[string]bool, len(l.slots)+len(limits))
+	for id := range l.slots {
+		ids[id] = true
+	}
+	for id := range limits {
+		ids[id] = true
+	}
+	stats := make([]AgentRunStats, 0, len(ids))
+	for id := range ids {
+		st := AgentRunStats{AgentID: id, Limit: limits[id]}
+		if s := l.slots[id]; s != nil {
+			st.Active = s.active
+			st.Queued = len(s.waiters)
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].AgentID < stats[j].AgentID })
+	return stats
+}
+
+// agentRunLimit 读取 Agent 的 max_concurrent_runs（未配置或 <= 0 表示不限制）
+func (m *AgentManager) agentRunLimit(agentID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cfg == nil {
+		return 0
+	}
+	for _, a := range m.cfg.Agents.List {
+		if a.ID == agentID {
+			return a.MaxConcurrentRuns
+		}
+	}
+	return 0
+}
+
+// runWithAgentSlot 在 Agent 的并发名额内执行 fn；超出 max_concurrent_runs 时排队等待
+func (m *AgentManager) runWithAgentSlot(ctx context.Context, agentID string, fn func() (interface{}, error)) (interface{}, error) {
+	limit := m.agentRunLimit(agentID)
+	if err := m.runLimiter.acquire(ctx, agentID, limit); err != nil {
+		return nil, err
+	}
+	defer func() {
+		m.runLimiter.release(agentID, m.agentRunLimit(agentID))
+	}()
+	return fn()
+}
+
+// ActiveRunStats 返回各 Agent 当前的活跃 / 排队 Run 数及并发上限，供 status 展示
+func (m *AgentManager) ActiveRunStats() []AgentRunStats {
+	limits := make(map[string]int)
+	m.mu.RLock()
+	if m.cfg != nil {
+		for _, a := range m.cfg.Agents.List {
+			if a.MaxConcurrentRuns > 0 {
+				limits[a.ID] = a.MaxConcurrentRuns
+			}
+		}
+	}
+	m.mu.RUnlock()
+	return m.runLimiter.snapshot(limits)
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestAgentMaxConcurrentRunsSerializesCappedAgent(t *testing.T) {
+	m := &AgentManager{cfg: &config.Config{Agents: config.AgentsConfig{List: []config.AgentConfig{
+		{ID: "capped", MaxConcurrentRuns: 1},
+		{ID: "free"},
+	}}}}
+
+	const runs = 4
+	var active, peak [2]int32
+	run := func(idx int, agentID string) {
+		_, err := m.runWithAgentSlot(context.Background(), agentID, func() (interface{}, error) {
+			n := atomic.AddInt32(&active[idx], 1)
+			for {
+				p := atomic.LoadInt32(&peak[idx])
+				if n <= p || atomic.CompareAndSwapInt32(&peak[idx], p, n) {
+					break
+				}
+			}
+			time.Sleep(30 * time.Millisecond)
+			atomic.AddInt32(&active[idx], -1)
+			return nil, nil
+		})
+		if err != nil {
+			t.Errorf("run %s: %v", agentID, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); run(0, "capped") }()
+		go func() { defer wg.Done(); run(1, "free") }()
+	}
+
+	// 运行期间 status 能看到受限 Agent 的排队情况
+	var capped AgentRunStats
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		for _, st := range m.ActiveRunStats() {
+			if st.AgentID == "capped" {
+				capped = st
+			}
+		}
+		if capped.Queued == runs-1 {
+			break
+		}
+	}
+	if capped.Limit != 1 || capped.Active != 1 || capped.Queued != runs-1 {
+		t.Fatalf("capped stats = %+v, want limit 1, active 1, queued %d", capped, runs-1)
+	}
+
+	wg.Wait()
+	if peak[0] != 1 {
+		t.Fatalf("capped agent peak concurrency = %d, want 1", peak[0])
+	}
+	if peak[1] < 2 {
+		t.Fatalf("uncapped agent peak concurrency = %d, want runs in parallel", peak[1])
+	}
+}
+
+func TestAgentRunSlotReleasedWhenWaiterCancelled(t *testing.T) {
+	var l agentRunLimiter
+	if err := l.acquire(context.Background(), "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, "a", 1); err == nil {
+		t.Fatal("expected queued acquire to fail after ctx timeout")
+	}
+	l.release("a", 1)
+	if err := l.acquire(context.Background(), "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if st := l.snapshot(nil); len(st) != 1 || st[0].Active != 1 || st[0].Queued != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// announceQueue 按父会话串行投递分身宣告，避免多个分身同时完成时反复打断父会话的运行。
+// 每个父会话同一时刻只有一个投递协程；批量窗口内（以及上一次投递期间）到达的宣告合并为一条消息
+type announceQueue struct {
+	deliver AnnounceCallback
+	window  func() time.Duration
+
+	mu      sync.Mutex
+	pending map[string][]*pendingAnnounce // 父会话 -> 待投递宣告（按到达顺序）
+	running map[string]bool               // 父会话是否已有投递协程
+}
+
+// pendingAnnounce 一条待投递宣告；done 接收投递结果
+type pendingAnnounce struct {
+	message string
+	done    chan error
+}
+
+// newAnnounceQueue 创建宣告队列；window 为 nil 时从 agents.defaults.subagents.announce_batch_ms 读取
+func newAnnounceQueue(deliver AnnounceCallback, window func() time.Duration) *announceQueue {
+	if window == nil {
+		window = announceBatchWindowFromConfig
+	}
+	return &announceQueue{
+		deliver: deliver,
+		window:  window,
+		pending: make(map[string][]*pendingAnnounce),
+		running: make(map[string]bool),
+	}
+}
+
+// announceBatchWindowFromConfig 读取宣告批量窗口
+func announceBatchWindowFromConfig() time.Duration {
+	cfg := config.Get()
+	if cfg == nil || cfg.Agents.Defaults.Subagents == nil || cfg.Agents.Defaults.Subagents.AnnounceBatchMs <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.Agents.Defaults.Subagents.AnnounceBatchMs) * time.Millisecond
+}
+
+// Enqueue 排队一条宣告并阻塞到其所在批次投递完成，返回投递结果（调用方据此决定是否清理子会话）
+func (q *announceQueue) Enqueue(sessionKey, message string) error {
+	item := &pendingAnnounce{message: message, done: make(chan error, 1)}
+	q.mu.Lock()
+	q.pending[sessionKey] = append(q.pending[sessionKey], item)
+	if !q.running[sessionKey] {
+		q.running[sessionKey] = true
+		go q.run(sessionKey)
+	}
+	q.mu.Unlock()
+	return <-item.done
+}
+
+// run 父会话的投递协程：等待批量窗口后取出全部待投递宣告合并投递，直到队列为空
+func (q *announceQueue) run(sessionKey string) {
+	for {
+		if window := q.window(); window > 0 {
+			time.Sleep(window)
+		}
+
+		q.mu.Lock()
+		batch := q.pending[sessionKey]
+		delete(q.pending, sessionKey)
+		if len(batch) == 0 {
+			delete(q.running, sessionKey)
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+
+		messages := make([]string, len(batch))
+		for i, item := range batch {
+			messages[i] = item.message
+		}
+		err := q.deliver(sessionKey, joinAnnouncements(messages))
+		if len(batch) > 1 {
+			logger.Info("Subagent announcements delivered as one batch",
+				zap.String("session_key", sessionKey),
+				zap.Int("count", len(batch)),
+				zap.Error(err))
+		}
+		for _, item := range batch {
+			item.done <- err
+		}
+	}
+}
+
+// joinAnnouncements 合并多条宣告：单条原样返回，多条加上说明并以分隔线连接
+func joinAnnouncements(messages []string) string {
+	if len(messages) == 1 {
+		return messages[0]
+	}
+	header := fmt.Sprintf("%d background tasks finished. Cover them together in a single reply.", len(messages))
+	return header + "\n\n" + strings.Join(messages, "\n\n---\n\n")
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestConcurrentSubagentCompletionsSteerParentOnce(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{sessionMgr: sessMgr, subagentRegistry: NewSubagentRegistry(t.TempDir())}
+	m.announceQueue = newAnnounceQueue(m.sendToSession, func() time.Duration { return 100 * time.Millisecond })
+	m.subagentAnnouncer = NewSubagentAnnouncer(func(sessionKey, message string) error {
+		return m.announceQueue.Enqueue(sessionKey, message)
+	})
+
+	const parent = "agent:main:main"
+	parentRun := NewOrchestrator(&LoopConfig{Provider: &fakeChatProvider{}, MaxIterations: 1}, NewAgentState())
+	parentRun.setRunning(true)
+	m.setActiveRun(parent, "run-parent", parentRun)
+
+	const children = 5
+	var wg sync.WaitGroup
+	for i := 0; i < children; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.handleSubagentCompletion(fmt.Sprintf("child-%d", i), &SubagentRunRecord{
+				RunID:               fmt.Sprintf("child-%d", i),
+				ChildSessionKey:     fmt.Sprintf("agent:main:subagent:%d", i),
+				RequesterSessionKey: parent,
+				Task:                fmt.Sprintf("task %d", i),
+				Cleanup:             "keep",
+				Outcome:             &SubagentRunOutcome{Status: "ok"},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	steered := parentRun.takeSteering()
+	if len(steered) != 1 {
+		t.Fatalf("parent steered %d times, want 1 batched announce", len(steered))
+	}
+	text := extractTextContent(steered[0])
+	for i := 0; i < children; i++ {
+		if !strings.Contains(text, fmt.Sprintf(`"task %d"`, i)) {
+			t.Errorf("batched announce missing task %d: %q", i, text)
+		}
+	}
+}
+
+func TestAnnounceQueueSerializesDeliveriesPerSession(t *testing.T) {
+	var inFlight, maxInFlight, calls atomic.Int32
+	var mu sync.Mutex
+	var delivered []string
+	q := newAnnounceQueue(func(sessionKey, message string) error {
+		if n := inFlight.Add(1); n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		delivered = append(delivered, message)
+		mu.Unlock()
+		inFlight.Add(-1)
+		return nil
+	}, func() time.Duration { return 0 })
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := q.Enqueue("agent:main:main", fmt.Sprintf("announce-%d", i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if maxInFlight.Load() != 1 {
+		t.Errorf("max concurrent deliveries = %d, want 1", maxInFlight.Load())
+	}
+	if calls.Load() >= n {
+		t.Errorf("deliveries = %d, want announcements arriving during a delivery to be batched", calls.Load())
+	}
+	all := strings.Join(delivered, "\n")
+	for i := 0; i < n; i++ {
+		if !strings.Contains(all, fmt.Sprintf("announce-%d", i)) {
+			t.Errorf("announce-%d was not delivered", i)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+const (
+	// autoTitleTimeout 单次生成标题的超时
+	autoTitleTimeout = 30 * time.Second
+	// autoTitleMaxRunes 标题最大长度（字符数），超出截断
+	autoTitleMaxRunes = 60
+	// autoTitleMaxInputRunes 每条消息交给模型的最大字符数
+	autoTitleMaxInputRunes = 2000
+	// autoTitleMaxSuffix 标题与其他会话的标签重复时追加 " (n)" 的最大序号，超出时放弃
+	autoTitleMaxSuffix = 100
+	// autoTitleSystemPrompt 生成标题的系统提示
+	autoTitleSystemPrompt = "Generate a short title (at most 6 words) for the following conversation, in the language of the conversation. Output only the title, without quotes or trailing punctuation."
+)
+
+// shouldAutoTitle 判断本次回复后是否为会话生成标题：开启 session.auto_title、非子 agent / internal 会话、
+// 尚无 label，且 user 轮次恰好达到 auto_title_after_turns（只尝试一次，失败不重试）
+func shouldAutoTitle(cfg *config.Config, channel, sessionKey string, sess *session.Session) bool {
+	if cfg == nil || !cfg.Session.AutoTitle || channel == "internal" || session.IsSubagentSessionKey(sessionKey) {
+		return false
+	}
+	if label, _ := sess.GetMetadata(session.MetadataLabel).(string); strings.TrimSpace(label) != "" {
+		return false
+	}
+	afterTurns := cfg.Session.AutoTitleAfterTurns
+	if afterTurns <= 0 {
+		afterTurns = 1
+	}
+	turns := 0
+	for _, msg := range sess.GetHistory(-1) {
+		if msg.Role == string(RoleUser) {
+			turns++
+		}
+	}
+	return turns == afterTurns
+}
+
+// autoTitleSession 调用模型为会话生成标题并写入 label；label 在生成期间被设置时不覆盖，
+// 与其他会话的标签重复时追加 " (2)"、" (3)" 等序号（标签须唯一，见 sessions.patch）
+func (m *AgentManager) autoTitleSession(ctx context.Context, sessionKey string, sess *session.Session, model string) {
+	if m.provider == nil {
+		return
+	}
+	var b strings.Builder
+	for _, msg := range sess.GetHistory(-1) {
+		if msg.Role != string(RoleUser) && msg.Role != string(RoleAssistant) {
+			continue
+		}
+		content := strings.TrimSpace(msg.Content)
+		if content == "" {
+			continue
+		}
+		if r := []rune(content); len(r) > autoTitleMaxInputRunes {
+			content = string(r[:autoTitleMaxInputRunes]) + summaryTruncatedSuffix
+		}
+		b.WriteString(msg.Role + ": " + content + "\n\n")
+	}
+	if b.Len() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, autoTitleTimeout)
+	defer cancel()
+	opts := []providers.ChatOption{providers.WithMaxTokens(32)}
+	if model != "" {
+		opts = append(opts, providers.WithModel(model))
+	}
+	resp, err := m.provider.Chat(ctx, []providers.Message{
+		{Role: "system", Content: autoTitleSystemPrompt},
+		{Role: "user", Content: b.String()},
+	}, nil, opts...)
+	if err != nil {
+		logger.Warn("Failed to generate session title", zap.String("session_key", sessionKey), zap.Error(err))
+		return
+	}
+	title := cleanAutoTitle(resp.Content)
+	if title == "" {
+		return
+	}
+	if label, _ := sess.GetMetadata(session.MetadataLabel).(string); strings.TrimSpace(label) != "" {
+		return
+	}
+	if title = m.uniqueAutoTitle(sessionKey, title); title == "" {
+		return
+	}
+	sess.PatchMetadata(map[string]interface{}{session.MetadataLabel: title})
+	if err := m.sessionMgr.Save(sess); err != nil {
+		logger.Warn("Failed to save session title", zap.String("session_key", sessionKey), zap.Error(err))
+		return
+	}
+	logger.Info("Generated session title", zap.String("session_key", sessionKey), zap.String("title", title))
+}
+
+// uniqueAutoTitle 返回不与其他会话标签重复的标题：依次尝试 title、"title (2)"、"title (3)"……，都被占用时返回空
+func (m *AgentManager) uniqueAutoTitle(sessionKey, title string) string {
+	for n := 1; n <= autoTitleMaxSuffix; n++ {
+		candidate := title
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%d)", title, n)
+		}
+		if owner, ok := m.sessionMgr.FindByLabel(candidate); !ok || owner == sessionKey {
+			return candidate
+		}
+	}
+	logger.Warn("Generated session title already in use", zap.String("session_key", sessionKey), zap.String("title", title))
+	return ""
+}
+
+// cleanAutoTitle 取模型输出的第一行，去掉引号、"Title:" 前缀与结尾标点并截断
+func cleanAutoTitle(s string) string {
+	s = StripThinkTags(s, "")
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	s = strings.TrimSpace(s)
+	if len(s) > 6 && strings.EqualFold(s[:6], "title:") {
+		s = strings.TrimSpace(s[6:])
+	}
+	s = strings.Trim(s, "\"'`*#“”‘’「」 ")
+	s = strings.TrimRight(s, ".。!！?？:：")
+	if r := []rune(s); len(r) > autoTitleMaxRunes {
+		s = strings.TrimSpace(string(r[:autoTitleMaxRunes]))
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// titleProvider 回复固定标题
+type titleProvider struct {
+	fakeChatProvider
+	model string
+}
+
+func (p *titleProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	opts := &providers.ChatOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	p.model = opts.Model
+	return &providers.Response{Content: "\"Trip to Kyoto.\"\nextra"}, nil
+}
+
+func TestAutoTitleAfterFirstExchange(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Session.AutoTitle = true
+	cfg.Session.AutoTitleModel = "gpt-4o-mini"
+	config.Set(cfg)
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := &titleProvider{}
+	m := &AgentManager{bus: bus.NewMessageBus(64), sessionMgr: sessMgr, provider: titles}
+	defer m.bus.Close()
+
+	const key = "agent:main:main"
+	sess, _ := sessMgr.GetOrCreate(key)
+	orchestrator := NewOrchestrator(&LoopConfig{Provider: &fakeChatProvider{}, MaxIterations: 3}, NewAgentState())
+	msg := &bus.InboundMessage{ID: "run-1", Channel: "websocket", ChatID: key, Content: "plan a trip to kyoto"}
+	userMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: msg.Content}}, Timestamp: time.Now().UnixMilli()}
+	if _, err := m.executeAgentRun(context.Background(), msg, nil, orchestrator, []AgentMessage{userMsg}, key, userMsg, sess, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sess.GetMetadata("label") == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := sess.GetMetadata("label"); got != "Trip to Kyoto" {
+		t.Fatalf("label = %v, want %q", got, "Trip to Kyoto")
+	}
+	if titles.model != "gpt-4o-mini" {
+		t.Errorf("title model = %q, want gpt-4o-mini", titles.model)
+	}
+	if shouldAutoTitle(cfg, "websocket", key, sess) {
+		t.Error("labeled session should not be titled again")
+	}
+
+	// 第二个会话生成同样的标题时追加序号，保持标签唯一
+	other, _ := sessMgr.GetOrCreate("agent:main:other")
+	other.AddMessage(session.Message{Role: "user", Content: "kyoto again"})
+	m.autoTitleSession(context.Background(), "agent:main:other", other, "")
+	if got := other.GetMetadata(session.MetadataLabel); got != "Trip to Kyoto (2)" {
+		t.Fatalf("second label = %v, want %q", got, "Trip to Kyoto (2)")
+	}
+	if owner, _ := sessMgr.FindByLabel("Trip to Kyoto"); owner != key {
+		t.Errorf("label owner = %q, want %q", owner, key)
+	}
+
+	sub, _ := sessMgr.GetOrCreate("agent:main:subagent:abc")
+	sub.AddMessage(session.Message{Role: "user", Content: "task"})
+	if shouldAutoTitle(cfg, "internal", "agent:main:subagent:abc", sub) {
+		t.Error("subagent session should not be titled")
+	}
+}
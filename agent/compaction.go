@@ -2,7 +2,9 @@ package agent
 
 import (
 	"errors"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // CharsPerTokenEstimate 简单 token 估算：每 token 约 4 字符（英文偏多时更准）
@@ -99,23 +101,151 @@ func LimitHistoryTurns(messages []AgentMessage, maxTurns int) []AgentMessage {
 	return messages
 }
 
-// TruncateToolResult 将单条 tool 结果文本截断到不超过 maxChars 字符，并在末尾追加截断说明。
-// 若 content 已不超过 maxChars，返回原 content。
-func TruncateToolResult(content string, maxChars int) string {
+// FitHistoryToBudget 从最早的 user 轮次开始整轮丢弃，直到估算 token 数不超过 budget（至少保留最后一个轮次）。
+// 返回裁剪后的消息与丢弃的轮次数；budget <= 0 或未超出时原样返回
+func FitHistoryToBudget(messages []AgentMessage, budget int) ([]AgentMessage, int) {
+	if budget <= 0 || EstimateMessagesTokens(messages) <= budget {
+		return messages, 0
+	}
+	totalTurns, keepTurns, sum := 0, 0, 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		sum += EstimateMessageTokens(messages[i])
+		if messages[i].Role != RoleUser {
+			continue
+		}
+		totalTurns++
+		if keepTurns == 0 || sum <= budget && keepTurns == totalTurns-1 {
+			keepTurns++
+		}
+	}
+	if keepTurns == 0 || keepTurns == totalTurns {
+		return messages, 0
+	}
+	return LimitHistoryTurns(messages, keepTurns), totalTurns - keepTurns
+}
+
+// DefaultTruncationMarker 默认截断标记，{n} 替换为省略的字符（rune）数
+const DefaultTruncationMarker = "\n…[{n} chars omitted]…\n"
+
+// ToolResultTruncation tool 结果截断选项：保留开头与结尾，中间以标记替代。
+// HeadChars / TailChars 为 0 时按预算自动分配（开头 2/3、结尾 1/3）；Marker 为空时使用 DefaultTruncationMarker。
+type ToolResultTruncation struct {
+	HeadChars int
+	TailChars int
+	Marker    string
+}
+
+// codeFence Markdown 代码块围栏
+const codeFence = "```"
+
+// TruncateToolResult 将单条 tool 结果文本截断到约 maxChars 字节（UTF-8）：保留开头与结尾，中间替换为“…[N chars omitted]…”，
+// N 为省略的字符（rune）数。截断点不拆分 UTF-8 字符，尽量落在行边界，并补齐被截断的代码块围栏。若 content 已不超过 maxChars，返回原 content。
+func TruncateToolResult(content string, maxChars int, opts ToolResultTruncation) string {
 	if maxChars <= 0 || len(content) <= maxChars {
 		return content
 	}
-	suffix := "\n\n[Content truncated — original was too large for the model's context window.]"
-	keep := maxChars - len(suffix)
-	if keep < 0 {
-		keep = 0
+	marker := opts.Marker
+	if marker == "" {
+		marker = DefaultTruncationMarker
+	}
+	// 标记与补齐的围栏也计入预算
+	budget := maxChars - len(marker) - 2*(len(codeFence)+1) - 10
+	if budget < 0 {
+		budget = 0
 	}
-	return content[:keep] + suffix
+	head, tail := splitTruncationBudget(budget, opts.HeadChars, opts.TailChars)
+
+	headEnd := lineBoundaryBefore(content, head)
+	tailStart := lineBoundaryAfter(content, len(content)-tail)
+	if tailStart < headEnd {
+		tailStart = headEnd
+	}
+
+	headPart := content[:headEnd]
+	tailPart := content[tailStart:]
+	// 开头停在代码块内：补上闭合围栏；结尾从代码块内开始：补上开启围栏
+	if countFences(headPart)%2 == 1 {
+		headPart = strings.TrimRight(headPart, "\n") + "\n" + codeFence
+	}
+	if tailPart != "" && countFences(content[:tailStart])%2 == 1 {
+		tailPart = codeFence + "\n" + tailPart
+	}
+
+	omitted := utf8.RuneCountInString(content[headEnd:tailStart])
+	return headPart + strings.ReplaceAll(marker, "{n}", strconv.Itoa(omitted)) + tailPart
+}
+
+// splitTruncationBudget 按配置分配开头/结尾保留字符数，总和不超过 budget
+func splitTruncationBudget(budget, head, tail int) (int, int) {
+	switch {
+	case head <= 0 && tail <= 0:
+		head = budget * 2 / 3
+		tail = budget - head
+	case head <= 0:
+		tail = min(tail, budget)
+		head = budget - tail
+	case tail <= 0:
+		head = min(head, budget)
+		tail = budget - head
+	case head+tail > budget:
+		// 均超过预算时按比例缩小
+		h := int(int64(budget) * int64(head) / int64(head+tail))
+		head, tail = h, budget-h
+	}
+	return head, tail
+}
+
+// lineBoundaryBefore 返回不超过 pos 的截断点：优先落在换行之后（回退不超过一半），且不拆分 UTF-8 字符
+func lineBoundaryBefore(s string, pos int) int {
+	if pos >= len(s) {
+		return len(s)
+	}
+	if pos <= 0 {
+		return 0
+	}
+	if i := strings.LastIndexByte(s[:pos], '\n'); i >= 0 && i+1 >= pos/2 {
+		return i + 1
+	}
+	for pos > 0 && !utf8.RuneStart(s[pos]) {
+		pos--
+	}
+	return pos
+}
+
+// lineBoundaryAfter 返回不小于 pos 的起点：优先落在换行之后（前移不超过剩余的一半），且不拆分 UTF-8 字符
+func lineBoundaryAfter(s string, pos int) int {
+	if pos <= 0 {
+		return 0
+	}
+	if pos >= len(s) {
+		return len(s)
+	}
+	if s[pos-1] == '\n' {
+		return pos
+	}
+	if i := strings.IndexByte(s[pos:], '\n'); i >= 0 && i < (len(s)-pos)/2 {
+		return pos + i + 1
+	}
+	for pos < len(s) && !utf8.RuneStart(s[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// countFences 统计以 ``` 开头的行数（代码块围栏）
+func countFences(s string) int {
+	n := 0
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), codeFence) {
+			n++
+		}
+	}
+	return n
 }
 
 // TruncateToolResultByContext 按上下文窗口的 ToolResultMaxFraction 计算最大字符数并截断。
 // contextWindowTokens 为模型上下文窗口 token 数。
-func TruncateToolResultByContext(content string, contextWindowTokens int) string {
+func TruncateToolResultByContext(content string, contextWindowTokens int, opts ToolResultTruncation) string {
 	if contextWindowTokens <= 0 {
 		return content
 	}
@@ -124,7 +254,7 @@ func TruncateToolResultByContext(content string, contextWindowTokens int) string
 		maxTokens = 500
 	}
 	maxChars := maxTokens * CharsPerTokenEstimate
-	return TruncateToolResult(content, maxChars)
+	return TruncateToolResult(content, maxChars, opts)
 }
 
 // contentBlocksToText 从 ContentBlock 切片提取纯文本（用于估算和截断）
@@ -142,7 +272,7 @@ func contentBlocksToText(content []ContentBlock) string {
 }
 
 // CopyMessagesWithTruncatedToolResults 复制 messages 并对其中 role=tool 的消息按上下文窗口截断，返回新切片（不修改原切片）
-func CopyMessagesWithTruncatedToolResults(messages []AgentMessage, contextWindowTokens int) []AgentMessage {
+func CopyMessagesWithTruncatedToolResults(messages []AgentMessage, contextWindowTokens int, opts ToolResultTruncation) []AgentMessage {
 	if contextWindowTokens <= 0 {
 		out := make([]AgentMessage, len(messages))
 		copy(out, messages)
@@ -158,7 +288,7 @@ func CopyMessagesWithTruncatedToolResults(messages []AgentMessage, contextWindow
 		if text == "" {
 			continue
 		}
-		truncated := TruncateToolResultByContext(text, contextWindowTokens)
+		truncated := TruncateToolResultByContext(text, contextWindowTokens, opts)
 		if truncated != text {
 			out[i].Content = []ContentBlock{TextContent{Text: truncated}}
 		}
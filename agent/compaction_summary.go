@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
 )
 
 // SummarizeFunc 用于调用 LLM 对给定对话内容做摘要（与 OpenClaw generateSummary / summarizeWithFallback 对齐）
@@ -16,16 +20,38 @@ const (
 	// DefaultSummaryTailKeep 压缩时保留的末尾消息数（最近几轮）
 	DefaultSummaryTailKeep = 12
 	// SummaryMessagePrefix 替换为摘要时使用的单条消息前缀
-	SummaryMessagePrefix = "[Previous conversation summary]: "
+	SummaryMessagePrefix = session.SummaryMessagePrefix
+	// SummaryChunkReserveTokens 每次摘要调用为系统提示与摘要输出预留的 token 数
+	SummaryChunkReserveTokens = 2048
+	// maxSummaryReduceDepth 分层合并摘要的最大层数，防止摘要不收敛时无限递归
+	maxSummaryReduceDepth = 4
+	// summaryTruncatedSuffix 单条内容超出摘要块时的截断标记
+	summaryTruncatedSuffix = "... [truncated]"
+	// summarizerSystemPrompt 摘要调用的系统提示
+	summarizerSystemPrompt = "You are a summarizer. Output a concise summary of the following conversation. Preserve key decisions, TODOs, and constraints. Output only the summary, no preamble."
+	// sessionHistorySummaryTimeout 会话超出落盘上限时单次摘要的超时
+	sessionHistorySummaryTimeout = 2 * time.Minute
 )
 
 // CompactWithSummary 当消息总 token 可能超窗时，对“中间段”做 LLM 摘要并替换为一条 user 消息，再返回新列表（与 OpenClaw 方案 B 对齐）
-// 若 summarize 为 nil 或估算未超限，则返回原 messages（不修改）。
+// 若 summarize 为 nil 或估算未超限，则返回原 messages（不修改）。摘要模型窗口视为与主模型相同。
 func CompactWithSummary(
 	ctx context.Context,
 	messages []AgentMessage,
 	contextWindowTokens, reserveTokens int,
 	summarize SummarizeFunc,
+) ([]AgentMessage, error) {
+	return CompactWithChunkedSummary(ctx, messages, contextWindowTokens, reserveTokens, contextWindowTokens, summarize)
+}
+
+// CompactWithChunkedSummary 同 CompactWithSummary，但中间段按摘要模型窗口（summarizerWindowTokens）切块：
+// 每块单独摘要，再对各块摘要做合并摘要（必要时逐层合并），避免单次摘要请求本身超窗。
+// summarizerWindowTokens <= 0 时使用 contextWindowTokens。
+func CompactWithChunkedSummary(
+	ctx context.Context,
+	messages []AgentMessage,
+	contextWindowTokens, reserveTokens, summarizerWindowTokens int,
+	summarize SummarizeFunc,
 ) ([]AgentMessage, error) {
 	if summarize == nil || len(messages) == 0 {
 		return messages, nil
@@ -52,9 +78,11 @@ func CompactWithSummary(
 		return messages, nil
 	}
 
+	if summarizerWindowTokens <= 0 {
+		summarizerWindowTokens = contextWindowTokens
+	}
 	mid := messages[midStart:midEnd]
-	prompt := messagesToSummaryPrompt(mid)
-	summary, err := summarize(ctx, prompt)
+	summary, err := summarizeInChunks(ctx, mid, SummaryChunkTokens(summarizerWindowTokens), summarize)
 	if err != nil {
 		return nil, fmt.Errorf("compaction summarization failed: %w", err)
 	}
@@ -79,13 +107,128 @@ func CompactWithSummary(
 	return out, nil
 }
 
-func messagesToSummaryPrompt(messages []AgentMessage) string {
-	var b strings.Builder
+// SummaryChunkTokens 由摘要模型上下文窗口推导单次摘要输入的 token 上限
+func SummaryChunkTokens(summarizerWindowTokens int) int {
+	chunk := summarizerWindowTokens - SummaryChunkReserveTokens
+	if chunk < summarizerWindowTokens/2 {
+		chunk = summarizerWindowTokens / 2
+	}
+	if chunk < 1 {
+		chunk = 1
+	}
+	return chunk
+}
+
+// summarizeInChunks 将消息切成不超过 chunkTokens 的若干块分别摘要，再分层合并各块摘要
+func summarizeInChunks(ctx context.Context, messages []AgentMessage, chunkTokens int, summarize SummarizeFunc) (string, error) {
+	chunks := splitSummaryPrompts(messageSummaryLines(messages), chunkTokens)
+	if len(chunks) == 1 {
+		return summarize(ctx, chunks[0])
+	}
+
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		summary, err := summarize(ctx, chunk)
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, strings.TrimSpace(summary))
+	}
+	return mergeSummaries(ctx, summaries, chunkTokens, summarize, 0)
+}
+
+// mergeSummaries 合并多段摘要；合并输入仍超出 chunkTokens 时先分组合并，再逐层向上
+func mergeSummaries(ctx context.Context, summaries []string, chunkTokens int, summarize SummarizeFunc, depth int) (string, error) {
+	lines := make([]string, 0, len(summaries))
+	for i, s := range summaries {
+		lines = append(lines, fmt.Sprintf("Summary of part %d:\n%s", i+1, s))
+	}
+	groups := splitSummaryPrompts(lines, chunkTokens)
+	if len(groups) == 1 {
+		return summarize(ctx, mergeSummaryPrompt(groups[0]))
+	}
+	if depth >= maxSummaryReduceDepth || len(groups) >= len(summaries) {
+		// 继续分组也无法收敛：整体截断到单块后做最终合并
+		body := truncateSummaryText(strings.Join(lines, "\n\n"), chunkTokens*CharsPerTokenEstimate)
+		return summarize(ctx, mergeSummaryPrompt(body))
+	}
+
+	merged := make([]string, 0, len(groups))
+	for _, group := range groups {
+		summary, err := summarize(ctx, mergeSummaryPrompt(group))
+		if err != nil {
+			return "", err
+		}
+		merged = append(merged, strings.TrimSpace(summary))
+	}
+	return mergeSummaries(ctx, merged, chunkTokens, summarize, depth+1)
+}
+
+func mergeSummaryPrompt(body string) string {
+	return "The following are summaries of consecutive parts of one conversation. Merge them into a single summary.\n\n" + body
+}
+
+// truncateSummaryText 将文本截断到 maxChars 以内（含截断标记）
+func truncateSummaryText(text string, maxChars int) string {
+	if len(text) <= maxChars {
+		return text
+	}
+	if maxChars <= len(summaryTruncatedSuffix) {
+		return text[:maxChars]
+	}
+	return text[:maxChars-len(summaryTruncatedSuffix)] + summaryTruncatedSuffix
+}
+
+// messageSummaryLines 将每条消息渲染为 "role: text" 文本
+func messageSummaryLines(messages []AgentMessage) []string {
+	lines := make([]string, 0, len(messages))
 	for _, m := range messages {
-		b.WriteString(string(m.Role))
-		b.WriteString(": ")
-		b.WriteString(contentBlocksToText(m.Content))
+		lines = append(lines, string(m.Role)+": "+contentBlocksToText(m.Content))
+	}
+	return lines
+}
+
+// splitSummaryPrompts 按 token 估算将文本行打包为若干不超过 chunkTokens 的块；单行超限时截断
+func splitSummaryPrompts(lines []string, chunkTokens int) []string {
+	maxChars := chunkTokens * CharsPerTokenEstimate
+	var chunks []string
+	var b strings.Builder
+	for _, line := range lines {
+		line = truncateSummaryText(line, maxChars)
+		if b.Len() > 0 && b.Len()+len(line)+2 > maxChars {
+			chunks = append(chunks, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+		b.WriteString(line)
 		b.WriteString("\n\n")
 	}
-	return strings.TrimSpace(b.String())
+	if b.Len() > 0 || len(chunks) == 0 {
+		chunks = append(chunks, strings.TrimSpace(b.String()))
+	}
+	return chunks
+}
+
+// NewSessionHistoryCompactor 返回供 session.Manager 在会话超出 max_messages / max_bytes 时使用的摘要函数：
+// 对被裁掉的最早若干轮按摘要模型窗口（summarizer_context_tokens，未配置时为 context_tokens）切块摘要
+func NewSessionHistoryCompactor(provider providers.Provider, defaults config.AgentDefaults) session.HistoryCompactor {
+	summarizerWindowTokens := defaults.SummarizerContextTokens
+	if summarizerWindowTokens <= 0 {
+		summarizerWindowTokens, _ = ResolveContextWindow(defaults.ContextTokens, 0)
+	}
+	summarize := func(ctx context.Context, prompt string) (string, error) {
+		resp, err := provider.Chat(ctx, []providers.Message{
+			{Role: "system", Content: summarizerSystemPrompt},
+			{Role: "user", Content: prompt},
+		}, nil)
+		if err != nil {
+			return "", err
+		}
+		return resp.Content, nil
+	}
+	return func(dropped []session.Message) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionHistorySummaryTimeout)
+		defer cancel()
+		summary, err := summarizeInChunks(ctx, sessionMessagesToAgentMessages(dropped), SummaryChunkTokens(summarizerWindowTokens), summarize)
+		return strings.TrimSpace(summary), err
+	}
 }
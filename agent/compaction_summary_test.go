@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompactWithChunkedSummary_HistoryExceedsSummarizerWindow(t *testing.T) {
+	const summarizerWindow = 4096
+	chunkTokens := SummaryChunkTokens(summarizerWindow)
+
+	// 约 200 条、每条约 500 token，总量远超摘要模型窗口
+	messages := make([]AgentMessage, 0, 200)
+	for i := 0; i < 200; i++ {
+		role := RoleUser
+		if i%2 == 1 {
+			role = RoleAssistant
+		}
+		messages = append(messages, AgentMessage{
+			Role:    role,
+			Content: []ContentBlock{TextContent{Text: fmt.Sprintf("msg %d ", i) + generateLongText(2000)}},
+		})
+	}
+	if EstimateMessagesTokens(messages) < 10*summarizerWindow {
+		t.Fatalf("test history too small: %d tokens", EstimateMessagesTokens(messages))
+	}
+
+	calls := 0
+	merges := 0
+	summarize := func(ctx context.Context, prompt string) (string, error) {
+		calls++
+		if EstimateTokens(prompt) > chunkTokens+EstimateTokens(mergeSummaryPrompt("")) {
+			t.Errorf("summarizer prompt exceeds window: %d tokens > %d", EstimateTokens(prompt), chunkTokens)
+		}
+		if strings.HasPrefix(prompt, mergeSummaryPrompt("")) {
+			merges++
+		}
+		return fmt.Sprintf("summary #%d", calls), nil
+	}
+
+	out, err := CompactWithChunkedSummary(context.Background(), messages, 16000, 4096, summarizerWindow, summarize)
+	if err != nil {
+		t.Fatalf("CompactWithChunkedSummary() error = %v", err)
+	}
+
+	wantLen := DefaultSummaryHeadKeep + 1 + DefaultSummaryTailKeep
+	if len(out) != wantLen {
+		t.Fatalf("len(out) = %d, want %d", len(out), wantLen)
+	}
+	if calls < 2 {
+		t.Errorf("expected chunked summarization, got %d summarize calls", calls)
+	}
+	if merges == 0 {
+		t.Error("expected summaries of chunks to be merged")
+	}
+	text := contentBlocksToText(out[DefaultSummaryHeadKeep].Content)
+	if !strings.HasPrefix(text, SummaryMessagePrefix) {
+		t.Errorf("summary message = %q, want prefix %q", text, SummaryMessagePrefix)
+	}
+}
+
+func TestCompactWithChunkedSummary_SingleChunk(t *testing.T) {
+	messages := make([]AgentMessage, 0, 20)
+	for i := 0; i < 20; i++ {
+		messages = append(messages, AgentMessage{
+			Role:    RoleUser,
+			Content: []ContentBlock{TextContent{Text: generateLongText(400)}},
+		})
+	}
+
+	calls := 0
+	summarize := func(ctx context.Context, prompt string) (string, error) {
+		calls++
+		return "short", nil
+	}
+
+	// 主窗口很小触发压缩，摘要窗口足够大：只需一次摘要调用
+	if _, err := CompactWithChunkedSummary(context.Background(), messages, 1000, 500, 128000, summarize); err != nil {
+		t.Fatalf("CompactWithChunkedSummary() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("summarize calls = %d, want 1", calls)
+	}
+}
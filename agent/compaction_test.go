@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateToolResultKeepsHeadAndTailOfLargeJSON(t *testing.T) {
+	items := make([]map[string]interface{}, 400)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": i, "name": fmt.Sprintf("item-%03d", i), "note": "数据"}
+	}
+	raw, err := json.MarshalIndent(map[string]interface{}{"items": items}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(raw)
+
+	got := TruncateToolResult(content, 2000, ToolResultTruncation{})
+	if len(got) > 2000 {
+		t.Errorf("truncated length %d exceeds budget", len(got))
+	}
+	idx := strings.Index(got, "\n…[")
+	if idx < 0 || !strings.Contains(got, " chars omitted]…\n") {
+		t.Fatalf("missing truncation marker:\n%s", got)
+	}
+	head := got[:idx]
+	tail := got[strings.Index(got, "]…\n")+len("]…\n"):]
+	if !strings.HasPrefix(content, head) || !strings.HasSuffix(content, tail) {
+		t.Fatal("head/tail must be verbatim slices of the original")
+	}
+	// 在行边界截断：开头以换行结束，结尾从完整行开始
+	if !strings.HasSuffix(head, "\n") || !strings.HasPrefix(content[len(content)-len(tail)-1:], "\n") {
+		t.Errorf("cut is not on a line boundary: head ends %q, tail starts %q", head[len(head)-10:], tail[:10])
+	}
+	if !utf8.ValidString(got) {
+		t.Error("truncation split a UTF-8 rune")
+	}
+	var omitted int
+	fmt.Sscanf(got[idx+len("\n…["):], "%d", &omitted)
+	if want := utf8.RuneCountInString(content[len(head) : len(content)-len(tail)]); omitted != want {
+		t.Errorf("marker reports %d omitted chars, want %d", omitted, want)
+	}
+	if len(head) < len(tail) {
+		t.Errorf("default split should favour the head: head=%d tail=%d", len(head), len(tail))
+	}
+}
+
+func TestTruncateToolResultBalancesCodeFencesAndHonoursOptions(t *testing.T) {
+	content := "output:\n```go\n" + strings.Repeat("fmt.Println(\"line\")\n", 200) + "```\ndone\n"
+	opts := ToolResultTruncation{HeadChars: 100, TailChars: 300, Marker: "\n<<{n} omitted>>\n"}
+
+	got := TruncateToolResult(content, 1000, opts)
+	if !strings.Contains(got, " omitted>>\n") {
+		t.Fatalf("custom marker not used:\n%s", got)
+	}
+	if n := strings.Count(got, "```"); n%2 != 0 {
+		t.Errorf("code fences unbalanced (%d):\n%s", n, got)
+	}
+	parts := strings.SplitN(got, "<<", 2)
+	if len(parts[0]) > 100+len("\n```\n") || !strings.HasSuffix(strings.TrimRight(parts[0], "\n"), "```") {
+		t.Errorf("head should be ~100 chars and close the fence: %q", parts[0])
+	}
+	if !strings.HasSuffix(got, "```\ndone\n") {
+		t.Errorf("tail should keep the end of the output: %q", got[len(got)-40:])
+	}
+
+	if short := "small result"; TruncateToolResult(short, 1000, opts) != short {
+		t.Error("content within budget must be returned unchanged")
+	}
+}
+
+func TestFitHistoryToBudgetDropsOldestTurns(t *testing.T) {
+	turn := func(i int) []AgentMessage {
+		text := strings.Repeat(fmt.Sprintf("%d", i%10), 400) // 每条约 100 tokens
+		return []AgentMessage{
+			{Role: RoleUser, Content: []ContentBlock{TextContent{Text: text}}},
+			{Role: RoleAssistant, Content: []ContentBlock{TextContent{Text: text}}},
+		}
+	}
+	var messages []AgentMessage
+	for i := 0; i < 10; i++ {
+		messages = append(messages, turn(i)...)
+	}
+
+	if got, dropped := FitHistoryToBudget(messages, 5000); dropped != 0 || len(got) != len(messages) {
+		t.Fatalf("within budget: dropped=%d len=%d", dropped, len(got))
+	}
+	got, dropped := FitHistoryToBudget(messages, 650)
+	if dropped != 7 || len(got) != 6 || got[0].Role != RoleUser {
+		t.Fatalf("dropped=%d len=%d", dropped, len(got))
+	}
+	if EstimateMessagesTokens(got) > 650 {
+		t.Fatalf("trimmed history still over budget: %d", EstimateMessagesTokens(got))
+	}
+	// 最后一个轮次本身超出预算时仍保留
+	got, dropped = FitHistoryToBudget(messages, 50)
+	if dropped != 9 || len(got) != 2 {
+		t.Fatalf("tiny budget: dropped=%d len=%d", dropped, len(got))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/internal"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/session"
@@ -51,6 +52,34 @@ func (b *ContextBuilder) BuildSystemPromptWithMode(skills []*Skill, mode PromptM
 	return b.buildSystemPromptWithSkills(skillsContent, mode)
 }
 
+// ExpandSystemPrompt 按本次运行展开 agent 配置的 system_prompt 模板（{{.Date}} 取当前时间），
+// Workspace 未设置时使用构建器的工作区；模板在加载配置时已校验，展开失败时记录警告并原样使用
+func (b *ContextBuilder) ExpandSystemPrompt(text string, vars config.SystemPromptVars) string {
+	if vars.Workspace == "" && b != nil {
+		vars.Workspace = b.workspace
+	}
+	defaults := config.AgentDefaults{UserTimezone: vars.UserTimezone}
+	loc, err := defaults.UserLocation()
+	if err != nil {
+		loc = time.Local
+	}
+	now := time.Now().In(loc)
+	if vars.UserTimezone == "" {
+		vars.UserTimezone = loc.String()
+		if vars.UserTimezone == "Local" {
+			vars.UserTimezone, _ = now.Zone()
+		}
+	}
+	vars.Date = now.Format("2006-01-02")
+
+	expanded, err := config.RenderSystemPrompt(text, vars)
+	if err != nil {
+		logger.Warn("Failed to expand system prompt template, using it verbatim", zap.Error(err))
+		return text
+	}
+	return expanded
+}
+
 // buildSystemPromptWithSkills 使用指定的技能内容和模式构建系统提示词
 func (b *ContextBuilder) buildSystemPromptWithSkills(skillsContent string, mode PromptMode) string {
 	isMinimal := mode == PromptModeMinimal || mode == PromptModeNone
@@ -127,10 +156,12 @@ func (b *ContextBuilder) buildIdentityAndTools() string {
 		"web_search":             "Search the web using API",
 		"web_fetch":              "Fetch web pages",
 		"use_skill":              "Load a specialized skill. SKILLS HAVE HIGHEST PRIORITY - always check Skills section first before using other tools",
+		"list_skills":            "List available skills (name, description), optionally filtered by keyword; use before use_skill when unsure",
 		"sessions_list":         "List session keys for the agent",
 		"sessions_history":      "Get recent messages for a session by session_key",
 		"sessions_send":         "Send a message to a session by session_key",
 		"session_status":        "Get status (message count, updated_at) for a session",
+		"agent_activity":        "Summarize another agent's recent sessions and runs (supervisor agents only)",
 	}
 
 	// 构建工具列表
@@ -138,8 +169,8 @@ func (b *ContextBuilder) buildIdentityAndTools() string {
 		"smart_search", "browser_navigate", "browser_screenshot", "browser_get_text",
 		"browser_click", "browser_fill_input", "browser_execute_script",
 		"read_file", "write_file", "list_files", "run_shell",
-		"web_search", "web_fetch", "use_skill",
-		"sessions_list", "sessions_history", "sessions_send", "session_status",
+		"web_search", "web_fetch", "use_skill", "list_skills",
+		"sessions_list", "sessions_history", "sessions_send", "session_status", "agent_activity",
 	}
 
 	var toolLines []string
@@ -626,6 +657,11 @@ func (b *ContextBuilder) loadBootstrapFiles() string {
 // 每个 tool 消息必须有一个前置的 assistant 消息，且该消息包含对应的 tool_calls
 // 此外，过滤掉没有 tool_name 的旧 tool 消息（向后兼容）
 func (b *ContextBuilder) validateHistoryMessages(history []session.Message) []session.Message {
+	return filterOrphanedToolMessages(history)
+}
+
+// filterOrphanedToolMessages 见 validateHistoryMessages；会话格式错误修复时复用
+func filterOrphanedToolMessages(history []session.Message) []session.Message {
 	var valid []session.Message
 
 	for i, msg := range history {
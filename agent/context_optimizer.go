@@ -262,13 +262,6 @@ func NewProgressiveCompression(maxTokens, reserveTokens int) *ProgressiveCompres
 	return &ProgressiveCompression{
 		optimizer: optimizer,
 		levels: []CompressionLevel{
-			{
-				Name:      "none",
-				MaxTokens: maxTokens,
-				Compression: func(msgs []AgentMessage) []AgentMessage {
-					return msgs
-				},
-			},
 			{
 				Name:      "light",
 				MaxTokens: int(float64(maxTokens) * 0.8),
@@ -297,7 +290,7 @@ func NewProgressiveCompression(maxTokens, reserveTokens int) *ProgressiveCompres
 	}
 }
 
-// Compress 执行渐进式压缩
+// Compress 执行渐进式压缩（调用方已判定需要压缩，因此至少执行 light 级别）
 func (p *ProgressiveCompression) Compress(messages []AgentMessage) []AgentMessage {
 	currentTokens := EstimateMessagesTokens(messages)
 
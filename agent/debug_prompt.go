@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/redact"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// debugPromptLog 输出完整 prompt 的日志函数（测试中可替换）
+var debugPromptLog = logger.Info
+
+// sessionDebugPrompts 会话元数据 debugPrompts 为 true 时返回 true（由 sessions.patch 设置，仅 control 角色可开启）
+func sessionDebugPrompts(sess *session.Session) bool {
+	if sess == nil {
+		return false
+	}
+	v, _ := sess.GetMetadata(session.MetadataDebugPrompts).(bool)
+	return v
+}
+
+// withDebugPrompts 会话开启 debugPrompts 时在本次运行选项中打开完整 prompt 日志
+func withDebugPrompts(opts *RunOptions, sess *session.Session) *RunOptions {
+	if !sessionDebugPrompts(sess) {
+		return opts
+	}
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	opts.DebugPrompts = true
+	return opts
+}
+
+// logDebugPrompt 以 info 级别记录发给 LLM 的完整 prompt（system + messages + tools），已脱敏
+func (o *Orchestrator) logDebugPrompt(sessionKey, model string, messages []providers.Message, toolDefs []providers.ToolDefinition) {
+	known := o.skillAPIKeys()
+
+	type loggedMessage struct {
+		Role       string               `json:"role"`
+		Content    string               `json:"content"`
+		Images     int                  `json:"images,omitempty"`
+		ToolCallID string               `json:"tool_call_id,omitempty"`
+		ToolCalls  []providers.ToolCall `json:"tool_calls,omitempty"`
+	}
+	logged := make([]loggedMessage, 0, len(messages))
+	for _, m := range messages {
+		logged = append(logged, loggedMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			Images:     len(m.Images),
+			ToolCallID: m.ToolCallID,
+			ToolCalls:  m.ToolCalls,
+		})
+	}
+	messagesJSON, err := json.Marshal(logged)
+	if err != nil {
+		messagesJSON = []byte(fmt.Sprintf("marshal messages failed: %v", err))
+	}
+	toolsJSON, err := json.Marshal(toolDefs)
+	if err != nil {
+		toolsJSON = []byte(fmt.Sprintf("marshal tools failed: %v", err))
+	}
+
+	debugPromptLog("=== Debug Prompt ===",
+		zap.String("session_key", sessionKey),
+		zap.String("model", model),
+		zap.Int("messages_count", len(messages)),
+		zap.String("messages", redact.Text(string(messagesJSON), known)),
+		zap.String("tools", redact.Text(string(toolsJSON), known)))
+}
+
+// skillAPIKeys 本次运行技能配置的 API key，输出 prompt 时按原值脱敏
+func (o *Orchestrator) skillAPIKeys() []string {
+	known := make([]string, 0)
+	for _, skill := range o.skills {
+		if skill.APIKey != "" {
+			known = append(known, skill.APIKey)
+		}
+	}
+	return known
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// fakeChatProvider 直接返回固定回复
+type fakeChatProvider struct{}
+
+func (p *fakeChatProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return &providers.Response{Content: "done"}, nil
+}
+
+func (p *fakeChatProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *fakeChatProvider) Close() error            { return nil }
+func (p *fakeChatProvider) SupportsStreaming() bool { return false }
+
+func TestDebugPromptsLogsOnlyTargetedSession(t *testing.T) {
+	type logged struct {
+		sessionKey string
+		messages   string
+	}
+	var logs []logged
+	orig := debugPromptLog
+	debugPromptLog = func(msg string, fields ...zap.Field) {
+		entry := logged{}
+		for _, f := range fields {
+			switch f.Key {
+			case "session_key":
+				entry.sessionKey = f.String
+			case "messages":
+				entry.messages = f.String
+			}
+		}
+		logs = append(logs, entry)
+	}
+	defer func() { debugPromptLog = orig }()
+
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	debugSess, _ := mgr.GetOrCreate("agent:main:debug")
+	debugSess.PatchMetadata(map[string]interface{}{session.MetadataDebugPrompts: true})
+	otherSess, _ := mgr.GetOrCreate("agent:main:other")
+
+	cfg := &LoopConfig{Provider: &fakeChatProvider{}, MaxIterations: 1}
+	run := func(sess *session.Session, text string) {
+		state := NewAgentState()
+		state.SessionKey = sess.Key
+		o := NewOrchestrator(cfg, state)
+		prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: text}}}
+		if _, err := o.Run(context.Background(), []AgentMessage{prompt}, withDebugPrompts(nil, sess)); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	run(otherSess, "hello from other")
+	run(debugSess, "my api_key=sk-abcdefghijklmnopqrstuvwxyz please")
+
+	if len(logs) != 1 {
+		t.Fatalf("debug prompt logs = %d, want 1", len(logs))
+	}
+	if logs[0].sessionKey != "agent:main:debug" {
+		t.Errorf("logged session = %q, want agent:main:debug", logs[0].sessionKey)
+	}
+	if strings.Contains(logs[0].messages, "sk-abcdefghijklmnopqrstuvwxyz") {
+		t.Errorf("secret not redacted: %s", logs[0].messages)
+	}
+	if !strings.Contains(logs[0].messages, "please") {
+		t.Errorf("prompt content missing from log: %s", logs[0].messages)
+	}
+
+	// 关闭后不再记录
+	debugSess.PatchMetadata(map[string]interface{}{session.MetadataDebugPrompts: nil})
+	run(debugSess, "again")
+	if len(logs) != 1 {
+		t.Errorf("debug prompt logs after disabling = %d, want 1", len(logs))
+	}
+}
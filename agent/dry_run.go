@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/redact"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// DryRunRequest dry-run 时组装出的 LLM 请求（与实际调用时发送的 messages / tools / 选项一致）
+type DryRunRequest struct {
+	Model    string                     `json:"model"`
+	Messages []providers.Message        `json:"messages"`
+	Tools    []providers.ToolDefinition `json:"tools"`
+	Options  DryRunChatOptions          `json:"options"`
+}
+
+// DryRunChatOptions 本次请求的调用选项（providers.ChatOptions 的 JSON 形式）
+type DryRunChatOptions struct {
+	Model       string  `json:"model,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	MaxTokens   int     `json:"maxTokens,omitempty"`
+	Thinking    string  `json:"thinking,omitempty"`
+}
+
+// dryRun 本次运行是否为 dry-run
+func (o *Orchestrator) dryRun() bool {
+	return o.runOpts != nil && o.runOpts.DryRun
+}
+
+// dryRunResponse 不调用 provider，将组装好的请求序列化为 JSON（按 debugPrompts 相同规则脱敏），
+// 通过 dry_run 事件发出并作为无 tool call 的 assistant 回复返回，使本次运行在第一轮结束
+func (o *Orchestrator) dryRunResponse(model string, messages []providers.Message, toolDefs []providers.ToolDefinition, chatOpts []providers.ChatOption) AgentMessage {
+	var applied providers.ChatOptions
+	for _, opt := range chatOpts {
+		opt(&applied)
+	}
+	if toolDefs == nil {
+		toolDefs = []providers.ToolDefinition{}
+	}
+	req := DryRunRequest{
+		Model:    model,
+		Messages: messages,
+		Tools:    toolDefs,
+		Options: DryRunChatOptions{
+			Model:       applied.Model,
+			Temperature: applied.Temperature,
+			MaxTokens:   applied.MaxTokens,
+			Thinking:    applied.Thinking,
+		},
+	}
+	payload, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		payload = []byte(fmt.Sprintf(`{"error": %q}`, err.Error()))
+	}
+	text := redact.Text(string(payload), o.skillAPIKeys())
+
+	logger.Info("=== Dry Run: LLM call skipped ===",
+		zap.String("session_key", o.state.SessionKey),
+		zap.String("model", model),
+		zap.Int("messages_count", len(messages)),
+		zap.Int("tools_count", len(toolDefs)))
+	o.emit(NewEvent(EventDryRun).WithContent(text))
+	o.emit(NewEvent(EventMessageEnd))
+
+	return AgentMessage{
+		Role:      RoleAssistant,
+		Content:   []ContentBlock{TextContent{Text: text}},
+		Timestamp: time.Now().UnixMilli(),
+		Metadata:  map[string]any{"stop_reason": "dry_run"},
+	}
+}
+
+// PreviewRun agent.preview：以会话当前历史加上 content 作为用户消息做一次 dry-run，返回将发给 LLM 的请求 JSON。
+// 使用与正常运行相同的 agent、会话级模型/思考等级与上下文裁剪，不调用 LLM，不写入会话
+func (m *AgentManager) PreviewRun(ctx context.Context, sessionKey, content string) (string, error) {
+	agentID, _, ok := session.ParseAgentSessionKey(sessionKey)
+	if !ok {
+		return "", fmt.Errorf("invalid session key: %s", sessionKey)
+	}
+	m.mu.RLock()
+	agent, ok := m.agents[agentID]
+	if !ok {
+		agent = m.defaultAgent
+	}
+	m.mu.RUnlock()
+	if agent == nil {
+		return "", fmt.Errorf("no agent for session: %s", sessionKey)
+	}
+
+	// 不按 reset 策略重置：预览不应改变会话状态
+	sess, err := m.sessionMgr.GetOrCreateWithPolicy(sessionKey, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	messages := sessionMessagesToAgentMessages(sess.GetHydratedHistory(-1))
+	messages = append(messages, AgentMessage{
+		Role:      RoleUser,
+		Content:   []ContentBlock{TextContent{Text: content}},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	messages, _ = agent.FitHistoryToContext(messages)
+
+	opts := withSessionLevels(withModelOverride(m.buildRunOptionsForSession(sessionKey), sess), sess)
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	opts.DryRun = true
+
+	final, err := agent.CreateOrchestratorForRun(sessionKey).Run(ctx, messages, opts)
+	if err != nil {
+		return "", err
+	}
+	for i := len(final) - 1; i >= 0; i-- {
+		if final[i].Role == RoleAssistant {
+			return strings.TrimSpace(extractTextContent(final[i])), nil
+		}
+	}
+	return "", fmt.Errorf("dry run produced no request")
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// countingProvider 记录 Chat 调用次数
+type countingProvider struct {
+	fakeChatProvider
+	calls int
+}
+
+func (p *countingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.calls++
+	return p.fakeChatProvider.Chat(ctx, messages, tools, options...)
+}
+
+func TestDryRunReturnsRequestWithoutCallingProvider(t *testing.T) {
+	provider := &countingProvider{}
+	cfg := &LoopConfig{Provider: provider, Model: "gpt-4o", MaxTokens: 512, MaxIterations: 3}
+	state := NewAgentState()
+	state.SessionKey = "agent:main:main"
+	state.SystemPrompt = "You are helpful."
+	state.Tools = []Tool{&fakeTool{name: "exec"}}
+	o := NewOrchestrator(cfg, state)
+	events := o.Subscribe()
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "what is my token=abc123secret"}}}
+	final, err := o.Run(context.Background(), []AgentMessage{prompt}, &RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if provider.calls != 0 {
+		t.Fatalf("provider called %d times during dry run", provider.calls)
+	}
+
+	last := final[len(final)-1]
+	if last.Role != RoleAssistant {
+		t.Fatalf("last message role = %s", last.Role)
+	}
+	var req DryRunRequest
+	if err := json.Unmarshal([]byte(extractTextContent(last)), &req); err != nil {
+		t.Fatalf("reply is not a request payload: %v", err)
+	}
+	if req.Model != "gpt-4o" || req.Options.MaxTokens != 512 {
+		t.Errorf("model/options = %q %+v", req.Model, req.Options)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != "You are helpful." {
+		t.Fatalf("messages = %+v", req.Messages)
+	}
+	if req.Messages[1].Content != "what is my token=[REDACTED]" {
+		t.Errorf("user message = %q", req.Messages[1].Content)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Name != "exec" {
+		t.Errorf("tools = %+v", req.Tools)
+	}
+
+	var dryRunEvents int
+	for len(events) > 0 {
+		if e := <-events; e.Type == EventDryRun {
+			dryRunEvents++
+			if e.Content != extractTextContent(last) {
+				t.Error("dry_run event content differs from the returned payload")
+			}
+		}
+	}
+	if dryRunEvents != 1 {
+		t.Errorf("dry_run events = %d, want 1", dryRunEvents)
+	}
+}
+
+func TestConfiguredSystemPromptExpandedPerRun(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &LoopConfig{
+		Provider:         &countingProvider{},
+		MaxIterations:    1,
+		ContextBuilder:   NewContextBuilder(NewMemoryStore(workspace), workspace),
+		SystemPrompt:     `You are {{.AgentName}}; today is {{.Date}} in {{.UserTimezone}}. Keep \{{braces}}.`,
+		SystemPromptVars: config.SystemPromptVars{AgentName: "Claw", UserTimezone: "UTC"},
+	}
+	o := NewOrchestrator(cfg, NewAgentState())
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}}
+	final, err := o.Run(context.Background(), []AgentMessage{prompt}, &RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var req DryRunRequest
+	if err := json.Unmarshal([]byte(extractTextContent(final[len(final)-1])), &req); err != nil {
+		t.Fatal(err)
+	}
+	want := "You are Claw; today is " + time.Now().UTC().Format("2006-01-02") + " in UTC. Keep {{braces}}.\n\n---\n\n"
+	if system := req.Messages[0].Content; !strings.HasPrefix(system, want) {
+		t.Errorf("system prompt should start with the expanded template:\n%.200s", system)
+	}
+}
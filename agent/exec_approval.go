@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// defaultApprovalTimeout 等待审批的最长时间，超时视为拒绝
+const defaultApprovalTimeout = 10 * time.Minute
+
+// ToolApprovalFunc 工具执行前的审批：返回是否允许执行，拒绝时附带原因
+type ToolApprovalFunc func(ctx context.Context, toolCallID, toolName string, args map[string]any) (approved bool, reason string)
+
+// PendingApproval 等待用户决定的一次工具执行
+type PendingApproval struct {
+	ID          string         `json:"approvalId"`
+	SessionKey  string         `json:"sessionKey"`
+	RunID       string         `json:"runId"`
+	ToolCallID  string         `json:"toolCallId"`
+	ToolName    string         `json:"name"`
+	Key         string         `json:"key"` // remember 时写入允许列表的键，见 approvalKey
+	Args        map[string]any `json:"args,omitempty"`
+	CreatedAtMs int64          `json:"createdAtMs"`
+	ExpiresAtMs int64          `json:"expiresAtMs"`
+
+	decision chan bool
+}
+
+// approvalRegistry 等待审批的工具执行（approvalId -> 请求）；零值可用
+type approvalRegistry struct {
+	mu      sync.Mutex
+	pending map[string]*PendingApproval
+}
+
+func (r *approvalRegistry) add(p *PendingApproval) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		r.pending = make(map[string]*PendingApproval)
+	}
+	r.pending[p.ID] = p
+}
+
+func (r *approvalRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, id)
+}
+
+// resolve 投递决定并移除请求；请求不存在（已决定、超时或运行结束）时返回错误
+func (r *approvalRegistry) resolve(id string, approved bool) (*PendingApproval, error) {
+	r.mu.Lock()
+	p, ok := r.pending[id]
+	delete(r.pending, id)
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("approval not found or already resolved: %s", id)
+	}
+	p.decision <- approved
+	return p, nil
+}
+
+// toolRequiresApproval 需要审批的工具：exec（shell）与 browser_*
+func toolRequiresApproval(name string) bool {
+	return name == "exec" || strings.HasPrefix(name, "browser_")
+}
+
+// approvalKey 审批的记忆键：exec 为 "exec:<命令>"，只放行完全相同的命令；其他工具为工具名
+func approvalKey(toolName string, args map[string]any) string {
+	if toolName == "exec" {
+		command, _ := args["command"].(string)
+		return "exec:" + strings.TrimSpace(command)
+	}
+	return toolName
+}
+
+// SetApprovalAllowlist 设置持久化的审批允许列表（exec.approval.resolve remember 写入的 approvalKey），在 approvals.allowlist 之外放行
+func (m *AgentManager) SetApprovalAllowlist(allowed func(key string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.approvalAllowlist = allowed
+}
+
+// ResolveToolApproval 对等待中的工具执行作出决定，返回该请求的工具名与记忆键（供调用方记住决定）
+func (m *AgentManager) ResolveToolApproval(approvalID string, approved bool) (toolName, key string, err error) {
+	p, err := m.approvals.resolve(approvalID, approved)
+	if err != nil {
+		return "", "", err
+	}
+	logger.Info("Tool approval resolved",
+		zap.String("approval_id", approvalID),
+		zap.String("tool_name", p.ToolName),
+		zap.Bool("approved", approved))
+	return p.ToolName, p.Key, nil
+}
+
+// toolAllowlisted 工具调用是否在 approvals.allowlist（工具名或记忆键）或持久化的允许列表（记忆键）中
+func (m *AgentManager) toolAllowlisted(cfg *config.Config, toolName string, args map[string]any) bool {
+	key := approvalKey(toolName, args)
+	if slices.Contains(cfg.Approvals.Allowlist, toolName) || slices.Contains(cfg.Approvals.Allowlist, key) {
+		return true
+	}
+	m.mu.RLock()
+	allowed := m.approvalAllowlist
+	m.mu.RUnlock()
+	return allowed != nil && allowed(key)
+}
+
+// ApprovalsRequired approvals.behavior 为 manual / prompt 时工具执行需要审批；只有网关的 Agent 运行会等待审批，
+// CLI 与 TUI 的运行在本地终端中直接执行工具
+func ApprovalsRequired(cfg *config.Config) bool {
+	return cfg != nil && (cfg.Approvals.Behavior == "manual" || cfg.Approvals.Behavior == "prompt")
+}
+
+// toolApprover 返回本次运行的审批函数：approvals.behavior 为 manual / prompt 时，需要审批且不在允许列表中的工具
+// 登记为等待审批并发出 approval 事件，阻塞到 exec.approval.resolve 作出决定、运行取消或超时；其他情况返回 nil
+func (m *AgentManager) toolApprover(events *bus.AgentEventEmitter, sessionKey, runID string) ToolApprovalFunc {
+	cfg := config.Get()
+	if !ApprovalsRequired(cfg) {
+		return nil
+	}
+	return func(ctx context.Context, toolCallID, toolName string, args map[string]any) (bool, string) {
+		if !toolRequiresApproval(toolName) || m.toolAllowlisted(cfg, toolName, args) {
+			return true, ""
+		}
+		now := time.Now()
+		p := &PendingApproval{
+			ID:          uuid.New().String(),
+			SessionKey:  sessionKey,
+			RunID:       runID,
+			ToolCallID:  toolCallID,
+			ToolName:    toolName,
+			Key:         approvalKey(toolName, args),
+			Args:        args,
+			CreatedAtMs: now.UnixMilli(),
+			ExpiresAtMs: now.Add(defaultApprovalTimeout).UnixMilli(),
+			decision:    make(chan bool, 1),
+		}
+		m.approvals.add(p)
+		defer m.approvals.remove(p.ID)
+
+		emit := func(data map[string]interface{}) {
+			if events != nil {
+				_ = events.Emit(context.WithoutCancel(ctx), bus.AgentStreamApproval, data)
+			}
+		}
+		emit(map[string]interface{}{
+			"phase":       "requested",
+			"approvalId":  p.ID,
+			"toolCallId":  toolCallID,
+			"name":        toolName,
+			"args":        args,
+			"createdAtMs": p.CreatedAtMs,
+			"expiresAtMs": p.ExpiresAtMs,
+		})
+		logger.Info("Tool execution awaiting approval",
+			zap.String("approval_id", p.ID),
+			zap.String("session_key", sessionKey),
+			zap.String("tool_name", toolName))
+
+		timer := time.NewTimer(defaultApprovalTimeout)
+		defer timer.Stop()
+		var approved bool
+		var reason string
+		select {
+		case approved = <-p.decision:
+			if !approved {
+				reason = "denied by user"
+			}
+		case <-ctx.Done():
+			reason = "run cancelled while awaiting approval"
+		case <-timer.C:
+			reason = "approval timed out"
+		}
+		emit(map[string]interface{}{
+			"phase":      "resolved",
+			"approvalId": p.ID,
+			"toolCallId": toolCallID,
+			"name":       toolName,
+			"approved":   approved,
+			"reason":     reason,
+		})
+		return approved, reason
+	}
+}
+
+// withToolApproval 在运行选项中设置工具审批函数
+func withToolApproval(opts *RunOptions, approve ToolApprovalFunc) *RunOptions {
+	if approve == nil {
+		return opts
+	}
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	opts.ApproveTool = approve
+	return opts
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// waitPendingApproval 等待审批函数登记请求，返回其 approvalId
+func waitPendingApproval(t *testing.T, m *AgentManager) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.approvals.mu.Lock()
+		for id := range m.approvals.pending {
+			m.approvals.mu.Unlock()
+			return id
+		}
+		m.approvals.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no pending approval registered")
+	return ""
+}
+
+func TestToolApproverBlocksUntilResolved(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Approvals.Behavior = "manual"
+	cfg.Approvals.Allowlist = []string{"browser_navigate"}
+	config.Set(cfg)
+
+	m := &AgentManager{}
+	approve := m.toolApprover(nil, "agent:main:main", "run-1")
+	if approve == nil {
+		t.Fatal("manual approvals should produce an approver")
+	}
+
+	// 无需审批或已在允许列表中的工具直接放行
+	for _, name := range []string{"read_file", "browser_navigate"} {
+		if ok, _ := approve(context.Background(), "tc", name, nil); !ok {
+			t.Fatalf("%s should not require approval", name)
+		}
+	}
+
+	type decision struct {
+		ok     bool
+		reason string
+	}
+	run := func() chan decision {
+		done := make(chan decision, 1)
+		go func() {
+			ok, reason := approve(context.Background(), "tc-1", "exec", map[string]any{"command": "ls"})
+			done <- decision{ok, reason}
+		}()
+		return done
+	}
+
+	done := run()
+	id := waitPendingApproval(t, m)
+	select {
+	case <-done:
+		t.Fatal("approver returned before a decision")
+	case <-time.After(20 * time.Millisecond):
+	}
+	name, key, err := m.ResolveToolApproval(id, false)
+	if err != nil || name != "exec" || key != "exec:ls" {
+		t.Fatalf("ResolveToolApproval = %q, %q, %v", name, key, err)
+	}
+	if d := <-done; d.ok || d.reason != "denied by user" {
+		t.Fatalf("denied decision = %+v", d)
+	}
+	if _, _, err := m.ResolveToolApproval(id, true); err == nil {
+		t.Fatal("resolving twice should fail")
+	}
+
+	done = run()
+	if _, _, err := m.ResolveToolApproval(waitPendingApproval(t, m), true); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-done; !d.ok {
+		t.Fatalf("approved decision = %+v", d)
+	}
+
+	// 注入的持久化允许列表按命令放行：记住 ls 不放行其他命令
+	m.SetApprovalAllowlist(func(key string) bool { return key == "exec:ls" })
+	if ok, _ := approve(context.Background(), "tc-2", "exec", map[string]any{"command": "ls"}); !ok {
+		t.Fatal("remembered command should not require approval")
+	}
+	done = make(chan decision, 1)
+	go func() {
+		ok, reason := approve(context.Background(), "tc-3", "exec", map[string]any{"command": "rm -rf /tmp/x"})
+		done <- decision{ok, reason}
+	}()
+	if _, _, err := m.ResolveToolApproval(waitPendingApproval(t, m), false); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-done; d.ok {
+		t.Fatal("a different command must still require approval")
+	}
+}
+
+func TestApprovalKey(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"exec", map[string]any{"command": " git status "}, "exec:git status"},
+		{"exec", nil, "exec:"},
+		{"browser_navigate", map[string]any{"url": "https://example.com"}, "browser_navigate"},
+	}
+	for _, tt := range tests {
+		if got := approvalKey(tt.name, tt.args); got != tt.want {
+			t.Errorf("approvalKey(%s, %v) = %q, want %q", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestToolApproverCancelledRun(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Approvals.Behavior = "prompt"
+	config.Set(cfg)
+
+	m := &AgentManager{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool, 1)
+	go func() {
+		ok, _ := m.toolApprover(nil, "agent:main:main", "run-1")(ctx, "tc", "exec", nil)
+		done <- ok
+	}()
+	waitPendingApproval(t, m)
+	cancel()
+	if <-done {
+		t.Fatal("cancelled run should not execute the tool")
+	}
+
+	cfg.Approvals.Behavior = "auto"
+	if m.toolApprover(nil, "agent:main:main", "run-1") != nil {
+		t.Fatal("auto approvals should not produce an approver")
+	}
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/i18n"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/process"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// defaultCommandPrefix 入站命令默认前缀（channels.command_prefix）
+const defaultCommandPrefix = "/"
+
+// resetOnNewMetadata /new 时清除的会话设置；label 等标识类字段保留
+var resetOnNewMetadata = []string{session.MetadataModelOverride, session.MetadataThinkingLevel, session.MetadataVerboseLevel, session.MetadataReasoningLevel, session.MetadataDebugPrompts}
+
+// inboundCommandHelp 各命令的参数提示与帮助文案，顺序即 /help 的展示顺序
+var inboundCommandHelp = []struct {
+	name string
+	args string
+	help i18n.Key
+}{
+	{"new", "", i18n.CommandHelpNew},
+	{"model", " [name|default]", i18n.CommandHelpModel},
+	{"clear", "", i18n.CommandHelpClear},
+	{"help", "", i18n.CommandHelpHelp},
+}
+
+// inboundCommandSettings 返回命令前缀与启用的命令；关闭预处理时 enabled 为空
+func inboundCommandSettings(cfg *config.Config) (prefix string, enabled []string) {
+	prefix = defaultCommandPrefix
+	if cfg == nil {
+		return prefix, config.InboundCommandNames
+	}
+	if cfg.Channels.DisableCommands {
+		return prefix, nil
+	}
+	if cfg.Channels.CommandPrefix != "" {
+		prefix = cfg.Channels.CommandPrefix
+	}
+	if len(cfg.Channels.Commands) == 0 {
+		return prefix, config.InboundCommandNames
+	}
+	for _, name := range cfg.Channels.Commands {
+		enabled = append(enabled, strings.ToLower(strings.TrimSpace(name)))
+	}
+	return prefix, enabled
+}
+
+// parseInboundCommand 解析入站命令，返回小写命令名与参数；不是已启用的命令时 ok 为 false。
+// 兼容 Telegram 群组中的 /model@bot_name 写法
+func parseInboundCommand(content, prefix string, enabled []string) (name, arg string, ok bool) {
+	content = strings.TrimSpace(content)
+	if len(enabled) == 0 || !strings.HasPrefix(content, prefix) {
+		return "", "", false
+	}
+	fields := strings.Fields(strings.TrimPrefix(content, prefix))
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	name = strings.ToLower(fields[0])
+	if i := strings.Index(name, "@"); i > 0 {
+		name = name[:i]
+	}
+	if !slices.Contains(enabled, name) {
+		return "", "", false
+	}
+	return name, strings.Join(fields[1:], " "), true
+}
+
+// handleInboundCommand 在消息到达 LLM 前处理 /new、/model、/clear、/help 等命令并直接回复；
+// 返回 true 表示消息已作为命令处理，不再发起 Run。修改会话的命令排入该会话的 lane，
+// 在进行中的 Run 保存结果之后执行，避免 /new、/clear 被 Run 的 updateSession 覆盖
+func (m *AgentManager) handleInboundCommand(ctx context.Context, msg *bus.InboundMessage, agent *Agent, sess *session.Session) bool {
+	prefix, enabled := inboundCommandSettings(config.Get())
+	name, arg, ok := parseInboundCommand(msg.Content, prefix, enabled)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case name == "help":
+		var b strings.Builder
+		b.WriteString(i18n.T(i18n.CommandHelp))
+		for _, c := range inboundCommandHelp {
+			if slices.Contains(enabled, c.name) {
+				fmt.Fprintf(&b, "\n%s%s%s - %s", prefix, c.name, c.args, i18n.T(c.help))
+			}
+		}
+		m.replyInboundCommand(ctx, msg, name, sess, b.String())
+		return true
+	case name == "model" && arg == "":
+		m.replyInboundCommand(ctx, msg, name, sess, i18n.T(i18n.CommandModelCurrent, currentSessionModel(agent, sess), prefix))
+		return true
+	}
+
+	lane := fmt.Sprintf("session:%s", sess.Key)
+	go func() {
+		_, err := process.EnqueueCommandInLane(ctx, lane, func(context.Context) (interface{}, error) {
+			m.applyInboundCommand(ctx, msg, name, arg, sess)
+			return nil, nil
+		}, nil)
+		if err != nil {
+			logger.Error("Failed to run inbound command in lane",
+				zap.String("command", name),
+				zap.String("lane", lane),
+				zap.Error(err))
+		}
+	}()
+	return true
+}
+
+// applyInboundCommand 在会话 lane 内执行修改会话的命令（/new、/clear、/model <name|default>），保存后回复
+func (m *AgentManager) applyInboundCommand(ctx context.Context, msg *bus.InboundMessage, name, arg string, sess *session.Session) {
+	var reply string
+	switch name {
+	case "new":
+		sess.Clear()
+		updates := make(map[string]interface{}, len(resetOnNewMetadata))
+		for _, k := range resetOnNewMetadata {
+			updates[k] = nil
+		}
+		sess.PatchMetadata(updates)
+		reply = i18n.T(i18n.CommandNew)
+	case "clear":
+		sess.Clear()
+		reply = i18n.T(i18n.CommandClear)
+	case "model":
+		if strings.EqualFold(arg, "default") {
+			sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: nil})
+			reply = i18n.T(i18n.CommandModelReset)
+		} else {
+			sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: arg})
+			reply = i18n.T(i18n.CommandModelSet, arg)
+		}
+	}
+
+	if err := m.sessionMgr.Save(sess); err != nil {
+		logger.Error("Failed to save session after inbound command",
+			zap.String("command", name),
+			zap.String("session_key", sess.Key),
+			zap.Error(err))
+		m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, inboundLocale(msg), err)
+		return
+	}
+	m.replyInboundCommand(ctx, msg, name, sess, reply)
+}
+
+// replyInboundCommand 记录并回复已处理的命令
+func (m *AgentManager) replyInboundCommand(ctx context.Context, msg *bus.InboundMessage, name string, sess *session.Session, reply string) {
+	logger.Info("Inbound command handled",
+		zap.String("command", name),
+		zap.String("channel", msg.Channel),
+		zap.String("session_key", sess.Key))
+	m.publishRunFinalToBus(ctx, msg.Channel, msg.ChatID, msg.ID, reply)
+}
+
+// currentSessionModel 返回会话当前使用的模型：会话覆盖优先，其次为 Agent 模型
+func currentSessionModel(agent *Agent, sess *session.Session) string {
+	if v, ok := sess.GetMetadata(session.MetadataModelOverride).(string); ok && v != "" {
+		return v
+	}
+	if agent != nil {
+		if model := agent.GetState().Model; model != "" {
+			return model
+		}
+	}
+	return "(default)"
+}
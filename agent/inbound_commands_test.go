@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/session"
+)
+
+func newCommandTestManager(t *testing.T) (*AgentManager, *session.Session, *bus.OutboundSubscription) {
+	t.Helper()
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := mgr.GetOrCreate("agent:main:main")
+	sess.AddMessage(session.Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+	sess.AddMessage(session.Message{Role: "assistant", Content: "hi", Timestamp: time.Now()})
+	sess.PatchMetadata(map[string]interface{}{"label": "work", "thinkingLevel": "high"})
+
+	m := &AgentManager{bus: bus.NewMessageBus(10), sessionMgr: mgr}
+	t.Cleanup(func() { m.bus.Close() })
+	sub := m.bus.SubscribeOutbound()
+	return m, sess, sub
+}
+
+func runCommand(t *testing.T, m *AgentManager, sess *session.Session, sub *bus.OutboundSubscription, content string) string {
+	t.Helper()
+	msg := &bus.InboundMessage{ID: "run-cmd", Channel: "telegram", ChatID: "42", Content: content}
+	if !m.handleInboundCommand(context.Background(), msg, nil, sess) {
+		t.Fatalf("%q was not handled as a command", content)
+	}
+	select {
+	case out := <-sub.Channel:
+		return out.Content
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no reply for %q", content)
+	}
+	return ""
+}
+
+func TestInboundCommands(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: "en"}})
+
+	t.Run("new", func(t *testing.T) {
+		m, sess, sub := newCommandTestManager(t)
+		sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: "gpt-4o"})
+		if reply := runCommand(t, m, sess, sub, "/new"); !strings.Contains(reply, "new session") {
+			t.Errorf("reply = %q", reply)
+		}
+		if n := len(sess.GetHistory(0)); n != 0 {
+			t.Errorf("history after /new = %d messages", n)
+		}
+		if sess.GetMetadata(session.MetadataModelOverride) != nil || sess.GetMetadata("thinkingLevel") != nil {
+			t.Errorf("session settings not reset: %v", sess.Metadata)
+		}
+		if sess.GetMetadata("label") != "work" {
+			t.Errorf("label should be kept: %v", sess.Metadata)
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		m, sess, sub := newCommandTestManager(t)
+		runCommand(t, m, sess, sub, "/clear")
+		if n := len(sess.GetHistory(0)); n != 0 {
+			t.Errorf("history after /clear = %d messages", n)
+		}
+		if sess.GetMetadata("thinkingLevel") != "high" {
+			t.Errorf("/clear should keep session settings: %v", sess.Metadata)
+		}
+	})
+
+	t.Run("model", func(t *testing.T) {
+		m, sess, sub := newCommandTestManager(t)
+		runCommand(t, m, sess, sub, "/model@goclaw_bot openai/gpt-4o")
+		if got := sess.GetMetadata(session.MetadataModelOverride); got != "openai/gpt-4o" {
+			t.Fatalf("modelOverride = %v", got)
+		}
+		if reply := runCommand(t, m, sess, sub, "/model"); !strings.Contains(reply, "openai/gpt-4o") {
+			t.Errorf("/model reply = %q, want current override", reply)
+		}
+		runCommand(t, m, sess, sub, "/model default")
+		if got := sess.GetMetadata(session.MetadataModelOverride); got != nil {
+			t.Errorf("modelOverride after reset = %v", got)
+		}
+		if n := len(sess.GetHistory(0)); n != 2 {
+			t.Errorf("/model should not touch history, got %d messages", n)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		m, sess, sub := newCommandTestManager(t)
+		reply := runCommand(t, m, sess, sub, "/help")
+		for _, want := range []string{"/new", "/model", "/clear", "/help"} {
+			if !strings.Contains(reply, want) {
+				t.Errorf("help missing %s: %q", want, reply)
+			}
+		}
+	})
+}
+
+func TestInboundCommandsConfigurable(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+
+	m, sess, _ := newCommandTestManager(t)
+	msg := func(content string) *bus.InboundMessage {
+		return &bus.InboundMessage{ID: "run-cmd", Channel: "telegram", ChatID: "42", Content: content}
+	}
+
+	config.Set(&config.Config{Channels: config.ChannelsConfig{CommandPrefix: "!", Commands: []string{"model"}}})
+	if m.handleInboundCommand(context.Background(), msg("/new"), nil, sess) {
+		t.Error("/new handled although prefix is !")
+	}
+	if m.handleInboundCommand(context.Background(), msg("!clear"), nil, sess) {
+		t.Error("!clear handled although only model is enabled")
+	}
+	if !m.handleInboundCommand(context.Background(), msg("!model gpt-4o"), nil, sess) {
+		t.Error("!model not handled")
+	}
+
+	config.Set(&config.Config{Channels: config.ChannelsConfig{DisableCommands: true}})
+	if m.handleInboundCommand(context.Background(), msg("/new"), nil, sess) {
+		t.Error("/new handled although commands are disabled")
+	}
+	if n := len(sess.GetHistory(0)); n != 2 {
+		t.Errorf("history changed by ignored commands: %d messages", n)
+	}
+}
+
+func TestNewCommandDuringRunAppliesAfterRun(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	dir := t.TempDir()
+	sessMgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus(64)
+	defer msgBus.Close()
+	provider := &blockingStreamProvider{chunks: []string{"Once upon a time"}, release: make(chan struct{})}
+	m := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        msgBus,
+		Provider:   provider,
+		SessionMgr: sessMgr,
+		Tools:      NewToolRegistry(),
+		DataDir:    t.TempDir(),
+	})
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Workspace: config.WorkspaceConfig{Path: workspace},
+		Agents:    config.AgentsConfig{List: []config.AgentConfig{{ID: "main", Default: true}}},
+	}
+	if err := m.SetupFromConfig(cfg, NewContextBuilder(NewMemoryStore(workspace), workspace)); err != nil {
+		t.Fatal(err)
+	}
+	sub := msgBus.SubscribeOutbound()
+	defer sub.Unsubscribe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	key := session.BuildAgentSessionKey("main", "telegram", "", "42", session.DefaultMainKey, false)
+	if err := m.RouteInbound(ctx, &bus.InboundMessage{ID: "run-1", Channel: "telegram", ChatID: "42", Content: "tell me a story"}); err != nil {
+		t.Fatal(err)
+	}
+	// 等到运行已登记，再在运行中发送 /new
+	for len(m.SessionRunIDs(key)) == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("run did not start")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err := m.RouteInbound(ctx, &bus.InboundMessage{ID: "cmd-1", Channel: "telegram", ChatID: "42", Content: "/new"}); err != nil {
+		t.Fatal(err)
+	}
+	close(provider.release)
+
+	for {
+		select {
+		case out := <-sub.Channel:
+			if out.ID != "cmd-1" {
+				continue
+			}
+			// /new 在运行保存结果之后执行，会话保持清空
+			if history := mustReload(t, dir, key).GetHistory(0); len(history) != 0 {
+				t.Fatalf("history after /new = %+v, want empty", history)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("no reply to /new")
+		}
+	}
+}
@@ -2,7 +2,9 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"sync"
 	"time"
@@ -10,7 +12,10 @@ import (
 	"github.com/smallnest/goclaw/agent/tools"
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/diskspace"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/metrics"
 	"github.com/smallnest/goclaw/process"
 	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
@@ -34,7 +39,34 @@ type AgentManager struct {
 	// 分身支持
 	subagentRegistry  *SubagentRegistry
 	subagentAnnouncer *SubagentAnnouncer
+	announceQueue     *announceQueue // 按父会话串行/合并投递分身宣告
 	dataDir           string
+	// 最终回复后处理链（agents.defaults.post_processors）及其构建所用的配置，配置重载后重新构建
+	postProcess    *PostProcessPipeline
+	postProcessCfg *config.Config
+	// 正在执行的 Run（sessionKey -> orchestrator），供 SteerSession 注入消息
+	activeRunsMu sync.Mutex
+	activeRuns   map[string]*activeRun
+	// 执行中 Run 是否已发送终态消息（runId -> sent），保证每个 Run 只有一个 final/error
+	runRepliesMu sync.Mutex
+	runReplies   map[string]bool
+	// 按 Agent 限制同时执行的 Run 数（agents.list[].max_concurrent_runs）
+	runLimiter agentRunLimiter
+	// 通道静默时段内排队的入站消息（channels.<name>.quiet_hours.mode = queue）
+	quietHours quietHoursQueue
+	// 入站 URL 媒体下载（channels.fetch_media）
+	mediaFetcher mediaFetcher
+	// 已提交（排队或执行中）的 Run 的取消函数（runId -> run），供 chat.abort 中止
+	pendingRunsMu sync.Mutex
+	pendingRuns   map[string]*pendingRun
+	// 各会话当前运行的 sessions_send 消息链，用于检测 agent 互发循环
+	sendChains sendChainTracker
+	// 等待 exec.approval.resolve 决定的工具执行（approvalId -> 请求）
+	approvals approvalRegistry
+	// 持久化的审批允许列表（exec-approvals 文件），由网关注入
+	approvalAllowlist func(toolName string) bool
+	// 模型提供商可用性（启动探测，不可用时新运行立即报错）
+	health providerHealth
 }
 
 // BindingEntry Agent 绑定条目
@@ -76,6 +108,7 @@ func NewAgentManager(cfg *NewAgentManagerConfig) *AgentManager {
 		dataDir:           cfg.DataDir,
 		contextBuilder:    cfg.ContextBuilder,
 		skillsLoader:      cfg.SkillsLoader,
+		health:            providerHealth{recheck: make(chan struct{}, 1)},
 	}
 }
 
@@ -147,6 +180,8 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 
 	m.cfg = cfg
 	m.contextBuilder = contextBuilder
+	m.postProcess = newConfiguredPostProcessPipeline(cfg.Agents.Defaults)
+	m.postProcessCfg = cfg
 
 	logger.Info("Setting up agents from config")
 
@@ -162,17 +197,26 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 	process.SetCommandLaneConcurrency(string(process.LaneSubagent), subagentConcurrent)
 
 	// 2. 创建 Agent 实例（此时 state.Tools 会包含上面已注册的 sessions_spawn、sessions_list 等）
+	// 重载时构建新的 agents/bindings 映射，最后整体替换；进行中的运行持有各自的 *Agent 引用，不受替换影响
+	agents := make(map[string]*Agent)
+	bindings := make(map[string]*BindingEntry)
+	var defaultAgent *Agent
 	for _, agentCfg := range cfg.Agents.List {
-		if err := m.createAgent(agentCfg, contextBuilder, cfg); err != nil {
+		agent, err := m.createAgent(agentCfg, contextBuilder, cfg)
+		if err != nil {
 			logger.Error("Failed to create agent",
 				zap.String("agent_id", agentCfg.ID),
 				zap.Error(err))
 			continue
 		}
+		agents[agentCfg.ID] = agent
+		if agentCfg.Default {
+			defaultAgent = agent
+		}
 	}
 
 	// 3. 如果没有配置 Agent，创建默认 Agent
-	if len(m.agents) == 0 {
+	if len(agents) == 0 {
 		logger.Info("No agents configured, creating default agent")
 		defaultAgentCfg := config.AgentConfig{
 			ID:        "main", // 使用 "main" 作为默认 Agent ID，与 gateway 返回的 mainSessionKey 一致
@@ -181,14 +225,17 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 			Model:     cfg.Agents.Defaults.Model,
 			Workspace: cfg.Workspace.Path,
 		}
-		if err := m.createAgent(defaultAgentCfg, contextBuilder, cfg); err != nil {
+		agent, err := m.createAgent(defaultAgentCfg, contextBuilder, cfg)
+		if err != nil {
 			return fmt.Errorf("failed to create default agent: %w", err)
 		}
+		agents[defaultAgentCfg.ID] = agent
+		defaultAgent = agent
 	}
 
 	// 4. 设置绑定
 	for _, binding := range cfg.Bindings {
-		if err := m.setupBinding(binding); err != nil {
+		if err := setupBinding(binding, agents, bindings); err != nil {
 			logger.Error("Failed to setup binding",
 				zap.String("agent_id", binding.AgentID),
 				zap.String("channel", binding.Match.Channel),
@@ -197,6 +244,10 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 		}
 	}
 
+	m.agents = agents
+	m.bindings = bindings
+	m.defaultAgent = defaultAgent
+
 	logger.Info("Agent manager setup complete",
 		zap.Int("agents", len(m.agents)),
 		zap.Int("bindings", len(m.bindings)))
@@ -206,6 +257,11 @@ func (m *AgentManager) SetupFromConfig(cfg *config.Config, contextBuilder *Conte
 
 // setupSubagentSupport 设置分身支持
 func (m *AgentManager) setupSubagentSupport(cfg *config.Config, contextBuilder *ContextBuilder) {
+	// 宣告队列跨配置重载保留，避免重载时丢失排队中的宣告
+	if m.announceQueue == nil {
+		m.announceQueue = newAnnounceQueue(m.sendToSession, nil)
+	}
+
 	// 加载分身注册表
 	if err := m.subagentRegistry.LoadFromDisk(); err != nil {
 		logger.Warn("Failed to load subagent registry", zap.Error(err))
@@ -221,8 +277,8 @@ func (m *AgentManager) setupSubagentSupport(cfg *config.Config, contextBuilder *
 
 	// 更新宣告器回调
 	m.subagentAnnouncer = NewSubagentAnnouncer(func(sessionKey, message string) error {
-		// 发送宣告消息到指定会话
-		return m.sendToSession(sessionKey, message)
+		// 经宣告队列发送到指定会话：同一父会话串行投递，批量窗口内的宣告合并
+		return m.announceQueue.Enqueue(sessionKey, message)
 	})
 
 	// 创建分身注册表适配器
@@ -246,6 +302,8 @@ func (m *AgentManager) setupSubagentSupport(cfg *config.Config, contextBuilder *
 		agentID, _, _ := ParseAgentSessionKey(sessionKey)
 		if agentID == "" {
 			// 尝试从绑定中查找
+			m.mu.RLock()
+			defer m.mu.RUnlock()
 			for _, entry := range m.bindings {
 				if entry.Agent != nil {
 					return entry.AgentID
@@ -266,7 +324,7 @@ func (m *AgentManager) setupSubagentSupport(cfg *config.Config, contextBuilder *
 	logger.Info("Subagent support configured")
 }
 
-// setupSessionTools 注册会话类工具（sessions_list, sessions_history, sessions_send, session_status）
+// setupSessionTools 注册会话类工具（sessions_list, sessions_history, sessions_send, session_status, agent_activity）
 func (m *AgentManager) setupSessionTools() {
 	if err := m.tools.RegisterExisting(tools.NewSessionsListTool(m.sessionMgr)); err != nil {
 		logger.Warn("Failed to register sessions_list tool", zap.Error(err))
@@ -274,8 +332,9 @@ func (m *AgentManager) setupSessionTools() {
 	if err := m.tools.RegisterExisting(tools.NewSessionsHistoryTool(m.sessionMgr)); err != nil {
 		logger.Warn("Failed to register sessions_history tool", zap.Error(err))
 	}
-	sendTool := tools.NewSessionsSendTool(m.sessionMgr, func(ctx context.Context, sessionKey, content string) error {
-		return m.sendToSession(sessionKey, content)
+	sendTool := tools.NewSessionsSendTool(m.sessionMgr, func(ctx context.Context, sessionKey, content string) (string, error) {
+		result, err := m.sendFromSession(ctx, sessionKey, content)
+		return string(result), err
 	})
 	if err := m.tools.RegisterExisting(sendTool); err != nil {
 		logger.Warn("Failed to register sessions_send tool", zap.Error(err))
@@ -284,6 +343,9 @@ func (m *AgentManager) setupSessionTools() {
 	if err := m.tools.RegisterExisting(tools.NewSessionStatusTool(m.sessionMgr, nil)); err != nil {
 		logger.Warn("Failed to register session_status tool", zap.Error(err))
 	}
+	if err := m.tools.RegisterExisting(tools.NewAgentActivityTool(m.summarizeAgentActivity)); err != nil {
+		logger.Warn("Failed to register agent_activity tool", zap.Error(err))
+	}
 }
 
 // subagentRegistryAdapter 分身注册表适配器
@@ -362,44 +424,25 @@ func (m *AgentManager) handleSubagentSpawn(result *tools.SubagentSpawnResult) er
 	return nil
 }
 
-// sendToSession 发送消息到指定会话
+// sendToSession 发送消息到指定会话：有运行中的 Run 时中断并在其中处理，否则作为该会话的下一次运行
 func (m *AgentManager) sendToSession(sessionKey, message string) error {
-	// 解析会话密钥获取 agent ID
-	agentID, _, _ := ParseAgentSessionKey(sessionKey)
-
-	// 获取对应的 Agent
-	agent, ok := m.GetAgent(agentID)
-	if !ok {
-		// 尝试使用默认 Agent
-		agent = m.defaultAgent
-	}
+	_, err := m.SteerSession(context.Background(), sessionKey, message)
+	return err
+}
 
-	if agent == nil {
-		return fmt.Errorf("no agent found for session: %s", sessionKey)
+// agentDisplayName Agent 显示名称：name，其次 identity.name，最后为 ID
+func agentDisplayName(cfg config.AgentConfig) string {
+	if name := strings.TrimSpace(cfg.Name); name != "" {
+		return name
 	}
-
-	// 构建 AgentMessage
-	agentMsg := AgentMessage{
-		Role: RoleUser,
-		Content: []ContentBlock{
-			TextContent{Text: message},
-		},
+	if cfg.Identity != nil && strings.TrimSpace(cfg.Identity.Name) != "" {
+		return strings.TrimSpace(cfg.Identity.Name)
 	}
-
-	// 注入为 steering 消息（中断当前运行）
-	// 如果 agent 正在运行，这会立即中断并处理新消息
-	agent.state.Steer(agentMsg)
-
-	logger.Info("Message sent to session",
-		zap.String("session_key", sessionKey),
-		zap.String("agent_id", agentID),
-		zap.Int("message_length", len(message)))
-
-	return nil
+	return cfg.ID
 }
 
 // createAgent 创建 Agent 实例
-func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *ContextBuilder, globalCfg *config.Config) error {
+func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *ContextBuilder, globalCfg *config.Config) (*Agent, error) {
 	// 获取 workspace 路径
 	workspace := cfg.Workspace
 	if workspace == "" {
@@ -435,6 +478,7 @@ func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *Conte
 		Tools:                       m.tools,
 		Context:                     contextBuilder,
 		Model:                       model,
+		FallbackModel:               globalCfg.Agents.Defaults.FallbackModel,
 		Workspace:                   workspace,
 		MaxIteration:                maxIterations,
 		Temperature:                 temperature,
@@ -442,11 +486,18 @@ func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *Conte
 		ContextWindowTokens:         ctxTokens,
 		ReserveTokens:                reserveTokens,
 		MaxHistoryTurns:             maxHistoryTurns,
+		SummarizerContextTokens:     globalCfg.Agents.Defaults.SummarizerContextTokens,
+		ToolResultTruncation:        toolResultTruncationFromConfig(globalCfg.Agents.Defaults.ToolResultTruncation),
 		ModelRequestIntervalSeconds: globalCfg.Agents.Defaults.ModelRequestIntervalSeconds,
+		ToolErrorPolicy:             globalCfg.Agents.Defaults.ToolErrorPolicy,
+		ToolErrorMaxConsecutive:     globalCfg.Agents.Defaults.ToolErrorMaxConsecutive,
+		ToolTimeoutSeconds:          globalCfg.Tools.TimeoutSeconds,
+		AgentName:                   agentDisplayName(cfg),
+		UserTimezone:                globalCfg.Agents.Defaults.UserTimezone,
 		SkillsLoader:                m.skillsLoader,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create agent %s: %w", cfg.ID, err)
+		return nil, fmt.Errorf("failed to create agent %s: %w", cfg.ID, err)
 	}
 
 	// 设置系统提示词
@@ -454,14 +505,6 @@ func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *Conte
 		agent.SetSystemPrompt(cfg.SystemPrompt)
 	}
 
-	// 存储到管理器
-	m.agents[cfg.ID] = agent
-
-	// 如果是默认 Agent，设置默认
-	if cfg.Default {
-		m.defaultAgent = agent
-	}
-
 	logger.Info("Agent created",
 		zap.String("agent_id", cfg.ID),
 		zap.String("name", cfg.Name),
@@ -469,13 +512,13 @@ func (m *AgentManager) createAgent(cfg config.AgentConfig, contextBuilder *Conte
 		zap.String("model", model),
 		zap.Bool("is_default", cfg.Default))
 
-	return nil
+	return agent, nil
 }
 
-// setupBinding 设置 Agent 绑定
-func (m *AgentManager) setupBinding(binding config.BindingConfig) error {
+// setupBinding 在 bindings 中设置 Agent 绑定
+func setupBinding(binding config.BindingConfig, agents map[string]*Agent, bindings map[string]*BindingEntry) error {
 	// 获取 Agent
-	agent, ok := m.agents[binding.AgentID]
+	agent, ok := agents[binding.AgentID]
 	if !ok {
 		return fmt.Errorf("agent not found: %s", binding.AgentID)
 	}
@@ -484,7 +527,7 @@ func (m *AgentManager) setupBinding(binding config.BindingConfig) error {
 	bindingKey := fmt.Sprintf("%s:%s", binding.Match.Channel, binding.Match.AccountID)
 
 	// 存储绑定
-	m.bindings[bindingKey] = &BindingEntry{
+	bindings[bindingKey] = &BindingEntry{
 		AgentID:   binding.AgentID,
 		Channel:   binding.Match.Channel,
 		AccountID: binding.Match.AccountID,
@@ -498,53 +541,64 @@ func (m *AgentManager) setupBinding(binding config.BindingConfig) error {
 	return nil
 }
 
-// RouteInbound 路由入站消息到对应的 Agent
+// RouteInbound 路由入站消息到对应的 Agent；通道处于静默时段（quiet_hours）时先行拦截
+// 只在读锁内解析 Agent 引用，处理消息时不持锁，避免与 SetupFromConfig 重载互相阻塞
 func (m *AgentManager) RouteInbound(ctx context.Context, msg *bus.InboundMessage) error {
+	if m.deferForQuietHours(ctx, msg) {
+		return nil
+	}
+	agent, err := m.resolveAgent(msg)
+	if err != nil {
+		return err
+	}
+	return m.handleInboundMessage(ctx, msg, agent)
+}
+
+// resolveAgent 在读锁内按 internal sessionKey / 绑定 / 默认 Agent 选择处理消息的 Agent
+func (m *AgentManager) resolveAgent(msg *bus.InboundMessage) (*Agent, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var agent *Agent
-
-	// 与 OpenClaw 一致：internal channel 为子 agent 触发，sessionKey=ChatID，agent 从 sessionKey 解析
-	if msg.Channel == "internal" {
-		agentID, _, ok := ParseAgentSessionKey(msg.ChatID)
+	// 与 OpenClaw 一致：internal channel 为子 agent 或 steering 排队触发，sessionKey=ChatID，agent 从 sessionKey 解析；
+	// 带来源渠道的排队运行同样按其会话键解析
+	if key := queuedRunSessionKey(msg); key != "" || msg.Channel == "internal" {
+		if key == "" {
+			key = msg.ChatID
+		}
+		agentID, _, ok := session.ParseAgentSessionKey(key)
 		if !ok {
-			return fmt.Errorf("invalid internal session key: %s", msg.ChatID)
+			return nil, fmt.Errorf("invalid internal session key: %s", key)
 		}
-		if a, ok := m.GetAgent(agentID); ok {
-			agent = a
-		} else {
+		agent, ok := m.agents[agentID]
+		if !ok {
 			agent = m.defaultAgent
 		}
 		if agent == nil {
-			return fmt.Errorf("no agent for internal run: %s", agentID)
+			return nil, fmt.Errorf("no agent for internal run: %s", agentID)
 		}
-		logger.Debug("Internal (subagent) message routed by session key",
-			zap.String("chat_id", msg.ChatID),
+		logger.Debug("Internal message routed by session key",
+			zap.String("session_key", key),
 			zap.String("agent_id", agentID))
-		return m.handleInboundMessage(ctx, msg, agent)
+		return agent, nil
 	}
 
 	// 构建绑定键
 	bindingKey := fmt.Sprintf("%s:%s", msg.Channel, msg.AccountID)
 
 	// 查找绑定的 Agent
-	entry, ok := m.bindings[bindingKey]
-	if ok {
-		agent = entry.Agent
+	if entry, ok := m.bindings[bindingKey]; ok {
 		logger.Debug("Message routed by binding",
 			zap.String("binding_key", bindingKey),
 			zap.String("agent_id", entry.AgentID))
-	} else if m.defaultAgent != nil {
-		agent = m.defaultAgent
+		return entry.Agent, nil
+	}
+	if m.defaultAgent != nil {
 		logger.Debug("Message routed to default agent",
 			zap.String("channel", msg.Channel),
 			zap.String("account_id", msg.AccountID))
-	} else {
-		return fmt.Errorf("no agent found for message: %s", bindingKey)
+		return m.defaultAgent, nil
 	}
-
-	return m.handleInboundMessage(ctx, msg, agent)
+	return nil, fmt.Errorf("no agent found for message: %s", bindingKey)
 }
 
 // handleInboundMessage 处理入站消息
@@ -555,6 +609,13 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 		zap.String("account_id", msg.AccountID),
 		zap.String("chat_id", msg.ChatID))
 
+	// 数据目录剩余空间严重不足（且配置了 gateway.pause_runs_on_low_disk）时拒绝新运行，避免会话写入失败
+	if err := diskspace.AllowRun(); err != nil {
+		logger.Error("Rejecting run: disk space critically low", zap.String("channel", msg.Channel))
+		m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, inboundLocale(msg), err)
+		return nil
+	}
+
 	// 获取 agent ID（从 agent 实例或使用默认值）
 	agentID := session.DefaultAgentID
 	if agent != nil && agent.GetID() != "" {
@@ -563,13 +624,19 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 
 	// 获取配置中的 mainKey
 	mainKey := session.DefaultMainKey
+	m.mu.RLock()
 	if m.cfg != nil && m.cfg.Session.MainKey != "" {
 		mainKey = m.cfg.Session.MainKey
 	}
+	m.mu.RUnlock()
 
 	// 生成会话键（与 OpenClaw 对齐：所有 session key 都以 agent:<agentId>: 开头）
 	var sessionKey string
-	if msg.Channel == "internal" {
+	queuedKey := queuedRunSessionKey(msg)
+	if queuedKey != "" {
+		// 排队运行（steering / sessions_send）：会话由元数据指定，Channel / ChatID 只用于投递回复
+		sessionKey = queuedKey
+	} else if msg.Channel == "internal" {
 		// 子 agent：ChatID 即为 childSessionKey（agent:<id>:subagent:<uuid>）
 		sessionKey = msg.ChatID
 		logger.Info("Using child session for subagent", zap.String("session_key", sessionKey))
@@ -603,6 +670,27 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 		logger.Error("Failed to get session", zap.Error(err))
 		return err
 	}
+	// 记录会话来源渠道/账号，供 sessions.list 按渠道分组与过滤
+	if sess.RecordOrigin(msg.Channel, msg.AccountID) {
+		if err := m.sessionMgr.Save(sess); err != nil {
+			logger.Warn("Failed to save session origin", zap.String("session_key", sessionKey), zap.Error(err))
+		}
+	}
+
+	// 通道用户的 /new、/model 等命令在到达 LLM 前拦截（Web 控制台有独立的会话控件，internal 为系统触发）
+	if queuedKey == "" && msg.Channel != "internal" && msg.Channel != "websocket" && m.handleInboundCommand(ctx, msg, agent, sess) {
+		return nil
+	}
+
+	// 本次运行所用模型的提供商不可用（探测失败且尚未恢复）时立即返回错误，而不是等到运行超时；/model 等命令不受影响
+	if err := m.providerUnavailable(m.runModel(agent, sessionKey, sess)); err != nil {
+		logger.Error("Rejecting run: model provider unavailable",
+			zap.String("channel", msg.Channel),
+			zap.String("session_key", sessionKey),
+			zap.Error(err))
+		m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, inboundLocale(msg), err)
+		return nil
+	}
 
 	// 转换为 Agent 消息
 	agentMsg := AgentMessage{
@@ -611,14 +699,10 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 		Timestamp: msg.Timestamp.UnixMilli(),
 	}
 
-	// 添加媒体内容
+	// 添加媒体内容（仅有 URL 的图片在会话 lane 内再下载，见 fetchInboundMedia）
 	for _, media := range msg.Media {
 		if media.Type == "image" {
-			agentMsg.Content = append(agentMsg.Content, ImageContent{
-				URL:      media.URL,
-				Data:     media.Base64,
-				MimeType: media.MimeType,
-			})
+			agentMsg.Content = append(agentMsg.Content, ImageContent{URL: media.URL, Data: media.Base64, MimeType: media.MimeType})
 		}
 	}
 
@@ -626,8 +710,10 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 	orchestrator := agent.CreateOrchestratorForRun(sessionKey)
 
 	// 加载历史消息并构造发给 orchestrator 的列表（与 OpenClaw 一致）
-	// internal（子 agent）：先写入当前用户消息再读历史，与主 agent 同一套 session 流程
-	if msg.Channel == "internal" {
+	// internal（子 agent）：先写入当前用户消息再读历史，与主 agent 同一套 session 流程；
+	// websocket 的用户消息已由 chat.send 写入。排队运行的用户消息与其他渠道一样随运行结果在 lane 内由 updateSession 写入
+	userTurnSaved := queuedKey == "" && (msg.Channel == "websocket" || msg.Channel == "internal")
+	if msg.Channel == "internal" && queuedKey == "" {
 		sessMsg := session.Message{
 			Role:      "user",
 			Content:   msg.Content,
@@ -639,22 +725,35 @@ func (m *AgentManager) handleInboundMessage(ctx context.Context, msg *bus.Inboun
 			return err
 		}
 	}
-	history := sess.GetHistory(-1) // -1 表示加载所有历史消息
+	history := sess.GetHydratedHistory(-1) // -1 表示加载所有历史消息；外置媒体回填 base64 后随历史发给模型
 	historyAgentMsgs := sessionMessagesToAgentMessages(history)
 	var allMessages []AgentMessage
-	if msg.Channel == "websocket" || msg.Channel == "internal" {
+	if userTurnSaved {
 		allMessages = historyAgentMsgs
 	} else {
 		allMessages = append(historyAgentMsgs, agentMsg)
 	}
 
+	// 按上下文窗口预先丢弃最早的轮次，避免长会话首次调用因溢出白白失败一次；溢出重试与摘要压缩仍作为兜底。
+	// 丢弃的消息仍在会话中，historyLen 同步扣减，保证只保存本次新产生的消息
+	historyLen := len(history)
+	if fitted, dropped := agent.FitHistoryToContext(allMessages); dropped > 0 {
+		logger.Info("Trimmed history to fit context window",
+			zap.String("session_key", sessionKey),
+			zap.Int("dropped_turns", dropped),
+			zap.Int("messages_before", len(allMessages)),
+			zap.Int("messages_after", len(fitted)))
+		historyLen -= len(allMessages) - len(fitted)
+		allMessages = fitted
+	}
+
 	logger.Info("About to call orchestrator.Run",
 		zap.String("session_key", sessionKey),
 		zap.Int("history_count", len(history)),
 		zap.Int("all_messages_count", len(allMessages)))
 
 	// 异步处理消息，避免阻塞主 agent 接收新消息
-	go m.processMessageAsync(ctx, msg, agent, orchestrator, allMessages, sessionKey, agentMsg, sess, len(history))
+	go m.processMessageAsync(ctx, msg, agent, orchestrator, allMessages, sessionKey, agentMsg, sess, historyLen)
 
 	return nil
 }
@@ -668,14 +767,27 @@ func (m *AgentManager) processMessageAsync(ctx context.Context, msg *bus.Inbound
 	}
 
 	// 单次 Run 超时：模型 API 断开或不可达时不会无限卡住，超时后 ctx 取消、返回错误给用户（continueExecuteAgentRun 会发 phase: error）
-	runCtx := ctx
+	// chat.abort 通过登记的 abort 取消本次 Run（排队中或执行中）
+	runCtx, abort := context.WithCancel(ctx)
+	runCancel := context.CancelFunc(func() {})
 	if cfg := config.Get(); cfg != nil && cfg.Agents.Defaults.RunTimeoutSeconds > 0 {
-		runCtx, _ = context.WithTimeout(ctx, time.Duration(cfg.Agents.Defaults.RunTimeoutSeconds)*time.Second)
+		runCtx, runCancel = context.WithTimeout(runCtx, time.Duration(cfg.Agents.Defaults.RunTimeoutSeconds)*time.Second)
 	}
+	registeredID := m.registerRunCancel(msg.ID, sessionKey, abort)
 
 	go func() {
+		defer abort()
+		defer m.unregisterRunCancel(registeredID)
+		defer runCancel()
 		_, err := process.EnqueueCommandInLane(ctx, lane, func(laneCtx context.Context) (interface{}, error) {
-			return m.executeAgentRun(runCtx, msg, agent, orchestrator, allMessages, sessionKey, agentMsg, sess, historyLen)
+			// 非 websocket / internal 渠道的 allMessages 末尾即本条消息，下载后的图片同步替换
+			if m.fetchInboundMedia(runCtx, &agentMsg) && msg.Channel != "websocket" && msg.Channel != "internal" && len(allMessages) > 0 {
+				allMessages = append(allMessages[:len(allMessages)-1:len(allMessages)-1], agentMsg)
+			}
+			// 在 session lane 内再占用该 Agent 的并发名额，超出 max_concurrent_runs 时排队
+			return m.runWithAgentSlot(laneCtx, agent.GetID(), func() (interface{}, error) {
+				return m.executeAgentRun(runCtx, msg, agent, orchestrator, allMessages, sessionKey, agentMsg, sess, historyLen)
+			})
 		}, nil)
 		if err != nil {
 			logger.Error("Failed to execute agent run in lane",
@@ -719,99 +831,153 @@ func (m *AgentManager) buildRunOptionsForSession(sessionKey string) *RunOptions
 	return &RunOptions{Model: model, MaxIterations: maxIter}
 }
 
-// emitAgentEvent 向总线发送 Agent 事件（与 OpenClaw emitAgentEvent 对齐），供 Control UI 显示进度
-func (m *AgentManager) emitAgentEvent(ctx context.Context, runId, sessionKey string, seq *int, stream bus.AgentEventStream, data map[string]interface{}) {
-	*seq++
-	payload := &bus.AgentEventPayload{
-		RunId:      runId,
-		Seq:        *seq,
-		Stream:     stream,
-		Ts:         time.Now().UnixMilli(),
-		Data:       data,
-		SessionKey: sessionKey,
-	}
-	_ = m.bus.PublishAgentEvent(ctx, payload)
-}
-
 // executeAgentRun 执行 agent 运行（在 lane 中串行执行）
 func (m *AgentManager) executeAgentRun(ctx context.Context, msg *bus.InboundMessage, agent *Agent, orchestrator *Orchestrator, allMessages []AgentMessage, sessionKey string, agentMsg AgentMessage, sess *session.Session, historyLen int) (interface{}, error) {
 	runId := msg.ID
-	seq := 0
+	// 工具（如 cron_add）据此记录之后回到哪个聊天；internal 消息没有可投递的聊天
+	if msg.Channel != "internal" {
+		ctx = tools.WithReplyTarget(ctx, tools.ReplyTarget{Channel: msg.Channel, AccountID: msg.AccountID, ChatID: msg.ChatID})
+	}
+	// 本次运行的 agent 事件（与 OpenClaw emitAgentEvent 对齐）：流式增量、工具与生命周期事件共用一个有序的 seq
+	events := m.bus.NewAgentEventEmitter(runId, sessionKey)
+	m.beginRunReply(runId)
+	defer m.endRunReply(runId)
+	// 由 sessions_send 触发的运行记录消息链，运行中再次发送时据此检测循环
+	m.sendChains.set(sessionKey, sendChainFromMetadata(msg.Metadata))
+	defer m.sendChains.set(sessionKey, nil)
 
 	// 与 OpenClaw 一致：先发送 lifecycle start，UI 可显示“运行中”
-	m.emitAgentEvent(ctx, runId, sessionKey, &seq, bus.AgentStreamLifecycle, map[string]interface{}{
+	_ = events.Emit(ctx, bus.AgentStreamLifecycle, map[string]interface{}{
 		"phase": "start",
 	})
+	metrics.RunsStarted.Inc()
 
 	eventChan := orchestrator.Subscribe()
 	eventCtx, eventCancel := context.WithCancel(ctx)
 	streamDone := make(chan struct{})
 	var accumulated strings.Builder
+	// 非 websocket / internal 渠道的本轮用户消息在 allMessages 末尾、尚未写入会话，检查点须先写入它
+	var prompt *session.Message
+	if historyLen >= 0 && len(allMessages) > historyLen {
+		prompt = &session.Message{
+			Role:      string(RoleUser),
+			Content:   extractTextContent(agentMsg),
+			Timestamp: time.Unix(agentMsg.Timestamp/1000, 0),
+			Metadata:  map[string]interface{}{session.MetadataRunID: runId},
+		}
+	}
+	checkpoint := newStreamCheckpointer(m.sessionMgr, sess, runId, prompt)
 
+	handleEvent := func(event *Event) {
+		// 每次尝试（含 run_retry 重试）开始时清空上一次尝试残留的流式文本
+		if event.Type == EventAgentStart {
+			accumulated.Reset()
+		}
+		if event.Type == EventMessageDelta && event.Content != "" {
+			accumulated.WriteString(event.Content)
+			m.publishStreamDelta(ctx, msg.Channel, msg.ChatID, msg.ID, accumulated.String())
+			checkpoint.onDelta(accumulated.String())
+			// 与 OpenClaw 一致：assistant 流式增量也发 agent 事件
+			_ = events.Emit(ctx, bus.AgentStreamAssistant, map[string]interface{}{
+				"text": accumulated.String(),
+			})
+		}
+		if event.Type == EventToolExecutionStart {
+			// 与 Control UI app-tool-stream 对齐：toolCallId, name, phase, args
+			_ = events.Emit(ctx, bus.AgentStreamTool, map[string]interface{}{
+				"toolCallId": event.ToolID,
+				"name":       event.ToolName,
+				"phase":      "start",
+				"args":       event.ToolArgs,
+			})
+		}
+		if event.Type == EventToolExecutionEnd {
+			resultText := ""
+			if event.ToolResult != nil {
+				resultText = extractToolResultContent(event.ToolResult.Content)
+			}
+			// UI 用 phase "result" 显示工具输出
+			_ = events.Emit(ctx, bus.AgentStreamTool, map[string]interface{}{
+				"toolCallId": event.ToolID,
+				"name":       event.ToolName,
+				"phase":      "result",
+				"error":      event.ToolError,
+				"result":     resultText,
+			})
+		}
+	}
 	go func() {
 		defer close(streamDone)
 		for {
 			select {
 			case <-eventCtx.Done():
-				return
+				// Run 已结束：先处理缓冲中尚未消费的事件，避免最后的流式增量丢失
+				for {
+					select {
+					case event, ok := <-eventChan:
+						if !ok {
+							return
+						}
+						handleEvent(event)
+					default:
+						return
+					}
+				}
 			case event, ok := <-eventChan:
 				if !ok {
 					return
 				}
-				if event.Type == EventMessageDelta && event.Content != "" {
-					accumulated.WriteString(event.Content)
-					m.publishStreamDelta(ctx, msg.Channel, msg.ChatID, msg.ID, accumulated.String())
-					// 与 OpenClaw 一致：assistant 流式增量也发 agent 事件
-					m.emitAgentEvent(ctx, runId, sessionKey, &seq, bus.AgentStreamAssistant, map[string]interface{}{
-						"text": accumulated.String(),
-					})
-				}
-				if event.Type == EventToolExecutionStart {
-					// 与 Control UI app-tool-stream 对齐：toolCallId, name, phase, args
-					m.emitAgentEvent(ctx, runId, sessionKey, &seq, bus.AgentStreamTool, map[string]interface{}{
-						"toolCallId": event.ToolID,
-						"name":       event.ToolName,
-						"phase":      "start",
-						"args":       event.ToolArgs,
-					})
-				}
-				if event.Type == EventToolExecutionEnd {
-					resultText := ""
-					if event.ToolResult != nil {
-						resultText = extractToolResultContent(event.ToolResult.Content)
-					}
-					// UI 用 phase "result" 显示工具输出
-					m.emitAgentEvent(ctx, runId, sessionKey, &seq, bus.AgentStreamTool, map[string]interface{}{
-						"toolCallId": event.ToolID,
-						"name":       event.ToolName,
-						"phase":      "result",
-						"error":      event.ToolError,
-						"result":     resultText,
-					})
-				}
+				handleEvent(event)
 			}
 		}
 	}()
 
-	runOpts := m.buildRunOptionsForSession(sessionKey)
-	finalMessages, err := orchestrator.Run(ctx, allMessages, runOpts)
+	// 每日 token 预算（providers.daily_token_budget）用尽时不再发起新的 run，直接以友好提示结束
+	var finalMessages []AgentMessage
+	var pendingSteering []AgentMessage
+	err := providers.DefaultUsageTracker().CheckBudget()
+	if err == nil {
+		runOpts := withToolApproval(withDebugPrompts(withSessionLevels(withModelOverride(m.buildRunOptionsForSession(sessionKey), sess), sess), sess), m.toolApprover(events, sessionKey, runId))
+		m.setActiveRun(sessionKey, runId, orchestrator)
+		// agents.defaults.run_retry：临时错误且尚未执行工具时从同一份输入重新执行，各次尝试的用量合并计入本次 Run
+		var runUsage session.TokenUsage
+		finalMessages, err = runWithRetry(ctx, sessionKey, runId, func() ([]AgentMessage, bool, error) {
+			msgs, runErr := orchestrator.Run(ctx, allMessages, runOpts)
+			runUsage.Add(orchestrator.Usage())
+			return msgs, orchestrator.ToolsExecuted() > 0, runErr
+		})
+		m.clearActiveRun(sessionKey, orchestrator)
+		// 本次 Run 的 token 用量累加到会话元数据（随 updateSession 落盘），sessions.usage 据此返回实际用量
+		runUsage.RunID = runId
+		sess.AddTokenUsage(runUsage)
+		pendingSteering = orchestrator.takeSteering()
+	}
 
 	eventCancel()
 	<-streamDone
+	checkpoint.finish()
 
 	// 与 OpenClaw 一致：发送 lifecycle end 或 error，UI 可显示完成/错误
 	if err != nil {
-		m.emitAgentEvent(ctx, runId, sessionKey, &seq, bus.AgentStreamLifecycle, map[string]interface{}{
+		lifecycle := map[string]interface{}{
 			"phase": "error",
 			"error": err.Error(),
-		})
+		}
+		errMeta := runErrorMetadata(err)
+		maps.Copy(lifecycle, errMeta)
+		_ = events.Emit(ctx, bus.AgentStreamLifecycle, lifecycle)
+		metrics.RunsFailed.Inc(fmt.Sprint(errMeta["errorCode"]))
+		m.noteProviderFailure(err)
 	} else {
-		m.emitAgentEvent(ctx, runId, sessionKey, &seq, bus.AgentStreamLifecycle, map[string]interface{}{
+		_ = events.Emit(ctx, bus.AgentStreamLifecycle, map[string]interface{}{
 			"phase": "end",
 		})
+		metrics.RunsCompleted.Inc()
 	}
 
 	m.continueExecuteAgentRun(ctx, msg, sessionKey, sess, historyLen, finalMessages, agentMsg, err)
+	// 本次运行已由 updateSession 落盘，再排入未处理的 steering，下一次运行读到的历史包含本次结果
+	m.requeueSteering(ctx, msg, sessionKey, pendingSteering)
 
 	if err != nil {
 		return nil, err
@@ -840,71 +1006,54 @@ func (m *AgentManager) continueExecuteAgentRun(ctx context.Context, msg *bus.Inb
 		hasToolCallIDMismatch := strings.Contains(errStr, "tool_call_id") && strings.Contains(errStr, "mismatch")
 		hasMissingReasoning := strings.Contains(errStr, "reasoning_content") && strings.Contains(errStr, "assistant tool call")
 		if hasToolCallIDMismatch || hasMissingReasoning {
-			logger.Warn("Detected old session format, clearing session",
-				zap.String("session_key", sessionKey),
-				zap.Error(err))
-			// Clear old session and retry
-			if delErr := m.sessionMgr.Delete(sessionKey); delErr != nil {
-				logger.Error("Failed to clear old session", zap.Error(delErr))
-				return
-			}
-			logger.Info("Cleared old session, retrying with fresh session")
-			// Get fresh session
-			freshSess, getErr := m.sessionMgr.GetOrCreate(sessionKey)
-			if getErr != nil {
-				logger.Error("Failed to create fresh session", zap.Error(getErr))
-				return
-			}
-
-			// Get agent from session key
-			agentID, _, _ := ParseAgentSessionKey(sessionKey)
-			agent, ok := m.GetAgent(agentID)
-			if !ok {
-				agent = m.defaultAgent
-			}
-			if agent == nil {
-				logger.Error("No agent found for retry")
-				return
-			}
-
-			// Retry with fresh session (no history)
-			finalMessages, retryErr := agent.GetOrchestrator().Run(ctx, []AgentMessage{agentMsg}, nil)
-			if retryErr != nil {
-				logger.Error("Agent execution failed on retry", zap.Error(retryErr))
-				m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, retryErr)
-				return
-			}
-			// Update session with new messages
-			m.updateSession(freshSess, finalMessages, 0)
-			// Publish response
-			if len(finalMessages) > 0 {
-				lastMsg := finalMessages[len(finalMessages)-1]
-				if lastMsg.Role == RoleAssistant {
-					m.publishToBus(ctx, msg.Channel, msg.ChatID, msg.ID, lastMsg)
+			switch formatErrorRecoveryMode() {
+			case FormatErrorRecoveryRepair:
+				logger.Warn("Detected session format error, repairing session",
+					zap.String("session_key", sessionKey),
+					zap.Error(err))
+				if m.repairSessionAndRetry(ctx, msg, sessionKey, sess, agentMsg, hasMissingReasoning) {
+					return
+				}
+			case FormatErrorRecoveryDelete:
+				logger.Warn("Detected old session format, clearing session",
+					zap.String("session_key", sessionKey),
+					zap.Error(err))
+				// Clear old session and retry
+				if delErr := m.sessionMgr.Delete(sessionKey); delErr != nil {
+					logger.Error("Failed to clear old session", zap.Error(delErr))
+					return
+				}
+				logger.Info("Cleared old session, retrying with fresh session")
+				// Get fresh session
+				freshSess, getErr := m.sessionMgr.GetOrCreate(sessionKey)
+				if getErr != nil {
+					logger.Error("Failed to create fresh session", zap.Error(getErr))
+					return
 				}
+				// Retry with fresh session (no history)
+				m.retryRunWithSession(ctx, msg, sessionKey, freshSess, []AgentMessage{agentMsg}, 0)
+				return
 			}
-			return
 		}
 		logger.Error("Agent execution failed", zap.Error(err))
-		m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, err)
+		m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, inboundLocale(msg), err)
 		return
 	}
 
+	// 最终回复后处理（只作用于最后一条 assistant 消息），会话与发布使用同一份处理后文本
+	applyPostProcess(m.postProcessPipeline(), finalMessages, sessionKey)
+
 	// 更新会话（只保存新产生的消息）并在发布前完成 Save，保证 chat.history 能读到助手回复
-	m.updateSession(sess, finalMessages, historyLen)
+	m.updateSession(sess, finalMessages, historyLen, msg.ID)
+
+	// 新会话首次回复后异步生成标题（session.auto_title）
+	if cfg := config.Get(); shouldAutoTitle(cfg, msg.Channel, sessionKey, sess) {
+		go m.autoTitleSession(context.Background(), sessionKey, sess, cfg.Session.AutoTitleModel)
+	}
 
 	// 发布响应（Save 已在 updateSession 内完成）；子 agent（internal）不推 bus，结果通过 announcer 回主会话
-	if msg.Channel != "internal" && len(finalMessages) > 0 {
-		lastMsg := finalMessages[len(finalMessages)-1]
-		if lastMsg.Role == RoleAssistant {
-			content := extractTextContent(lastMsg)
-			if strings.TrimSpace(content) != "" {
-				m.publishToBus(ctx, msg.Channel, msg.ChatID, msg.ID, lastMsg)
-			} else {
-				// LLM 返回空回复时也要发 state: "final"，否则前端收不到结束事件会一直转圈
-				m.publishRunFinalToBus(ctx, msg.Channel, msg.ChatID, msg.ID, "")
-			}
-		}
+	if msg.Channel != "internal" {
+		m.publishRunReply(ctx, msg, finalMessages)
 	}
 
 	// 子 agent 完成后标记完成，由 SetOnRunComplete 回调（handleSubagentCompletion）统一做 announcer + cleanup
@@ -928,8 +1077,8 @@ func (m *AgentManager) continueExecuteAgentRun(ctx context.Context, msg *bus.Inb
 	}
 }
 
-// updateSession 更新会话
-func (m *AgentManager) updateSession(sess *session.Session, messages []AgentMessage, historyLen int) {
+// updateSession 更新会话；新消息带上 runId 元数据（sessions.diff 按 run 比较）
+func (m *AgentManager) updateSession(sess *session.Session, messages []AgentMessage, historyLen int, runID string) {
 	// 只保存新产生的消息（不包括历史消息）
 	newMessages := messages
 	if historyLen >= 0 && len(messages) > historyLen {
@@ -942,6 +1091,9 @@ func (m *AgentManager) updateSession(sess *session.Session, messages []AgentMess
 			Content:   extractTextContent(msg),
 			Timestamp: time.Unix(msg.Timestamp/1000, 0),
 		}
+		if runID != "" {
+			sessMsg.Metadata = map[string]interface{}{session.MetadataRunID: runID}
+		}
 
 		if msg.Role == RoleAssistant {
 			for _, block := range msg.Content {
@@ -953,7 +1105,7 @@ func (m *AgentManager) updateSession(sess *session.Session, messages []AgentMess
 					})
 				}
 			}
-			if reasoning, ok := msg.Metadata["reasoning_content"].(string); ok && strings.TrimSpace(reasoning) != "" {
+			if reasoning := messageReasoning(msg); reasoning != "" {
 				if sessMsg.Metadata == nil {
 					sessMsg.Metadata = make(map[string]interface{})
 				}
@@ -982,78 +1134,204 @@ func (m *AgentManager) updateSession(sess *session.Session, messages []AgentMess
 	}
 }
 
-// friendlyRunErrorMessage 将限流/406 等错误转为对用户友好的中文提示，其余错误返回原始文案
+// friendlyRunErrorMessage 将限流/406、预算用尽、上下文溢出、超时等错误转为对用户友好的提示（语言见 gateway.locale），其余错误返回原始文案
 func friendlyRunErrorMessage(runErr error) string {
+	return friendlyRunErrorMessageFor("", runErr)
+}
+
+// friendlyRunErrorMessageFor 同 friendlyRunErrorMessage，但按 locale（如连接握手的语言偏好）选择文案；locale 为空时使用 gateway.locale
+func friendlyRunErrorMessageFor(locale string, runErr error) string {
 	if runErr == nil {
 		return ""
 	}
-	classifier := types.NewSimpleErrorClassifier()
-	if classifier.ClassifyError(runErr) == types.FailoverReasonRateLimit {
-		delaySec := types.ExtractRateLimitDelay(runErr, 30, 60)
+	if errors.Is(runErr, providers.ErrDailyTokenBudgetExceeded) {
+		return i18n.TFor(locale, i18n.RunBudgetExceeded)
+	}
+	if errors.Is(runErr, diskspace.ErrDiskCritical) {
+		return i18n.TFor(locale, i18n.RunDiskCritical)
+	}
+	if errors.Is(runErr, context.Canceled) {
+		return i18n.TFor(locale, i18n.RunAborted)
+	}
+	if delaySec, limited := rateLimitRetryAfter(runErr); limited {
 		if delaySec > 0 {
-			return fmt.Sprintf("请求过于频繁或模型暂时限流，请 %d 秒后再试。", delaySec)
+			return i18n.TFor(locale, i18n.RunRateLimitedRetry, delaySec)
 		}
-		return "请求过于频繁或模型暂时限流，请稍后再试。"
+		return i18n.TFor(locale, i18n.RunRateLimited)
+	}
+	if IsContextOverflowError(runErr) {
+		return i18n.TFor(locale, i18n.RunContextOverflow)
+	}
+	var toolStop *ToolErrorStopError
+	if errors.As(runErr, &toolStop) {
+		return toolStop.Error()
+	}
+	var capErr *ModelCapabilityError
+	if errors.As(runErr, &capErr) {
+		return capErr.Error()
+	}
+	var unavailable *ProviderUnavailableError
+	if errors.As(runErr, &unavailable) {
+		return i18n.TFor(locale, i18n.RunProviderDown)
+	}
+	if errors.Is(runErr, context.DeadlineExceeded) || types.NewSimpleErrorClassifier().ClassifyError(runErr) == types.FailoverReasonTimeout {
+		return i18n.TFor(locale, i18n.RunTimeout)
 	}
 	return runErr.Error()
 }
 
-// publishRunErrorToBus 在 Run 报错（如超时、模型 API 断开）时发布一条 chat 事件 state: "error"，便于前端按 chat 结束统一收尾（与 OpenClaw 的 aborted 一致）
-func (m *AgentManager) publishRunErrorToBus(ctx context.Context, channel, chatID, runID string, runErr error) {
-	if runErr == nil {
+// Run 失败时 chat 事件的错误码（出站消息元数据 errorCode），客户端据此处理而不必解析本地化文案
+const (
+	RunErrorCodeRateLimit       = "rate_limit"
+	RunErrorCodeBudgetExceeded  = "budget_exceeded"
+	RunErrorCodeDiskCritical    = "disk_critical"
+	RunErrorCodeAborted         = "aborted"
+	RunErrorCodeContextOverflow = "context_overflow"
+	RunErrorCodeToolErrors      = "tool_errors"
+	RunErrorCodeModelCapability = "model_capability"
+	RunErrorCodeProviderDown    = "provider_unavailable"
+	RunErrorCodeUnknown         = "unknown"
+)
+
+// rateLimitRetryAfter 错误是否为限流，以及建议等待的秒数（解析 "reset after Ns"，默认 30，最多 60）
+func rateLimitRetryAfter(runErr error) (int, bool) {
+	if types.NewSimpleErrorClassifier().ClassifyError(runErr) != types.FailoverReasonRateLimit {
+		return 0, false
+	}
+	return types.ExtractRateLimitDelay(runErr, 30, 60), true
+}
+
+// runErrorMetadata Run 失败的结构化信息（errorCode，限流时另有 retryAfterSeconds），随 chat error 事件发给客户端；
+// 分类与 friendlyRunErrorMessage 一致，其余错误使用 provider 错误分类（auth、timeout、billing 等）
+func runErrorMetadata(runErr error) map[string]interface{} {
+	code := RunErrorCodeUnknown
+	var toolStop *ToolErrorStopError
+	var capErr *ModelCapabilityError
+	var unavailable *ProviderUnavailableError
+	delaySec, limited := rateLimitRetryAfter(runErr)
+	switch {
+	case errors.Is(runErr, providers.ErrDailyTokenBudgetExceeded):
+		code = RunErrorCodeBudgetExceeded
+	case errors.Is(runErr, diskspace.ErrDiskCritical):
+		code = RunErrorCodeDiskCritical
+	case errors.Is(runErr, context.Canceled):
+		code = RunErrorCodeAborted
+	case limited:
+		code = RunErrorCodeRateLimit
+	case IsContextOverflowError(runErr):
+		code = RunErrorCodeContextOverflow
+	case errors.As(runErr, &toolStop):
+		code = RunErrorCodeToolErrors
+	case errors.As(runErr, &capErr):
+		code = RunErrorCodeModelCapability
+	case errors.As(runErr, &unavailable):
+		code = RunErrorCodeProviderDown
+	default:
+		code = string(types.NewSimpleErrorClassifier().ClassifyError(runErr))
+	}
+	meta := map[string]interface{}{"errorCode": code}
+	if code == RunErrorCodeRateLimit && delaySec > 0 {
+		meta["retryAfterSeconds"] = delaySec
+	}
+	return meta
+}
+
+// beginRunReply 标记 Run 开始，此后该 runId 只允许发送一个终态消息
+func (m *AgentManager) beginRunReply(runID string) {
+	m.runRepliesMu.Lock()
+	defer m.runRepliesMu.Unlock()
+	if m.runReplies == nil {
+		m.runReplies = make(map[string]bool)
+	}
+	m.runReplies[runID] = false
+}
+
+// endRunReply Run 结束后移除标记；相同 runId 再次运行（如重发同一 idempotencyKey）时重新计数
+func (m *AgentManager) endRunReply(runID string) {
+	m.runRepliesMu.Lock()
+	defer m.runRepliesMu.Unlock()
+	delete(m.runReplies, runID)
+}
+
+// claimRunTerminal 登记一次终态消息；返回 false 表示执行中的 Run 已发送过终态消息，调用方应跳过发布。
+// 不在执行中的 runId（如命令回复、运行前被拒绝）总是返回 true
+func (m *AgentManager) claimRunTerminal(runID string) bool {
+	m.runRepliesMu.Lock()
+	defer m.runRepliesMu.Unlock()
+	sent, running := m.runReplies[runID]
+	if !running {
+		return true
+	}
+	if sent {
+		logger.Warn("Skipping duplicate terminal message for run", zap.String("run_id", runID))
+		return false
+	}
+	m.runReplies[runID] = true
+	return true
+}
+
+// publishRunReply 发布 Run 的唯一终态消息：最后一条 assistant 有文本时为带完整文本的 final
+// （前端按 runId 与此前的 delta 对齐），否则为空 final，保证前端总能收到结束事件
+func (m *AgentManager) publishRunReply(ctx context.Context, msg *bus.InboundMessage, finalMessages []AgentMessage) {
+	content := ""
+	if len(finalMessages) > 0 {
+		if lastMsg := finalMessages[len(finalMessages)-1]; lastMsg.Role == RoleAssistant {
+			content = extractTextContent(lastMsg)
+		}
+	}
+	// LLM 返回空回复时也要发 state: "final"，否则前端收不到结束事件会一直转圈
+	if strings.TrimSpace(content) == "" {
+		content = ""
+	}
+	m.publishRunFinalToBus(ctx, msg.Channel, msg.ChatID, msg.ID, content)
+}
+
+// publishRunErrorToBus 在 Run 报错（如超时、模型 API 断开）时发布一条 chat 事件 state: "error"，便于前端按 chat 结束统一收尾（与 OpenClaw 的 aborted 一致）；
+// locale 为入站消息的语言偏好（见 inboundLocale），为空时按 gateway.locale
+func (m *AgentManager) publishRunErrorToBus(ctx context.Context, channel, chatID, runID, locale string, runErr error) {
+	if runErr == nil || !m.claimRunTerminal(runID) {
 		return
 	}
-	content := friendlyRunErrorMessage(runErr)
+	content := friendlyRunErrorMessageFor(locale, runErr)
 	outbound := &bus.OutboundMessage{
 		ID:        runID,
 		Channel:   channel,
 		ChatID:    chatID,
 		Content:   content,
+		Metadata:  runErrorMetadata(runErr),
 		ChatState: "error",
 		Timestamp: time.Now(),
 	}
-	if err := m.bus.PublishOutbound(ctx, outbound); err != nil {
+	// 中止或超时时 ctx 已取消，错误消息仍需送达
+	if err := m.bus.PublishOutbound(context.WithoutCancel(ctx), outbound); err != nil {
 		logger.Error("Failed to publish run error to outbound", zap.Error(err))
 	}
 }
 
-// publishRunFinalToBus 在 Run 正常结束但内容为空时发送 state: "final"，让前端能结束当前 run
-func (m *AgentManager) publishRunFinalToBus(ctx context.Context, channel, chatID, runID, content string) {
-	outbound := &bus.OutboundMessage{
-		ID:        runID,
-		Channel:   channel,
-		ChatID:    chatID,
-		Content:   content,
-		ChatState: "final",
-		Timestamp: time.Now(),
-	}
-	if err := m.bus.PublishOutbound(ctx, outbound); err != nil {
-		logger.Error("Failed to publish run final to outbound", zap.Error(err))
+// inboundLocale 入站消息携带的语言偏好（如 WebSocket 连接 connect 握手的 locale），未携带时为空
+func inboundLocale(msg *bus.InboundMessage) string {
+	if msg == nil {
+		return ""
 	}
+	locale, _ := msg.Metadata["locale"].(string)
+	return locale
 }
 
-// publishToBus 发布消息到总线
-func (m *AgentManager) publishToBus(ctx context.Context, channel, chatID, runID string, msg AgentMessage) {
-	content := extractTextContent(msg)
-
-	// 如果内容为空（只有工具调用，没有文本），不发布消息
-	if strings.TrimSpace(content) == "" {
-		logger.Debug("Skipping empty message publish (tool-only response)",
-			zap.String("run_id", runID),
-			zap.String("role", string(msg.Role)))
+// publishRunFinalToBus 发送 state: "final"（content 可为空），让前端能结束当前 run
+func (m *AgentManager) publishRunFinalToBus(ctx context.Context, channel, chatID, runID, content string) {
+	if !m.claimRunTerminal(runID) {
 		return
 	}
-
 	outbound := &bus.OutboundMessage{
-		ID:        runID, // 保留原始 runId，确保前端能匹配
+		ID:        runID,
 		Channel:   channel,
 		ChatID:    chatID,
 		Content:   content,
-		Timestamp: time.Unix(msg.Timestamp/1000, 0),
+		ChatState: "final",
+		Timestamp: time.Now(),
 	}
-
 	if err := m.bus.PublishOutbound(ctx, outbound); err != nil {
-		logger.Error("Failed to publish outbound", zap.Error(err))
+		logger.Error("Failed to publish run final to outbound", zap.Error(err))
 	}
 }
 
@@ -1081,6 +1359,14 @@ func (m *AgentManager) publishStreamDelta(ctx context.Context, channel, chatID,
 	}
 }
 
+// toolResultTruncationFromConfig 将 agents.defaults.tool_result_truncation 转为截断选项；未配置时全部用默认
+func toolResultTruncationFromConfig(cfg *config.ToolResultTruncationConfig) ToolResultTruncation {
+	if cfg == nil {
+		return ToolResultTruncation{}
+	}
+	return ToolResultTruncation{HeadChars: cfg.HeadChars, TailChars: cfg.TailChars, Marker: cfg.Marker}
+}
+
 // sessionMessagesToAgentMessages 将 session 消息转换为 Agent 消息
 func sessionMessagesToAgentMessages(sessMsgs []session.Message) []AgentMessage {
 	result := make([]AgentMessage, 0, len(sessMsgs))
@@ -1121,6 +1407,11 @@ func sessionMessagesToAgentMessages(sessMsgs []session.Message) []AgentMessage {
 			}
 			agentMsg.Metadata["reasoning_content"] = reasoning
 		}
+		for _, media := range sessMsg.Media {
+			if media.Type == "image" && (media.URL != "" || media.Base64 != "") {
+				agentMsg.Content = append(agentMsg.Content, ImageContent{URL: media.URL, Data: media.Base64, MimeType: media.MimeType})
+			}
+		}
 
 		result = append(result, agentMsg)
 	}
@@ -1148,6 +1439,30 @@ func (m *AgentManager) ListAgents() []string {
 	return ids
 }
 
+// ReloadSkills 按禁用列表与各技能 API key 重新发现技能并推送到所有 Agent（skills.update / skills.reload），下一次 Run 生效
+func (m *AgentManager) ReloadSkills(disabled []string, apiKeys map[string]string) error {
+	if m.skillsLoader == nil {
+		return nil
+	}
+	m.skillsLoader.SetDisabled(disabled)
+	m.skillsLoader.SetAPIKeys(apiKeys)
+	if err := m.skillsLoader.Discover(); err != nil {
+		return fmt.Errorf("failed to reload skills: %w", err)
+	}
+	skills := m.skillsLoader.List()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, agent := range m.agents {
+		agent.SetSkills(skills)
+	}
+	logger.Info("Skills reloaded",
+		zap.Int("count", len(skills)),
+		zap.Strings("disabled", disabled),
+		zap.Int("agents", len(m.agents)))
+	return nil
+}
+
 // Start 启动所有 Agent
 func (m *AgentManager) Start(ctx context.Context) error {
 	m.mu.RLock()
@@ -1163,6 +1478,9 @@ func (m *AgentManager) Start(ctx context.Context) error {
 		}
 	}
 
+	// 探测模型提供商可用性
+	go m.monitorProvider(ctx)
+
 	// 启动消息处理器
 	go m.processMessages(ctx)
 
@@ -1271,6 +1589,9 @@ func (m *AgentManager) getOrCreateSubagent(parentAgentID, subagentID string, par
 		ReserveTokens:               0,  // 使用默认
 		MaxHistoryTurns:             0,  // 不限制
 		ModelRequestIntervalSeconds: m.cfg.Agents.Defaults.ModelRequestIntervalSeconds,
+		ToolErrorPolicy:             m.cfg.Agents.Defaults.ToolErrorPolicy,
+		ToolErrorMaxConsecutive:     m.cfg.Agents.Defaults.ToolErrorMaxConsecutive,
+		ToolTimeoutSeconds:          m.cfg.Tools.TimeoutSeconds,
 		SkillsLoader:                m.skillsLoader,
 	})
 	if err != nil {
@@ -0,0 +1,510 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestFriendlyRunErrorMessageFollowsLocale(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+
+	rateErr := errors.New("429 Too Many Requests: reset after 12s")
+
+	// 未配置或不支持的 locale：回退到英文
+	for _, locale := range []string{"", "fr-FR"} {
+		config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: locale}})
+		if msg := friendlyRunErrorMessage(rateErr); msg != "Too many requests or the model is rate limited. Please try again in 12 seconds." {
+			t.Errorf("locale %q message = %q, want the English rate-limit notice", locale, msg)
+		}
+	}
+
+	config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: "en-US"}})
+	if msg := friendlyRunErrorMessage(rateErr); msg != "Too many requests or the model is rate limited. Please try again in 12 seconds." {
+		t.Errorf("en locale message = %q", msg)
+	}
+
+	config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: "zh"}})
+	if msg := friendlyRunErrorMessage(rateErr); !strings.Contains(msg, "限流") {
+		t.Errorf("zh locale message = %q", msg)
+	}
+}
+
+func TestFriendlyRunErrorMessageForConnectionLocale(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: "zh"}})
+
+	rateErr := errors.New("429 Too Many Requests: reset after 12s")
+	if msg := friendlyRunErrorMessageFor("en-GB,zh;q=0.5", rateErr); msg != "Too many requests or the model is rate limited. Please try again in 12 seconds." {
+		t.Errorf("connection locale should override gateway.locale, got %q", msg)
+	}
+	if msg := friendlyRunErrorMessageFor("fr-FR, de;q=0.8", rateErr); !strings.HasPrefix(msg, "Too many requests") {
+		t.Errorf("unsupported locale should fall back to English, got %q", msg)
+	}
+	if msg := friendlyRunErrorMessageFor("fr, zh-CN;q=0.7", rateErr); !strings.Contains(msg, "限流") {
+		t.Errorf("first supported locale should win, got %q", msg)
+	}
+
+	timeoutErr := fmt.Errorf("chat completion: %w", context.DeadlineExceeded)
+	if msg := friendlyRunErrorMessageFor("en", timeoutErr); !strings.HasPrefix(msg, "The model took too long to respond") {
+		t.Errorf("en timeout message = %q", msg)
+	}
+	if msg := friendlyRunErrorMessage(errors.New("504 Gateway Timeout")); !strings.Contains(msg, "超时") {
+		t.Errorf("zh timeout message = %q", msg)
+	}
+}
+
+func TestRunErrorMetadata(t *testing.T) {
+	tests := []struct {
+		err        error
+		code       string
+		retryAfter interface{}
+	}{
+		{errors.New("429 Too Many Requests: reset after 12s"), RunErrorCodeRateLimit, 12},
+		{errors.New("rate limit exceeded"), RunErrorCodeRateLimit, 30},
+		{fmt.Errorf("run: %w", providers.ErrDailyTokenBudgetExceeded), RunErrorCodeBudgetExceeded, nil},
+		{context.Canceled, RunErrorCodeAborted, nil},
+		{errors.New("401 invalid api key"), "auth", nil},
+		{errors.New("something odd"), RunErrorCodeUnknown, nil},
+	}
+	for _, tt := range tests {
+		meta := runErrorMetadata(tt.err)
+		if meta["errorCode"] != tt.code || meta["retryAfterSeconds"] != tt.retryAfter {
+			t.Errorf("runErrorMetadata(%q) = %v, want code %s retryAfter %v", tt.err, meta, tt.code, tt.retryAfter)
+		}
+	}
+
+	m := &AgentManager{bus: bus.NewMessageBus(10)}
+	defer m.bus.Close()
+	sub := m.bus.SubscribeOutbound()
+	m.publishRunErrorToBus(context.Background(), "websocket", "agent:main:main", "run-1", "", tests[0].err)
+	select {
+	case out := <-sub.Channel:
+		if out.ChatState != "error" || out.Metadata["errorCode"] != RunErrorCodeRateLimit || out.Metadata["retryAfterSeconds"] != 12 {
+			t.Fatalf("outbound error = %+v", out)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no outbound error published")
+	}
+}
+
+func TestFormatErrorRepairsSessionInsteadOfDeleting(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{}) // 未配置 recover_on_format_error：默认 repair
+
+	const key = "agent:main:main"
+	dir := t.TempDir()
+	mgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := mgr.GetOrCreate(key)
+	now := time.Now()
+	for _, msg := range []session.Message{
+		{Role: "user", Content: "list files", Timestamp: now},
+		{Role: "assistant", ToolCalls: []session.ToolCall{{ID: "call_1", Name: "exec"}, {ID: "call_2", Name: "read_file"}}, Timestamp: now},
+		{Role: "tool", Content: "a.txt", ToolCallID: "call_1", Metadata: map[string]interface{}{"tool_name": "exec"}, Timestamp: now},
+		{Role: "assistant", Content: "Found a.txt", Timestamp: now},
+		{Role: "tool", Content: "stale", ToolCallID: "call_9", Metadata: map[string]interface{}{"tool_name": "exec"}, Timestamp: now},
+	} {
+		sess.AddMessage(msg)
+	}
+	if err := mgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &steeringProvider{}
+	m := &AgentManager{
+		bus:          bus.NewMessageBus(10),
+		sessionMgr:   mgr,
+		defaultAgent: &Agent{orchestrator: NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 3}, NewAgentState())},
+	}
+	defer m.bus.Close()
+
+	inbound := &bus.InboundMessage{ID: "run-1", Channel: "telegram", ChatID: "42", Content: "and now?"}
+	agentMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "and now?"}}, Timestamp: now.UnixMilli()}
+	runErr := errors.New("400 Bad Request: tool_call_id mismatch in messages")
+	m.continueExecuteAgentRun(context.Background(), inbound, key, sess, 5, nil, agentMsg, runErr)
+
+	if _, err := os.Stat(filepath.Join(dir, session.KeyToSafeFilename(key)+".jsonl")); err != nil {
+		t.Fatalf("session file should be kept: %v", err)
+	}
+	if len(provider.calls) != 1 {
+		t.Fatalf("retry LLM calls = %d, want 1", len(provider.calls))
+	}
+	for _, pm := range provider.calls[0] {
+		if pm.ToolCallID == "call_9" {
+			t.Error("orphaned tool result was sent on retry")
+		}
+	}
+
+	history := sess.GetHistory(0)
+	var roles []string
+	for _, msg := range history {
+		roles = append(roles, msg.Role)
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,tool,assistant,user,assistant" {
+		t.Fatalf("repaired history roles = %s", got)
+	}
+	if calls := history[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call_1" {
+		t.Errorf("dangling tool call not removed: %+v", calls)
+	}
+	if history[0].Content != "list files" || history[5].Content != "reply" {
+		t.Errorf("history not preserved around repair: %+v", history)
+	}
+}
+
+func TestRouteInboundDuringConfigReload(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus(256)
+	defer msgBus.Close()
+	m := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        msgBus,
+		Provider:   &fakeChatProvider{},
+		SessionMgr: sessMgr,
+		Tools:      NewToolRegistry(),
+		DataDir:    t.TempDir(),
+	})
+	workspace := t.TempDir()
+	contextBuilder := NewContextBuilder(NewMemoryStore(workspace), workspace)
+	cfgFor := func(boundAgent string) *config.Config {
+		return &config.Config{
+			Workspace: config.WorkspaceConfig{Path: workspace},
+			Agents: config.AgentsConfig{List: []config.AgentConfig{
+				{ID: "main", Default: true},
+				{ID: "helper"},
+			}},
+			Bindings: []config.BindingConfig{{AgentID: boundAgent, Match: config.BindingMatch{Channel: "telegram", AccountID: "bot"}}},
+		}
+	}
+	if err := m.SetupFromConfig(cfgFor("main"), contextBuilder); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := msgBus.SubscribeOutbound()
+	defer sub.Unsubscribe()
+
+	const runs = 40
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < runs; i++ {
+			bound := "main"
+			if i%2 == 1 {
+				bound = "helper"
+			}
+			if err := m.SetupFromConfig(cfgFor(bound), contextBuilder); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < runs; i++ {
+		msg := &bus.InboundMessage{
+			ID:        fmt.Sprintf("run-%d", i),
+			Channel:   "telegram",
+			AccountID: "bot",
+			ChatID:    fmt.Sprintf("group-%d", i), // 群组会话互相独立，便于逐条确认回复
+			Content:   "hi",
+		}
+		if err := m.RouteInbound(ctx, msg); err != nil {
+			t.Fatalf("RouteInbound: %v", err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("config reload blocked while routing")
+	}
+
+	replied := make(map[string]bool)
+	for len(replied) < runs {
+		select {
+		case out := <-sub.Channel:
+			if out.Content == "done" {
+				replied[out.ChatID] = true
+			}
+		case <-ctx.Done():
+			t.Fatalf("got %d/%d replies after reload", len(replied), runs)
+		}
+	}
+}
+
+// streamingFakeProvider 逐块推送 chunks，结束时在 Done 中带上完整文本
+type streamingFakeProvider struct {
+	fakeChatProvider
+	chunks []string
+}
+
+func (p *streamingFakeProvider) SupportsStreaming() bool { return true }
+
+func (p *streamingFakeProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, callback providers.StreamCallback, options ...providers.ChatOption) error {
+	for _, c := range p.chunks {
+		callback(providers.StreamChunk{Content: c})
+	}
+	callback(providers.StreamChunk{Content: strings.Join(p.chunks, ""), Done: true})
+	return nil
+}
+
+func TestStreamedRunPublishesSingleFinal(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{bus: bus.NewMessageBus(64), sessionMgr: sessMgr}
+	defer m.bus.Close()
+	sub := m.bus.SubscribeOutbound()
+
+	const key = "agent:main:main"
+	sess, _ := sessMgr.GetOrCreate(key)
+	provider := &streamingFakeProvider{chunks: []string{"Hel", "lo ", "there"}}
+	orchestrator := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 3}, NewAgentState())
+	msg := &bus.InboundMessage{ID: "run-stream", Channel: "websocket", ChatID: key, Content: "hi"}
+	userMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}, Timestamp: time.Now().UnixMilli()}
+
+	if _, err := m.executeAgentRun(context.Background(), msg, nil, orchestrator, []AgentMessage{userMsg}, key, userMsg, sess, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 流式增量之后只应有一条带完整文本的 final，不再重复推送完整消息或空 final
+	var deltas, finals int
+	var finalText string
+	for done := false; !done; {
+		select {
+		case out := <-sub.Channel:
+			if out.IsStream {
+				deltas++
+			} else {
+				finals++
+				finalText = out.Content
+			}
+		case <-time.After(200 * time.Millisecond):
+			done = true
+		}
+	}
+	if deltas == 0 {
+		t.Fatal("expected streamed deltas")
+	}
+	if finals != 1 || finalText != "Hello there" {
+		t.Fatalf("finals = %d (last %q), want exactly one final with the complete text", finals, finalText)
+	}
+}
+
+// countingChatProvider 记录模型调用次数
+type countingChatProvider struct {
+	fakeChatProvider
+	calls int
+}
+
+func (p *countingChatProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.calls++
+	return p.fakeChatProvider.Chat(ctx, messages, tools, options...)
+}
+
+func TestExhaustedDailyBudgetSkipsRun(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	tracker := providers.DefaultUsageTracker()
+	tracker.Configure(100, "")
+	defer tracker.Configure(0, "")
+	tracker.Record("test", "budget-model", providers.Usage{TotalTokens: 100}, false)
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{bus: bus.NewMessageBus(64), sessionMgr: sessMgr}
+	defer m.bus.Close()
+	sub := m.bus.SubscribeOutbound()
+
+	const key = "agent:main:main"
+	sess, _ := sessMgr.GetOrCreate(key)
+	provider := &countingChatProvider{}
+	orchestrator := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 3}, NewAgentState())
+	msg := &bus.InboundMessage{ID: "run-budget", Channel: "websocket", ChatID: key, Content: "hi"}
+	userMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}, Timestamp: time.Now().UnixMilli()}
+
+	_, err = m.executeAgentRun(context.Background(), msg, nil, orchestrator, []AgentMessage{userMsg}, key, userMsg, sess, 0)
+	if !errors.Is(err, providers.ErrDailyTokenBudgetExceeded) {
+		t.Fatalf("err = %v, want ErrDailyTokenBudgetExceeded", err)
+	}
+	if provider.calls != 0 {
+		t.Fatalf("provider called %d times after the budget was used up", provider.calls)
+	}
+	select {
+	case out := <-sub.Channel:
+		if want := friendlyRunErrorMessage(err); out.Content != want {
+			t.Fatalf("reply = %q, want %q", out.Content, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no reply published")
+	}
+}
+
+// usageReportingProvider 每次调用都上报固定的 token 用量
+type usageReportingProvider struct{ fakeChatProvider }
+
+func (p *usageReportingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return &providers.Response{Content: "done", Usage: providers.Usage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150}}, nil
+}
+
+func TestProviderReportedUsageAccumulatesInSession(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	dir := t.TempDir()
+	sessMgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{bus: bus.NewMessageBus(64), sessionMgr: sessMgr}
+	defer m.bus.Close()
+
+	const key = "agent:main:main"
+	sess, _ := sessMgr.GetOrCreate(key)
+	for i, runID := range []string{"run-1", "run-2"} {
+		orchestrator := NewOrchestrator(&LoopConfig{Provider: &usageReportingProvider{}, MaxIterations: 3}, NewAgentState())
+		msg := &bus.InboundMessage{ID: runID, Channel: "websocket", ChatID: key, Content: "hi"}
+		userMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}, Timestamp: time.Now().UnixMilli()}
+		if _, err := m.executeAgentRun(context.Background(), msg, nil, orchestrator, []AgentMessage{userMsg}, key, userMsg, sess, 2*i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 重新从磁盘加载，确认累计用量已随会话保存
+	reloaded, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := reloaded.GetOrCreate(key)
+	usage, ok := got.TokenUsage()
+	if !ok {
+		t.Fatal("expected token usage in session metadata")
+	}
+	want := session.TokenUsage{Calls: 2, PromptTokens: 240, CompletionTokens: 60, TotalTokens: 300}
+	if usage != want || usage.Estimated() {
+		t.Fatalf("session usage = %+v, want %+v", usage, want)
+	}
+	if last, ok := got.GetMetadata(session.MetadataLastRunUsage).(map[string]interface{}); !ok || last["runId"] != "run-2" || last["totalTokens"] != float64(150) {
+		t.Fatalf("last run usage = %#v", got.GetMetadata(session.MetadataLastRunUsage))
+	}
+}
+
+func TestRunReplyGuardDropsDuplicateTerminal(t *testing.T) {
+	m := &AgentManager{bus: bus.NewMessageBus(10)}
+	defer m.bus.Close()
+	sub := m.bus.SubscribeOutbound()
+	ctx := context.Background()
+
+	m.beginRunReply("run-1")
+	m.publishRunFinalToBus(ctx, "telegram", "42", "run-1", "answer")
+	m.publishRunFinalToBus(ctx, "telegram", "42", "run-1", "")
+	m.publishRunErrorToBus(ctx, "telegram", "42", "run-1", "", errors.New("late failure"))
+	m.endRunReply("run-1")
+	// 相同 runId 的新一次执行可以再次发送终态消息
+	m.beginRunReply("run-1")
+	m.publishRunFinalToBus(ctx, "telegram", "42", "run-1", "second")
+	m.endRunReply("run-1")
+
+	var got []string
+	for done := false; !done; {
+		select {
+		case out := <-sub.Channel:
+			got = append(got, out.Content)
+		case <-time.After(200 * time.Millisecond):
+			done = true
+		}
+	}
+	if strings.Join(got, "|") != "answer|second" {
+		t.Fatalf("terminal messages = %q, want one per run", got)
+	}
+}
+
+func TestThinkingContentSurvivesSessionReload(t *testing.T) {
+	dir := t.TempDir()
+	sessMgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{sessionMgr: sessMgr}
+
+	const key = "agent:main:main"
+	sess, _ := sessMgr.GetOrCreate(key)
+	messages := []AgentMessage{
+		{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "2+2?"}}, Timestamp: time.Now().UnixMilli()},
+		{Role: RoleAssistant, Content: []ContentBlock{
+			ThinkingContent{Thinking: "add the numbers"},
+			ThinkingContent{Thinking: "answer is 4"},
+			TextContent{Text: "4"},
+		}, Timestamp: time.Now().UnixMilli()},
+	}
+	m.updateSession(sess, messages, 0, "run-1")
+
+	reloaded, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := reloaded.GetOrCreate(key)
+	history := got.GetHistory(0)
+	if len(history) != 2 || history[1].Metadata["reasoning_content"] != "add the numbers\nanswer is 4" {
+		t.Fatalf("persisted history = %#v", history)
+	}
+	providerMsgs := convertToProviderMessages(sessionMessagesToAgentMessages(history))
+	if providerMsgs[1].ReasoningContent != "add the numbers\nanswer is 4" || providerMsgs[1].Content != "4" {
+		t.Fatalf("provider message after reload = %#v", providerMsgs[1])
+	}
+}
+
+func TestSessionHistoryKeepsExternalizedImages(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessMgr.SetMediaInlineMax(8)
+	encoded := base64.StdEncoding.EncodeToString([]byte("a fairly large png payload"))
+	sess, _ := sessMgr.GetOrCreate("agent:main:main")
+	sess.AddMessage(session.Message{Role: "user", Content: "look", Media: []session.Media{{Type: "image", Base64: encoded, MimeType: "image/png"}}, Timestamp: time.Now()})
+	if sess.GetHistory(0)[0].Media[0].Ref == "" {
+		t.Fatal("image was not externalized")
+	}
+
+	msgs := sessionMessagesToAgentMessages(sess.GetHydratedHistory(0))
+	if len(msgs) != 1 || len(msgs[0].Content) != 2 {
+		t.Fatalf("messages = %#v", msgs)
+	}
+	img, ok := msgs[0].Content[1].(ImageContent)
+	if !ok || img.Data != encoded || img.MimeType != "image/png" {
+		t.Fatalf("image block = %#v", msgs[0].Content[1])
+	}
+}
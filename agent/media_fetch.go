@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultFetchMediaMaxBytes 入站媒体下载的默认大小上限
+	defaultFetchMediaMaxBytes = 10 << 20
+	// defaultFetchMediaTimeout 入站媒体下载的默认超时
+	defaultFetchMediaTimeout = 15 * time.Second
+	// mediaCacheTTL 下载结果的缓存时长（同一图片常在多条消息或重试中重复出现）
+	mediaCacheTTL = 5 * time.Minute
+	// mediaCacheMaxEntries 缓存条目上限
+	mediaCacheMaxEntries = 32
+)
+
+// mediaFetcher 将入站 URL 媒体下载为 base64：仅允许 http(s)，拒绝连接回环/内网/链路本地地址（防 SSRF），
+// 限制大小与超时，并短暂缓存结果；零值可用
+type mediaFetcher struct {
+	mu    sync.Mutex
+	cache map[string]fetchedMedia
+	// allowPrivate 仅测试使用：允许连接本地地址
+	allowPrivate bool
+}
+
+// fetchedMedia 下载后的媒体
+type fetchedMedia struct {
+	data     string // base64
+	mimeType string
+	expires  time.Time
+}
+
+// fetchInboundMedia 开启 channels.fetch_media 时把消息中仅有 URL 的图片下载为 base64（下载失败时保留 URL），返回是否有改动。
+// 在会话 lane 内执行：慢速主机只阻塞本会话，不阻塞入站消息分发
+func (m *AgentManager) fetchInboundMedia(ctx context.Context, msg *AgentMessage) bool {
+	changed := false
+	for i, block := range msg.Content {
+		img, ok := block.(ImageContent)
+		if !ok {
+			continue
+		}
+		if fetched := m.fetchImage(ctx, img); fetched.Data != img.Data {
+			if !changed {
+				// 不修改调用方共享的 Content 底层数组
+				msg.Content = append([]ContentBlock(nil), msg.Content...)
+				changed = true
+			}
+			msg.Content[i] = fetched
+		}
+	}
+	return changed
+}
+
+// fetchImage 开启 channels.fetch_media 时把仅有 URL 的图片下载为 base64，未开启或下载失败时原样返回
+func (m *AgentManager) fetchImage(ctx context.Context, img ImageContent) ImageContent {
+	if img.Data != "" || img.URL == "" {
+		return img
+	}
+	cfg := config.Get()
+	if cfg == nil || !cfg.Channels.FetchMedia {
+		return img
+	}
+	maxBytes := cfg.Channels.FetchMediaMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultFetchMediaMaxBytes
+	}
+	timeout := time.Duration(cfg.Channels.FetchMediaTimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultFetchMediaTimeout
+	}
+
+	fetched, err := m.mediaFetcher.fetch(ctx, img.URL, maxBytes, timeout)
+	if err != nil {
+		logger.Warn("Failed to fetch inbound media, passing URL through",
+			zap.String("url", img.URL),
+			zap.Error(err))
+		return img
+	}
+	img.Data = fetched.data
+	img.MimeType = fetched.mimeType
+	return img
+}
+
+// fetch 下载 rawURL 并返回 base64 内容；命中缓存时直接返回
+func (f *mediaFetcher) fetch(ctx context.Context, rawURL string, maxBytes int64, timeout time.Duration) (fetchedMedia, error) {
+	now := time.Now()
+	f.mu.Lock()
+	if cached, ok := f.cache[rawURL]; ok && now.Before(cached.expires) {
+		f.mu.Unlock()
+		return cached, nil
+	}
+	f.mu.Unlock()
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fetchedMedia{}, fmt.Errorf("unsupported media URL")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fetchedMedia{}, err
+	}
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return fetchedMedia{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fetchedMedia{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return fetchedMedia{}, fmt.Errorf("media too large: %d bytes (limit %d)", resp.ContentLength, maxBytes)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return fetchedMedia{}, err
+	}
+	if int64(len(body)) > maxBytes {
+		return fetchedMedia{}, fmt.Errorf("media too large: exceeds %d bytes", maxBytes)
+	}
+
+	mimeType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	mimeType = strings.TrimSpace(mimeType)
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(body)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return fetchedMedia{}, fmt.Errorf("not an image: %s", mimeType)
+	}
+
+	fetched := fetchedMedia{
+		data:     base64.StdEncoding.EncodeToString(body),
+		mimeType: mimeType,
+		expires:  now.Add(mediaCacheTTL),
+	}
+	f.store(rawURL, fetched, now)
+	return fetched, nil
+}
+
+// store 写入缓存，先清理过期条目，仍超出上限时清空
+func (f *mediaFetcher) store(key string, media fetchedMedia, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = make(map[string]fetchedMedia)
+	}
+	for k, v := range f.cache {
+		if !now.Before(v.expires) {
+			delete(f.cache, k)
+		}
+	}
+	if len(f.cache) >= mediaCacheMaxEntries {
+		clear(f.cache)
+	}
+	f.cache[key] = media
+}
+
+// client 返回在建立连接时校验目标 IP 的 HTTP 客户端（重定向与 DNS 重绑定同样受限）
+func (f *mediaFetcher) client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !f.allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to fetch media from non-public address %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}
+}
+
+// cgnatNet 运营商级 NAT 共享地址段（RFC 6598），常用于云厂商内网与 Tailscale 等
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP 是否为公网地址
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatNet.Contains(ip))
+}
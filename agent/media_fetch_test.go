@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestFetchImageDownloadsURLWhenEnabled(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write(png)
+	}))
+	defer srv.Close()
+
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	m := &AgentManager{}
+	media := ImageContent{URL: srv.URL + "/cat.png"}
+	if img := m.fetchImage(context.Background(), media); img.Data != "" || hits.Load() != 0 {
+		t.Fatalf("media fetched with fetch_media disabled: %+v", img)
+	}
+
+	config.Set(&config.Config{Channels: config.ChannelsConfig{FetchMedia: true}})
+	// 默认拒绝本地地址
+	if img := m.fetchImage(context.Background(), media); img.Data != "" || img.URL != media.URL {
+		t.Fatalf("loopback URL should not be fetched: %+v", img)
+	}
+
+	m.mediaFetcher.allowPrivate = true
+	img := m.fetchImage(context.Background(), media)
+	if img.Data != base64.StdEncoding.EncodeToString(png) || img.MimeType != "image/png" {
+		t.Fatalf("unexpected image: %+v", img)
+	}
+	m.fetchImage(context.Background(), media)
+	if hits.Load() != 1 {
+		t.Errorf("expected cached download, got %d requests", hits.Load())
+	}
+
+	config.Set(&config.Config{Channels: config.ChannelsConfig{FetchMedia: true, FetchMediaMaxBytes: 4}})
+	big := ImageContent{URL: srv.URL + "/big.png"}
+	if img := m.fetchImage(context.Background(), big); img.Data != "" {
+		t.Errorf("oversized media should not be inlined")
+	}
+}
+
+func TestFetchInboundMediaReplacesURLImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(png)
+	}))
+	defer srv.Close()
+
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Channels: config.ChannelsConfig{FetchMedia: true}})
+
+	m := &AgentManager{}
+	m.mediaFetcher.allowPrivate = true
+	content := []ContentBlock{TextContent{Text: "look"}, ImageContent{URL: srv.URL + "/cat.png"}}
+	msg := AgentMessage{Role: RoleUser, Content: content}
+	if !m.fetchInboundMedia(context.Background(), &msg) {
+		t.Fatal("fetchInboundMedia() = false, want image replaced")
+	}
+	if img, ok := msg.Content[1].(ImageContent); !ok || img.Data == "" {
+		t.Fatalf("image not inlined: %+v", msg.Content[1])
+	}
+	if img := content[1].(ImageContent); img.Data != "" {
+		t.Error("original content slice was modified")
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "100.64.0.1", "100.127.255.254", "::1", "fe80::1"} {
+		if isPublicIP(net.ParseIP(addr)) {
+			t.Errorf("isPublicIP(%s) = true, want false", addr)
+		}
+	}
+	for _, addr := range []string{"8.8.8.8", "100.128.0.1", "2606:4700::1111"} {
+		if !isPublicIP(net.ParseIP(addr)) {
+			t.Errorf("isPublicIP(%s) = false, want true", addr)
+		}
+	}
+}
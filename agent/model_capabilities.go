@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/i18n"
+)
+
+// ModelCapabilities 模型能力
+type ModelCapabilities struct {
+	Vision    bool `json:"vision"`
+	Tools     bool `json:"tools"`
+	Reasoning bool `json:"reasoning"`
+}
+
+// modelCapabilityRule 内置能力表的一条规则：模型名（小写）包含 match 时生效
+type modelCapabilityRule struct {
+	match string
+	caps  ModelCapabilities
+}
+
+// builtinModelCapabilities 常见模型的能力，按顺序匹配第一条；未匹配的模型视为支持图片与工具（不拦截，交给 provider 判断）
+var builtinModelCapabilities = []modelCapabilityRule{
+	{"-vl", ModelCapabilities{Vision: true, Tools: true}},
+	{"vision", ModelCapabilities{Vision: true, Tools: true}},
+	{"glm-4v", ModelCapabilities{Vision: true, Tools: true}},
+	{"deepseek-reasoner", ModelCapabilities{Tools: true, Reasoning: true}},
+	{"deepseek-r1", ModelCapabilities{Tools: true, Reasoning: true}},
+	{"deepseek", ModelCapabilities{Tools: true}},
+	{"gpt-3.5", ModelCapabilities{Tools: true}},
+	{"o1-mini", ModelCapabilities{Reasoning: true}},
+	{"o3-mini", ModelCapabilities{Tools: true, Reasoning: true}},
+	{"qwen-turbo", ModelCapabilities{Tools: true}},
+	{"qwen-plus", ModelCapabilities{Tools: true}},
+	{"qwen-max", ModelCapabilities{Tools: true}},
+	{"moonshot-v1", ModelCapabilities{Tools: true}},
+	{"gpt-5", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"gpt-4o", ModelCapabilities{Vision: true, Tools: true}},
+	{"gpt-4.1", ModelCapabilities{Vision: true, Tools: true}},
+	{"o1", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"o3", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"o4", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"claude-3-7", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"claude-sonnet-4", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"claude-opus-4", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"claude", ModelCapabilities{Vision: true, Tools: true}},
+	{"gemini-2.5", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+	{"gemini", ModelCapabilities{Vision: true, Tools: true}},
+	{"thinking", ModelCapabilities{Vision: true, Tools: true, Reasoning: true}},
+}
+
+// LookupModelCapabilities 返回模型能力：内置表打底，agents.defaults.model_capabilities 中同名模型的字段覆盖
+func LookupModelCapabilities(model string) ModelCapabilities {
+	caps := ModelCapabilities{Vision: true, Tools: true}
+	name := strings.ToLower(strings.TrimSpace(model))
+	for _, rule := range builtinModelCapabilities {
+		if strings.Contains(name, rule.match) {
+			caps = rule.caps
+			break
+		}
+	}
+	if cfg := config.Get(); cfg != nil {
+		if o := modelCapabilityOverride(cfg.Agents.Defaults.ModelCapabilities, model); o != nil {
+			if o.Vision != nil {
+				caps.Vision = *o.Vision
+			}
+			if o.Tools != nil {
+				caps.Tools = *o.Tools
+			}
+			if o.Reasoning != nil {
+				caps.Reasoning = *o.Reasoning
+			}
+		}
+	}
+	return caps
+}
+
+// modelCapabilityOverride 按模型名（不区分大小写）查找 model_capabilities 覆盖；viper 会把配置文件中的 map 键转为小写
+func modelCapabilityOverride(overrides map[string]*config.ModelCapabilityConfig, model string) *config.ModelCapabilityConfig {
+	model = strings.TrimSpace(model)
+	if o, ok := overrides[model]; ok {
+		return o
+	}
+	for name, o := range overrides {
+		if strings.EqualFold(name, model) {
+			return o
+		}
+	}
+	return nil
+}
+
+// Has 是否具备指定能力（config.ModelCapabilityVision 等）
+func (c ModelCapabilities) Has(capability string) bool {
+	switch capability {
+	case config.ModelCapabilityVision:
+		return c.Vision
+	case config.ModelCapabilityTools:
+		return c.Tools
+	case config.ModelCapabilityReasoning:
+		return c.Reasoning
+	}
+	return true
+}
+
+// ModelCapabilityError 本次运行需要的能力当前模型不具备且未配置可用模型，Error() 为面向用户的提示
+type ModelCapabilityError struct {
+	Model      string
+	Capability string
+}
+
+func (e *ModelCapabilityError) Error() string {
+	if e.Capability == config.ModelCapabilityVision {
+		return i18n.T(i18n.RunModelNoVision, e.Model)
+	}
+	return i18n.T(i18n.RunModelCapability, e.Model, e.Capability)
+}
+
+// requiredCapabilities 按消息内容判断本次运行需要的能力：含图片需要 vision。
+// 工具只在配置了 capability_models.tools 时才要求（改用该模型）；否则不支持工具的模型本次运行不发送工具定义
+func requiredCapabilities(state *AgentState) []string {
+	var required []string
+	if messagesHaveImages(state.Messages) {
+		required = append(required, config.ModelCapabilityVision)
+	}
+	if len(state.Tools) > 0 && capabilityRoute(config.ModelCapabilityTools) != "" {
+		required = append(required, config.ModelCapabilityTools)
+	}
+	return required
+}
+
+// capabilityRoute 缺少某能力时改用的模型（agents.defaults.capability_models），未配置时为空
+func capabilityRoute(capability string) string {
+	cfg := config.Get()
+	if cfg == nil {
+		return ""
+	}
+	return strings.TrimSpace(cfg.Agents.Defaults.CapabilityModels[capability])
+}
+
+func messagesHaveImages(msgs []AgentMessage) bool {
+	for _, msg := range msgs {
+		for _, block := range msg.Content {
+			if img, ok := block.(ImageContent); ok && (img.Data != "" || img.URL != "") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveCapabilityModel 检查当前模型是否具备本次运行所需能力；缺少时改用 agents.defaults.capability_models 中配置的模型，
+// 未配置（或配置的模型同样缺少）时返回 ModelCapabilityError。返回空字符串表示无需切换
+func resolveCapabilityModel(model string, required []string) (string, error) {
+	if len(required) == 0 {
+		return "", nil
+	}
+	caps := LookupModelCapabilities(model)
+	routed := ""
+	for _, capability := range required {
+		if caps.Has(capability) {
+			continue
+		}
+		candidate := capabilityRoute(capability)
+		if candidate == "" || !satisfiesAll(candidate, required) {
+			return "", &ModelCapabilityError{Model: model, Capability: capability}
+		}
+		routed = candidate
+		caps = LookupModelCapabilities(candidate)
+	}
+	return routed, nil
+}
+
+func satisfiesAll(model string, required []string) bool {
+	caps := LookupModelCapabilities(model)
+	for _, capability := range required {
+		if !caps.Has(capability) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/i18n"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// modelRecordingProvider 记录每次调用使用的模型；收到图片时模拟 provider 的原始报错
+type modelRecordingProvider struct {
+	fakeChatProvider
+	models []string
+}
+
+func (p *modelRecordingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	opts := &providers.ChatOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	p.models = append(p.models, opts.Model)
+	for _, m := range messages {
+		if len(m.Images) > 0 && opts.Model == "deepseek-chat" {
+			return nil, errors.New("400 Bad Request: unknown variant `image_url`")
+		}
+	}
+	return &providers.Response{Content: "done"}, nil
+}
+
+func imagePrompt() []AgentMessage {
+	return []AgentMessage{{Role: RoleUser, Content: []ContentBlock{
+		TextContent{Text: "what is this?"},
+		ImageContent{Data: "aGVsbG8=", MimeType: "image/png"},
+	}}}
+}
+
+func TestImageToTextOnlyModelReturnsFriendlyMessage(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	provider := &modelRecordingProvider{}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, Model: "deepseek-chat", MaxIterations: 3}, NewAgentState())
+	_, err := o.Run(context.Background(), imagePrompt(), nil)
+
+	var capErr *ModelCapabilityError
+	if !errors.As(err, &capErr) || capErr.Capability != config.ModelCapabilityVision {
+		t.Fatalf("Run() error = %v, want vision capability error", err)
+	}
+	if len(provider.models) != 0 {
+		t.Fatalf("provider called %d times, want none", len(provider.models))
+	}
+	if got, want := friendlyRunErrorMessage(err), i18n.T(i18n.RunModelNoVision, "deepseek-chat"); got != want {
+		t.Fatalf("friendly message = %q, want %q", got, want)
+	}
+}
+
+func TestImageRoutedToConfiguredVisionModel(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	vision := false
+	config.Set(&config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{
+		ModelCapabilities: map[string]*config.ModelCapabilityConfig{"my-local-model": {Vision: &vision}},
+		CapabilityModels:  map[string]string{config.ModelCapabilityVision: "gpt-4o"},
+	}}})
+
+	provider := &modelRecordingProvider{}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, Model: "my-local-model", MaxIterations: 3}, NewAgentState())
+	if _, err := o.Run(context.Background(), imagePrompt(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.models) != 1 || provider.models[0] != "gpt-4o" {
+		t.Fatalf("models used = %v, want [gpt-4o]", provider.models)
+	}
+
+	// 纯文本消息仍使用主模型
+	if _, err := o.Run(context.Background(), []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if provider.models[1] != "my-local-model" {
+		t.Fatalf("models used = %v, want text run on primary model", provider.models)
+	}
+}
+
+// toolDefsRecordingProvider 记录每次调用收到的工具定义数
+type toolDefsRecordingProvider struct {
+	fakeChatProvider
+	toolCounts []int
+}
+
+func (p *toolDefsRecordingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.toolCounts = append(p.toolCounts, len(tools))
+	return &providers.Response{Content: "done"}, nil
+}
+
+func TestModelWithoutToolsRunsWithoutToolDefinitions(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	provider := &toolDefsRecordingProvider{}
+	state := NewAgentState()
+	state.Tools = []Tool{&fakeTool{name: "exec"}}
+	hi := []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}}}
+
+	o := NewOrchestrator(&LoopConfig{Provider: provider, Model: "o1-mini", MaxIterations: 3}, state)
+	if _, err := o.Run(context.Background(), hi, nil); err != nil {
+		t.Fatalf("o1-mini run failed: %v", err)
+	}
+	o = NewOrchestrator(&LoopConfig{Provider: provider, Model: "gpt-4o", MaxIterations: 3}, state)
+	if _, err := o.Run(context.Background(), hi, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.toolCounts) != 2 || provider.toolCounts[0] != 0 || provider.toolCounts[1] != 1 {
+		t.Fatalf("tool definitions sent = %v, want [0 1]", provider.toolCounts)
+	}
+}
+
+func TestModelCapabilitiesOverrideIgnoresCase(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	vision := false
+	// viper 读取配置文件时 map 键被转为小写
+	config.Set(&config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{
+		ModelCapabilities: map[string]*config.ModelCapabilityConfig{"my-local-model": {Vision: &vision}},
+	}}})
+	if LookupModelCapabilities("My-Local-Model").Vision {
+		t.Fatal("override for my-local-model not applied to My-Local-Model")
+	}
+}
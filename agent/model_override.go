@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// KnownModel 判断模型是否可用于会话覆盖：配置中出现过的模型（默认/备用/各 Agent/分身/能力路由）、
+// 价格表（内置与 providers.pricing）中的模型（完全匹配或仅多出日期后缀），或已缓存的提供商模型目录中的模型
+func KnownModel(cfg *config.Config, model string) bool {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return false
+	}
+	if configuredModel(cfg, model) {
+		return true
+	}
+	if cfg != nil {
+		if providers.NewPricingTable(cfg.Providers.Pricing).HasModel(model) {
+			return true
+		}
+	} else if providers.NewPricingTable(nil).HasModel(model) {
+		return true
+	}
+	return providers.CatalogHasModel(model)
+}
+
+// configuredModel 判断模型是否出现在配置中（默认/备用/各 Agent/分身/能力路由），忽略大小写
+func configuredModel(cfg *config.Config, model string) bool {
+	if cfg == nil || model == "" {
+		return false
+	}
+	d := cfg.Agents.Defaults
+	configured := []string{d.Model, d.FallbackModel}
+	if d.Subagents != nil {
+		configured = append(configured, d.Subagents.Model)
+	}
+	for _, a := range cfg.Agents.List {
+		configured = append(configured, a.Model)
+	}
+	for _, m := range d.CapabilityModels {
+		configured = append(configured, m)
+	}
+	for m := range d.ModelCapabilities {
+		configured = append(configured, m)
+	}
+	for _, m := range configured {
+		if strings.EqualFold(strings.TrimSpace(m), model) {
+			return true
+		}
+	}
+	return false
+}
+
+// 指标与日志中的 model 标签：未指定模型时使用 provider 默认模型；未配置的模型归为 other
+const (
+	providerDefaultModelLabel = "(provider default)"
+	otherModelLabel           = "other"
+)
+
+// metricsModelLabel 指标的 model 标签：本 Orchestrator 的模型 / 备用模型或配置中出现过的模型原样使用，
+// 其余（会话 /model 覆盖等用户指定的模型）归为 "other"，避免标签基数无界
+func (o *Orchestrator) metricsModelLabel(model string) string {
+	if model == providerDefaultModelLabel ||
+		strings.EqualFold(model, strings.TrimSpace(o.config.Model)) ||
+		strings.EqualFold(model, strings.TrimSpace(o.config.FallbackModel)) ||
+		configuredModel(config.Get(), model) {
+		return model
+	}
+	return otherModelLabel
+}
+
+// sessionModelOverride 会话元数据中的 modelOverride，未设置或不是已知模型时为空（withModelOverride 同样忽略未知模型）
+func sessionModelOverride(sess *session.Session) string {
+	if sess == nil {
+		return ""
+	}
+	override, _ := sess.GetMetadata(session.MetadataModelOverride).(string)
+	override = strings.TrimSpace(override)
+	if override == "" || !KnownModel(config.Get(), override) {
+		return ""
+	}
+	return override
+}
+
+// runModel 运行实际使用的模型：会话 modelOverride、子 agent 会话的 agents.defaults.subagents.model，否则为 Agent 的模型
+func (m *AgentManager) runModel(agent *Agent, sessionKey string, sess *session.Session) string {
+	if override := sessionModelOverride(sess); override != "" {
+		return override
+	}
+	if session.IsSubagentSessionKey(sessionKey) {
+		if cfg := config.Get(); cfg != nil && cfg.Agents.Defaults.Subagents != nil {
+			if model := strings.TrimSpace(cfg.Agents.Defaults.Subagents.Model); model != "" {
+				return model
+			}
+		}
+	}
+	if agent == nil || agent.GetOrchestrator() == nil {
+		return ""
+	}
+	return strings.TrimSpace(agent.GetOrchestrator().config.Model)
+}
+
+// withModelOverride 会话元数据 modelOverride（sessions.patch model / 入站命令 /model）为已知模型时覆盖本次运行的模型；
+// 未知模型记录告警后忽略，沿用 Agent（或分身配置）的模型
+func withModelOverride(opts *RunOptions, sess *session.Session) *RunOptions {
+	if sess == nil {
+		return opts
+	}
+	override, _ := sess.GetMetadata(session.MetadataModelOverride).(string)
+	override = strings.TrimSpace(override)
+	if override == "" {
+		return opts
+	}
+	if !KnownModel(config.Get(), override) {
+		logger.Warn("Ignoring unknown session model override",
+			zap.String("session_key", sess.Key),
+			zap.String("model", override))
+		return opts
+	}
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	opts.Model = override
+	logger.Info("Using session model override",
+		zap.String("session_key", sess.Key),
+		zap.String("model", override))
+	return opts
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestWithModelOverride(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Agents: config.AgentsConfig{
+		Defaults: config.AgentDefaults{Model: "main-model"},
+		List:     []config.AgentConfig{{ID: "cheap", Model: "local-small"}},
+	}})
+
+	sess := &session.Session{Key: "agent:main:main", Metadata: map[string]interface{}{}}
+	if opts := withModelOverride(nil, sess); opts != nil {
+		t.Fatalf("no override: %+v", opts)
+	}
+
+	sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: " local-small "})
+	if opts := withModelOverride(nil, sess); opts == nil || opts.Model != "local-small" {
+		t.Fatalf("configured override: %+v", opts)
+	}
+
+	// 价格表中的模型（含带日期的变体）同样可用；子 agent 的其他选项保留
+	sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: "claude-3-opus-20240229"})
+	if opts := withModelOverride(&RunOptions{MaxIterations: 20}, sess); opts.Model != "claude-3-opus-20240229" || opts.MaxIterations != 20 {
+		t.Fatalf("priced override: %+v", opts)
+	}
+
+	// 价格表条目名的任意扩展不算已知模型
+	sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: "gpt-4o-evil"})
+	if opts := withModelOverride(nil, sess); opts != nil {
+		t.Fatalf("prefix-only match should be ignored: %+v", opts)
+	}
+
+	sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: "no-such-model"})
+	if opts := withModelOverride(nil, sess); opts != nil {
+		t.Fatalf("unknown override should be ignored: %+v", opts)
+	}
+}
@@ -2,21 +2,33 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/metrics"
 	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
 	"github.com/smallnest/goclaw/types"
 	"go.uber.org/zap"
 )
 
 // RunOptions 单次运行的可选覆盖（如子 agent 使用 agents.defaults.subagents 的 model/max_iterations）
 type RunOptions struct {
-	Model          string // 覆盖本次调用的模型，空表示用 config.Model
-	MaxIterations  int    // 覆盖本次最大迭代数，<=0 表示用 config.MaxIterations
+	Model         string           // 覆盖本次调用的模型，空表示用 config.Model
+	MaxIterations int              // 覆盖本次最大迭代数，<=0 表示用 config.MaxIterations
+	DebugPrompts  bool             // 以 info 级别记录本次运行发给 LLM 的完整 prompt（会话元数据 debugPrompts）
+	ApproveTool   ToolApprovalFunc // 工具执行前的审批（approvals.behavior 为 manual / prompt），nil 表示不审批
+	ThinkingLevel string           // 思考等级（会话元数据 thinkingLevel / reasoningLevel），仅对支持推理的模型生效
+	VerboseLevel  string           // 回复详略（会话元数据 verboseLevel），在 system prompt 中追加对应指引
+	DryRun        bool             // 只组装请求不调用 LLM：发出 dry_run 事件并以请求 JSON 作为 assistant 回复（agent.preview）
 }
 
 // Orchestrator manages the agent execution loop
@@ -28,7 +40,27 @@ type Orchestrator struct {
 	cancelFunc      context.CancelFunc
 	progressTracker *ProgressTracker
 	runOpts         *RunOptions   // 本次 Run 的覆盖，仅 Run 内有效
+	skills          []*Skill      // 本次 Run 开始时的技能快照，运行中技能变更不影响当前 Run
 	lastLLMCallTime time.Time     // 上次调用 LLM 的时间，用于 model_request_interval 间隔
+	modelFallback   string        // 主模型不可用后本次 Run 改用的备用模型，空表示未切换
+	capabilityModel string        // 主模型缺少本次 Run 所需能力（如图片）时改用的模型（capability_models），空表示未切换
+	toolsExecuted   int           // 本次 Run 已执行完成的工具调用数，Run 失败后据此判断能否整次重试
+
+	// 事件发送背压状态（emit）
+	emitMu          sync.Mutex
+	pendingDelta    string // 通道满时尚未发出的 message_delta 内容
+	coalescedDeltas int64
+	droppedEvents   int64
+	consumerStalled bool // 关键事件等待超时后置位，避免每个事件都阻塞
+
+	// 本次 Run 的 steering 队列（Steer）
+	steerMu    sync.Mutex
+	steerQueue []AgentMessage
+	running    bool
+
+	// 本次 Run 的 token 用量（provider 上报，未上报时估算），Run 结束后由 Usage 读取
+	usageMu sync.Mutex
+	usage   session.TokenUsage
 }
 
 // NewOrchestrator creates a new agent orchestrator
@@ -37,6 +69,7 @@ func NewOrchestrator(config *LoopConfig, initialState *AgentState) *Orchestrator
 	return &Orchestrator{
 		config:          config,
 		state:           initialState,
+		skills:          config.CurrentSkills(),
 		eventChan:       make(chan *Event, 512),
 		progressTracker: NewProgressTracker(initialState.SessionKey),
 	}
@@ -45,7 +78,17 @@ func NewOrchestrator(config *LoopConfig, initialState *AgentState) *Orchestrator
 // Run starts the agent loop with initial prompts. opts is optional (e.g. subagent run uses subagents model/max_iterations).
 func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage, opts *RunOptions) ([]AgentMessage, error) {
 	o.runOpts = opts
-	defer func() { o.runOpts = nil }()
+	o.modelFallback = ""
+	defer func() { o.runOpts = nil; o.modelFallback = "" }()
+	o.usageMu.Lock()
+	o.usage = session.TokenUsage{}
+	o.usageMu.Unlock()
+	o.capabilityModel = ""
+	defer func() { o.capabilityModel = "" }()
+	o.toolsExecuted = 0
+	o.skills = o.config.CurrentSkills()
+	o.setRunning(true)
+	defer o.setRunning(false)
 
 	logger.Info("=== Orchestrator Run Start ===",
 		zap.Int("prompts_count", len(prompts)))
@@ -58,6 +101,22 @@ func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage, opts *Ru
 	copy(newMessages, prompts)
 	currentState := o.state.Clone()
 	currentState.AddMessages(newMessages)
+	currentState.LoadedSkills = filterLoadedSkills(currentState.LoadedSkills, o.skills)
+
+	// 按消息内容检查模型能力（图片需要 vision 等）：缺少时改用 capability_models 中的模型，未配置则直接返回明确提示而不是 provider 报错
+	routed, err := resolveCapabilityModel(o.effectiveModel(), requiredCapabilities(currentState))
+	if err != nil {
+		logger.Warn("Model lacks capability required by run", zap.Error(err))
+		o.emitErrorEnd(currentState, err)
+		return currentState.Messages, err
+	}
+	if routed != "" {
+		logger.Info("Routing run to capable model",
+			zap.String("session_key", currentState.SessionKey),
+			zap.String("model", o.effectiveModel()),
+			zap.String("routed_model", routed))
+		o.capabilityModel = routed
+	}
 
 	// Start progress tracking（子 agent 可通过 opts.MaxIterations 覆盖）
 	maxIter := o.effectiveMaxIterations()
@@ -87,6 +146,15 @@ func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage, opts *Ru
 	}
 	o.emit(endEvent)
 
+	o.emitMu.Lock()
+	if o.droppedEvents > 0 || o.coalescedDeltas > 0 {
+		logger.Warn("Orchestrator event backpressure",
+			zap.String("session_key", o.state.SessionKey),
+			zap.Int64("dropped_events", o.droppedEvents),
+			zap.Int64("coalesced_deltas", o.coalescedDeltas))
+	}
+	o.emitMu.Unlock()
+
 	cancel()
 	if err != nil {
 		return nil, fmt.Errorf("agent loop failed: %w", err)
@@ -95,6 +163,14 @@ func (o *Orchestrator) Run(ctx context.Context, prompts []AgentMessage, opts *Ru
 	return finalMessages, nil
 }
 
+// customSystemPrompt 本次运行展开模板变量后的 agent system_prompt，未配置时为空
+func (o *Orchestrator) customSystemPrompt() string {
+	if o.config.SystemPrompt == "" {
+		return ""
+	}
+	return o.config.ContextBuilder.ExpandSystemPrompt(o.config.SystemPrompt, o.config.SystemPromptVars)
+}
+
 // effectiveMaxIterations 返回本次运行的有效最大迭代数（含 RunOptions 覆盖）
 func (o *Orchestrator) effectiveMaxIterations() int {
 	if o.runOpts != nil && o.runOpts.MaxIterations > 0 {
@@ -109,6 +185,12 @@ func (o *Orchestrator) effectiveMaxIterations() int {
 
 // effectiveModel 返回本次运行的有效模型（子 agent 可通过 RunOptions.Model 覆盖）
 func (o *Orchestrator) effectiveModel() string {
+	if o.modelFallback != "" {
+		return o.modelFallback
+	}
+	if o.capabilityModel != "" {
+		return o.capabilityModel
+	}
 	if o.runOpts != nil && strings.TrimSpace(o.runOpts.Model) != "" {
 		return strings.TrimSpace(o.runOpts.Model)
 	}
@@ -124,6 +206,7 @@ func (o *Orchestrator) runLoop(ctx context.Context, state *AgentState) ([]AgentM
 
 	maxIter := o.effectiveMaxIterations()
 	iteration := 0
+	toolErrors := newToolErrorTracker(o.config.ToolErrorPolicy, o.config.ToolErrorMaxConsecutive)
 
 	// Outer loop: continues when queued follow-up messages arrive
 	for {
@@ -174,13 +257,14 @@ func (o *Orchestrator) runLoop(ctx context.Context, state *AgentState) ([]AgentM
 			reserve := EffectiveReserveTokens(o.config.ReserveTokens)
 			summarizeFunc := func(ctx context.Context, prompt string) (string, error) {
 				msgs := []providers.Message{
-					{Role: "system", Content: "You are a summarizer. Output a concise summary of the following conversation. Preserve key decisions, TODOs, and constraints. Output only the summary, no preamble."},
+					{Role: "system", Content: summarizerSystemPrompt},
 					{Role: "user", Content: prompt},
 				}
 				resp, callErr := o.config.Provider.Chat(ctx, msgs, nil)
 				if callErr != nil {
 					return "", callErr
 				}
+				o.recordUsage(msgs, resp.Content, &resp.Usage)
 				return resp.Content, nil
 			}
 			for attempt := 0; attempt <= maxContextOverflowRetries; attempt++ {
@@ -197,7 +281,7 @@ func (o *Orchestrator) runLoop(ctx context.Context, state *AgentState) ([]AgentM
 					continue
 				}
 				if attempt == 1 {
-					compacted, compactErr := CompactWithSummary(ctx, state.Messages, contextWindow, reserve, summarizeFunc)
+					compacted, compactErr := CompactWithChunkedSummary(ctx, state.Messages, contextWindow, reserve, o.config.SummarizerContextTokens, summarizeFunc)
 					if compactErr != nil {
 						logger.Warn("Compaction summarization failed, giving up", zap.Error(compactErr))
 						break
@@ -216,7 +300,7 @@ func (o *Orchestrator) runLoop(ctx context.Context, state *AgentState) ([]AgentM
 			// LLM 返回空回复且无 tool calls 时视为异常，避免静默结束、只交互一次
 			toolCalls := extractToolCalls(assistantMsg)
 			if strings.TrimSpace(extractTextContent(assistantMsg)) == "" && len(toolCalls) == 0 {
-				emptyErr := fmt.Errorf("LLM 返回了空回复，请稍后重试或检查模型与代理配置（若为 9router/心流可尝试 model_request_interval_seconds 或更换模型）")
+				emptyErr := errors.New(i18n.T(i18n.RunEmptyReply))
 				logger.Warn("Empty LLM response, treating as error", zap.Error(emptyErr))
 				o.emitErrorEnd(state, emptyErr)
 				return state.Messages, emptyErr
@@ -238,6 +322,14 @@ func (o *Orchestrator) runLoop(ctx context.Context, state *AgentState) ([]AgentM
 				for _, result := range results {
 					state.AddMessage(result)
 				}
+				o.toolsExecuted += len(results)
+
+				// 工具报错策略：同一工具反复报相同错误时提前结束，避免空转到 max_iterations
+				if stopErr := toolErrors.observe(results); stopErr != nil {
+					logger.Warn("Stopping run after tool errors", zap.Error(stopErr))
+					o.emitErrorEnd(state, stopErr)
+					return state.Messages, stopErr
+				}
 
 				// If steering messages arrived, skip remaining tools
 				if steeringAfterTools {
@@ -279,7 +371,7 @@ func (o *Orchestrator) streamAssistantResponseWithRateLimitRetry(ctx context.Con
 	var lastErr error
 	for attempt := 0; attempt <= maxRateLimitRetries; attempt++ {
 		// 若配置了模型请求最小间隔，则等待至满足间隔后再调用（缓解同一会话内连续请求导致 406）
-		if o.config.ModelRequestInterval > 0 {
+		if o.config.ModelRequestInterval > 0 && !o.dryRun() {
 			elapsed := time.Since(o.lastLLMCallTime)
 			if elapsed < o.config.ModelRequestInterval {
 				wait := o.config.ModelRequestInterval - elapsed
@@ -293,6 +385,9 @@ func (o *Orchestrator) streamAssistantResponseWithRateLimitRetry(ctx context.Con
 			o.lastLLMCallTime = time.Now()
 		}
 		msg, err := o.streamAssistantResponse(ctx, state)
+		if err != nil && o.switchToFallbackModel(err, classifier) {
+			msg, err = o.streamAssistantResponse(ctx, state)
+		}
 		if err == nil {
 			return msg, nil
 		}
@@ -305,6 +400,7 @@ func (o *Orchestrator) streamAssistantResponseWithRateLimitRetry(ctx context.Con
 			delaySec = 30
 		}
 		if attempt < maxRateLimitRetries {
+			metrics.RateLimitRetries.Inc()
 			logger.Info("Rate limit / 406, waiting before retry",
 				zap.Int("wait_seconds", delaySec),
 				zap.Int("attempt", attempt+1),
@@ -324,6 +420,95 @@ func (o *Orchestrator) streamAssistantResponseWithRateLimitRetry(ctx context.Con
 	return AgentMessage{}, lastErr
 }
 
+// switchToFallbackModel 主模型被 provider 拒绝（模型不存在/已下线）且配置了 fallback_model 时，
+// 本次 Run 剩余调用改用备用模型；每次 Run 最多切换一次
+func (o *Orchestrator) switchToFallbackModel(err error, classifier types.ErrorClassifier) bool {
+	fallback := strings.TrimSpace(o.config.FallbackModel)
+	if fallback == "" || o.modelFallback != "" {
+		return false
+	}
+	if classifier.ClassifyError(err) != types.FailoverReasonModelNotFound {
+		return false
+	}
+	primary := o.effectiveModel()
+	if strings.EqualFold(primary, fallback) {
+		return false
+	}
+	logger.Warn("Model unavailable, retrying with fallback model",
+		zap.String("session_key", o.state.SessionKey),
+		zap.String("model", primary),
+		zap.String("fallback_model", fallback),
+		zap.Error(err))
+	o.modelFallback = fallback
+	return true
+}
+
+// skillsPromptContent 构建系统提示词中的技能部分（基于本次 Run 的技能快照）
+func (o *Orchestrator) skillsPromptContent(state *AgentState) string {
+	if len(state.LoadedSkills) > 0 {
+		// Second phase: inject full content of loaded skills
+		return o.config.ContextBuilder.buildSelectedSkills(state.LoadedSkills, o.skills)
+	}
+	if len(o.skills) > 0 {
+		// First phase: inject skill summary (available skills list)
+		return o.config.ContextBuilder.buildSkillsPrompt(o.skills, PromptModeFull)
+	}
+	return ""
+}
+
+// findSkill 在本次 Run 的技能快照中查找技能
+func (o *Orchestrator) findSkill(name string) *Skill {
+	for _, skill := range o.skills {
+		if skill.Name == name || skill.Key == name {
+			return skill
+		}
+	}
+	return nil
+}
+
+// checkSkillAPIKey use_skill 选择声明 requiresApiKey 的技能时，校验其 API key 已设置
+func (o *Orchestrator) checkSkillAPIKey(tc ToolCallContent) error {
+	if tc.Name != "use_skill" {
+		return nil
+	}
+	name, _ := tc.Arguments["skill_name"].(string)
+	skill := o.findSkill(name)
+	if skill == nil || !skill.RequiresAPIKey || skill.APIKey != "" {
+		return nil
+	}
+	key := skill.Key
+	if key == "" {
+		key = skill.Name
+	}
+	return fmt.Errorf("skill %q requires an API key; set it via skills.update (skillKey=%q, apiKey=...) and try again", skill.Name, key)
+}
+
+// checkToolApproval 执行前按本次运行的审批函数确认工具调用；未获批准时返回给 LLM 的说明，批准或无需审批时返回空串
+func (o *Orchestrator) checkToolApproval(ctx context.Context, tc ToolCallContent) string {
+	if o.runOpts == nil || o.runOpts.ApproveTool == nil {
+		return ""
+	}
+	approved, reason := o.runOpts.ApproveTool(ctx, tc.ID, tc.Name, tc.Arguments)
+	if approved {
+		return ""
+	}
+	if reason == "" {
+		reason = "denied by user"
+	}
+	return fmt.Sprintf("Tool %s was not executed: %s", tc.Name, reason)
+}
+
+// skillEnv 返回已加载技能的 API key 环境变量（变量名 -> key）
+func (o *Orchestrator) skillEnv(loadedSkills []string) map[string]string {
+	env := make(map[string]string)
+	for _, name := range loadedSkills {
+		if skill := o.findSkill(name); skill != nil && skill.APIKey != "" {
+			env[skill.APIKeyEnvName()] = skill.APIKey
+		}
+	}
+	return env
+}
+
 // streamAssistantResponse calls the LLM and streams the response
 func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *AgentState) (AgentMessage, error) {
 	logger.Debug("streamAssistantResponse Start",
@@ -355,7 +540,7 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 		messages = LimitHistoryTurns(messages, maxTurns)
 		logger.Debug("Context: limited history turns", zap.Int("max_turns", maxTurns), zap.Int("messages_after", len(messages)))
 	}
-	messages = CopyMessagesWithTruncatedToolResults(messages, contextWindow)
+	messages = CopyMessagesWithTruncatedToolResults(messages, contextWindow, o.config.ToolResultTruncation)
 	if limit := contextWindow - reserve; limit > 0 {
 		estimated := EstimateMessagesTokens(messages)
 		if estimated > limit {
@@ -376,8 +561,11 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 		providerMsgs = convertToProviderMessages(messages)
 	}
 
-	// Prepare tool definitions
-	toolDefs := convertToToolDefinitions(state.Tools)
+	// Prepare tool definitions（不支持工具调用的模型如 o1-mini 不发送工具定义，只做纯对话）
+	var toolDefs []providers.ToolDefinition
+	if LookupModelCapabilities(o.effectiveModel()).Tools {
+		toolDefs = convertToToolDefinitions(state.Tools)
+	}
 
 	// Emit message start
 	o.emit(NewEvent(EventMessageStart))
@@ -387,19 +575,21 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 
 	// Build system prompt with skills if context builder is available
 	if o.config.ContextBuilder != nil {
-		skillsContent := ""
-		if len(state.LoadedSkills) > 0 {
-			// Second phase: inject full content of loaded skills
-			skillsContent = o.config.ContextBuilder.buildSelectedSkills(state.LoadedSkills, o.config.Skills)
-		} else if len(o.config.Skills) > 0 {
-			// First phase: inject skill summary (available skills list)
-			skillsContent = o.config.ContextBuilder.buildSkillsPrompt(o.config.Skills, PromptModeFull)
-		}
+		skillsContent := o.skillsPromptContent(state)
 		systemPrompt := o.config.ContextBuilder.buildSystemPromptWithSkills(skillsContent, PromptModeFull)
+		// agent 配置的 system_prompt 置于开头
+		if custom := o.customSystemPrompt(); strings.TrimSpace(custom) != "" {
+			systemPrompt = custom + "\n\n---\n\n" + systemPrompt
+		}
 		fullMessages = append(fullMessages, providers.Message{
 			Role:    "system",
 			Content: systemPrompt,
 		})
+	} else if custom := o.customSystemPrompt(); custom != "" {
+		fullMessages = append(fullMessages, providers.Message{
+			Role:    "system",
+			Content: custom,
+		})
 	} else if state.SystemPrompt != "" {
 		// Fallback to stored system prompt
 		fullMessages = append(fullMessages, providers.Message{
@@ -407,12 +597,24 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 			Content: state.SystemPrompt,
 		})
 	}
+	if o.runOpts != nil {
+		if guidance := verboseGuidance(o.runOpts.VerboseLevel); guidance != "" {
+			if len(fullMessages) > 0 {
+				fullMessages[0].Content += "\n\n" + guidance
+			} else {
+				fullMessages = append(fullMessages, providers.Message{Role: "system", Content: guidance})
+			}
+		}
+	}
 	fullMessages = append(fullMessages, providerMsgs...)
 
 	// 本次运行的有效 model（子 agent 可通过 RunOptions.Model 覆盖）
 	modelForRequest := o.effectiveModel()
 	if modelForRequest == "" || strings.EqualFold(modelForRequest, "default") {
-		modelForRequest = "(provider default)"
+		modelForRequest = providerDefaultModelLabel
+	}
+	if o.runOpts != nil && o.runOpts.DebugPrompts {
+		o.logDebugPrompt(state.SessionKey, modelForRequest, fullMessages, toolDefs)
 	}
 	logger.Info("=== Calling LLM ===",
 		zap.String("model", modelForRequest),
@@ -431,19 +633,42 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 	if o.config.MaxTokens > 0 {
 		chatOpts = append(chatOpts, providers.WithMaxTokens(o.config.MaxTokens))
 	}
+	chatOpts = append(chatOpts, o.thinkingChatOptions(o.effectiveModel())...)
+
+	if o.dryRun() {
+		return o.dryRunResponse(modelForRequest, fullMessages, toolDefs, chatOpts), nil
+	}
+
+	// 占用全局 LLM 调用名额（providers.max_concurrent_calls，主 agent 与子 agent 共用），调用结束或 ctx 取消时释放
+	ctx, releaseSlot, err := providers.AcquireCallSlot(ctx)
+	if err != nil {
+		return AgentMessage{}, fmt.Errorf("waiting for LLM call slot: %w", err)
+	}
+	defer releaseSlot()
 
 	// 检查是否支持流式输出（provider 支持且实现了 StreamingProvider 接口）
 	streamingProvider, supportsStreaming := o.config.Provider.(providers.StreamingProvider)
 	useStreaming := supportsStreaming && o.config.Provider.SupportsStreaming()
 
 	var response *providers.Response
-	var err error
+
+	// LLM 调用耗时（不含等待调用名额），按模型（未配置的模型归为 other）与成功 / 失败计入 goclaw_llm_call_duration_seconds
+	callStart := time.Now()
+	defer func() {
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		metrics.LLMCallDuration.Observe(time.Since(callStart).Seconds(), o.metricsModelLabel(modelForRequest), status)
+	}()
 
 	if useStreaming {
 		// 使用流式 API
 		logger.Debug("Using streaming API")
 		var content strings.Builder
 		var toolCalls []providers.ToolCall
+		var reasoning string
+		var reportedUsage *providers.Usage
 
 		err = streamingProvider.ChatStream(ctx, fullMessages, toolDefs, func(chunk providers.StreamChunk) {
 			if chunk.Error != nil {
@@ -460,6 +685,10 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 			if chunk.Done {
 				content.WriteString(chunk.Content)
 				toolCalls = chunk.ToolCalls
+				reasoning = chunk.ReasoningContent
+			}
+			if chunk.Usage != nil {
+				reportedUsage = chunk.Usage
 			}
 		}, chatOpts...)
 
@@ -470,10 +699,12 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 
 		// 构建响应
 		response = &providers.Response{
-			Content:      content.String(),
-			ToolCalls:    toolCalls,
-			FinishReason: "stop",
+			Content:          content.String(),
+			ReasoningContent: reasoning,
+			ToolCalls:        toolCalls,
+			FinishReason:     "stop",
 		}
+		o.recordUsage(fullMessages, response.Content, reportedUsage)
 	} else {
 		// 使用非流式 API
 		logger.Debug("Using non-streaming API")
@@ -482,6 +713,7 @@ func (o *Orchestrator) streamAssistantResponse(ctx context.Context, state *Agent
 			logger.Error("LLM call failed", zap.Error(err))
 			return AgentMessage{}, fmt.Errorf("LLM call failed: %w", err)
 		}
+		o.recordUsage(fullMessages, response.Content, &response.Usage)
 	}
 
 	logger.Info("=== LLM Response Received ===",
@@ -522,6 +754,9 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 		skillName  string // For use_skill tracking
 	}
 
+	// 已加载技能的 API key 以环境变量形式注入工具执行上下文
+	skillEnv := o.skillEnv(state.LoadedSkills)
+
 	// Execute tools in parallel
 	var wg sync.WaitGroup
 	resultsChan := make(chan toolExecutionResult, len(toolCalls))
@@ -531,10 +766,19 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 		go func(index int, tc ToolCallContent) {
 			defer wg.Done()
 
+			// Find tool
+			var tool Tool
+			for _, t := range state.Tools {
+				if t.Name() == tc.Name {
+					tool = t
+					break
+				}
+			}
+
 			logger.Debug("Tool call start",
 				zap.String("tool_id", tc.ID),
 				zap.String("tool_name", tc.Name),
-				zap.Any("arguments", tc.Arguments))
+				toolArgsField(tool, tc.Arguments))
 
 			// Update progress tracking
 			o.progressTracker.StartTool(tc.Name, len(toolCalls))
@@ -542,15 +786,6 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 			// Emit tool execution start
 			o.emit(NewEvent(EventToolExecutionStart).WithToolExecution(tc.ID, tc.Name, tc.Arguments))
 
-			// Find tool
-			var tool Tool
-			for _, t := range state.Tools {
-				if t.Name() == tc.Name {
-					tool = t
-					break
-				}
-			}
-
 			var result ToolResult
 			var err error
 			var skillName string
@@ -564,14 +799,30 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 				logger.Error("Tool not found",
 					zap.String("tool_name", tc.Name),
 					zap.String("tool_id", tc.ID))
+			} else if keyErr := o.checkSkillAPIKey(tc); keyErr != nil {
+				err = keyErr
+				result = ToolResult{
+					Content: []ContentBlock{TextContent{Text: keyErr.Error()}},
+					Details: map[string]any{"error": keyErr.Error()},
+				}
+			} else if denied := o.checkToolApproval(ctx, tc); denied != "" {
+				result = ToolResult{
+					Content: []ContentBlock{TextContent{Text: denied}},
+					Details: map[string]any{"denied": true},
+				}
+				logger.Info("Tool execution not approved",
+					zap.String("tool_id", tc.ID),
+					zap.String("tool_name", tc.Name),
+					zap.String("reason", denied))
 			} else {
 				state.AddPendingTool(tc.ID)
 
 				// 将 session key 添加到 context 中，供工具使用
 				toolCtx := context.WithValue(ctx, "session_key", state.SessionKey)
+				toolCtx = tools.WithSkillEnv(toolCtx, skillEnv)
 
 				// Execute tool with streaming support
-				result, err = tool.Execute(toolCtx, tc.Arguments, func(partial ToolResult) {
+				result, err = o.executeToolWithTimeout(toolCtx, tool, tc, func(partial ToolResult) {
 					// Emit update event
 					o.emit(NewEvent(EventToolExecutionUpdate).
 						WithToolExecution(tc.ID, tc.Name, tc.Arguments).
@@ -593,7 +844,7 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 				logger.Error("Tool execution failed",
 					zap.String("tool_id", tc.ID),
 					zap.String("tool_name", tc.Name),
-					zap.Any("arguments", tc.Arguments),
+					toolArgsField(tool, tc.Arguments),
 					zap.Error(err))
 			} else {
 				// Extract content for logging
@@ -601,7 +852,7 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 				logger.Debug("Tool execution success",
 					zap.String("tool_id", tc.ID),
 					zap.String("tool_name", tc.Name),
-					zap.Any("arguments", tc.Arguments),
+					toolArgsField(tool, tc.Arguments),
 					zap.Int("result_length", len(contentText)),
 					zap.String("result_preview", truncateString(contentText, 200)))
 			}
@@ -657,7 +908,10 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 		results = append(results, res.resultMsg)
 
 		// Update LoadedSkills for use_skill
-		if res.skillName != "" {
+		if res.skillName != "" && len(filterLoadedSkills([]string{res.skillName}, o.skills)) == 0 {
+			logger.Warn("use_skill selected an unavailable skill, ignored",
+				zap.String("skill_name", res.skillName))
+		} else if res.skillName != "" {
 			alreadyLoaded := false
 			for _, loaded := range state.LoadedSkills {
 				if loaded == res.skillName {
@@ -686,16 +940,111 @@ func (o *Orchestrator) executeToolCalls(ctx context.Context, toolCalls []ToolCal
 	return results, nil
 }
 
-// emit sends an event to the event channel (non-blocking to avoid deadlock when consumer is slow)
+// criticalEventTimeout 关键事件在通道满时最多阻塞等待的时间
+const criticalEventTimeout = 2 * time.Second
+
+// totalDroppedEvents 进程内所有 orchestrator 因订阅方过慢丢弃的事件数
+var totalDroppedEvents atomic.Int64
+
+// TotalDroppedEvents 返回进程内因事件通道满而丢弃的事件总数（供日志/指标使用）
+func TotalDroppedEvents() int64 {
+	return totalDroppedEvents.Load()
+}
+
+// isCriticalEvent 生命周期、消息开始/结束、工具开始/结束为关键事件，不应丢弃；增量类事件可丢弃或合并
+func isCriticalEvent(t EventType) bool {
+	switch t {
+	case EventMessageDelta, EventMessageUpdate, EventToolExecutionUpdate:
+		return false
+	}
+	return true
+}
+
+// emit sends an event to the event channel.
+// 通道满时：message_delta 合并到下一次发送（不丢文本），其他增量事件直接丢弃；
+// 关键事件最多阻塞 criticalEventTimeout，超时后视为订阅方停滞，后续关键事件不再等待直到通道恢复
 func (o *Orchestrator) emit(event *Event) {
 	if o.eventChan == nil {
 		return
 	}
+	o.emitMu.Lock()
+	defer o.emitMu.Unlock()
+
+	if event.Type == EventMessageDelta {
+		event.Content = o.pendingDelta + event.Content
+		if o.trySendLocked(event) {
+			o.pendingDelta = ""
+		} else {
+			o.pendingDelta = event.Content
+			o.coalescedDeltas++
+		}
+		return
+	}
+
+	if !isCriticalEvent(event.Type) {
+		if !o.trySendLocked(event) {
+			o.recordDropLocked(event)
+		}
+		return
+	}
+
+	// 关键事件前先发出合并中的增量，保持顺序
+	if o.pendingDelta != "" {
+		delta := NewEvent(EventMessageDelta).WithContent(o.pendingDelta)
+		o.pendingDelta = ""
+		o.sendCriticalLocked(delta)
+	}
+	o.sendCriticalLocked(event)
+}
+
+// trySendLocked 非阻塞发送；调用方需持有 emitMu
+func (o *Orchestrator) trySendLocked(event *Event) bool {
 	select {
 	case o.eventChan <- event:
+		o.consumerStalled = false
+		return true
 	default:
-		logger.Debug("event channel full, dropping event", zap.String("type", string(event.Type)))
+		return false
+	}
+}
+
+// sendCriticalLocked 有界阻塞发送关键事件；调用方需持有 emitMu
+func (o *Orchestrator) sendCriticalLocked(event *Event) {
+	if o.trySendLocked(event) {
+		return
+	}
+	if o.consumerStalled {
+		o.recordDropLocked(event)
+		return
+	}
+	timer := time.NewTimer(criticalEventTimeout)
+	defer timer.Stop()
+	select {
+	case o.eventChan <- event:
+	case <-timer.C:
+		o.consumerStalled = true
+		o.recordDropLocked(event)
+	}
+}
+
+// recordDropLocked 记录丢弃的事件；调用方需持有 emitMu
+func (o *Orchestrator) recordDropLocked(event *Event) {
+	o.droppedEvents++
+	totalDroppedEvents.Add(1)
+	if isCriticalEvent(event.Type) {
+		logger.Warn("event channel full, dropping critical event",
+			zap.String("type", string(event.Type)),
+			zap.String("session_key", o.state.SessionKey))
+		return
 	}
+	logger.Debug("event channel full, dropping event", zap.String("type", string(event.Type)))
+}
+
+// DroppedEvents 返回本 orchestrator 丢弃的事件数
+func (o *Orchestrator) DroppedEvents() int64 {
+	o.emitMu.Lock()
+	defer o.emitMu.Unlock()
+	return o.droppedEvents
 }
 
 // emitErrorEnd emits an error end event
@@ -704,14 +1053,71 @@ func (o *Orchestrator) emitErrorEnd(state *AgentState, err error) {
 	o.emit(event)
 }
 
-// fetchSteeringMessages gets steering messages from config
+// recordUsage 累加一次 LLM 调用的用量；provider 未上报时按字符估算
+func (o *Orchestrator) recordUsage(messages []providers.Message, completion string, reported *providers.Usage) {
+	usage, estimated := providers.ResolveUsage(messages, completion, reported)
+	metrics.LLMTokens.Add(float64(usage.PromptTokens), "prompt", strconv.FormatBool(estimated))
+	metrics.LLMTokens.Add(float64(usage.CompletionTokens), "completion", strconv.FormatBool(estimated))
+	o.usageMu.Lock()
+	defer o.usageMu.Unlock()
+	o.usage.Calls++
+	if estimated {
+		o.usage.EstimatedCalls++
+	}
+	o.usage.PromptTokens += int64(usage.PromptTokens)
+	o.usage.CompletionTokens += int64(usage.CompletionTokens)
+	o.usage.TotalTokens += int64(usage.TotalTokens)
+}
+
+// Usage 返回最近一次 Run 的 token 用量
+func (o *Orchestrator) Usage() session.TokenUsage {
+	o.usageMu.Lock()
+	defer o.usageMu.Unlock()
+	return o.usage
+}
+
+// ToolsExecuted 返回最近一次 Run 执行完成的工具调用数
+func (o *Orchestrator) ToolsExecuted() int {
+	return o.toolsExecuted
+}
+
+// Steer 向正在执行的 Run 注入一条 steering 消息，在当前工具批次结束或本回合结束时处理；
+// 未在运行时返回 false，由调用方将消息排入该会话的下一次运行
+func (o *Orchestrator) Steer(msg AgentMessage) bool {
+	o.steerMu.Lock()
+	defer o.steerMu.Unlock()
+	if !o.running {
+		return false
+	}
+	o.steerQueue = append(o.steerQueue, msg)
+	return true
+}
+
+// setRunning 标记 Run 是否在执行，决定 Steer 是否可注入
+func (o *Orchestrator) setRunning(running bool) {
+	o.steerMu.Lock()
+	o.running = running
+	o.steerMu.Unlock()
+}
+
+// takeSteering 取出并清空本 Run 的 steering 队列；Run 结束后调用可拿到来不及处理的消息
+func (o *Orchestrator) takeSteering() []AgentMessage {
+	o.steerMu.Lock()
+	defer o.steerMu.Unlock()
+	msgs := o.steerQueue
+	o.steerQueue = nil
+	return msgs
+}
+
+// fetchSteeringMessages gets steering messages from this run's queue and config
 func (o *Orchestrator) fetchSteeringMessages() []AgentMessage {
+	msgs := o.takeSteering()
 	if o.config.GetSteeringMessages != nil {
-		msgs, _ := o.config.GetSteeringMessages()
-		return msgs
+		more, _ := o.config.GetSteeringMessages()
+		return append(msgs, more...)
 	}
 	// Fall back to state queue
-	return o.state.DequeueSteeringMessages()
+	return append(msgs, o.state.DequeueSteeringMessages()...)
 }
 
 // fetchFollowUpMessages gets follow-up messages from config
@@ -764,9 +1170,7 @@ func convertToProviderMessages(messages []AgentMessage) []providers.Message {
 		providerMsg := providers.Message{
 			Role: string(msg.Role),
 		}
-		if reasoning, ok := msg.Metadata["reasoning_content"].(string); ok {
-			providerMsg.ReasoningContent = reasoning
-		}
+		providerMsg.ReasoningContent = messageReasoning(msg)
 
 		// Extract content
 		for _, block := range msg.Content {
@@ -783,10 +1187,6 @@ func convertToProviderMessages(messages []AgentMessage) []providers.Message {
 				} else if b.URL != "" {
 					providerMsg.Images = append(providerMsg.Images, b.URL)
 				}
-			case ThinkingContent:
-				if strings.TrimSpace(providerMsg.ReasoningContent) == "" {
-					providerMsg.ReasoningContent = b.Thinking
-				}
 			}
 		}
 
@@ -898,6 +1298,11 @@ func extractToolResultContent(content []ContentBlock) string {
 	return result.String()
 }
 
+// toolArgsField 工具参数日志字段：按工具声明与通用敏感模式（token/password/secret 等）脱敏
+func toolArgsField(tool Tool, args map[string]any) zap.Field {
+	return zap.Any("arguments", tools.RedactToolArgs(tool, args))
+}
+
 // truncateString truncates a string to a maximum length
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,215 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/metrics"
+	"github.com/smallnest/goclaw/internal/redact"
+	"github.com/smallnest/goclaw/providers"
+)
+
+func TestEmitNeverDropsCriticalEventsWithSlowSubscriber(t *testing.T) {
+	o := NewOrchestrator(&LoopConfig{}, NewAgentState())
+	o.eventChan = make(chan *Event, 4)
+
+	var received []*Event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev := range o.eventChan {
+			received = append(received, ev)
+			time.Sleep(time.Millisecond) // 慢订阅方
+		}
+	}()
+
+	var want strings.Builder
+	o.emit(NewEvent(EventAgentStart))
+	for turn := 0; turn < 5; turn++ {
+		o.emit(NewEvent(EventTurnStart))
+		o.emit(NewEvent(EventMessageStart))
+		for i := 0; i < 200; i++ {
+			o.emit(NewEvent(EventMessageDelta).WithContent("x"))
+			want.WriteString("x")
+		}
+		o.emit(NewEvent(EventMessageEnd))
+		o.emit(NewEvent(EventToolExecutionStart).WithToolExecution("t", "exec", nil))
+		o.emit(NewEvent(EventToolExecutionEnd).WithToolExecution("t", "exec", nil))
+		o.emit(NewEvent(EventTurnEnd))
+	}
+	o.emit(NewEvent(EventAgentEnd))
+	close(o.eventChan)
+	<-done
+
+	counts := map[EventType]int{}
+	var text strings.Builder
+	for _, ev := range received {
+		counts[ev.Type]++
+		if ev.Type == EventMessageDelta {
+			text.WriteString(ev.Content)
+		}
+	}
+	for typ, n := range map[EventType]int{
+		EventAgentStart: 1, EventAgentEnd: 1, EventTurnStart: 5, EventTurnEnd: 5,
+		EventMessageStart: 5, EventMessageEnd: 5, EventToolExecutionStart: 5, EventToolExecutionEnd: 5,
+	} {
+		if counts[typ] != n {
+			t.Errorf("%s events = %d, want %d", typ, counts[typ], n)
+		}
+	}
+	// 增量被合并而非丢弃：文本完整
+	if text.String() != want.String() {
+		t.Errorf("delta text length = %d, want %d", text.Len(), want.Len())
+	}
+	if counts[EventMessageDelta] >= 1000 {
+		t.Errorf("expected deltas to be coalesced under backpressure, got %d delta events", counts[EventMessageDelta])
+	}
+	if o.DroppedEvents() != 0 {
+		t.Errorf("DroppedEvents() = %d, want 0", o.DroppedEvents())
+	}
+}
+
+func TestEmitDropsNonCriticalEventsWhenFull(t *testing.T) {
+	o := NewOrchestrator(&LoopConfig{}, NewAgentState())
+	o.eventChan = make(chan *Event, 1)
+
+	o.emit(NewEvent(EventToolExecutionUpdate))
+	o.emit(NewEvent(EventToolExecutionUpdate))
+	if o.DroppedEvents() != 1 {
+		t.Errorf("DroppedEvents() = %d, want 1", o.DroppedEvents())
+	}
+}
+
+func TestToolArgsFieldRedactsSensitiveArguments(t *testing.T) {
+	base := tools.NewBaseTool("deploy", "deploy", nil, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		return "", nil
+	}).WithSensitiveArgs("payload")
+	tool := ToAgentTools([]tools.Tool{base})[0]
+
+	args := map[string]any{
+		"target":   "prod",
+		"payload":  "BEGIN PRIVATE KEY",
+		"apiToken": "sk-123",
+		"auth":     map[string]any{"password": "hunter2", "user": "ops"},
+	}
+	field := toolArgsField(tool, args)
+	if field.Key != "arguments" {
+		t.Fatalf("field key = %q", field.Key)
+	}
+	logged, ok := field.Interface.(map[string]interface{})
+	if !ok {
+		t.Fatalf("field value type %T", field.Interface)
+	}
+	if logged["target"] != "prod" || logged["auth"].(map[string]interface{})["user"] != "ops" {
+		t.Errorf("non-sensitive arguments must be kept: %+v", logged)
+	}
+	if logged["payload"] != redact.Placeholder || logged["apiToken"] != redact.Placeholder ||
+		logged["auth"].(map[string]interface{})["password"] != redact.Placeholder {
+		t.Errorf("sensitive arguments not masked: %+v", logged)
+	}
+	if args["apiToken"] != "sk-123" || args["auth"].(map[string]any)["password"] != "hunter2" {
+		t.Error("redaction must not modify the arguments passed to the tool")
+	}
+
+	// 未找到工具时仍按通用模式脱敏
+	if got := toolArgsField(nil, map[string]any{"client_secret": "x"}).Interface.(map[string]interface{}); got["client_secret"] != redact.Placeholder {
+		t.Errorf("unknown tool args not masked: %+v", got)
+	}
+}
+
+// modelRejectingProvider 对指定模型返回 404 模型不存在，记录每次调用的模型
+type modelRejectingProvider struct {
+	rejected string
+	models   []string
+}
+
+func (p *modelRejectingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	opts := &providers.ChatOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	p.models = append(p.models, opts.Model)
+	if opts.Model == p.rejected {
+		return nil, errors.New("404 Not Found: The model `" + opts.Model + "` does not exist or you do not have access to it.")
+	}
+	return &providers.Response{Content: "hello from " + opts.Model}, nil
+}
+
+func (p *modelRejectingProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *modelRejectingProvider) Close() error            { return nil }
+func (p *modelRejectingProvider) SupportsStreaming() bool { return false }
+
+func TestRunFallsBackWhenPrimaryModelNotFound(t *testing.T) {
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}}
+
+	provider := &modelRejectingProvider{rejected: "gpt-4o-typo"}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, Model: "gpt-4o-typo", FallbackModel: "gpt-4o-mini", MaxIterations: 3}, NewAgentState())
+	final, err := o.Run(context.Background(), []AgentMessage{prompt}, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := strings.Join(provider.models, ","); got != "gpt-4o-typo,gpt-4o-mini" {
+		t.Fatalf("models called = %s, want primary then fallback", got)
+	}
+	if text := extractTextContent(final[len(final)-1]); text != "hello from gpt-4o-mini" {
+		t.Fatalf("final reply = %q", text)
+	}
+	if o.effectiveModel() != "gpt-4o-typo" {
+		t.Fatalf("fallback must not outlive the run, effective model = %q", o.effectiveModel())
+	}
+
+	// 未配置 fallback_model 时保持原错误
+	provider = &modelRejectingProvider{rejected: "gpt-4o-typo"}
+	o = NewOrchestrator(&LoopConfig{Provider: provider, Model: "gpt-4o-typo", MaxIterations: 3}, NewAgentState())
+	if _, err := o.Run(context.Background(), []AgentMessage{prompt}, nil); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("Run() without fallback error = %v, want model-not-found", err)
+	}
+	if len(provider.models) != 1 {
+		t.Fatalf("calls without fallback = %d, want 1", len(provider.models))
+	}
+}
+
+func TestRunRecordsLLMMetrics(t *testing.T) {
+	model := "metrics-test-model"
+	cfg := &LoopConfig{Provider: &fakeChatProvider{}, Model: model, MaxIterations: 1}
+	o := NewOrchestrator(cfg, NewAgentState())
+	before := metrics.LLMTokens.Value("completion", "true")
+
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}}
+	if _, err := o.Run(context.Background(), []AgentMessage{prompt}, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := metrics.LLMCallDuration.Count(model, "ok"); got != 1 {
+		t.Errorf("llm call observations = %d, want 1", got)
+	}
+	// fakeChatProvider 不返回 usage，completion token 按估算计入
+	if metrics.LLMTokens.Value("completion", "true") <= before {
+		t.Error("estimated completion tokens were not recorded")
+	}
+}
+
+func TestLLMMetricsBucketUnconfiguredModels(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Model: "configured-metrics-model"}}})
+
+	o := NewOrchestrator(&LoopConfig{Provider: &fakeChatProvider{}, Model: "agent-metrics-model", MaxIterations: 1}, NewAgentState())
+	for model, want := range map[string]string{
+		"agent-metrics-model":      "agent-metrics-model",
+		"configured-metrics-model": "configured-metrics-model",
+		"(provider default)":       "(provider default)",
+		"user-typed-model-42":      "other",
+	} {
+		if got := o.metricsModelLabel(model); got != want {
+			t.Errorf("metricsModelLabel(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// PostProcessor 对最终 assistant 文本做确定性后处理（如追加引用、改写链接、去除内部备注）
+type PostProcessor interface {
+	Name() string
+	PostProcess(text, sessionKey string) string
+}
+
+// PostProcessFunc 以函数形式实现 PostProcessor
+type PostProcessFunc struct {
+	name string
+	fn   func(text, sessionKey string) string
+}
+
+// NewPostProcessFunc 创建函数式后处理器
+func NewPostProcessFunc(name string, fn func(text, sessionKey string) string) *PostProcessFunc {
+	return &PostProcessFunc{name: name, fn: fn}
+}
+
+// Name 返回后处理器名称
+func (p *PostProcessFunc) Name() string { return p.name }
+
+// PostProcess 执行后处理
+func (p *PostProcessFunc) PostProcess(text, sessionKey string) string { return p.fn(text, sessionKey) }
+
+var (
+	postProcessorsMu sync.RWMutex
+	postProcessors   = map[string]PostProcessor{}
+)
+
+// RegisterPostProcessor 注册后处理器；同名覆盖
+func RegisterPostProcessor(p PostProcessor) {
+	postProcessorsMu.Lock()
+	defer postProcessorsMu.Unlock()
+	postProcessors[p.Name()] = p
+}
+
+// GetPostProcessor 按名称获取后处理器
+func GetPostProcessor(name string) (PostProcessor, bool) {
+	postProcessorsMu.RLock()
+	defer postProcessorsMu.RUnlock()
+	p, ok := postProcessors[name]
+	return p, ok
+}
+
+// ListPostProcessors 列出已注册的后处理器名称
+func ListPostProcessors() []string {
+	postProcessorsMu.RLock()
+	defer postProcessorsMu.RUnlock()
+	names := make([]string, 0, len(postProcessors))
+	for name := range postProcessors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPostProcess 对最后一条 assistant 消息的文本执行后处理（只处理最终消息，不处理流式增量）
+func applyPostProcess(pipeline *PostProcessPipeline, messages []AgentMessage, sessionKey string) {
+	if pipeline.Len() == 0 || len(messages) == 0 {
+		return
+	}
+	last := &messages[len(messages)-1]
+	if last.Role != RoleAssistant {
+		return
+	}
+	text := extractTextContent(*last)
+	if text == "" {
+		return
+	}
+	processed := pipeline.Apply(text, sessionKey)
+	if processed == text {
+		return
+	}
+	// 处理后的文本放在第一个文本块的位置，其余文本块合并掉，非文本块保持原有顺序
+	content := make([]ContentBlock, 0, len(last.Content))
+	replaced := false
+	for _, block := range last.Content {
+		if _, ok := block.(TextContent); ok {
+			if !replaced {
+				content = append(content, TextContent{Text: processed})
+				replaced = true
+			}
+			continue
+		}
+		content = append(content, block)
+	}
+	last.Content = content
+}
+
+// postProcessPipeline 返回当前配置的后处理链；配置热重载（config.Get 返回新配置）后按新配置重新构建
+func (m *AgentManager) postProcessPipeline() *PostProcessPipeline {
+	cfg := config.Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cfg != nil && cfg != m.postProcessCfg {
+		m.postProcess = newConfiguredPostProcessPipeline(cfg.Agents.Defaults)
+		m.postProcessCfg = cfg
+	}
+	return m.postProcess
+}
+
+// PostProcessPipeline 按配置顺序依次执行的后处理器链
+type PostProcessPipeline struct {
+	processors []PostProcessor
+}
+
+// NewPostProcessPipeline 按名称构建后处理链（对应 agents.defaults.post_processors）；未知名称记录告警并跳过
+func NewPostProcessPipeline(names []string) *PostProcessPipeline {
+	return buildPostProcessPipeline(names, nil)
+}
+
+// newConfiguredPostProcessPipeline 按 agents.defaults 构建后处理链：link_rewrite 使用本份配置的 link_rewrites，
+// 不修改全局注册表，配置重载后重新构建即可生效
+func newConfiguredPostProcessPipeline(defaults config.AgentDefaults) *PostProcessPipeline {
+	return buildPostProcessPipeline(defaults.PostProcessors, map[string]PostProcessor{
+		"link_rewrite": NewPostProcessFunc("link_rewrite", NewLinkRewriter(defaults.LinkRewrites)),
+	})
+}
+
+// buildPostProcessPipeline overrides 中的同名后处理器优先于全局注册表
+func buildPostProcessPipeline(names []string, overrides map[string]PostProcessor) *PostProcessPipeline {
+	pipeline := &PostProcessPipeline{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := overrides[name]
+		if !ok {
+			p, ok = GetPostProcessor(name)
+		}
+		if !ok {
+			logger.Warn("Unknown post processor, skipped", zap.String("name", name))
+			continue
+		}
+		pipeline.processors = append(pipeline.processors, p)
+	}
+	return pipeline
+}
+
+// Len 返回链中后处理器数量
+func (p *PostProcessPipeline) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.processors)
+}
+
+// Apply 依次执行后处理
+func (p *PostProcessPipeline) Apply(text, sessionKey string) string {
+	if p == nil {
+		return text
+	}
+	for _, proc := range p.processors {
+		text = proc.PostProcess(text, sessionKey)
+	}
+	return text
+}
+
+// thinkTagPattern 匹配模型输出中的 <think>/<thinking> 块
+var thinkTagPattern = regexp.MustCompile(`(?s)<(think|thinking)>.*?</(think|thinking)>`)
+
+// StripThinkTags 去除 <think>...</think> 与 <thinking>...</thinking> 块
+func StripThinkTags(text, sessionKey string) string {
+	return strings.TrimSpace(thinkTagPattern.ReplaceAllString(text, ""))
+}
+
+// TrimWhitespace 去除首尾及行尾空白，并将连续多个空行压缩为一个
+func TrimWhitespace(text, sessionKey string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// NewLinkRewriter 创建链接改写后处理器：将以 rules 中前缀开头的 URL 替换为对应前缀（按前缀长度从长到短匹配）
+func NewLinkRewriter(rules map[string]string) func(text, sessionKey string) string {
+	prefixes := make([]string, 0, len(rules))
+	for from := range rules {
+		if from != "" {
+			prefixes = append(prefixes, from)
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	urlPattern := regexp.MustCompile(`https?://[^\s)\]>"']+`)
+	return func(text, sessionKey string) string {
+		if len(prefixes) == 0 {
+			return text
+		}
+		return urlPattern.ReplaceAllStringFunc(text, func(u string) string {
+			for _, from := range prefixes {
+				if strings.HasPrefix(u, from) {
+					return rules[from] + strings.TrimPrefix(u, from)
+				}
+			}
+			return u
+		})
+	}
+}
+
+func init() {
+	RegisterPostProcessor(NewPostProcessFunc("trim_whitespace", TrimWhitespace))
+	RegisterPostProcessor(NewPostProcessFunc("strip_think_tags", StripThinkTags))
+	// link_rewrite 的规则来自 agents.defaults.link_rewrites，AgentManager 按当前配置构建（见 newConfiguredPostProcessPipeline）
+	RegisterPostProcessor(NewPostProcessFunc("link_rewrite", NewLinkRewriter(nil)))
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestTrimWhitespace(t *testing.T) {
+	in := "  \n\nHello  \n\n\n\nWorld\t\n\n"
+	want := "Hello\n\nWorld"
+	if got := TrimWhitespace(in, "s"); got != want {
+		t.Errorf("TrimWhitespace() = %q, want %q", got, want)
+	}
+}
+
+func TestStripThinkTags(t *testing.T) {
+	in := "<think>internal\nnotes</think>Answer <thinking>more</thinking>here"
+	want := "Answer here"
+	if got := StripThinkTags(in, "s"); got != want {
+		t.Errorf("StripThinkTags() = %q, want %q", got, want)
+	}
+}
+
+func TestLinkRewriter(t *testing.T) {
+	rewrite := NewLinkRewriter(map[string]string{
+		"http://wiki.internal/":      "https://wiki.example.com/",
+		"http://wiki.internal/team/": "https://team.example.com/",
+	})
+	in := "See http://wiki.internal/page and (http://wiki.internal/team/x) or https://other.com/a"
+	want := "See https://wiki.example.com/page and (https://team.example.com/x) or https://other.com/a"
+	if got := rewrite(in, "s"); got != want {
+		t.Errorf("link rewrite = %q, want %q", got, want)
+	}
+}
+
+func TestPostProcessPipelineOrder(t *testing.T) {
+	RegisterPostProcessor(NewPostProcessFunc("test_append_a", func(text, sessionKey string) string { return text + "a" }))
+	RegisterPostProcessor(NewPostProcessFunc("test_append_b", func(text, sessionKey string) string { return text + "b" }))
+
+	if got := NewPostProcessPipeline([]string{"test_append_a", "test_append_b"}).Apply("x", "s"); got != "xab" {
+		t.Errorf("pipeline a,b = %q, want %q", got, "xab")
+	}
+	if got := NewPostProcessPipeline([]string{"test_append_b", "unknown", "test_append_a"}).Apply("x", "s"); got != "xba" {
+		t.Errorf("pipeline b,a = %q, want %q", got, "xba")
+	}
+}
+
+func TestApplyPostProcessOnlyFinalAssistant(t *testing.T) {
+	pipeline := NewPostProcessPipeline([]string{"strip_think_tags", "trim_whitespace"})
+	messages := []AgentMessage{
+		{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "<think>u</think>q"}}},
+		{Role: RoleAssistant, Content: []ContentBlock{TextContent{Text: "<think>x</think>  answer  "}}},
+	}
+	applyPostProcess(pipeline, messages, "agent:main:main")
+
+	if got := extractTextContent(messages[1]); got != "answer" {
+		t.Errorf("final assistant text = %q, want %q", got, "answer")
+	}
+	if got := extractTextContent(messages[0]); !strings.Contains(got, "<think>") {
+		t.Errorf("user message should not be post-processed, got %q", got)
+	}
+}
+
+func TestApplyPostProcessKeepsBlockOrder(t *testing.T) {
+	pipeline := NewPostProcessPipeline([]string{"trim_whitespace"})
+	image := ImageContent{Data: "aGVsbG8=", MimeType: "image/png"}
+	messages := []AgentMessage{{Role: RoleAssistant, Content: []ContentBlock{
+		ThinkingContent{Thinking: "plan"},
+		TextContent{Text: "  answer  "},
+		image,
+	}}}
+	applyPostProcess(pipeline, messages, "agent:main:main")
+
+	content := messages[0].Content
+	if len(content) != 3 {
+		t.Fatalf("content = %+v", content)
+	}
+	if _, ok := content[0].(ThinkingContent); !ok {
+		t.Errorf("block 0 = %T, want ThinkingContent", content[0])
+	}
+	if text, ok := content[1].(TextContent); !ok || text.Text != "answer" {
+		t.Errorf("block 1 = %+v, want processed text", content[1])
+	}
+	if _, ok := content[2].(ImageContent); !ok {
+		t.Errorf("block 2 = %T, want ImageContent", content[2])
+	}
+}
+
+func TestPostProcessPipelineFollowsConfigReload(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	withRules := &config.Config{}
+	withRules.Agents.Defaults.PostProcessors = []string{"link_rewrite"}
+	withRules.Agents.Defaults.LinkRewrites = map[string]string{"http://wiki.internal/": "https://wiki.example.com/"}
+	config.Set(withRules)
+
+	m := &AgentManager{}
+	if got := m.postProcessPipeline().Apply("http://wiki.internal/a", "s"); got != "https://wiki.example.com/a" {
+		t.Fatalf("rewrite = %q", got)
+	}
+
+	withoutRules := &config.Config{}
+	withoutRules.Agents.Defaults.PostProcessors = []string{"link_rewrite"}
+	config.Set(withoutRules)
+	if got := m.postProcessPipeline().Apply("http://wiki.internal/a", "s"); got != "http://wiki.internal/a" {
+		t.Fatalf("stale link_rewrite rules after reload: %q", got)
+	}
+	if got := NewPostProcessPipeline([]string{"link_rewrite"}).Apply("http://wiki.internal/a", "s"); got != "http://wiki.internal/a" {
+		t.Fatalf("global link_rewrite picked up config rules: %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/types"
+	"go.uber.org/zap"
+)
+
+// 模型提供商可用性探测
+const (
+	providerProbeTimeout    = 10 * time.Second
+	providerRecheckInterval = 30 * time.Second // 不可用期间重新探测的间隔
+)
+
+// ProviderUnavailableError 探测判定模型提供商不可用（密钥缺失或无效、网络不可达）时，使用该提供商的新运行立即以此错误结束，
+// 而不是等到运行超时；Detail 为探测的原始错误，只记录日志，用户看到的是本地化的通用提示
+type ProviderUnavailableError struct {
+	Provider string
+	Detail   string
+}
+
+func (e *ProviderUnavailableError) Error() string {
+	return "model provider unavailable: " + e.Detail
+}
+
+// providerHealth 最近一次探测结果；degraded 表示提供商不可用，期间拒绝新运行
+type providerHealth struct {
+	mu       sync.RWMutex
+	result   *providers.PreflightResult
+	degraded bool
+	recheck  chan struct{} // 运行因提供商故障失败后请求重新探测（见 noteProviderFailure）
+}
+
+// degradingReason 判定提供商整体不可用的失败原因；限流、服务端错误等暂时性问题不拒绝运行
+func degradingReason(reason string) bool {
+	switch types.FailoverReason(reason) {
+	case types.FailoverReasonAuth, types.FailoverReasonNetworkError, types.FailoverReasonTimeout, types.FailoverReasonBilling:
+		return true
+	}
+	return false
+}
+
+// SetProviderPreflight 记录启动时已完成的预检结果（providers.preflight），Start 时不再重复探测
+func (m *AgentManager) SetProviderPreflight(result providers.PreflightResult) {
+	m.recordProviderHealth(result)
+}
+
+// ProviderHealth 返回最近一次提供商探测结果与是否处于降级状态；尚未探测时 CheckedAt 为 0
+func (m *AgentManager) ProviderHealth() (providers.PreflightResult, bool) {
+	m.health.mu.RLock()
+	defer m.health.mu.RUnlock()
+	if m.health.result == nil {
+		return providers.PreflightResult{}, false
+	}
+	return *m.health.result, m.health.degraded
+}
+
+// providerUnavailable 运行使用的模型（见 runModel）与降级的探测结果属于同一提供商时返回 ProviderUnavailableError；
+// 其他提供商的模型（如会话 /model 切换到的模型）照常运行
+func (m *AgentManager) providerUnavailable(model string) error {
+	result, degraded := m.ProviderHealth()
+	if !degraded {
+		return nil
+	}
+	m.mu.RLock()
+	cfg := m.cfg
+	m.mu.RUnlock()
+	provider := providers.ResolveProviderName(cfg, model)
+	if provider != providers.ResolveProviderName(cfg, result.Model) {
+		return nil
+	}
+	return &ProviderUnavailableError{Provider: provider, Detail: result.Error}
+}
+
+func (m *AgentManager) recordProviderHealth(result providers.PreflightResult) {
+	degraded := !result.OK() && degradingReason(result.Reason)
+	m.health.mu.Lock()
+	wasDegraded := m.health.degraded
+	m.health.result = &result
+	m.health.degraded = degraded
+	m.health.mu.Unlock()
+
+	switch {
+	case degraded && !wasDegraded:
+		logger.Error("Model provider unavailable, rejecting runs until it recovers",
+			zap.String("model", result.Model),
+			zap.String("reason", result.Reason),
+			zap.String("error", result.Error))
+	case !degraded && wasDegraded:
+		logger.Info("Model provider recovered", zap.String("model", result.Model))
+	}
+}
+
+// probeProvider 以默认模型发起一次极小调用探测提供商
+func (m *AgentManager) probeProvider(ctx context.Context) {
+	model := ""
+	m.mu.RLock()
+	if m.cfg != nil {
+		model = m.cfg.Agents.Defaults.Model
+	}
+	m.mu.RUnlock()
+	m.recordProviderHealth(providers.Preflight(ctx, m.provider, model, providerProbeTimeout))
+}
+
+// noteProviderFailure 运行以提供商整体故障（鉴权、网络、超时、欠费）失败时请求 monitorProvider 重新探测；
+// 用户中止的运行不计入
+func (m *AgentManager) noteProviderFailure(err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	if !degradingReason(string(types.NewSimpleErrorClassifier().ClassifyError(err))) {
+		return
+	}
+	select {
+	case m.health.recheck <- struct{}{}:
+	default:
+	}
+}
+
+// monitorProvider 开启 providers.preflight 时启动探测一次（已有预检结果时跳过）；之后仅在运行因提供商故障失败后、
+// 以及降级期间定期重新探测（每次探测都是一次计费调用），恢复后放行运行
+func (m *AgentManager) monitorProvider(ctx context.Context) {
+	if m.provider == nil {
+		return
+	}
+	m.mu.RLock()
+	preflight := m.cfg != nil && m.cfg.Providers.Preflight
+	m.mu.RUnlock()
+	if result, _ := m.ProviderHealth(); preflight && result.CheckedAt == 0 {
+		m.probeProvider(ctx)
+	}
+	ticker := time.NewTicker(providerRecheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, degraded := m.ProviderHealth(); degraded {
+				m.probeProvider(ctx)
+			}
+		case <-m.health.recheck:
+			// 多个运行同时失败时合并为一次探测，降级期间交给定时重探
+			if result, degraded := m.ProviderHealth(); !degraded && time.Since(time.UnixMilli(result.CheckedAt)) >= providerRecheckInterval {
+				m.probeProvider(ctx)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/i18n"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestDegradedProviderRejectsRunsImmediately(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus(16)
+	defer msgBus.Close()
+	m := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        msgBus,
+		Provider:   &fakeChatProvider{},
+		SessionMgr: sessMgr,
+		Tools:      NewToolRegistry(),
+		DataDir:    t.TempDir(),
+	})
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Workspace: config.WorkspaceConfig{Path: workspace},
+		Agents:    config.AgentsConfig{List: []config.AgentConfig{{ID: "main", Default: true}}},
+	}
+	if err := m.SetupFromConfig(cfg, NewContextBuilder(NewMemoryStore(workspace), workspace)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 限流等暂时性失败不降级
+	m.SetProviderPreflight(providers.PreflightResult{Status: providers.PreflightStatusError, Reason: "rate_limit", CheckedAt: 1})
+	if _, degraded := m.ProviderHealth(); degraded {
+		t.Fatal("rate limit should not degrade the provider")
+	}
+	m.SetProviderPreflight(providers.PreflightResult{
+		Status: providers.PreflightStatusError, Reason: "auth", Error: "invalid API key: 401", CheckedAt: 1,
+	})
+	if _, degraded := m.ProviderHealth(); !degraded {
+		t.Fatal("auth failure should degrade the provider")
+	}
+
+	sub := msgBus.SubscribeOutbound()
+	defer sub.Unsubscribe()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.RouteInbound(ctx, &bus.InboundMessage{ID: "run-1", Channel: "telegram", ChatID: "42", Content: "hi"}); err != nil {
+		t.Fatalf("RouteInbound: %v", err)
+	}
+	select {
+	case out := <-sub.Channel:
+		// 用户看到本地化的通用提示，而不是提供商的原始错误
+		if out.ChatState != "error" || out.Content != i18n.T(i18n.RunProviderDown) {
+			t.Fatalf("reply = %+v", out)
+		}
+		if strings.Contains(out.Content, "401") || out.Metadata["errorCode"] != RunErrorCodeProviderDown {
+			t.Fatalf("reply leaks provider error or lacks code: %+v", out)
+		}
+	case <-ctx.Done():
+		t.Fatal("no error reply while provider is degraded")
+	}
+
+	// 只拦截与探测同一提供商的模型：会话切换到其他提供商的模型照常运行
+	m.SetProviderPreflight(providers.PreflightResult{
+		Model: "gpt-4o-mini", Status: providers.PreflightStatusError, Reason: "auth", Error: "invalid API key: 401", CheckedAt: 3,
+	})
+	sess, err := sessMgr.GetOrCreate("agent:main:main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.PatchMetadata(map[string]interface{}{session.MetadataModelOverride: "claude-3-5-haiku"})
+	if model := m.runModel(m.defaultAgent, sess.Key, sess); model != "claude-3-5-haiku" {
+		t.Fatalf("runModel = %q, want the session override", model)
+	}
+	if err := m.providerUnavailable("claude-3-5-haiku"); err != nil {
+		t.Fatalf("run on another provider rejected: %v", err)
+	}
+	if err := m.providerUnavailable("gpt-4o"); err == nil {
+		t.Fatal("run on the degraded provider allowed")
+	}
+
+	m.SetProviderPreflight(providers.PreflightResult{Status: providers.PreflightStatusOK, CheckedAt: 4})
+	if err := m.providerUnavailable(""); err != nil {
+		t.Fatalf("recovered provider still rejects runs: %v", err)
+	}
+}
+
+// probeCountingProvider 记录探测（Chat）次数
+type probeCountingProvider struct {
+	fakeChatProvider
+	calls atomic.Int32
+}
+
+func (p *probeCountingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.calls.Add(1)
+	return p.fakeChatProvider.Chat(ctx, messages, tools, options...)
+}
+
+func TestProviderMonitorProbesOnlyWhenEnabledOrAfterFailure(t *testing.T) {
+	provider := &probeCountingProvider{}
+	m := NewAgentManager(&NewAgentManagerConfig{Provider: provider, DataDir: t.TempDir()})
+	m.cfg = &config.Config{}
+	m.cfg.Agents.Defaults.Model = "gpt-4o-mini"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.monitorProvider(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+	if n := provider.calls.Load(); n != 0 {
+		t.Fatalf("provider probed %d times at startup without providers.preflight", n)
+	}
+
+	// 用户中止与非提供商故障不触发探测
+	m.noteProviderFailure(context.Canceled)
+	m.noteProviderFailure(errors.New("429 Too Many Requests: rate limit exceeded"))
+	time.Sleep(50 * time.Millisecond)
+	if n := provider.calls.Load(); n != 0 {
+		t.Fatalf("provider probed %d times after non-degrading failures", n)
+	}
+
+	m.noteProviderFailure(errors.New("dial tcp: connection refused"))
+	deadline := time.Now().Add(2 * time.Second)
+	for provider.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := provider.calls.Load(); n != 1 {
+		t.Fatalf("provider probed %d times after a network failure, want 1", n)
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// quietHoursQueue 静默时段内排队的入站消息（按通道），时段结束时由定时器统一处理；零值可用。
+// 只保存在内存中，进程重启时丢失
+type quietHoursQueue struct {
+	mu      sync.Mutex
+	pending map[string][]*bus.InboundMessage
+	timers  map[string]*time.Timer
+}
+
+// quietHoursEnd 判断 now 是否处于静默时段内，是则返回静默结束时刻（相邻或重叠的时间段合并计算）
+func quietHoursEnd(q *config.QuietHoursConfig, now time.Time) (time.Time, bool) {
+	loc, err := q.Location()
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, ok := quietRangeEnd(q.Ranges, now.In(loc))
+	if !ok {
+		return time.Time{}, false
+	}
+	// 结束时刻落在另一段内（如 22:00-24:00 与 00:00-07:00）时顺延
+	for i := 0; i < len(q.Ranges); i++ {
+		next, ok := quietRangeEnd(q.Ranges, end)
+		if !ok || !next.After(end) {
+			break
+		}
+		end = next
+	}
+	return end, true
+}
+
+// quietRangeEnd 返回 t 所在时间段中最晚的结束时刻
+func quietRangeEnd(ranges []string, t time.Time) (time.Time, bool) {
+	minute := t.Hour()*60 + t.Minute()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	var latest time.Time
+	found := false
+	for _, r := range ranges {
+		start, end, err := config.ParseQuietRange(r)
+		if err != nil {
+			continue
+		}
+		var until time.Time
+		switch {
+		case start < end && minute >= start && minute < end:
+			until = day.Add(time.Duration(end) * time.Minute)
+		case start > end && minute >= start:
+			until = day.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute)
+		case start > end && minute < end:
+			until = day.Add(time.Duration(end) * time.Minute)
+		default:
+			continue
+		}
+		if !found || until.After(latest) {
+			latest, found = until, true
+		}
+	}
+	return latest, found
+}
+
+// deferForQuietHours 通道处于静默时段时拦截入站消息：reply 模式按配置自动回复后丢弃，queue 模式排队到时段结束再处理。
+// 返回 true 表示消息已被拦截
+func (m *AgentManager) deferForQuietHours(ctx context.Context, msg *bus.InboundMessage) bool {
+	if msg.Channel == "internal" || msg.Channel == "websocket" {
+		return false
+	}
+	cfg := config.Get()
+	if cfg == nil {
+		return false
+	}
+	q := cfg.Channels.QuietHours(msg.Channel)
+	if q == nil {
+		return false
+	}
+	end, quiet := quietHoursEnd(q, time.Now())
+	if !quiet {
+		return false
+	}
+
+	if q.Mode == config.QuietHoursModeQueue {
+		m.quietHours.enqueue(msg, end, func() { m.flushQuietHours(msg.Channel) })
+		logger.Info("Inbound message queued for quiet hours",
+			zap.String("channel", msg.Channel),
+			zap.String("chat_id", msg.ChatID),
+			zap.Time("until", end))
+	} else {
+		logger.Info("Inbound message ignored during quiet hours",
+			zap.String("channel", msg.Channel),
+			zap.String("chat_id", msg.ChatID))
+	}
+	if q.AutoReply != "" {
+		m.publishRunFinalToBus(ctx, msg.Channel, msg.ChatID, msg.ID, q.AutoReply)
+	}
+	return true
+}
+
+// enqueue 排队消息；该通道还没有定时器时安排在 until 处理
+func (qq *quietHoursQueue) enqueue(msg *bus.InboundMessage, until time.Time, flush func()) {
+	qq.mu.Lock()
+	defer qq.mu.Unlock()
+	if qq.pending == nil {
+		qq.pending = make(map[string][]*bus.InboundMessage)
+		qq.timers = make(map[string]*time.Timer)
+	}
+	qq.pending[msg.Channel] = append(qq.pending[msg.Channel], msg)
+	if qq.timers[msg.Channel] == nil {
+		qq.timers[msg.Channel] = time.AfterFunc(time.Until(until), flush)
+	}
+}
+
+// take 取出通道排队的全部消息并清除定时器
+func (qq *quietHoursQueue) take(channel string) []*bus.InboundMessage {
+	qq.mu.Lock()
+	defer qq.mu.Unlock()
+	if t := qq.timers[channel]; t != nil {
+		t.Stop()
+		delete(qq.timers, channel)
+	}
+	msgs := qq.pending[channel]
+	delete(qq.pending, channel)
+	return msgs
+}
+
+// flushQuietHours 静默时段结束：按到达顺序处理该通道排队的消息
+func (m *AgentManager) flushQuietHours(channel string) {
+	msgs := m.quietHours.take(channel)
+	if len(msgs) == 0 {
+		return
+	}
+	logger.Info("Quiet hours ended, processing queued messages",
+		zap.String("channel", channel),
+		zap.Int("count", len(msgs)))
+	ctx := context.Background()
+	for _, msg := range msgs {
+		agent, err := m.resolveAgent(msg)
+		if err == nil {
+			err = m.handleInboundMessage(ctx, msg, agent)
+		}
+		if err != nil {
+			logger.Error("Failed to process queued quiet hours message",
+				zap.String("channel", channel),
+				zap.String("chat_id", msg.ChatID),
+				zap.Error(err))
+		}
+	}
+}
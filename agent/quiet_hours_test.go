@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	q := &config.QuietHoursConfig{Enabled: true, Timezone: "UTC", Ranges: []string{"22:00-24:00", "00:00-07:00", "12:00-13:00"}}
+	tests := []struct {
+		now   time.Time
+		quiet bool
+		end   time.Time
+	}{
+		{at(10, 23, 15), true, at(11, 7, 0)}, // 两段相接，顺延到次日 07:00
+		{at(11, 6, 59), true, at(11, 7, 0)},
+		{at(11, 7, 0), false, time.Time{}},
+		{at(11, 12, 30), true, at(11, 13, 0)},
+		{at(11, 18, 0), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		end, quiet := quietHoursEnd(q, tt.now)
+		if quiet != tt.quiet || !end.Equal(tt.end) {
+			t.Errorf("quietHoursEnd(%s) = %s, %v; want %s, %v", tt.now.Format(time.RFC3339), end, quiet, tt.end, tt.quiet)
+		}
+	}
+}
+
+func TestQuietHoursQueueDefersMessageUntilWindowEnds(t *testing.T) {
+	now := time.Now().UTC()
+	window := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Channels: config.ChannelsConfig{Telegram: config.TelegramChannelConfig{
+		QuietHours: &config.QuietHoursConfig{Enabled: true, Timezone: "UTC", Ranges: []string{window}, Mode: config.QuietHoursModeQueue},
+	}}})
+
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{bus: bus.NewMessageBus(10), sessionMgr: mgr, defaultAgent: &Agent{}}
+	defer m.bus.Close()
+	sub := m.bus.SubscribeOutbound()
+
+	msg := &bus.InboundMessage{ID: "quiet-1", Channel: "telegram", ChatID: "42", Content: "/help", Timestamp: now}
+	if err := m.RouteInbound(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case out := <-sub.Channel:
+		t.Fatalf("message processed during quiet hours: %q", out.Content)
+	case <-time.After(100 * time.Millisecond):
+	}
+	m.quietHours.mu.Lock()
+	queued, scheduled := len(m.quietHours.pending["telegram"]), m.quietHours.timers["telegram"] != nil
+	m.quietHours.mu.Unlock()
+	if queued != 1 || !scheduled {
+		t.Fatalf("queued = %d, timer scheduled = %v; want 1 queued message with a timer", queued, scheduled)
+	}
+
+	// 静默时段结束时定时器调用 flushQuietHours
+	m.flushQuietHours("telegram")
+	select {
+	case out := <-sub.Channel:
+		if out.ChatID != "42" || out.Content == "" {
+			t.Fatalf("unexpected reply after quiet hours: %+v", out)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued message was not processed after quiet hours")
+	}
+	if left := m.quietHours.take("telegram"); len(left) != 0 {
+		t.Fatalf("queue not drained: %d left", len(left))
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// pendingRun 已提交（排队或执行中）的 Run，chat.abort 通过 cancel 中止
+type pendingRun struct {
+	sessionKey string
+	cancel     context.CancelFunc
+}
+
+// registerRunCancel 登记 Run 的取消函数；runID 为空（如 internal 排队运行）时生成一个，返回实际登记的 runId
+func (m *AgentManager) registerRunCancel(runID, sessionKey string, cancel context.CancelFunc) string {
+	if runID == "" {
+		runID = "run-" + uuid.NewString()
+	}
+	m.pendingRunsMu.Lock()
+	defer m.pendingRunsMu.Unlock()
+	if m.pendingRuns == nil {
+		m.pendingRuns = make(map[string]*pendingRun)
+	}
+	m.pendingRuns[runID] = &pendingRun{sessionKey: sessionKey, cancel: cancel}
+	return runID
+}
+
+// unregisterRunCancel Run 结束（正常完成、出错或被中止）后移除登记
+func (m *AgentManager) unregisterRunCancel(runID string) {
+	m.pendingRunsMu.Lock()
+	defer m.pendingRunsMu.Unlock()
+	delete(m.pendingRuns, runID)
+}
+
+// SessionRunIDs 返回会话已提交（排队或执行中）的 runId（已排序）
+func (m *AgentManager) SessionRunIDs(sessionKey string) []string {
+	m.pendingRunsMu.Lock()
+	var runIDs []string
+	for runID, r := range m.pendingRuns {
+		if r.sessionKey == sessionKey {
+			runIDs = append(runIDs, runID)
+		}
+	}
+	m.pendingRunsMu.Unlock()
+	sort.Strings(runIDs)
+	return runIDs
+}
+
+// AbortSessionRuns 中止会话所有已提交的 Run（执行中的返回 ctx 取消错误，排队中的轮到时立即结束），返回被中止的 runId（已排序）
+func (m *AgentManager) AbortSessionRuns(sessionKey string) []string {
+	m.pendingRunsMu.Lock()
+	var runIDs []string
+	var cancels []context.CancelFunc
+	for runID, r := range m.pendingRuns {
+		if r.sessionKey == sessionKey {
+			runIDs = append(runIDs, runID)
+			cancels = append(cancels, r.cancel)
+			delete(m.pendingRuns, runID)
+		}
+	}
+	m.pendingRunsMu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	sort.Strings(runIDs)
+	if len(runIDs) > 0 {
+		logger.Info("Session runs aborted",
+			zap.String("session_key", sessionKey),
+			zap.Strings("run_ids", runIDs))
+	}
+	return runIDs
+}
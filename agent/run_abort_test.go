@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// blockingProvider 调用阻塞到 ctx 取消
+type blockingProvider struct {
+	started chan struct{}
+}
+
+func (p *blockingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	close(p.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *blockingProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *blockingProvider) Close() error            { return nil }
+func (p *blockingProvider) SupportsStreaming() bool { return false }
+
+func TestAbortSessionRunsCancelsActiveRun(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: "en"}})
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{bus: bus.NewMessageBus(64), sessionMgr: sessMgr}
+	defer m.bus.Close()
+	sub := m.bus.SubscribeOutbound()
+
+	const key = "agent:main:abort"
+	sess, _ := sessMgr.GetOrCreate(key)
+	start := func(runID string, provider providers.Provider) {
+		orchestrator := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 3}, NewAgentState())
+		msg := &bus.InboundMessage{ID: runID, Channel: "telegram", ChatID: "42", Content: "hi"}
+		userMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}, Timestamp: time.Now().UnixMilli()}
+		m.processMessageAsync(context.Background(), msg, &Agent{}, orchestrator, []AgentMessage{userMsg}, key, userMsg, sess, 0)
+	}
+	waitReply := func() *bus.OutboundMessage {
+		t.Helper()
+		select {
+		case out := <-sub.Channel:
+			return out
+		case <-time.After(5 * time.Second):
+			t.Fatal("no terminal message")
+		}
+		return nil
+	}
+	pending := func() int {
+		m.pendingRunsMu.Lock()
+		defer m.pendingRunsMu.Unlock()
+		return len(m.pendingRuns)
+	}
+
+	provider := &blockingProvider{started: make(chan struct{})}
+	start("run-abort", provider)
+	<-provider.started
+	if ids := m.AbortSessionRuns("agent:main:other"); len(ids) != 0 {
+		t.Fatalf("aborted runs of another session: %v", ids)
+	}
+	ids := m.AbortSessionRuns(key)
+	if len(ids) != 1 || ids[0] != "run-abort" {
+		t.Fatalf("aborted run ids = %v, want [run-abort]", ids)
+	}
+	if out := waitReply(); out.ID != "run-abort" || !strings.Contains(out.Content, "aborted") {
+		t.Fatalf("terminal message = %+v, want aborted error", out)
+	}
+
+	// 正常完成的 Run 也会移除登记
+	start("run-ok", &steeringProvider{})
+	if out := waitReply(); out.ID != "run-ok" || out.Content != "reply" {
+		t.Fatalf("terminal message = %+v", out)
+	}
+	for deadline := time.Now().Add(2 * time.Second); pending() > 0 && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+	}
+	if n := pending(); n != 0 {
+		t.Fatalf("pending runs after completion = %d, want 0", n)
+	}
+	if ids := m.AbortSessionRuns(key); len(ids) != 0 {
+		t.Fatalf("aborted finished runs: %v", ids)
+	}
+}
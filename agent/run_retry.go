@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/types"
+	"go.uber.org/zap"
+)
+
+// runRetryableReasons 整次 Run 可重试的错误类型：均为临时故障，重新执行有机会成功。
+// 上下文溢出不在其中（输入不变，重试仍会溢出）
+var runRetryableReasons = []types.FailoverReason{
+	types.FailoverReasonNetworkError,
+	types.FailoverReasonServerError,
+	types.FailoverReasonTimeout,
+	types.FailoverReasonRateLimit,
+}
+
+// runRetryStrategy 按 agents.defaults.run_retry 构造整次 Run 的重试策略；未配置或 max_attempts<=1 时返回 nil
+func runRetryStrategy() *types.RetryStrategy {
+	cfg := config.Get()
+	if cfg == nil || cfg.Agents.Defaults.RunRetry == nil || cfg.Agents.Defaults.RunRetry.MaxAttempts <= 1 {
+		return nil
+	}
+	r := cfg.Agents.Defaults.RunRetry
+	strategy := (&types.RetryConfig{
+		MaxRetries:    r.MaxAttempts - 1,
+		InitialDelay:  time.Duration(r.BackoffMs) * time.Millisecond,
+		MaxDelay:      time.Duration(r.MaxBackoffMs) * time.Millisecond,
+		BackoffFactor: r.BackoffFactor,
+	}).ToRetryStrategy(types.NewSimpleErrorClassifier())
+	strategy.RetryableErrors = runRetryableReasons
+	return strategy
+}
+
+// runWithRetry 执行 run，以可重试的临时错误结束时按策略退避后重新执行。每次尝试都从同一份输入（运行前的会话状态）开始，
+// 不会重复写入用户消息；失败的尝试中已有工具执行完成（exec、写文件、发消息等有副作用）时不重试，以免重复执行；
+// Run 被中止或超时（ctx 结束）后不再重试。run 返回本次尝试的消息、是否执行过工具与错误
+func runWithRetry(ctx context.Context, sessionKey, runID string, run func() ([]AgentMessage, bool, error)) ([]AgentMessage, error) {
+	strategy := runRetryStrategy()
+	for attempt := 0; ; attempt++ {
+		messages, toolsExecuted, err := run()
+		if err == nil || strategy == nil || ctx.Err() != nil || !strategy.ShouldRetry(err, attempt) {
+			return messages, err
+		}
+		if toolsExecuted {
+			logger.Warn("Run failed with transient error after executing tools, not retrying",
+				zap.String("session_key", sessionKey),
+				zap.String("run_id", runID),
+				zap.Error(err))
+			return messages, err
+		}
+		logger.Warn("Run failed with transient error, retrying",
+			zap.String("session_key", sessionKey),
+			zap.String("run_id", runID),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", strategy.GetDelay(attempt)),
+			zap.Error(err))
+		if waitErr := strategy.Wait(ctx, attempt); waitErr != nil {
+			return messages, err
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// flakyProvider 前 failures 次调用返回 err，之后正常回复
+type flakyProvider struct {
+	fakeChatProvider
+	failures int32
+	err      error
+	calls    atomic.Int32
+}
+
+func (p *flakyProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	if p.calls.Add(1) <= p.failures {
+		return nil, p.err
+	}
+	return &providers.Response{Content: "recovered"}, nil
+}
+
+func TestRunRetriesTransientErrorFromSameSessionState(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"transient", errors.New("read tcp: connection reset by peer"), false, 2},
+		{"non-retryable", errors.New("401 Unauthorized: invalid api key"), true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := config.Get()
+			defer config.Set(orig)
+			cfg := &config.Config{}
+			cfg.Agents.Defaults.RunRetry = &config.RunRetryConfig{MaxAttempts: 3, BackoffMs: 1}
+			config.Set(cfg)
+
+			sessMgr, err := session.NewManager(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := &AgentManager{bus: bus.NewMessageBus(64), sessionMgr: sessMgr}
+			defer m.bus.Close()
+
+			const key = "agent:main:main"
+			sess, _ := sessMgr.GetOrCreate(key)
+			provider := &flakyProvider{failures: 1, err: tt.err}
+			orchestrator := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 3}, NewAgentState())
+			msg := &bus.InboundMessage{ID: "run-1", Channel: "websocket", ChatID: key, Content: "hi"}
+			userMsg := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "hi"}}, Timestamp: time.Now().UnixMilli()}
+
+			_, err = m.executeAgentRun(context.Background(), msg, nil, orchestrator, []AgentMessage{userMsg}, key, userMsg, sess, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("executeAgentRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := provider.calls.Load(); got != tt.wantCalls {
+				t.Fatalf("provider calls = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr {
+				return
+			}
+			history := sess.GetHistory(-1)
+			var users int
+			for _, h := range history {
+				if h.Role == "user" {
+					users++
+				}
+			}
+			if users != 1 || history[len(history)-1].Content != "recovered" {
+				t.Fatalf("unexpected history after retry: %+v", history)
+			}
+		})
+	}
+}
+
+// toolThenFailProvider 第一次调用请求执行工具，之后返回临时错误
+type toolThenFailProvider struct {
+	fakeChatProvider
+	calls atomic.Int32
+}
+
+func (p *toolThenFailProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	if p.calls.Add(1)%2 == 1 {
+		return &providers.Response{ToolCalls: []providers.ToolCall{{ID: "call", Name: "send", Params: map[string]interface{}{}}}}, nil
+	}
+	return nil, errors.New("read tcp: connection reset by peer")
+}
+
+// countingTool 记录执行次数
+type countingTool struct {
+	fakeTool
+	runs atomic.Int32
+}
+
+func (t *countingTool) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
+	t.runs.Add(1)
+	return ToolResult{Content: []ContentBlock{TextContent{Text: "sent"}}}, nil
+}
+
+func TestRunRetrySkipsAttemptsThatExecutedTools(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.RunRetry = &config.RunRetryConfig{MaxAttempts: 3, BackoffMs: 1}
+	config.Set(cfg)
+
+	tool := &countingTool{fakeTool: fakeTool{name: "send"}}
+	state := NewAgentState()
+	state.Tools = []Tool{tool}
+	o := NewOrchestrator(&LoopConfig{Provider: &toolThenFailProvider{}, MaxIterations: 5}, state)
+	prompt := []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "send it"}}}}
+
+	_, err := runWithRetry(context.Background(), "agent:main:main", "run-1", func() ([]AgentMessage, bool, error) {
+		msgs, runErr := o.Run(context.Background(), prompt, nil)
+		return msgs, o.ToolsExecuted() > 0, runErr
+	})
+	if err == nil {
+		t.Fatal("runWithRetry() error = nil, want the transient error")
+	}
+	if got := tool.runs.Load(); got != 1 {
+		t.Fatalf("tool executed %d times, want 1 (no retry after side effects)", got)
+	}
+}
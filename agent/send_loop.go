@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// MetadataSendChain 入站消息元数据：sessions_send 经过的会话链（最早的发送方在前）
+	MetadataSendChain = "sendChain"
+	// defaultSessionSendMaxDepth session_send_max_depth 未配置时的默认值
+	defaultSessionSendMaxDepth = 5
+)
+
+// sendChainTracker 记录各会话当前运行的 sessions_send 消息链（sessionKey -> 链），供该运行中再次发送时延长；零值可用
+type sendChainTracker struct {
+	mu     sync.Mutex
+	chains map[string][]string
+}
+
+// get 返回会话当前运行的消息链（不在链中时为 nil）
+func (t *sendChainTracker) get(sessionKey string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.chains[sessionKey])
+}
+
+// set 设置会话当前运行的消息链，chain 为空时清除
+func (t *sendChainTracker) set(sessionKey string, chain []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(chain) == 0 {
+		delete(t.chains, sessionKey)
+		return
+	}
+	if t.chains == nil {
+		t.chains = make(map[string][]string)
+	}
+	t.chains[sessionKey] = chain
+}
+
+// extend 消息注入正在执行的运行时，保留较长的链，使后续回发仍计入跳数
+func (t *sendChainTracker) extend(sessionKey string, chain []string) {
+	if len(chain) > len(t.get(sessionKey)) {
+		t.set(sessionKey, chain)
+	}
+}
+
+// sendChainFromMetadata 读取入站消息中的消息链（经总线传递时可能为 []interface{}）
+func sendChainFromMetadata(metadata map[string]interface{}) []string {
+	switch v := metadata[MetadataSendChain].(type) {
+	case []string:
+		return slices.Clone(v)
+	case []interface{}:
+		chain := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				chain = append(chain, s)
+			}
+		}
+		return chain
+	}
+	return nil
+}
+
+// sessionSendMaxDepth 读取 agents.defaults.session_send_max_depth
+func sessionSendMaxDepth() int {
+	if cfg := config.Get(); cfg != nil && cfg.Agents.Defaults.SessionSendMaxDepth > 0 {
+		return cfg.Agents.Defaults.SessionSendMaxDepth
+	}
+	return defaultSessionSendMaxDepth
+}
+
+// sendFromSession sessions_send 的实现：发送方为 ctx 中的 session_key，消息链为发送方当前运行的链加上发送方本身。
+// 目标已在链中（形成环）且链长超过 session_send_max_depth 时拒绝发送，打断 agent 之间的互发循环
+func (m *AgentManager) sendFromSession(ctx context.Context, sessionKey, message string) (SteerResult, error) {
+	requester, _ := ctx.Value("session_key").(string)
+	var chain []string
+	if requester != "" {
+		chain = append(m.sendChains.get(requester), requester)
+	}
+	if maxDepth := sessionSendMaxDepth(); slices.Contains(chain, sessionKey) && len(chain) > maxDepth {
+		logger.Warn("sessions_send loop detected, message dropped",
+			zap.String("from", requester),
+			zap.String("to", sessionKey),
+			zap.Strings("chain", chain),
+			zap.Int("max_depth", maxDepth))
+		return "", fmt.Errorf("message loop detected (%s -> %s): exceeds session_send_max_depth %d", strings.Join(chain, " -> "), sessionKey, maxDepth)
+	}
+
+	if runID, ok := m.SteerActiveRun(sessionKey, message); ok {
+		m.sendChains.extend(sessionKey, chain)
+		logger.Info("Steering message injected into active run",
+			zap.String("session_key", sessionKey),
+			zap.String("run_id", runID),
+			zap.Int("message_length", len(message)))
+		return SteerInterrupted, nil
+	}
+	var metadata map[string]interface{}
+	if len(chain) > 0 {
+		metadata = map[string]interface{}{MetadataSendChain: chain}
+	}
+	if err := m.queueSessionRun(ctx, sessionKey, message, metadata, nil); err != nil {
+		return "", err
+	}
+	return SteerQueued, nil
+}
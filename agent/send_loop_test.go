@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestSessionsSendPingPongLoopIsBroken(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.SessionSendMaxDepth = 3
+	config.Set(cfg)
+
+	m := &AgentManager{bus: bus.NewMessageBus(16)}
+	defer m.bus.Close()
+
+	const a, b = "agent:alpha:main", "agent:beta:main"
+	peer := map[string]string{a: b, b: a}
+
+	// alpha 的用户发起的运行（无消息链）先发给 beta，之后两个 agent 每次运行都回发给对方
+	ctx := context.WithValue(context.Background(), "session_key", a)
+	if _, err := m.sendFromSession(ctx, b, "ping"); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	sends := 1
+	for range 10 {
+		msg, err := m.bus.ConsumeInbound(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 与 executeAgentRun 相同：运行期间记录入站消息带来的链
+		m.sendChains.set(msg.ChatID, sendChainFromMetadata(msg.Metadata))
+		ctx := context.WithValue(context.Background(), "session_key", msg.ChatID)
+		_, err = m.sendFromSession(ctx, peer[msg.ChatID], "pong")
+		m.sendChains.set(msg.ChatID, nil)
+		if err != nil {
+			if !strings.Contains(err.Error(), "message loop detected") {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			break
+		}
+		sends++
+	}
+	if sends != 3 {
+		t.Fatalf("sends before loop was broken = %d, want 3", sends)
+	}
+
+	// 不成环的发送不受限制
+	m.sendChains.set(a, []string{"agent:x:main", "agent:y:main", "agent:z:main", "agent:w:main"})
+	if _, err := m.sendFromSession(context.WithValue(context.Background(), "session_key", a), "agent:v:main", "hi"); err != nil {
+		t.Fatalf("acyclic chain rejected: %v", err)
+	}
+}
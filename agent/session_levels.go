@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// 回复详略等级（会话元数据 verboseLevel）
+const (
+	verboseLevelConcise  = "concise"
+	verboseLevelDetailed = "detailed"
+)
+
+// sessionLevel 读取字符串元数据；布尔值视为 on/off
+func sessionLevel(sess *session.Session, key string) string {
+	switch v := sess.GetMetadata(key).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case bool:
+		if v {
+			return "on"
+		}
+		return "off"
+	}
+	return ""
+}
+
+// sessionThinkingLevel 会话的思考等级：thinkingLevel 优先，未设置或无法识别时使用 reasoningLevel
+func sessionThinkingLevel(sess *session.Session) string {
+	if level := providers.NormalizeThinkingLevel(sessionLevel(sess, session.MetadataThinkingLevel)); level != "" {
+		return level
+	}
+	return providers.NormalizeThinkingLevel(sessionLevel(sess, session.MetadataReasoningLevel))
+}
+
+// normalizeVerboseLevel 规范化回复详略：off/low/brief 为简洁，on/high/full 为详细；其余返回空
+func normalizeVerboseLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "off", "low", "minimal", "brief", "concise":
+		return verboseLevelConcise
+	case "on", "high", "full", "verbose", "detailed":
+		return verboseLevelDetailed
+	}
+	return ""
+}
+
+// verboseGuidance 按回复详略追加到 system prompt 的指引
+func verboseGuidance(level string) string {
+	switch level {
+	case verboseLevelConcise:
+		return "## Response Length\nThe user prefers concise replies: answer directly, skip preamble and recaps, and keep explanations to what is needed."
+	case verboseLevelDetailed:
+		return "## Response Length\nThe user prefers detailed replies: explain your reasoning, describe the steps and tool results that led to the answer, and mention relevant caveats."
+	}
+	return ""
+}
+
+// withSessionLevels 把会话的思考等级与回复详略带入本次运行选项
+func withSessionLevels(opts *RunOptions, sess *session.Session) *RunOptions {
+	if sess == nil {
+		return opts
+	}
+	thinking := sessionThinkingLevel(sess)
+	verbose := normalizeVerboseLevel(sessionLevel(sess, session.MetadataVerboseLevel))
+	if thinking == "" && verbose == "" {
+		return opts
+	}
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	opts.ThinkingLevel = thinking
+	opts.VerboseLevel = verbose
+	return opts
+}
+
+// thinkingChatOptions 当前模型支持推理（model_capabilities reasoning）时传递思考等级，否则忽略
+func (o *Orchestrator) thinkingChatOptions(model string) []providers.ChatOption {
+	if o.runOpts == nil || o.runOpts.ThinkingLevel == "" || !LookupModelCapabilities(model).Reasoning {
+		return nil
+	}
+	return []providers.ChatOption{providers.WithThinkingLevel(o.runOpts.ThinkingLevel)}
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestWithSessionLevels(t *testing.T) {
+	sess := &session.Session{Key: "agent:main:main", Metadata: map[string]interface{}{
+		session.MetadataReasoningLevel: "on",
+		session.MetadataVerboseLevel:   "off",
+	}}
+	opts := withSessionLevels(nil, sess)
+	if opts == nil || opts.ThinkingLevel != "medium" || opts.VerboseLevel != verboseLevelConcise {
+		t.Fatalf("opts = %+v", opts)
+	}
+
+	// thinkingLevel 优先于 reasoningLevel；不支持推理的模型不传思考等级
+	sess.PatchMetadata(map[string]interface{}{session.MetadataThinkingLevel: "high"})
+	o := &Orchestrator{runOpts: withSessionLevels(nil, sess)}
+	if o.runOpts.ThinkingLevel != "high" {
+		t.Fatalf("ThinkingLevel = %q", o.runOpts.ThinkingLevel)
+	}
+	if got := o.thinkingChatOptions("o3-mini"); len(got) != 1 {
+		t.Fatalf("reasoning model options = %d", len(got))
+	}
+	if got := o.thinkingChatOptions("gpt-4o"); len(got) != 0 {
+		t.Fatalf("non-reasoning model options = %d", len(got))
+	}
+}
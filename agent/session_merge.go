@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// sessions.merge 的合并方式
+const (
+	SessionMergeAppend     = "append"             // 源会话消息整体追加到目标会话之后
+	SessionMergeInterleave = "interleave-by-time" // 按轮次（user 消息开始的一段）的时间交错合并
+)
+
+// SessionMergeResult 合并结果
+type SessionMergeResult struct {
+	TargetKey     string `json:"key"`
+	SourceKey     string `json:"sourceKey"`
+	MessageCount  int    `json:"messageCount"` // 合并后目标会话的消息数
+	MergedCount   int    `json:"mergedCount"`  // 来自源会话的消息数
+	RemovedCount  int    `json:"removedCount"` // 序列校验移除的消息数（孤立 tool 结果等）
+	SourceDeleted bool   `json:"sourceDeleted"`
+}
+
+// MergeSessions 将 sourceKey 的消息合并到 targetKey（append 或 interleave-by-time），修复 tool 调用配对后保存目标会话；
+// deleteSource 为 true 时删除源会话。不允许合并到自身、合并 global 会话或有运行中 Run 的会话
+func (m *AgentManager) MergeSessions(sourceKey, targetKey, strategy string, deleteSource bool) (*SessionMergeResult, error) {
+	if sourceKey == "" || targetKey == "" {
+		return nil, fmt.Errorf("sourceKey and targetKey are required")
+	}
+	if sourceKey == targetKey {
+		return nil, fmt.Errorf("cannot merge a session into itself")
+	}
+	if sourceKey == "global" || targetKey == "global" {
+		return nil, fmt.Errorf("cannot merge the global session")
+	}
+	if strategy == "" {
+		strategy = SessionMergeAppend
+	}
+	if strategy != SessionMergeAppend && strategy != SessionMergeInterleave {
+		return nil, fmt.Errorf("unknown merge strategy: %s", strategy)
+	}
+
+	for _, key := range []string{sourceKey, targetKey} {
+		if !m.sessionMgr.Exists(key) {
+			return nil, fmt.Errorf("session not found: %s", key)
+		}
+		// 运行结束时会保存会话，合并运行中的会话会丢失其中一方的消息
+		if runIDs := m.SessionRunIDs(key); len(runIDs) > 0 {
+			return nil, fmt.Errorf("session %s has active runs (%s); wait for them or call chat.abort first", key, strings.Join(runIDs, ", "))
+		}
+	}
+	// 只读加载：不按重置策略清空不新鲜的源会话
+	source, err := m.sessionMgr.Lookup(sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	target, err := m.sessionMgr.Lookup(targetKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceMsgs := source.GetHistory(-1)
+	merged := mergeSessionMessages(target.GetHistory(-1), sourceMsgs, strategy)
+	repaired, _ := repairSessionHistory(merged, false)
+	target.SetMessages(repaired)
+	if err := m.sessionMgr.Save(target); err != nil {
+		return nil, fmt.Errorf("failed to save merged session: %w", err)
+	}
+
+	result := &SessionMergeResult{
+		TargetKey:    targetKey,
+		SourceKey:    sourceKey,
+		MessageCount: len(repaired),
+		MergedCount:  len(sourceMsgs),
+		RemovedCount: len(merged) - len(repaired),
+	}
+	if deleteSource {
+		if err := m.sessionMgr.Delete(sourceKey); err != nil {
+			return nil, fmt.Errorf("merged but failed to delete source session: %w", err)
+		}
+		result.SourceDeleted = true
+	}
+	logger.Info("Sessions merged",
+		zap.String("source_key", sourceKey),
+		zap.String("target_key", targetKey),
+		zap.String("strategy", strategy),
+		zap.Int("message_count", result.MessageCount),
+		zap.Int("removed", result.RemovedCount))
+	return result, nil
+}
+
+// mergeSessionMessages 合并两段消息。interleave-by-time 以轮次为单位排序（同一轮内 tool call 与结果不会被拆开），
+// 时间相同时目标会话在前
+func mergeSessionMessages(target, source []session.Message, strategy string) []session.Message {
+	merged := make([]session.Message, 0, len(target)+len(source))
+	if strategy != SessionMergeInterleave {
+		merged = append(merged, target...)
+		return append(merged, source...)
+	}
+	turns := append(splitSessionTurns(target), splitSessionTurns(source)...)
+	sort.SliceStable(turns, func(i, j int) bool {
+		return turns[i][0].Timestamp.Before(turns[j][0].Timestamp)
+	})
+	for _, turn := range turns {
+		merged = append(merged, turn...)
+	}
+	return merged
+}
+
+// splitSessionTurns 按 user 消息切分轮次；开头的非 user 消息单独成一轮
+func splitSessionTurns(msgs []session.Message) [][]session.Message {
+	var turns [][]session.Message
+	for _, msg := range msgs {
+		if msg.Role == "user" || len(turns) == 0 {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+	}
+	return turns
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestMergeSessionsInterleaveByTime(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &AgentManager{sessionMgr: sessMgr}
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+	toolMeta := map[string]interface{}{"tool_name": "read_file"}
+
+	target, _ := sessMgr.GetOrCreate("agent:main:main")
+	target.SetMessages([]session.Message{
+		{Role: "user", Content: "t1", Timestamp: at(0)},
+		{Role: "assistant", Content: "a1", Timestamp: at(1)},
+		{Role: "user", Content: "t2", Timestamp: at(10)},
+		{Role: "assistant", Content: "a2", Timestamp: at(11)},
+	})
+	source, _ := sessMgr.GetOrCreate("agent:main:alias")
+	source.SetMessages([]session.Message{
+		{Role: "user", Content: "s1", Timestamp: at(5)},
+		{Role: "assistant", ToolCalls: []session.ToolCall{{ID: "c1", Name: "read_file"}}, Timestamp: at(6)},
+		// 工具结果时间晚于目标会话的下一轮，仍须紧跟对应的 tool call
+		{Role: "tool", Content: "file", ToolCallID: "c1", Metadata: toolMeta, Timestamp: at(12)},
+		{Role: "assistant", Content: "s-done", Timestamp: at(13)},
+		// 孤立 tool 结果由序列校验移除
+		{Role: "tool", Content: "stale", ToolCallID: "gone", Metadata: toolMeta, Timestamp: at(14)},
+	})
+	for _, s := range []*session.Session{target, source} {
+		if err := sessMgr.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := m.MergeSessions("agent:main:alias", "agent:main:main", SessionMergeInterleave, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MessageCount != 8 || result.RemovedCount != 1 || !result.SourceDeleted {
+		t.Fatalf("result = %+v", result)
+	}
+
+	merged, _ := sessMgr.GetOrCreate("agent:main:main")
+	var got []string
+	for _, msg := range merged.GetHistory(-1) {
+		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+			got = append(got, "call:"+msg.ToolCalls[0].ID)
+			continue
+		}
+		got = append(got, msg.Content)
+	}
+	want := []string{"t1", "a1", "s1", "call:c1", "file", "s-done", "t2", "a2"}
+	if len(got) != len(want) {
+		t.Fatalf("merged = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("merged = %v, want %v", got, want)
+		}
+	}
+	if keys, _ := sessMgr.List(); len(keys) != 1 {
+		t.Fatalf("sessions after merge = %v, want source deleted", keys)
+	}
+
+	if _, err := m.MergeSessions("agent:main:main", "agent:main:main", SessionMergeAppend, false); err == nil {
+		t.Fatal("expected merging a session into itself to fail")
+	}
+	if _, err := m.MergeSessions("global", "agent:main:main", SessionMergeAppend, false); err == nil {
+		t.Fatal("expected merging the global session to fail")
+	}
+}
+
+func TestMergeSessionsKeepsStaleSourceAndRejectsActiveRuns(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, content := range map[string]string{"agent:main:main": "target", "agent:main:old": "stale source"} {
+		sess, _ := writer.GetOrCreate(key)
+		sess.SetMessages([]session.Message{{Role: "user", Content: content, Timestamp: time.Now().Add(-3 * time.Hour)}})
+		sess.UpdatedAt = time.Now().Add(-3 * time.Hour)
+		if err := writer.Save(sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 新的 Manager 未缓存这两个会话；按空闲策略它们都已不新鲜，合并不应先把它们重置
+	sessMgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessMgr.SetResetPolicy(&session.ResetPolicy{Mode: session.ResetModeIdle, IdleMinutes: 30})
+	m := &AgentManager{sessionMgr: sessMgr}
+
+	m.registerRunCancel("run-1", "agent:main:main", func() {})
+	if _, err := m.MergeSessions("agent:main:old", "agent:main:main", SessionMergeAppend, false); err == nil || !strings.Contains(err.Error(), "active runs") {
+		t.Fatalf("merge into a session with an active run: err = %v", err)
+	}
+	m.unregisterRunCancel("run-1")
+
+	if _, err := m.MergeSessions("agent:main:missing", "agent:main:main", SessionMergeAppend, false); err == nil {
+		t.Fatal("expected merging an unknown session to fail")
+	}
+	if sessMgr.Exists("agent:main:missing") {
+		t.Fatal("merge created the unknown source session")
+	}
+
+	result, err := m.MergeSessions("agent:main:old", "agent:main:main", SessionMergeAppend, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MergedCount != 1 || result.MessageCount != 2 {
+		t.Fatalf("result = %+v, want both stale histories kept", result)
+	}
+}
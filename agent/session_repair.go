@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// 会话格式错误（tool_call_id 不匹配、缺少 reasoning_content）的恢复方式，见 session.recover_on_format_error.mode
+const (
+	FormatErrorRecoveryRepair = "repair" // 默认：原地修复历史后重试
+	FormatErrorRecoveryDelete = "delete" // 删除会话后以空历史重试（旧行为）
+	FormatErrorRecoveryNone   = "none"   // 不处理，直接返回错误
+)
+
+// formatErrorRecoveryMode 读取 session.recover_on_format_error.mode，未配置时为 repair
+func formatErrorRecoveryMode() string {
+	if cfg := config.Get(); cfg != nil && cfg.Session.RecoverOnFormatError != nil {
+		switch mode := strings.ToLower(strings.TrimSpace(cfg.Session.RecoverOnFormatError.Mode)); mode {
+		case FormatErrorRecoveryDelete, FormatErrorRecoveryNone:
+			return mode
+		}
+	}
+	return FormatErrorRecoveryRepair
+}
+
+// sessionRepairReport 一次修复中移除的内容
+type sessionRepairReport struct {
+	OrphanedToolResults int // 没有对应 tool call 的 tool 结果
+	DanglingToolCalls   int // 没有 tool 结果的 tool call
+	MissingReasoning    int // 缺少 reasoning_content 的 assistant tool call 消息
+}
+
+func (r sessionRepairReport) changed() bool {
+	return r.OrphanedToolResults+r.DanglingToolCalls+r.MissingReasoning > 0
+}
+
+// repairSessionHistory 修复会话消息序列：移除孤立的 tool 结果与无结果的 tool call；
+// dropMissingReasoning 为 true 时同时移除缺少 reasoning_content 的 assistant tool call 消息及其结果。
+func repairSessionHistory(history []session.Message, dropMissingReasoning bool) ([]session.Message, sessionRepairReport) {
+	var report sessionRepairReport
+
+	msgs := history
+	if dropMissingReasoning {
+		msgs = make([]session.Message, 0, len(history))
+		for _, msg := range history {
+			if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+				if reasoning, _ := msg.Metadata["reasoning_content"].(string); strings.TrimSpace(reasoning) == "" {
+					report.MissingReasoning++
+					continue
+				}
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+
+	// 孤立 tool 结果：与发送前的序列校验一致
+	valid := filterOrphanedToolMessages(msgs)
+	report.OrphanedToolResults = len(msgs) - len(valid)
+
+	// 无结果的 tool call：从 assistant 中移除；移除后既无文本也无调用的消息整体丢弃
+	answered := make(map[string]bool)
+	for _, msg := range valid {
+		if msg.Role == "tool" && msg.ToolCallID != "" {
+			answered[msg.ToolCallID] = true
+		}
+	}
+	repaired := make([]session.Message, 0, len(valid))
+	for _, msg := range valid {
+		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+			var kept []session.ToolCall
+			for _, tc := range msg.ToolCalls {
+				if answered[tc.ID] {
+					kept = append(kept, tc)
+				} else {
+					report.DanglingToolCalls++
+				}
+			}
+			if len(kept) != len(msg.ToolCalls) {
+				msg.ToolCalls = kept
+				if len(kept) == 0 && strings.TrimSpace(msg.Content) == "" {
+					continue
+				}
+			}
+		}
+		repaired = append(repaired, msg)
+	}
+	return repaired, report
+}
+
+// repairSessionAndRetry 原地修复会话历史并重试本次运行；未发现可修复内容时返回 false，由调用方按原错误处理
+func (m *AgentManager) repairSessionAndRetry(ctx context.Context, msg *bus.InboundMessage, sessionKey string, sess *session.Session, agentMsg AgentMessage, dropMissingReasoning bool) bool {
+	repaired, report := repairSessionHistory(sess.GetHistory(-1), dropMissingReasoning)
+	if !report.changed() {
+		logger.Warn("Session format error but nothing to repair",
+			zap.String("session_key", sessionKey))
+		return false
+	}
+	sess.SetMessages(repaired)
+	if err := m.sessionMgr.Save(sess); err != nil {
+		logger.Error("Failed to save repaired session", zap.Error(err))
+		return false
+	}
+	logger.Info("Repaired session history, retrying",
+		zap.String("session_key", sessionKey),
+		zap.Int("orphaned_tool_results", report.OrphanedToolResults),
+		zap.Int("dangling_tool_calls", report.DanglingToolCalls),
+		zap.Int("missing_reasoning", report.MissingReasoning),
+		zap.Int("messages_after", len(repaired)))
+
+	allMessages := sessionMessagesToAgentMessages(repaired)
+	if msg.Channel != "websocket" && msg.Channel != "internal" {
+		allMessages = append(allMessages, agentMsg)
+	}
+	m.retryRunWithSession(ctx, msg, sessionKey, sess, allMessages, len(repaired))
+	return true
+}
+
+// retryRunWithSession 会话恢复后重试一次运行：成功则写回会话并发布回复，失败则发布错误
+func (m *AgentManager) retryRunWithSession(ctx context.Context, msg *bus.InboundMessage, sessionKey string, sess *session.Session, messages []AgentMessage, historyLen int) {
+	agentID, _, _ := ParseAgentSessionKey(sessionKey)
+	agent, ok := m.GetAgent(agentID)
+	if !ok {
+		agent = m.GetDefaultAgent()
+	}
+	if agent == nil {
+		logger.Error("No agent found for retry")
+		return
+	}
+
+	finalMessages, retryErr := agent.GetOrchestrator().Run(ctx, messages, nil)
+	if retryErr != nil {
+		logger.Error("Agent execution failed on retry", zap.Error(retryErr))
+		m.publishRunErrorToBus(ctx, msg.Channel, msg.ChatID, msg.ID, inboundLocale(msg), retryErr)
+		return
+	}
+	m.updateSession(sess, finalMessages, historyLen, msg.ID)
+	m.publishRunReply(ctx, msg, finalMessages)
+}
@@ -10,8 +10,10 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/smallnest/goclaw/agent/tools"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/skills"
 	"go.uber.org/zap"
@@ -26,7 +28,10 @@ type Skill struct {
 	Author      string `yaml:"author"`
 	Homepage    string `yaml:"homepage"`
 	Always      bool   `yaml:"always"`
-	Metadata    struct {
+	// RequiresAPIKey 技能需要 API key（通过 skills.update 设置），执行前校验并注入环境变量 APIKeyEnv
+	RequiresAPIKey bool   `yaml:"requiresApiKey"`
+	APIKeyEnv      string `yaml:"apiKeyEnv"`
+	Metadata       struct {
 		OpenClaw struct {
 			Emoji    string `yaml:"emoji"`
 			Always   bool   `yaml:"always"`
@@ -44,6 +49,8 @@ type Skill struct {
 	} `yaml:"metadata"`
 	Requires SkillRequirements `yaml:"requires"` // 兼容旧格式
 	Content  string            `yaml:"-"`        // 技能内容（Markdown）
+	Key      string            `yaml:"-"`        // 技能目录名（与 skills overlay 的 key 对应）
+	APIKey   string            `yaml:"-"`        // skills overlay 中设置的 API key（List 时填充）
 	// 缺失的依赖信息
 	MissingDeps *MissingDeps `yaml:"-"` // 解析时填充
 }
@@ -77,11 +84,14 @@ type SkillInstall struct {
 
 // SkillsLoader 技能加载器
 type SkillsLoader struct {
+	mu           sync.RWMutex
 	workspace    string
 	skillsDirs   []string
 	skills       map[string]*Skill
 	alwaysSkills []string
-	autoInstall  bool // 是否启用自动安装依赖
+	disabled     map[string]bool   // 被禁用的技能（技能名或目录名），不出现在 List 中
+	apiKeys      map[string]string // 技能名或目录名 -> API key
+	autoInstall  bool              // 是否启用自动安装依赖
 }
 
 // NewSkillsLoader 创建技能加载器
@@ -99,8 +109,70 @@ func (l *SkillsLoader) SetAutoInstall(enabled bool) {
 	l.autoInstall = enabled
 }
 
-// Discover 发现技能
+// SetDisabled 设置被禁用的技能（技能名或目录名均可），下次 List 起生效
+func (l *SkillsLoader) SetDisabled(names []string) {
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" {
+			disabled[name] = true
+		}
+	}
+	l.mu.Lock()
+	l.disabled = disabled
+	l.mu.Unlock()
+}
+
+// SetAPIKeys 设置各技能的 API key（key 为技能名或目录名），下次 List 起生效
+func (l *SkillsLoader) SetAPIKeys(apiKeys map[string]string) {
+	keys := make(map[string]string, len(apiKeys))
+	for name, key := range apiKeys {
+		if name != "" && key != "" {
+			keys[name] = key
+		}
+	}
+	l.mu.Lock()
+	l.apiKeys = keys
+	l.mu.Unlock()
+}
+
+// isDisabledLocked 判断技能是否被禁用，调用方需持有锁
+func (l *SkillsLoader) isDisabledLocked(skill *Skill) bool {
+	return l.disabled[skill.Name] || (skill.Key != "" && l.disabled[skill.Key])
+}
+
+// apiKeyLocked 返回技能的 API key（技能名优先于目录名），调用方需持有锁
+func (l *SkillsLoader) apiKeyLocked(skill *Skill) string {
+	if key := l.apiKeys[skill.Name]; key != "" {
+		return key
+	}
+	if skill.Key != "" {
+		return l.apiKeys[skill.Key]
+	}
+	return ""
+}
+
+// APIKeyEnvName 返回注入 API key 使用的环境变量名，默认 <NAME>_API_KEY
+func (s *Skill) APIKeyEnvName() string {
+	if s.APIKeyEnv != "" {
+		return s.APIKeyEnv
+	}
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s.Name)
+	return strings.ToUpper(name) + "_API_KEY"
+}
+
+// Discover 发现技能；重复调用会重新扫描目录（用于 skills.reload）
 func (l *SkillsLoader) Discover() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.skills = make(map[string]*Skill)
+	l.alwaysSkills = nil
+
 	// 只使用配置的技能目录（~/.goclaw/skills）
 	for _, dir := range l.skillsDirs {
 		if err := l.discoverInDir(dir); err != nil {
@@ -175,6 +247,7 @@ func (l *SkillsLoader) loadSkill(path string) error {
 	skill.MissingDeps = l.getMissingDeps(&skill)
 
 	// 使用目录名作为技能名
+	skill.Key = filepath.Base(path)
 	if skill.Name == "" {
 		skill.Name = filepath.Base(path)
 	}
@@ -233,6 +306,12 @@ func (l *SkillsLoader) parseSkillMetadata(content string, skill *Skill) error {
 		if always := frontmatter["always"]; always != "" {
 			skill.Always = always == "true"
 		}
+		if requires := frontmatter["requiresApiKey"]; requires != "" {
+			skill.RequiresAPIKey = requires == "true"
+		}
+		if env := frontmatter["apiKeyEnv"]; env != "" {
+			skill.APIKeyEnv = env
+		}
 
 		// 解析 OpenClaw/goclaw 元数据
 		metadata := skills.ParseOpenClawMetadata(frontmatter)
@@ -240,6 +319,9 @@ func (l *SkillsLoader) parseSkillMetadata(content string, skill *Skill) error {
 			// 映射到旧的 Skill 结构
 			skill.Metadata.OpenClaw.Emoji = metadata.Emoji
 			skill.Metadata.OpenClaw.Always = metadata.Always
+			if skill.APIKeyEnv == "" {
+				skill.APIKeyEnv = metadata.PrimaryEnv
+			}
 			if metadata.Requires != nil {
 				skill.Metadata.OpenClaw.Requires.Bins = metadata.Requires.Bins
 				skill.Metadata.OpenClaw.Requires.AnyBins = metadata.Requires.AnyBins
@@ -302,28 +384,78 @@ func (l *SkillsLoader) extractContent(content string) string {
 	return content[endIndex+7:] // 跳过 "---\n"
 }
 
-// List 列出所有技能
+// List 列出所有可用技能（不含被禁用的技能）
 func (l *SkillsLoader) List() []*Skill {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	result := make([]*Skill, 0, len(l.skills))
 	for _, skill := range l.skills {
+		if l.isDisabledLocked(skill) {
+			continue
+		}
+		if key := l.apiKeyLocked(skill); key != "" {
+			withKey := *skill
+			withKey.APIKey = key
+			skill = &withKey
+		}
 		result = append(result, skill)
 	}
 	return result
 }
 
+// Summaries 返回可用技能的名称与描述，供 list_skills 工具使用
+func (l *SkillsLoader) Summaries() []tools.SkillSummary {
+	skills := l.List()
+	result := make([]tools.SkillSummary, 0, len(skills))
+	for _, skill := range skills {
+		result = append(result, tools.SkillSummary{
+			Name:           skill.Name,
+			Description:    skill.Description,
+			Emoji:          skill.Metadata.OpenClaw.Emoji,
+			Always:         skill.Always || skill.Metadata.OpenClaw.Always,
+			RequiresAPIKey: skill.RequiresAPIKey,
+		})
+	}
+	return result
+}
+
+// filterLoadedSkills 过滤掉不在可用技能列表中的已加载技能（如被禁用的技能）
+func filterLoadedSkills(loaded []string, available []*Skill) []string {
+	if len(loaded) == 0 {
+		return loaded
+	}
+	names := make(map[string]bool, len(available))
+	for _, skill := range available {
+		names[skill.Name] = true
+	}
+	result := make([]string, 0, len(loaded))
+	for _, name := range loaded {
+		if names[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
 // Get 获取技能
 func (l *SkillsLoader) Get(name string) (*Skill, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	skill, ok := l.skills[name]
 	return skill, ok
 }
 
 // GetAlwaysSkills 获取始终加载的技能
 func (l *SkillsLoader) GetAlwaysSkills() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.alwaysSkills
 }
 
 // BuildSummary 构建技能摘要
 func (l *SkillsLoader) BuildSummary() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	if len(l.skills) == 0 {
 		return "No skills available."
 	}
@@ -350,7 +482,9 @@ func (l *SkillsLoader) BuildSummary() string {
 
 // LoadContent 加载技能内容
 func (l *SkillsLoader) LoadContent(name string) (string, error) {
+	l.mu.RLock()
 	skill, ok := l.skills[name]
+	l.mu.RUnlock()
 	if !ok {
 		return "", fmt.Errorf("skill not found: %s", name)
 	}
@@ -360,7 +494,7 @@ func (l *SkillsLoader) LoadContent(name string) (string, error) {
 
 // InstallDependencies 安装技能依赖
 func (l *SkillsLoader) InstallDependencies(skillName string) error {
-	skill, ok := l.skills[skillName]
+	skill, ok := l.Get(skillName)
 	if !ok {
 		return fmt.Errorf("skill not found: %s", skillName)
 	}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/agent/tools"
+)
+
+func writeTestSkill(t *testing.T, dir, name, description string, extraFrontmatter ...string) {
+	t.Helper()
+	skillDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(skillDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	frontmatter := append([]string{"name: " + name, "description: " + description}, extraFrontmatter...)
+	content := "---\n" + strings.Join(frontmatter, "\n") + "\n---\n# " + name + "\n"
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadSkillsTogglesNextRunSkillPrompt(t *testing.T) {
+	skillsDir := t.TempDir()
+	writeTestSkill(t, skillsDir, "weather", "Get the weather")
+	writeTestSkill(t, skillsDir, "github", "Work with GitHub")
+
+	loader := NewSkillsLoader(t.TempDir(), []string{skillsDir})
+	if err := loader.Discover(); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	cfg := &LoopConfig{
+		Skills:         loader.List(),
+		ContextBuilder: NewContextBuilder(nil, t.TempDir()),
+	}
+	agent := &Agent{loopConfig: cfg, state: NewAgentState()}
+	m := &AgentManager{agents: map[string]*Agent{"main": agent}, skillsLoader: loader}
+
+	state := NewAgentState()
+	before := NewOrchestrator(cfg, state)
+	if prompt := before.skillsPromptContent(state); !strings.Contains(prompt, `<skill name="weather">`) {
+		t.Fatalf("initial prompt should advertise weather skill:\n%s", prompt)
+	}
+
+	// 禁用 weather：下一次 Run 不再列出
+	if err := m.ReloadSkills([]string{"weather"}, nil); err != nil {
+		t.Fatalf("ReloadSkills() error = %v", err)
+	}
+	next := NewOrchestrator(cfg, state)
+	prompt := next.skillsPromptContent(state)
+	if strings.Contains(prompt, `<skill name="weather">`) {
+		t.Errorf("disabled skill still advertised:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, `<skill name="github">`) {
+		t.Errorf("enabled skill missing from prompt:\n%s", prompt)
+	}
+
+	// 已在运行中的 Run 保留开始时的快照
+	if !strings.Contains(before.skillsPromptContent(state), `<skill name="weather">`) {
+		t.Error("in-flight run should keep its skill snapshot")
+	}
+
+	// 已加载的 weather 在下一次 Run 中被移除
+	if got := filterLoadedSkills([]string{"weather", "github"}, next.skills); len(got) != 1 || got[0] != "github" {
+		t.Errorf("filterLoadedSkills() = %v, want [github]", got)
+	}
+
+	// 重新启用
+	if err := m.ReloadSkills(nil, nil); err != nil {
+		t.Fatalf("ReloadSkills() error = %v", err)
+	}
+	if prompt := NewOrchestrator(cfg, state).skillsPromptContent(state); !strings.Contains(prompt, `<skill name="weather">`) {
+		t.Errorf("re-enabled skill missing from prompt:\n%s", prompt)
+	}
+}
+
+// fakeTool 记录执行时 context 中的技能环境变量
+type fakeTool struct {
+	name string
+	env  []string
+}
+
+func (t *fakeTool) Name() string               { return t.name }
+func (t *fakeTool) Description() string        { return t.name }
+func (t *fakeTool) Parameters() map[string]any { return map[string]any{} }
+func (t *fakeTool) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
+	t.env = tools.SkillEnvFromContext(ctx)
+	return ToolResult{Content: []ContentBlock{TextContent{Text: "ok"}}}, nil
+}
+
+func TestSkillAPIKeyInjectedIntoToolExecution(t *testing.T) {
+	skillsDir := t.TempDir()
+	writeTestSkill(t, skillsDir, "weather", "Get the weather", "requiresApiKey: true")
+
+	loader := NewSkillsLoader(t.TempDir(), []string{skillsDir})
+	if err := loader.Discover(); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	cfg := &LoopConfig{Skills: loader.List(), ContextBuilder: NewContextBuilder(nil, t.TempDir())}
+	useSkill := &fakeTool{name: "use_skill"}
+	exec := &fakeTool{name: "exec"}
+	state := NewAgentState()
+	state.Tools = []Tool{useSkill, exec}
+
+	// 未设置 key：use_skill 返回提示通过 skills.update 设置的错误，技能不被加载
+	o := NewOrchestrator(cfg, state)
+	results, _ := o.executeToolCalls(context.Background(), []ToolCallContent{
+		{ID: "1", Name: "use_skill", Arguments: map[string]any{"skill_name": "weather"}},
+	}, state)
+	if errText, _ := results[0].Metadata["error"].(string); !strings.Contains(errText, "skills.update") {
+		t.Fatalf("expected missing api key error, got %q", errText)
+	}
+	if len(state.LoadedSkills) != 0 {
+		t.Fatalf("skill without api key should not be loaded, got %v", state.LoadedSkills)
+	}
+
+	// 设置 key 后：加载技能，后续工具执行可拿到 WEATHER_API_KEY
+	loader.SetAPIKeys(map[string]string{"weather": "secret-123"})
+	cfg.SetSkills(loader.List())
+	o = NewOrchestrator(cfg, state)
+	o.executeToolCalls(context.Background(), []ToolCallContent{
+		{ID: "2", Name: "use_skill", Arguments: map[string]any{"skill_name": "weather"}},
+	}, state)
+	if len(state.LoadedSkills) != 1 {
+		t.Fatalf("LoadedSkills = %v, want [weather]", state.LoadedSkills)
+	}
+	o.executeToolCalls(context.Background(), []ToolCallContent{
+		{ID: "3", Name: "exec", Arguments: map[string]any{"command": "curl"}},
+	}, state)
+	if len(exec.env) != 1 || exec.env[0] != "WEATHER_API_KEY=secret-123" {
+		t.Errorf("tool env = %v, want [WEATHER_API_KEY=secret-123]", exec.env)
+	}
+	if strings.Contains(o.skillsPromptContent(state), "secret-123") {
+		t.Error("api key must not leak into the skill prompt")
+	}
+}
+
+func TestListSkillsToolReturnsEnabledSkills(t *testing.T) {
+	skillsDir := t.TempDir()
+	writeTestSkill(t, skillsDir, "weather", "Get the weather forecast")
+	writeTestSkill(t, skillsDir, "git-helper", "Work with git repositories")
+	writeTestSkill(t, skillsDir, "legacy", "Disabled skill")
+
+	loader := NewSkillsLoader(t.TempDir(), []string{skillsDir})
+	if err := loader.Discover(); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	loader.SetDisabled([]string{"legacy"})
+
+	tool := tools.NewListSkillsTool(loader.Summaries)
+	out, err := tool.Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var result struct {
+		Count  int                  `json:"count"`
+		Skills []tools.SkillSummary `json:"skills"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("unmarshal %q: %v", out, err)
+	}
+	if result.Count != 2 || len(result.Skills) != 2 {
+		t.Fatalf("skills = %+v, want weather and git-helper", result.Skills)
+	}
+	if result.Skills[0].Name != "git-helper" || result.Skills[1].Name != "weather" {
+		t.Fatalf("skills not sorted by name: %+v", result.Skills)
+	}
+	if result.Skills[1].Description != "Get the weather forecast" {
+		t.Fatalf("description = %q", result.Skills[1].Description)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]interface{}{"query": "FORECAST"})
+	if !strings.Contains(out, `"count":1`) || !strings.Contains(out, "weather") {
+		t.Fatalf("query filter result = %s", out)
+	}
+}
+
+func TestRemovedSkillStaysLoadedForInFlightRun(t *testing.T) {
+	skillsDir := t.TempDir()
+	writeTestSkill(t, skillsDir, "weather", "Get the weather")
+
+	loader := NewSkillsLoader(t.TempDir(), []string{skillsDir})
+	if err := loader.Discover(); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	cfg := &LoopConfig{Skills: loader.List(), ContextBuilder: NewContextBuilder(nil, t.TempDir())}
+	m := &AgentManager{agents: map[string]*Agent{"main": {loopConfig: cfg, state: NewAgentState()}}, skillsLoader: loader}
+
+	state := NewAgentState()
+	state.LoadedSkills = []string{"weather"}
+	inFlight := NewOrchestrator(cfg, state)
+
+	// skills.remove：删除目录后重载
+	if err := os.RemoveAll(filepath.Join(skillsDir, "weather")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ReloadSkills(nil, nil); err != nil {
+		t.Fatalf("ReloadSkills() error = %v", err)
+	}
+	if prompt := inFlight.skillsPromptContent(state); !strings.Contains(prompt, "# weather") {
+		t.Errorf("in-flight run lost its loaded skill content:\n%s", prompt)
+	}
+	if next := NewOrchestrator(cfg, NewAgentState()); len(next.skills) != 0 {
+		t.Errorf("removed skill still available to the next run: %v", next.skills)
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// SteerResult 表示 steering 消息的投递方式
+type SteerResult string
+
+const (
+	// SteerInterrupted 会话有正在执行的 Run，消息已注入并在该 Run 内处理
+	SteerInterrupted SteerResult = "interrupted"
+	// SteerQueued 会话没有正在执行的 Run，消息作为该会话的下一次运行排入 session lane
+	SteerQueued SteerResult = "queued"
+)
+
+// activeRun 会话当前正在执行的 Run
+type activeRun struct {
+	runID        string
+	orchestrator *Orchestrator
+}
+
+// setActiveRun 记录会话正在执行的 Run（session lane 保证同一会话同一时刻只有一个）
+func (m *AgentManager) setActiveRun(sessionKey, runID string, o *Orchestrator) {
+	m.activeRunsMu.Lock()
+	defer m.activeRunsMu.Unlock()
+	if m.activeRuns == nil {
+		m.activeRuns = make(map[string]*activeRun)
+	}
+	m.activeRuns[sessionKey] = &activeRun{runID: runID, orchestrator: o}
+}
+
+// clearActiveRun 清除会话的运行记录（仅当仍是同一个 orchestrator 时）
+func (m *AgentManager) clearActiveRun(sessionKey string, o *Orchestrator) {
+	m.activeRunsMu.Lock()
+	defer m.activeRunsMu.Unlock()
+	if r, ok := m.activeRuns[sessionKey]; ok && r.orchestrator == o {
+		delete(m.activeRuns, sessionKey)
+	}
+}
+
+// SteerActiveRun 将消息注入会话正在执行的 Run；返回该 Run 的 runId，无运行中的 Run 时 ok 为 false
+func (m *AgentManager) SteerActiveRun(sessionKey, message string) (runID string, ok bool) {
+	m.activeRunsMu.Lock()
+	r := m.activeRuns[sessionKey]
+	m.activeRunsMu.Unlock()
+	if r == nil {
+		return "", false
+	}
+	msg := AgentMessage{
+		Role:      RoleUser,
+		Content:   []ContentBlock{TextContent{Text: message}},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if !r.orchestrator.Steer(msg) {
+		return "", false
+	}
+	return r.runID, true
+}
+
+// SteerSession 向会话发送 steering 消息：有正在执行的 Run 时中断并在其中处理（SteerInterrupted），
+// 否则作为该会话的下一次运行排队（SteerQueued），排在 session lane 中已排队的运行之后
+func (m *AgentManager) SteerSession(ctx context.Context, sessionKey, message string) (SteerResult, error) {
+	if runID, ok := m.SteerActiveRun(sessionKey, message); ok {
+		logger.Info("Steering message injected into active run",
+			zap.String("session_key", sessionKey),
+			zap.String("run_id", runID),
+			zap.Int("message_length", len(message)))
+		return SteerInterrupted, nil
+	}
+	if err := m.queueSessionRun(ctx, sessionKey, message, nil, nil); err != nil {
+		return "", err
+	}
+	logger.Info("Steering message queued as next run",
+		zap.String("session_key", sessionKey),
+		zap.Int("message_length", len(message)))
+	return SteerQueued, nil
+}
+
+// queuedRunSessionKey 返回排队运行所属的会话键（bus.MetadataQueuedSession），普通入站消息为空
+func queuedRunSessionKey(msg *bus.InboundMessage) string {
+	key, _ := msg.Metadata[bus.MetadataQueuedSession].(string)
+	return key
+}
+
+// queueSessionRun 发布一条排队运行的入站消息（可带元数据），由 RouteInbound 按 sessionKey 在该会话 lane 中启动新的运行。
+// origin 为触发排队的原始入站消息：回复投递到其渠道与聊天；为 nil 或 internal 时以 internal 发布，不投递回复
+func (m *AgentManager) queueSessionRun(ctx context.Context, sessionKey, message string, metadata map[string]interface{}, origin *bus.InboundMessage) error {
+	if _, _, ok := session.ParseAgentSessionKey(sessionKey); !ok {
+		return fmt.Errorf("invalid session key: %s", sessionKey)
+	}
+	if m.bus == nil {
+		return fmt.Errorf("no message bus to queue run for session: %s", sessionKey)
+	}
+	msg := &bus.InboundMessage{
+		Channel:   "internal",
+		ChatID:    sessionKey,
+		Content:   message,
+		Metadata:  maps.Clone(metadata),
+		Timestamp: time.Now(),
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]interface{})
+	}
+	msg.Metadata[bus.MetadataQueuedSession] = sessionKey
+	if origin != nil && origin.Channel != "internal" {
+		msg.Channel = origin.Channel
+		msg.AccountID = origin.AccountID
+		msg.SenderID = origin.SenderID
+		msg.ChatID = origin.ChatID
+		if locale := inboundLocale(origin); locale != "" {
+			msg.Metadata["locale"] = locale
+		}
+	}
+	return m.bus.PublishInbound(ctx, msg)
+}
+
+// requeueSteering Run 结束时仍未处理的 steering 消息排入该会话的下一次运行，避免丢失；
+// 须在 updateSession 保存本次运行之后调用，回复投递到原始入站消息的渠道
+func (m *AgentManager) requeueSteering(ctx context.Context, origin *bus.InboundMessage, sessionKey string, msgs []AgentMessage) {
+	for _, msg := range msgs {
+		if err := m.queueSessionRun(context.WithoutCancel(ctx), sessionKey, extractTextContent(msg), nil, origin); err != nil {
+			logger.Warn("Failed to requeue steering message",
+				zap.String("session_key", sessionKey),
+				zap.Error(err))
+		}
+	}
+}
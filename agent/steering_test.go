@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// steeringProvider 第一次调用时执行 onFirstCall（模拟运行中收到 steering），并记录每次调用的消息
+type steeringProvider struct {
+	onFirstCall func()
+	calls       [][]providers.Message
+}
+
+func (p *steeringProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.calls = append(p.calls, messages)
+	if len(p.calls) == 1 && p.onFirstCall != nil {
+		p.onFirstCall()
+	}
+	return &providers.Response{Content: "reply"}, nil
+}
+
+func (p *steeringProvider) ChatWithTools(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+func (p *steeringProvider) Close() error            { return nil }
+func (p *steeringProvider) SupportsStreaming() bool { return false }
+
+func TestSteerSessionInterruptsActiveRun(t *testing.T) {
+	const key = "agent:main:main"
+	m := &AgentManager{bus: bus.NewMessageBus(10)}
+	defer m.bus.Close()
+
+	var result SteerResult
+	provider := &steeringProvider{}
+	provider.onFirstCall = func() {
+		var err error
+		result, err = m.SteerSession(context.Background(), key, "stop and summarize")
+		if err != nil {
+			t.Errorf("SteerSession() error = %v", err)
+		}
+	}
+	state := NewAgentState()
+	state.SessionKey = key
+	o := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 5}, state)
+
+	m.setActiveRun(key, "run-1", o)
+	prompt := AgentMessage{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "long task"}}}
+	final, err := o.Run(context.Background(), []AgentMessage{prompt}, nil)
+	m.clearActiveRun(key, o)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result != SteerInterrupted {
+		t.Fatalf("SteerSession() = %q, want %q", result, SteerInterrupted)
+	}
+	// steering 消息在同一 Run 内处理：第二次 LLM 调用包含该消息，最终消息中也有它
+	if len(provider.calls) != 2 {
+		t.Fatalf("LLM calls = %d, want 2", len(provider.calls))
+	}
+	last := provider.calls[1][len(provider.calls[1])-1]
+	if last.Role != "user" || last.Content != "stop and summarize" {
+		t.Errorf("second call last message = %+v, want steering message", last)
+	}
+	found := false
+	for _, msg := range final {
+		if msg.Role == RoleUser && extractTextContent(msg) == "stop and summarize" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("steering message missing from final messages")
+	}
+	if left := o.takeSteering(); len(left) != 0 {
+		t.Errorf("unprocessed steering messages = %d, want 0", len(left))
+	}
+}
+
+func TestSteerSessionQueuesWhenNoActiveRun(t *testing.T) {
+	const key = "agent:main:main"
+	m := &AgentManager{bus: bus.NewMessageBus(10)}
+	defer m.bus.Close()
+
+	// 已结束的 Run 不再接受 steering
+	o := NewOrchestrator(&LoopConfig{Provider: &fakeChatProvider{}, MaxIterations: 1}, NewAgentState())
+	m.setActiveRun(key, "run-1", o)
+	if o.Steer(AgentMessage{Role: RoleUser}) {
+		t.Fatal("Steer() on idle orchestrator should return false")
+	}
+
+	result, err := m.SteerSession(context.Background(), key, "next question")
+	if err != nil {
+		t.Fatalf("SteerSession() error = %v", err)
+	}
+	if result != SteerQueued {
+		t.Fatalf("SteerSession() = %q, want %q", result, SteerQueued)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := m.bus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected queued inbound run: %v", err)
+	}
+	if msg.Channel != "internal" || msg.ChatID != key || msg.Content != "next question" || queuedRunSessionKey(msg) != key {
+		t.Errorf("queued inbound = %+v", msg)
+	}
+
+	if _, err := m.SteerSession(context.Background(), "not-a-session", "x"); err == nil || !strings.Contains(err.Error(), "invalid session key") {
+		t.Errorf("SteerSession() with invalid key error = %v", err)
+	}
+}
+
+func TestQueuedSteeringRepliesOnOriginChannel(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus(16)
+	defer msgBus.Close()
+	m := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        msgBus,
+		Provider:   &fakeChatProvider{},
+		SessionMgr: sessMgr,
+		Tools:      NewToolRegistry(),
+		DataDir:    t.TempDir(),
+	})
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Workspace: config.WorkspaceConfig{Path: workspace},
+		Agents:    config.AgentsConfig{List: []config.AgentConfig{{ID: "main", Default: true}}},
+	}
+	if err := m.SetupFromConfig(cfg, NewContextBuilder(NewMemoryStore(workspace), workspace)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 运行结束时仍未处理的 steering 按原始入站消息的渠道排队
+	const key = "agent:main:telegram:bot:dm:42"
+	origin := &bus.InboundMessage{ID: "run-1", Channel: "telegram", AccountID: "bot", ChatID: "42", Content: "long task"}
+	m.requeueSteering(context.Background(), origin, key, []AgentMessage{{
+		Role: RoleUser, Content: []ContentBlock{TextContent{Text: "/new and summarize"}},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queued, err := msgBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected queued inbound run: %v", err)
+	}
+	if queued.Channel != "telegram" || queued.ChatID != "42" || queuedRunSessionKey(queued) != key {
+		t.Fatalf("queued inbound = %+v", queued)
+	}
+
+	sub := msgBus.SubscribeOutbound()
+	defer sub.Unsubscribe()
+	if err := m.RouteInbound(ctx, queued); err != nil {
+		t.Fatalf("RouteInbound: %v", err)
+	}
+	select {
+	case out := <-sub.Channel:
+		if out.Channel != "telegram" || out.ChatID != "42" || out.Content != "done" {
+			t.Fatalf("reply = %+v", out)
+		}
+	case <-ctx.Done():
+		t.Fatal("queued steering run got no reply on the origin channel")
+	}
+
+	// 排队的消息不按命令处理，用户消息与回复在 lane 内一起写入会话
+	sess, err := sessMgr.Lookup(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := sess.GetHistory(0)
+	if len(history) != 2 || history[0].Role != "user" || history[0].Content != "/new and summarize" || history[1].Content != "done" {
+		t.Fatalf("history = %+v", history)
+	}
+}
@@ -146,6 +146,18 @@ func (r *SubagentRegistry) GetRun(runID string) (*SubagentRunRecord, bool) {
 	return record, ok
 }
 
+// ListRuns 列出所有分身运行
+func (r *SubagentRegistry) ListRuns() []*SubagentRunRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*SubagentRunRecord, 0, len(r.runs))
+	for _, record := range r.runs {
+		result = append(result, record)
+	}
+	return result
+}
+
 // ListRunsForRequester 列出请求者的所有分身运行
 func (r *SubagentRegistry) ListRunsForRequester(requesterSessionKey string) []*SubagentRunRecord {
 	r.mu.RLock()
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/i18n"
+)
+
+// defaultToolErrorMaxConsecutive stop_after_n 未配置 N 时的默认值
+const defaultToolErrorMaxConsecutive = 3
+
+// ToolErrorStopError 工具报错策略触发时结束运行的错误，Error() 为面向用户的提示
+type ToolErrorStopError struct {
+	Tool  string
+	Count int
+	Err   string
+}
+
+func (e *ToolErrorStopError) Error() string {
+	return i18n.T(i18n.RunToolErrorStop, e.Tool, e.Count, e.Err)
+}
+
+// toolErrorStreak 单个工具的连续相同错误
+type toolErrorStreak struct {
+	err   string
+	count int
+}
+
+// toolErrorTracker 在一次运行内按工具统计连续相同错误（tool_error_policy）
+type toolErrorTracker struct {
+	limit   int // 0 表示不停止（continue）
+	streaks map[string]*toolErrorStreak
+}
+
+// newToolErrorTracker 按策略创建；continue（或空）时 observe 永远返回 nil
+func newToolErrorTracker(policy string, maxConsecutive int) *toolErrorTracker {
+	t := &toolErrorTracker{streaks: make(map[string]*toolErrorStreak)}
+	switch policy {
+	case config.ToolErrorPolicyStop:
+		t.limit = 1
+	case config.ToolErrorPolicyStopAfterN:
+		t.limit = maxConsecutive
+		if t.limit <= 0 {
+			t.limit = defaultToolErrorMaxConsecutive
+		}
+	}
+	return t
+}
+
+// observe 记录一批工具结果：成功或错误内容变化时重置该工具的计数；达到上限时返回 ToolErrorStopError
+func (t *toolErrorTracker) observe(results []AgentMessage) error {
+	if t.limit <= 0 {
+		return nil
+	}
+	for _, res := range results {
+		name, _ := res.Metadata["tool_name"].(string)
+		errText, failed := res.Metadata["error"].(string)
+		if !failed {
+			delete(t.streaks, name)
+			continue
+		}
+		s := t.streaks[name]
+		if s == nil || s.err != errText {
+			s = &toolErrorStreak{err: errText}
+			t.streaks[name] = s
+		}
+		s.count++
+		if s.count >= t.limit {
+			return &ToolErrorStopError{Tool: name, Count: s.count, Err: errText}
+		}
+	}
+	return nil
+}
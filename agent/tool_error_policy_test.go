@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// toolCallingProvider 每次都请求调用同一个工具
+type toolCallingProvider struct {
+	fakeChatProvider
+	calls int
+}
+
+func (p *toolCallingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, options ...providers.ChatOption) (*providers.Response, error) {
+	p.calls++
+	return &providers.Response{ToolCalls: []providers.ToolCall{{ID: "call", Name: "broken", Params: map[string]interface{}{}}}}, nil
+}
+
+// failingTool 总是返回相同错误
+type failingTool struct{ fakeTool }
+
+func (t *failingTool) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
+	return ToolResult{}, errors.New("missing API key")
+}
+
+func TestToolErrorPolicyStopsAfterRepeatedErrors(t *testing.T) {
+	provider := &toolCallingProvider{}
+	cfg := &LoopConfig{
+		Provider:                provider,
+		MaxIterations:           10,
+		ToolErrorPolicy:         config.ToolErrorPolicyStopAfterN,
+		ToolErrorMaxConsecutive: 2,
+	}
+	state := NewAgentState()
+	state.Tools = []Tool{&failingTool{fakeTool{name: "broken"}}}
+	o := NewOrchestrator(cfg, state)
+
+	_, err := o.Run(context.Background(), []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "go"}}}}, nil)
+	var stopErr *ToolErrorStopError
+	if !errors.As(err, &stopErr) {
+		t.Fatalf("Run() error = %v, want ToolErrorStopError", err)
+	}
+	if stopErr.Tool != "broken" || stopErr.Count != 2 {
+		t.Fatalf("stop error = %+v", stopErr)
+	}
+	if provider.calls != 2 {
+		t.Fatalf("provider calls = %d, want run to stop after 2 failing turns", provider.calls)
+	}
+	if msg := friendlyRunErrorMessage(err); msg != stopErr.Error() {
+		t.Fatalf("friendly message = %q, want %q", msg, stopErr.Error())
+	}
+}
+
+func TestToolErrorPolicyContinueRunsToMaxIterations(t *testing.T) {
+	provider := &toolCallingProvider{}
+	state := NewAgentState()
+	state.Tools = []Tool{&failingTool{fakeTool{name: "broken"}}}
+	o := NewOrchestrator(&LoopConfig{Provider: provider, MaxIterations: 4}, state)
+
+	_, err := o.Run(context.Background(), []AgentMessage{{Role: RoleUser, Content: []ContentBlock{TextContent{Text: "go"}}}}, nil)
+	var stopErr *ToolErrorStopError
+	if err == nil || errors.As(err, &stopErr) {
+		t.Fatalf("Run() error = %v, want max iterations error", err)
+	}
+	if provider.calls != 4 {
+		t.Fatalf("provider calls = %d, want 4", provider.calls)
+	}
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/smallnest/goclaw/agent/tools"
 )
@@ -90,6 +91,22 @@ func (a *toolAdapter) Parameters() map[string]any {
 	return result
 }
 
+// SensitiveArgs 透传底层工具声明的敏感参数
+func (a *toolAdapter) SensitiveArgs() []string {
+	if s, ok := a.tool.(tools.SensitiveArgsTool); ok {
+		return s.SensitiveArgs()
+	}
+	return nil
+}
+
+// ExecTimeout 透传底层工具声明的执行超时
+func (a *toolAdapter) ExecTimeout() time.Duration {
+	if t, ok := a.tool.(tools.TimeoutTool); ok {
+		return t.ExecTimeout()
+	}
+	return 0
+}
+
 func (a *toolAdapter) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
 	// Convert params to existing format
 	existingParams := make(map[string]interface{})
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultToolTimeout 单次工具调用的默认执行超时（tools.timeout_seconds 为 0 时）
+const DefaultToolTimeout = 120 * time.Second
+
+// toolTimeoutGrace 自带超时的工具在其超时之后额外等待的时间，让工具先按自己的超时返回（如 exec 返回已有输出）
+const toolTimeoutGrace = 5 * time.Second
+
+// ToolTimeoutError 工具在超时时间内未返回；作为工具结果交给模型，由模型决定如何继续
+type ToolTimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	if e.Timeout < time.Second {
+		return fmt.Sprintf("tool %s timed out after %s", e.Tool, e.Timeout)
+	}
+	return fmt.Sprintf("tool %s timed out after %ds", e.Tool, int(e.Timeout.Seconds()))
+}
+
+// toolTimeout 工具调用的超时：tools.timeout_seconds 与工具自身超时（tools.TimeoutTool）加宽限中较长者；
+// 工具声明 tools.NoExecTimeout 时返回 0，不设整体超时
+func (o *Orchestrator) toolTimeout(tool Tool) time.Duration {
+	timeout := DefaultToolTimeout
+	if o.config.ToolTimeout > 0 {
+		timeout = o.config.ToolTimeout
+	}
+	if t, ok := tool.(tools.TimeoutTool); ok {
+		own := t.ExecTimeout()
+		if own < 0 {
+			return 0
+		}
+		timeout = max(timeout, own+toolTimeoutGrace)
+	}
+	return timeout
+}
+
+// executeToolWithTimeout 在带截止时间的 context 中执行工具。超时后立即返回超时结果，不再等待工具；
+// 工具在后台 goroutine 中执行，结果通道带缓冲，忽略 context 的工具最终返回时 goroutine 即可退出，
+// 超时后的进度更新不再发出
+func (o *Orchestrator) executeToolWithTimeout(ctx context.Context, tool Tool, tc ToolCallContent, onUpdate func(ToolResult)) (ToolResult, error) {
+	timeout := o.toolTimeout(tool)
+	if timeout == 0 {
+		return tool.Execute(ctx, tc.Arguments, onUpdate)
+	}
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result ToolResult
+		err    error
+	}
+	var abandoned atomic.Bool
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := tool.Execute(toolCtx, tc.Arguments, func(partial ToolResult) {
+			if !abandoned.Load() {
+				onUpdate(partial)
+			}
+		})
+		if abandoned.Load() {
+			logger.Warn("Tool returned after its timeout, result discarded",
+				zap.String("tool_id", tc.ID),
+				zap.String("tool_name", tc.Name))
+		}
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-toolCtx.Done():
+		abandoned.Store(true)
+		if ctx.Err() != nil {
+			return ToolResult{}, ctx.Err()
+		}
+		err := &ToolTimeoutError{Tool: tc.Name, Timeout: timeout}
+		logger.Warn("Tool execution timed out",
+			zap.String("tool_id", tc.ID),
+			zap.String("tool_name", tc.Name),
+			zap.Duration("timeout", timeout))
+		return ToolResult{
+			Content: []ContentBlock{TextContent{Text: err.Error()}},
+			Details: map[string]any{"error": err.Error(), "timeout": true},
+		}, err
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/agent/tools"
+)
+
+// hangingTool 忽略 context 取消，直到 release 关闭才返回
+type hangingTool struct {
+	release chan struct{}
+}
+
+func (t *hangingTool) Name() string               { return "hang" }
+func (t *hangingTool) Description() string        { return "hang" }
+func (t *hangingTool) Parameters() map[string]any { return map[string]any{} }
+func (t *hangingTool) Execute(ctx context.Context, params map[string]any, onUpdate func(ToolResult)) (ToolResult, error) {
+	<-t.release
+	onUpdate(ToolResult{Content: []ContentBlock{TextContent{Text: "late"}}})
+	return ToolResult{Content: []ContentBlock{TextContent{Text: "done"}}}, nil
+}
+
+func TestHungToolTimesOut(t *testing.T) {
+	hang := &hangingTool{release: make(chan struct{})}
+	defer close(hang.release)
+	state := NewAgentState()
+	state.Tools = []Tool{hang, &fakeTool{name: "exec"}}
+	o := NewOrchestrator(&LoopConfig{ToolTimeout: 50 * time.Millisecond}, state)
+
+	start := time.Now()
+	results, _ := o.executeToolCalls(context.Background(), []ToolCallContent{
+		{ID: "1", Name: "hang"},
+		{ID: "2", Name: "exec"},
+	}, state)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("executeToolCalls blocked for %v", elapsed)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %d", len(results))
+	}
+	if errText, _ := results[0].Metadata["error"].(string); errText != "tool hang timed out after 50ms" {
+		t.Fatalf("timeout error = %q", errText)
+	}
+	if text := extractToolResultContent(results[0].Content); text != "tool hang timed out after 50ms" {
+		t.Fatalf("timeout result content = %q", text)
+	}
+	if _, failed := results[1].Metadata["error"]; failed {
+		t.Fatalf("other tool should succeed: %+v", results[1].Metadata)
+	}
+}
+
+func TestToolTimeoutHonorsToolOwnTimeout(t *testing.T) {
+	o := NewOrchestrator(&LoopConfig{ToolTimeout: 30 * time.Second}, NewAgentState())
+	noop := func(ctx context.Context, params map[string]interface{}) (string, error) { return "", nil }
+
+	plain := ToAgentTools([]tools.Tool{tools.NewBaseTool("read_file", "", nil, noop)})[0]
+	if got := o.toolTimeout(plain); got != 30*time.Second {
+		t.Errorf("plain tool timeout = %v, want the global 30s", got)
+	}
+	shell := ToAgentTools([]tools.Tool{tools.NewBaseTool("exec", "", nil, noop).WithExecTimeout(10 * time.Minute)})[0]
+	if got := o.toolTimeout(shell); got != 10*time.Minute+toolTimeoutGrace {
+		t.Errorf("exec timeout = %v, want its own timeout plus grace", got)
+	}
+	short := ToAgentTools([]tools.Tool{tools.NewBaseTool("exec", "", nil, noop).WithExecTimeout(5 * time.Second)})[0]
+	if got := o.toolTimeout(short); got != 30*time.Second {
+		t.Errorf("short tool timeout = %v, want the global 30s", got)
+	}
+	spawn := ToAgentTools([]tools.Tool{tools.NewBaseTool("spawn", "", nil, noop).WithExecTimeout(tools.NoExecTimeout)})[0]
+	if got := o.toolTimeout(spawn); got != 0 {
+		t.Errorf("spawn timeout = %v, want none", got)
+	}
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// AgentActivitySummarizer 汇总 agent 近期活动；requesterSessionKey 为调用方会话，用于鉴权
+type AgentActivitySummarizer func(ctx context.Context, requesterSessionKey, agentID string, limit int) (map[string]interface{}, error)
+
+// AgentActivityTool 查询其他 agent 的近期活动（会话、分身运行），供主管 agent 做路由决策
+type AgentActivityTool struct {
+	summarize AgentActivitySummarizer
+}
+
+// NewAgentActivityTool 创建 agent_activity 工具；summarize 由 AgentManager 注入
+func NewAgentActivityTool(summarize AgentActivitySummarizer) *AgentActivityTool {
+	return &AgentActivityTool{summarize: summarize}
+}
+
+// Name 返回工具名
+func (t *AgentActivityTool) Name() string {
+	return "agent_activity"
+}
+
+// Description 返回描述
+func (t *AgentActivityTool) Description() string {
+	return "Summarize another agent's recent activity: session count, last activity, recent sessions and subagent runs. Only supervisor agents may query agents other than themselves."
+}
+
+// Parameters 返回参数 schema
+func (t *AgentActivityTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"agentId": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the agent to summarize",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Max recent sessions/runs to include (default 5, max 50)",
+			},
+		},
+		"required": []interface{}{"agentId"},
+	}
+}
+
+// Execute 执行
+func (t *AgentActivityTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	if t.summarize == nil {
+		return "", fmt.Errorf("agent_activity not configured")
+	}
+	agentID, _ := params["agentId"].(string)
+	if agentID == "" {
+		return "", fmt.Errorf("agentId is required")
+	}
+	limit := 5
+	if l, ok := params["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+	if limit > 50 {
+		limit = 50
+	}
+	requester, _ := ctx.Value("session_key").(string)
+	summary, err := t.summarize(ctx, requester, agentID, limit)
+	if err != nil {
+		return "", err
+	}
+	out, _ := json.Marshal(summary)
+	return string(out), nil
+}
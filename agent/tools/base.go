@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // ContentBlock represents a block of content in a message
@@ -52,6 +53,15 @@ type Tool interface {
 	Execute(ctx context.Context, params map[string]interface{}) (string, error)
 }
 
+// NoExecTimeout ExecTimeout 返回该值时工具不受 tools.timeout_seconds 限制（如立即返回、由子 agent 自行控制时长的 spawn）
+const NoExecTimeout time.Duration = -1
+
+// TimeoutTool 可选接口：工具声明自身的执行超时（如 exec 的 tools.shell.timeout）。
+// 整体超时取 tools.timeout_seconds 与自身超时中较长者，使工具能先按自己的超时返回
+type TimeoutTool interface {
+	ExecTimeout() time.Duration
+}
+
 // ToolCall 工具调用
 type ToolCall struct {
 	ID       string                 `json:"id"`
@@ -63,10 +73,12 @@ type ToolCall struct {
 
 // BaseTool 基础工具
 type BaseTool struct {
-	name        string
-	description string
-	parameters  map[string]interface{}
-	executeFunc func(ctx context.Context, params map[string]interface{}) (string, error)
+	name          string
+	description   string
+	parameters    map[string]interface{}
+	executeFunc   func(ctx context.Context, params map[string]interface{}) (string, error)
+	sensitiveArgs []string
+	execTimeout   time.Duration
 }
 
 // NewBaseTool 创建基础工具
@@ -94,6 +106,28 @@ func (t *BaseTool) Parameters() map[string]interface{} {
 	return t.parameters
 }
 
+// WithSensitiveArgs 声明日志中始终脱敏的参数名（见 SensitiveArgsTool）
+func (t *BaseTool) WithSensitiveArgs(names ...string) *BaseTool {
+	t.sensitiveArgs = append(t.sensitiveArgs, names...)
+	return t
+}
+
+// SensitiveArgs 返回需要脱敏的参数名
+func (t *BaseTool) SensitiveArgs() []string {
+	return t.sensitiveArgs
+}
+
+// WithExecTimeout 声明工具自身的执行超时（见 TimeoutTool），NoExecTimeout 表示不受整体超时限制
+func (t *BaseTool) WithExecTimeout(d time.Duration) *BaseTool {
+	t.execTimeout = d
+	return t
+}
+
+// ExecTimeout 返回工具自身的执行超时，0 表示未声明
+func (t *BaseTool) ExecTimeout() time.Duration {
+	return t.execTimeout
+}
+
 // Execute 执行工具
 func (t *BaseTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
 	return t.executeFunc(ctx, params)
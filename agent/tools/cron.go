@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/cron"
+)
+
+// cronJobRow cron_list / cron_add 返回给 LLM 的任务
+type cronJobRow struct {
+	ID             string `json:"id"`
+	Schedule       string `json:"schedule"`
+	Timezone       string `json:"timezone,omitempty"`
+	SessionKey     string `json:"session_key,omitempty"`
+	Channel        string `json:"channel,omitempty"`
+	Enabled        bool   `json:"enabled"`
+	Label          string `json:"label,omitempty"`
+	Prompt         string `json:"prompt,omitempty"`
+	DeleteAfterRun bool   `json:"delete_after_run,omitempty"`
+	NextRunAt      string `json:"next_run_at,omitempty"`
+}
+
+func newCronJobRow(job cron.StoredJob, now time.Time) cronJobRow {
+	row := cronJobRow{
+		ID:             job.ID,
+		Schedule:       job.Schedule,
+		Timezone:       job.Timezone,
+		SessionKey:     job.SessionKey,
+		Channel:        job.Channel,
+		Enabled:        job.Enabled,
+		Label:          job.Label,
+		Prompt:         job.Prompt,
+		DeleteAfterRun: job.DeleteAfterRun,
+	}
+	if schedule, err := cron.ParseInLocation(job.Schedule, job.Timezone); job.Enabled && err == nil {
+		if at := schedule.Next(now); !at.IsZero() {
+			row.NextRunAt = at.Format(time.RFC3339)
+		}
+	}
+	return row
+}
+
+// CronListTool 列出定时任务（与网关 cron.list 读取同一任务文件）
+type CronListTool struct {
+	store *cron.Store
+}
+
+// NewCronListTool 创建 cron_list 工具
+func NewCronListTool(store *cron.Store) *CronListTool {
+	return &CronListTool{store: store}
+}
+
+// Name 返回工具名
+func (t *CronListTool) Name() string {
+	return "cron_list"
+}
+
+// Description 返回描述
+func (t *CronListTool) Description() string {
+	return "List scheduled cron jobs (id, schedule, timezone, target session, prompt, next run time). By default only jobs that deliver to the current session are listed; set all=true to list every job, or pass id to read a single job."
+}
+
+// Parameters 返回参数 schema
+func (t *CronListTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Return only the job with this id",
+			},
+			"all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "List jobs for all sessions, not just the current one (default false)",
+			},
+		},
+	}
+}
+
+// Execute 执行
+func (t *CronListTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	jobs, err := t.store.Load()
+	if err != nil {
+		return "", fmt.Errorf("load cron jobs: %w", err)
+	}
+	id, _ := params["id"].(string)
+	all, _ := params["all"].(bool)
+	current, _ := ctx.Value("session_key").(string)
+	now := time.Now()
+	rows := make([]cronJobRow, 0, len(jobs))
+	for _, job := range jobs {
+		switch {
+		case id != "":
+			if job.ID != id {
+				continue
+			}
+		case !all && current != "" && job.SessionKey != current:
+			continue
+		}
+		rows = append(rows, newCronJobRow(job, now))
+	}
+	if id != "" && len(rows) == 0 {
+		return fmt.Sprintf("Cron job %s not found", id), nil
+	}
+	out, _ := json.Marshal(rows)
+	return string(out), nil
+}
+
+// CronAddTool 创建定时任务，触发时把 prompt 作为消息发送到目标会话（默认当前会话）
+type CronAddTool struct {
+	store *cron.Store
+}
+
+// NewCronAddTool 创建 cron_add 工具
+func NewCronAddTool(store *cron.Store) *CronAddTool {
+	return &CronAddTool{store: store}
+}
+
+// Name 返回工具名
+func (t *CronAddTool) Name() string {
+	return "cron_add"
+}
+
+// Description 返回描述
+func (t *CronAddTool) Description() string {
+	return "Schedule a follow-up task. When the schedule fires, prompt is sent as a message to the target session (the current session by default), e.g. to remind the user later. " +
+		"schedule is a 5-field cron expression (minute hour day-of-month month day-of-week), a descriptor such as @daily, or @every <duration>. " +
+		"For a one-time reminder (\"tomorrow at 9am\"), use a specific date such as \"0 9 16 10 *\" with once=true so the job is deleted after it runs."
+}
+
+// Parameters 返回参数 schema
+func (t *CronAddTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"schedule": map[string]interface{}{
+				"type":        "string",
+				"description": "Cron expression, e.g. \"0 9 * * mon-fri\", \"@daily\" or \"@every 2h\"",
+			},
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "Message sent to the session when the job fires",
+			},
+			"label": map[string]interface{}{
+				"type":        "string",
+				"description": "Short human-readable name for the job",
+			},
+			"timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "IANA timezone for the schedule, e.g. \"Asia/Shanghai\" (default: server local time)",
+			},
+			"once": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Delete the job after it fires once (default false)",
+			},
+			"session_key": map[string]interface{}{
+				"type":        "string",
+				"description": "Target session key (default: the current session)",
+			},
+		},
+		"required": []interface{}{"schedule", "prompt"},
+	}
+}
+
+// Execute 执行；参数错误以说明文字返回，便于 LLM 修正后重试
+func (t *CronAddTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	spec, _ := params["schedule"].(string)
+	prompt, _ := params["prompt"].(string)
+	label, _ := params["label"].(string)
+	timezone, _ := params["timezone"].(string)
+	once, _ := params["once"].(bool)
+	sessionKey, _ := params["session_key"].(string)
+	spec = strings.TrimSpace(spec)
+	prompt = strings.TrimSpace(prompt)
+	if spec == "" || prompt == "" {
+		return "Cannot create cron job: schedule and prompt are required.", nil
+	}
+	current, _ := ctx.Value("session_key").(string)
+	if sessionKey == "" {
+		sessionKey = current
+	}
+	if sessionKey == "" {
+		return "Cannot create cron job: no target session; pass session_key.", nil
+	}
+	schedule, err := cron.ParseInLocation(spec, timezone)
+	if err != nil {
+		return fmt.Sprintf("Cannot create cron job: invalid schedule %q: %v. Use a 5-field cron expression such as \"0 9 * * *\" (minute hour day-of-month month day-of-week).", spec, err), nil
+	}
+	now := time.Now()
+	if schedule.Next(now).IsZero() {
+		return fmt.Sprintf("Cannot create cron job: schedule %q never fires.", spec), nil
+	}
+
+	job := cron.StoredJob{
+		Schedule:       spec,
+		Timezone:       strings.TrimSpace(timezone),
+		SessionKey:     sessionKey,
+		Enabled:        true,
+		Label:          label,
+		Prompt:         prompt,
+		DeleteAfterRun: once,
+	}
+	// 任务属于当前会话时回复投递到创建它的聊天（如 Telegram），否则只进入目标会话
+	if target, ok := ReplyTargetFromContext(ctx); ok && sessionKey == current {
+		job.Channel = target.Channel
+		job.AccountID = target.AccountID
+		job.ChatID = target.ChatID
+	}
+	job, err = t.store.Add(job)
+	if err != nil {
+		return "", fmt.Errorf("save cron job: %w", err)
+	}
+	out, _ := json.Marshal(newCronJobRow(job, now))
+	return string(out), nil
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/cron"
+)
+
+func TestCronAddAndListTools(t *testing.T) {
+	store := cron.NewStore(filepath.Join(t.TempDir(), "cron.json"))
+	add := NewCronAddTool(store)
+	list := NewCronListTool(store)
+	ctx := context.WithValue(context.Background(), "session_key", "agent:main:main")
+
+	// 无效表达式返回说明文字而不是错误
+	out, err := add.Execute(ctx, map[string]interface{}{"schedule": "61 9 * * *", "prompt": "remind me"})
+	if err != nil || !strings.Contains(out, "invalid schedule") {
+		t.Fatalf("invalid schedule: out=%q err=%v", out, err)
+	}
+	out, err = add.Execute(ctx, map[string]interface{}{"schedule": "0 9 * * *", "prompt": "hi", "timezone": "Mars/Base"})
+	if err != nil || !strings.Contains(out, "Cannot create cron job") {
+		t.Fatalf("invalid timezone: out=%q err=%v", out, err)
+	}
+
+	out, err = add.Execute(ctx, map[string]interface{}{"schedule": "0 9 * * *", "prompt": "Remind me to call Bob", "once": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row cronJobRow
+	if err := json.Unmarshal([]byte(out), &row); err != nil {
+		t.Fatalf("decode %q: %v", out, err)
+	}
+	if row.SessionKey != "agent:main:main" || !row.DeleteAfterRun || row.NextRunAt == "" {
+		t.Fatalf("unexpected job: %+v", row)
+	}
+	if _, err := add.Execute(ctx, map[string]interface{}{"schedule": "@daily", "prompt": "report", "session_key": "agent:other:main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []cronJobRow
+	out, _ = list.Execute(ctx, map[string]interface{}{})
+	if err := json.Unmarshal([]byte(out), &rows); err != nil || len(rows) != 1 || rows[0].ID != row.ID {
+		t.Fatalf("current-session list = %s (%v)", out, err)
+	}
+	out, _ = list.Execute(ctx, map[string]interface{}{"all": true})
+	if err := json.Unmarshal([]byte(out), &rows); err != nil || len(rows) != 2 {
+		t.Fatalf("all list = %s (%v)", out, err)
+	}
+	if out, _ = list.Execute(ctx, map[string]interface{}{"id": "missing"}); !strings.Contains(out, "not found") {
+		t.Fatalf("missing id = %q", out)
+	}
+}
+
+func TestCronAddRecordsReplyTarget(t *testing.T) {
+	store := cron.NewStore(filepath.Join(t.TempDir(), "cron.json"))
+	add := NewCronAddTool(store)
+	ctx := context.WithValue(context.Background(), "session_key", "agent:main:telegram:42")
+	ctx = WithReplyTarget(ctx, ReplyTarget{Channel: "telegram", AccountID: "bot1", ChatID: "42"})
+
+	if _, err := add.Execute(ctx, map[string]interface{}{"schedule": "0 9 * * *", "prompt": "standup"}); err != nil {
+		t.Fatal(err)
+	}
+	// 其他会话的任务不继承当前聊天
+	if _, err := add.Execute(ctx, map[string]interface{}{"schedule": "@daily", "prompt": "report", "session_key": "agent:other:main"}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := store.Load()
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("jobs = %+v (%v)", jobs, err)
+	}
+	for _, job := range jobs {
+		switch job.SessionKey {
+		case "agent:main:telegram:42":
+			if job.Channel != "telegram" || job.AccountID != "bot1" || job.ChatID != "42" {
+				t.Fatalf("current-session job target = %+v", job)
+			}
+		default:
+			if job.Channel != "" || job.ChatID != "" {
+				t.Fatalf("other-session job should have no target: %+v", job)
+			}
+		}
+	}
+}
@@ -302,7 +302,7 @@ func (t *FileSystemTool) GetTools() []Tool {
 				"required": []string{"path", "content"},
 			},
 			t.WriteFile,
-		),
+		).WithSensitiveArgs("content"),
 		NewBaseTool(
 			"edit_file",
 			"Replace all occurrences of old_string with new_string in a file. Use for precise edits to existing files.",
@@ -325,7 +325,7 @@ func (t *FileSystemTool) GetTools() []Tool {
 				"required": []string{"path", "old_string", "new_string"},
 			},
 			t.EditFile,
-		),
+		).WithSensitiveArgs("old_string", "new_string"),
 		NewBaseTool(
 			"list_dir",
 			"List contents of a directory",
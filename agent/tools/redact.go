@@ -0,0 +1,17 @@
+package tools
+
+import "github.com/smallnest/goclaw/internal/redact"
+
+// SensitiveArgsTool 可选接口：工具声明始终需要在日志中脱敏的参数名（如文件内容）
+type SensitiveArgsTool interface {
+	SensitiveArgs() []string
+}
+
+// RedactToolArgs 按工具声明（SensitiveArgsTool）与通用敏感名称规则脱敏参数，用于日志
+func RedactToolArgs(tool interface{}, args map[string]interface{}) map[string]interface{} {
+	var extra []string
+	if s, ok := tool.(SensitiveArgsTool); ok {
+		extra = s.SensitiveArgs()
+	}
+	return redact.Map(args, extra...)
+}
@@ -94,7 +94,7 @@ func (r *Registry) Execute(ctx context.Context, name string, params map[string]i
 	// 执行工具
 	logger.Info("Executing tool",
 		zap.String("tool", name),
-		zap.Any("params", params),
+		zap.Any("params", RedactToolArgs(tool, params)),
 	)
 
 	result, err := tool.Execute(ctx, params)
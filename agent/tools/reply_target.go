@@ -0,0 +1,27 @@
+package tools
+
+import "context"
+
+// ReplyTarget 当前运行的回复投递目标（入站消息的渠道、账号与聊天）
+type ReplyTarget struct {
+	Channel   string
+	AccountID string
+	ChatID    string
+}
+
+// replyTargetKey context 中回复投递目标的 key
+type replyTargetKey struct{}
+
+// WithReplyTarget 将当前运行的回复投递目标放入 context，供 cron_add 等需要之后回到同一聊天的工具使用
+func WithReplyTarget(ctx context.Context, target ReplyTarget) context.Context {
+	if target.Channel == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, replyTargetKey{}, target)
+}
+
+// ReplyTargetFromContext 返回 context 中的回复投递目标
+func ReplyTargetFromContext(ctx context.Context) (ReplyTarget, bool) {
+	target, ok := ctx.Value(replyTargetKey{}).(ReplyTarget)
+	return target, ok
+}
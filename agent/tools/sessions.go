@@ -132,11 +132,11 @@ func (t *SessionsHistoryTool) Execute(ctx context.Context, params map[string]int
 // SessionsSendTool 向指定会话发送一条消息（需注入发送回调）
 type SessionsSendTool struct {
 	sessionMgr *session.Manager
-	sendFunc   func(ctx context.Context, sessionKey, content string) error
+	sendFunc   func(ctx context.Context, sessionKey, content string) (string, error)
 }
 
-// NewSessionsSendTool 创建 sessions_send 工具；sendFunc 为向会话发送消息的实现（如通过 bus）
-func NewSessionsSendTool(sessionMgr *session.Manager, sendFunc func(ctx context.Context, sessionKey, content string) error) *SessionsSendTool {
+// NewSessionsSendTool 创建 sessions_send 工具；sendFunc 为向会话发送消息的实现，返回投递方式（interrupted / queued）
+func NewSessionsSendTool(sessionMgr *session.Manager, sendFunc func(ctx context.Context, sessionKey, content string) (string, error)) *SessionsSendTool {
 	return &SessionsSendTool{sessionMgr: sessionMgr, sendFunc: sendFunc}
 }
 
@@ -147,7 +147,7 @@ func (t *SessionsSendTool) Name() string {
 
 // Description 返回描述
 func (t *SessionsSendTool) Description() string {
-	return "Send a message to a session by session_key. The message will be delivered to that session's channel/chat."
+	return "Send a message to a session by session_key. If that session has a run in progress, the message interrupts it and is handled in-line (interrupted); otherwise it starts the session's next run (queued)."
 }
 
 // Parameters 返回参数 schema
@@ -178,10 +178,14 @@ func (t *SessionsSendTool) Execute(ctx context.Context, params map[string]interf
 	if key == "" || content == "" {
 		return "", fmt.Errorf("session_key and content are required")
 	}
-	if err := t.sendFunc(ctx, key, content); err != nil {
+	status, err := t.sendFunc(ctx, key, content)
+	if err != nil {
 		return "", fmt.Errorf("send: %w", err)
 	}
-	return fmt.Sprintf("Message sent to session %s", key), nil
+	if status == "" {
+		return fmt.Sprintf("Message sent to session %s", key), nil
+	}
+	return fmt.Sprintf("Message sent to session %s (%s)", key, status), nil
 }
 
 // SessionStatusTool 返回当前会话状态（key、消息数、最后活动时间）
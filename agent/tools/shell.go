@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -96,6 +97,9 @@ func (t *ShellTool) execDirect(ctx context.Context, command string) (string, err
 	if t.workingDir != "" {
 		cmd.Dir = t.workingDir
 	}
+	if env := SkillEnvFromContext(ctx); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -124,6 +128,7 @@ func (t *ShellTool) execInSandbox(ctx context.Context, command string) (string,
 	resp, err := t.dockerClient.ContainerCreate(ctx, &container.Config{
 		Image:      t.sandboxConfig.Image,
 		Cmd:        []string{"sh", "-c", command},
+		Env:        SkillEnvFromContext(ctx),
 		WorkingDir: t.sandboxConfig.Workdir,
 		Tty:        false,
 	}, &container.HostConfig{
@@ -238,7 +243,7 @@ func (t *ShellTool) GetTools() []Tool {
 				"required": []string{"command"},
 			},
 			t.Exec,
-		),
+		).WithExecTimeout(t.timeout),
 	}
 }
 
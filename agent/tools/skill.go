@@ -3,11 +3,42 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 )
 
+// skillEnvKey context 中技能环境变量的 key
+type skillEnvKey struct{}
+
+// WithSkillEnv 将已加载技能需要的环境变量（如 API key）放入 context，供 shell 等执行类工具注入子进程
+func WithSkillEnv(ctx context.Context, env map[string]string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, skillEnvKey{}, env)
+}
+
+// SkillEnvFromContext 返回 context 中的技能环境变量，按变量名排序的 KEY=VALUE 列表
+func SkillEnvFromContext(ctx context.Context) []string {
+	env, _ := ctx.Value(skillEnvKey{}).(map[string]string)
+	if len(env) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, name+"="+env[name])
+	}
+	return result
+}
+
 // useSkillResult 表示使用技能的结果
 type useSkillResult struct {
 	Success      bool   `json:"success"`
@@ -64,3 +95,64 @@ func NewUseSkillTool() *BaseTool {
 		},
 	)
 }
+
+// SkillSummary list_skills 返回的技能信息（来自技能清单 frontmatter）
+type SkillSummary struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Emoji          string `json:"emoji,omitempty"`
+	Always         bool   `json:"always,omitempty"`
+	RequiresAPIKey bool   `json:"requires_api_key,omitempty"`
+}
+
+// listSkillsResult list_skills 的返回结构
+type listSkillsResult struct {
+	Count  int            `json:"count"`
+	Skills []SkillSummary `json:"skills"`
+	Hint   string         `json:"hint,omitempty"`
+}
+
+// NewListSkillsTool 创建列出可用技能的工具，与 use_skill 配合：技能较多、提示词中的摘要被截断时，
+// 模型可先按关键字查询再选择加载。list 返回当前启用（未被禁用）的技能。
+func NewListSkillsTool(list func() []SkillSummary) *BaseTool {
+	return NewBaseTool(
+		"list_skills",
+		"List the skills available to you (name and description). Optionally filter by a keyword. Load one with use_skill.",
+		map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional keyword to filter skills by name or description (case-insensitive)",
+				},
+			},
+		},
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			query, _ := params["query"].(string)
+			query = strings.ToLower(strings.TrimSpace(query))
+
+			skills := make([]SkillSummary, 0)
+			if list != nil {
+				for _, skill := range list() {
+					if query != "" &&
+						!strings.Contains(strings.ToLower(skill.Name), query) &&
+						!strings.Contains(strings.ToLower(skill.Description), query) {
+						continue
+					}
+					skills = append(skills, skill)
+				}
+			}
+			sort.Slice(skills, func(i, j int) bool { return skills[i].Name < skills[j].Name })
+
+			result := listSkillsResult{Count: len(skills), Skills: skills}
+			if len(skills) > 0 {
+				result.Hint = "Call use_skill with a skill name to load its full instructions."
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+	)
+}
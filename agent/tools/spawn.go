@@ -86,6 +86,6 @@ func (t *SpawnTool) GetTools() []Tool {
 				"required": []string{"task"},
 			},
 			t.Spawn,
-		),
+		).WithExecTimeout(NoExecTimeout),
 	}
 }
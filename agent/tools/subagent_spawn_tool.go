@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/smallnest/goclaw/config"
@@ -269,6 +270,11 @@ func (t *SubagentSpawnTool) Parameters() map[string]interface{} {
 }
 
 // Execute 执行工具
+// ExecTimeout 只登记并发布子 agent 运行，不等待其完成；子 agent 的时长由 run_timeout_seconds 控制
+func (t *SubagentSpawnTool) ExecTimeout() time.Duration {
+	return NoExecTimeout
+}
+
 func (t *SubagentSpawnTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
 	// 解析参数
 	spawnParams, err := t.parseParams(params)
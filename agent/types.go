@@ -2,9 +2,11 @@ package agent
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
 )
@@ -64,6 +66,21 @@ func (t ThinkingContent) ContentType() string {
 	return "thinking"
 }
 
+// messageReasoning 返回消息的思考内容：优先 metadata.reasoning_content，否则拼接 ThinkingContent 块。
+// 写入会话时据此保存到 metadata.reasoning_content，重新加载后仍能回传给 provider
+func messageReasoning(msg AgentMessage) string {
+	if reasoning, ok := msg.Metadata["reasoning_content"].(string); ok && strings.TrimSpace(reasoning) != "" {
+		return reasoning
+	}
+	var parts []string
+	for _, block := range msg.Content {
+		if t, ok := block.(ThinkingContent); ok && strings.TrimSpace(t.Thinking) != "" {
+			parts = append(parts, t.Thinking)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
 // AgentMessage represents a message in the agent conversation (renamed to avoid conflict with context.go)
 type AgentMessage struct {
 	ID        string         `json:"id,omitempty"`
@@ -106,6 +123,7 @@ type AgentState struct {
 	// Queues for message injection (inspired by pi-mono)
 	SteeringQueue []AgentMessage
 	FollowUpQueue []AgentMessage
+	queueMu       sync.Mutex // 保护 SteeringQueue/FollowUpQueue，同一 Agent 的多个会话可并发运行
 
 	// Session key
 	SessionKey string
@@ -129,6 +147,7 @@ const (
 	EventToolExecutionStart  EventType = "tool_execution_start"
 	EventToolExecutionUpdate EventType = "tool_execution_update"
 	EventToolExecutionEnd    EventType = "tool_execution_end"
+	EventDryRun              EventType = "dry_run" // dry-run 组装出的 LLM 请求（Content 为 JSON），未实际调用
 )
 
 // Event represents an event from the agent
@@ -151,6 +170,7 @@ type Event struct {
 // LoopConfig contains configuration for the agent loop
 type LoopConfig struct {
 	Model         string
+	FallbackModel string // 主模型返回“模型不存在”时重试一次使用的模型，空表示不启用
 	Provider      providers.Provider
 	SessionMgr    *session.Manager
 	MaxIterations int
@@ -164,10 +184,25 @@ type LoopConfig struct {
 	ContextWindowTokens int // 模型上下文窗口 token 数
 	ReserveTokens       int // 保留给系统提示与回复的 token 数
 	MaxHistoryTurns     int // 发送给 LLM 时最多保留的 user 轮次数，0 表示不限制
+	// 压缩摘要模型的上下文窗口，超长历史按此切块分层摘要；0 表示与 ContextWindowTokens 相同
+	SummarizerContextTokens int
+	// 超长 tool 结果截断方式（首尾保留大小与标记）
+	ToolResultTruncation ToolResultTruncation
 
 	// 同一会话内两次 LLM 调用的最小间隔，用于缓解 406/限流；0 表示不限制
 	ModelRequestInterval time.Duration
 
+	// 工具报错策略：continue（默认）| stop | stop_after_n；ToolErrorMaxConsecutive 为 stop_after_n 的 N（0 表示默认 3）
+	ToolErrorPolicy         string
+	ToolErrorMaxConsecutive int
+
+	// 单次工具调用的执行超时，0 表示 DefaultToolTimeout
+	ToolTimeout time.Duration
+
+	// agent 配置的 system_prompt（模板，每次运行按 SystemPromptVars 展开后置于 system prompt 开头），空表示不设置
+	SystemPrompt     string
+	SystemPromptVars config.SystemPromptVars
+
 	// Hooks for message transformation
 	ConvertToLLM     func([]AgentMessage) ([]providers.Message, error)
 	TransformContext func([]AgentMessage) ([]AgentMessage, error)
@@ -177,9 +212,25 @@ type LoopConfig struct {
 	GetFollowUpMessages func() ([]AgentMessage, error)
 
 	// Skills support
-	Skills         []*Skill
+	Skills         []*Skill // 运行时通过 SetSkills 更新（skills.update / skills.reload）
 	LoadedSkills   []string
 	ContextBuilder *ContextBuilder
+
+	skillsMu sync.RWMutex
+}
+
+// SetSkills 替换可用技能列表；已开始的 Run 使用其启动时的快照，下一次 Run 生效
+func (c *LoopConfig) SetSkills(skills []*Skill) {
+	c.skillsMu.Lock()
+	c.Skills = skills
+	c.skillsMu.Unlock()
+}
+
+// CurrentSkills 返回当前可用技能列表
+func (c *LoopConfig) CurrentSkills() []*Skill {
+	c.skillsMu.RLock()
+	defer c.skillsMu.RUnlock()
+	return c.Skills
 }
 
 // NewAgentState creates a new agent state
@@ -250,16 +301,22 @@ func (s *AgentState) ClearPendingTools() {
 
 // Steer adds a steering message to interrupt the agent mid-run
 func (s *AgentState) Steer(msg AgentMessage) {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
 	s.SteeringQueue = append(s.SteeringQueue, msg)
 }
 
 // FollowUp adds a follow-up message to be processed after agent finishes
 func (s *AgentState) FollowUp(msg AgentMessage) {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
 	s.FollowUpQueue = append(s.FollowUpQueue, msg)
 }
 
 // DequeueSteeringMessages gets and clears steering messages
 func (s *AgentState) DequeueSteeringMessages() []AgentMessage {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
 	msgs := s.SteeringQueue
 	s.SteeringQueue = make([]AgentMessage, 0)
 	return msgs
@@ -267,6 +324,8 @@ func (s *AgentState) DequeueSteeringMessages() []AgentMessage {
 
 // DequeueFollowUpMessages gets and clears follow-up messages
 func (s *AgentState) DequeueFollowUpMessages() []AgentMessage {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
 	msgs := s.FollowUpQueue
 	s.FollowUpQueue = make([]AgentMessage, 0)
 	return msgs
@@ -274,6 +333,8 @@ func (s *AgentState) DequeueFollowUpMessages() []AgentMessage {
 
 // HasQueuedMessages checks if there are queued messages
 func (s *AgentState) HasQueuedMessages() bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
 	return len(s.SteeringQueue) > 0 || len(s.FollowUpQueue) > 0
 }
 
@@ -282,11 +343,13 @@ func (s *AgentState) Clone() *AgentState {
 	messages := make([]AgentMessage, len(s.Messages))
 	copy(messages, s.Messages)
 
+	s.queueMu.Lock()
 	steering := make([]AgentMessage, len(s.SteeringQueue))
 	copy(steering, s.SteeringQueue)
 
 	followUp := make([]AgentMessage, len(s.FollowUpQueue))
 	copy(followUp, s.FollowUpQueue)
+	s.queueMu.Unlock()
 
 	s.pendingMu.Lock()
 	pendingTools := make(map[string]bool, len(s.PendingTools))
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/session"
+	"go.uber.org/zap"
+)
+
+// write-ahead 默认落盘频率
+const (
+	defaultWriteAheadEveryDeltas = 20
+	defaultWriteAheadInterval    = 5 * time.Second
+)
+
+// streamCheckpointer 将流式输出中累计的 assistant 文本周期性写入会话（临时消息），
+// 进程在运行中途崩溃时回复不会全部丢失；运行结束时由 finish 移除临时消息
+type streamCheckpointer struct {
+	sessionMgr  *session.Manager
+	sess        *session.Session
+	runID       string
+	prompt      *session.Message // 尚未写入会话的本轮用户消息，第一段回复之前写入
+	everyDeltas int
+	interval    time.Duration
+
+	pending  int
+	lastSave time.Time
+	saved    bool
+}
+
+// newStreamCheckpointer 根据 session.write_ahead 创建检查点；未启用时返回 nil（nil 上的方法均为空操作）。
+// prompt 为本轮尚未写入会话的用户消息（非 websocket 渠道由 updateSession 在运行结束时写入），已在会话中时为 nil
+func newStreamCheckpointer(sessionMgr *session.Manager, sess *session.Session, runID string, prompt *session.Message) *streamCheckpointer {
+	cfg := config.Get()
+	if cfg == nil || cfg.Session.WriteAhead == nil || !cfg.Session.WriteAhead.Enabled || sessionMgr == nil || sess == nil {
+		return nil
+	}
+	w := cfg.Session.WriteAhead
+	c := &streamCheckpointer{
+		sessionMgr:  sessionMgr,
+		sess:        sess,
+		runID:       runID,
+		prompt:      prompt,
+		everyDeltas: w.EveryDeltas,
+		interval:    time.Duration(w.IntervalSeconds) * time.Second,
+		lastSave:    time.Now(),
+	}
+	if c.everyDeltas <= 0 {
+		c.everyDeltas = defaultWriteAheadEveryDeltas
+	}
+	if c.interval <= 0 {
+		c.interval = defaultWriteAheadInterval
+	}
+	return c
+}
+
+// onDelta 收到一个流式增量；accumulated 为本次运行目前累计的全部文本
+func (c *streamCheckpointer) onDelta(accumulated string) {
+	if c == nil {
+		return
+	}
+	if c.prompt != nil {
+		// 先落盘本轮用户消息，崩溃后留下的临时回复总有对应的提问
+		c.sess.AddProvisionalPrompt(c.runID, *c.prompt)
+		c.prompt = nil
+		if !c.save() {
+			return
+		}
+	}
+	c.pending++
+	if c.pending < c.everyDeltas && time.Since(c.lastSave) < c.interval {
+		return
+	}
+	c.sess.UpsertProvisional(c.runID, accumulated)
+	if c.save() {
+		c.pending = 0
+	}
+}
+
+// save 落盘当前会话，失败时只记录日志
+func (c *streamCheckpointer) save() bool {
+	if err := c.sessionMgr.Save(c.sess); err != nil {
+		logger.Warn("Failed to checkpoint streaming reply",
+			zap.String("session_key", c.sess.Key),
+			zap.Error(err))
+		return false
+	}
+	c.lastSave = time.Now()
+	c.saved = true
+	return true
+}
+
+// finish 运行结束（成功或失败）时移除临时消息；成功时最终消息由 updateSession 写入
+func (c *streamCheckpointer) finish() {
+	if c == nil || !c.sess.RemoveProvisional(c.runID) || !c.saved {
+		return
+	}
+	if err := c.sessionMgr.Save(c.sess); err != nil {
+		logger.Warn("Failed to remove streaming checkpoint",
+			zap.String("session_key", c.sess.Key),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// blockingStreamProvider 推送 chunks 后阻塞到 release 关闭再结束，模拟运行中途崩溃前的状态
+type blockingStreamProvider struct {
+	fakeChatProvider
+	chunks  []string
+	release chan struct{}
+}
+
+func (p *blockingStreamProvider) SupportsStreaming() bool { return true }
+
+func (p *blockingStreamProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, callback providers.StreamCallback, options ...providers.ChatOption) error {
+	for _, c := range p.chunks {
+		callback(providers.StreamChunk{Content: c})
+	}
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	callback(providers.StreamChunk{Content: strings.Join(p.chunks, ""), Done: true})
+	return nil
+}
+
+func TestWriteAheadLeavesRecoverablePartialAfterCrash(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Session: config.SessionConfig{
+		WriteAhead: &config.WriteAheadConfig{Enabled: true, EveryDeltas: 2},
+	}})
+
+	dir := t.TempDir()
+	sessMgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus(64)
+	defer msgBus.Close()
+	provider := &blockingStreamProvider{chunks: []string{"Once", " upon", " a time"}, release: make(chan struct{})}
+	m := NewAgentManager(&NewAgentManagerConfig{
+		Bus:        msgBus,
+		Provider:   provider,
+		SessionMgr: sessMgr,
+		Tools:      NewToolRegistry(),
+		DataDir:    t.TempDir(),
+	})
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Workspace: config.WorkspaceConfig{Path: workspace},
+		Agents:    config.AgentsConfig{List: []config.AgentConfig{{ID: "main", Default: true}}},
+	}
+	if err := m.SetupFromConfig(cfg, NewContextBuilder(NewMemoryStore(workspace), workspace)); err != nil {
+		t.Fatal(err)
+	}
+	sub := msgBus.SubscribeOutbound()
+	defer sub.Unsubscribe()
+
+	// 非 websocket 渠道：本轮用户消息只在运行结束时随回复写入，检查点须先写入它
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	key := session.BuildAgentSessionKey("main", "telegram", "", "42", session.DefaultMainKey, false)
+	if err := m.RouteInbound(ctx, &bus.InboundMessage{ID: "run-1", Channel: "telegram", ChatID: "42", Content: "write a long story"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟崩溃：运行仍阻塞时由新的 Manager 从磁盘读取；第三段增量未达到落盘条件
+	var history []session.Message
+	for len(history) < 2 || history[1].Content != "Once upon" {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("checkpoint not written, history on disk = %+v", history)
+		case <-time.After(10 * time.Millisecond):
+		}
+		history = mustReload(t, dir, key).GetHistory(0)
+	}
+	if len(history) != 2 || history[0].Role != "user" || history[0].Content != "write a long story" {
+		t.Fatalf("history on disk = %+v, want user prompt before the partial reply", history)
+	}
+	if partial := history[1]; partial.Role != "assistant" || !session.IsIncomplete(partial) {
+		t.Fatalf("partial reply = %+v", partial)
+	}
+
+	// 正常结束：临时消息被移除，用户消息与最终回复各只有一条
+	close(provider.release)
+	select {
+	case out := <-sub.Channel:
+		if out.Content != "Once upon a time" {
+			t.Fatalf("reply = %+v", out)
+		}
+	case <-ctx.Done():
+		t.Fatal("no final reply")
+	}
+	got := mustReload(t, dir, key).GetHistory(0)
+	if len(got) != 2 || got[0].Content != "write a long story" || got[1].Content != "Once upon a time" || session.IsIncomplete(got[1]) {
+		t.Fatalf("history after finish = %+v", got)
+	}
+}
+
+func mustReload(t *testing.T, dir, key string) *session.Session {
+	t.Helper()
+	mgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := mgr.Lookup(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestWriteAheadDisabledByDefault(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{})
+
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := mgr.GetOrCreate("agent:main:main")
+	checkpoint := newStreamCheckpointer(mgr, sess, "run-1", nil)
+	if checkpoint != nil {
+		t.Fatal("checkpointer should be disabled without session.write_ahead.enabled")
+	}
+	checkpoint.onDelta("text") // nil 安全
+	checkpoint.finish()
+	if n := len(sess.GetHistory(0)); n != 0 {
+		t.Fatalf("history len = %d, want 0", n)
+	}
+}
@@ -0,0 +1,44 @@
+package bus
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AgentEventEmitter 单次运行的 Agent 事件发送器：并发的发送方（流式增量、工具、生命周期）共用同一序号，
+// seq 严格递增且与进入总线的顺序一致，UI 可据此排序
+type AgentEventEmitter struct {
+	bus        *MessageBus
+	runId      string
+	sessionKey string
+
+	mu  sync.Mutex // 分配序号与入队在同一临界区内完成，保证序号顺序即投递顺序
+	seq atomic.Int64
+}
+
+// NewAgentEventEmitter 为一次运行创建事件发送器
+func (b *MessageBus) NewAgentEventEmitter(runId, sessionKey string) *AgentEventEmitter {
+	return &AgentEventEmitter{bus: b, runId: runId, sessionKey: sessionKey}
+}
+
+// Emit 分配下一个序号并发布事件；可被多个 goroutine 并发调用
+func (e *AgentEventEmitter) Emit(ctx context.Context, stream AgentEventStream, data map[string]interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	payload := &AgentEventPayload{
+		RunId:      e.runId,
+		Seq:        int(e.seq.Add(1)),
+		Stream:     stream,
+		Ts:         time.Now().UnixMilli(),
+		Data:       data,
+		SessionKey: e.sessionKey,
+	}
+	return e.bus.PublishAgentEvent(ctx, payload)
+}
+
+// Seq 返回已分配的最后一个序号
+func (e *AgentEventEmitter) Seq() int {
+	return int(e.seq.Load())
+}
@@ -0,0 +1,102 @@
+package bus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgentEventEmitterConcurrentSeqIsOrdered(t *testing.T) {
+	b := NewMessageBus(1000)
+	defer b.Close()
+	sub := b.SubscribeAgentEvent()
+	defer sub.Unsubscribe()
+
+	const emitters, perEmitter = 8, 12 // 总数小于订阅缓冲（100），不会因订阅方未及时读取而丢弃
+	events := b.NewAgentEventEmitter("run-1", "agent:main:main")
+	var wg sync.WaitGroup
+	for i := 0; i < emitters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perEmitter; j++ {
+				_ = events.Emit(context.Background(), AgentStreamAssistant, map[string]interface{}{"n": j})
+			}
+		}()
+	}
+	wg.Wait()
+
+	const total = emitters * perEmitter
+	if events.Seq() != total {
+		t.Fatalf("Seq() = %d, want %d", events.Seq(), total)
+	}
+	// 订阅方收到的顺序即 seq 顺序：1..total，无重复、无乱序
+	for want := 1; want <= total; want++ {
+		select {
+		case payload := <-sub.Channel:
+			if payload.Seq != want || payload.RunId != "run-1" {
+				t.Fatalf("event %d: seq = %d runId = %q", want, payload.Seq, payload.RunId)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for event %d", want)
+		}
+	}
+}
+
+func TestCriticalAgentEventSurvivesFullSubscriber(t *testing.T) {
+	b := NewMessageBus(10)
+	defer b.Close()
+	sub := b.SubscribeAgentEvent()
+	defer sub.Unsubscribe()
+
+	events := b.NewAgentEventEmitter("run-1", "agent:main:main")
+	// 订阅方不读取，流式增量远超缓冲后被丢弃
+	for i := 0; i < 300; i++ {
+		_ = events.Emit(context.Background(), AgentStreamAssistant, map[string]interface{}{"text": "x"})
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- events.Emit(cancelled, AgentStreamLifecycle, map[string]interface{}{"phase": "end"})
+	}()
+
+	// 订阅方恢复读取后，end 事件必须送达
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case payload := <-sub.Channel:
+			if payload.Stream == AgentStreamLifecycle {
+				if err := <-done; err != nil {
+					t.Fatalf("Emit(end) error = %v", err)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("lifecycle end event was dropped")
+		}
+	}
+}
+
+func TestAgentEventPayloadCritical(t *testing.T) {
+	cases := []struct {
+		stream AgentEventStream
+		phase  string
+		want   bool
+	}{
+		{AgentStreamLifecycle, "start", false},
+		{AgentStreamLifecycle, "end", true},
+		{AgentStreamLifecycle, "error", true},
+		{AgentStreamTool, "start", false},
+		{AgentStreamTool, "result", true},
+		{AgentStreamAssistant, "", false},
+		{AgentStreamApproval, "requested", true},
+	}
+	for _, c := range cases {
+		p := &AgentEventPayload{Stream: c.stream, Data: map[string]interface{}{"phase": c.phase}}
+		if got := p.Critical(); got != c.want {
+			t.Errorf("Critical(%s/%s) = %v, want %v", c.stream, c.phase, got, c.want)
+		}
+	}
+}
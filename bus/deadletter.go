@@ -0,0 +1,108 @@
+package bus
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDeadLetterCapacity 死信队列默认容量，超出时淘汰最早的条目
+const DefaultDeadLetterCapacity = 1000
+
+// ErrDeadLetterNotFound 死信条目不存在（已重试或已被淘汰）
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter 重试后仍无法投递的出站消息；ID 与出站消息 ID 相同
+type DeadLetter struct {
+	ID       string           `json:"id"`
+	Message  *OutboundMessage `json:"message"`
+	Reason   string           `json:"reason"`
+	Attempts int              `json:"attempts"`
+	FailedAt time.Time        `json:"failedAt"`
+}
+
+// DeadLetterStore 内存中的死信队列（进程重启后清空），按失败时间保留最近 capacity 条
+type DeadLetterStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*DeadLetter
+}
+
+// NewDeadLetterStore 创建死信队列；capacity 为 0 时使用默认容量
+func NewDeadLetterStore(capacity int) *DeadLetterStore {
+	if capacity <= 0 {
+		capacity = DefaultDeadLetterCapacity
+	}
+	return &DeadLetterStore{
+		capacity: capacity,
+		entries:  make(map[string]*DeadLetter),
+	}
+}
+
+// Add 记录一条投递失败的消息；同一消息再次失败时覆盖原条目
+func (s *DeadLetterStore) Add(msg *OutboundMessage, reason string, attempts int) DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &DeadLetter{
+		ID:       msg.ID,
+		Message:  msg,
+		Reason:   reason,
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	}
+	s.entries[entry.ID] = entry
+	if over := len(s.entries) - s.capacity; over > 0 {
+		for _, old := range s.sortedLocked()[:over] {
+			delete(s.entries, old.ID)
+		}
+	}
+	return *entry
+}
+
+// List 按失败时间升序返回全部死信
+func (s *DeadLetterStore) List() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sorted := s.sortedLocked()
+	out := make([]DeadLetter, len(sorted))
+	for i, e := range sorted {
+		out[i] = *e
+	}
+	return out
+}
+
+// Len 返回死信数量
+func (s *DeadLetterStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Take 取出并移除一条死信
+func (s *DeadLetterStore) Take(id string) (DeadLetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[id]
+	if !ok {
+		return DeadLetter{}, false
+	}
+	delete(s.entries, id)
+	return *entry, true
+}
+
+func (s *DeadLetterStore) sortedLocked() []*DeadLetter {
+	sorted := make([]*DeadLetter, 0, len(s.entries))
+	for _, e := range s.entries {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].FailedAt.Before(sorted[j].FailedAt)
+	})
+	return sorted
+}
+
+// DeadLetters 返回总线的死信队列
+func (b *MessageBus) DeadLetters() *DeadLetterStore {
+	return b.deadLetters
+}
@@ -0,0 +1,24 @@
+package bus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadLetterStoreCapacity(t *testing.T) {
+	s := NewDeadLetterStore(2)
+	for _, id := range []string{"a", "b", "c"} {
+		s.Add(&OutboundMessage{ID: id, Channel: "telegram"}, "send failed", 3)
+		time.Sleep(time.Millisecond)
+	}
+	list := s.List()
+	if len(list) != 2 || list[0].ID != "b" || list[1].ID != "c" {
+		t.Fatalf("List() = %+v", list)
+	}
+	if entry, ok := s.Take("c"); !ok || entry.Attempts != 3 || entry.Reason != "send failed" {
+		t.Fatalf("Take(c) = %+v, %v", entry, ok)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Len() = %d", s.Len())
+	}
+}
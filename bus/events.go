@@ -30,6 +30,10 @@ func (m *InboundMessage) SessionKey() string {
 	return m.Channel + ":" + m.ChatID
 }
 
+// MetadataQueuedSession 入站消息元数据：消息所属的会话键（steering / sessions_send 排队运行、定时任务触发）。
+// 设置时 agent 按该键选择会话与 Agent，Channel / ChatID 只用于投递回复；用户消息随运行结果在 session lane 内写入会话
+const MetadataQueuedSession = "queuedSession"
+
 // OutboundMessage 出站消息
 type OutboundMessage struct {
 	ID        string                 `json:"id"`
@@ -57,7 +61,7 @@ func (m *InboundMessage) IsSystemMessage() bool {
 	return m.Channel == "system"
 }
 
-// AgentEventStream 与 OpenClaw 对齐：lifecycle | tool | assistant | error
+// AgentEventStream 与 OpenClaw 对齐：lifecycle | tool | assistant | error；approval 为工具执行审批（goclaw 扩展）
 type AgentEventStream string
 
 const (
@@ -65,6 +69,7 @@ const (
 	AgentStreamTool      AgentEventStream = "tool"
 	AgentStreamAssistant AgentEventStream = "assistant"
 	AgentStreamError     AgentEventStream = "error"
+	AgentStreamApproval  AgentEventStream = "approval"
 )
 
 // AgentEventPayload 与 OpenClaw infra/agent-events.ts 一致，供 Control UI 显示进度与工具执行
@@ -76,3 +81,20 @@ type AgentEventPayload struct {
 	Data       map[string]interface{} `json:"data"`
 	SessionKey string                 `json:"sessionKey,omitempty"`
 }
+
+// Critical 是否为不可丢弃的事件：生命周期结束/出错、工具结果与审批事件丢失会让 UI 停在进行中状态
+func (p *AgentEventPayload) Critical() bool {
+	if p == nil {
+		return false
+	}
+	phase, _ := p.Data["phase"].(string)
+	switch p.Stream {
+	case AgentStreamLifecycle:
+		return phase == "end" || phase == "error"
+	case AgentStreamTool:
+		return phase == "result"
+	case AgentStreamError, AgentStreamApproval:
+		return true
+	}
+	return false
+}
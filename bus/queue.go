@@ -7,9 +7,13 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/metrics"
 	"go.uber.org/zap"
 )
 
+// criticalAgentEventWait 关键 Agent 事件（见 AgentEventPayload.Critical）在队列满时的最长等待时间
+const criticalAgentEventWait = 2 * time.Second
+
 // MessageBus 消息总线
 type MessageBus struct {
 	inbound         chan *InboundMessage
@@ -23,6 +27,7 @@ type MessageBus struct {
 	closed          bool
 	fanoutStopped   bool
 	agentFanoutStop bool
+	deadLetters     *DeadLetterStore
 }
 
 // NewMessageBus 创建消息总线
@@ -33,6 +38,7 @@ func NewMessageBus(bufferSize int) *MessageBus {
 		outSubs:     make(map[string]chan *OutboundMessage),
 		agentEvents: make(chan *AgentEventPayload, bufferSize*2),
 		agentSubs:   make(map[string]chan *AgentEventPayload),
+		deadLetters: NewDeadLetterStore(0),
 		closed:      false,
 	}
 	// 启动广播 goroutine
@@ -60,6 +66,7 @@ func (b *MessageBus) PublishInbound(ctx context.Context, msg *InboundMessage) er
 
 	select {
 	case b.inbound <- msg:
+		metrics.MessagesInbound.Inc(msg.Channel)
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
@@ -113,6 +120,9 @@ func (b *MessageBus) PublishOutbound(ctx context.Context, msg *OutboundMessage)
 		logger.Debug("Outbound message published successfully",
 			zap.String("id", msg.ID),
 			zap.Int("outbound_queue_size", len(b.outbound)))
+		if !msg.IsStream {
+			metrics.MessagesOutbound.Inc(msg.Channel)
+		}
 		return nil
 	case <-ctx.Done():
 		logger.Warn("PublishOutbound context cancelled",
@@ -162,6 +172,18 @@ func (b *MessageBus) PublishAgentEvent(ctx context.Context, payload *AgentEventP
 		payload.Ts = time.Now().UnixMilli()
 	}
 
+	if payload.Critical() {
+		// 关键事件不丢弃：即使 ctx 已取消（run 被中止时仍需送达 end/error）也有限等待入队
+		timer := time.NewTimer(criticalAgentEventWait)
+		defer timer.Stop()
+		select {
+		case b.agentEvents <- payload:
+			return nil
+		case <-timer.C:
+			return ErrAgentEventQueueFull
+		}
+	}
+
 	select {
 	case b.agentEvents <- payload:
 		return nil
@@ -218,11 +240,23 @@ func (b *MessageBus) UnsubscribeAgentEvent(subID string) {
 func (b *MessageBus) fanoutAgentEvents() {
 	for payload := range b.agentEvents {
 		b.agentSubsMu.RLock()
+		critical := payload.Critical()
 		for _, ch := range b.agentSubs {
 			select {
 			case ch <- payload:
+				continue
 			default:
 			}
+			if !critical {
+				continue
+			}
+			// 订阅者暂时积压时，关键事件有限等待而非直接丢弃
+			timer := time.NewTimer(criticalAgentEventWait)
+			select {
+			case ch <- payload:
+			case <-timer.C:
+			}
+			timer.Stop()
 		}
 		b.agentSubsMu.RUnlock()
 	}
@@ -396,7 +430,8 @@ func (b *MessageBus) OutboundChan() <-chan *OutboundMessage {
 
 // Errors
 var (
-	ErrBusClosed = &BusError{Message: "message bus is closed"}
+	ErrBusClosed           = &BusError{Message: "message bus is closed"}
+	ErrAgentEventQueueFull = &BusError{Message: "agent event queue is full"}
 )
 
 // BusError 总线错误
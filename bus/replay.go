@@ -0,0 +1,119 @@
+package bus
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件重放缓冲默认值
+const (
+	DefaultReplayEventsPerSession = 500
+	DefaultReplayTTL              = 10 * time.Minute
+)
+
+// ReplayEvent 缓冲的一条 Agent 事件；Seq 为 Gateway 广播帧的全局序号（跨运行单调递增）
+type ReplayEvent struct {
+	Seq     uint64             `json:"seq"`
+	Payload *AgentEventPayload `json:"payload"`
+}
+
+// ReplayBuffer 按会话保存最近的 Agent 事件，供断线重连的客户端按 seq 补齐。
+// 每个会话为固定容量的环形缓冲；运行结束时丢弃该运行的过程事件，仅保留结束事件，
+// 会话超过 TTL 无新事件后整体淘汰
+type ReplayBuffer struct {
+	mu         sync.Mutex
+	perSession int
+	ttl        time.Duration
+	sessions   map[string]*replaySession
+	lastPrune  time.Time
+	now        func() time.Time
+}
+
+type replaySession struct {
+	events    []ReplayEvent
+	droppedTo uint64 // 因容量淘汰的最大 seq，sinceSeq 小于它时补齐不完整
+	updatedAt time.Time
+}
+
+// NewReplayBuffer 创建事件重放缓冲；perSession/ttl 为 0 时使用默认值
+func NewReplayBuffer(perSession int, ttl time.Duration) *ReplayBuffer {
+	if perSession <= 0 {
+		perSession = DefaultReplayEventsPerSession
+	}
+	if ttl <= 0 {
+		ttl = DefaultReplayTTL
+	}
+	return &ReplayBuffer{
+		perSession: perSession,
+		ttl:        ttl,
+		sessions:   make(map[string]*replaySession),
+		now:        time.Now,
+	}
+}
+
+// Add 记录一条已广播的事件；sessionKey 为空时忽略
+func (b *ReplayBuffer) Add(sessionKey string, seq uint64, payload *AgentEventPayload) {
+	if sessionKey == "" || payload == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.pruneLocked(now)
+
+	s := b.sessions[sessionKey]
+	if s == nil {
+		s = &replaySession{}
+		b.sessions[sessionKey] = s
+	}
+	s.updatedAt = now
+
+	if payload.Stream == AgentStreamLifecycle && payload.Critical() {
+		// 运行结束：过程事件不再需要重放，只保留结束事件让重连的客户端得知运行已结束
+		kept := s.events[:0]
+		for _, e := range s.events {
+			if e.Payload.RunId != payload.RunId {
+				kept = append(kept, e)
+			}
+		}
+		s.events = kept
+	}
+
+	s.events = append(s.events, ReplayEvent{Seq: seq, Payload: payload})
+	if over := len(s.events) - b.perSession; over > 0 {
+		s.droppedTo = s.events[over-1].Seq
+		s.events = append(s.events[:0], s.events[over:]...)
+	}
+}
+
+// Since 返回会话中 seq 大于 sinceSeq 的事件（按 seq 升序）；truncated 表示 sinceSeq 之后有事件因容量已被淘汰
+func (b *ReplayBuffer) Since(sessionKey string, sinceSeq uint64) (events []ReplayEvent, truncated bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pruneLocked(b.now())
+
+	s := b.sessions[sessionKey]
+	if s == nil {
+		return []ReplayEvent{}, false
+	}
+	events = make([]ReplayEvent, 0, len(s.events))
+	for _, e := range s.events {
+		if e.Seq > sinceSeq {
+			events = append(events, e)
+		}
+	}
+	return events, s.droppedTo > sinceSeq
+}
+
+// pruneLocked 淘汰超过 TTL 未更新的会话（至多每分钟扫描一次）
+func (b *ReplayBuffer) pruneLocked(now time.Time) {
+	if now.Sub(b.lastPrune) < time.Minute {
+		return
+	}
+	b.lastPrune = now
+	for key, s := range b.sessions {
+		if now.Sub(s.updatedAt) > b.ttl {
+			delete(b.sessions, key)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package bus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayBufferSinceAndRunEnd(t *testing.T) {
+	b := NewReplayBuffer(10, time.Minute)
+	ev := func(run string, stream AgentEventStream, phase string) *AgentEventPayload {
+		return &AgentEventPayload{RunId: run, Stream: stream, Data: map[string]interface{}{"phase": phase}}
+	}
+	b.Add("agent:main:main", 1, ev("r1", AgentStreamLifecycle, "start"))
+	b.Add("agent:main:main", 2, ev("r1", AgentStreamAssistant, ""))
+	b.Add("agent:main:other", 3, ev("r2", AgentStreamLifecycle, "start"))
+	b.Add("agent:main:main", 4, ev("r1", AgentStreamTool, "start"))
+
+	events, truncated := b.Since("agent:main:main", 1)
+	if truncated || len(events) != 2 || events[0].Seq != 2 || events[1].Seq != 4 {
+		t.Fatalf("Since(1) = %+v truncated=%v", events, truncated)
+	}
+
+	// 运行结束后只保留结束事件
+	b.Add("agent:main:main", 5, ev("r1", AgentStreamLifecycle, "end"))
+	events, _ = b.Since("agent:main:main", 0)
+	if len(events) != 1 || events[0].Seq != 5 {
+		t.Fatalf("after end: %+v", events)
+	}
+	if events, _ := b.Since("agent:main:other", 0); len(events) != 1 {
+		t.Fatalf("other session affected: %+v", events)
+	}
+}
+
+func TestReplayBufferCapacityAndTTL(t *testing.T) {
+	b := NewReplayBuffer(3, time.Minute)
+	now := time.Unix(1000, 0)
+	b.now = func() time.Time { return now }
+	for seq := uint64(1); seq <= 5; seq++ {
+		b.Add("s", seq, &AgentEventPayload{RunId: "r", Stream: AgentStreamAssistant})
+	}
+	events, truncated := b.Since("s", 1)
+	if !truncated || len(events) != 3 || events[0].Seq != 3 {
+		t.Fatalf("Since(1) = %+v truncated=%v", events, truncated)
+	}
+	if _, truncated := b.Since("s", 2); truncated {
+		t.Fatal("Since(2) should not be truncated")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if events, _ := b.Since("s", 0); len(events) != 0 {
+		t.Fatalf("expired session still buffered: %+v", events)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 )
 
 // BaseChannel 通道基础接口
@@ -32,6 +34,28 @@ type BaseChannel interface {
 	IsAllowed(senderID string) bool
 }
 
+// DeliveryResult 单条出站消息的投递结果
+type DeliveryResult struct {
+	MessageID string // 平台侧的消息 ID（通道能提供时）
+}
+
+// ResultSender 可选接口：发送并返回平台侧投递结果的通道；未实现时以 Send 的返回值作为投递结果
+type ResultSender interface {
+	SendWithResult(msg *bus.OutboundMessage) (DeliveryResult, error)
+}
+
+// LoginChannel 支持扫码 / 配对登录的通道（如 WhatsApp bridge），供 web.login.start / web.login.wait 使用
+type LoginChannel interface {
+	// LoginStart 请求新的登录二维码（或配对码）内容
+	LoginStart() (qr string, err error)
+
+	// LoginConnected 查询账号是否已完成配对并连接
+	LoginConnected(ctx context.Context) (bool, error)
+}
+
+// LoginQRValidity 登录二维码的有效期（过期后需重新 web.login.start）
+const LoginQRValidity = 60 * time.Second
+
 // BaseChannelConfig 通道基础配置
 type BaseChannelConfig struct {
 	Enabled    bool     `mapstructure:"enabled" json:"enabled"`
@@ -152,3 +176,25 @@ func (c *BaseChannelImpl) SendStream(chatID string, stream <-chan *bus.StreamMes
 
 	return nil
 }
+
+// channelCommands 由通道自身处理的命令；其他以 / 开头的消息（如 /new、/model、/help）照常进入总线，由 Agent 预处理
+var channelCommands = map[string]bool{"/start": true, "/status": true}
+
+// isChannelCommand 判断消息是否为通道自身处理的命令
+func isChannelCommand(content string) bool {
+	return channelCommands[content]
+}
+
+// welcomeText /start 欢迎语；def 为未配置 gateway.locale 时的语言
+func welcomeText(def string) string {
+	return i18n.TWithDefault(def, i18n.ChannelWelcome)
+}
+
+// statusText /status 运行状态文案；def 为未配置 gateway.locale 时的语言
+func statusText(def string, running bool) string {
+	state := i18n.TWithDefault(def, i18n.ChannelOffline)
+	if running {
+		state = i18n.TWithDefault(def, i18n.ChannelOnline)
+	}
+	return i18n.TWithDefault(def, i18n.ChannelStatus, state)
+}
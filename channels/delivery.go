@@ -0,0 +1,241 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DeliveryReport 出站消息经 DispatchOutbound 投递后的结果
+type DeliveryReport struct {
+	Result DeliveryResult
+	Err    error
+}
+
+// deliveryWaiters 按出站消息 ID 等待投递结果（channels.send.test 使用）
+type deliveryWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]chan DeliveryReport
+}
+
+// AwaitDelivery 在发布出站消息前登记，返回接收该消息投递结果的 channel 与取消登记的函数
+func (m *Manager) AwaitDelivery(msgID string) (<-chan DeliveryReport, func()) {
+	ch := make(chan DeliveryReport, 1)
+	m.delivery.mu.Lock()
+	if m.delivery.waiters == nil {
+		m.delivery.waiters = make(map[string]chan DeliveryReport)
+	}
+	m.delivery.waiters[msgID] = ch
+	m.delivery.mu.Unlock()
+	return ch, func() {
+		m.delivery.mu.Lock()
+		delete(m.delivery.waiters, msgID)
+		m.delivery.mu.Unlock()
+	}
+}
+
+// reportDelivery 将投递结果交给登记的等待方（没有等待方时忽略）
+func (m *Manager) reportDelivery(msgID string, result DeliveryResult, err error) {
+	m.delivery.mu.Lock()
+	ch, ok := m.delivery.waiters[msgID]
+	delete(m.delivery.waiters, msgID)
+	m.delivery.mu.Unlock()
+	if ok {
+		ch <- DeliveryReport{Result: result, Err: err}
+	}
+}
+
+// deliver 通过通道发送；通道实现 ResultSender 时带回平台侧结果
+func deliver(channel BaseChannel, msg *bus.OutboundMessage) (DeliveryResult, error) {
+	if rs, ok := channel.(ResultSender); ok {
+		return rs.SendWithResult(msg)
+	}
+	return DeliveryResult{}, channel.Send(msg)
+}
+
+// AccountChannelName 返回通道类型下某账号的注册名（default 账号为通道类型本身，其余为 <type>:<accountId>）
+func AccountChannelName(channelType, accountID string) string {
+	return buildChannelName(channelType, accountID)
+}
+
+// DeliveryRetryPolicy 出站投递失败后的重试策略（指数退避）
+type DeliveryRetryPolicy struct {
+	MaxAttempts int           // 含首次发送的最大尝试次数
+	BaseDelay   time.Duration // 首次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待上限
+}
+
+// DefaultDeliveryRetryPolicy 未配置时的重试策略
+var DefaultDeliveryRetryPolicy = DeliveryRetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// DeliveryRetryPolicyFromConfig 由 channels.delivery_* 配置构造重试策略，未设置的项使用默认值
+func DeliveryRetryPolicyFromConfig(cfg *config.ChannelsConfig) DeliveryRetryPolicy {
+	p := DefaultDeliveryRetryPolicy
+	if cfg == nil {
+		return p
+	}
+	if cfg.DeliveryMaxAttempts > 0 {
+		p.MaxAttempts = cfg.DeliveryMaxAttempts
+	}
+	if cfg.DeliveryRetryBaseMs > 0 {
+		p.BaseDelay = time.Duration(cfg.DeliveryRetryBaseMs) * time.Millisecond
+	}
+	if cfg.DeliveryRetryMaxMs > 0 {
+		p.MaxDelay = time.Duration(cfg.DeliveryRetryMaxMs) * time.Millisecond
+	}
+	return p
+}
+
+// backoff 返回第 n 次重试（从 1 开始）前的等待时间
+func (p DeliveryRetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// deliveryQueues 按会话（通道 + chatID）排队的出站消息：某条消息进入重试后，同一会话的后续消息排在其后依次投递，
+// 保证到达顺序；队列存在即表示该会话有 worker 在运行
+type deliveryQueues struct {
+	mu     sync.Mutex
+	queues map[string][]*bus.OutboundMessage
+}
+
+func deliveryQueueKey(msg *bus.OutboundMessage) string {
+	return msg.Channel + "\x00" + msg.ChatID
+}
+
+// deliveryPending 判断 msg 所属会话是否有消息在排队重试
+func (m *Manager) deliveryPending(msg *bus.OutboundMessage) bool {
+	m.queues.mu.Lock()
+	defer m.queues.mu.Unlock()
+	_, ok := m.queues.queues[deliveryQueueKey(msg)]
+	return ok
+}
+
+// enqueueIfPending 会话已有排队中的消息时将 msg 追加到队尾并返回 true
+func (m *Manager) enqueueIfPending(msg *bus.OutboundMessage) bool {
+	m.queues.mu.Lock()
+	defer m.queues.mu.Unlock()
+	key := deliveryQueueKey(msg)
+	if _, ok := m.queues.queues[key]; !ok {
+		return false
+	}
+	m.queues.queues[key] = append(m.queues.queues[key], msg)
+	return true
+}
+
+// enqueueDelivery 将 msg 交给会话队列：已有队列时排到队尾，否则创建队列并启动 worker。
+// attempts / lastErr 为 msg 已进行的尝试次数与最近一次错误（首次发送失败时为 1 与该错误）
+func (m *Manager) enqueueDelivery(ctx context.Context, msg *bus.OutboundMessage, attempts int, lastErr error) {
+	m.queues.mu.Lock()
+	defer m.queues.mu.Unlock()
+	key := deliveryQueueKey(msg)
+	if _, ok := m.queues.queues[key]; ok {
+		m.queues.queues[key] = append(m.queues.queues[key], msg)
+		return
+	}
+	if m.queues.queues == nil {
+		m.queues.queues = make(map[string][]*bus.OutboundMessage)
+	}
+	m.queues.queues[key] = []*bus.OutboundMessage{}
+	go m.runDeliveryQueue(ctx, key, msg, attempts, lastErr)
+}
+
+// nextQueued 取出会话队列的下一条消息；队列为空时删除队列并返回 nil
+func (m *Manager) nextQueued(key string) *bus.OutboundMessage {
+	m.queues.mu.Lock()
+	defer m.queues.mu.Unlock()
+	queue := m.queues.queues[key]
+	if len(queue) == 0 {
+		delete(m.queues.queues, key)
+		return nil
+	}
+	m.queues.queues[key] = queue[1:]
+	return queue[0]
+}
+
+// runDeliveryQueue 依次投递会话队列中的消息：每条按重试策略重试，成功或全部失败后才报告最终结果，
+// 失败的消息写入死信队列后继续投递下一条
+func (m *Manager) runDeliveryQueue(ctx context.Context, key string, msg *bus.OutboundMessage, attempts int, lastErr error) {
+	for msg != nil {
+		result, attempts, err := m.deliverWithRetry(ctx, msg, attempts, lastErr)
+		m.reportDelivery(msg.ID, result, err)
+		if err != nil {
+			m.deadLetter(msg, err, attempts)
+		}
+		msg, attempts, lastErr = m.nextQueued(key), 0, nil
+	}
+}
+
+// deliverWithRetry 按重试策略投递 msg（已尝试 attempts 次，最近一次错误为 lastErr），返回结果与总尝试次数；
+// 分发器退出时停止等待并返回最近一次错误
+func (m *Manager) deliverWithRetry(ctx context.Context, msg *bus.OutboundMessage, attempts int, lastErr error) (DeliveryResult, int, error) {
+	m.mu.RLock()
+	policy := m.retry
+	m.mu.RUnlock()
+
+	err := lastErr
+	for attempts < max(policy.MaxAttempts, 1) {
+		if attempts > 0 {
+			select {
+			case <-ctx.Done():
+				if err == nil {
+					err = ctx.Err()
+				}
+				return DeliveryResult{}, attempts, err
+			case <-time.After(policy.backoff(attempts)):
+			}
+		}
+		attempts++
+		channel, ok := m.Get(msg.Channel)
+		if !ok {
+			err = fmt.Errorf("channel not found: %s", msg.Channel)
+			continue
+		}
+		var result DeliveryResult
+		if result, err = deliver(channel, msg); err == nil {
+			if attempts > 1 {
+				logger.Info("Outbound message delivered after retry",
+					zap.String("channel", msg.Channel),
+					zap.String("chat_id", msg.ChatID),
+					zap.Int("attempts", attempts))
+			}
+			return result, attempts, nil
+		}
+		logger.Warn("Outbound delivery attempt failed",
+			zap.String("channel", msg.Channel),
+			zap.Int("attempt", attempts),
+			zap.Error(err))
+	}
+	return DeliveryResult{}, attempts, err
+}
+
+// RetryDeadLetter 重新投递一条死信：直接交给原通道的会话队列（不经总线，避免 websocket 等其他订阅方重复收到），
+// 再次失败时重新进入死信队列
+func (m *Manager) RetryDeadLetter(ctx context.Context, id string) error {
+	entry, ok := m.bus.DeadLetters().Take(id)
+	if !ok {
+		return bus.ErrDeadLetterNotFound
+	}
+	m.enqueueDelivery(ctx, entry.Message, 0, nil)
+	return nil
+}
+
+// deadLetter 将无法投递的消息写入总线死信队列
+func (m *Manager) deadLetter(msg *bus.OutboundMessage, err error, attempts int) {
+	m.bus.DeadLetters().Add(msg, err.Error(), attempts)
+	logger.Error("Outbound message moved to dead-letter queue",
+		zap.String("id", msg.ID),
+		zap.String("channel", msg.Channel),
+		zap.String("chat_id", msg.ChatID),
+		zap.Int("attempts", attempts),
+		zap.Error(err))
+}
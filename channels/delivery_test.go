@@ -0,0 +1,134 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+)
+
+// flakyChannel 前 failures 次发送失败，之后成功；记录成功送达的消息内容
+type flakyChannel struct {
+	*BaseChannelImpl
+	mu        sync.Mutex
+	failures  int
+	attempts  int
+	delivered []string
+}
+
+func (c *flakyChannel) Send(msg *bus.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts++
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("network down")
+	}
+	c.delivered = append(c.delivered, msg.Content)
+	return nil
+}
+
+func (c *flakyChannel) snapshot() (int, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts, append([]string(nil), c.delivered...)
+}
+
+func newDeliveryTestManager(t *testing.T, failures int) (*Manager, *bus.MessageBus, *flakyChannel) {
+	t.Helper()
+	b := bus.NewMessageBus(10)
+	t.Cleanup(func() { _ = b.Close() })
+	m := NewManager(b)
+	m.retry = DeliveryRetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	ch := &flakyChannel{BaseChannelImpl: NewBaseChannelImpl("test", "default", BaseChannelConfig{}, b), failures: failures}
+	if err := m.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+	return m, b, ch
+}
+
+func TestDeliveryRetryKeepsOrderAndReportsFinalOutcome(t *testing.T) {
+	m, b, ch := newDeliveryTestManager(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m.DispatchOutbound(ctx) }()
+	time.Sleep(20 * time.Millisecond) // 等待分发器订阅
+
+	report, done := m.AwaitDelivery("m1")
+	defer done()
+	for i, content := range []string{"first", "second", "third"} {
+		msg := &bus.OutboundMessage{ID: []string{"m1", "m2", "m3"}[i], Channel: "test", ChatID: "42", Content: content}
+		if err := b.PublishOutbound(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case r := <-report:
+		if r.Err != nil {
+			t.Fatalf("first message reported %v, want success after retry", r.Err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no delivery report")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		attempts, delivered := ch.snapshot()
+		if len(delivered) == 3 {
+			if delivered[0] != "first" || delivered[1] != "second" || delivered[2] != "third" || attempts != 4 {
+				t.Fatalf("delivered %v in %d attempts, want in order in 4", delivered, attempts)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivered %v", delivered)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if b.DeadLetters().Len() != 0 {
+		t.Fatal("delivered message moved to the dead-letter queue")
+	}
+}
+
+func TestRetryDeadLetterTargetsOnlyItsChannel(t *testing.T) {
+	m, b, ch := newDeliveryTestManager(t, 3)
+	sub := b.SubscribeOutbound()
+	defer sub.Unsubscribe()
+
+	msg := &bus.OutboundMessage{ID: "m1", Channel: "test", ChatID: "42", Content: "hi"}
+	m.enqueueDelivery(context.Background(), msg, 0, nil)
+	deadline := time.Now().Add(2 * time.Second)
+	for b.DeadLetters().Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("message was not dead-lettered after exhausting retries")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if entry := b.DeadLetters().List()[0]; entry.Attempts != 3 {
+		t.Fatalf("dead letter attempts = %d, want 3", entry.Attempts)
+	}
+
+	if err := m.RetryDeadLetter(context.Background(), "m1"); err != nil {
+		t.Fatalf("RetryDeadLetter() error = %v", err)
+	}
+	for {
+		if _, delivered := ch.snapshot(); len(delivered) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("dead letter was not redelivered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case got := <-sub.Channel:
+		t.Fatalf("retry re-published %+v to bus subscribers", got)
+	default:
+	}
+	if err := m.RetryDeadLetter(context.Background(), "m1"); !errors.Is(err, bus.ErrDeadLetterNotFound) {
+		t.Fatalf("second RetryDeadLetter() error = %v", err)
+	}
+}
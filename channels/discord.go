@@ -8,6 +8,7 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 )
@@ -124,7 +125,7 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	}
 
 	// 处理命令
-	if strings.HasPrefix(m.Content, "/") {
+	if isChannelCommand(m.Content) {
 		c.handleCommand(context.Background(), m)
 		return
 	}
@@ -181,23 +182,12 @@ func (c *DiscordChannel) handleCommand(ctx context.Context, m *discordgo.Message
 
 	switch command {
 	case "/start":
-		_, err := c.session.ChannelMessageSend(m.ChannelID, "👋 Welcome to goclaw!\n\nI can help you with various tasks. Send /help to see available commands.")
-		if err != nil {
-			logger.Error("Failed to send Discord message", zap.Error(err))
-		}
-	case "/help":
-		helpText := `🐾 goclaw commands:
-
-/start - Get started
-/help - Show this help message
-
-You can chat with me directly and I'll do my best to help!`
-		_, err := c.session.ChannelMessageSend(m.ChannelID, helpText)
+		_, err := c.session.ChannelMessageSend(m.ChannelID, welcomeText(i18n.LocaleEN))
 		if err != nil {
 			logger.Error("Failed to send Discord message", zap.Error(err))
 		}
 	case "/status":
-		statusText := fmt.Sprintf("✅ goclaw is running\n\nChannel status: %s", map[bool]string{true: "🟢 Online", false: "🔴 Offline"}[c.IsRunning()])
+		statusText := statusText(i18n.LocaleEN, c.IsRunning())
 		_, err := c.session.ChannelMessageSend(m.ChannelID, statusText)
 		if err != nil {
 			logger.Error("Failed to send Discord message", zap.Error(err))
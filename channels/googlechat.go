@@ -7,11 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 	"google.golang.org/api/chat/v1"
@@ -117,7 +117,7 @@ func (c *GoogleChatChannel) HandleWebhook(ctx context.Context, event *chat.Depre
 	}
 
 	// 处理命令
-	if strings.HasPrefix(event.Message.Text, "/") {
+	if isChannelCommand(event.Message.Text) {
 		return c.handleCommand(ctx, event)
 	}
 
@@ -145,16 +145,9 @@ func (c *GoogleChatChannel) handleCommand(ctx context.Context, event *chat.Depre
 	var responseText string
 	switch command {
 	case "/start":
-		responseText = "👋 Welcome to goclaw!\n\nI can help you with various tasks. Send /help to see available commands."
-	case "/help":
-		responseText = `🐾 goclaw commands:
-
-/start - Get started
-/help - Show this help message
-
-You can chat with me directly and I'll do my best to help!`
+		responseText = welcomeText(i18n.LocaleEN)
 	case "/status":
-		responseText = fmt.Sprintf("✅ goclaw is running\n\nChannel status: %s", map[bool]string{true: "🟢 Online", false: "🔴 Offline"}[c.IsRunning()])
+		responseText = statusText(i18n.LocaleEN, c.IsRunning())
 	default:
 		return nil
 	}
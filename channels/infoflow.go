@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
 	infoflow "github.com/smallnest/infoflow"
 	"go.uber.org/zap"
@@ -142,7 +143,7 @@ func (c *InfoflowChannel) handleDefault(cmd, fromUserID string, body infoflow.Bo
 func (c *InfoflowChannel) handleStatus(cmd, fromUserID string, body infoflow.Body, msg infoflow.HiMessage) error {
 	if !c.IsAllowed(fromUserID) {
 		return c.sender.SendMsg2Group(msg.GroupID,
-			infoflow.CreateText("你无权查看状态"),
+			infoflow.CreateText(i18n.T(i18n.ChannelStatusDenied)),
 		)
 	}
 
@@ -17,6 +17,9 @@ type Manager struct {
 	channels map[string]BaseChannel
 	bus      *bus.MessageBus
 	mu       sync.RWMutex
+	delivery deliveryWaiters
+	queues   deliveryQueues
+	retry    DeliveryRetryPolicy
 }
 
 // NewManager 创建通道管理器
@@ -24,6 +27,7 @@ func NewManager(bus *bus.MessageBus) *Manager {
 	return &Manager{
 		channels: make(map[string]BaseChannel),
 		bus:      bus,
+		retry:    DefaultDeliveryRetryPolicy,
 	}
 }
 
@@ -93,6 +97,11 @@ func (m *Manager) Get(name string) (BaseChannel, bool) {
 	return channel, ok
 }
 
+// GetAccount 按通道类型与账号 ID 获取已注册的通道（default 账号注册名为通道类型本身）
+func (m *Manager) GetAccount(channelType, accountID string) (BaseChannel, bool) {
+	return m.Get(buildChannelName(channelType, accountID))
+}
+
 // List 列出所有通道名称
 func (m *Manager) List() []string {
 	m.mu.RLock()
@@ -171,22 +180,42 @@ func (m *Manager) DispatchOutbound(ctx context.Context) error {
 				continue
 			}
 
+			// 该会话有消息在重试时排到其后，保证顺序；流式增量由后续完整消息覆盖，直接丢弃
+			if msg.IsStream {
+				if m.deliveryPending(msg) {
+					continue
+				}
+			} else if m.enqueueIfPending(msg) {
+				continue
+			}
+
 			// 查找对应的通道
 			channel, ok := m.Get(msg.Channel)
 			if !ok {
 				logger.Warn("Channel not found for outbound message",
 					zap.String("channel", msg.Channel),
 				)
+				err := fmt.Errorf("channel not found: %s", msg.Channel)
+				m.reportDelivery(msg.ID, DeliveryResult{}, err)
+				m.deadLetter(msg, err, 1)
 				continue
 			}
 
 			// 发送消息
-			if err := channel.Send(msg); err != nil {
+			result, err := deliver(channel, msg)
+			if err != nil {
 				logger.Error("Failed to send message via channel",
 					zap.String("channel", msg.Channel),
 					zap.Error(err),
 				)
+				// 流式增量由后续完整消息覆盖，不重试；其余消息在会话队列中重试，结束后报告最终结果
+				if msg.IsStream {
+					m.reportDelivery(msg.ID, result, err)
+				} else {
+					m.enqueueDelivery(ctx, msg, 1, err)
+				}
 			} else {
+				m.reportDelivery(msg.ID, result, nil)
 				logger.Info("Message sent successfully via channel",
 					zap.String("channel", msg.Channel),
 					zap.String("chat_id", msg.ChatID))
@@ -197,6 +226,10 @@ func (m *Manager) DispatchOutbound(ctx context.Context) error {
 
 // SetupFromConfig 从配置设置通道
 func (m *Manager) SetupFromConfig(cfg *config.Config) error {
+	m.mu.Lock()
+	m.retry = DeliveryRetryPolicyFromConfig(&cfg.Channels)
+	m.mu.Unlock()
+
 	// 1. 优先使用新的多账号配置格式
 	// 2. 如果没有账号配置，则回退到旧的配置格式
 
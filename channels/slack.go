@@ -8,6 +8,7 @@ import (
 
 	"github.com/slack-go/slack"
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 )
@@ -126,7 +127,7 @@ func (c *SlackChannel) handleMessage(ctx context.Context, ev *slack.MessageEvent
 	}
 
 	// 处理命令
-	if strings.HasPrefix(ev.Text, "/") {
+	if isChannelCommand(ev.Text) {
 		c.handleCommand(ctx, ev)
 		return
 	}
@@ -158,23 +159,12 @@ func (c *SlackChannel) handleCommand(ctx context.Context, ev *slack.MessageEvent
 
 	switch command {
 	case "/start":
-		_, _, err := c.client.PostMessage(ev.Channel, slack.MsgOptionText("👋 Welcome to goclaw!\n\nI can help you with various tasks. Send /help to see available commands.", false))
-		if err != nil {
-			logger.Error("Failed to send Slack message", zap.Error(err))
-		}
-	case "/help":
-		helpText := `🐾 goclaw commands:
-
-/start - Get started
-/help - Show this help message
-
-You can chat with me directly and I'll do my best to help!`
-		_, _, err := c.client.PostMessage(ev.Channel, slack.MsgOptionText(helpText, false))
+		_, _, err := c.client.PostMessage(ev.Channel, slack.MsgOptionText(welcomeText(i18n.LocaleEN), false))
 		if err != nil {
 			logger.Error("Failed to send Slack message", zap.Error(err))
 		}
 	case "/status":
-		statusText := fmt.Sprintf("✅ goclaw is running\n\nChannel status: %s", map[bool]string{true: "🟢 Online", false: "🔴 Offline"}[c.IsRunning()])
+		statusText := statusText(i18n.LocaleEN, c.IsRunning())
 		_, _, err := c.client.PostMessage(ev.Channel, slack.MsgOptionText(statusText, false))
 		if err != nil {
 			logger.Error("Failed to send Slack message", zap.Error(err))
@@ -7,10 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 )
@@ -109,7 +109,7 @@ func (c *TeamsChannel) HandleWebhook(ctx context.Context, webhookMsg *TeamsWebho
 	}
 
 	// 处理命令
-	if strings.HasPrefix(webhookMsg.Text, "/") {
+	if isChannelCommand(webhookMsg.Text) {
 		return c.handleCommand(ctx, webhookMsg)
 	}
 
@@ -139,16 +139,9 @@ func (c *TeamsChannel) handleCommand(ctx context.Context, webhookMsg *TeamsWebho
 	var responseText string
 	switch command {
 	case "/start":
-		responseText = "👋 Welcome to goclaw!\n\nI can help you with various tasks. Send /help to see available commands."
-	case "/help":
-		responseText = `🐾 goclaw commands:
-
-/start - Get started
-/help - Show this help message
-
-You can chat with me directly and I'll do my best to help!`
+		responseText = welcomeText(i18n.LocaleEN)
 	case "/status":
-		responseText = fmt.Sprintf("✅ goclaw is running\n\nChannel status: %s", map[bool]string{true: "🟢 Online", false: "🔴 Offline"}[c.IsRunning()])
+		responseText = statusText(i18n.LocaleEN, c.IsRunning())
 	default:
 		return nil
 	}
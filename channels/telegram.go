@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
 	telegrambot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/internal/i18n"
 	"github.com/smallnest/goclaw/internal/logger"
 	"go.uber.org/zap"
 )
@@ -121,7 +121,7 @@ func (c *TelegramChannel) handleUpdate(ctx context.Context, update *telegrambot.
 	}
 
 	// 处理命令
-	if strings.HasPrefix(content, "/") {
+	if isChannelCommand(content) {
 		return c.handleCommand(ctx, message, content)
 	}
 
@@ -152,23 +152,12 @@ func (c *TelegramChannel) handleCommand(ctx context.Context, message *telegrambo
 
 	switch command {
 	case "/start":
-		msg := telegrambot.NewMessage(chatID, "👋 欢迎使用 goclaw!\n\n我可以帮助你完成各种任务。发送 /help 查看可用命令。")
-		if _, err := c.bot.Send(msg); err != nil {
-			return err
-		}
-	case "/help":
-		helpText := `🐾 goclaw 命令列表：
-
-/start - 开始使用
-/help - 显示帮助
-
-你可以直接与我对话，我会尽力帮助你！`
-		msg := telegrambot.NewMessage(chatID, helpText)
+		msg := telegrambot.NewMessage(chatID, welcomeText(i18n.LocaleZH))
 		if _, err := c.bot.Send(msg); err != nil {
 			return err
 		}
 	case "/status":
-		statusText := fmt.Sprintf("✅ goclaw 运行中\n\n通道状态: %s", map[bool]string{true: "🟢 在线", false: "🔴 离线"}[c.IsRunning()])
+		statusText := statusText(i18n.LocaleZH, c.IsRunning())
 		msg := telegrambot.NewMessage(chatID, statusText)
 		if _, err := c.bot.Send(msg); err != nil {
 			return err
@@ -217,14 +206,20 @@ func (c *TelegramChannel) extractMedia(message *telegrambot.Message) []bus.Media
 
 // Send 发送消息
 func (c *TelegramChannel) Send(msg *bus.OutboundMessage) error {
+	_, err := c.SendWithResult(msg)
+	return err
+}
+
+// SendWithResult 发送消息并返回 Telegram 消息 ID
+func (c *TelegramChannel) SendWithResult(msg *bus.OutboundMessage) (DeliveryResult, error) {
 	if !c.IsRunning() {
-		return fmt.Errorf("telegram channel is not running")
+		return DeliveryResult{}, fmt.Errorf("telegram channel is not running")
 	}
 
 	// 解析 ChatID
 	chatID, err := strconv.ParseInt(msg.ChatID, 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid chat id: %w", err)
+		return DeliveryResult{}, fmt.Errorf("invalid chat id: %w", err)
 	}
 
 	// 创建消息
@@ -241,9 +236,9 @@ func (c *TelegramChannel) Send(msg *bus.OutboundMessage) error {
 	}
 
 	// 发送消息
-	_, err = c.bot.Send(tgMsg)
+	sent, err := c.bot.Send(tgMsg)
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %w", err)
+		return DeliveryResult{}, fmt.Errorf("failed to send telegram message: %w", err)
 	}
 
 	logger.Info("Telegram message sent",
@@ -251,5 +246,5 @@ func (c *TelegramChannel) Send(msg *bus.OutboundMessage) error {
 		zap.Int("content_length", len(msg.Content)),
 	)
 
-	return nil
+	return DeliveryResult{MessageID: strconv.Itoa(sent.MessageID)}, nil
 }
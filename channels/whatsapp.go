@@ -186,6 +186,62 @@ func (c *WhatsAppChannel) Send(msg *bus.OutboundMessage) error {
 	return nil
 }
 
+// LoginStart 向 bridge 请求登录二维码（GET /login/qr，返回 {"qr": "..."}）
+func (c *WhatsAppChannel) LoginStart() (string, error) {
+	if c.bridgeURL == "" {
+		return "", fmt.Errorf("whatsapp bridge URL not configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		QR        string `json:"qr"`
+		Connected bool   `json:"connected"`
+	}
+	if err := c.getBridgeJSON(ctx, "/login/qr", &result); err != nil {
+		return "", err
+	}
+	if result.QR == "" {
+		if result.Connected {
+			return "", fmt.Errorf("whatsapp account is already connected")
+		}
+		return "", fmt.Errorf("whatsapp bridge returned no QR code")
+	}
+	return result.QR, nil
+}
+
+// LoginConnected 查询 bridge 连接状态（GET /status，返回 {"connected": bool}）
+func (c *WhatsAppChannel) LoginConnected(ctx context.Context) (bool, error) {
+	if c.bridgeURL == "" {
+		return false, fmt.Errorf("whatsapp bridge URL not configured")
+	}
+	var result struct {
+		Connected bool `json:"connected"`
+	}
+	if err := c.getBridgeJSON(ctx, "/status", &result); err != nil {
+		return false, err
+	}
+	return result.Connected, nil
+}
+
+// getBridgeJSON 请求 bridge 的 GET 接口并解析 JSON 响应
+func (c *WhatsAppChannel) getBridgeJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.bridgeURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
 // WhatsAppMessage WhatsApp 消息
 type WhatsAppMessage struct {
 	ID        string `json:"id"`
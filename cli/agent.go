@@ -66,8 +66,8 @@ func runAgent(cmd *cobra.Command, args []string) {
 
 	// Initialize logger if verbose or thinking mode is enabled（按日期写入 logs/goclaw-2006-01-02.log）
 	if agentVerbose || agentThinking {
-		logPath := filepath.Join(internal.GetGoclawDir(), "logs", "goclaw-"+time.Now().Format("2006-01-02")+".log")
-		if err := logger.InitWithFile("debug", false, logPath); err != nil {
+		logDir := filepath.Join(internal.GetGoclawDir(), "logs")
+		if err := logger.InitWithDailyFile("debug", false, logDir); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 			os.Exit(1)
 		}
@@ -114,9 +114,13 @@ func runAgent(cmd *cobra.Command, args []string) {
 	if cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
 			Mode: cfg.Session.Reset.Mode, AtHour: cfg.Session.Reset.AtHour, IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		sessionMgr.SetResetPolicy(&p)
 	}
+	sessionMgr.SetMediaInlineMax(cfg.Session.MediaInlineMaxBytes)
+	sessionMgr.SetHistoryLimits(cfg.Session.MaxMessages, cfg.Session.MaxBytes)
 
 	// Create memory store
 	memoryStore := agent.NewMemoryStore(workspace)
@@ -206,6 +210,9 @@ func runAgent(cmd *cobra.Command, args []string) {
 	if err := skillsLoader.Discover(); err != nil && agentVerbose {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to discover skills: %v\n", err)
 	}
+	if err := toolRegistry.RegisterExisting(tools.NewListSkillsTool(skillsLoader.Summaries)); err != nil && agentVerbose {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to register list_skills: %v\n", err)
+	}
 
 	// Create LLM provider
 	provider, err := providers.NewProvider(cfg)
@@ -214,6 +221,13 @@ func runAgent(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	defer provider.Close()
+	// Summarize the oldest turns when a session exceeds its on-disk limit
+	sessionMgr.SetHistoryCompactor(agent.NewSessionHistoryCompactor(provider, cfg.Agents.Defaults))
+	defer sessionMgr.WaitHistoryCompaction()
+	// Tool approvals are only enforced for gateway runs; a one-shot CLI run has nobody to answer them
+	if agent.ApprovalsRequired(cfg) {
+		fmt.Fprintf(os.Stderr, "Warning: approvals.behavior=%s only applies to gateway runs; tools run without approval here\n", cfg.Approvals.Behavior)
+	}
 
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(agentTimeout)*time.Second)
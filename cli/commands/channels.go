@@ -127,9 +127,7 @@ func getAllKnownChannels() []ChannelInfo {
 
 // getChannelsFromGateway retrieves channel list from gateway
 func getChannelsFromGateway(timeout int) []ChannelInfo {
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := localGatewayClient(time.Duration(timeout) * time.Second)
 
 	// Try different WebSocket gateway ports
 	ports := []int{28789, 28790, 28791}
@@ -137,7 +135,7 @@ func getChannelsFromGateway(timeout int) []ChannelInfo {
 
 	for _, port := range ports {
 		// Try to get channels from the HTTP API
-		url := fmt.Sprintf("http://localhost:%d/api/channels", port)
+		url := localGatewayURL(port, "/api/channels")
 		resp, err := client.Get(url)
 		if err != nil {
 			continue
@@ -173,9 +171,7 @@ func getChannelsFromGateway(timeout int) []ChannelInfo {
 
 // getChannelStatusFromGateway retrieves channel status from gateway
 func getChannelStatusFromGateway(channelName string, timeout int) map[string]interface{} {
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := localGatewayClient(time.Duration(timeout) * time.Second)
 
 	// Try different WebSocket gateway ports
 	ports := []int{28789, 28790, 28791}
@@ -183,7 +179,7 @@ func getChannelStatusFromGateway(channelName string, timeout int) map[string]int
 	for _, port := range ports {
 		// If channel name is specified, get specific channel status
 		// Otherwise, get all channels
-		url := fmt.Sprintf("http://localhost:%d/api/channels", port)
+		url := localGatewayURL(port, "/api/channels")
 		if channelName != "" {
 			url += "?channel=" + channelName
 		}
@@ -229,7 +225,7 @@ func getChannelStatusFromGateway(channelName string, timeout int) map[string]int
 	// Gateway is offline or endpoint not available
 	// Try health check as fallback
 	for _, port := range ports {
-		url := fmt.Sprintf("http://localhost:%d/health", port)
+		url := localGatewayURL(port, "/health")
 		resp, err := client.Get(url)
 		if err == nil {
 			defer resp.Body.Close()
@@ -370,14 +366,12 @@ func outputChannelStatusText(channelName string, status map[string]interface{})
 
 // checkGatewayOnline checks if the gateway is running
 func checkGatewayOnline(timeout int) bool {
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := localGatewayClient(time.Duration(timeout) * time.Second)
 
 	ports := []int{28789, 28790, 28791}
 
 	for _, port := range ports {
-		url := fmt.Sprintf("http://localhost:%d/health", port)
+		url := localGatewayURL(port, "/health")
 		resp, err := client.Get(url)
 		if err == nil {
 			resp.Body.Close()
@@ -527,12 +527,10 @@ func (r *CommandRegistry) checkGatewayStatus(timeout int) GatewayStatus {
 	result := GatewayStatus{Online: false}
 
 	ports := []int{28789, 28790, 28791}
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := localGatewayClient(time.Duration(timeout) * time.Second)
 
 	for _, port := range ports {
-		url := fmt.Sprintf("http://localhost:%d/health", port)
+		url := localGatewayURL(port, "/health")
 		resp, err := client.Get(url)
 		if err == nil {
 			defer resp.Body.Close()
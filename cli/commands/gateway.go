@@ -163,18 +163,18 @@ func GatewayCommand() *cobra.Command {
 
 // runGateway runs the gateway server
 func runGateway(cmd *cobra.Command, args []string) {
-	// 日志同时输出到 stdout 与 ~/.goclaw/logs/goclaw-2006-01-02.log（按日期）
-	logPath := filepath.Join(internal.GetGoclawDir(), "logs", "goclaw-"+time.Now().Format("2006-01-02")+".log")
+	// 日志同时输出到 stdout 与 ~/.goclaw/logs/goclaw-2006-01-02.log（按日期，跨天自动切换文件）
+	logDir := filepath.Join(internal.GetGoclawDir(), "logs")
 	logLevel := "info"
 	if gatewayVerbose {
 		logLevel = "debug"
 	}
-	if err := logger.InitWithFile(logLevel, false, logPath); err != nil {
+	if err := logger.InitWithDailyFile(logLevel, false, logDir); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync() // nolint:errcheck
-	logger.Info("Log file", zap.String("path", logPath))
+	logger.Info("Log file", zap.String("path", logger.FilePath()))
 
 	fmt.Println("🚀 Starting goclaw Gateway")
 
@@ -224,6 +224,8 @@ func runGateway(cmd *cobra.Command, args []string) {
 	if cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
 			Mode: cfg.Session.Reset.Mode, AtHour: cfg.Session.Reset.AtHour, IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		sessionPolicy = &p
 		sessionMgr.SetResetPolicy(sessionPolicy)
@@ -253,6 +255,10 @@ func runGateway(cmd *cobra.Command, args []string) {
 			ReadTimeout:    60 * time.Second,
 			WriteTimeout:   10 * time.Second,
 			MaxMessageSize: 10 * 1024 * 1024,
+			EnableTLS:      cfg.Gateway.WebSocket.EnableTLS,
+			CertFile:       cfg.Gateway.WebSocket.CertFile,
+			KeyFile:        cfg.Gateway.WebSocket.KeyFile,
+			AllowedOrigins: cfg.Gateway.WebSocket.AllowedOrigins,
 		}
 
 		if gatewayPassword != "" {
@@ -275,6 +281,14 @@ func runGateway(cmd *cobra.Command, args []string) {
 		cancel()
 	}()
 
+	// Sweep idle sessions in the background; the sweeper reads the current reset policy on every tick,
+	// so an idle policy enabled by a config reload takes effect without a restart
+	var sweepInterval time.Duration
+	if r := cfg.Session.Reset; r != nil {
+		sweepInterval = time.Duration(r.SweepIntervalSeconds) * time.Second
+	}
+	sessionMgr.StartIdleSweeper(ctx, sweepInterval)
+
 	// Start gateway
 	if err := gatewayServer.Start(ctx); err != nil {
 		logger.Fatal("Failed to start gateway", zap.Error(err))
@@ -300,12 +314,17 @@ func runGateway(cmd *cobra.Command, args []string) {
 			// Update session manager configuration
 			if newCfg.Session.Reset != nil {
 				p := session.ToResetPolicy(&session.SessionResetConfigLike{
-					Mode:        newCfg.Session.Reset.Mode,
-					AtHour:      newCfg.Session.Reset.AtHour,
-					IdleMinutes: newCfg.Session.Reset.IdleMinutes,
+					Mode:          newCfg.Session.Reset.Mode,
+					AtHour:        newCfg.Session.Reset.AtHour,
+					IdleMinutes:   newCfg.Session.Reset.IdleMinutes,
+					NotifyOnReset: newCfg.Session.Reset.NotifyOnReset,
+					Timezone:      newCfg.Session.Reset.Timezone,
 				})
 				sessionMgr.SetResetPolicy(&p)
 				gatewayServer.SetSessionResetPolicy(&p)
+			} else {
+				sessionMgr.SetResetPolicy(nil)
+				gatewayServer.SetSessionResetPolicy(nil)
 			}
 
 			// Broadcast config reload notification to all connected clients
@@ -356,8 +375,8 @@ func runGateway(cmd *cobra.Command, args []string) {
 	}
 
 	fmt.Printf("Gateway listening on %s:%d\n", displayHost, displayPort)
-	fmt.Printf("WebSocket: ws://%s:%d/ws\n", displayHost, displayPort)
-	fmt.Printf("Health: http://%s:%d/health\n", displayHost, displayPort)
+	fmt.Printf("WebSocket: %s://%s:%d/ws\n", cfg.Gateway.WebSocketScheme(), displayHost, displayPort)
+	fmt.Printf("Health: %s://%s:%d/health\n", cfg.Gateway.HTTPScheme(), displayHost, displayPort)
 
 	if gatewayAuth || gatewayToken != "" || gatewayPassword != "" {
 		fmt.Println("Authentication: enabled")
@@ -380,12 +399,12 @@ func runGateway(cmd *cobra.Command, args []string) {
 // runGatewayStatus shows gateway status
 func runGatewayStatus(cmd *cobra.Command, args []string) {
 	// Try to connect to local gateway
-	url := fmt.Sprintf("http://localhost:%d/health", gatewayPort)
+	url := localGatewayURL(gatewayPort, "/health")
 	if gatewayPort == 0 {
-		url = "http://localhost:28789/health"
+		url = localGatewayURL(28789, "/health")
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := localGatewayClient(5 * time.Second)
 	resp, err := client.Get(url)
 	if err != nil {
 		fmt.Printf("Gateway status: offline\n")
@@ -412,12 +431,12 @@ func runGatewayStatus(cmd *cobra.Command, args []string) {
 
 // runGatewayHealth checks gateway health
 func runGatewayHealth(cmd *cobra.Command, args []string) {
-	url := fmt.Sprintf("http://localhost:%d/health", gatewayPort)
+	url := localGatewayURL(gatewayPort, "/health")
 	if gatewayPort == 0 {
-		url = "http://localhost:28789/health"
+		url = localGatewayURL(28789, "/health")
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := localGatewayClient(5 * time.Second)
 	resp, err := client.Get(url)
 	if err != nil {
 		fmt.Printf("Health check failed: %v\n", err)
@@ -444,8 +463,8 @@ func runGatewayProbe(cmd *cobra.Command, args []string) {
 
 	fmt.Println("Probing for gateway...")
 	for _, port := range ports {
-		url := fmt.Sprintf("http://localhost:%d/health", port)
-		client := &http.Client{Timeout: 2 * time.Second}
+		url := localGatewayURL(port, "/health")
+		client := localGatewayClient(2 * time.Second)
 
 		resp, err := client.Get(url)
 		if err == nil {
@@ -1219,12 +1238,12 @@ func restartWindowsService() {
 
 // checkGatewayRunning checks if the gateway is responding
 func checkGatewayRunning() bool {
-	url := fmt.Sprintf("http://localhost:%d/health", gatewayPort)
+	url := localGatewayURL(gatewayPort, "/health")
 	if gatewayPort == 0 {
-		url = "http://localhost:28789/health"
+		url = localGatewayURL(28789, "/health")
 	}
 
-	client := &http.Client{Timeout: 2 * time.Second}
+	client := localGatewayClient(2 * time.Second)
 	resp, err := client.Get(url)
 	if err != nil {
 		return false
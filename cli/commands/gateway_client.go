@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// localGatewayConfig returns the gateway section of the loaded config (defaults when none can be loaded)
+var localGatewayConfig = sync.OnceValue(func() *config.GatewayConfig {
+	if cfg := config.Get(); cfg != nil {
+		return &cfg.Gateway
+	}
+	if cfg, err := config.Load(""); err == nil && cfg != nil {
+		return &cfg.Gateway
+	}
+	return &config.GatewayConfig{}
+})
+
+// localGatewayURL returns the loopback URL of path on port, using https when the gateway serves TLS
+func localGatewayURL(port int, path string) string {
+	return fmt.Sprintf("%s://localhost:%d%s", localGatewayConfig().HTTPScheme(), port, path)
+}
+
+// localGatewayClient returns an HTTP client for probing the local gateway
+func localGatewayClient(timeout time.Duration) *http.Client {
+	return GatewayHTTPClient(localGatewayConfig(), "localhost", timeout)
+}
+
+// GatewayHTTPClient returns an HTTP client for calling the gateway at host. With TLS enabled,
+// certificate verification is skipped for loopback hosts only: the certificate is normally
+// issued for the public hostname, not localhost.
+func GatewayHTTPClient(gw *config.GatewayConfig, host string, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if gw.WebSocket.EnableTLS && isLoopbackHost(host) {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return client
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -51,10 +51,8 @@ func runHealth(cmd *cobra.Command, args []string) {
 	var resp *http.Response
 
 	for _, port := range ports {
-		url := fmt.Sprintf("http://localhost:%d/health", port)
-		client := &http.Client{
-			Timeout: time.Duration(healthTimeout) * time.Second,
-		}
+		url := localGatewayURL(port, "/health")
+		client := localGatewayClient(time.Duration(healthTimeout) * time.Second)
 
 		resp, lastErr = client.Get(url)
 		if lastErr == nil {
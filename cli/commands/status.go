@@ -88,6 +88,8 @@ func runStatus(cmd *cobra.Command, args []string) {
 	} else if cfg != nil && cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
 			Mode: cfg.Session.Reset.Mode, AtHour: cfg.Session.Reset.AtHour, IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		sessionMgr.SetResetPolicy(&p)
 	}
@@ -116,12 +118,10 @@ func checkGatewayStatus(timeout int) GatewayStatus {
 	result := GatewayStatus{Online: false}
 
 	ports := []int{28789, 28790, 28791}
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := localGatewayClient(time.Duration(timeout) * time.Second)
 
 	for _, port := range ports {
-		url := fmt.Sprintf("http://localhost:%d/health", port)
+		url := localGatewayURL(port, "/health")
 		resp, err := client.Get(url)
 		if err == nil {
 			defer resp.Body.Close()
@@ -59,6 +59,9 @@ func NewTUIAgent(
 
 	// Register use_skill tool
 	_ = toolRegistry.RegisterExisting(tools.NewUseSkillTool())
+	if skillsLoader != nil {
+		_ = toolRegistry.RegisterExisting(tools.NewListSkillsTool(skillsLoader.Summaries))
+	}
 
 	if feishuDocsTool := tools.NewFeishuDocsToolFromConfig(feishuCfg); feishuDocsTool != nil {
 		for _, tool := range feishuDocsTool.GetTools() {
@@ -204,9 +207,13 @@ func runTUI(cmd *cobra.Command, args []string) {
 	if cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
 			Mode: cfg.Session.Reset.Mode, AtHour: cfg.Session.Reset.AtHour, IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		sessionMgr.SetResetPolicy(&p)
 	}
+	sessionMgr.SetMediaInlineMax(cfg.Session.MediaInlineMaxBytes)
+	sessionMgr.SetHistoryLimits(cfg.Session.MaxMessages, cfg.Session.MaxBytes)
 
 	// Create memory store
 	memoryStore := agent.NewMemoryStore(workspace)
@@ -222,6 +229,13 @@ func runTUI(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	defer provider.Close()
+	// Summarize the oldest turns when a session exceeds its on-disk limit
+	sessionMgr.SetHistoryCompactor(agent.NewSessionHistoryCompactor(provider, cfg.Agents.Defaults))
+	defer sessionMgr.WaitHistoryCompaction()
+	// Tool approvals are only enforced for gateway runs; nobody could answer them from here
+	if agent.ApprovalsRequired(cfg) {
+		fmt.Fprintf(os.Stderr, "Warning: approvals.behavior=%s only applies to gateway runs; tools run without approval in the TUI\n", cfg.Approvals.Behavior)
+	}
 
 	// Create skills loader（Windows 兼容）
 	goclawDir := internal.GetGoclawDir()
@@ -406,9 +420,9 @@ func processTUIDialogue(
 	historyLimit int,
 ) string {
 	// Load history messages
-	history := sess.GetHistory(historyLimit)
+	history := sess.GetHydratedHistory(historyLimit)
 	if historyLimit < 0 || historyLimit > 1000 {
-		history = sess.GetHistory(-1) // unlimited
+		history = sess.GetHydratedHistory(-1) // unlimited
 	}
 
 	// Convert session messages to agent messages
@@ -530,7 +544,7 @@ func runAgentIteration(
 		}
 
 		// Build messages
-		history := sess.GetHistory(tuiHistoryLimit)
+		history := sess.GetHydratedHistory(tuiHistoryLimit)
 
 		// 检查是否需要添加错误处理指导
 		var errorGuidance string
@@ -954,6 +968,11 @@ func sessionMessagesToAgentMessages(history []session.Message) []agent.AgentMess
 			}
 			agentMsg.Metadata["reasoning_content"] = reasoning
 		}
+		for _, media := range sessMsg.Media {
+			if media.Type == "image" && (media.URL != "" || media.Base64 != "") {
+				agentMsg.Content = append(agentMsg.Content, agent.ImageContent{URL: media.URL, Data: media.Base64, MimeType: media.MimeType})
+			}
+		}
 
 		result = append(result, agentMsg)
 	}
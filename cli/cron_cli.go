@@ -88,6 +88,7 @@ var (
 	cronAddCron        string
 	cronAddMessage     string
 	cronAddSystemEvent string
+	cronAddTimezone    string
 	cronRunsID         string
 	cronRunsLimit      int
 	cronRunForce       bool
@@ -98,6 +99,7 @@ var (
 	cronEditCron        string
 	cronEditMessage     string
 	cronEditSystemEvent string
+	cronEditTimezone    string
 	cronEditEnable      bool
 	cronEditDisable     bool
 )
@@ -132,6 +134,7 @@ func init() {
 	cronAddCmd.Flags().StringVar(&cronAddCron, "cron", "", "Cron expression")
 	cronAddCmd.Flags().StringVar(&cronAddMessage, "message", "", "Message to send")
 	cronAddCmd.Flags().StringVar(&cronAddSystemEvent, "system-event", "", "System event type")
+	cronAddCmd.Flags().StringVar(&cronAddTimezone, "timezone", "", "IANA timezone for the schedule (e.g., Asia/Shanghai; default: server local)")
 	_ = cronAddCmd.MarkFlagRequired("name")
 
 	// cron runs flags
@@ -148,6 +151,7 @@ func init() {
 	cronEditCmd.Flags().StringVar(&cronEditCron, "cron", "", "Cron expression")
 	cronEditCmd.Flags().StringVar(&cronEditMessage, "message", "", "Message to send")
 	cronEditCmd.Flags().StringVar(&cronEditSystemEvent, "system-event", "", "System event type")
+	cronEditCmd.Flags().StringVar(&cronEditTimezone, "timezone", "", "IANA timezone for the schedule (e.g., Asia/Shanghai)")
 	cronEditCmd.Flags().BoolVar(&cronEditEnable, "enable", false, "Enable the job")
 	cronEditCmd.Flags().BoolVar(&cronEditDisable, "disable", false, "Disable the job")
 }
@@ -268,7 +272,7 @@ func runCronAdd(cmd *cobra.Command, args []string) {
 	}
 
 	// Validate schedule
-	if _, err := cron.Parse(schedule); err != nil {
+	if _, err := cron.ParseInLocation(schedule, cronAddTimezone); err != nil {
 		fmt.Fprintf(os.Stderr, "Invalid schedule: %v\n", err)
 		os.Exit(1)
 	}
@@ -292,6 +296,7 @@ func runCronAdd(cmd *cobra.Command, args []string) {
 		ID:        id,
 		Name:      cronAddName,
 		Schedule:  schedule,
+		Timezone:  cronAddTimezone,
 		Task:      task,
 		Message:   cronAddMessage,
 		EventType: cronAddSystemEvent,
@@ -322,7 +327,7 @@ func runCronEdit(cmd *cobra.Command, args []string) {
 	// Check if any edit flag is provided
 	hasChanges := cronEditName != "" || cronEditAt != "" || cronEditEvery != "" ||
 		cronEditCron != "" || cronEditMessage != "" || cronEditSystemEvent != "" ||
-		cronEditEnable || cronEditDisable
+		cronEditTimezone != "" || cronEditEnable || cronEditDisable
 
 	if !hasChanges {
 		fmt.Fprintln(os.Stderr, "Error: No changes specified. Use at least one flag:")
@@ -332,6 +337,7 @@ func runCronEdit(cmd *cobra.Command, args []string) {
 		fmt.Fprintln(os.Stderr, "  --cron <expression>")
 		fmt.Fprintln(os.Stderr, "  --message <text>")
 		fmt.Fprintln(os.Stderr, "  --system-event <text>")
+		fmt.Fprintln(os.Stderr, "  --timezone <tz>")
 		fmt.Fprintln(os.Stderr, "  --enable")
 		fmt.Fprintln(os.Stderr, "  --disable")
 		os.Exit(1)
@@ -379,6 +385,15 @@ func runCronEdit(cmd *cobra.Command, args []string) {
 		job.Schedule = schedule
 	}
 
+	// Update timezone if specified
+	if cronEditTimezone != "" {
+		if _, err := cron.LoadLocation(cronEditTimezone); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid timezone: %v\n", err)
+			os.Exit(1)
+		}
+		job.Timezone = cronEditTimezone
+	}
+
 	// Update message if specified
 	if cronEditMessage != "" {
 		job.Message = cronEditMessage
@@ -412,6 +427,9 @@ func runCronEdit(cmd *cobra.Command, args []string) {
 	fmt.Printf("  ID: %s\n", job.ID)
 	fmt.Printf("  Name: %s\n", job.Name)
 	fmt.Printf("  Schedule: %s\n", job.Schedule)
+	if job.Timezone != "" {
+		fmt.Printf("  Timezone: %s\n", job.Timezone)
+	}
 	fmt.Printf("  Task: %s\n", job.Task)
 	if job.EventType != "" {
 		fmt.Printf("  Event Type: %s\n", job.EventType)
@@ -604,6 +622,7 @@ type JobData struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
 	Schedule  string    `json:"schedule"`
+	Timezone  string    `json:"timezone,omitempty"`
 	Task      string    `json:"task"`
 	Message   string    `json:"message,omitempty"`
 	EventType string    `json:"event_type,omitempty"`
@@ -2,17 +2,23 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/internal"
+	"github.com/smallnest/goclaw/providers"
 	"github.com/spf13/cobra"
 )
 
+// onboardProbeTimeout onboard 校验 API key 的超时
+const onboardProbeTimeout = 15 * time.Second
+
 var (
 	onboardAPIKey       string
 	onboardBaseURL      string
@@ -33,6 +39,7 @@ var providerOptions = []struct {
 	{"openrouter", "OpenRouter (多模型聚合)"},
 	{"9router", "9router (本地代理 / OpenAI 兼容)"},
 	{"kimi", "Kimi / 月之暗面 (Kimi K2.5, ...)"},
+	{"gemini", "Google Gemini (Gemini 2.5 Pro/Flash, ...)"},
 }
 
 // 各 provider 的推荐模型列表（与 OpenClaw 常用选项对齐）
@@ -63,6 +70,9 @@ var suggestedModelsByProvider = map[string][]string{
 		"kimi-k2.5", "kimi-k2-0905-preview", "kimi-k2-turbo-preview",
 		"kimi-k2-thinking", "kimi-k2-thinking-turbo",
 	},
+	"gemini": {
+		"gemini-2.5-flash", "gemini-2.5-pro", "gemini-2.0-flash",
+	},
 }
 
 var onboardCmd = &cobra.Command{
@@ -84,7 +94,7 @@ func init() {
 	onboardCmd.Flags().StringVarP(&onboardAPIKey, "api-key", "k", "", "API key for the provider (required in non-interactive mode)")
 	onboardCmd.Flags().StringVarP(&onboardBaseURL, "base-url", "u", "", "Base URL for the provider API")
 	onboardCmd.Flags().StringVarP(&onboardModel, "model", "m", "", "Model name to use")
-	onboardCmd.Flags().StringVarP(&onboardProvider, "provider", "p", "openai", "Provider: openai, anthropic, openrouter, 9router, or kimi")
+	onboardCmd.Flags().StringVarP(&onboardProvider, "provider", "p", "openai", "Provider: openai, anthropic, openrouter, 9router, kimi, or gemini")
 	onboardCmd.Flags().BoolVar(&onboardSkipPrompts, "skip-prompts", false, "Skip all prompts (use defaults)")
 	onboardCmd.Flags().BoolVar(&onboardReconfigure, "reconfigure", false, "Re-run API key and model setup even when config exists (like OpenClaw)")
 	onboardCmd.Flags().BoolVar(&onboardReset, "reset", false, "Full reset: remove config, sessions, and workspace, then run onboarding (like OpenClaw --reset)")
@@ -326,8 +336,16 @@ func nonInteractiveSetup(cfg *config.Config) error {
 		if onboardModel != "" {
 			cfg.Agents.Defaults.Model = onboardModel
 		}
+	case "gemini":
+		cfg.Providers.Gemini.APIKey = onboardAPIKey
+		if onboardBaseURL != "" {
+			cfg.Providers.Gemini.BaseURL = onboardBaseURL
+		}
+		if onboardModel != "" {
+			cfg.Agents.Defaults.Model = onboardModel
+		}
 	default:
-		return fmt.Errorf("invalid provider: %s (must be openai, anthropic, openrouter, 9router, or kimi)", provider)
+		return fmt.Errorf("invalid provider: %s (must be openai, anthropic, openrouter, 9router, kimi, or gemini)", provider)
 	}
 
 	fmt.Printf("  ✓ Provider configured: %s\n", provider)
@@ -344,7 +362,8 @@ func interactiveSetup(cfg *config.Config, fullFlow bool) error {
 		cfg.Providers.Anthropic.APIKey != "" ||
 		cfg.Providers.OpenRouter.APIKey != "" ||
 		cfg.Providers.Router9.APIKey != "" ||
-		cfg.Providers.Moonshot.APIKey != ""
+		cfg.Providers.Moonshot.APIKey != "" ||
+		cfg.Providers.Gemini.APIKey != ""
 
 	if hasAPIKey {
 		fmt.Println("  API key already configured. Press Enter to keep or enter new value:")
@@ -392,6 +411,12 @@ func interactiveSetup(cfg *config.Config, fullFlow bool) error {
 		} else {
 			defaultBaseURL = "https://api.moonshot.cn/v1"
 		}
+	case "gemini":
+		if cfg.Providers.Gemini.BaseURL != "" {
+			defaultBaseURL = cfg.Providers.Gemini.BaseURL
+		} else {
+			defaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+		}
 	}
 	baseURL := promptString("Base URL (press Enter for default)", defaultBaseURL, false)
 
@@ -433,8 +458,27 @@ func interactiveSetup(cfg *config.Config, fullFlow bool) error {
 		cfg.Providers.Moonshot.APIKey = apiKey
 		cfg.Providers.Moonshot.BaseURL = baseURL
 		cfg.Agents.Defaults.Model = model
+	case "gemini":
+		cfg.Providers.Gemini.APIKey = apiKey
+		cfg.Providers.Gemini.BaseURL = baseURL
+		cfg.Agents.Defaults.Model = model
 	default:
-		return fmt.Errorf("invalid provider: %s (must be openai, anthropic, openrouter, 9router, or kimi)", provider)
+		return fmt.Errorf("invalid provider: %s (must be openai, anthropic, openrouter, 9router, kimi, or gemini)", provider)
+	}
+
+	// 用一次极小调用校验 API key / base URL，失败只提示，不阻止保存
+	probeProvider := provider
+	if probeProvider == "kimi" {
+		probeProvider = string(providers.ProviderTypeMoonshot)
+	}
+	fmt.Println("  Testing API key...")
+	if res, err := providers.ProbeProvider(context.Background(), cfg, providers.ProbeRequest{Provider: probeProvider, Model: model}, onboardProbeTimeout); err != nil {
+		fmt.Printf("  ⚠ Skipped provider test: %v\n", err)
+	} else if res.OK() {
+		fmt.Printf("  ✓ Provider reachable (%d ms)\n", res.LatencyMs)
+	} else {
+		fmt.Printf("  ⚠ Provider test failed: %s\n", res.Error)
+		fmt.Println("    The configuration is saved anyway; fix the key or base URL and run onboard again.")
 	}
 
 	if fullFlow {
@@ -463,6 +507,8 @@ func promptProviderChoice(cfg *config.Config) string {
 		defaultProvider = "9router"
 	} else if cfg.Providers.Moonshot.APIKey != "" {
 		defaultProvider = "kimi"
+	} else if cfg.Providers.Gemini.APIKey != "" {
+		defaultProvider = "gemini"
 	}
 	fmt.Println("  模型供应商 (enter number or name):")
 	for i, opt := range providerOptions {
@@ -493,6 +539,8 @@ func promptProviderChoice(cfg *config.Config) string {
 		return "9router"
 	case "kimi", "moonshot":
 		return "kimi"
+	case "gemini", "google":
+		return "gemini"
 	}
 	return line
 }
@@ -641,6 +689,8 @@ func getAPIKeyForProvider(cfg *config.Config, provider string) string {
 		return cfg.Providers.Router9.APIKey
 	case "kimi":
 		return cfg.Providers.Moonshot.APIKey
+	case "gemini":
+		return cfg.Providers.Gemini.APIKey
 	}
 	return ""
 }
@@ -712,6 +762,9 @@ func printSummary(cfg *config.Config) {
 	} else if cfg.Providers.Moonshot.APIKey != "" {
 		providerName = "Kimi (Moonshot)"
 		providerAPIKey = maskAPIKey(cfg.Providers.Moonshot.APIKey)
+	} else if cfg.Providers.Gemini.APIKey != "" {
+		providerName = "Google Gemini"
+		providerAPIKey = maskAPIKey(cfg.Providers.Gemini.APIKey)
 	}
 
 	if providerName != "" {
@@ -760,7 +813,7 @@ func printSummary(cfg *config.Config) {
 	}
 
 	// Gateway info
-	fmt.Printf("  Gateway:   http://%s:%d\n", cfg.Gateway.Host, cfg.Gateway.Port)
+	fmt.Printf("  Gateway:   %s://%s:%d\n", cfg.Gateway.HTTPScheme(), cfg.Gateway.Host, cfg.Gateway.Port)
 
 	fmt.Println()
 	fmt.Println("═════════════════════════════════════════════════════════")
@@ -772,10 +825,10 @@ func printSummary(cfg *config.Config) {
 	fmt.Println("     or: $ goclaw gateway run --port 28789")
 	fmt.Println()
 	fmt.Println("  2. Connect via HTTP:")
-	fmt.Printf("     $ curl http://localhost:%d/health\n", cfg.Gateway.Port)
+	fmt.Printf("     $ curl %s://localhost:%d/health\n", cfg.Gateway.HTTPScheme(), cfg.Gateway.Port)
 	fmt.Println()
 	fmt.Println("  3. WebSocket / Web UI:")
-	fmt.Printf("     ws://localhost:%d/ws  (or open http://localhost:28789/)\n", cfg.Gateway.Port)
+	fmt.Printf("     %s://localhost:%d/ws  (or open %s://localhost:%d/)\n", cfg.Gateway.WebSocketScheme(), cfg.Gateway.Port, cfg.Gateway.HTTPScheme(), cfg.Gateway.Port)
 	fmt.Println()
 	fmt.Println("  4. View configuration:")
 	fmt.Printf("     $ goclaw config show\n")
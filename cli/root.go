@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -18,6 +19,7 @@ import (
 	"github.com/smallnest/goclaw/cron"
 	"github.com/smallnest/goclaw/gateway"
 	"github.com/smallnest/goclaw/internal"
+	"github.com/smallnest/goclaw/internal/diskspace"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/internal/workspace"
 	"github.com/smallnest/goclaw/memory"
@@ -144,15 +146,15 @@ func runStart(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// 初始化日志，按日期写入 ~/.goclaw/logs/goclaw-2006-01-02.log
-	logPath := filepath.Join(internal.GetGoclawDir(), "logs", "goclaw-"+time.Now().Format("2006-01-02")+".log")
-	if err := logger.InitWithFile("info", false, logPath); err != nil {
+	// 初始化日志，按日期写入 ~/.goclaw/logs/goclaw-2006-01-02.log（跨天自动切换文件）
+	logDir := filepath.Join(internal.GetGoclawDir(), "logs")
+	if err := logger.InitWithDailyFile("info", false, logDir); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer func() { _ = logger.Sync() }()
 
-	logger.Info("Starting goclaw agent", zap.String("log_file", logPath))
+	logger.Info("Starting goclaw agent", zap.String("log_file", logger.FilePath()))
 	configFile := config.ConfigFileUsed()
 	if configFile == "" {
 		configFile = "(defaults/env only)"
@@ -198,9 +200,13 @@ func runStart(cmd *cobra.Command, args []string) {
 	if cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
 			Mode: cfg.Session.Reset.Mode, AtHour: cfg.Session.Reset.AtHour, IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		sessionMgr.SetResetPolicy(&p)
 	}
+	sessionMgr.SetMediaInlineMax(cfg.Session.MediaInlineMaxBytes)
+	sessionMgr.SetHistoryLimits(cfg.Session.MaxMessages, cfg.Session.MaxBytes)
 
 	// 创建记忆存储
 	memoryStore := agent.NewMemoryStore(workspaceDir)
@@ -236,6 +242,9 @@ func runStart(cmd *cobra.Command, args []string) {
 	if err := toolRegistry.RegisterExisting(tools.NewUseSkillTool()); err != nil {
 		logger.Warn("Failed to register use_skill tool", zap.Error(err))
 	}
+	if err := toolRegistry.RegisterExisting(tools.NewListSkillsTool(skillsLoader.Summaries)); err != nil {
+		logger.Warn("Failed to register list_skills tool", zap.Error(err))
+	}
 
 	if feishuDocsTool := tools.NewFeishuDocsToolFromConfig(cfg.Channels.Feishu); feishuDocsTool != nil {
 		for _, tool := range feishuDocsTool.GetTools() {
@@ -318,11 +327,25 @@ func runStart(cmd *cobra.Command, args []string) {
 		logger.Fatal("Failed to create LLM provider", zap.Error(err))
 	}
 	defer provider.Close()
+	// 会话超出落盘上限时用 LLM 摘要被裁掉的最早若干轮
+	sessionMgr.SetHistoryCompactor(agent.NewSessionHistoryCompactor(provider, cfg.Agents.Defaults))
 
 	// 创建上下文
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 数据目录磁盘空间监控（status / /health?deep 展示，严重不足时可暂停新运行）
+	diskMonitor := diskspace.NewMonitor(internal.GetGoclawDir(), cfg.Gateway.MinFreeDiskMB, cfg.Gateway.PauseRunsOnLowDisk)
+	diskMonitor.Start(ctx)
+	diskspace.SetDefault(diskMonitor)
+
+	// idle 重置策略下后台巡检空闲会话，无需等到下次访问；巡检每轮读取当前策略，未启用 idle 策略时空转
+	var sweepInterval time.Duration
+	if r := cfg.Session.Reset; r != nil {
+		sweepInterval = time.Duration(r.SweepIntervalSeconds) * time.Second
+	}
+	sessionMgr.StartIdleSweeper(ctx, sweepInterval)
+
 	// 创建通道管理器
 	channelMgr := channels.NewManager(messageBus)
 	if err := channelMgr.SetupFromConfig(cfg); err != nil {
@@ -331,15 +354,46 @@ func runStart(cmd *cobra.Command, args []string) {
 
 	// 创建网关服务器
 	gatewayServer := gateway.NewServer(&cfg.Gateway, messageBus, channelMgr, sessionMgr)
+	// 注册定时任务工具（与网关 cron.* 共用同一个任务存储），agent 可为当前会话安排后续任务
+	cronJobs := gatewayServer.Handler().CronStore()
+	for _, t := range []tools.Tool{tools.NewCronListTool(cronJobs), tools.NewCronAddTool(cronJobs)} {
+		if err := toolRegistry.RegisterExisting(t); err != nil {
+			logger.Warn("Failed to register cron tool", zap.String("tool", t.Name()), zap.Error(err))
+		}
+	}
 	if cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
-			Mode:        cfg.Session.Reset.Mode,
-			AtHour:      cfg.Session.Reset.AtHour,
-			IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			Mode:          cfg.Session.Reset.Mode,
+			AtHour:        cfg.Session.Reset.AtHour,
+			IdleMinutes:   cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		gatewayServer.SetSessionResetPolicy(&p)
 	}
+	// providers.preflight：接收流量前以默认模型预检一次（同时预热连接），结果反映到 /ready
+	var preflight *providers.PreflightResult
+	if cfg.Providers.Preflight {
+		result := providers.Preflight(ctx, provider, cfg.Agents.Defaults.Model, providers.DefaultPreflightTimeout)
+		preflight = &result
+		if result.OK() {
+			logger.Info("Provider preflight succeeded",
+				zap.String("model", result.Model),
+				zap.Int64("latency_ms", result.LatencyMs))
+		} else {
+			logger.Error("Provider preflight failed",
+				zap.String("model", result.Model),
+				zap.String("reason", result.Reason),
+				zap.Int64("latency_ms", result.LatencyMs),
+				zap.String("error", result.Error))
+		}
+		gatewayServer.SetPreflightResult(result)
+	}
 	if err := gatewayServer.Start(ctx); err != nil {
+		// 开启 TLS 但证书不可用、或 /metrics 端口无法监听时直接退出，避免以明文或无网关状态运行
+		if errors.Is(err, gateway.ErrTLSCertificate) || errors.Is(err, gateway.ErrMetricsListen) {
+			logger.Fatal("Failed to start gateway server", zap.Error(err))
+		}
 		logger.Warn("Failed to start gateway server", zap.Error(err))
 	}
 	defer func() { _ = gatewayServer.Stop() }()
@@ -363,6 +417,54 @@ func runStart(cmd *cobra.Command, args []string) {
 		logger.Fatal("Failed to setup agent manager", zap.Error(err))
 	}
 
+	// skills.update / skills.reload 推送到运行中的 agent；启动时应用已持久化的禁用状态
+	gatewayServer.Handler().SetSkillsReloader(agentManager.ReloadSkills)
+	if err := gatewayServer.Handler().ReloadSkills(); err != nil {
+		logger.Warn("Failed to apply skills overlay", zap.Error(err))
+	}
+
+	// node.list 展示版本与已注册工具
+	gatewayServer.Handler().SetAppVersion(Version)
+	gatewayServer.Handler().SetToolNamesProvider(func() []string {
+		names := make([]string, 0, toolRegistry.Count())
+		for _, t := range toolRegistry.ListExisting() {
+			names = append(names, t.Name())
+		}
+		return names
+	})
+
+	// chat.send steer: true 时注入会话正在执行的 Run
+	gatewayServer.Handler().SetRunSteerer(agentManager.SteerActiveRun)
+
+	// agent.preview 以 dry-run 方式组装会话的 LLM 请求
+	gatewayServer.Handler().SetRunPreviewer(agentManager.PreviewRun)
+
+	// exec.approval.resolve 放行或拒绝等待审批的工具执行；remember 写入的 allowlist 免于再次审批
+	gatewayServer.Handler().SetApprovalResolver(agentManager.ResolveToolApproval)
+	agentManager.SetApprovalAllowlist(gatewayServer.Handler().ExecAllowlisted)
+
+	// chat.abort 中止会话排队中与执行中的 Run
+	gatewayServer.Handler().SetRunAborter(agentManager.AbortSessionRuns)
+
+	// sessions.archive 拒绝归档仍有 Run 的会话
+	gatewayServer.Handler().SetSessionRunLister(agentManager.SessionRunIDs)
+
+	// 启动预检结果交给 AgentManager，避免重复探测；status / node.list 展示提供商是否可用
+	if preflight != nil {
+		agentManager.SetProviderPreflight(*preflight)
+	}
+	gatewayServer.Handler().SetProviderHealthProvider(agentManager.ProviderHealth)
+	// /ready 在没有任何 Agent 时返回 503
+	gatewayServer.Handler().SetAgentLister(agentManager.ListAgents)
+
+	// status 展示各 Agent 的活跃 / 排队 Run 数（agents.list[].max_concurrent_runs）
+	gatewayServer.Handler().SetRunStatsProvider(func() interface{} { return agentManager.ActiveRunStats() })
+
+	// sessions.merge 合并两个会话（修复 tool 调用配对后保存目标会话）
+	gatewayServer.Handler().SetSessionMerger(func(sourceKey, targetKey, strategy string, deleteSource bool) (interface{}, error) {
+		return agentManager.MergeSessions(sourceKey, targetKey, strategy, deleteSource)
+	})
+
 	// 处理信号
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -28,6 +28,19 @@ var sessionsListCmd = &cobra.Command{
 	Run:   runSessionsList,
 }
 
+var sessionsMigrateMediaCmd = &cobra.Command{
+	Use:   "migrate-media",
+	Short: "Move inline base64 media out of session files",
+	Long:  `Rewrite existing sessions so that inline media larger than the cap is stored in <sessions>/media and referenced by mediaRef.`,
+	Run:   runSessionsMigrateMedia,
+}
+
+// Flags for sessions migrate-media
+var (
+	sessionsMigrateMaxBytes int
+	sessionsMigrateStore    string
+)
+
 // Flags for sessions list
 var (
 	sessionsListJSON    bool
@@ -42,7 +55,44 @@ func init() {
 	sessionsListCmd.Flags().StringVar(&sessionsListStore, "store", "", "Path to sessions directory")
 	sessionsListCmd.Flags().BoolVar(&sessionsListActive, "active", false, "Show only active sessions")
 
+	sessionsMigrateMediaCmd.Flags().IntVar(&sessionsMigrateMaxBytes, "max-bytes", 0, "Inline media cap in bytes (default: session.media_inline_max_bytes, or 64KB)")
+	sessionsMigrateMediaCmd.Flags().StringVar(&sessionsMigrateStore, "store", "", "Path to sessions directory")
+
 	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsMigrateMediaCmd)
+}
+
+// runSessionsMigrateMedia externalizes inline media of existing sessions
+func runSessionsMigrateMedia(cmd *cobra.Command, args []string) {
+	sessionDir := filepath.Join(internal.GetGoclawDir(), "sessions")
+	cfg, _ := config.Load("")
+	if sessionsMigrateStore != "" {
+		sessionDir = sessionsMigrateStore
+	} else if cfg != nil && cfg.Session.Store != "" {
+		sessionDir = cfg.Session.Store
+	}
+
+	maxBytes := sessionsMigrateMaxBytes
+	if maxBytes <= 0 && cfg != nil {
+		maxBytes = cfg.Session.MediaInlineMaxBytes
+	}
+	if maxBytes <= 0 {
+		maxBytes = 64 * 1024
+	}
+
+	sessionMgr, err := session.NewManager(sessionDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating session manager: %v\n", err)
+		os.Exit(1)
+	}
+	sessionMgr.SetMediaInlineMax(maxBytes)
+
+	moved, err := sessionMgr.MigrateInlineMedia()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating media: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Externalized %d media item(s) to %s\n", moved, sessionMgr.MediaStore().Dir())
 }
 
 // SessionInfo represents session information for display
@@ -77,6 +127,8 @@ func runSessionsList(cmd *cobra.Command, args []string) {
 	if cfg != nil && cfg.Session.Reset != nil {
 		p := session.ToResetPolicy(&session.SessionResetConfigLike{
 			Mode: cfg.Session.Reset.Mode, AtHour: cfg.Session.Reset.AtHour, IdleMinutes: cfg.Session.Reset.IdleMinutes,
+			NotifyOnReset: cfg.Session.Reset.NotifyOnReset,
+			Timezone:      cfg.Session.Reset.Timezone,
 		})
 		sessionMgr.SetResetPolicy(&p)
 	}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,9 +12,11 @@ import (
 
 	"github.com/smallnest/goclaw/agent"
 	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/gateway"
 	"github.com/smallnest/goclaw/internal"
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/skills"
 	"github.com/spf13/cobra"
 )
 
@@ -24,11 +27,14 @@ var skillsCmd = &cobra.Command{
 
 var skillsListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all discovered skills",
+	Short: "List installed skills in ~/.goclaw/skills",
 	Run:   runSkillsList,
 }
 
-var skillsListVerbose bool
+var (
+	skillsListVerbose bool
+	skillsListJSON    bool
+)
 
 var skillsValidateCmd = &cobra.Command{
 	Use:   "validate [skill-name]",
@@ -104,6 +110,7 @@ func init() {
 
 	// list 命令
 	skillsListCmd.Flags().BoolVarP(&skillsListVerbose, "verbose", "v", false, "Show detailed information including prompt content")
+	skillsListCmd.Flags().BoolVar(&skillsListJSON, "json", false, "Output as JSON")
 	skillsCmd.AddCommand(skillsListCmd)
 
 	// search 命令
@@ -141,68 +148,78 @@ func runSkillsList(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to ensure builtin skills: %v\n", err)
 	}
 
-	// 加载配置
-	_, err := config.Load("")
+	// 与 skills.status 相同：扫描 ~/.goclaw/skills 并解析各技能的 SKILL.md，叠加 skills-overlay.json 的启用状态与 API key
+	homeDir, _ := os.UserHomeDir()
+	skillsDir := filepath.Join(homeDir, ".goclaw", "skills")
+	installed, err := skills.ScanInstalledSkills(skillsDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to load config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to scan skills: %v\n", err)
+		os.Exit(1)
+	}
+	overlays, err := gateway.LoadSkillOverlays()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load skills overlay: %v\n", err)
 	}
 
-	// 初始化日志
-	if err := logger.Init("warn", false); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
-		os.Exit(1)
+	entries := make([]skillListEntry, 0, len(installed))
+	for _, s := range installed {
+		entry := skillListEntry{InstalledSkill: s, Enabled: true, Broken: s.Malformed()}
+		if o, ok := overlays[s.Key]; ok {
+			entry.Enabled = o.IsEnabled()
+			if o.APIKey != "" {
+				entry.APIKeySet = true
+				entry.APIKey = maskSecret("API_KEY", o.APIKey)
+			}
+		}
+		entries = append(entries, entry)
 	}
-	defer func() { _ = logger.Sync() }()
 
-	// 创建技能加载器（统一使用 ~/.goclaw/skills 目录）
-	goclawDir := os.Getenv("HOME") + "/.goclaw"
-	skillsDir := goclawDir + "/skills"
-	skillsLoader := agent.NewSkillsLoader(goclawDir, []string{skillsDir})
-	if err := skillsLoader.Discover(); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to discover skills: %v\n", err)
-		os.Exit(1)
+	if skillsListJSON {
+		data, err := json.MarshalIndent(map[string]interface{}{"dir": skillsDir, "skills": entries}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode skills: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
 	}
 
-	skills := skillsLoader.List()
-	if len(skills) == 0 {
-		fmt.Println("No skills found.")
+	if len(entries) == 0 {
+		fmt.Printf("No skills found in %s.\n", skillsDir)
 		return
 	}
 
-	fmt.Printf("Found %d skills:\n\n", len(skills))
-	for _, skill := range skills {
-		fmt.Printf("📦 %s\n", skill.Name)
-		if skill.Description != "" {
-			fmt.Printf("   %s\n", skill.Description)
+	fmt.Printf("Found %d skills in %s:\n\n", len(entries), skillsDir)
+	for _, e := range entries {
+		title := e.Key
+		if e.Name != "" && e.Name != e.Key {
+			title = fmt.Sprintf("%s (%s)", e.Key, e.Name)
 		}
-
-		// 显示元数据信息
-		emoji := skill.Metadata.OpenClaw.Emoji
-		if emoji != "" {
-			fmt.Printf("   Icon: %s\n", emoji)
+		fmt.Printf("📦 %s\n", title)
+		if e.Malformed() {
+			fmt.Printf("   ⚠️  Malformed manifest: %s\n", e.Error)
+		} else if e.Description != "" {
+			fmt.Printf("   %s\n", e.Description)
 		}
 
-		requires := skill.Metadata.OpenClaw.Requires
-		if len(requires.Bins) > 0 {
-			fmt.Printf("   Requires: %v\n", requires.Bins)
-		}
-		if len(requires.AnyBins) > 0 {
-			fmt.Printf("   Requires (any): %v\n", requires.AnyBins)
+		enabled := "yes"
+		if !e.Enabled {
+			enabled = "no"
 		}
-		if len(requires.Env) > 0 {
-			fmt.Printf("   Env: %v\n", requires.Env)
-		}
-		if len(requires.OS) > 0 {
-			fmt.Printf("   OS: %v\n", requires.OS)
+		apiKey := "not set"
+		if e.APIKeySet {
+			apiKey = e.APIKey
 		}
+		fmt.Printf("   Enabled: %s   API key: %s\n", enabled, apiKey)
 
-		// 详细模式：显示 Prompt 内容
+		// 详细模式：显示路径与 Prompt 内容
 		if skillsListVerbose {
-			fmt.Printf("\n   --- Content ---\n")
-			content := strings.TrimSpace(skill.Content)
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				fmt.Printf("   %s\n", line)
+			fmt.Printf("   Path: %s\n", e.Path)
+			if content := strings.TrimSpace(e.Content); content != "" {
+				fmt.Printf("\n   --- Content ---\n")
+				for _, line := range strings.Split(content, "\n") {
+					fmt.Printf("   %s\n", line)
+				}
 			}
 		}
 
@@ -210,6 +227,15 @@ func runSkillsList(cmd *cobra.Command, args []string) {
 	}
 }
 
+// skillListEntry goclaw skills list 的一行：清单信息 + 覆盖配置（API key 已打码）
+type skillListEntry struct {
+	skills.InstalledSkill
+	Enabled   bool   `json:"enabled"`
+	APIKeySet bool   `json:"apiKeySet"`
+	APIKey    string `json:"apiKey,omitempty"`
+	Broken    bool   `json:"malformed,omitempty"`
+}
+
 func runSkillsValidate(cmd *cobra.Command, args []string) {
 	skillName := args[0]
 
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/cli/commands"
 	"github.com/smallnest/goclaw/config"
 	"github.com/smallnest/goclaw/gateway"
 	"github.com/spf13/cobra"
@@ -248,7 +249,7 @@ func callGatewayRPC(cfg *config.Config, method string, params map[string]interfa
 		port = 28789
 	}
 
-	url := fmt.Sprintf("http://%s:%d/rpc", host, port)
+	url := fmt.Sprintf("%s://%s:%d/rpc", cfg.Gateway.HTTPScheme(), host, port)
 
 	// Create JSON-RPC request
 	rpcRequest := map[string]interface{}{
@@ -274,9 +275,7 @@ func callGatewayRPC(cfg *config.Config, method string, params map[string]interfa
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := commands.GatewayHTTPClient(&cfg.Gateway, host, 10*time.Second)
 
 	resp, err := client.Do(req)
 	if err != nil {
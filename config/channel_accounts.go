@@ -0,0 +1,49 @@
+package config
+
+import "sort"
+
+// ChannelAccountInfo 通道下已配置的一个账号（不含凭证），供 channels.status 展示
+type ChannelAccountInfo struct {
+	ID      string `json:"accountId"`
+	Name    string `json:"name,omitempty"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ChannelAccounts 返回各通道配置的账号，按账号 ID 排序；
+// 未配置 accounts 但启用了通道（旧的单账号格式）时返回一个 ID 为 "default" 的账号
+func (c *ChannelsConfig) ChannelAccounts() map[string][]ChannelAccountInfo {
+	out := make(map[string][]ChannelAccountInfo)
+	add := func(channel string, enabled bool, accounts map[string]ChannelAccountConfig) {
+		if len(accounts) == 0 {
+			if enabled {
+				out[channel] = []ChannelAccountInfo{{ID: "default", Enabled: true}}
+			}
+			return
+		}
+		list := make([]ChannelAccountInfo, 0, len(accounts))
+		for id, acc := range accounts {
+			// 通道整体关闭时其下账号均不会启动
+			list = append(list, ChannelAccountInfo{ID: id, Name: acc.Name, Enabled: enabled && acc.Enabled})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		out[channel] = list
+	}
+	add("telegram", c.Telegram.Enabled, c.Telegram.Accounts)
+	add("whatsapp", c.WhatsApp.Enabled, c.WhatsApp.Accounts)
+	add("feishu", c.Feishu.Enabled, c.Feishu.Accounts)
+	add("qq", c.QQ.Enabled, c.QQ.Accounts)
+	add("wework", c.WeWork.Enabled, c.WeWork.Accounts)
+	add("dingtalk", c.DingTalk.Enabled, c.DingTalk.Accounts)
+	add("infoflow", c.Infoflow.Enabled, c.Infoflow.Accounts)
+	return out
+}
+
+// DefaultChannelAccountID 返回第一个启用的账号 ID（账号已按 ID 排序），没有启用的账号时返回空字符串
+func DefaultChannelAccountID(accounts []ChannelAccountInfo) string {
+	for _, acc := range accounts {
+		if acc.Enabled {
+			return acc.ID
+		}
+	}
+	return ""
+}
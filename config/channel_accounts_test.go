@@ -0,0 +1,37 @@
+package config
+
+import "testing"
+
+func TestChannelAccounts(t *testing.T) {
+	c := &ChannelsConfig{
+		Telegram: TelegramChannelConfig{
+			Enabled: true,
+			Accounts: map[string]ChannelAccountConfig{
+				"support": {Enabled: true, Name: "Support Bot"},
+				"alerts":  {Enabled: false, Name: "Alerts Bot"},
+				"sales":   {Enabled: true},
+			},
+		},
+		Feishu:   FeishuChannelConfig{Enabled: true},
+		WhatsApp: WhatsAppChannelConfig{Enabled: false, Accounts: map[string]ChannelAccountConfig{"a": {Enabled: true}}},
+	}
+	got := c.ChannelAccounts()
+
+	tg := got["telegram"]
+	if len(tg) != 3 || tg[0].ID != "alerts" || tg[1].ID != "sales" || tg[2].ID != "support" || tg[2].Name != "Support Bot" {
+		t.Fatalf("telegram accounts = %+v", tg)
+	}
+	if id := DefaultChannelAccountID(tg); id != "sales" {
+		t.Errorf("DefaultChannelAccountID(telegram) = %q, want sales", id)
+	}
+	if fs := got["feishu"]; len(fs) != 1 || fs[0].ID != "default" || !fs[0].Enabled {
+		t.Errorf("feishu legacy account = %+v", fs)
+	}
+	wa := got["whatsapp"]
+	if len(wa) != 1 || wa[0].Enabled || DefaultChannelAccountID(wa) != "" {
+		t.Errorf("disabled channel account = %+v", wa)
+	}
+	if _, ok := got["qq"]; ok {
+		t.Error("unconfigured channel should be omitted")
+	}
+}
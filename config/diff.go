@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/smallnest/goclaw/internal/redact"
+)
+
+// ConfigDiffEntry 一项配置差异：path 为点路径（数组用下标，如 agents.list.0.model，与 config.get 的 key 相同），
+// 新增或删除的字段 old / new 为 nil
+type ConfigDiffEntry struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// DiffConfigs 按 JSON 结构逐字段比较两份配置，结果按遍历顺序（对象键有序、数组按下标）；任一为 nil 时视为空配置
+func DiffConfigs(oldCfg, newCfg *Config) ([]ConfigDiffEntry, error) {
+	oldTree, err := configTree(oldCfg)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := configTree(newCfg)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ConfigDiffEntry
+	diffConfigTree("", oldTree, newTree, &entries)
+	return entries, nil
+}
+
+// configTree 将配置转为 JSON 通用结构（map / slice / 标量）
+func configTree(cfg *Config) (interface{}, error) {
+	if cfg == nil {
+		return map[string]interface{}{}, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// diffConfigTree 递归比较，对象按键、数组按下标展开，其余类型整体比较
+func diffConfigTree(path string, a, b interface{}, entries *[]ConfigDiffEntry) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(av)+len(bv))
+			for k := range av {
+				keys = append(keys, k)
+			}
+			for k := range bv {
+				if _, seen := av[k]; !seen {
+					keys = append(keys, k)
+				}
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				diffConfigTree(join(k), av[k], bv[k], entries)
+			}
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			for i := 0; i < max(len(av), len(bv)); i++ {
+				var x, y interface{}
+				if i < len(av) {
+					x = av[i]
+				}
+				if i < len(bv) {
+					y = bv[i]
+				}
+				diffConfigTree(join(strconv.Itoa(i)), x, y, entries)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*entries = append(*entries, ConfigDiffEntry{Path: path, Old: a, New: b})
+	}
+}
+
+// RedactDiffEntries 返回脱敏后的差异副本：密钥类字段（api_key、token、secret 等）的非空取值替换为 redact.Placeholder，
+// 整体新增或删除的子树同样递归处理。用于写入变更历史或对外返回
+func RedactDiffEntries(entries []ConfigDiffEntry) []ConfigDiffEntry {
+	out := make([]ConfigDiffEntry, len(entries))
+	for i, e := range entries {
+		key := e.Path[strings.LastIndex(e.Path, ".")+1:]
+		if redact.IsSensitiveKey(key) {
+			out[i] = ConfigDiffEntry{Path: e.Path, Old: redact.Value(e.Old), New: redact.Value(e.New)}
+			continue
+		}
+		out[i] = ConfigDiffEntry{Path: e.Path, Old: redact.Tree(e.Old), New: redact.Tree(e.New)}
+	}
+	return out
+}
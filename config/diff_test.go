@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/goclaw/internal/redact"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	oldCfg := &Config{}
+	oldCfg.Gateway.Port = 18789
+	oldCfg.Agents.Defaults.Model = "gpt-4o"
+	oldCfg.Agents.List = []AgentConfig{{ID: "main", Model: "gpt-4o"}}
+
+	newCfg := &Config{}
+	newCfg.Gateway.Port = 18789
+	newCfg.Agents.Defaults.Model = "claude-3-5-sonnet"
+	newCfg.Agents.List = []AgentConfig{{ID: "main", Model: "gpt-4o-mini"}, {ID: "coder"}}
+
+	entries, err := DiffConfigs(oldCfg, newCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]ConfigDiffEntry)
+	for _, e := range entries {
+		got[e.Path] = e
+	}
+	if e, ok := got["agents.defaults.model"]; !ok || e.Old != "gpt-4o" || e.New != "claude-3-5-sonnet" {
+		t.Errorf("agents.defaults.model diff = %+v", e)
+	}
+	if e, ok := got["agents.list.0.model"]; !ok || e.Old != "gpt-4o" || e.New != "gpt-4o-mini" {
+		t.Errorf("agents.list.0.model diff = %+v", e)
+	}
+	if e, ok := got["agents.list.1"]; !ok || e.Old != nil || e.New == nil {
+		t.Errorf("added agent should be reported as a whole: %+v", e)
+	}
+	if _, ok := got["gateway.port"]; ok {
+		t.Error("unchanged field reported")
+	}
+
+	if same, _ := DiffConfigs(oldCfg, oldCfg); len(same) != 0 {
+		t.Errorf("identical configs produced %d entries", len(same))
+	}
+}
+
+func TestHistoryRedactsSecretChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := NewConfigHistory(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldCfg := &Config{}
+	oldCfg.Providers.OpenAI.APIKey = "sk-old-secret"
+	newCfg := &Config{}
+	newCfg.Providers.OpenAI.APIKey = "sk-new-secret"
+	newCfg.Agents.List = []AgentConfig{{ID: "main"}}
+	if err := h.Record(oldCfg, newCfg, true, nil, "auto"); err != nil {
+		t.Fatal(err)
+	}
+
+	change := h.GetLatest().Changes["providers.openai.api_key"].(map[string]interface{})
+	if change["old"] != redact.Placeholder || change["new"] != redact.Placeholder {
+		t.Errorf("api_key change = %v, want redacted", change)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var persisted []struct {
+		Changes map[string]interface{} `json:"changes"`
+	}
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := json.Marshal(persisted[0].Changes); strings.Contains(string(raw), "secret") {
+		t.Errorf("persisted changes leak the key: %s", raw)
+	}
+
+	// 整体新增的子树中的密钥字段同样脱敏，非密钥字段保持原值
+	entries := RedactDiffEntries([]ConfigDiffEntry{{
+		Path: "channels.telegram",
+		New:  map[string]interface{}{"token": "123:abc", "enabled": true, "max_tokens": 10.0},
+	}})
+	sub := entries[0].New.(map[string]interface{})
+	if sub["token"] != redact.Placeholder || sub["enabled"] != true || sub["max_tokens"] != 10.0 {
+		t.Errorf("redacted subtree = %v", sub)
+	}
+}
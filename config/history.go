@@ -81,7 +81,7 @@ func (h *ConfigHistory) Record(oldCfg, newCfg *Config, success bool, err error,
 	return h.save()
 }
 
-// detectChanges 检测配置变更
+// detectChanges 检测配置变更：路径 -> {old, new}（与 config.diff 使用同一比较逻辑），密钥类字段脱敏后记录
 func (h *ConfigHistory) detectChanges(oldCfg, newCfg *Config) map[string]interface{} {
 	changes := make(map[string]interface{})
 
@@ -89,48 +89,15 @@ func (h *ConfigHistory) detectChanges(oldCfg, newCfg *Config) map[string]interfa
 		return changes
 	}
 
-	// 检测 Gateway 配置变更
-	if oldCfg.Gateway.Port != newCfg.Gateway.Port {
-		changes["gateway.port"] = map[string]interface{}{
-			"old": oldCfg.Gateway.Port,
-			"new": newCfg.Gateway.Port,
-		}
-	}
-
-	if oldCfg.Gateway.Host != newCfg.Gateway.Host {
-		changes["gateway.host"] = map[string]interface{}{
-			"old": oldCfg.Gateway.Host,
-			"new": newCfg.Gateway.Host,
-		}
-	}
-
-	// 检测 WebSocket 配置变更
-	if oldCfg.Gateway.WebSocket.Port != newCfg.Gateway.WebSocket.Port {
-		changes["gateway.websocket.port"] = map[string]interface{}{
-			"old": oldCfg.Gateway.WebSocket.Port,
-			"new": newCfg.Gateway.WebSocket.Port,
-		}
-	}
-
-	// 检测 Agent 配置变更
-	if oldCfg.Agents.Defaults.Model != newCfg.Agents.Defaults.Model {
-		changes["agents.defaults.model"] = map[string]interface{}{
-			"old": oldCfg.Agents.Defaults.Model,
-			"new": newCfg.Agents.Defaults.Model,
-		}
-	}
-
-	if oldCfg.Agents.Defaults.Temperature != newCfg.Agents.Defaults.Temperature {
-		changes["agents.defaults.temperature"] = map[string]interface{}{
-			"old": oldCfg.Agents.Defaults.Temperature,
-			"new": newCfg.Agents.Defaults.Temperature,
-		}
+	entries, err := DiffConfigs(oldCfg, newCfg)
+	if err != nil {
+		logger.Warn("Failed to diff config", zap.Error(err))
+		return changes
 	}
-
-	if oldCfg.Agents.Defaults.MaxTokens != newCfg.Agents.Defaults.MaxTokens {
-		changes["agents.defaults.max_tokens"] = map[string]interface{}{
-			"old": oldCfg.Agents.Defaults.MaxTokens,
-			"new": newCfg.Agents.Defaults.MaxTokens,
+	for _, e := range RedactDiffEntries(entries) {
+		changes[e.Path] = map[string]interface{}{
+			"old": e.Old,
+			"new": e.New,
 		}
 	}
 
@@ -3,10 +3,14 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -93,8 +97,11 @@ func setDefaults(v *viper.Viper) {
 	// Gateway 默认配置
 	v.SetDefault("gateway.host", "localhost")
 	v.SetDefault("gateway.port", 28789)
+	v.SetDefault("gateway.min_free_disk_mb", 500)
 	v.SetDefault("gateway.read_timeout", 30)
 	v.SetDefault("gateway.write_timeout", 30)
+	v.SetDefault("gateway.pprof.enabled", false)
+	v.SetDefault("gateway.metrics.enabled", false)
 
 	// 工具默认配置
 	v.SetDefault("tools.shell.enabled", true)
@@ -304,98 +311,197 @@ func GetWorkspacePath(cfg *Config) (string, error) {
 	return filepath.Join(home, ".goclaw", "workspace"), nil
 }
 
-// Validate 验证配置
+// Validate 验证配置；存在 error 级问题时返回 *ValidationError（包含全部问题，而非第一个）
 func Validate(cfg *Config) error {
-	if err := validateAgents(cfg); err != nil {
-		return fmt.Errorf("agents config invalid: %w", err)
+	var errs []ValidationIssue
+	for _, issue := range ValidateIssues(cfg) {
+		if issue.Severity == IssueSeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
 	}
+	return &ValidationError{Issues: errs}
+}
+
+// ValidateIssues 校验配置并返回全部字段级问题（按检查顺序），供 config.validate 等展示
+func ValidateIssues(cfg *Config) []ValidationIssue {
+	v := &validationIssues{}
+	validateAgents(v, cfg)
+	validateProviders(v, cfg)
+	validateChannels(v, cfg)
+	validateTools(v, cfg)
+	validateGateway(v, cfg)
+	validateSession(v, cfg)
+	return v.list
+}
 
-	if err := validateProviders(cfg); err != nil {
-		return fmt.Errorf("providers config invalid: %w", err)
+// validateSession 验证会话配置
+func validateSession(v *validationIssues, cfg *Config) {
+	if cfg.Session.MediaInlineMaxBytes < 0 {
+		v.errorf("session.media_inline_max_bytes", "must not be negative")
+	}
+	if r := cfg.Session.RecoverOnFormatError; r != nil {
+		switch strings.ToLower(strings.TrimSpace(r.Mode)) {
+		case "", "repair", "delete", "none":
+		default:
+			v.errorf("session.recover_on_format_error.mode", "must be repair, delete or none")
+		}
+	}
+	if w := cfg.Session.WriteAhead; w != nil {
+		if w.EveryDeltas < 0 {
+			v.errorf("session.write_ahead.every_deltas", "must not be negative")
+		}
+		if w.IntervalSeconds < 0 {
+			v.errorf("session.write_ahead.interval_seconds", "must not be negative")
+		}
+	}
+	if cfg.Session.MaxMessages < 0 {
+		v.errorf("session.max_messages", "must not be negative")
 	}
+	if cfg.Session.MaxBytes < 0 {
+		v.errorf("session.max_bytes", "must not be negative")
+	}
+	if cfg.Session.AutoTitleAfterTurns < 0 {
+		v.errorf("session.auto_title_after_turns", "must not be negative")
+	}
+	if r := cfg.Session.Reset; r != nil && r.Timezone != "" {
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			v.errorf("session.reset.timezone", "invalid timezone %q: %v", r.Timezone, err)
+		}
+	}
+	if r := cfg.Session.Reset; r != nil && r.SweepIntervalSeconds < 0 {
+		v.errorf("session.reset.sweep_interval_seconds", "must not be negative")
+	}
+	for _, channel := range slices.Sorted(maps.Keys(cfg.Session.ResetByChannel)) {
+		if tz := cfg.Session.ResetByChannel[channel].Timezone; tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				v.errorf("session.reset_by_channel."+channel+".timezone", "invalid timezone %q: %v", tz, err)
+			}
+		}
+	}
+}
 
-	if err := validateChannels(cfg); err != nil {
-		return fmt.Errorf("channels config invalid: %w", err)
+// validateAgents 验证 Agent 配置
+func validateAgents(v *validationIssues, cfg *Config) {
+	d := cfg.Agents.Defaults
+	if d.Model == "" {
+		v.errorf("agents.defaults.model", "model cannot be empty")
 	}
 
-	if err := validateTools(cfg); err != nil {
-		return fmt.Errorf("tools config invalid: %w", err)
+	if d.MaxIterations <= 0 {
+		v.errorf("agents.defaults.max_iterations", "must be positive")
 	}
 
-	if err := validateGateway(cfg); err != nil {
-		return fmt.Errorf("gateway config invalid: %w", err)
+	if d.Temperature < 0 || d.Temperature > 2 {
+		v.errorf("agents.defaults.temperature", "must be between 0 and 2")
 	}
 
-	return nil
-}
+	if d.MaxTokens <= 0 {
+		v.errorf("agents.defaults.max_tokens", "must be positive")
+	}
 
-// validateAgents 验证 Agent 配置
-func validateAgents(cfg *Config) error {
-	if cfg.Agents.Defaults.Model == "" {
-		return fmt.Errorf("model cannot be empty")
+	if t := d.ToolResultTruncation; t != nil && (t.HeadChars < 0 || t.TailChars < 0) {
+		v.errorf("agents.defaults.tool_result_truncation", "head_chars and tail_chars must not be negative")
 	}
 
-	if cfg.Agents.Defaults.MaxIterations <= 0 {
-		return fmt.Errorf("max_iterations must be positive")
+	switch d.ToolErrorPolicy {
+	case "", ToolErrorPolicyContinue, ToolErrorPolicyStop, ToolErrorPolicyStopAfterN:
+	default:
+		v.errorf("agents.defaults.tool_error_policy", "must be one of %s, %s, %s", ToolErrorPolicyContinue, ToolErrorPolicyStop, ToolErrorPolicyStopAfterN)
 	}
 
-	if cfg.Agents.Defaults.Temperature < 0 || cfg.Agents.Defaults.Temperature > 2 {
-		return fmt.Errorf("temperature must be between 0 and 2")
+	if d.ToolErrorMaxConsecutive < 0 {
+		v.errorf("agents.defaults.tool_error_max_consecutive", "must not be negative")
 	}
 
-	if cfg.Agents.Defaults.MaxTokens <= 0 {
-		return fmt.Errorf("max_tokens must be positive")
+	for _, capability := range slices.Sorted(maps.Keys(d.CapabilityModels)) {
+		path := "agents.defaults.capability_models." + capability
+		switch capability {
+		case ModelCapabilityVision, ModelCapabilityTools, ModelCapabilityReasoning:
+		default:
+			v.errorf(path, "unknown capability %q", capability)
+		}
+		if strings.TrimSpace(d.CapabilityModels[capability]) == "" {
+			v.errorf(path, "model cannot be empty")
+		}
 	}
 
-	return nil
-}
+	if r := d.RunRetry; r != nil && (r.MaxAttempts < 0 || r.BackoffMs < 0 || r.MaxBackoffMs < 0 || r.BackoffFactor < 0) {
+		v.errorf("agents.defaults.run_retry", "values must not be negative")
+	}
 
-// validateProviders 验证 LLM 提供商配置
-func validateProviders(cfg *Config) error {
-	// 至少需要一个提供商配置了 API 密钥
-	hasProvider := false
+	if d.SessionSendMaxDepth < 0 {
+		v.errorf("agents.defaults.session_send_max_depth", "must not be negative")
+	}
 
-	if cfg.Providers.OpenRouter.APIKey != "" {
-		hasProvider = true
-		if err := validateAPIKey(cfg.Providers.OpenRouter.APIKey); err != nil {
-			return fmt.Errorf("openrouter: %w", err)
-		}
+	if s := d.Subagents; s != nil && s.AnnounceBatchMs < 0 {
+		v.errorf("agents.defaults.subagents.announce_batch_ms", "must not be negative")
 	}
 
-	if cfg.Providers.OpenAI.APIKey != "" {
-		hasProvider = true
-		if err := validateAPIKey(cfg.Providers.OpenAI.APIKey); err != nil {
-			return fmt.Errorf("openai: %w", err)
-		}
+	if _, err := d.UserLocation(); err != nil {
+		v.errorf("agents.defaults.user_timezone", "%v", err)
 	}
 
-	if cfg.Providers.Anthropic.APIKey != "" {
-		hasProvider = true
-		if err := validateAPIKey(cfg.Providers.Anthropic.APIKey); err != nil {
-			return fmt.Errorf("anthropic: %w", err)
+	for i, a := range cfg.Agents.List {
+		if a.MaxConcurrentRuns < 0 {
+			v.errorf(fmt.Sprintf("agents.list.%d.max_concurrent_runs", i), "agent %s: must not be negative", a.ID)
+		}
+		if err := ValidateSystemPrompt(a.SystemPrompt); err != nil {
+			v.errorf(fmt.Sprintf("agents.list.%d.system_prompt", i), "agent %s: invalid template: %v", a.ID, err)
 		}
 	}
+}
 
-	if cfg.Providers.Moonshot.APIKey != "" {
+// validateProviders 验证 LLM 提供商配置
+func validateProviders(v *validationIssues, cfg *Config) {
+	// 至少需要一个提供商配置了 API 密钥
+	hasProvider := false
+	for _, p := range []struct {
+		path, key string
+	}{
+		{"providers.openrouter", cfg.Providers.OpenRouter.APIKey},
+		{"providers.openai", cfg.Providers.OpenAI.APIKey},
+		{"providers.anthropic", cfg.Providers.Anthropic.APIKey},
+		{"providers.moonshot", cfg.Providers.Moonshot.APIKey},
+		{"providers.gemini", cfg.Providers.Gemini.APIKey},
+	} {
+		if p.key == "" {
+			continue
+		}
 		hasProvider = true
-		if err := validateAPIKey(cfg.Providers.Moonshot.APIKey); err != nil {
-			return fmt.Errorf("moonshot (kimi): %w", err)
+		if err := validateAPIKey(p.key); err != nil {
+			v.errorf(p.path+".api_key", "%v", err)
 		}
 	}
 
 	if !hasProvider {
-		return fmt.Errorf("at least one provider must be configured with an API key")
+		v.errorf("providers", "at least one provider must be configured with an API key")
 	}
 
-	return nil
+	if err := validateHeaders(cfg.Providers.OpenRouter.Headers); err != nil {
+		v.errorf("providers.openrouter.headers", "%v", err)
+	}
+	if err := validateHeaders(cfg.Providers.OpenAI.Headers); err != nil {
+		v.errorf("providers.openai.headers", "%v", err)
+	}
+	for i, p := range cfg.Providers.Profiles {
+		if err := validateHeaders(p.Headers); err != nil {
+			v.errorf(fmt.Sprintf("providers.profiles.%d.headers", i), "profile %s: %v", p.Name, err)
+		}
+		if p.MaxConcurrent < 0 {
+			v.errorf(fmt.Sprintf("providers.profiles.%d.max_concurrent", i), "profile %s: must not be negative", p.Name)
+		}
+	}
 }
 
 // validateChannels 验证通道配置
-func validateChannels(cfg *Config) error {
+func validateChannels(v *validationIssues, cfg *Config) {
 	// Telegram
 	if cfg.Channels.Telegram.Enabled {
 		if cfg.Channels.Telegram.Token == "" {
-			return fmt.Errorf("telegram token is required when enabled")
+			v.errorf("channels.telegram.token", "telegram token is required when enabled")
 		}
 		// Telegram Bot Token format: <bot_id>:<api_key>
 		// Example: 123456789:ABCDEF1234ghIkl-zyx57W2v1u123ew11
@@ -405,65 +511,94 @@ func validateChannels(cfg *Config) error {
 	// WhatsApp
 	if cfg.Channels.WhatsApp.Enabled {
 		if cfg.Channels.WhatsApp.BridgeURL == "" {
-			return fmt.Errorf("whatsapp bridge_url is required when enabled")
-		}
-		if !strings.HasPrefix(cfg.Channels.WhatsApp.BridgeURL, "http") {
-			return fmt.Errorf("whatsapp bridge_url must be a valid URL")
+			v.errorf("channels.whatsapp.bridge_url", "whatsapp bridge_url is required when enabled")
+		} else if !strings.HasPrefix(cfg.Channels.WhatsApp.BridgeURL, "http") {
+			v.errorf("channels.whatsapp.bridge_url", "whatsapp bridge_url must be a valid URL")
 		}
 	}
 
 	// Feishu
 	if cfg.Channels.Feishu.Enabled {
 		if cfg.Channels.Feishu.AppID == "" {
-			return fmt.Errorf("feishu app_id is required when enabled")
+			v.errorf("channels.feishu.app_id", "feishu app_id is required when enabled")
 		}
 		if cfg.Channels.Feishu.AppSecret == "" {
-			return fmt.Errorf("feishu app_secret is required when enabled")
+			v.errorf("channels.feishu.app_secret", "feishu app_secret is required when enabled")
 		}
 		mode, err := normalizeFeishuEventMode(cfg.Channels.Feishu.EventMode)
 		if err != nil {
-			return err
-		}
-		cfg.Channels.Feishu.EventMode = mode
-		if mode == "webhook" && cfg.Channels.Feishu.VerificationToken == "" {
-			return fmt.Errorf("feishu verification_token is required when event_mode=webhook")
+			v.errorf("channels.feishu.event_mode", "%v", err)
+		} else {
+			cfg.Channels.Feishu.EventMode = mode
+			if mode == "webhook" && cfg.Channels.Feishu.VerificationToken == "" {
+				v.errorf("channels.feishu.verification_token", "feishu verification_token is required when event_mode=webhook")
+			}
 		}
 		if cfg.Channels.Feishu.WebhookPort < 0 || cfg.Channels.Feishu.WebhookPort > 65535 {
-			return fmt.Errorf("feishu webhook_port must be between 0 and 65535")
+			v.errorf("channels.feishu.webhook_port", "feishu webhook_port must be between 0 and 65535")
 		}
 	}
 
 	// QQ
 	if cfg.Channels.QQ.Enabled {
 		if cfg.Channels.QQ.AppID == "" {
-			return fmt.Errorf("qq app_id is required when enabled")
+			v.errorf("channels.qq.app_id", "qq app_id is required when enabled")
 		}
 		if cfg.Channels.QQ.AppSecret == "" {
-			return fmt.Errorf("qq app_secret is required when enabled")
+			v.errorf("channels.qq.app_secret", "qq app_secret is required when enabled")
 		}
 	}
 
 	// WeWork (企业微信)
 	if cfg.Channels.WeWork.Enabled {
 		if cfg.Channels.WeWork.CorpID == "" {
-			return fmt.Errorf("wework corp_id is required when enabled")
+			v.errorf("channels.wework.corp_id", "wework corp_id is required when enabled")
 		}
 		if cfg.Channels.WeWork.Secret == "" {
-			return fmt.Errorf("wework secret is required when enabled")
+			v.errorf("channels.wework.secret", "wework secret is required when enabled")
 		}
 		if cfg.Channels.WeWork.AgentID == "" {
-			return fmt.Errorf("wework agent_id is required when enabled")
+			v.errorf("channels.wework.agent_id", "wework agent_id is required when enabled")
 		}
 		if cfg.Channels.WeWork.WebhookPort < 0 || cfg.Channels.WeWork.WebhookPort > 65535 {
-			return fmt.Errorf("wework webhook_port must be between 0 and 65535")
+			v.errorf("channels.wework.webhook_port", "wework webhook_port must be between 0 and 65535")
 		}
 	}
 
-	return nil
+	// 入站命令
+	if strings.ContainsAny(cfg.Channels.CommandPrefix, " \t\r\n") {
+		v.errorf("channels.command_prefix", "must not contain whitespace")
+	}
+	for i, name := range cfg.Channels.Commands {
+		if !slices.Contains(InboundCommandNames, strings.ToLower(strings.TrimSpace(name))) {
+			v.errorf(fmt.Sprintf("channels.commands.%d", i), "unknown command %q (supported: %s)", name, strings.Join(InboundCommandNames, ", "))
+		}
+	}
+
+	// 静默时段
+	for _, channel := range []string{"telegram", "whatsapp", "feishu", "dingtalk", "qq", "wework", "infoflow"} {
+		validateQuietHours(v, channel, cfg.Channels.QuietHours(channel))
+	}
+
+	if cfg.Channels.FetchMediaMaxBytes < 0 {
+		v.errorf("channels.fetch_media_max_bytes", "must not be negative")
+	}
+	if cfg.Channels.FetchMediaTimeoutMs < 0 {
+		v.errorf("channels.fetch_media_timeout_ms", "must not be negative")
+	}
+	if cfg.Channels.DeliveryMaxAttempts < 0 {
+		v.errorf("channels.delivery_max_attempts", "must not be negative")
+	}
+	if cfg.Channels.DeliveryRetryBaseMs < 0 {
+		v.errorf("channels.delivery_retry_base_ms", "must not be negative")
+	}
+	if cfg.Channels.DeliveryRetryMaxMs < 0 {
+		v.errorf("channels.delivery_retry_max_ms", "must not be negative")
+	}
 }
 
 // validateTools 验证工具配置
-func validateTools(cfg *Config) error {
+func validateTools(v *validationIssues, cfg *Config) {
 	// Shell 工具配置验证
 	if cfg.Tools.Shell.Enabled {
 		// 检查危险命令是否在拒绝列表中
@@ -477,51 +612,91 @@ func validateTools(cfg *Config) error {
 				}
 			}
 			if !found {
-				return fmt.Errorf("shell tool: dangerous command '%s' should be in denied_cmds", dangerous)
+				v.errorf("tools.shell.denied_cmds", "dangerous command '%s' should be in denied_cmds", dangerous)
 			}
 		}
 
 		if cfg.Tools.Shell.Timeout <= 0 {
-			return fmt.Errorf("shell timeout must be positive")
+			v.errorf("tools.shell.timeout", "shell timeout must be positive")
 		}
 	}
 
 	// Web 工具配置验证
 	if cfg.Tools.Web.SearchAPIKey != "" {
 		if cfg.Tools.Web.SearchEngine == "" {
-			return fmt.Errorf("web search_engine is required when search_api_key is set")
+			v.errorf("tools.web.search_engine", "web search_engine is required when search_api_key is set")
 		}
 	}
 
 	if cfg.Tools.Web.Timeout <= 0 {
-		return fmt.Errorf("web timeout must be positive")
+		v.errorf("tools.web.timeout", "web timeout must be positive")
+	}
+
+	if cfg.Tools.TimeoutSeconds < 0 {
+		v.errorf("tools.timeout_seconds", "must not be negative")
 	}
 
 	// 浏览器工具配置验证
 	if cfg.Tools.Browser.Enabled {
 		if cfg.Tools.Browser.Timeout <= 0 {
-			return fmt.Errorf("browser timeout must be positive")
+			v.errorf("tools.browser.timeout", "browser timeout must be positive")
 		}
 	}
-
-	return nil
 }
 
 // validateGateway 验证网关配置
-func validateGateway(cfg *Config) error {
+func validateGateway(v *validationIssues, cfg *Config) {
 	if cfg.Gateway.Port <= 0 || cfg.Gateway.Port > 65535 {
-		return fmt.Errorf("gateway port must be between 1 and 65535")
+		v.errorf("gateway.port", "gateway port must be between 1 and 65535")
 	}
 
 	if cfg.Gateway.ReadTimeout <= 0 {
-		return fmt.Errorf("gateway read_timeout must be positive")
+		v.errorf("gateway.read_timeout", "gateway read_timeout must be positive")
 	}
 
 	if cfg.Gateway.WriteTimeout <= 0 {
-		return fmt.Errorf("gateway write_timeout must be positive")
+		v.errorf("gateway.write_timeout", "gateway write_timeout must be positive")
 	}
 
-	return nil
+	if cfg.Gateway.Pprof.Enabled && strings.TrimSpace(cfg.Gateway.WebSocket.AuthToken) == "" {
+		v.errorf("gateway.websocket.auth_token", "gateway pprof requires websocket auth_token to be set")
+	}
+
+	if locale := strings.ToLower(strings.TrimSpace(cfg.Gateway.Locale)); locale != "" && !strings.HasPrefix(locale, "en") && !strings.HasPrefix(locale, "zh") {
+		v.errorf("gateway.locale", "gateway locale must be en or zh, got %q", cfg.Gateway.Locale)
+	}
+
+	if cfg.Gateway.MinFreeDiskMB < 0 {
+		v.errorf("gateway.min_free_disk_mb", "gateway min_free_disk_mb must not be negative")
+	}
+
+	if cfg.Gateway.WebSocket.SendQueueSize < 0 {
+		v.errorf("gateway.websocket.send_queue_size", "gateway websocket send_queue_size must not be negative")
+	}
+
+	if ws := cfg.Gateway.WebSocket; ws.EnableTLS {
+		if strings.TrimSpace(ws.CertFile) == "" {
+			v.errorf("gateway.websocket.cert_file", "gateway websocket enable_tls requires cert_file")
+		}
+		if strings.TrimSpace(ws.KeyFile) == "" {
+			v.errorf("gateway.websocket.key_file", "gateway websocket enable_tls requires key_file")
+		}
+	}
+
+	for i, origin := range cfg.Gateway.WebSocket.AllowedOrigins {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			v.errorf(fmt.Sprintf("gateway.websocket.allowed_origins.%d", i), "allowed origin must not be empty")
+		} else if _, err := path.Match(origin, ""); err != nil {
+			v.errorf(fmt.Sprintf("gateway.websocket.allowed_origins.%d", i), "invalid origin pattern %q: %v", origin, err)
+		}
+	}
+
+	if cfg.Gateway.Metrics.Port < 0 || cfg.Gateway.Metrics.Port > 65535 {
+		v.errorf("gateway.metrics.port", "gateway metrics port must be between 0 and 65535")
+	} else if cfg.Gateway.Metrics.Enabled && cfg.Gateway.Metrics.Port != 0 && (cfg.Gateway.Metrics.Port == cfg.Gateway.Port || cfg.Gateway.Metrics.Port == cfg.Gateway.WebSocket.Port) {
+		v.errorf("gateway.metrics.port", "gateway metrics port %d is already used by the gateway; set 0 to serve /metrics on the gateway port", cfg.Gateway.Metrics.Port)
+	}
 }
 
 // validateAPIKey 验证 API 密钥格式
@@ -540,6 +715,19 @@ func validateAPIKey(key string) error {
 	return nil
 }
 
+// validateHeaders 验证自定义请求头：名称为合法 token，值不含换行
+func validateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s value cannot contain line breaks", name)
+		}
+	}
+	return nil
+}
+
 func normalizeFeishuEventMode(mode string) (string, error) {
 	mode = strings.TrimSpace(strings.ToLower(mode))
 	if mode == "" || mode == "webhook" {
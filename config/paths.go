@@ -95,6 +95,11 @@ func GetCompletionsDir(dataDir string) string {
 	return filepath.Join(dataDir, "completions")
 }
 
+// GetUsageDailyPath returns the persisted daily token usage file path
+func GetUsageDailyPath(dataDir string) string {
+	return filepath.Join(dataDir, "data", "usage_daily.json")
+}
+
 // EnsureDataDirs creates all necessary data directories
 func EnsureDataDirs(dataDir string) error {
 	dirs := []string{
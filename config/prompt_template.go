@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SystemPromptVars agents.list[].system_prompt 模板可用的变量，每次运行时解析
+type SystemPromptVars struct {
+	Date         string // 当前日期（YYYY-MM-DD，按 UserTimezone）
+	AgentName    string // Agent 显示名称
+	Workspace    string // Agent 工作区路径
+	UserTimezone string // 用户时区（agents.defaults.user_timezone，未配置时为本机时区）
+}
+
+// escapedTemplateOpen 模板中表示字面量 "{{" 的写法
+const escapedTemplateOpen = `\{{`
+
+// parseSystemPrompt 解析 system_prompt 模板；\{{ 输出字面量 {{（也可用 {{"{{"}}）
+func parseSystemPrompt(text string) (*template.Template, error) {
+	text = strings.ReplaceAll(text, escapedTemplateOpen, `{{"{{"}}`)
+	return template.New("system_prompt").Option("missingkey=error").Parse(text)
+}
+
+// RenderSystemPrompt 展开 system_prompt 中的 {{.Date}}、{{.AgentName}}、{{.Workspace}}、{{.UserTimezone}}；
+// 不含 {{ 的提示词原样返回
+func RenderSystemPrompt(text string, vars SystemPromptVars) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := parseSystemPrompt(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// ValidateSystemPrompt 检查模板语法与变量名（未知变量在加载配置时报错，而不是运行中）
+func ValidateSystemPrompt(text string) error {
+	_, err := RenderSystemPrompt(text, SystemPromptVars{})
+	return err
+}
+
+// UserLocation 返回 agents.defaults.user_timezone 对应的时区，未配置时为本机时区
+func (d *AgentDefaults) UserLocation() (*time.Location, error) {
+	tz := strings.TrimSpace(d.UserTimezone)
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSystemPrompt(t *testing.T) {
+	vars := SystemPromptVars{Date: "2026-10-15", AgentName: "Claw", Workspace: "/work", UserTimezone: "Asia/Shanghai"}
+	tests := []struct {
+		name, text, want string
+	}{
+		{"variables", "You are {{.AgentName}}. Today is {{.Date}} ({{.UserTimezone}}), cwd {{.Workspace}}.",
+			"You are Claw. Today is 2026-10-15 (Asia/Shanghai), cwd /work."},
+		{"verbatim", "Reply in JSON like {\"a\": 1}.", "Reply in JSON like {\"a\": 1}."},
+		{"backslash escape", `Use \{{name}} placeholders for {{.AgentName}}`, "Use {{name}} placeholders for Claw"},
+		{"quoted escape", `Literal {{"{{"}}.Date}}`, "Literal {{.Date}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderSystemPrompt(tt.text, vars)
+			if err != nil {
+				t.Fatalf("RenderSystemPrompt() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderSystemPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsBadSystemPromptTemplates(t *testing.T) {
+	if err := ValidateSystemPrompt("Today is {{.Date}}"); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+	for _, bad := range []string{"Hi {{.Nmae}}", "Hi {{.AgentName"} {
+		if err := ValidateSystemPrompt(bad); err == nil {
+			t.Errorf("ValidateSystemPrompt(%q) = nil, want error", bad)
+		}
+	}
+
+	cfg := &Config{}
+	cfg.Providers.OpenAI.APIKey = "sk-test-key-1234567890"
+	cfg.Gateway.Port = 8080
+	cfg.Gateway.ReadTimeout = 1
+	cfg.Gateway.WriteTimeout = 1
+	cfg.Tools.Web.Timeout = 1
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Agents.Defaults.MaxIterations = 10
+	cfg.Agents.Defaults.MaxTokens = 1000
+	cfg.Agents.Defaults.UserTimezone = "Mars/Olympus"
+	cfg.Agents.List = []AgentConfig{{ID: "main", SystemPrompt: "You are {{.Name}}"}}
+
+	issues := ValidateIssues(cfg)
+	if len(issues) != 2 {
+		t.Fatalf("issues = %+v, want user_timezone and system_prompt", issues)
+	}
+	if issues[0].Path != "agents.defaults.user_timezone" || issues[1].Path != "agents.list.0.system_prompt" {
+		t.Errorf("issue paths = %s, %s", issues[0].Path, issues[1].Path)
+	}
+	if !strings.Contains(issues[1].Message, "Name") {
+		t.Errorf("system_prompt issue should name the bad field: %s", issues[1].Message)
+	}
+}
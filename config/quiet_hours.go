@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 静默时段内入站消息的处理方式
+const (
+	QuietHoursModeReply = "reply" // 不处理消息，配置了 auto_reply 时回复该文案（默认）
+	QuietHoursModeQueue = "queue" // 排队，静默时段结束后按到达顺序处理
+)
+
+// QuietHoursConfig 通道静默时段（channels.<name>.quiet_hours）
+type QuietHoursConfig struct {
+	Enabled   bool     `mapstructure:"enabled" json:"enabled"`
+	Ranges    []string `mapstructure:"ranges" json:"ranges"`         // 时间段 "HH:MM-HH:MM"，结束早于开始表示跨午夜（如 "22:00-07:00"）
+	Timezone  string   `mapstructure:"timezone" json:"timezone"`     // IANA 时区（如 Asia/Shanghai），空表示本机时区
+	Mode      string   `mapstructure:"mode" json:"mode"`             // reply | queue，默认 reply
+	AutoReply string   `mapstructure:"auto_reply" json:"auto_reply"` // 静默时段内收到消息时的自动回复，空表示不回复
+}
+
+// QuietHours 返回指定通道的静默时段配置；未配置或未启用时返回 nil
+func (c *ChannelsConfig) QuietHours(channel string) *QuietHoursConfig {
+	var q *QuietHoursConfig
+	switch channel {
+	case "telegram":
+		q = c.Telegram.QuietHours
+	case "whatsapp":
+		q = c.WhatsApp.QuietHours
+	case "feishu":
+		q = c.Feishu.QuietHours
+	case "dingtalk":
+		q = c.DingTalk.QuietHours
+	case "qq":
+		q = c.QQ.QuietHours
+	case "wework":
+		q = c.WeWork.QuietHours
+	case "infoflow":
+		q = c.Infoflow.QuietHours
+	}
+	if q == nil || !q.Enabled {
+		return nil
+	}
+	return q
+}
+
+// Location 返回静默时段使用的时区
+func (q *QuietHoursConfig) Location() (*time.Location, error) {
+	if strings.TrimSpace(q.Timezone) == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(strings.TrimSpace(q.Timezone))
+}
+
+// ParseQuietRange 解析 "HH:MM-HH:MM"，返回起止时刻（当天的分钟数）
+func ParseQuietRange(r string) (start, end int, err error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(r), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid quiet hours range %q, want HH:MM-HH:MM", r)
+	}
+	if start, err = parseClock(from); err != nil {
+		return 0, 0, fmt.Errorf("invalid quiet hours range %q: %w", r, err)
+	}
+	if end, err = parseClock(to); err != nil {
+		return 0, 0, fmt.Errorf("invalid quiet hours range %q: %w", r, err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("invalid quiet hours range %q: start equals end", r)
+	}
+	return start, end, nil
+}
+
+// parseClock 解析 "HH:MM"（00:00-24:00）为分钟数
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// validateQuietHours 验证通道静默时段配置
+func validateQuietHours(v *validationIssues, channel string, q *QuietHoursConfig) {
+	if q == nil || !q.Enabled {
+		return
+	}
+	path := "channels." + channel + ".quiet_hours"
+	if len(q.Ranges) == 0 {
+		v.errorf(path+".ranges", "ranges is required when enabled")
+	}
+	for i, r := range q.Ranges {
+		if _, _, err := ParseQuietRange(r); err != nil {
+			v.errorf(fmt.Sprintf("%s.ranges.%d", path, i), "%v", err)
+		}
+	}
+	if _, err := q.Location(); err != nil {
+		v.errorf(path+".timezone", "%v", err)
+	}
+	switch q.Mode {
+	case "", QuietHoursModeReply, QuietHoursModeQueue:
+	default:
+		v.errorf(path+".mode", "mode must be reply or queue")
+	}
+}
@@ -28,6 +28,14 @@ type SessionConfig struct {
 	MainKey         string                    `mapstructure:"main_key" json:"main_key"`                  // 主会话键（用于 per-sender 时的规范 key）
 	Reset           *SessionResetConfig      `mapstructure:"reset" json:"reset"`                        // 全局重置策略
 	ResetByChannel  map[string]SessionResetConfig `mapstructure:"reset_by_channel" json:"reset_by_channel"` // 按 channel 覆盖
+	MediaInlineMaxBytes int `mapstructure:"media_inline_max_bytes" json:"media_inline_max_bytes"` // 会话内联媒体 base64 上限（字节），超过则外置到 <store>/media 仅保留 mediaRef；0 表示不外置
+	RecoverOnFormatError *FormatErrorRecoveryConfig `mapstructure:"recover_on_format_error" json:"recover_on_format_error"` // 历史格式错误（tool_call_id 不匹配等）时的恢复方式
+	WriteAhead *WriteAheadConfig `mapstructure:"write_ahead" json:"write_ahead"` // 流式输出过程中周期性落盘未完成的回复，进程崩溃后可在历史中看到
+	MaxMessages int   `mapstructure:"max_messages" json:"max_messages"` // 单个会话落盘的消息数上限，超出时最早的若干轮被摘要或丢弃；0 表示不限制
+	MaxBytes    int64 `mapstructure:"max_bytes" json:"max_bytes"`       // 单个会话文件的字节数上限（近似），超出处理同 max_messages；0 表示不限制
+	AutoTitle           bool   `mapstructure:"auto_title" json:"auto_title"`                         // 新会话首次回复后由模型生成简短标题写入 label（已有 label 的会话与子 agent 会话跳过）
+	AutoTitleModel      string `mapstructure:"auto_title_model" json:"auto_title_model"`             // 生成标题使用的模型（建议便宜的小模型），空表示 provider 默认模型
+	AutoTitleAfterTurns int    `mapstructure:"auto_title_after_turns" json:"auto_title_after_turns"` // 第几轮回复后生成标题，0 表示 1
 }
 
 // SessionResetConfig 会话重置策略
@@ -35,6 +43,22 @@ type SessionResetConfig struct {
 	Mode        string `mapstructure:"mode" json:"mode"`               // daily | idle
 	AtHour      int    `mapstructure:"at_hour" json:"at_hour"`         // daily 时 0-23，默认 4
 	IdleMinutes int    `mapstructure:"idle_minutes" json:"idle_minutes"` // idle 时多少分钟无活动则视为不新鲜
+	NotifyOnReset bool `mapstructure:"notify_on_reset" json:"notify_on_reset"` // 按策略重置时在新会话开头写入一条系统提示，告知用户之前的对话已重置
+	Timezone    string `mapstructure:"timezone" json:"timezone"`       // at_hour 所在时区（IANA 名称，如 Asia/Shanghai），默认服务器本地时区
+	// SweepIntervalSeconds idle 模式下 Gateway 后台巡检空闲会话的间隔（秒），0 为默认 300
+	SweepIntervalSeconds int `mapstructure:"sweep_interval_seconds" json:"sweep_interval_seconds"`
+}
+
+// FormatErrorRecoveryConfig 会话格式错误恢复配置
+type FormatErrorRecoveryConfig struct {
+	Mode string `mapstructure:"mode" json:"mode"` // repair（默认，原地移除孤立 tool 消息后重试）| delete（删除会话后重试）| none
+}
+
+// WriteAheadConfig 流式回复检查点配置：满足任一条件即落盘一次（默认每 20 个增量或 5 秒）
+type WriteAheadConfig struct {
+	Enabled         bool `mapstructure:"enabled" json:"enabled"`
+	EveryDeltas     int  `mapstructure:"every_deltas" json:"every_deltas"`         // 每累计多少个流式增量落盘一次，0 使用默认 20
+	IntervalSeconds int  `mapstructure:"interval_seconds" json:"interval_seconds"` // 距上次落盘超过多少秒即落盘，0 使用默认 5
 }
 
 // WorkspaceConfig Workspace 配置
@@ -51,15 +75,62 @@ type AgentsConfig struct {
 // AgentDefaults Agent 默认配置
 type AgentDefaults struct {
 	Model             string           `mapstructure:"model" json:"model"`
+	FallbackModel     string           `mapstructure:"fallback_model" json:"fallback_model"` // 主模型被 provider 拒绝（不存在/已下线）时改用的模型，空表示不启用
 	MaxIterations     int              `mapstructure:"max_iterations" json:"max_iterations"`
 	Temperature       float64          `mapstructure:"temperature" json:"temperature"`
 	MaxTokens         int              `mapstructure:"max_tokens" json:"max_tokens"`
 	ContextTokens     int              `mapstructure:"context_tokens" json:"context_tokens"`           // 模型上下文窗口 token 数，0 表示用 provider 默认
 	LimitHistoryTurns int              `mapstructure:"limit_history_turns" json:"limit_history_turns"` // 发给 LLM 时最多保留的 user 轮次，0 表示不限制（与 OpenClaw 对齐）
+	SummarizerContextTokens int        `mapstructure:"summarizer_context_tokens" json:"summarizer_context_tokens"` // 压缩摘要模型的上下文窗口，用于切块摘要；0 表示与 context_tokens 相同
+	PostProcessors    []string          `mapstructure:"post_processors" json:"post_processors"` // 最终回复的后处理链，按顺序执行（trim_whitespace、strip_think_tags、link_rewrite）
+	LinkRewrites      map[string]string `mapstructure:"link_rewrites" json:"link_rewrites"`     // link_rewrite 规则：URL 前缀 -> 替换前缀
 	RunTimeoutSeconds         int          `mapstructure:"run_timeout_seconds" json:"run_timeout_seconds"`                   // 单次 Run 最大耗时（秒），0 表示不限制；超时后 ctx 取消，避免模型 API 断开时无限卡住
 	ModelRequestIntervalSeconds int       `mapstructure:"model_request_interval_seconds" json:"model_request_interval_seconds"` // 同一会话内两次调用模型的最小间隔（秒），0 表示不限制；用于缓解 406/限流（OpenClaw 无此配置，为 goclaw 扩展）
 	Retry             *RetryConfig     `mapstructure:"retry" json:"retry"`                             // 重试配置
+	RunRetry          *RunRetryConfig  `mapstructure:"run_retry" json:"run_retry"`                     // 整次 Run 以临时错误结束时的重试，nil 表示不重试
 	Subagents         *SubagentsConfig `mapstructure:"subagents" json:"subagents"`
+	// sessions_send 消息链回到已经过的会话（A→B→A）时允许的最大跳数，超出后拒绝发送以打断循环；0 表示默认 5
+	SessionSendMaxDepth int `mapstructure:"session_send_max_depth" json:"session_send_max_depth"`
+	ToolResultTruncation *ToolResultTruncationConfig `mapstructure:"tool_result_truncation" json:"tool_result_truncation"` // 超长 tool 结果的截断方式（保留首尾）
+	// 工具报错时的处理：continue（默认，错误作为结果交给模型）、stop（首次报错即结束运行）、stop_after_n（同一工具连续 N 次相同错误后结束）
+	ToolErrorPolicy string `mapstructure:"tool_error_policy" json:"tool_error_policy"`
+	// stop_after_n 的 N，0 表示默认 3
+	ToolErrorMaxConsecutive int `mapstructure:"tool_error_max_consecutive" json:"tool_error_max_consecutive"`
+	// 模型能力覆盖（模型名 -> 能力，模型名不区分大小写），未配置的字段使用内置表
+	ModelCapabilities map[string]*ModelCapabilityConfig `mapstructure:"model_capabilities" json:"model_capabilities"`
+	// 主模型缺少某能力时改用的模型（vision / tools / reasoning -> 模型名），未配置则直接提示用户；
+	// 未配置 tools 时，不支持工具的模型只是不发送工具定义
+	CapabilityModels map[string]string `mapstructure:"capability_models" json:"capability_models"`
+	// 用户所在时区（IANA 名称，如 Asia/Shanghai），用于 system_prompt 模板的 {{.Date}} / {{.UserTimezone}}；空表示本机时区
+	UserTimezone string `mapstructure:"user_timezone" json:"user_timezone"`
+}
+
+// ModelCapabilityConfig 单个模型的能力覆盖，nil 表示使用内置表
+type ModelCapabilityConfig struct {
+	Vision    *bool `mapstructure:"vision" json:"vision,omitempty"`
+	Tools     *bool `mapstructure:"tools" json:"tools,omitempty"`
+	Reasoning *bool `mapstructure:"reasoning" json:"reasoning,omitempty"`
+}
+
+// 模型能力名（agents.defaults.capability_models 的键）
+const (
+	ModelCapabilityVision    = "vision"
+	ModelCapabilityTools     = "tools"
+	ModelCapabilityReasoning = "reasoning"
+)
+
+// 工具报错策略（agents.defaults.tool_error_policy）
+const (
+	ToolErrorPolicyContinue   = "continue"
+	ToolErrorPolicyStop       = "stop"
+	ToolErrorPolicyStopAfterN = "stop_after_n"
+)
+
+// ToolResultTruncationConfig tool 结果截断配置：超出上下文预算时保留开头与结尾，中间替换为标记
+type ToolResultTruncationConfig struct {
+	HeadChars int    `mapstructure:"head_chars" json:"head_chars"` // 保留开头字符数，0 表示按预算自动分配（2/3）
+	TailChars int    `mapstructure:"tail_chars" json:"tail_chars"` // 保留结尾字符数，0 表示按预算自动分配（1/3）
+	Marker    string `mapstructure:"marker" json:"marker"`         // 截断标记，{n} 替换为省略字符数；默认 "\n…[{n} chars omitted]…\n"
 }
 
 // RetryConfig 重试配置
@@ -71,6 +142,15 @@ type RetryConfig struct {
 	BackoffFactor float64       `mapstructure:"backoff_factor" json:"backoff_factor"`
 }
 
+// RunRetryConfig 整次 Run 的重试：以网络错误、5xx、超时、限流等临时错误结束时，从运行前的会话状态重新执行；
+// 认证、计费、模型不存在、参数错误等立即失败
+type RunRetryConfig struct {
+	MaxAttempts   int     `mapstructure:"max_attempts" json:"max_attempts"`     // 总尝试次数（含首次），<=1 表示不重试
+	BackoffMs     int     `mapstructure:"backoff_ms" json:"backoff_ms"`         // 首次重试前的等待（毫秒），0 表示 1000
+	MaxBackoffMs  int     `mapstructure:"max_backoff_ms" json:"max_backoff_ms"` // 等待上限（毫秒），0 表示 30000
+	BackoffFactor float64 `mapstructure:"backoff_factor" json:"backoff_factor"` // 指数退避因子，0 表示 2
+}
+
 // SubagentsConfig 分身配置
 type SubagentsConfig struct {
 	MaxConcurrent       int    `mapstructure:"max_concurrent" json:"max_concurrent"`
@@ -78,6 +158,8 @@ type SubagentsConfig struct {
 	Model               string `mapstructure:"model" json:"model"`
 	Thinking            string `mapstructure:"thinking" json:"thinking"`
 	TimeoutSeconds      int    `mapstructure:"timeout_seconds" json:"timeout_seconds"`
+	// 同一父会话的分身宣告串行投递；窗口内完成的多个分身合并为一条宣告（毫秒，0 表示不等待，仅合并投递期间到达的宣告）
+	AnnounceBatchMs int `mapstructure:"announce_batch_ms" json:"announce_batch_ms"`
 }
 
 // AgentSubagentConfig 单 Agent 分身配置
@@ -98,9 +180,12 @@ type AgentConfig struct {
 	Model        string                 `mapstructure:"model" json:"model"`                 // 使用的模型
 	Workspace    string                 `mapstructure:"workspace" json:"workspace"`         // 独立工作区路径
 	Identity     *AgentIdentity         `mapstructure:"identity" json:"identity"`           // Agent 身份配置
-	SystemPrompt string                 `mapstructure:"system_prompt" json:"system_prompt"` // 系统提示词
+	SystemPrompt string                 `mapstructure:"system_prompt" json:"system_prompt"` // 系统提示词，支持 {{.Date}} 等模板变量
 	Metadata     map[string]interface{} `mapstructure:"metadata" json:"metadata"`           // 额外元数据
 	Subagents    *AgentSubagentConfig   `mapstructure:"subagents" json:"subagents"`         // 分身配置
+	Supervisor   bool                   `mapstructure:"supervisor" json:"supervisor"`       // 主管 agent：可通过 agent_activity 查询其他 agent 的活动
+	// 该 Agent 同时执行的 Run 上限（跨会话），超出的排队等待；0 表示不限制
+	MaxConcurrentRuns int `mapstructure:"max_concurrent_runs" json:"max_concurrent_runs"`
 }
 
 // AgentIdentity Agent 身份配置
@@ -130,8 +215,26 @@ type ChannelsConfig struct {
 	QQ       QQChannelConfig       `mapstructure:"qq" json:"qq"`
 	WeWork   WeWorkChannelConfig   `mapstructure:"wework" json:"wework"`
 	Infoflow InfoflowChannelConfig `mapstructure:"infoflow" json:"infoflow"`
+
+	// 入站命令预处理（/new、/model 等），在到达 LLM 前拦截并直接回复
+	CommandPrefix   string   `mapstructure:"command_prefix" json:"command_prefix"`     // 命令前缀，默认 "/"
+	Commands        []string `mapstructure:"commands" json:"commands"`                 // 启用的命令（new/model/clear/help），为空表示全部启用
+	DisableCommands bool     `mapstructure:"disable_commands" json:"disable_commands"` // 关闭入站命令预处理
+
+	// 入站 URL 媒体下载：开启后图片 URL 下载为 base64 再交给模型（适用于只接受内联图片的 provider，如 Gemini）
+	FetchMedia          bool  `mapstructure:"fetch_media" json:"fetch_media"`
+	FetchMediaMaxBytes  int64 `mapstructure:"fetch_media_max_bytes" json:"fetch_media_max_bytes"`   // 单个文件上限，默认 10MB
+	FetchMediaTimeoutMs int   `mapstructure:"fetch_media_timeout_ms" json:"fetch_media_timeout_ms"` // 单次下载超时，默认 15000
+
+	// 出站投递失败重试：按指数退避重试，仍失败的消息进入死信队列（channels.deadletter.list / retry）
+	DeliveryMaxAttempts int `mapstructure:"delivery_max_attempts" json:"delivery_max_attempts"`   // 含首次发送的最大尝试次数，默认 3；1 表示不重试
+	DeliveryRetryBaseMs int `mapstructure:"delivery_retry_base_ms" json:"delivery_retry_base_ms"` // 首次重试前的等待，之后每次翻倍，默认 1000
+	DeliveryRetryMaxMs  int `mapstructure:"delivery_retry_max_ms" json:"delivery_retry_max_ms"`   // 单次等待上限，默认 30000
 }
 
+// InboundCommandNames 支持的入站命令名称（不含前缀）
+var InboundCommandNames = []string{"new", "model", "clear", "help"}
+
 // ChannelAccountConfig 通道账号配置（支持多账号）
 type ChannelAccountConfig struct {
 	Enabled           bool     `mapstructure:"enabled" json:"enabled"`
@@ -164,6 +267,7 @@ type TelegramChannelConfig struct {
 	Enabled    bool     `mapstructure:"enabled" json:"enabled"`
 	Token      string   `mapstructure:"token" json:"token"`
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -173,6 +277,7 @@ type WhatsAppChannelConfig struct {
 	Enabled    bool     `mapstructure:"enabled" json:"enabled"`
 	BridgeURL  string   `mapstructure:"bridge_url" json:"bridge_url"`
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -187,6 +292,7 @@ type FeishuChannelConfig struct {
 	EventMode         string   `mapstructure:"event_mode" json:"event_mode"` // webhook / long_connection
 	WebhookPort       int      `mapstructure:"webhook_port" json:"webhook_port"`
 	AllowedIDs        []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -197,6 +303,7 @@ type QQChannelConfig struct {
 	AppID      string   `mapstructure:"app_id" json:"app_id"`           // QQ 机器人 AppID
 	AppSecret  string   `mapstructure:"app_secret" json:"app_secret"`   // AppSecret (ClientSecret)
 	AllowedIDs []string `mapstructure:"allowed_ids" json:"allowed_ids"` // 允许的用户/群ID列表
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -211,6 +318,7 @@ type WeWorkChannelConfig struct {
 	EncodingAESKey string   `mapstructure:"encoding_aes_key" json:"encoding_aes_key"`
 	WebhookPort    int      `mapstructure:"webhook_port" json:"webhook_port"`
 	AllowedIDs     []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -221,6 +329,7 @@ type DingTalkChannelConfig struct {
 	ClientID     string   `mapstructure:"client_id" json:"client_id"`
 	ClientSecret string   `mapstructure:"secret" json:"secret"`
 	AllowedIDs   []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -233,6 +342,7 @@ type InfoflowChannelConfig struct {
 	AESKey      string   `mapstructure:"aes_key" json:"aes_key"`
 	WebhookPort int      `mapstructure:"webhook_port" json:"webhook_port"`
 	AllowedIDs  []string `mapstructure:"allowed_ids" json:"allowed_ids"`
+	QuietHours *QuietHoursConfig `mapstructure:"quiet_hours" json:"quiet_hours"` // 静默时段：期间的入站消息自动回复或排队到时段结束再处理
 	// 多账号配置（新格式）
 	Accounts map[string]ChannelAccountConfig `mapstructure:"accounts" json:"accounts"`
 }
@@ -244,21 +354,33 @@ type ProvidersConfig struct {
 	Anthropic          AnthropicProviderConfig  `mapstructure:"anthropic" json:"anthropic"`
 	Moonshot           MoonshotProviderConfig   `mapstructure:"moonshot" json:"moonshot"`   // Kimi（月之暗面）OpenAI 兼容 API，与 OpenClaw 对齐
 	Router9            Router9ProviderConfig   `mapstructure:"9router" json:"9router"`       // 9router 本地代理，OpenAI 兼容 API
+	Gemini             GeminiProviderConfig     `mapstructure:"gemini" json:"gemini"`         // Google Gemini（generateContent API）
 	Profiles           []ProviderProfileConfig  `mapstructure:"profiles" json:"profiles"`
 	Failover           FailoverConfig           `mapstructure:"failover" json:"failover"`
 	MaxConcurrentCalls int                      `mapstructure:"max_concurrent_calls" json:"max_concurrent_calls"` // 全局并发 LLM 调用上限，0=不限制，1=串行（多 agent 时建议 1 防卡死）
+	DailyTokenBudget   int64                    `mapstructure:"daily_token_budget" json:"daily_token_budget"`     // 每日 token 软预算，0=不限制；达到后新的 run 直接失败，次日自动恢复
+	Pricing            map[string]ModelPricing  `mapstructure:"pricing" json:"pricing"`                           // 模型单价（覆盖或补充内置价格表），供 usage.cost 计算费用
+	Preflight          bool                     `mapstructure:"preflight" json:"preflight"`                       // 网关启动时以默认模型发起一次极小调用，提前暴露密钥/模型/网络问题并反映到 /ready
+}
+
+// ModelPricing 模型单价（美元 / 1K tokens）
+type ModelPricing struct {
+	Input  float64 `mapstructure:"input" json:"input"`
+	Output float64 `mapstructure:"output" json:"output"`
 }
 
 // ProviderProfileConfig 提供商配置
 type ProviderProfileConfig struct {
 	Name          string                 `mapstructure:"name" json:"name"`
-	Provider      string                 `mapstructure:"provider" json:"provider"` // openai, anthropic, openrouter, moonshot
+	Provider      string                 `mapstructure:"provider" json:"provider"` // openai, anthropic, openrouter, moonshot, gemini
 	APIKey        string                 `mapstructure:"api_key" json:"api_key"`
 	BaseURL       string                 `mapstructure:"base_url" json:"base_url"`
 	ExtraBody     map[string]interface{} `mapstructure:"extra_body" json:"extra_body"`
 	Priority      int                    `mapstructure:"priority" json:"priority"`
 	ContextWindow int                    `mapstructure:"context_window" json:"context_window"` // 该 profile 的模型上下文窗口 token 数，0 表示默认
 	Streaming     *bool                  `mapstructure:"streaming" json:"streaming"`           // 是否启用流式输出，默认 true，某些模型（如 Ollama）可能需要禁用
+	Headers       map[string]string      `mapstructure:"headers" json:"headers"`               // 附加请求头（openai 兼容与 openrouter 生效）
+	MaxConcurrent int                    `mapstructure:"max_concurrent" json:"max_concurrent"` // 该 profile 的并发调用上限，0=不限制；与 providers.max_concurrent_calls 叠加生效
 }
 
 // FailoverConfig 故障转移配置
@@ -277,11 +399,12 @@ type CircuitBreakerConfig struct {
 
 // OpenRouterProviderConfig OpenRouter 配置
 type OpenRouterProviderConfig struct {
-	APIKey     string `mapstructure:"api_key" json:"api_key"`
-	BaseURL    string `mapstructure:"base_url" json:"base_url"`
-	Timeout    int    `mapstructure:"timeout" json:"timeout"`
-	MaxRetries int    `mapstructure:"max_retries" json:"max_retries"`
-	Streaming  *bool  `mapstructure:"streaming" json:"streaming"` // 是否启用流式输出，默认 true（当前实现暂不支持流式，预留）
+	APIKey     string            `mapstructure:"api_key" json:"api_key"`
+	BaseURL    string            `mapstructure:"base_url" json:"base_url"`
+	Timeout    int               `mapstructure:"timeout" json:"timeout"`
+	MaxRetries int               `mapstructure:"max_retries" json:"max_retries"`
+	Streaming  *bool             `mapstructure:"streaming" json:"streaming"` // 是否启用流式输出，默认 true（当前实现暂不支持流式，预留）
+	Headers    map[string]string `mapstructure:"headers" json:"headers"`     // 附加请求头，覆盖默认的 HTTP-Referer/X-Title；值为空表示去掉该头
 }
 
 // OpenAIProviderConfig OpenAI 配置
@@ -291,13 +414,15 @@ type OpenAIProviderConfig struct {
 	Timeout   int                    `mapstructure:"timeout" json:"timeout"`
 	ExtraBody map[string]interface{} `mapstructure:"extra_body" json:"extra_body"`
 	Streaming *bool                  `mapstructure:"streaming" json:"streaming"` // 是否启用流式输出，默认 true
+	Headers   map[string]string      `mapstructure:"headers" json:"headers"`     // 附加到每个请求的 HTTP 头（如企业代理鉴权）
 }
 
 // AnthropicProviderConfig Anthropic 配置
 type AnthropicProviderConfig struct {
-	APIKey  string `mapstructure:"api_key" json:"api_key"`
-	BaseURL string `mapstructure:"base_url" json:"base_url"`
-	Timeout int    `mapstructure:"timeout" json:"timeout"`
+	APIKey    string `mapstructure:"api_key" json:"api_key"`
+	BaseURL   string `mapstructure:"base_url" json:"base_url"`
+	Timeout   int    `mapstructure:"timeout" json:"timeout"`
+	Streaming *bool  `mapstructure:"streaming" json:"streaming"` // 是否启用流式输出（Messages API SSE），默认 true
 }
 
 // MoonshotProviderConfig 月之暗面 Kimi 配置（OpenAI 兼容 API）
@@ -319,6 +444,13 @@ type Router9ProviderConfig struct {
 	ExtraBody    map[string]interface{} `mapstructure:"extra_body" json:"extra_body"`
 }
 
+// GeminiProviderConfig Google Gemini 配置
+type GeminiProviderConfig struct {
+	APIKey    string `mapstructure:"api_key" json:"api_key"`
+	BaseURL   string `mapstructure:"base_url" json:"base_url"`   // 默认 "https://generativelanguage.googleapis.com/v1beta"
+	Streaming *bool  `mapstructure:"streaming" json:"streaming"` // 是否启用流式输出（streamGenerateContent），默认 true
+}
+
 // GatewayConfig 网关配置
 type GatewayConfig struct {
 	Host         string          `mapstructure:"host" json:"host"`
@@ -326,19 +458,59 @@ type GatewayConfig struct {
 	ReadTimeout  time.Duration   `mapstructure:"read_timeout" json:"read_timeout"`
 	WriteTimeout time.Duration   `mapstructure:"write_timeout" json:"write_timeout"`
 	WebSocket    WebSocketConfig `mapstructure:"websocket" json:"websocket"`
+	Pprof        PprofConfig     `mapstructure:"pprof" json:"pprof"`
+	Metrics      MetricsConfig   `mapstructure:"metrics" json:"metrics"`
+	Locale       string          `mapstructure:"locale" json:"locale"` // 系统消息语言：en / zh，空或不支持时为英文
+	MinFreeDiskMB      int  `mapstructure:"min_free_disk_mb" json:"min_free_disk_mb"`             // ~/.goclaw 所在卷剩余空间告警阈值（MB），默认 500；低于 1/4 视为严重不足
+	PauseRunsOnLowDisk bool `mapstructure:"pause_runs_on_low_disk" json:"pause_runs_on_low_disk"` // 剩余空间严重不足时暂停新运行（返回明确错误），默认 false
+}
+
+// PprofConfig 调试 profile 配置（/debug/pprof/ 与 debug.goroutines）；默认关闭，启用后需携带 websocket.auth_token 访问
+type PprofConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+}
+
+// MetricsConfig Prometheus 指标端点（/metrics）配置；默认关闭
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled" json:"enabled"`
+	Host    string `mapstructure:"host" json:"host"` // 独立端口的监听地址，空为 gateway.host
+	Port    int    `mapstructure:"port" json:"port"` // 独立端口；0 表示挂在 gateway HTTP 端口上
 }
 
 // WebSocketConfig WebSocket 配置
 type WebSocketConfig struct {
-	Host         string        `mapstructure:"host" json:"host"`
-	Port         int           `mapstructure:"port" json:"port"`
-	Path         string        `mapstructure:"path" json:"path"`
-	EnableAuth   bool          `mapstructure:"enable_auth" json:"enable_auth"`
-	AuthToken    string        `mapstructure:"auth_token" json:"auth_token"`
-	PingInterval time.Duration `mapstructure:"ping_interval" json:"ping_interval"`
-	PongTimeout  time.Duration `mapstructure:"pong_timeout" json:"pong_timeout"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
+	Host          string        `mapstructure:"host" json:"host"`
+	Port          int           `mapstructure:"port" json:"port"`
+	Path          string        `mapstructure:"path" json:"path"`
+	EnableAuth    bool          `mapstructure:"enable_auth" json:"enable_auth"`
+	AuthToken     string        `mapstructure:"auth_token" json:"auth_token"`
+	PingInterval  time.Duration `mapstructure:"ping_interval" json:"ping_interval"`
+	PongTimeout   time.Duration `mapstructure:"pong_timeout" json:"pong_timeout"`
+	ReadTimeout   time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
+	WriteTimeout  time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
+	SendQueueSize int           `mapstructure:"send_queue_size" json:"send_queue_size"` // 每连接发送队列高水位（帧数），超过后合并流式增量；0 为默认 256
+	EnableTLS     bool          `mapstructure:"enable_tls" json:"enable_tls"`           // HTTP 与 WebSocket 服务器使用 HTTPS / WSS
+	CertFile      string        `mapstructure:"cert_file" json:"cert_file"`             // PEM 证书（可含中间证书），文件更新后自动重新加载
+	KeyFile       string        `mapstructure:"key_file" json:"key_file"`               // PEM 私钥
+	// 允许建立 WebSocket 连接的页面 Origin：精确值（如 https://app.example.com）或通配（如 https://*.example.com，"*" 为全部）；
+	// 为空时仅允许与网关同源或来自 localhost 的页面，不带 Origin 的非浏览器客户端不受限制
+	AllowedOrigins []string `mapstructure:"allowed_origins" json:"allowed_origins"`
+}
+
+// HTTPScheme gateway HTTP 地址的 scheme：开启 TLS 时为 https
+func (g *GatewayConfig) HTTPScheme() string {
+	if g.WebSocket.EnableTLS {
+		return "https"
+	}
+	return "http"
+}
+
+// WebSocketScheme gateway WebSocket 地址的 scheme：开启 TLS 时为 wss
+func (g *GatewayConfig) WebSocketScheme() string {
+	if g.WebSocket.EnableTLS {
+		return "wss"
+	}
+	return "ws"
 }
 
 // ToolsConfig 工具配置
@@ -347,6 +519,8 @@ type ToolsConfig struct {
 	Shell      ShellToolConfig      `mapstructure:"shell" json:"shell"`
 	Web        WebToolConfig        `mapstructure:"web" json:"web"`
 	Browser    BrowserToolConfig    `mapstructure:"browser" json:"browser"`
+	// 单次工具调用的执行超时（秒），超时后以错误结果返回给模型；0 表示默认 120 秒
+	TimeoutSeconds int `mapstructure:"timeout_seconds" json:"timeout_seconds"`
 }
 
 // FileSystemToolConfig 文件系统工具配置
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 校验问题的严重程度：error 阻止加载/保存，warning 仅提示
+const (
+	IssueSeverityError   = "error"
+	IssueSeverityWarning = "warning"
+)
+
+// ValidationIssue 字段级校验问题；Path 为配置点路径（如 agents.defaults.model、gateway.port）
+type ValidationIssue struct {
+	Path     string `json:"path"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationError Validate 返回的错误，包含全部 error 级问题
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		parts = append(parts, issue.Path+": "+issue.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// validationIssues 校验过程中累积问题，而不是遇到第一个错误就返回
+type validationIssues struct {
+	list []ValidationIssue
+}
+
+func (v *validationIssues) errorf(path, format string, args ...interface{}) {
+	v.list = append(v.list, ValidationIssue{Path: path, Severity: IssueSeverityError, Message: fmt.Sprintf(format, args...)})
+}
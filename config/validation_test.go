@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAccumulatesIssues(t *testing.T) {
+	cfg := &Config{}
+	cfg.Providers.OpenAI.APIKey = "sk-test-key-1234567890"
+	cfg.Gateway.Port = 70000
+	cfg.Gateway.ReadTimeout = 1
+	cfg.Gateway.WriteTimeout = 1
+	cfg.Tools.Web.Timeout = 1
+	cfg.Agents.Defaults.MaxIterations = 10
+	cfg.Agents.Defaults.MaxTokens = 1000
+	cfg.Session.MaxMessages = -1
+
+	issues := ValidateIssues(cfg)
+	paths := make(map[string]bool)
+	for _, issue := range issues {
+		if issue.Severity != IssueSeverityError {
+			t.Errorf("issue %+v: severity = %q", issue, issue.Severity)
+		}
+		paths[issue.Path] = true
+	}
+	for _, want := range []string{"agents.defaults.model", "gateway.port", "session.max_messages"} {
+		if !paths[want] {
+			t.Errorf("missing issue for %s in %+v", want, issues)
+		}
+	}
+	if len(issues) != 3 {
+		t.Errorf("got %d issues, want 3: %+v", len(issues), issues)
+	}
+
+	var verr *ValidationError
+	if err := Validate(cfg); !errors.As(err, &verr) || len(verr.Issues) != 3 {
+		t.Fatalf("Validate() = %v, want ValidationError with 3 issues", err)
+	}
+
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Gateway.Port = 8080
+	cfg.Session.MaxMessages = 0
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() after fixes = %v", err)
+	}
+}
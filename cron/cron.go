@@ -79,12 +79,3 @@ func (c *Cron) Schedule(schedule Schedule, jobFunc func(), id string) {
 func (c *Cron) Remove(id string) {
 	delete(c.jobs, id)
 }
-
-// Parse 解析 cron 表达式（简化版）
-func Parse(spec string) (Schedule, error) {
-	// 简化实现：只支持 "every X minutes" 格式
-	// 实际应该使用完整的 cron 解析器
-	return ScheduleFunc(func(t time.Time) time.Time {
-		return t.Add(time.Minute)
-	}), nil
-}
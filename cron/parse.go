@@ -0,0 +1,248 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors 常用的预定义调度
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// nextSearchYears 找不到下一次触发时间时最多向后搜索的年数（如 2 月 30 日永不触发）
+const nextSearchYears = 5
+
+// Parse 解析 cron 表达式：标准 5 段（分 时 日 月 周，支持 * ? , - / 与月份/星期英文缩写，周日可写 0 或 7），
+// 以及 @yearly/@annually/@monthly/@weekly/@daily/@midnight/@hourly 与 @every <duration>。
+// 触发时间按传入 Next 的时间所在时区计算
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	if strings.HasPrefix(spec, "@") {
+		if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+			d, err := time.ParseDuration(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("invalid @every duration %q: %w", rest, err)
+			}
+			if d < time.Minute {
+				return nil, fmt.Errorf("@every duration must be at least 1m, got %s", d)
+			}
+			return everySchedule(d), nil
+		}
+		expanded, ok := descriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown descriptor %q", spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	s := &specSchedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], "minute", 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], "hour", 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], "day-of-month", 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], "month", 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], "day-of-week", 0, 7, weekdayNames); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = isWildcard(fields[2])
+	s.dowAny = isWildcard(fields[4])
+	return s, nil
+}
+
+// ParseInLocation 解析 cron 表达式，触发时间按时区 tz（IANA 名称）计算；tz 为空时使用服务器本地时区
+func ParseInLocation(spec, tz string) (Schedule, error) {
+	loc, err := LoadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return InLocation(schedule, loc), nil
+}
+
+// LoadLocation 解析时区名；空字符串为服务器本地时区
+func LoadLocation(tz string) (*time.Location, error) {
+	tz = strings.TrimSpace(tz)
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+	return loc, nil
+}
+
+// InLocation 返回在 loc 时区计算触发时间的调度
+func InLocation(schedule Schedule, loc *time.Location) Schedule {
+	return ScheduleFunc(func(t time.Time) time.Time {
+		return schedule.Next(t.In(loc))
+	})
+}
+
+// NextRuns 返回 from 之后最多 n 次触发时间；调度永不触发时提前结束
+func NextRuns(schedule Schedule, from time.Time, n int) []time.Time {
+	runs := make([]time.Time, 0, n)
+	t := from
+	for len(runs) < n {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// specSchedule 5 段表达式，各字段以位图表示允许的取值
+type specSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny / dowAny 日、周字段以 * 或 ? 开头：两者都受限时任一匹配即可，否则须同时匹配
+	domAny, dowAny bool
+}
+
+// Next 返回 t 之后（按分钟对齐）的下一次触发时间；nextSearchYears 年内无匹配时返回零值
+func (s *specSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(nextSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *specSchedule) dayMatches(t time.Time) bool {
+	domOK := has(s.dom, t.Day())
+	dowOK := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// everySchedule 固定间隔（@every）
+type everySchedule time.Duration
+
+// Next 实现 Schedule 接口
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(e))
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func isWildcard(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
+
+// parseField 解析单个字段（逗号分隔的 值 / 范围 / 步长）为位图
+func parseField(field, name string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, name)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = min, max
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(a, name, names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(b, name, names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseValue(rng, name, names)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max {
+			return 0, fmt.Errorf("%s value out of range [%d, %d]: %q", name, min, max, part)
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid %s range %q: start is after end", name, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s, name string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, name)
+	}
+	return v, nil
+}
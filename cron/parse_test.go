@@ -0,0 +1,87 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNextRuns(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	from := time.Date(2026, 1, 30, 10, 17, 45, 0, shanghai) // Friday
+
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"*/15 * * * *", []string{"2026-01-30T10:30:00+08:00", "2026-01-30T10:45:00+08:00", "2026-01-30T11:00:00+08:00"}},
+		{"30 9 * * mon-fri", []string{"2026-02-02T09:30:00+08:00", "2026-02-03T09:30:00+08:00", "2026-02-04T09:30:00+08:00"}},
+		{"0 0 1,15 * *", []string{"2026-02-01T00:00:00+08:00", "2026-02-15T00:00:00+08:00", "2026-03-01T00:00:00+08:00"}},
+		{"0 12 1 * 7", []string{"2026-02-01T12:00:00+08:00", "2026-02-08T12:00:00+08:00", "2026-02-15T12:00:00+08:00"}},
+		{"@daily", []string{"2026-01-31T00:00:00+08:00", "2026-02-01T00:00:00+08:00", "2026-02-02T00:00:00+08:00"}},
+		{"@hourly", []string{"2026-01-30T11:00:00+08:00", "2026-01-30T12:00:00+08:00", "2026-01-30T13:00:00+08:00"}},
+		{"@every 90m", []string{"2026-01-30T11:47:45+08:00", "2026-01-30T13:17:45+08:00", "2026-01-30T14:47:45+08:00"}},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.spec, err)
+			continue
+		}
+		var got []string
+		for _, run := range NextRuns(schedule, from, 3) {
+			got = append(got, run.Format(time.RFC3339))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Parse(%q) next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"0 0 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 * foo *",
+		"@sometimes",
+		"@every 10s",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+}
+
+func TestNextRunsNeverFires(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := NextRuns(schedule, time.Now(), 3); len(runs) != 0 {
+		t.Errorf("Feb 30 should never fire, got %v", runs)
+	}
+}
+
+func TestParseInLocation(t *testing.T) {
+	schedule, err := ParseInLocation("0 4 * * *", "America/New_York")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	from := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	// 04:00 EDT (UTC-4) = 08:00 UTC
+	if got, want := schedule.Next(from), time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got.UTC(), want)
+	}
+
+	if _, err := ParseInLocation("0 4 * * *", "Mars/Olympus"); err == nil || !strings.Contains(err.Error(), "invalid timezone") {
+		t.Errorf("invalid timezone error = %v", err)
+	}
+}
@@ -29,6 +29,7 @@ type Job struct {
 	ID         string
 	Name       string
 	Schedule   string
+	Timezone   string // Schedule 所在时区（IANA 名称），空为服务器本地时区
 	Task       string
 	TargetChat string
 	Enabled    bool
@@ -112,7 +113,7 @@ func (s *Scheduler) AddJob(job *Job) error {
 // scheduleJob 调度任务
 func (s *Scheduler) scheduleJob(job *Job) error {
 	// 解析 cron 表达式
-	schedule, err := Parse(job.Schedule)
+	schedule, err := ParseInLocation(job.Schedule, job.Timezone)
 	if err != nil {
 		return fmt.Errorf("invalid cron schedule: %w", err)
 	}
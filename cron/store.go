@@ -0,0 +1,210 @@
+package cron
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StoredJob 定时任务文件（~/.goclaw/cron.json）中的任务，与前端约定：id, schedule, sessionKey, enabled, label 等；
+// 网关 cron.* 方法与 agent 的 cron_list / cron_add 工具共用
+type StoredJob struct {
+	ID         string `json:"id"`
+	Schedule   string `json:"schedule"`
+	Timezone   string `json:"timezone,omitempty"` // schedule 所在时区（IANA 名称），空为服务器本地时区
+	SessionKey string `json:"sessionKey,omitempty"`
+	// Channel / AccountID / ChatID 回复投递的渠道与聊天（cron_add 记录创建时所在的聊天），为空时回复只进入会话与 Control UI
+	Channel   string `json:"channel,omitempty"`
+	AccountID string `json:"accountId,omitempty"`
+	ChatID    string `json:"chatId,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	Label     string `json:"label,omitempty"`
+	Prompt    string `json:"prompt,omitempty"` // 触发时发送给 agent 的内容，为空时使用 label
+	CreatedAt int64  `json:"createdAt,omitempty"`
+	// DeleteAfterRun 一次性任务：定时触发后即从文件中删除（如“明天 9 点提醒我”）
+	DeleteAfterRun bool `json:"deleteAfterRun,omitempty"`
+}
+
+// Store 基于 JSON 文件的任务存储。Add / Update / Remove 在同一把锁内完成读改写，
+// 进程内应共用一个 Store（网关 cron.* 与 agent 工具共用 Handler.CronStore），否则并发修改会互相覆盖。
+// 读取结果按文件的修改时间与大小缓存，文件未变化时 Load 只做一次 stat（后台调度器每秒调用）
+type Store struct {
+	path string
+	mu   sync.Mutex
+
+	cached    bool
+	cacheJobs []StoredJob
+	cacheMod  time.Time
+	cacheSize int64
+}
+
+// DefaultStorePath 默认任务文件路径
+func DefaultStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".goclaw", "cron.json")
+}
+
+// NewStore 创建任务存储，path 为空时使用 DefaultStorePath
+func NewStore(path string) *Store {
+	if path == "" {
+		path = DefaultStorePath()
+	}
+	return &Store{path: path}
+}
+
+func (c *Store) Load() ([]StoredJob, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load()
+}
+
+// load 返回任务列表副本；文件修改时间与大小未变时直接使用缓存
+func (c *Store) load() ([]StoredJob, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.cached = false
+			return []StoredJob{}, nil
+		}
+		return nil, err
+	}
+	if c.cached && info.ModTime().Equal(c.cacheMod) && info.Size() == c.cacheSize {
+		return slices.Clone(c.cacheJobs), nil
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Jobs []StoredJob `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if out.Jobs == nil {
+		out.Jobs = []StoredJob{}
+	}
+	c.setCache(info, out.Jobs)
+	return slices.Clone(out.Jobs), nil
+}
+
+func (c *Store) setCache(info os.FileInfo, jobs []StoredJob) {
+	c.cached = true
+	c.cacheJobs = slices.Clone(jobs)
+	c.cacheMod = info.ModTime()
+	c.cacheSize = info.Size()
+}
+
+func (c *Store) Save(jobs []StoredJob) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save(jobs)
+}
+
+func (c *Store) save(jobs []StoredJob) error {
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(struct {
+		Jobs []StoredJob `json:"jobs"`
+	}{Jobs: jobs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		c.cached = false
+		return err
+	}
+	if info, err := os.Stat(c.path); err == nil {
+		c.setCache(info, jobs)
+	} else {
+		c.cached = false
+	}
+	return nil
+}
+
+func (c *Store) Add(job StoredJob) (StoredJob, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs, err := c.load()
+	if err != nil {
+		return StoredJob{}, err
+	}
+	if job.ID == "" {
+		job.ID = time.Now().Format("20060102150405") + "-" + randomShortID()
+	}
+	if job.CreatedAt == 0 {
+		job.CreatedAt = time.Now().UnixMilli()
+	}
+	jobs = append(jobs, job)
+	if err := c.save(jobs); err != nil {
+		return StoredJob{}, err
+	}
+	return job, nil
+}
+
+func (c *Store) Update(id string, patch map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs, err := c.load()
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if jobs[i].ID == id {
+			if v, ok := patch["enabled"].(bool); ok {
+				jobs[i].Enabled = v
+			}
+			if v, ok := patch["schedule"].(string); ok {
+				jobs[i].Schedule = v
+			}
+			if v, ok := patch["timezone"].(string); ok {
+				jobs[i].Timezone = v
+			}
+			if v, ok := patch["sessionKey"].(string); ok {
+				jobs[i].SessionKey = v
+			}
+			if v, ok := patch["label"].(string); ok {
+				jobs[i].Label = v
+			}
+			if v, ok := patch["prompt"].(string); ok {
+				jobs[i].Prompt = v
+			}
+			return c.save(jobs)
+		}
+	}
+	return os.ErrNotExist
+}
+
+func (c *Store) Remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs, err := c.load()
+	if err != nil {
+		return err
+	}
+	newJobs := make([]StoredJob, 0, len(jobs))
+	for _, j := range jobs {
+		if j.ID != id {
+			newJobs = append(newJobs, j)
+		}
+	}
+	return c.save(newJobs)
+}
+
+func randomShortID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "id"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,97 @@
+package cron
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreConcurrentAddKeepsAllJobs(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "cron.json"))
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Add(StoredJob{Schedule: "0 9 * * *", Enabled: true}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	jobs, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != n {
+		t.Fatalf("got %d jobs after %d concurrent adds", len(jobs), n)
+	}
+
+	// 并发停用前一半、删除后一半，任何一次修改都不应被其他读改写覆盖
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(id string, remove bool) {
+			defer wg.Done()
+			var err error
+			if remove {
+				err = s.Remove(id)
+			} else {
+				err = s.Update(id, map[string]interface{}{"enabled": false})
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		}(j.ID, i >= n/2)
+	}
+	wg.Wait()
+
+	jobs, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != n/2 {
+		t.Fatalf("got %d jobs, want %d", len(jobs), n/2)
+	}
+	for _, j := range jobs {
+		if j.Enabled {
+			t.Errorf("job %s lost its update", j.ID)
+		}
+	}
+}
+
+func TestStoreLoadSeesExternalEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron.json")
+	s := NewStore(path)
+	if _, err := s.Add(StoredJob{Schedule: "0 9 * * *"}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := s.Load()
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Load = %v, %v", jobs, err)
+	}
+	jobs[0].Schedule = "mutated"
+	if again, _ := s.Load(); again[0].Schedule != "0 9 * * *" {
+		t.Fatal("Load returned the cached slice instead of a copy")
+	}
+
+	// 外部编辑文件后（修改时间或大小变化）应重新读取
+	data := `{"jobs":[{"id":"a","schedule":"0 8 * * *"},{"id":"b","schedule":"0 10 * * *"}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "a" {
+		t.Fatalf("Load after external edit = %+v", jobs)
+	}
+}
@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultFilesListMaxDepth recursive 时未传 maxDepth 的默认遍历深度
+	defaultFilesListMaxDepth = 8
+	// maxFilesListEntries 单次列出的条目上限，超出时返回 truncated
+	maxFilesListEntries = 5000
+	// defaultFilesGetMaxBytes agents.files.get 未传 maxBytes 时最多读取的字节数
+	defaultFilesGetMaxBytes = 1 << 20
+
+	// fileEncodingUTF8 / fileEncodingBase64 agents.files.get/set 的 content 编码
+	fileEncodingUTF8   = "utf8"
+	fileEncodingBase64 = "base64"
+)
+
+// workspaceFileEntry agents.files.list 中的一项；path 为相对工作区的路径（使用 /）
+type workspaceFileEntry struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	IsDir        bool   `json:"isDir"`
+	Missing      bool   `json:"missing"`
+	Size         int64  `json:"size"`
+	ModifiedAtMs int64  `json:"modifiedAtMs"`
+}
+
+// listWorkspaceFiles 列出工作区文件；recursive 时按 maxDepth（根目录下的条目深度为 1）递归，
+// 不跟随符号链接，指向工作区外的符号链接直接跳过。返回是否因条目上限被截断
+func listWorkspaceFiles(workspace string, recursive bool, maxDepth int) ([]workspaceFileEntry, bool, error) {
+	if !recursive {
+		maxDepth = 1
+	} else if maxDepth <= 0 {
+		maxDepth = defaultFilesListMaxDepth
+	}
+	root, err := filepath.EvalSymlinks(workspace)
+	if err != nil {
+		return nil, false, err
+	}
+
+	files := make([]workspaceFileEntry, 0)
+	truncated := false
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if path == root {
+			return err
+		}
+		if err != nil {
+			// 无权限等错误只跳过该条目
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return nil
+		}
+		depth := strings.Count(rel, string(filepath.Separator)) + 1
+		if len(files) >= maxFilesListEntries {
+			truncated = true
+			return fs.SkipAll
+		}
+
+		info, infoErr := d.Info()
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, evalErr := filepath.EvalSymlinks(path)
+			if recursive && (evalErr != nil || !pathWithin(root, target)) {
+				return nil
+			}
+			if evalErr == nil {
+				info, infoErr = os.Stat(target)
+			}
+		}
+		entry := workspaceFileEntry{Name: d.Name(), Path: filepath.ToSlash(rel)}
+		if infoErr == nil && info != nil {
+			entry.IsDir = info.IsDir()
+			if !entry.IsDir {
+				entry.Size = info.Size()
+			}
+			entry.ModifiedAtMs = info.ModTime().UnixMilli()
+		}
+		files = append(files, entry)
+
+		if d.IsDir() && depth >= maxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	return files, truncated, err
+}
+
+// pathWithin path 是否位于 root 之内（含 root 本身）
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// workspaceFileContent agents.files.get 读取的内容：文本为 utf8，其他（非法 UTF-8 或含 NUL）为 base64
+type workspaceFileContent struct {
+	Content   string
+	Encoding  string
+	MimeType  string
+	Size      int64
+	Truncated bool
+}
+
+// readWorkspaceFile 最多读取 maxBytes 字节，超出时 Truncated 为 true；文本截断处不拆开多字节字符
+func readWorkspaceFile(path string, maxBytes int64) (*workspaceFileContent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", filepath.Base(path))
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+	out := &workspaceFileContent{Size: info.Size(), Truncated: info.Size() > int64(len(data))}
+
+	text := data
+	if out.Truncated {
+		// 截断可能落在多字节字符中间，去掉末尾不完整的字符后再判断
+		for i := 0; i < utf8.UTFMax-1 && len(text) > 0 && !utf8.Valid(text); i++ {
+			text = text[:len(text)-1]
+		}
+	}
+	if utf8.Valid(text) && !bytes.ContainsRune(text, 0) {
+		out.Content = string(text)
+		out.Encoding = fileEncodingUTF8
+	} else {
+		out.Content = base64.StdEncoding.EncodeToString(data)
+		out.Encoding = fileEncodingBase64
+	}
+	out.MimeType = mime.TypeByExtension(filepath.Ext(path))
+	if out.MimeType == "" {
+		out.MimeType = http.DetectContentType(data)
+	}
+	return out, nil
+}
+
+// decodeFileContent 按 encoding（utf8 默认 / base64）解码 agents.files.set 的 content
+func decodeFileContent(content, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", fileEncodingUTF8:
+		return []byte(content), nil
+	case fileEncodingBase64:
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q: expected utf8 or base64", encoding)
+	}
+}
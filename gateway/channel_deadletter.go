@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smallnest/goclaw/bus"
+)
+
+// deadLetterList channels.deadletter.list：{channel, limit} 列出重试后仍无法投递的出站消息（最近失败的在前）
+func (h *Handler) deadLetterList(params map[string]interface{}) (interface{}, error) {
+	channel := strings.TrimSpace(getString(params, "channel"))
+	limit := 100
+	if n, ok := params["limit"].(float64); ok && n > 0 {
+		limit = int(n)
+	}
+	all := h.bus.DeadLetters().List()
+	entries := make([]bus.DeadLetter, 0, min(len(all), limit))
+	for i := len(all) - 1; i >= 0 && len(entries) < limit; i-- {
+		if channel != "" && all[i].Message.Channel != channel && channelTypeOf(all[i].Message.Channel) != channel {
+			continue
+		}
+		entries = append(entries, all[i])
+	}
+	return map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+		"total":   len(all),
+	}, nil
+}
+
+// deadLetterRetry channels.deadletter.retry：{id} 或 {all: true} 将死信直接交回原通道重新投递；再次失败的消息会重新进入死信队列
+func (h *Handler) deadLetterRetry(params map[string]interface{}) (interface{}, error) {
+	if h.channelMgr == nil {
+		return nil, fmt.Errorf("channel manager not available")
+	}
+	var ids []string
+	if getBool(params, "all", false) {
+		for _, entry := range h.bus.DeadLetters().List() {
+			ids = append(ids, entry.ID)
+		}
+	} else if id := strings.TrimSpace(getString(params, "id")); id != "" {
+		ids = []string{id}
+	} else {
+		return nil, NewRPCError(ErrorInvalidParams, "id or all is required")
+	}
+
+	requeued := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if err := h.channelMgr.RetryDeadLetter(context.Background(), id); err != nil {
+			if errors.Is(err, bus.ErrDeadLetterNotFound) && len(ids) == 1 {
+				return nil, NewRPCError(ErrorNotFound, "dead letter not found: %s", id)
+			}
+			if errors.Is(err, bus.ErrDeadLetterNotFound) {
+				continue
+			}
+			return map[string]interface{}{"requeued": requeued, "error": err.Error()}, nil
+		}
+		requeued = append(requeued, id)
+	}
+	return map[string]interface{}{"requeued": requeued}, nil
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/channels"
+)
+
+// channelSendTestTimeout channels.send.test 等待投递结果的默认与最大超时
+const (
+	channelSendTestTimeout    = 15 * time.Second
+	channelSendTestMaxTimeout = 60 * time.Second
+)
+
+// testChannelSend channels.send.test：{channel, accountId, chatId, text, timeoutMs} 经正常的出站分发路径发送一条消息，
+// 等待通道回报投递结果，返回 {delivered, error, latencyMs, messageId}
+func (h *Handler) testChannelSend(params map[string]interface{}) (interface{}, error) {
+	channel := strings.TrimSpace(getString(params, "channel"))
+	chatID := strings.TrimSpace(getString(params, "chatId"))
+	if channel == "" || chatID == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "channel and chatId are required")
+	}
+	text := getString(params, "text")
+	if strings.TrimSpace(text) == "" {
+		text = "goclaw channel delivery test"
+	}
+	name := channel
+	if accountID := strings.TrimSpace(getString(params, "accountId")); accountID != "" {
+		name = channels.AccountChannelName(channelTypeOf(channel), accountID)
+	}
+	if _, ok := h.channelMgr.Get(name); !ok {
+		return nil, NewRPCError(ErrorNotFound, "channel not found: %s", name)
+	}
+	timeout := channelSendTestTimeout
+	if ms, ok := params["timeoutMs"].(float64); ok && ms > 0 {
+		timeout = min(time.Duration(ms)*time.Millisecond, channelSendTestMaxTimeout)
+	}
+
+	msg := &bus.OutboundMessage{
+		ID:        uuid.New().String(),
+		Channel:   name,
+		ChatID:    chatID,
+		Content:   text,
+		Timestamp: time.Now(),
+	}
+	// 先登记再发布，避免分发过快时丢失结果
+	result, cancel := h.channelMgr.AwaitDelivery(msg.ID)
+	defer cancel()
+
+	start := time.Now()
+	out := map[string]interface{}{
+		"channel":   name,
+		"chatId":    chatID,
+		"delivered": false,
+	}
+	if err := h.bus.PublishOutbound(context.Background(), msg); err != nil {
+		out["error"] = "failed to publish: " + err.Error()
+		return out, nil
+	}
+	select {
+	case report := <-result:
+		out["latencyMs"] = time.Since(start).Milliseconds()
+		if report.Err != nil {
+			out["error"] = report.Err.Error()
+			return out, nil
+		}
+		out["delivered"] = true
+		if report.Result.MessageID != "" {
+			out["messageId"] = report.Result.MessageID
+		}
+	case <-time.After(timeout):
+		out["latencyMs"] = time.Since(start).Milliseconds()
+		out["error"] = "timed out waiting for delivery result"
+	}
+	return out, nil
+}
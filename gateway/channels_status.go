@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/channels"
+	"github.com/smallnest/goclaw/config"
+)
+
+// channelConnectedTimeout 查询扫码登录类通道（如 WhatsApp bridge）账号连接状态的超时
+const channelConnectedTimeout = 2 * time.Second
+
+// channelTypeOf 多账号通道的注册名为 <type>:<accountId>，返回其中的通道类型
+func channelTypeOf(name string) string {
+	if i := strings.Index(name, ":"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// channelsStatus channels.status：按通道类型汇总，channelAccounts[type] 为配置中的账号（含启用状态、显示名与运行状态），
+// channelDefaultAccountId[type] 为第一个启用的账号；可选 channel 只返回该通道（类型或 type:accountId）
+func (h *Handler) channelsStatus(params map[string]interface{}) (interface{}, error) {
+	configured := map[string][]config.ChannelAccountInfo{}
+	if cfg := config.Get(); cfg != nil {
+		configured = cfg.Channels.ChannelAccounts()
+	}
+	registered := make(map[string][]string)
+	for _, name := range h.channelMgr.List() {
+		t := channelTypeOf(name)
+		registered[t] = append(registered[t], name)
+	}
+
+	var types []string
+	if name := strings.TrimSpace(getString(params, "channel")); name != "" {
+		types = []string{channelTypeOf(name)}
+	} else {
+		seen := make(map[string]bool)
+		for t := range registered {
+			seen[t] = true
+		}
+		for t := range configured {
+			seen[t] = true
+		}
+		for t := range seen {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+	}
+
+	channelsMap := make(map[string]interface{})
+	channelAccounts := make(map[string]interface{})
+	channelDefaultAccountId := make(map[string]string)
+	for _, t := range types {
+		accounts := configured[t]
+		if len(accounts) == 0 {
+			// 无配置账号的通道（如插件注册的通道）按已注册实例列出
+			sort.Strings(registered[t])
+			for _, name := range registered[t] {
+				id := "default"
+				if ch, ok := h.channelMgr.Get(name); ok && ch.AccountID() != "" {
+					id = ch.AccountID()
+				}
+				accounts = append(accounts, config.ChannelAccountInfo{ID: id, Enabled: true})
+			}
+		}
+
+		entries := make([]map[string]interface{}, 0, len(accounts))
+		running := 0
+		for _, acc := range accounts {
+			entry := h.channelAccountStatus(t, acc)
+			if entry["running"] == true {
+				running++
+			}
+			entries = append(entries, entry)
+		}
+		defaultID := config.DefaultChannelAccountID(accounts)
+
+		status, err := h.channelMgr.Status(t)
+		if err != nil {
+			status = map[string]interface{}{"name": t, "enabled": defaultID != ""}
+		}
+		status["accounts"] = len(accounts)
+		status["accountsRunning"] = running
+		channelsMap[t] = status
+		channelAccounts[t] = entries
+		channelDefaultAccountId[t] = defaultID
+	}
+
+	return map[string]interface{}{
+		"ts":                      time.Now().UnixMilli(),
+		"channelOrder":            types,
+		"channelLabels":           map[string]string{},
+		"channels":                channelsMap,
+		"channelAccounts":         channelAccounts,
+		"channelDefaultAccountId": channelDefaultAccountId,
+	}, nil
+}
+
+// channelAccountStatus 单个账号的状态：registered 表示已创建通道实例，running 为实例是否运行中；
+// 支持扫码登录的通道额外返回 connected
+func (h *Handler) channelAccountStatus(channelType string, acc config.ChannelAccountInfo) map[string]interface{} {
+	entry := map[string]interface{}{
+		"accountId":  acc.ID,
+		"name":       acc.Name,
+		"enabled":    acc.Enabled,
+		"registered": false,
+		"running":    false,
+	}
+	ch, ok := h.channelMgr.GetAccount(channelType, acc.ID)
+	if !ok {
+		return entry
+	}
+	entry["registered"] = true
+	if r, ok := ch.(interface{ IsRunning() bool }); ok {
+		entry["running"] = r.IsRunning()
+	}
+	if lc, ok := ch.(channels.LoginChannel); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), channelConnectedTimeout)
+		connected, err := lc.LoginConnected(ctx)
+		cancel()
+		entry["connected"] = connected
+		if err != nil {
+			entry["error"] = err.Error()
+		}
+	}
+	return entry
+}
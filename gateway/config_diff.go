@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// configDiff config.diff：将候选配置 raw 与当前配置比较，返回 {path, old, new} 列表（密钥类字段脱敏）与校验结果，不写入文件。
+// hash 为当前配置的哈希，可作为随后 config.set / config.apply 的 baseHash
+func (h *Handler) configDiff(params map[string]interface{}) (interface{}, error) {
+	raw := getString(params, "raw")
+	if raw == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "raw parameter (JSON string) is required")
+	}
+	var candidate config.Config
+	if err := json.Unmarshal([]byte(raw), &candidate); err != nil {
+		return nil, NewRPCError(ErrorInvalidParams, "invalid config JSON: %v", err)
+	}
+
+	current := config.Get()
+	changes, err := config.DiffConfigs(current, &candidate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff config: %w", err)
+	}
+	changes = config.RedactDiffEntries(changes)
+
+	valid, issues := configIssues(&candidate)
+
+	result := map[string]interface{}{
+		"changes": changes,
+		"count":   len(changes),
+		"valid":   valid,
+		"issues":  issues,
+	}
+	if current != nil {
+		curRaw, _ := json.MarshalIndent(current, "", "  ")
+		result["hash"] = configHash(curRaw)
+		if baseHash := getString(params, "baseHash"); baseHash != "" {
+			result["baseHashMatches"] = baseHash == result["hash"]
+		}
+	}
+	return result, nil
+}
+
+// configHash 配置 JSON 的哈希（sha256 hex），即 config.get / config.diff 返回的 hash
+func configHash(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkConfigBaseHash config.set / config.apply 的并发保护：baseHash 非空时，当前配置（已加载的优先，否则读 path）的哈希须与之相同
+func checkConfigBaseHash(baseHash, path string) error {
+	if baseHash == "" {
+		return nil
+	}
+	var curRaw []byte
+	if cur := config.Get(); cur != nil {
+		curRaw, _ = json.MarshalIndent(cur, "", "  ")
+	} else if data, err := os.ReadFile(path); err == nil {
+		curRaw = data
+	}
+	if len(curRaw) > 0 && configHash(curRaw) != baseHash {
+		return fmt.Errorf("config changed (baseHash mismatch); reload and retry")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lookupConfigPath 按点路径在配置（JSON 解码后的通用结构）中取值，数组段为下标（如 agents.list.0.id）；
+// 路径不存在时 ok 为 false，存在但值为 null 时返回 nil, true
+func lookupConfigPath(root interface{}, path string) (value interface{}, ok bool) {
+	cur := root
+	for _, seg := range strings.Split(path, ".") {
+		if seg == "" {
+			return nil, false
+		}
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, exists := node[seg]
+			if !exists {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// configIssues 校验配置，返回是否有效（无 error 级问题）与全部字段级问题
+func configIssues(cfg *config.Config) (bool, []config.ValidationIssue) {
+	issues := config.ValidateIssues(cfg)
+	if issues == nil {
+		issues = []config.ValidationIssue{}
+	}
+	for _, issue := range issues {
+		if issue.Severity == config.IssueSeverityError {
+			return false, issues
+		}
+	}
+	return true, issues
+}
+
+// validateConfig config.validate：校验候选配置 raw 而不写入，返回 {valid, issues: [{path, severity, message}]}，
+// 供 Control UI 定位到具体字段；JSON 无法解析时返回 path 为空的单个问题
+func (h *Handler) validateConfig(params map[string]interface{}) (interface{}, error) {
+	raw := getString(params, "raw")
+	if raw == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "raw parameter (JSON string) is required")
+	}
+	var candidate config.Config
+	if err := json.Unmarshal([]byte(raw), &candidate); err != nil {
+		return map[string]interface{}{
+			"valid": false,
+			"issues": []config.ValidationIssue{{
+				Severity: config.IssueSeverityError,
+				Message:  "invalid config JSON: " + err.Error(),
+			}},
+		}, nil
+	}
+	valid, issues := configIssues(&candidate)
+	return map[string]interface{}{
+		"valid":  valid,
+		"issues": issues,
+	}, nil
+}
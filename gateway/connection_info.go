@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// 连接角色：开启 websocket 鉴权且 token 校验通过的连接为 control，否则为 anonymous
+const (
+	connRoleControl   = "control"
+	connRoleAnonymous = "anonymous"
+	connRoleDevice    = "device" // 以配对设备 token 连接，权限由 scopes 限定
+)
+
+// maxTrackedSessionKeys 每个连接最多记录的 sessionKey 数（按最近使用保留）
+const maxTrackedSessionKeys = 50
+
+// ConnectionInfo 连接元数据，供 connection.info 使用
+type ConnectionInfo struct {
+	ID              string   `json:"id"`
+	ConnectedAt     int64    `json:"connectedAt"` // Unix 毫秒
+	RemoteAddr      string   `json:"remoteAddr"`
+	ForwardedFor    string   `json:"forwardedFor,omitempty"`
+	UserAgent       string   `json:"userAgent,omitempty"`
+	Authenticated   bool     `json:"authenticated"`
+	Role            string   `json:"role"`
+	Scopes          []string `json:"scopes"` // 连接可调用方法的权限范围（见 methodScopes）
+	DeviceID        string   `json:"deviceId,omitempty"`
+	Client          string   `json:"client,omitempty"`
+	Locale          string   `json:"locale,omitempty"`        // connect 握手的 locale，chat.send 运行失败时的提示按此语言发送
+	SessionKeys     []string `json:"sessionKeys"`             // 该连接请求过的 sessionKey，最近使用的在前
+	Subscriptions   []string `json:"subscriptions,omitempty"` // chat.subscribe 订阅的 sessionKey；未订阅时省略（接收全部 agent 事件）
+	LastActivityAt  int64    `json:"lastActivityAt"`
+	Requests        int64    `json:"requests"`
+	QueuedFrames    int      `json:"queuedFrames"`    // 发送队列中待发送的广播帧
+	CoalescedFrames int64    `json:"coalescedFrames"` // 因客户端过慢被合并的流式增量帧累计数
+}
+
+// ConnectionInfoProvider 按连接 ID 查询连接元数据（由 Server 实现）
+type ConnectionInfoProvider interface {
+	ConnectionInfo(id string) (ConnectionInfo, bool)
+}
+
+// attachRequest 记录升级请求中的远端地址与 UA，并创建带连接上下文的 logger（鉴权结果由 setAuth 写入）
+func (c *Connection) attachRequest(r *http.Request) {
+	c.infoMu.Lock()
+	c.remoteAddr = r.RemoteAddr
+	c.forwardedFor = strings.TrimSpace(r.Header.Get("X-Forwarded-For"))
+	c.userAgent = r.UserAgent()
+	c.infoMu.Unlock()
+
+	fields := []zap.Field{zap.String("connection_id", c.ID), zap.String("remote_addr", c.remoteAddr)}
+	if c.forwardedFor != "" {
+		fields = append(fields, zap.String("forwarded_for", c.forwardedFor))
+	}
+	c.log = logger.With(fields...)
+}
+
+// connLog 返回带 connection_id / remote_addr 的 logger
+func (c *Connection) connLog() *zap.Logger {
+	if c.log != nil {
+		return c.log
+	}
+	return logger.With(zap.String("connection_id", c.ID))
+}
+
+// touch 记录一次请求：更新最近活动时间、请求中的 sessionKey，以及 connect 携带的设备、客户端与语言信息
+func (c *Connection) touch(req *JSONRPCRequest) {
+	now := time.Now().UnixMilli()
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	c.lastActivity = now
+	c.requests++
+	if req == nil {
+		return
+	}
+	key, _ := req.Params["sessionKey"].(string)
+	if key == "" {
+		key, _ = req.Params["session_key"].(string)
+	}
+	if key != "" {
+		if c.sessionKeys == nil {
+			c.sessionKeys = make(map[string]int64)
+		}
+		c.sessionKeys[key] = now
+		if len(c.sessionKeys) > maxTrackedSessionKeys {
+			oldest, oldestAt := "", int64(0)
+			for k, at := range c.sessionKeys {
+				// 同一毫秒内的多个 key 时间相同，跳过刚记录的 key，避免把它淘汰
+				if k != key && (oldest == "" || at < oldestAt) {
+					oldest, oldestAt = k, at
+				}
+			}
+			delete(c.sessionKeys, oldest)
+		}
+	}
+	if req.Method == "connect" {
+		// 设备 token 连接的 deviceId 以配对记录为准，不接受握手参数覆盖
+		if device, ok := req.Params["device"].(map[string]interface{}); ok && c.role != connRoleDevice {
+			if id, _ := device["id"].(string); id != "" {
+				c.deviceID = id
+			}
+		}
+		if client, ok := req.Params["client"].(map[string]interface{}); ok {
+			name, _ := client["id"].(string)
+			if version, _ := client["version"].(string); version != "" {
+				name = strings.TrimSpace(name + " " + version)
+			}
+			c.client = name
+		}
+		if locale, _ := req.Params["locale"].(string); strings.TrimSpace(locale) != "" {
+			c.locale = strings.TrimSpace(locale)
+		}
+	}
+}
+
+// Info 返回连接元数据快照
+func (c *Connection) Info() ConnectionInfo {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	keys := make([]string, 0, len(c.sessionKeys))
+	for k := range c.sessionKeys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if c.sessionKeys[keys[i]] != c.sessionKeys[keys[j]] {
+			return c.sessionKeys[keys[i]] > c.sessionKeys[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	var subscriptions []string
+	if c.subscriptions != nil {
+		subscriptions = sortedKeys(c.subscriptions)
+	}
+	lastActivity := c.lastActivity
+	if lastActivity == 0 {
+		lastActivity = c.CreatedAt.UnixMilli()
+	}
+	var queued int
+	var coalesced int64
+	if c.queue != nil {
+		queued, coalesced = c.queue.stats()
+	}
+	return ConnectionInfo{
+		ID:              c.ID,
+		ConnectedAt:     c.CreatedAt.UnixMilli(),
+		RemoteAddr:      c.remoteAddr,
+		ForwardedFor:    c.forwardedFor,
+		UserAgent:       c.userAgent,
+		Authenticated:   c.authenticated,
+		Role:            c.role,
+		Scopes:          c.scopes,
+		DeviceID:        c.deviceID,
+		Client:          c.client,
+		Locale:          c.locale,
+		SessionKeys:     keys,
+		Subscriptions:   subscriptions,
+		LastActivityAt:  lastActivity,
+		Requests:        c.requests,
+		QueuedFrames:    queued,
+		CoalescedFrames: coalesced,
+	}
+}
+
+// ConnectionInfo 按连接 ID 返回连接元数据
+func (s *Server) ConnectionInfo(id string) (ConnectionInfo, bool) {
+	s.connectionsMu.RLock()
+	conn, ok := s.connections[id]
+	s.connectionsMu.RUnlock()
+	if !ok {
+		return ConnectionInfo{}, false
+	}
+	return conn.Info(), true
+}
@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestConnectionTouchRecordsRequestMetadata(t *testing.T) {
+	conn := &Connection{ID: "c", CreatedAt: time.Now()}
+	r := httptest.NewRequest("GET", "/ws", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", " 1.2.3.4 ")
+	r.Header.Set("User-Agent", "ui-test")
+	conn.attachRequest(r)
+
+	conn.touch(&JSONRPCRequest{Method: "connect", Params: map[string]interface{}{
+		"device": map[string]interface{}{"id": "dev-1"},
+		"client": map[string]interface{}{"id": "control-ui", "version": "1.2"},
+		"locale": " zh-CN ",
+	}})
+	conn.touch(&JSONRPCRequest{Method: "chat.send", Params: map[string]interface{}{"sessionKey": "agent:main:a"}})
+	time.Sleep(2 * time.Millisecond)
+	conn.touch(&JSONRPCRequest{Method: "chat.history", Params: map[string]interface{}{"session_key": "agent:main:b"}})
+	conn.touch(nil)
+
+	info := conn.Info()
+	if info.RemoteAddr != "10.0.0.1:5000" || info.ForwardedFor != "1.2.3.4" || info.UserAgent != "ui-test" {
+		t.Fatalf("request metadata = %+v", info)
+	}
+	if info.DeviceID != "dev-1" || info.Client != "control-ui 1.2" || info.Locale != "zh-CN" {
+		t.Fatalf("connect metadata = %+v", info)
+	}
+	if info.Requests != 4 || info.LastActivityAt < info.ConnectedAt {
+		t.Fatalf("activity = %d requests, last %d", info.Requests, info.LastActivityAt)
+	}
+	// 最近使用的 sessionKey 在前
+	if len(info.SessionKeys) != 2 || info.SessionKeys[0] != "agent:main:b" || info.SessionKeys[1] != "agent:main:a" {
+		t.Fatalf("session keys = %v", info.SessionKeys)
+	}
+}
+
+func TestConnectionTouchKeepsDeviceIDForDeviceConnections(t *testing.T) {
+	conn := &Connection{ID: "c", CreatedAt: time.Now()}
+	conn.setAuth(AuthContext{Authenticated: true, Role: connRoleDevice, Scopes: []string{scopeRead}},
+		&PairedDevice{DeviceID: "paired", Role: "operator"})
+	conn.touch(&JSONRPCRequest{Method: "connect", Params: map[string]interface{}{
+		"device": map[string]interface{}{"id": "spoofed"},
+	}})
+	if got := conn.Info().DeviceID; got != "paired" {
+		t.Fatalf("device id = %q, want the paired device", got)
+	}
+}
+
+func TestConnectionTouchCapsSessionKeys(t *testing.T) {
+	conn := &Connection{ID: "c", CreatedAt: time.Now()}
+	var last string
+	for i := 0; i < maxTrackedSessionKeys+10; i++ {
+		last = fmt.Sprintf("agent:main:s%d", i)
+		conn.touch(&JSONRPCRequest{Method: "chat.send", Params: map[string]interface{}{"sessionKey": last}})
+	}
+	keys := conn.Info().SessionKeys
+	if len(keys) != maxTrackedSessionKeys {
+		t.Fatalf("tracked %d keys, want %d", len(keys), maxTrackedSessionKeys)
+	}
+	found := false
+	for _, k := range keys {
+		found = found || k == last
+	}
+	if !found {
+		t.Fatalf("most recent key %q evicted", last)
+	}
+}
+
+func TestConnectionInfoRPC(t *testing.T) {
+	s := NewServer(&config.GatewayConfig{}, nil, nil, nil)
+	control := &Connection{ID: "control", CreatedAt: time.Now()}
+	control.setAuth(AuthContext{Authenticated: true, Role: connRoleControl, Scopes: []string{scopeAdmin}}, nil)
+	device := &Connection{ID: "device", CreatedAt: time.Now()}
+	device.setAuth(AuthContext{Authenticated: true, Role: connRoleDevice, Scopes: []string{scopeRead}}, nil)
+	s.connections[control.ID] = control
+	s.connections[device.ID] = device
+	h := s.handler
+	h.SetConnectionInfoProvider(s)
+
+	call := func(conn string, params map[string]interface{}) *JSONRPCResponse {
+		return h.HandleRequest(conn, &JSONRPCRequest{ID: "1", Method: "connection.info", Params: params})
+	}
+	resp := call("device", map[string]interface{}{})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	res := resp.Result.(map[string]interface{})
+	if res["self"] != true || res["connection"].(ConnectionInfo).ID != "device" {
+		t.Fatalf("self info = %+v", res)
+	}
+
+	// 非 control 角色不能查询其他连接
+	if resp := call("device", map[string]interface{}{"id": "control"}); resp.Error == nil || resp.Error.Code != ErrorInvalidRequest {
+		t.Fatalf("device querying control = %+v", resp.Error)
+	}
+	resp = call("control", map[string]interface{}{"id": "device"})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if res := resp.Result.(map[string]interface{}); res["self"] != false || res["connection"].(ConnectionInfo).Role != connRoleDevice {
+		t.Fatalf("control querying device = %+v", res)
+	}
+	if resp := call("control", map[string]interface{}{"id": "gone"}); resp.Error == nil || resp.Error.Code != ErrorNotFound {
+		t.Fatalf("unknown connection = %+v", resp.Error)
+	}
+}
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/cron"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// cronTickInterval 检查到期任务的间隔（调度精度为分钟）
+const cronTickInterval = time.Second
+
+// cronRunner 后台执行 cronStore 中已启用的任务：到期时把 prompt 作为任务 sessionKey 的排队运行发布到总线，
+// 回复投递到任务记录的渠道与聊天（cron_add 创建时所在的聊天），未记录时与 chat.send 一样进入该会话并广播给前端
+type cronRunner struct {
+	store *cronStore
+	bus   *bus.MessageBus
+
+	mu   sync.Mutex
+	next map[string]cronNextFire // job id -> 下次触发
+}
+
+// cronNextFire 任务的下次触发时间；schedule/timezone 变化时重新计算
+type cronNextFire struct {
+	spec string
+	at   time.Time
+}
+
+func newCronRunner(store *cronStore, messageBus *bus.MessageBus) *cronRunner {
+	return &cronRunner{
+		store: store,
+		bus:   messageBus,
+		next:  make(map[string]cronNextFire),
+	}
+}
+
+// Run 按 cronTickInterval 检查到期任务，直到 ctx 取消
+func (r *cronRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(cronTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.tick(ctx, now)
+		}
+	}
+}
+
+// tick 触发已到期的任务；任务从共享 Store 读取（文件未变化时使用缓存），cron.add/update/remove 及外部编辑无需通知
+func (r *cronRunner) tick(ctx context.Context, now time.Time) {
+	jobs, err := r.store.Load()
+	if err != nil {
+		logger.Warn("Failed to load cron jobs", zap.Error(err))
+		return
+	}
+
+	var due []CronJob
+	r.mu.Lock()
+	seen := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if !job.Enabled {
+			continue
+		}
+		seen[job.ID] = true
+		spec := job.Timezone + "|" + job.Schedule
+		entry, ok := r.next[job.ID]
+		if !ok || entry.spec != spec {
+			at, err := nextCronFire(job, now)
+			if err != nil {
+				logger.Warn("Skipping cron job with invalid schedule",
+					zap.String("job_id", job.ID),
+					zap.String("schedule", job.Schedule),
+					zap.Error(err))
+			}
+			r.next[job.ID] = cronNextFire{spec: spec, at: at}
+			continue
+		}
+		if entry.at.IsZero() || now.Before(entry.at) {
+			continue
+		}
+		due = append(due, job)
+		at, _ := nextCronFire(job, now)
+		r.next[job.ID] = cronNextFire{spec: spec, at: at}
+	}
+	for id := range r.next {
+		if !seen[id] {
+			delete(r.next, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, job := range due {
+		if _, err := r.Fire(ctx, job); err != nil {
+			logger.Error("Cron job execution failed", zap.String("job_id", job.ID), zap.Error(err))
+			continue
+		}
+		if job.DeleteAfterRun {
+			if err := r.store.Remove(job.ID); err != nil {
+				logger.Warn("Failed to remove one-shot cron job", zap.String("job_id", job.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Fire 立即执行任务（定时触发与 cron.run 共用），返回本次运行的 runId
+func (r *cronRunner) Fire(ctx context.Context, job CronJob) (string, error) {
+	content := job.Prompt
+	if content == "" {
+		content = job.Label
+	}
+	if content == "" {
+		return "", fmt.Errorf("cron job %s has no prompt or label", job.ID)
+	}
+	runID := "cron-" + uuid.New().String()
+	msg := &bus.InboundMessage{
+		ID:        runID,
+		Channel:   job.Channel,
+		AccountID: job.AccountID,
+		SenderID:  "cron:" + job.ID,
+		ChatID:    job.ChatID,
+		Content:   content,
+		Metadata: map[string]interface{}{
+			"cronJobId": job.ID,
+			"scheduled": true,
+		},
+		Timestamp: time.Now(),
+	}
+	if msg.Channel == "" {
+		msg.Channel = "websocket"
+		msg.ChatID = job.SessionKey
+	}
+	// 按任务的会话运行并由 agent 在 session lane 内写入 prompt（与 steering 排队运行相同）
+	if job.SessionKey != "" {
+		msg.Metadata[bus.MetadataQueuedSession] = job.SessionKey
+	}
+	if err := r.bus.PublishInbound(ctx, msg); err != nil {
+		return "", err
+	}
+	logger.Info("Cron job fired",
+		zap.String("job_id", job.ID),
+		zap.String("session_key", job.SessionKey),
+		zap.String("channel", msg.Channel),
+		zap.String("run_id", runID))
+	return runID, nil
+}
+
+// nextCronFire 按任务的 schedule 与 timezone 计算 now 之后的下次触发时间；永不触发时返回零值
+func nextCronFire(job CronJob, now time.Time) (time.Time, error) {
+	schedule, err := cron.ParseInLocation(job.Schedule, job.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(now), nil
+}
+
+// nextCronWakeMs 已启用任务中最早的下次触发时间（毫秒时间戳），无可触发任务时为 0
+func nextCronWakeMs(jobs []CronJob, now time.Time) int64 {
+	var earliest time.Time
+	for _, job := range jobs {
+		if !job.Enabled {
+			continue
+		}
+		at, err := nextCronFire(job, now)
+		if err != nil || at.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || at.Before(earliest) {
+			earliest = at
+		}
+	}
+	if earliest.IsZero() {
+		return 0
+	}
+	return earliest.UnixMilli()
+}
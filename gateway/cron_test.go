@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/bus"
+)
+
+func TestCronRejectsInvalidSchedule(t *testing.T) {
+	h := NewHandler(nil, nil, nil)
+	h.cronStore = newCronStore(filepath.Join(t.TempDir(), "cron.json"))
+
+	resp := h.HandleRequest("c", &JSONRPCRequest{ID: "1", Method: "cron.add", Params: map[string]interface{}{
+		"schedule": "every tuesday", "prompt": "hi",
+	}})
+	if resp.Error == nil || resp.Error.Code != ErrorInvalidParams {
+		t.Fatalf("cron.add with bad schedule = %+v, want invalid params", resp.Error)
+	}
+
+	resp = h.HandleRequest("c", &JSONRPCRequest{ID: "2", Method: "cron.add", Params: map[string]interface{}{
+		"schedule": "0 9 * * *", "prompt": "hi",
+	}})
+	if resp.Error != nil {
+		t.Fatalf("cron.add: %+v", resp.Error)
+	}
+	jobs, err := h.cronStore.Load()
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("jobs = %v, %v", jobs, err)
+	}
+
+	resp = h.HandleRequest("c", &JSONRPCRequest{ID: "3", Method: "cron.update", Params: map[string]interface{}{
+		"id": jobs[0].ID, "patch": map[string]interface{}{"schedule": "61 * * * *"},
+	}})
+	if resp.Error == nil || resp.Error.Code != ErrorInvalidParams {
+		t.Fatalf("cron.update with bad schedule = %+v, want invalid params", resp.Error)
+	}
+}
+
+func TestCronFirePublishesToJobChannel(t *testing.T) {
+	messageBus := bus.NewMessageBus(4)
+	runner := newCronRunner(newCronStore(filepath.Join(t.TempDir(), "cron.json")), messageBus)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cases := []struct {
+		job       CronJob
+		channel   string
+		chatID    string
+		queuedKey string
+	}{
+		{
+			job:     CronJob{ID: "tg", SessionKey: "agent:main:telegram:42", Channel: "telegram", AccountID: "bot1", ChatID: "42", Prompt: "standup"},
+			channel: "telegram", chatID: "42", queuedKey: "agent:main:telegram:42",
+		},
+		{
+			// 未记录渠道的任务（Control UI 创建）回复进入会话并广播给前端
+			job:     CronJob{ID: "ui", SessionKey: "agent:main:main", Label: "report"},
+			channel: "websocket", chatID: "agent:main:main", queuedKey: "agent:main:main",
+		},
+	}
+	for _, tc := range cases {
+		runID, err := runner.Fire(ctx, tc.job)
+		if err != nil {
+			t.Fatalf("fire %s: %v", tc.job.ID, err)
+		}
+		msg, err := messageBus.ConsumeInbound(ctx)
+		if err != nil {
+			t.Fatalf("consume %s: %v", tc.job.ID, err)
+		}
+		if msg.ID != runID || msg.Channel != tc.channel || msg.ChatID != tc.chatID || msg.AccountID != tc.job.AccountID {
+			t.Fatalf("%s: published %+v", tc.job.ID, msg)
+		}
+		if key, _ := msg.Metadata[bus.MetadataQueuedSession].(string); key != tc.queuedKey {
+			t.Fatalf("%s: queued session = %q, want %q", tc.job.ID, key, tc.queuedKey)
+		}
+	}
+}
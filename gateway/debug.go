@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"strings"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// maxGoroutineGroups debug.goroutines 返回的最多分组数
+const maxGoroutineGroups = 50
+
+// registerPprofHandlers 在 mux 上注册 /debug/pprof/ 处理器（需 control 权限，即携带 websocket.auth_token）
+func (s *Server) registerPprofHandlers(mux *http.ServeMux) {
+	if !s.config.Pprof.Enabled {
+		return
+	}
+	if strings.TrimSpace(s.wsConfig.AuthToken) == "" {
+		logger.Warn("gateway.pprof.enabled is set but websocket auth_token is empty, pprof endpoints disabled")
+		return
+	}
+
+	mux.HandleFunc("/debug/pprof/", s.requireControlAuth(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", s.requireControlAuth(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", s.requireControlAuth(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", s.requireControlAuth(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", s.requireControlAuth(pprof.Trace))
+
+	logger.Info("pprof endpoints enabled", zap.String("path", "/debug/pprof/"))
+}
+
+// requireControlAuth 包装 handler，仅允许携带有效 control token 的请求
+func (s *Server) requireControlAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := s.wsConfig.AuthToken
+		token := requestToken(r)
+		if expected == "" || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// goroutineGroup 按状态与栈顶函数聚合的 goroutine 分组
+type goroutineGroup struct {
+	State    string
+	Function string
+	Count    int
+}
+
+// goroutineSummary 解析 goroutine dump（debug=2 格式），返回总数与按状态、栈顶函数的聚合
+func goroutineSummary() (int, map[string]int, []goroutineGroup) {
+	var buf bytes.Buffer
+	_ = runtimepprof.Lookup("goroutine").WriteTo(&buf, 2)
+	return parseGoroutineDump(buf.String())
+}
+
+// parseGoroutineDump 解析形如 "goroutine 1 [running]:\nmain.main()\n..." 的 dump 文本
+func parseGoroutineDump(dump string) (int, map[string]int, []goroutineGroup) {
+	byState := make(map[string]int)
+	groups := make(map[string]*goroutineGroup)
+	total := 0
+
+	for _, block := range strings.Split(dump, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		if len(lines) == 0 || !strings.HasPrefix(lines[0], "goroutine ") {
+			continue
+		}
+		total++
+		state := "unknown"
+		if i := strings.Index(lines[0], "["); i >= 0 {
+			if j := strings.Index(lines[0][i:], "]"); j > 0 {
+				state = lines[0][i+1 : i+j]
+				// "chan receive, 5 minutes" -> "chan receive"
+				if k := strings.Index(state, ","); k >= 0 {
+					state = state[:k]
+				}
+			}
+		}
+		fn := "unknown"
+		if len(lines) > 1 {
+			fn = strings.TrimSpace(lines[1])
+			if k := strings.LastIndex(fn, "("); k > 0 {
+				fn = fn[:k]
+			}
+		}
+		byState[state]++
+		key := state + "|" + fn
+		if g, ok := groups[key]; ok {
+			g.Count++
+		} else {
+			groups[key] = &goroutineGroup{State: state, Function: fn, Count: 1}
+		}
+	}
+
+	list := make([]goroutineGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Function < list[j].Function
+	})
+	return total, byState, list
+}
+
+// debugMethodsAllowed 调试 RPC 仅在开启 pprof 时、且调用连接以 token 认证并具备该方法的权限（logs 或 admin）时可用
+func (h *Handler) debugMethodsAllowed(connID, method string) bool {
+	cfg := config.Get()
+	if cfg == nil || !cfg.Gateway.Pprof.Enabled {
+		return false
+	}
+	auth, ok := h.callerAuth(connID)
+	return ok && auth.Authenticated && auth.Allows(method)
+}
+
+// registerDebugMethods 注册调试方法（仅 gateway.pprof.enabled 时可用）
+func (h *Handler) registerDebugMethods() {
+	// debug.goroutines - 返回 goroutine dump 摘要，用于排查卡死的 lane / agent 无响应
+	h.registry.Register("debug.goroutines", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		if !h.debugMethodsAllowed(sessionID, "debug.goroutines") {
+			return nil, fmt.Errorf("debug methods are disabled (set gateway.pprof.enabled and connect with an auth token that has the logs scope)")
+		}
+		total, byState, groups := goroutineSummary()
+		limit := maxGoroutineGroups
+		if v, ok := params["limit"].(float64); ok && v > 0 && int(v) < limit {
+			limit = int(v)
+		}
+		if len(groups) > limit {
+			groups = groups[:limit]
+		}
+		top := make([]map[string]interface{}, 0, len(groups))
+		for _, g := range groups {
+			top = append(top, map[string]interface{}{
+				"state":    g.State,
+				"function": g.Function,
+				"count":    g.Count,
+			})
+		}
+		result := map[string]interface{}{
+			"total":   total,
+			"numCPU":  runtime.NumCPU(),
+			"byState": byState,
+			"groups":  top,
+		}
+		if getBool(params, "full", false) {
+			var buf bytes.Buffer
+			_ = runtimepprof.Lookup("goroutine").WriteTo(&buf, 2)
+			result["dump"] = buf.String()
+		}
+		return result, nil
+	})
+}
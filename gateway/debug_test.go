@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestDebugMethodsAllowedPerConnection(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Gateway.Pprof.Enabled = true
+	config.Set(cfg)
+
+	h := NewHandler(nil, nil, nil)
+	h.SetConnectionInfoProvider(testConnections())
+	want := map[string]bool{"control": true, "admin": true, "logs": true, "chat": false, "anonymous": false, "missing": false}
+	for conn, allowed := range want {
+		if got := h.debugMethodsAllowed(conn, "debug.goroutines"); got != allowed {
+			t.Errorf("debugMethodsAllowed(%s) = %v, want %v", conn, got, allowed)
+		}
+	}
+
+	cfg.Gateway.Pprof.Enabled = false
+	if h.debugMethodsAllowed("control", "debug.goroutines") {
+		t.Error("debug methods must stay disabled without gateway.pprof.enabled")
+	}
+}
+
+func TestDebugGoroutinesRejectsUnprivilegedConnection(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	cfg.Gateway.Pprof.Enabled = true
+	config.Set(cfg)
+
+	h := NewHandler(nil, nil, nil)
+	h.SetConnectionInfoProvider(testConnections())
+	if resp := h.HandleRequest("anonymous", &JSONRPCRequest{ID: "1", Method: "debug.goroutines"}); resp.Error == nil {
+		t.Fatal("anonymous connection called debug.goroutines")
+	}
+	if resp := h.HandleRequest("control", &JSONRPCRequest{ID: "2", Method: "debug.goroutines"}); resp.Error != nil {
+		t.Fatalf("control connection rejected: %+v", resp.Error)
+	}
+}
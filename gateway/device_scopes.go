@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 设备 token 的权限范围：read 为只读查询，始终授予；admin 包含全部权限
+const (
+	scopeRead   = "read"
+	scopeChat   = "chat"
+	scopeConfig = "config"
+	scopeLogs   = "logs"
+	scopeAdmin  = "admin"
+)
+
+// knownScopes 可授予设备的权限范围
+var knownScopes = []string{scopeRead, scopeChat, scopeConfig, scopeLogs, scopeAdmin}
+
+// defaultDeviceScopes 配对/轮换未指定 scopes 时授予的只读权限
+var defaultDeviceScopes = []string{scopeRead}
+
+// methodScopes 方法所需的权限范围；空字符串表示公开方法，未列出的方法需要 admin
+var methodScopes = map[string]string{
+	"connect": "",
+	"health":  "",
+
+	"status": scopeRead, "last-heartbeat": scopeRead, "models.list": scopeRead, "system-presence": scopeRead,
+	"connection.info": scopeRead, "node.list": scopeRead, "config.get": scopeRead, "config.schema": scopeRead,
+	"config.diff": scopeRead, "config.validate": scopeRead, "sessions.list": scopeRead, "sessions.get": scopeRead,
+	"sessions.resolve": scopeRead, "sessions.diff": scopeRead, "sessions.export": scopeRead, "sessions.media.get": scopeRead,
+	"sessions.usage": scopeRead, "sessions.usage.timeseries": scopeRead, "sessions.usage.logs": scopeRead,
+	"usage.cost": scopeRead, "usage.live": scopeRead, "chat.history": scopeRead, "chat.events.since": scopeRead,
+	"chat.subscribe": scopeRead, "chat.unsubscribe": scopeRead,
+	"channels.status": scopeRead, "channels.list": scopeRead, "channels.deadletter.list": scopeRead, "agents.list": scopeRead,
+	"agent.identity.get": scopeRead, "agent.wait": scopeRead, "skills.status": scopeRead,
+	"agents.files.list": scopeRead, "agents.files.get": scopeRead, "cron.list": scopeRead,
+	"cron.status": scopeRead, "cron.preview": scopeRead, "exec.approvals.get": scopeRead,
+	"exec.approvals.node.get": scopeRead,
+
+	"chat.send": scopeChat, "chat.abort": scopeChat, "agent": scopeChat, "send": scopeChat,
+	"sessions.patch": scopeChat, "sessions.touch": scopeChat, "sessions.clear": scopeChat,
+	"sessions.merge": scopeChat, "sessions.delete": scopeChat, "sessions.archive": scopeChat, "sessions.unarchive": scopeChat,
+	"channels.deadletter.retry": scopeChat, "exec.approval.resolve": scopeChat,
+
+	"config.set": scopeConfig, "config.apply": scopeConfig, "update.run": scopeConfig,
+	"providers.test": scopeConfig, "skills.update": scopeConfig, "skills.reload": scopeConfig,
+	"skills.install": scopeConfig, "skills.remove": scopeConfig, "agents.files.set": scopeConfig, "cron.add": scopeConfig,
+	"cron.update": scopeConfig, "cron.run": scopeConfig, "cron.remove": scopeConfig,
+	"channels.logout": scopeConfig, "channels.send.test": scopeConfig, "web.login.start": scopeConfig,
+	"web.login.wait": scopeConfig, "exec.approvals.set": scopeConfig, "exec.approvals.node.set": scopeConfig,
+
+	"logs.get": scopeLogs, "logs.tail": scopeLogs, "logs.query": scopeLogs, "debug.goroutines": scopeLogs,
+	"agent.preview": scopeLogs,
+}
+
+// requiredScope 返回方法所需的权限范围
+func requiredScope(method string) string {
+	if scope, ok := methodScopes[method]; ok {
+		return scope
+	}
+	return scopeAdmin
+}
+
+// scopesAllow 判断授予的权限范围能否调用方法；read 随任一权限范围授予，没有任何权限范围（如 token 已吊销的连接）时只能调用公开方法
+func scopesAllow(granted []string, method string) bool {
+	need := requiredScope(method)
+	if need == "" {
+		return true
+	}
+	if need == scopeRead {
+		return len(granted) > 0
+	}
+	return slices.Contains(granted, need) || slices.Contains(granted, scopeAdmin)
+}
+
+// parseScopes 解析 scopes 参数（数组或逗号分隔字符串），去重排序并补上 read；未传时返回 defaultDeviceScopes
+func parseScopes(v interface{}) ([]string, error) {
+	var names []string
+	switch t := v.(type) {
+	case nil:
+		return slices.Clone(defaultDeviceScopes), nil
+	case string:
+		names = strings.Split(t, ",")
+	case []interface{}:
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("scopes must be strings")
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, fmt.Errorf("scopes must be an array of strings")
+	}
+	set := map[string]bool{scopeRead: true}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(knownScopes, name) {
+			return nil, fmt.Errorf("unknown scope %q (valid: %s)", name, strings.Join(knownScopes, ", "))
+		}
+		set[name] = true
+	}
+	scopes := make([]string, 0, len(set))
+	for s := range set {
+		scopes = append(scopes, s)
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestScopesAllow(t *testing.T) {
+	tests := []struct {
+		granted []string
+		method  string
+		want    bool
+	}{
+		{nil, "connect", true},
+		{nil, "health", true},
+		{nil, "sessions.list", false},
+		{[]string{scopeRead}, "sessions.list", true},
+		{[]string{scopeRead}, "chat.send", false},
+		{[]string{scopeRead, scopeChat}, "chat.send", true},
+		{[]string{scopeRead, scopeChat}, "exec.approval.resolve", true},
+		{[]string{scopeRead, scopeChat}, "exec.approvals.set", false},
+		{[]string{scopeRead, scopeConfig}, "exec.approvals.set", true},
+		{[]string{scopeRead, scopeConfig}, "logs.tail", false},
+		{[]string{scopeRead, scopeLogs}, "logs.tail", true},
+		{[]string{scopeRead, scopeLogs}, "device.token.rotate", false},
+		{[]string{scopeAdmin}, "device.token.rotate", true},
+		{[]string{scopeAdmin}, "sessions.list", true},
+		{[]string{scopeAdmin}, "unknown.method", true},
+		{[]string{scopeRead, scopeChat, scopeConfig, scopeLogs}, "unknown.method", false},
+	}
+	for _, tt := range tests {
+		if got := scopesAllow(tt.granted, tt.method); got != tt.want {
+			t.Errorf("scopesAllow(%v, %s) = %v, want %v", tt.granted, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		want    []string
+		wantErr bool
+	}{
+		{in: nil, want: []string{scopeRead}},
+		{in: "", want: []string{scopeRead}},
+		{in: "chat, Logs", want: []string{scopeChat, scopeLogs, scopeRead}},
+		{in: []interface{}{"admin", "chat", "chat"}, want: []string{scopeAdmin, scopeChat, scopeRead}},
+		{in: []interface{}{"read"}, want: []string{scopeRead}},
+		{in: "root", wantErr: true},
+		{in: []interface{}{"chat", 1}, wantErr: true},
+		{in: 42, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseScopes(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseScopes(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("parseScopes(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDevicesStoreAuthenticateAndRotate(t *testing.T) {
+	store := newDevicesStore(filepath.Join(t.TempDir(), "devices.json"))
+	token, err := store.Approve("req-1", "laptop", "operator", []string{scopeRead, scopeChat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Approve("req-2", "phone", "operator", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token      string
+		wantDevice string
+	}{
+		{token, "laptop"},
+		{"", ""},
+		{"goclaw-unknown", ""},
+	}
+	for _, tt := range tests {
+		d, ok := store.Authenticate(tt.token)
+		if ok != (tt.wantDevice != "") || d.DeviceID != tt.wantDevice {
+			t.Errorf("Authenticate(%q) = %+v, %v; want %q", tt.token, d, ok, tt.wantDevice)
+		}
+	}
+
+	// 只存摘要，不存明文 token
+	f, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range f.Paired {
+		if d.TokenHash == token {
+			t.Fatal("plaintext token persisted")
+		}
+	}
+	// 未存 scopes 的记录按只读处理
+	for _, d := range f.Paired {
+		if d.DeviceID == "phone" && !slices.Equal(d.GrantedScopes(), defaultDeviceScopes) {
+			t.Errorf("phone scopes = %v", d.GrantedScopes())
+		}
+	}
+
+	// 未传 scopes 时保留原权限，旧 token 随即失效
+	rotated, granted, err := store.Rotate("laptop", "operator", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(granted, []string{scopeRead, scopeChat}) {
+		t.Errorf("rotate kept scopes = %v", granted)
+	}
+	if _, ok := store.Authenticate(token); ok {
+		t.Error("old token still valid after rotate")
+	}
+	if d, ok := store.Authenticate(rotated); !ok || d.DeviceID != "laptop" {
+		t.Errorf("rotated token = %+v, %v", d, ok)
+	}
+
+	// 传入 scopes 时同时更新权限范围
+	_, granted, err = store.Rotate("phone", "operator", []string{scopeRead, scopeLogs})
+	if err != nil || !slices.Equal(granted, []string{scopeRead, scopeLogs}) {
+		t.Errorf("rotate phone = %v, %v", granted, err)
+	}
+
+	for _, tt := range []struct{ device, role string }{{"tablet", "operator"}, {"laptop", "node"}} {
+		if _, _, err := store.Rotate(tt.device, tt.role, nil); err != errDeviceNotPaired {
+			t.Errorf("Rotate(%s, %s) error = %v, want errDeviceNotPaired", tt.device, tt.role, err)
+		}
+	}
+}
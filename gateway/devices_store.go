@@ -2,25 +2,41 @@ package gateway
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 )
 
+var errDeviceNotPaired = errors.New("device is not paired")
+
 // PendingPairRequest 待批准的配对请求
 type PendingPairRequest struct {
 	RequestID string `json:"requestId"`
 	CreatedAt int64  `json:"createdAt"`
 }
 
-// PairedDevice 已配对设备（不存明文 token）
+// PairedDevice 已配对设备（不存明文 token，仅存 SHA-256 摘要用于连接鉴权）
 type PairedDevice struct {
-	DeviceID  string `json:"deviceId"`
-	Role      string `json:"role"`
-	CreatedAt int64  `json:"createdAt"`
+	DeviceID  string   `json:"deviceId"`
+	Role      string   `json:"role"`
+	Scopes    []string `json:"scopes,omitempty"`
+	TokenHash string   `json:"tokenHash,omitempty"`
+	CreatedAt int64    `json:"createdAt"`
+}
+
+// GrantedScopes 返回设备的权限范围；旧记录未存 scopes 时按只读处理
+func (p PairedDevice) GrantedScopes() []string {
+	if len(p.Scopes) == 0 {
+		return slices.Clone(defaultDeviceScopes)
+	}
+	return p.Scopes
 }
 
 type devicesStore struct {
@@ -96,7 +112,7 @@ func (d *devicesStore) AddPending(requestID string) error {
 	return d.Save(f)
 }
 
-func (d *devicesStore) Approve(requestID, deviceID, role string) (token string, err error) {
+func (d *devicesStore) Approve(requestID, deviceID, role string, scopes []string) (token string, err error) {
 	token, err = generateToken()
 	if err != nil {
 		return "", err
@@ -115,6 +131,8 @@ func (d *devicesStore) Approve(requestID, deviceID, role string) (token string,
 	f.Paired = append(f.Paired, PairedDevice{
 		DeviceID:  deviceID,
 		Role:      role,
+		Scopes:    scopes,
+		TokenHash: hashDeviceToken(token),
 		CreatedAt: time.Now().UnixMilli(),
 	})
 	if err := d.Save(f); err != nil {
@@ -153,13 +171,53 @@ func (d *devicesStore) Revoke(deviceID, role string) error {
 	return d.Save(f)
 }
 
-func (d *devicesStore) Rotate(deviceID, role string) (token string, err error) {
+// Rotate 为已配对设备签发新 token（旧 token 随即失效）；scopes 非空时同时更新权限范围
+func (d *devicesStore) Rotate(deviceID, role string, scopes []string) (token string, granted []string, err error) {
 	token, err = generateToken()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	// 不更新文件：配对记录保留，仅返回新 token 给调用方
-	return token, nil
+	f, err := d.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	for i, p := range f.Paired {
+		if p.DeviceID != deviceID || p.Role != role {
+			continue
+		}
+		if len(scopes) > 0 {
+			f.Paired[i].Scopes = scopes
+		}
+		f.Paired[i].TokenHash = hashDeviceToken(token)
+		if err := d.Save(f); err != nil {
+			return "", nil, err
+		}
+		return token, f.Paired[i].GrantedScopes(), nil
+	}
+	return "", nil, errDeviceNotPaired
+}
+
+// Authenticate 按 token 查找已配对设备
+func (d *devicesStore) Authenticate(token string) (PairedDevice, bool) {
+	if token == "" {
+		return PairedDevice{}, false
+	}
+	f, err := d.Load()
+	if err != nil {
+		return PairedDevice{}, false
+	}
+	hash := hashDeviceToken(token)
+	for _, p := range f.Paired {
+		if p.TokenHash != "" && subtle.ConstantTimeCompare([]byte(p.TokenHash), []byte(hash)) == 1 {
+			return p, true
+		}
+	}
+	return PairedDevice{}, false
+}
+
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func generateToken() (string, error) {
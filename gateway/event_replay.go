@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"strings"
+
+	"github.com/smallnest/goclaw/bus"
+)
+
+// SetEventReplayBuffer 设置 Agent 事件重放缓冲（由 Server 注入），供 chat.events.since 使用
+func (h *Handler) SetEventReplayBuffer(b *bus.ReplayBuffer) {
+	h.eventReplay = b
+}
+
+// eventsSince chat.events.since：{sessionKey, sinceSeq} 返回该会话在 sinceSeq 之后广播过的 agent 事件帧，
+// 帧格式与实时广播一致，客户端重连后可直接按原有逻辑处理；truncated 表示缓冲已淘汰部分事件，应改用 chat.history 重新同步
+func (h *Handler) eventsSince(params map[string]interface{}) (interface{}, error) {
+	sessionKey := strings.TrimSpace(getString(params, "sessionKey"))
+	if sessionKey == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "sessionKey is required")
+	}
+	var sinceSeq uint64
+	if v, ok := params["sinceSeq"].(float64); ok {
+		if v < 0 {
+			return nil, NewRPCError(ErrorInvalidParams, "sinceSeq must not be negative")
+		}
+		sinceSeq = uint64(v)
+	}
+	sessionKey = resolveGatewaySessionKey(sessionKey)
+
+	var events []bus.ReplayEvent
+	truncated := false
+	if h.eventReplay != nil {
+		events, truncated = h.eventReplay.Since(sessionKey, sinceSeq)
+	}
+	frames := make([]map[string]interface{}, 0, len(events))
+	latestSeq := sinceSeq
+	for _, e := range events {
+		frames = append(frames, agentEventFrame(e.Seq, e.Payload))
+		latestSeq = max(latestSeq, e.Seq)
+	}
+	return map[string]interface{}{
+		"sessionKey": sessionKey,
+		"events":     frames,
+		"latestSeq":  latestSeq,
+		"truncated":  truncated,
+	}, nil
+}
+
+// agentEventFrame 构造 agent 事件的广播帧；seq 为 Gateway 全局广播序号，客户端记录最大值用于重连补齐
+func agentEventFrame(seq uint64, payload *bus.AgentEventPayload) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "event",
+		"event":   "agent",
+		"payload": payload,
+		"seq":     seq,
+	}
+}
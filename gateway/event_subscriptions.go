@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSubscribedSessionKeys 每个连接最多订阅的 sessionKey 数
+const maxSubscribedSessionKeys = 100
+
+// EventSubscriber 管理连接的 agent 事件订阅（由 Server 实现），供 chat.subscribe / chat.unsubscribe 使用
+type EventSubscriber interface {
+	SubscribeSessions(connID string, keys []string) ([]string, bool)
+	UnsubscribeSessions(connID string, keys []string, all bool) ([]string, bool)
+}
+
+// SetEventSubscriber 设置 agent 事件订阅管理（由 Server 注入）
+func (h *Handler) SetEventSubscriber(s EventSubscriber) {
+	h.eventSubscriber = s
+}
+
+// sessionKeysParam 读取 sessionKeys（数组）与 sessionKey（单个），按网关规则解析并去重
+func sessionKeysParam(params map[string]interface{}) []string {
+	var raw []string
+	if list, ok := params["sessionKeys"].([]interface{}); ok {
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	if s := getString(params, "sessionKey"); s != "" {
+		raw = append(raw, s)
+	}
+	seen := make(map[string]bool, len(raw))
+	keys := make([]string, 0, len(raw))
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		key := resolveGatewaySessionKey(s)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// chatSubscribe chat.subscribe：{sessionKeys | sessionKey} 订阅这些会话的 agent 事件。
+// 订阅过的连接只接收已订阅会话的 agent 事件，从未订阅的连接仍接收全部（兼容旧客户端）
+func (h *Handler) chatSubscribe(sessionID string, params map[string]interface{}) (interface{}, error) {
+	keys := sessionKeysParam(params)
+	if len(keys) == 0 {
+		return nil, NewRPCError(ErrorInvalidParams, "sessionKeys is required")
+	}
+	if h.eventSubscriber == nil {
+		return nil, NewRPCError(ErrorInvalidRequest, "event subscriptions are not available")
+	}
+	subscribed, ok := h.eventSubscriber.SubscribeSessions(sessionID, keys)
+	if !ok {
+		return nil, NewRPCError(ErrorNotFound, "connection not found")
+	}
+	if len(subscribed) > maxSubscribedSessionKeys {
+		return nil, NewRPCError(ErrorInvalidParams, "too many subscribed sessions (max %d)", maxSubscribedSessionKeys)
+	}
+	return map[string]interface{}{"subscribed": subscribed}, nil
+}
+
+// chatUnsubscribe chat.unsubscribe：{sessionKeys | sessionKey} 或 {all: true} 取消订阅；
+// 取消全部后连接不再接收 agent 事件，直到再次订阅
+func (h *Handler) chatUnsubscribe(sessionID string, params map[string]interface{}) (interface{}, error) {
+	keys := sessionKeysParam(params)
+	all := getBool(params, "all", false)
+	if len(keys) == 0 && !all {
+		return nil, NewRPCError(ErrorInvalidParams, "sessionKeys or all is required")
+	}
+	if h.eventSubscriber == nil {
+		return nil, NewRPCError(ErrorInvalidRequest, "event subscriptions are not available")
+	}
+	subscribed, ok := h.eventSubscriber.UnsubscribeSessions(sessionID, keys, all)
+	if !ok {
+		return nil, NewRPCError(ErrorNotFound, "connection not found")
+	}
+	return map[string]interface{}{"subscribed": subscribed}, nil
+}
+
+// SubscribeSessions 为连接添加订阅；超过上限时不修改并返回合并后的列表，由调用方报错
+func (s *Server) SubscribeSessions(connID string, keys []string) ([]string, bool) {
+	conn, ok := s.connection(connID)
+	if !ok {
+		return nil, false
+	}
+	return conn.subscribe(keys), true
+}
+
+// UnsubscribeSessions 取消连接的订阅
+func (s *Server) UnsubscribeSessions(connID string, keys []string, all bool) ([]string, bool) {
+	conn, ok := s.connection(connID)
+	if !ok {
+		return nil, false
+	}
+	return conn.unsubscribe(keys, all), true
+}
+
+func (s *Server) connection(id string) (*Connection, bool) {
+	s.connectionsMu.RLock()
+	defer s.connectionsMu.RUnlock()
+	conn, ok := s.connections[id]
+	return conn, ok
+}
+
+func (c *Connection) subscribe(keys []string) []string {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	merged := make(map[string]bool, len(c.subscriptions)+len(keys))
+	for k := range c.subscriptions {
+		merged[k] = true
+	}
+	for _, k := range keys {
+		merged[k] = true
+	}
+	if len(merged) <= maxSubscribedSessionKeys {
+		c.subscriptions = merged
+	}
+	return sortedKeys(merged)
+}
+
+func (c *Connection) unsubscribe(keys []string, all bool) []string {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	if c.subscriptions == nil {
+		// 未订阅过的连接取消订阅后进入过滤模式，不再接收全部事件
+		c.subscriptions = make(map[string]bool)
+	}
+	if all {
+		clear(c.subscriptions)
+	}
+	for _, k := range keys {
+		delete(c.subscriptions, k)
+	}
+	return sortedKeys(c.subscriptions)
+}
+
+// wantsAgentEvent 连接是否接收该会话的 agent 事件：未订阅过的连接接收全部
+func (c *Connection) wantsAgentEvent(sessionKey string) bool {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	return c.subscriptions == nil || c.subscriptions[sessionKey]
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"fmt"
+	"slices"
+)
+
+// execAllowlistKey exec-approvals 文件中记住的审批键列表（exec.approval.resolve remember: true 写入，exec 为 "exec:<命令>"）
+const execAllowlistKey = "allowlist"
+
+// SetApprovalResolver 设置工具审批回调（由 AgentManager.ResolveToolApproval 提供），exec.approval.resolve 用于放行或拒绝等待中的工具执行
+func (h *Handler) SetApprovalResolver(resolver func(approvalID string, approved bool) (toolName, key string, err error)) {
+	h.approvalResolver = resolver
+}
+
+// ExecAllowlisted 审批键是否已记在 exec-approvals 文件的 allowlist 中（无需再次审批）
+func (h *Handler) ExecAllowlisted(key string) bool {
+	f, err := h.execApprovalsStore.Load()
+	if err != nil {
+		return false
+	}
+	return slices.Contains(execAllowlist(f.File), key)
+}
+
+// execAllowlist 读取 exec-approvals 文件中的 allowlist
+func execAllowlist(file map[string]interface{}) []string {
+	raw, _ := file[execAllowlistKey].([]interface{})
+	names := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok && s != "" {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+// rememberExecApproval 将审批键追加到 exec-approvals 文件的 allowlist
+func (h *Handler) rememberExecApproval(key string) error {
+	f, err := h.execApprovalsStore.Load()
+	if err != nil {
+		return err
+	}
+	names := execAllowlist(f.File)
+	if slices.Contains(names, key) {
+		return nil
+	}
+	list := make([]interface{}, 0, len(names)+1)
+	for _, n := range append(names, key) {
+		list = append(list, n)
+	}
+	f.File[execAllowlistKey] = list
+	return h.execApprovalsStore.Save(f)
+}
+
+// resolveExecApproval exec.approval.resolve：{approvalId, approved, remember}，将决定投递给等待中的工具执行
+func (h *Handler) resolveExecApproval(params map[string]interface{}) (interface{}, error) {
+	approvalID := getString(params, "approvalId")
+	if approvalID == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "approvalId is required")
+	}
+	approved, ok := params["approved"].(bool)
+	if !ok {
+		return nil, NewRPCError(ErrorInvalidParams, "approved (boolean) is required")
+	}
+	remember := getBool(params, "remember", false)
+	if h.approvalResolver == nil {
+		return nil, NewRPCError(ErrorNotFound, "approval not found: %s", approvalID)
+	}
+	toolName, key, err := h.approvalResolver(approvalID, approved)
+	if err != nil {
+		return nil, NewRPCError(ErrorNotFound, "%v", err)
+	}
+	remembered := false
+	if remember && approved {
+		if err := h.rememberExecApproval(key); err != nil {
+			return nil, fmt.Errorf("failed to remember approval for %s: %w", key, err)
+		}
+		remembered = true
+	}
+	return map[string]interface{}{
+		"ok":         true,
+		"approvalId": approvalID,
+		"approved":   approved,
+		"name":       toolName,
+		"key":        key,
+		"remembered": remembered,
+	}, nil
+}
@@ -3,13 +3,15 @@ package gateway
 import (
 	"bufio"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,8 +19,13 @@ import (
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/channels"
 	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/cron"
+	"github.com/smallnest/goclaw/internal"
+	"github.com/smallnest/goclaw/internal/diskspace"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
+	"github.com/smallnest/goclaw/skills"
 	"go.uber.org/zap"
 )
 
@@ -35,11 +42,29 @@ type Handler struct {
 	channelMgr        *channels.Manager
 	sessionPolicy     *session.ResetPolicy // 可选：与 OpenClaw 对齐，不新鲜会话自动重置
 	cronStore         *cronStore
+	cronRunner        *cronRunner
 	devicesStore      *devicesStore
 	execApprovalsStore *execApprovalsStore
 	skillsStore       *skillsStore
 	presenceProvider  PresenceProvider
+	connInfoProvider  ConnectionInfoProvider
 	lastHeartbeatGetter func() int64
+	skillsReloader    func(disabled []string, apiKeys map[string]string) error
+	runSteerer        func(sessionKey, message string) (runID string, ok bool)
+	runPreviewer      func(ctx context.Context, sessionKey, content string) (string, error)
+	runStatsProvider  func() interface{}
+	providerHealth    func() (providers.PreflightResult, bool)
+	agentLister       func() []string
+	sessionMerger     func(sourceKey, targetKey, strategy string, deleteSource bool) (interface{}, error)
+	runAborter        func(sessionKey string) []string
+	sessionRuns       func(sessionKey string) []string
+	approvalResolver  func(approvalID string, approved bool) (toolName, key string, err error)
+	toolNamesProvider func() []string
+	eventReplay       *bus.ReplayBuffer
+	eventSubscriber   EventSubscriber
+	deviceRevoker     DeviceConnectionRevoker
+	appVersion        string
+	startedAt         time.Time
 }
 
 // SetSessionResetPolicy 设置会话重置策略（由 Server 在启动时根据 config.session.reset 注入）
@@ -52,6 +77,79 @@ func (h *Handler) SetPresenceProvider(p PresenceProvider) {
 	h.presenceProvider = p
 }
 
+// SetConnectionInfoProvider 设置连接元数据来源（由 Server 在启动后注入），供 connection.info 使用
+func (h *Handler) SetConnectionInfoProvider(p ConnectionInfoProvider) {
+	h.connInfoProvider = p
+}
+
+// SetSkillsReloader 设置技能重载回调（由 AgentManager.ReloadSkills 提供），skills.update / skills.reload 时以禁用列表与 API key 调用
+func (h *Handler) SetSkillsReloader(reloader func(disabled []string, apiKeys map[string]string) error) {
+	h.skillsReloader = reloader
+}
+
+// SetRunSteerer 设置会话 steering 回调（由 AgentManager.SteerActiveRun 提供），chat.send 传 steer: true 时用于中断正在执行的 Run
+func (h *Handler) SetRunSteerer(steerer func(sessionKey, message string) (runID string, ok bool)) {
+	h.runSteerer = steerer
+}
+
+// SetRunPreviewer 设置 dry-run 回调（由 AgentManager.PreviewRun 提供），供 agent.preview 使用
+func (h *Handler) SetRunPreviewer(previewer func(ctx context.Context, sessionKey, content string) (string, error)) {
+	h.runPreviewer = previewer
+}
+
+// ReloadSkills 按 skills overlay 中的 enabled / apiKey 重载运行中 agent 的技能；未注入 reloader 时为空操作
+func (h *Handler) ReloadSkills() error {
+	if h.skillsReloader == nil {
+		return nil
+	}
+	overlays, err := h.skillsStore.Load()
+	if err != nil {
+		return err
+	}
+	disabled := make([]string, 0)
+	apiKeys := make(map[string]string)
+	for key, o := range overlays {
+		if !o.IsEnabled() {
+			disabled = append(disabled, key)
+		}
+		if o.APIKey != "" {
+			apiKeys[key] = o.APIKey
+		}
+	}
+	sort.Strings(disabled)
+	return h.skillsReloader(disabled, apiKeys)
+}
+
+// SetRunStatsProvider 设置各 Agent 活跃 / 排队 Run 数的数据源（由 AgentManager.ActiveRunStats 提供），status 中展示
+func (h *Handler) SetRunStatsProvider(provider func() interface{}) {
+	h.runStatsProvider = provider
+}
+
+// SetProviderHealthProvider 设置模型提供商可用性的数据源（由 AgentManager.ProviderHealth 提供），status / node.list 中展示
+func (h *Handler) SetProviderHealthProvider(provider func() (providers.PreflightResult, bool)) {
+	h.providerHealth = provider
+}
+
+// SetAgentLister 设置已加载 Agent 列表的数据源（由 AgentManager.ListAgents 提供），/health 与 /ready 中展示
+func (h *Handler) SetAgentLister(lister func() []string) {
+	h.agentLister = lister
+}
+
+// SetSessionMerger 设置会话合并回调（由 AgentManager.MergeSessions 提供），供 sessions.merge 使用
+func (h *Handler) SetSessionMerger(merger func(sourceKey, targetKey, strategy string, deleteSource bool) (interface{}, error)) {
+	h.sessionMerger = merger
+}
+
+// SetRunAborter 设置中止会话 Run 的回调（由 AgentManager.AbortSessionRuns 提供），供 chat.abort 使用
+func (h *Handler) SetRunAborter(aborter func(sessionKey string) []string) {
+	h.runAborter = aborter
+}
+
+// SetSessionRunLister 设置查询会话已提交 Run 的回调（由 AgentManager.SessionRunIDs 提供），sessions.archive 据此拒绝归档运行中的会话
+func (h *Handler) SetSessionRunLister(lister func(sessionKey string) []string) {
+	h.sessionRuns = lister
+}
+
 // SetLastHeartbeat 设置最后心跳时间获取函数（由 Server 在启动后注入）
 func (h *Handler) SetLastHeartbeat(getter func() int64) {
 	h.lastHeartbeatGetter = getter
@@ -124,6 +222,29 @@ func (h *Handler) getSession(key string) (*session.Session, error) {
 	return h.sessionMgr.GetOrCreate(canonical)
 }
 
+// lookupSession 只读获取会话：活动会话（不按重置策略重置、不存在时不创建），否则读取归档会话；都不存在时返回 not_found
+func (h *Handler) lookupSession(key string) (*session.Session, error) {
+	canonical := resolveGatewaySessionKey(key)
+	if canonical == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "session key is required")
+	}
+	sess, err := h.sessionMgr.Lookup(canonical)
+	if err == nil {
+		return sess, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load session %s: %w", canonical, err)
+	}
+	sess, err = h.sessionMgr.LoadArchived(canonical)
+	if err == nil {
+		return sess, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load archived session %s: %w", canonical, err)
+	}
+	return nil, NewRPCError(ErrorNotFound, "session not found: %s", canonical)
+}
+
 // buildConnectSnapshot 构造 connect 返回的 snapshot，与 OpenClaw 对齐：含 sessionDefaults，
 // 且按 OpenClaw 规则「一个 agent 一个主会话」：scope=global 时为 "global"，否则为 agent:<agentId>:<mainKey>。
 func buildConnectSnapshot() map[string]interface{} {
@@ -166,6 +287,71 @@ func buildConnectSnapshot() map[string]interface{} {
 	return snapshot
 }
 
+// connectSnapshotSections connect snapshot 可选段落 -> 提供数据的 RPC（顺序即 "full" 时的填充顺序）
+var connectSnapshotSections = []struct {
+	name   string
+	method string
+}{
+	{"sessions", "sessions.list"},
+	{"agents", "agents.list"},
+	{"channels", "channels.status"},
+	{"models", "models.list"},
+}
+
+// parseConnectSnapshotSections 解析 connect 的 snapshot 参数："full" 表示全部段落；
+// 也可传段落名数组或逗号分隔字符串（如 "sessions,models"）；缺省或 "minimal" 仅返回 sessionDefaults
+func parseConnectSnapshotSections(v interface{}) map[string]bool {
+	sections := make(map[string]bool)
+	var names []string
+	switch t := v.(type) {
+	case string:
+		names = strings.Split(t, ",")
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "full" {
+			for _, sec := range connectSnapshotSections {
+				sections[sec.name] = true
+			}
+			continue
+		}
+		if name != "" && name != "minimal" {
+			sections[name] = true
+		}
+	}
+	return sections
+}
+
+// fillConnectSnapshotSections 按请求的段落调用对应 RPC 并写入 snapshot；与直接调用该 RPC 相同经过 authorize，
+// 连接无权调用的段落省略。单个段落失败只记日志，不影响握手
+func (h *Handler) fillConnectSnapshotSections(sessionID string, snapshot map[string]interface{}, sections map[string]bool) {
+	for _, sec := range connectSnapshotSections {
+		if !sections[sec.name] {
+			continue
+		}
+		if !h.authorize(sessionID, sec.method) {
+			logger.Debug("connect snapshot section not authorized",
+				zap.String("section", sec.name),
+				zap.String("connection_id", sessionID))
+			continue
+		}
+		result, err := h.registry.Call(sec.method, sessionID, map[string]interface{}{})
+		if err != nil {
+			logger.Warn("connect snapshot section failed",
+				zap.String("section", sec.name),
+				zap.Error(err))
+			continue
+		}
+		snapshot[sec.name] = result
+	}
+}
+
 // NewHandler 创建处理器
 func NewHandler(messageBus *bus.MessageBus, sessionMgr *session.Manager, channelMgr *channels.Manager) *Handler {
 	h := &Handler{
@@ -177,7 +363,9 @@ func NewHandler(messageBus *bus.MessageBus, sessionMgr *session.Manager, channel
 		devicesStore:       newDevicesStore(""),
 		execApprovalsStore: newExecApprovalsStore(""),
 		skillsStore:        newSkillsStore(""),
+		startedAt:          time.Now(),
 	}
+	h.cronRunner = newCronRunner(h.cronStore, messageBus)
 
 	// 注册系统方法
 	h.registerSystemMethods()
@@ -191,11 +379,20 @@ func NewHandler(messageBus *bus.MessageBus, sessionMgr *session.Manager, channel
 	// 注册 Browser 方法
 	h.registerBrowserMethods()
 
+	// 注册调试方法
+	h.registerDebugMethods()
+
 	return h
 }
 
 // HandleRequest 处理请求。sessionID 为 WebSocket 连接 ID（与聊天 sessionKey 无关），仅用于日志与追踪。
 func (h *Handler) HandleRequest(sessionID string, req *JSONRPCRequest) *JSONRPCResponse {
+	if !h.authorize(sessionID, req.Method) {
+		logger.Warn("Unauthorized RPC method",
+			zap.String("method", req.Method),
+			zap.String("connection_id", sessionID))
+		return NewErrorResponse(req.ID, ErrorInvalidRequest, fmt.Sprintf("unauthorized for method %s", req.Method))
+	}
 	result, err := h.registry.Call(req.Method, sessionID, req.Params)
 	if err != nil {
 		logger.Error("Method execution failed",
@@ -203,7 +400,10 @@ func (h *Handler) HandleRequest(sessionID string, req *JSONRPCRequest) *JSONRPCR
 			zap.String("connection_id", sessionID),
 			zap.Error(err))
 		code := ErrorInternalError
-		if strings.Contains(err.Error(), "method not found") {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			code = rpcErr.Code
+		} else if strings.Contains(err.Error(), "method not found") {
 			code = ErrorMethodNotFound
 		}
 		return NewErrorResponse(req.ID, code, err.Error())
@@ -219,24 +419,27 @@ func (h *Handler) registerSystemMethods() {
 	h.registry.Register("connect", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		// 已实现的 method 列表，供前端 features.methods 能力检测
 		methods := []string{
-			"connect", "config.get", "config.set", "config.schema", "config.apply", "update.run",
-			"health", "status", "last-heartbeat", "models.list",
-			"sessions.list", "sessions.patch", "sessions.delete", "sessions.get", "sessions.clear",
-			"sessions.usage", "sessions.usage.timeseries", "sessions.usage.logs", "usage.cost",
-			"chat.send", "chat.history", "chat.abort",
-			"channels.status", "channels.list", "channels.logout",
+			"connect", "config.get", "config.set", "config.validate", "config.diff", "config.schema", "config.apply", "update.run",
+			"health", "status", "last-heartbeat", "models.list", "providers.test",
+			"sessions.list", "sessions.patch", "sessions.delete", "sessions.archive", "sessions.unarchive", "sessions.get", "sessions.clear", "sessions.touch", "sessions.merge", "sessions.diff", "sessions.export", "sessions.media.get",
+			"sessions.usage", "sessions.usage.timeseries", "sessions.usage.logs", "usage.cost", "usage.live",
+			"chat.send", "chat.history", "chat.abort", "chat.events.since", "chat.subscribe", "chat.unsubscribe",
+			"channels.status", "channels.list", "channels.logout", "channels.send.test", "channels.deadletter.list", "channels.deadletter.retry",
 			"web.login.start", "web.login.wait",
-			"agents.list", "agent.identity.get", "skills.status", "skills.update", "skills.install",
+			"agents.list", "agent.identity.get", "skills.status", "skills.update", "skills.reload", "skills.install", "skills.remove",
 			"agents.files.list", "agents.files.get", "agents.files.set",
-			"logs.get", "logs.tail",
-			"cron.list", "cron.status", "cron.add", "cron.update", "cron.run", "cron.remove",
-			"system-presence",
+			"logs.get", "logs.tail", "logs.query",
+			"cron.list", "cron.status", "cron.add", "cron.update", "cron.run", "cron.remove", "cron.preview",
+			"system-presence", "connection.info",
 			"device.pair.list", "device.pair.approve", "device.pair.reject", "device.token.revoke", "device.token.rotate",
 			"node.list",
 			"exec.approvals.get", "exec.approvals.set", "exec.approvals.node.get", "exec.approvals.node.set", "exec.approval.resolve",
-			"agent", "agent.wait", "send", "browser.request",
+			"agent", "agent.wait", "agent.preview", "send", "browser.request",
+			"debug.goroutines",
 		}
 		snapshot := buildConnectSnapshot()
+		// 可选：connect 参数 snapshot 为 "full" 或段落列表时，在 snapshot 中附带 sessions/agents/channels/models，减少冷启动往返
+		h.fillConnectSnapshotSections(sessionID, snapshot, parseConnectSnapshotSections(params["snapshot"]))
 		hello := map[string]interface{}{
 			"type":     "hello-ok",
 			"protocol": 3,
@@ -246,18 +449,20 @@ func (h *Handler) registerSystemMethods() {
 			},
 			"snapshot": snapshot,
 		}
-		// 可选：若前端传了 auth 且验证通过，可返回 auth.deviceToken 等；当前不签发
+		// auth：本连接的角色与权限范围（握手携带 auth.token 时已按该 token 重新认证），前端可据此隐藏无权调用的功能
+		if h.connInfoProvider != nil {
+			if info, ok := h.connInfoProvider.ConnectionInfo(sessionID); ok {
+				hello["auth"] = AuthContext{Authenticated: info.Authenticated, Role: info.Role, Scopes: info.Scopes}
+			}
+		}
 		return hello, nil
 	})
 
 	// config.get - 获取配置
 	h.registry.Register("config.get", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		// 当前仅支持返回完整配置（原始 JSON 字符串）
-		// 兼容旧参数：如果 key 存在且不是 "raw"，先忽略，后续可以扩展为按 key 读取
-		if key, ok := params["key"].(string); ok && key != "" && key != "raw" {
-			logger.Warn("config.get: key parameter is currently ignored, returning full config",
-				zap.String("key", key))
-		}
+		// key 为空或 "raw" 时返回完整配置；否则按点路径（如 gateway.port、agents.list.0.id）读取单个值
+		key, _ := params["key"].(string)
+		key = strings.TrimSpace(key)
 
 		// 优先使用内存中的配置
 		cfg := config.Get()
@@ -278,24 +483,26 @@ func (h *Handler) registerSystemMethods() {
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal config: %w", err)
 		}
+		var configMap map[string]interface{}
+		_ = json.Unmarshal(raw, &configMap)
+
+		if key != "" && key != "raw" {
+			value, ok := lookupConfigPath(configMap, key)
+			if !ok {
+				return nil, NewRPCError(ErrorNotFound, "config path not found: %s", key)
+			}
+			return map[string]interface{}{"key": key, "value": value}, nil
+		}
 
 		path, err := config.GetDefaultConfigPath()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get default config path: %w", err)
 		}
 
-		hashBytes := sha256.Sum256(raw)
-		hash := hex.EncodeToString(hashBytes[:])
+		hash := configHash(raw)
 		_, statErr := os.Stat(path)
 		exists := statErr == nil
-		valid := true
-		var issues []map[string]interface{}
-		if err := config.Validate(cfg); err != nil {
-			valid = false
-			issues = append(issues, map[string]interface{}{"path": "config", "message": err.Error()})
-		}
-		var configMap map[string]interface{}
-		_ = json.Unmarshal(raw, &configMap)
+		valid, issues := configIssues(cfg)
 
 		return map[string]interface{}{
 			"path":   path,
@@ -320,24 +527,8 @@ func (h *Handler) registerSystemMethods() {
 			return nil, fmt.Errorf("failed to get default config path: %w", err)
 		}
 
-		if baseHash, _ := params["baseHash"].(string); baseHash != "" {
-			cur := config.Get()
-			var curRaw []byte
-			if cur != nil {
-				curRaw, _ = json.MarshalIndent(cur, "", "  ")
-			} else {
-				data, err := os.ReadFile(path)
-				if err == nil {
-					curRaw = data
-				}
-			}
-			if len(curRaw) > 0 {
-				sum := sha256.Sum256(curRaw)
-				currentHash := hex.EncodeToString(sum[:])
-				if currentHash != baseHash {
-					return nil, fmt.Errorf("config changed (baseHash mismatch); reload and retry")
-				}
-			}
+		if err := checkConfigBaseHash(getString(params, "baseHash"), path); err != nil {
+			return nil, err
 		}
 
 		var cfg config.Config
@@ -374,6 +565,16 @@ func (h *Handler) registerSystemMethods() {
 		}, nil
 	})
 
+	// config.validate - 校验候选配置（不写入），返回字段级问题列表 {path, severity, message}
+	h.registry.Register("config.validate", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.validateConfig(params)
+	})
+
+	// config.diff - 预览候选配置相对当前配置的变更（含校验结果），不写入
+	h.registry.Register("config.diff", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.configDiff(params)
+	})
+
 	// config.apply - 应用配置并重载（与 config.set 类似，可选 sessionKey 提示）
 	h.registry.Register("config.apply", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		raw, ok := params["raw"].(string)
@@ -384,23 +585,8 @@ func (h *Handler) registerSystemMethods() {
 		if err != nil {
 			return nil, fmt.Errorf("failed to get default config path: %w", err)
 		}
-		if baseHash, _ := params["baseHash"].(string); baseHash != "" {
-			cur := config.Get()
-			var curRaw []byte
-			if cur != nil {
-				curRaw, _ = json.MarshalIndent(cur, "", "  ")
-			} else {
-				data, err := os.ReadFile(path)
-				if err == nil {
-					curRaw = data
-				}
-			}
-			if len(curRaw) > 0 {
-				sum := sha256.Sum256(curRaw)
-				if hex.EncodeToString(sum[:]) != baseHash {
-					return nil, fmt.Errorf("config changed (baseHash mismatch); reload and retry")
-				}
-			}
+		if err := checkConfigBaseHash(getString(params, "baseHash"), path); err != nil {
+			return nil, err
 		}
 		var cfg config.Config
 		if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
@@ -430,7 +616,7 @@ func (h *Handler) registerSystemMethods() {
 		return map[string]interface{}{"ok": true}, nil
 	})
 
-	// sessions.usage - 从会话列表汇总用量（消息数、估算 token）
+	// sessions.usage - 从会话列表汇总用量（消息数、token）：按历史内容估算 token（按角色拆分输入 / 输出），有 provider 上报的用量时另返回实际值
 	h.registry.Register("sessions.usage", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		keys, err := h.sessionMgr.List()
 		if err != nil {
@@ -448,14 +634,23 @@ func (h *Handler) registerSystemMethods() {
 			if err != nil {
 				continue
 			}
-			msgCount := len(sess.Messages)
-			estTokens := msgCount * 100 // 粗略估算
+			history := sess.GetHistory(-1)
+			est := session.EstimateHistoryTokens(history)
 			row := map[string]interface{}{
 				"key":         key,
-				"messageCount": msgCount,
-				"estimatedTokens": estTokens,
+				"messageCount": len(history),
+				"estimatedTokens": est.Total,
+				"inputTokens": est.Input,
+				"outputTokens": est.Output,
+				"tokensByRole": est.ByRole,
 				"updatedAtMs": sess.UpdatedAt.UnixMilli(),
 			}
+			if usage, ok := sess.TokenUsage(); ok {
+				row["promptTokens"] = usage.PromptTokens
+				row["completionTokens"] = usage.CompletionTokens
+				row["totalTokens"] = usage.TotalTokens
+				row["usageEstimated"] = usage.Estimated()
+			}
 			if startDate != "" || endDate != "" {
 				// 简单按日期过滤：用 updatedAt 日期
 				row["updatedAt"] = sess.UpdatedAt.Format("2006-01-02")
@@ -472,16 +667,35 @@ func (h *Handler) registerSystemMethods() {
 		}
 		return map[string]interface{}{"sessions": sessions, "ts": time.Now().UnixMilli()}, nil
 	})
+	// sessions.usage.timeseries - 按消息时间戳分桶（interval: hour / day / week，默认 day）统计消息数与估算 token；key 为空时汇总所有会话
 	h.registry.Register("sessions.usage.timeseries", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		key, _ := params["key"].(string)
-		return map[string]interface{}{"key": key, "points": []interface{}{}}, nil
+		interval, err := parseUsageInterval(params)
+		if err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+		}
+		points, err := h.computeUsageTimeseries(strings.TrimSpace(key), interval)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"key": key, "interval": interval, "points": points}, nil
 	})
 	h.registry.Register("sessions.usage.logs", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		key, _ := params["key"].(string)
 		return map[string]interface{}{"key": key, "logs": []interface{}{}}, nil
 	})
+	// usage.cost - 按价格表估算各会话费用，按模型 / 提供商汇总；startDate / endDate（YYYY-MM-DD）按消息时间过滤
 	h.registry.Register("usage.cost", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		return map[string]interface{}{"total": 0, "byModel": map[string]interface{}{}, "byProvider": map[string]interface{}{}}, nil
+		start, end, err := parseUsageDateRange(params)
+		if err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+		}
+		return h.computeUsageCost(start, end)
+	})
+
+	// usage.live - 启动以来各 provider/model 的累计 token 与当日预算状态
+	h.registry.Register("usage.live", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return providers.DefaultUsageTracker().Snapshot(), nil
 	})
 
 	// cron - 使用文件存储
@@ -493,8 +707,9 @@ func (h *Handler) registerSystemMethods() {
 		out := make([]interface{}, 0, len(jobs))
 		for _, j := range jobs {
 			out = append(out, map[string]interface{}{
-				"id": j.ID, "schedule": j.Schedule, "sessionKey": j.SessionKey,
-				"enabled": j.Enabled, "label": j.Label, "createdAt": j.CreatedAt,
+				"id": j.ID, "schedule": j.Schedule, "timezone": j.Timezone, "sessionKey": j.SessionKey,
+				"enabled": j.Enabled, "label": j.Label, "prompt": j.Prompt, "createdAt": j.CreatedAt,
+				"deleteAfterRun": j.DeleteAfterRun,
 			})
 		}
 		return map[string]interface{}{"jobs": out}, nil
@@ -504,21 +719,34 @@ func (h *Handler) registerSystemMethods() {
 		if err != nil {
 			return map[string]interface{}{"jobs": []interface{}{}, "nextWakeAtMs": int64(0)}, nil
 		}
+		now := time.Now()
 		out := make([]interface{}, 0, len(jobs))
 		for _, j := range jobs {
+			var nextRunAtMs int64
+			if at, err := nextCronFire(j, now); j.Enabled && err == nil && !at.IsZero() {
+				nextRunAtMs = at.UnixMilli()
+			}
 			out = append(out, map[string]interface{}{
-				"id": j.ID, "schedule": j.Schedule, "sessionKey": j.SessionKey,
-				"enabled": j.Enabled, "label": j.Label, "createdAt": j.CreatedAt,
+				"id": j.ID, "schedule": j.Schedule, "timezone": j.Timezone, "sessionKey": j.SessionKey,
+				"enabled": j.Enabled, "label": j.Label, "prompt": j.Prompt, "createdAt": j.CreatedAt,
+				"deleteAfterRun": j.DeleteAfterRun, "nextRunAtMs": nextRunAtMs,
 			})
 		}
-		return map[string]interface{}{"jobs": out, "nextWakeAtMs": int64(0)}, nil
+		return map[string]interface{}{"jobs": out, "nextWakeAtMs": nextCronWakeMs(jobs, now)}, nil
 	})
 	h.registry.Register("cron.add", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		job := CronJob{
 			Schedule:   getString(params, "schedule"),
+			Timezone:   getString(params, "timezone"),
 			SessionKey: getString(params, "sessionKey"),
 			Enabled:    getBool(params, "enabled", true),
 			Label:      getString(params, "label"),
+			Prompt:     getString(params, "prompt"),
+
+			DeleteAfterRun: getBool(params, "deleteAfterRun", false),
+		}
+		if _, err := cron.ParseInLocation(job.Schedule, job.Timezone); err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "%v", err)
 		}
 		added, err := h.cronStore.Add(job)
 		if err != nil {
@@ -535,14 +763,41 @@ func (h *Handler) registerSystemMethods() {
 		if patch == nil {
 			patch = map[string]interface{}{}
 		}
+		if tz, ok := patch["timezone"].(string); ok {
+			if _, err := cron.LoadLocation(tz); err != nil {
+				return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+			}
+		}
+		if spec, ok := patch["schedule"].(string); ok {
+			if _, err := cron.Parse(spec); err != nil {
+				return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+			}
+		}
 		if err := h.cronStore.Update(id, patch); err != nil {
 			return nil, err
 		}
 		return map[string]interface{}{"ok": true}, nil
 	})
+	// cron.run - 立即执行任务（与定时触发同一路径，不要求任务已启用）
 	h.registry.Register("cron.run", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		_, _ = params["id"].(string)
-		return map[string]interface{}{"ok": true}, nil
+		id, _ := params["id"].(string)
+		if id == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "id is required")
+		}
+		jobs, err := h.cronStore.Load()
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range jobs {
+			if j.ID == id {
+				runID, err := h.cronRunner.Fire(context.Background(), j)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"ok": true, "runId": runID}, nil
+			}
+		}
+		return nil, NewRPCError(ErrorNotFound, "cron job not found: %s", id)
 	})
 	h.registry.Register("cron.remove", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		id, _ := params["id"].(string)
@@ -554,6 +809,34 @@ func (h *Handler) registerSystemMethods() {
 		}
 		return map[string]interface{}{"ok": true}, nil
 	})
+	// cron.preview - 校验调度表达式并预览接下来的触发时间
+	h.registry.Register("cron.preview", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		spec := getString(params, "schedule")
+		if strings.TrimSpace(spec) == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "schedule is required")
+		}
+		count := 5
+		if c, ok := params["count"].(float64); ok && c > 0 {
+			count = min(int(c), 100)
+		}
+		loc, err := cron.LoadLocation(getString(params, "timezone"))
+		if err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+		}
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "invalid schedule: %v", err)
+		}
+		runs := cron.NextRuns(schedule, time.Now().In(loc), count)
+		if len(runs) == 0 {
+			return nil, NewRPCError(ErrorInvalidParams, "schedule never fires: %s", spec)
+		}
+		next := make([]string, 0, len(runs))
+		for _, t := range runs {
+			next = append(next, t.Format(time.RFC3339))
+		}
+		return map[string]interface{}{"schedule": spec, "timezone": loc.String(), "next": next}, nil
+	})
 
 	// system-presence - 从 Server 获取当前连接列表
 	h.registry.Register("system-presence", func(sessionID string, params map[string]interface{}) (interface{}, error) {
@@ -566,6 +849,29 @@ func (h *Handler) registerSystemMethods() {
 		return map[string]interface{}{"entries": entries}, nil
 	})
 
+	// connection.info - 返回调用方连接的元数据；control 角色可通过 id 查询其他连接
+	h.registry.Register("connection.info", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		if h.connInfoProvider == nil {
+			return nil, fmt.Errorf("connection info not available")
+		}
+		self, ok := h.connInfoProvider.ConnectionInfo(sessionID)
+		if !ok {
+			return nil, NewRPCError(ErrorNotFound, "connection not found: %s", sessionID)
+		}
+		id, _ := params["id"].(string)
+		if id == "" || id == sessionID {
+			return map[string]interface{}{"self": true, "connection": self}, nil
+		}
+		if self.Role != connRoleControl {
+			return nil, NewRPCError(ErrorInvalidRequest, "querying other connections requires control role")
+		}
+		other, ok := h.connInfoProvider.ConnectionInfo(id)
+		if !ok {
+			return nil, NewRPCError(ErrorNotFound, "connection not found: %s", id)
+		}
+		return map[string]interface{}{"self": false, "connection": other}, nil
+	})
+
 	// device - 使用文件存储
 	h.registry.Register("device.pair.list", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		f, err := h.devicesStore.Load()
@@ -578,7 +884,7 @@ func (h *Handler) registerSystemMethods() {
 		}
 		paired := make([]interface{}, 0, len(f.Paired))
 		for _, p := range f.Paired {
-			paired = append(paired, map[string]interface{}{"deviceId": p.DeviceID, "role": p.Role, "createdAt": p.CreatedAt})
+			paired = append(paired, map[string]interface{}{"deviceId": p.DeviceID, "role": p.Role, "scopes": p.GrantedScopes(), "createdAt": p.CreatedAt})
 		}
 		return map[string]interface{}{"pending": pending, "paired": paired}, nil
 	})
@@ -587,13 +893,18 @@ func (h *Handler) registerSystemMethods() {
 		if requestID == "" {
 			return nil, fmt.Errorf("requestId is required")
 		}
+		// scopes 限定该设备 token 可调用的方法（chat / config / logs / admin，read 始终授予），未传时仅只读
+		scopes, err := parseScopes(params["scopes"])
+		if err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "%s", err.Error())
+		}
 		deviceID := requestID
-		role := "control"
-		token, err := h.devicesStore.Approve(requestID, deviceID, role)
+		role := connRoleDevice
+		token, err := h.devicesStore.Approve(requestID, deviceID, role, scopes)
 		if err != nil {
 			return nil, err
 		}
-		return map[string]interface{}{"ok": true, "token": token}, nil
+		return map[string]interface{}{"ok": true, "token": token, "deviceId": deviceID, "role": role, "scopes": scopes}, nil
 	})
 	h.registry.Register("device.pair.reject", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		requestID, _ := params["requestId"].(string)
@@ -614,23 +925,37 @@ func (h *Handler) registerSystemMethods() {
 		if err := h.devicesStore.Revoke(deviceID, role); err != nil {
 			return nil, err
 		}
+		h.revokeDeviceConnections(deviceID, role, sessionID)
 		return map[string]interface{}{"ok": true}, nil
 	})
 	h.registry.Register("device.token.rotate", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		deviceID, _ := params["deviceId"].(string)
 		role, _ := params["role"].(string)
 		if role == "" {
-			role = "control"
+			role = connRoleDevice
+		}
+		// 未传 scopes 时保留原有权限范围
+		var scopes []string
+		if params["scopes"] != nil {
+			parsed, err := parseScopes(params["scopes"])
+			if err != nil {
+				return nil, NewRPCError(ErrorInvalidParams, "%s", err.Error())
+			}
+			scopes = parsed
+		}
+		token, granted, err := h.devicesStore.Rotate(deviceID, role, scopes)
+		if errors.Is(err, errDeviceNotPaired) {
+			return nil, NewRPCError(ErrorNotFound, "device %s with role %s is not paired", deviceID, role)
 		}
-		token, err := h.devicesStore.Rotate(deviceID, role)
 		if err != nil {
 			return nil, err
 		}
+		h.revokeDeviceConnections(deviceID, role, sessionID)
 		return map[string]interface{}{
 			"token":    token,
 			"role":     role,
 			"deviceId": deviceID,
-			"scopes":   []interface{}{},
+			"scopes":   granted,
 		}, nil
 	})
 
@@ -676,13 +1001,13 @@ func (h *Handler) registerSystemMethods() {
 		return map[string]interface{}{"ok": true}, nil
 	})
 	h.registry.Register("exec.approval.resolve", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		return map[string]interface{}{"ok": true}, nil
+		return h.resolveExecApproval(params)
 	})
 
-	// node.list - 返回本网关节点
+	// node.list - 返回本网关节点（能力、健康状况、运行时长与版本）
 	h.registry.Register("node.list", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		nodes := map[string]interface{}{
-			"local": map[string]interface{}{"id": "local", "capabilities": []interface{}{}},
+			"local": h.localNodeInfo(),
 		}
 		return map[string]interface{}{"nodes": nodes}, nil
 	})
@@ -696,13 +1021,31 @@ func (h *Handler) registerSystemMethods() {
 		}, nil
 	})
 
-	// status - Debug 用，与 health 类似或更详细
+	// status - Debug 用，与 health 类似或更详细（含启动以来的 provider token 用量）
 	h.registry.Register("status", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		return map[string]interface{}{
+		result := map[string]interface{}{
 			"status":    "ok",
 			"timestamp": time.Now().Unix(),
 			"version":   ProtocolVersion,
-		}, nil
+			"usage":     providers.DefaultUsageTracker().Snapshot(),
+		}
+		if h.runStatsProvider != nil {
+			result["agentRuns"] = h.runStatsProvider()
+		}
+		if provider, degraded := h.providerStatus(); provider != nil {
+			result["provider"] = provider
+			if degraded {
+				result["status"] = "degraded"
+			}
+		}
+		if mon := diskspace.Default(); mon != nil {
+			disk := mon.Status()
+			result["disk"] = disk
+			if disk.Level == diskspace.LevelLow || disk.Level == diskspace.LevelCritical {
+				result["status"] = "degraded"
+			}
+		}
+		return result, nil
 	})
 
 	// last-heartbeat - 最后心跳时间（由 Server 更新）
@@ -714,7 +1057,12 @@ func (h *Handler) registerSystemMethods() {
 		return map[string]interface{}{"ts": ts}, nil
 	})
 
-	// models.list - 从 config agents 与 ~/.goclaw/agents 收集 model 列表
+	// providers.test - 用给定的 provider / apiKey / baseURL / model 发起一次极小调用，保存配置前校验凭据
+	h.registry.Register("providers.test", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.testProvider(params)
+	})
+
+	// models.list - 从 config agents 与 ~/.goclaw/agents 收集 model 列表；refresh: true 时合并已配置提供商 /models 返回的模型（缓存数分钟）
 	h.registry.Register("models.list", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		models := make([]string, 0)
 		seen := make(map[string]bool)
@@ -752,7 +1100,23 @@ func (h *Handler) registerSystemMethods() {
 				}
 			}
 		}
-		return map[string]interface{}{"models": models}, nil
+		var catalog []providers.ModelInfo
+		var fetchErrors map[string]string
+		if getBool(params, "refresh", false) {
+			catalog, fetchErrors = providers.FetchModelCatalog(context.Background(), config.Get())
+		}
+		entries := providers.MergeModelCatalog(models, catalog)
+		for _, e := range entries[len(models):] {
+			if !seen[e.ID] {
+				models = append(models, e.ID)
+				seen[e.ID] = true
+			}
+		}
+		out := map[string]interface{}{"models": models, "entries": entries}
+		if len(fetchErrors) > 0 {
+			out["errors"] = fetchErrors
+		}
+		return out, nil
 	})
 
 	// logs - 获取日志
@@ -812,7 +1176,8 @@ func (h *Handler) registerSystemMethods() {
 		}, nil
 	})
 
-	// logs.tail - 与前端约定：cursor 为上次返回的 cursor（字节偏移），limit/maxBytes 限制条数与字节
+	// logs.tail - 与前端约定：cursor 为上次返回的 cursor（字节偏移），limit/maxBytes 限制条数与字节；
+	// file / signature 传上次返回值，日志文件切换或被替换时返回 reset: true（见 tailLogFile）
 	h.registry.Register("logs.tail", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		limit := 200
 		if l, ok := params["limit"].(float64); ok && l > 0 {
@@ -826,79 +1191,31 @@ func (h *Handler) registerSystemMethods() {
 		if c, ok := params["cursor"].(float64); ok && c >= 0 {
 			cursor = int64(c)
 		}
+		return tailLogFile(detectLogPath(), cursor, getString(params, "file"), getString(params, "signature"), limit, maxBytes)
+	})
 
-		logPath := detectLogPath()
-		if logPath == "" {
-			return map[string]interface{}{
-				"file": logPath, "cursor": 0, "lines": []string{}, "truncated": false, "reset": true,
-			}, nil
-		}
-
-		file, err := os.Open(logPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return map[string]interface{}{
-					"file": logPath, "cursor": 0, "lines": []string{}, "truncated": false, "reset": true,
-				}, nil
-			}
-			return nil, fmt.Errorf("failed to open log file: %w", err)
+	// logs.query - 解析日志行（JSON 或控制台编码）并按 level（不低于该级别）、sessionKey、runId、method、contains 过滤；
+	// cursor 为上次返回的字节偏移，done 表示已扫描到文件末尾。无法解析的行按原文匹配
+	h.registry.Register("logs.query", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		limit := 200
+		if l, ok := params["limit"].(float64); ok && l > 0 {
+			limit = min(int(l), 5000)
 		}
-		defer file.Close()
-
-		info, err := file.Stat()
-		if err != nil {
-			return nil, fmt.Errorf("failed to stat log file: %w", err)
+		var cursor int64
+		if c, ok := params["cursor"].(float64); ok && c >= 0 {
+			cursor = int64(c)
 		}
-		size := info.Size()
-		if cursor > size {
-			cursor = size
+		q := logQuery{
+			Level:      strings.ToLower(strings.TrimSpace(getString(params, "level"))),
+			SessionKey: strings.TrimSpace(getString(params, "sessionKey")),
+			RunID:      strings.TrimSpace(getString(params, "runId")),
+			Method:     strings.TrimSpace(getString(params, "method")),
+			Contains:   getString(params, "contains"),
 		}
-
-		// 从 cursor 位置往后读，或从文件末尾往前取 limit 行
-		if cursor == 0 {
-			// 从末尾取 limit 行
-			if size == 0 {
-				return map[string]interface{}{
-					"file": logPath, "cursor": 0, "lines": []string{}, "truncated": false, "reset": true,
-				}, nil
-			}
-			start := size - int64(maxBytes)
-			if start < 0 {
-				start = 0
-			}
-			_, _ = file.Seek(start, 0)
-			scanner := bufio.NewScanner(file)
-			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-			var tail []string
-			for scanner.Scan() {
-				tail = append(tail, scanner.Text())
-				if len(tail) > limit {
-					tail = tail[1:]
-				}
-			}
-			return map[string]interface{}{
-				"file": logPath, "cursor": size, "lines": tail, "truncated": start > 0, "reset": true,
-			}, nil
+		if _, ok := logLevelRank[q.Level]; q.Level != "" && !ok {
+			return nil, NewRPCError(ErrorInvalidParams, "invalid level %q (debug, info, warn, error)", q.Level)
 		}
-
-		_, _ = file.Seek(cursor, 0)
-		scanner := bufio.NewScanner(file)
-		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-		var lines []string
-		readBytes := int64(0)
-		for scanner.Scan() && len(lines) < limit && readBytes < int64(maxBytes) {
-			line := scanner.Text()
-			lines = append(lines, line)
-			readBytes += int64(len(line)) + 1
-		}
-		newCursor := cursor + readBytes
-		if newCursor > size {
-			newCursor = size
-		}
-		truncated := scanner.Scan() || readBytes >= int64(maxBytes)
-		return map[string]interface{}{
-			"file": logPath, "cursor": newCursor, "lines": lines, "truncated": truncated, "reset": false,
-		}, nil
+		return queryLogFile(detectLogPath(), q, cursor, limit)
 	})
 
 	// agents.list - 列出已配置的 Agents（AgentsListResult: defaultId, mainKey, scope, agents）
@@ -1062,28 +1379,30 @@ func (h *Handler) registerSystemMethods() {
 	// skills.status - 技能状态（合并目录与 overlay）
 	h.registry.Register("skills.status", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		overlays, _ := h.skillsStore.Load()
-		homeDir, _ := os.UserHomeDir()
-		skillsDir := filepath.Join(homeDir, ".goclaw", "skills")
-		entries, _ := os.ReadDir(skillsDir)
-		skills := make([]map[string]interface{}, 0)
-		for _, e := range entries {
-			if !e.IsDir() {
-				continue
-			}
-			key := e.Name()
+		installed, _ := skills.ScanInstalledSkills(managedSkillsDir())
+		result := make([]map[string]interface{}, 0, len(installed))
+		for _, s := range installed {
 			enabled := true
 			apiKey := ""
-			if o, ok := overlays[key]; ok {
-				enabled = o.Enabled
+			if o, ok := overlays[s.Key]; ok {
+				enabled = o.IsEnabled()
 				apiKey = o.APIKey
 			}
-			skills = append(skills, map[string]interface{}{
-				"key":     key,
-				"enabled": enabled,
-				"apiKey":  apiKey,
-			})
+			entry := map[string]interface{}{
+				"key":         s.Key,
+				"name":        s.Name,
+				"description": s.Description,
+				"enabled":     enabled,
+				"apiKey":      apiKey,
+			}
+			// 清单缺失或格式错误时标记，前端可提示修复
+			if s.Malformed() {
+				entry["malformed"] = true
+				entry["error"] = s.Error
+			}
+			result = append(result, entry)
 		}
-		return map[string]interface{}{"skills": skills}, nil
+		return map[string]interface{}{"skills": result}, nil
 	})
 
 	// skills.update - 更新技能 enabled 或 apiKey 并持久化
@@ -1107,11 +1426,105 @@ func (h *Handler) registerSystemMethods() {
 		if err := h.skillsStore.UpdateSkill(skillKey, enabled, apiKey); err != nil {
 			return nil, err
 		}
+		if err := h.ReloadSkills(); err != nil {
+			return nil, fmt.Errorf("skill updated but reload failed: %w", err)
+		}
+		return map[string]interface{}{"ok": true}, nil
+	})
+
+	// skills.reload - 重新扫描技能目录并按 overlay 推送到运行中的 agent（下一轮对话生效，进行中的运行保留开始时的技能）
+	h.registry.Register("skills.reload", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		if h.skillsReloader == nil {
+			return nil, fmt.Errorf("skills reload not available")
+		}
+		if err := h.ReloadSkills(); err != nil {
+			return nil, err
+		}
 		return map[string]interface{}{"ok": true}, nil
 	})
-	// skills.install - 安装技能（与 openclaw 前端兼容）
+
+	// skills.remove - {skillKey, force} 删除 ~/.goclaw/skills/<skillKey> 与其 overlay 条目并重载。
+	// 内置技能需 force:true；内置技能在下次启动时会被恢复，因此保留 enabled:false 的 overlay 使其保持禁用
+	h.registry.Register("skills.remove", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		skillKey := strings.TrimSpace(getString(params, "skillKey"))
+		if skillKey == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "skillKey is required")
+		}
+		if !skills.ValidSkillKey(skillKey) {
+			return nil, NewRPCError(ErrorInvalidParams, "invalid skillKey %q", skillKey)
+		}
+		builtin := internal.IsBuiltinSkill(skillKey)
+		if builtin && !getBool(params, "force", false) {
+			return nil, NewRPCError(ErrorInvalidRequest, "skill %s is built-in; pass force:true to remove it", skillKey)
+		}
+		skillDir := filepath.Join(managedSkillsDir(), skillKey)
+		if _, err := os.Stat(skillDir); err != nil {
+			if os.IsNotExist(err) {
+				return nil, NewRPCError(ErrorNotFound, "skill %s not found", skillKey)
+			}
+			return nil, fmt.Errorf("failed to stat skill %s: %w", skillKey, err)
+		}
+		if err := os.RemoveAll(skillDir); err != nil {
+			return nil, fmt.Errorf("failed to remove skill %s: %w", skillKey, err)
+		}
+		var err error
+		if builtin {
+			disabled := false
+			err = h.skillsStore.UpdateSkill(skillKey, &disabled, nil)
+		} else {
+			err = h.skillsStore.RemoveSkill(skillKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("skill removed but overlay update failed: %w", err)
+		}
+		if h.skillsReloader != nil {
+			if err := h.ReloadSkills(); err != nil {
+				return nil, fmt.Errorf("skill removed but reload failed: %w", err)
+			}
+		}
+		logger.Info("Skill removed", zap.String("skill_key", skillKey), zap.Bool("builtin", builtin))
+		return map[string]interface{}{"ok": true, "skillKey": skillKey, "builtin": builtin}, nil
+	})
+	// skills.install - {skillKey, source} 从本地目录/压缩包、git 地址或压缩包 URL 安装到 ~/.goclaw/skills/<skillKey>，
+	// 校验清单（SKILL.md 或 skill.json）后写入 overlay（默认启用）并重载；失败时不留下半安装的目录
 	h.registry.Register("skills.install", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		return map[string]interface{}{"message": "Installed"}, nil
+		skillKey := strings.TrimSpace(getString(params, "skillKey"))
+		source := strings.TrimSpace(getString(params, "source"))
+		if skillKey == "" || source == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "skillKey and source are required")
+		}
+		if !skills.ValidSkillKey(skillKey) {
+			return nil, NewRPCError(ErrorInvalidParams, "invalid skillKey %q", skillKey)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), skillInstallTimeout)
+		defer cancel()
+		installed, err := skills.InstallSkillPackage(ctx, managedSkillsDir(), skillKey, source)
+		if err != nil {
+			if errors.Is(err, skills.ErrSkillExists) {
+				return nil, NewRPCError(ErrorInvalidRequest, "skill %s is already installed", skillKey)
+			}
+			return nil, fmt.Errorf("failed to install skill %s: %w", skillKey, err)
+		}
+		enabled := true
+		if err := h.skillsStore.UpdateSkill(skillKey, &enabled, nil); err != nil {
+			return nil, fmt.Errorf("skill installed but overlay update failed: %w", err)
+		}
+		if h.skillsReloader != nil {
+			if err := h.ReloadSkills(); err != nil {
+				return nil, fmt.Errorf("skill installed but reload failed: %w", err)
+			}
+		}
+		logger.Info("Skill installed", zap.String("skill_key", skillKey), zap.String("source", source))
+		return map[string]interface{}{
+			"ok": true,
+			"skill": map[string]interface{}{
+				"key":         installed.Key,
+				"name":        installed.Name,
+				"description": installed.Description,
+				"path":        installed.Path,
+				"enabled":     true,
+			},
+		}, nil
 	})
 
 	// resolveWorkspace 优先用 params，否则用配置中的 workspace.path（与 agent 使用同一工作区）
@@ -1128,32 +1541,25 @@ func (h *Handler) registerSystemMethods() {
 		return filepath.Join(homeDir, ".goclaw", "workspace")
 	}
 
-	// agents.files.list - Agent 工作区文件列表
+	// agents.files.list - Agent 工作区文件列表；recursive 为 true 时按 maxDepth 递归列出子目录
 	h.registry.Register("agents.files.list", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		agentId, _ := params["agentId"].(string)
 		if agentId == "" {
 			return nil, fmt.Errorf("agentId is required")
 		}
 		workspace := resolveWorkspace(params)
-		entries, err := os.ReadDir(workspace)
+		maxDepth := 0
+		if v, ok := params["maxDepth"].(float64); ok && v > 0 {
+			maxDepth = int(v)
+		}
+		files, truncated, err := listWorkspaceFiles(workspace, getBool(params, "recursive", false), maxDepth)
 		if err != nil {
 			return map[string]interface{}{"agentId": agentId, "workspace": workspace, "files": []interface{}{}}, nil
 		}
-		files := make([]map[string]interface{}, 0)
-		for _, e := range entries {
-			info, _ := e.Info()
-			size := int64(0)
-			if info != nil {
-				size = info.Size()
-			}
-			files = append(files, map[string]interface{}{
-				"name": e.Name(), "path": e.Name(), "missing": false, "size": size,
-			})
-		}
-		return map[string]interface{}{"agentId": agentId, "workspace": workspace, "files": files}, nil
+		return map[string]interface{}{"agentId": agentId, "workspace": workspace, "files": files, "truncated": truncated}, nil
 	})
 
-	// agents.files.get - 读取 Agent 工作区文件
+	// agents.files.get - 读取 Agent 工作区文件；文本以 utf8 返回，二进制以 base64 返回（encoding / mimeType），超过 maxBytes（默认 1MB）时截断
 	h.registry.Register("agents.files.get", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		agentId, _ := params["agentId"].(string)
 		path, _ := params["path"].(string)
@@ -1166,17 +1572,25 @@ func (h *Handler) registerSystemMethods() {
 		if err != nil || strings.HasPrefix(rel, "..") {
 			return nil, fmt.Errorf("path outside workspace")
 		}
-		data, err := os.ReadFile(fullPath)
+		maxBytes := int64(defaultFilesGetMaxBytes)
+		if v, ok := params["maxBytes"].(float64); ok && v > 0 {
+			maxBytes = int64(v)
+		}
+		file, err := readWorkspaceFile(fullPath, maxBytes)
 		if err != nil {
 			return map[string]interface{}{"agentId": agentId, "workspace": workspace, "file": map[string]interface{}{"name": path, "path": path, "missing": true}}, nil
 		}
 		return map[string]interface{}{
 			"agentId": agentId, "workspace": workspace,
-			"file": map[string]interface{}{"name": filepath.Base(path), "path": path, "missing": false, "content": string(data)},
+			"file": map[string]interface{}{
+				"name": filepath.Base(path), "path": path, "missing": false,
+				"content": file.Content, "encoding": file.Encoding, "mimeType": file.MimeType,
+				"size": file.Size, "truncated": file.Truncated,
+			},
 		}, nil
 	})
 
-	// agents.files.set - 写入 Agent 工作区文件
+	// agents.files.set - 写入 Agent 工作区文件；encoding 为 base64 时先解码（与 agents.files.get 对应）
 	h.registry.Register("agents.files.set", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		agentId, _ := params["agentId"].(string)
 		path, _ := params["path"].(string)
@@ -1190,10 +1604,14 @@ func (h *Handler) registerSystemMethods() {
 		if err != nil || strings.HasPrefix(rel, "..") {
 			return nil, fmt.Errorf("path outside workspace")
 		}
+		data, err := decodeFileContent(content, getString(params, "encoding"))
+		if err != nil {
+			return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+		}
 		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 			return nil, fmt.Errorf("failed to create directory: %w", err)
 		}
-		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		if err := os.WriteFile(fullPath, data, 0644); err != nil {
 			return nil, fmt.Errorf("failed to write file: %w", err)
 		}
 		info, _ := os.Stat(fullPath)
@@ -1207,15 +1625,85 @@ func (h *Handler) registerSystemMethods() {
 		}, nil
 	})
 
-	// web.login.start / web.login.wait - WhatsApp 扫码登录占位
+	// web.login.start - 向支持登录的通道（默认 whatsapp）请求登录二维码，返回 {qr, expiresAt}
 	h.registry.Register("web.login.start", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		return map[string]interface{}{"message": "not implemented"}, nil
+		name, loginCh, err := h.loginChannel(params)
+		if err != nil {
+			return nil, err
+		}
+		qr, err := loginCh.LoginStart()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start login for channel %s: %w", name, err)
+		}
+		return map[string]interface{}{
+			"channel":   name,
+			"qr":        qr,
+			"expiresAt": time.Now().Add(channels.LoginQRValidity).UnixMilli(),
+		}, nil
 	})
+
+	// web.login.wait - 轮询通道连接状态直到配对完成或超时（timeoutMs，默认 30s，最长 120s）
 	h.registry.Register("web.login.wait", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		return map[string]interface{}{"message": "not implemented", "connected": false}, nil
+		name, loginCh, err := h.loginChannel(params)
+		if err != nil {
+			return nil, err
+		}
+		timeout := webLoginWaitDefault
+		if v, ok := params["timeoutMs"].(float64); ok && v > 0 {
+			timeout = min(time.Duration(v)*time.Millisecond, webLoginWaitMax)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		ticker := time.NewTicker(webLoginPollInterval)
+		defer ticker.Stop()
+		for {
+			connected, err := loginCh.LoginConnected(ctx)
+			if err == nil && connected {
+				return map[string]interface{}{"channel": name, "connected": true}, nil
+			}
+			if err != nil && ctx.Err() == nil {
+				logger.Debug("web.login.wait status check failed", zap.String("channel", name), zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return map[string]interface{}{"channel": name, "connected": false}, nil
+			case <-ticker.C:
+			}
+		}
 	})
 }
 
+// agentPreviewTimeout agent.preview 组装请求的超时（不调用 LLM，仅加载会话与构建上下文）
+const agentPreviewTimeout = 30 * time.Second
+
+// web.login.wait 轮询参数
+const (
+	webLoginWaitDefault  = 30 * time.Second
+	webLoginWaitMax      = 120 * time.Second
+	webLoginPollInterval = time.Second
+)
+
+// loginChannel 解析 web.login.* 的 channel 参数（默认 whatsapp），返回支持登录的通道
+func (h *Handler) loginChannel(params map[string]interface{}) (string, channels.LoginChannel, error) {
+	name := strings.TrimSpace(getString(params, "channel"))
+	if name == "" {
+		name = "whatsapp"
+	}
+	if h.channelMgr == nil {
+		return name, nil, NewRPCError(ErrorNotFound, "channel %s not found", name)
+	}
+	ch, ok := h.channelMgr.Get(name)
+	if !ok {
+		return name, nil, NewRPCError(ErrorNotFound, "channel %s not found", name)
+	}
+	loginCh, ok := ch.(channels.LoginChannel)
+	if !ok {
+		return name, nil, NewRPCError(ErrorInvalidParams, "login not supported for channel %s", name)
+	}
+	return name, loginCh, nil
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if v, ok := m[key].(string); ok {
 		return v
@@ -1230,13 +1718,61 @@ func getBool(m map[string]interface{}, key string, defaultVal bool) bool {
 	return defaultVal
 }
 
+// parseHistoryCursor 读取 chat.history 的 before / after 游标：数字为消息下标，字符串为 RFC3339 时间或毫秒时间戳
+func parseHistoryCursor(params map[string]interface{}, key string) (session.HistoryCursor, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return session.NoCursor, nil
+	case float64:
+		if v < 0 || v != float64(int(v)) {
+			return session.NoCursor, NewRPCError(ErrorInvalidParams, "%s must be a non-negative message index", key)
+		}
+		return session.HistoryCursor{Index: int(v)}, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return session.NoCursor, nil
+		}
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil && ms > 0 {
+			return session.HistoryCursor{Index: -1, At: time.UnixMilli(ms)}, nil
+		}
+		if at, err := time.Parse(time.RFC3339, v); err == nil {
+			return session.HistoryCursor{Index: -1, At: at}, nil
+		}
+	}
+	return session.NoCursor, NewRPCError(ErrorInvalidParams, "%s must be a message index or a timestamp (RFC3339 or milliseconds)", key)
+}
+
+// historyMetadata chat.history 返回的消息元数据：去掉 reasoning_content（思考内容经 includeReasoning 以 reasoning 字段返回）
+func historyMetadata(meta map[string]interface{}) map[string]interface{} {
+	if _, ok := meta["reasoning_content"]; !ok {
+		return meta
+	}
+	out := make(map[string]interface{}, len(meta)-1)
+	for k, v := range meta {
+		if k != "reasoning_content" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
 // detectLogPath 尝试自动检测日志文件路径
-// 与 CLI 中的逻辑保持一致，优先常见位置，其次返回默认路径
+// 优先当前进程写入的日志文件，其次日志目录中最新的按日期命名文件、常见位置，最后返回默认路径
 func detectLogPath() string {
+	// 当前进程正在写入的日志文件（当天的 goclaw-2006-01-02.log，跨天自动切换）
+	if p := logger.FilePath(); p != "" {
+		return p
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return ""
 	}
+	// 其次取日志目录中最新的按日期命名的文件
+	if matches, _ := filepath.Glob(filepath.Join(home, ".goclaw", "logs", "goclaw-*.log")); len(matches) > 0 {
+		sort.Strings(matches)
+		return matches[len(matches)-1]
+	}
 
 	candidates := []string{
 		filepath.Join(home, ".goclaw", "logs", "goclaw.log"),
@@ -1329,6 +1865,30 @@ func (h *Handler) registerAgentMethods() {
 		}
 	})
 
+	// agent.preview - {sessionKey, content} dry-run：返回以会话历史加 content 组装出的 LLM 请求（system prompt、messages、tools、选项），不调用 LLM、不写入会话
+	h.registry.Register("agent.preview", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		sessionKey := strings.TrimSpace(getString(params, "sessionKey"))
+		content := getString(params, "content")
+		if sessionKey == "" || strings.TrimSpace(content) == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "sessionKey and content are required")
+		}
+		if h.runPreviewer == nil {
+			return nil, NewRPCError(ErrorInvalidRequest, "agent preview is not available")
+		}
+		sessionKey = resolveGatewaySessionKey(sessionKey)
+		ctx, cancel := context.WithTimeout(context.Background(), agentPreviewTimeout)
+		defer cancel()
+		payload, err := h.runPreviewer(ctx, sessionKey, content)
+		if err != nil {
+			return nil, fmt.Errorf("preview failed: %w", err)
+		}
+		var request interface{} = payload
+		if json.Valid([]byte(payload)) {
+			request = json.RawMessage(payload)
+		}
+		return map[string]interface{}{"sessionKey": sessionKey, "request": request}, nil
+	})
+
 	// chat.history - 按 session_key 返回历史消息（与 OpenClaw 对齐，供 UI/TUI 加载会话）
 	h.registry.Register("chat.history", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		sessionKey, _ := params["sessionKey"].(string)
@@ -1351,16 +1911,35 @@ func (h *Handler) registerAgentMethods() {
 		if err != nil {
 			return nil, fmt.Errorf("failed to get session: %w", err)
 		}
-		history := sess.GetHistory(limit)
-		messages := make([]map[string]interface{}, 0, len(history))
-		for _, m := range history {
-			// 跳过只有工具调用而没有文本内容的 assistant 消息
-			if m.Role == "assistant" && strings.TrimSpace(m.Content) == "" && len(m.ToolCalls) > 0 {
+		// includeReasoning: true 时附带 assistant 消息的思考内容（metadata.reasoning_content），默认不返回以减小响应
+		includeReasoning := getBool(params, "includeReasoning", false)
+		// before / after 游标（消息下标，或 RFC3339 / 毫秒时间戳字符串）用于向前或向后翻页；都未传时返回最近 limit 条
+		before, err := parseHistoryCursor(params, "before")
+		if err != nil {
+			return nil, err
+		}
+		after, err := parseHistoryCursor(params, "after")
+		if err != nil {
+			return nil, err
+		}
+		page := sess.HistoryPage(before, after, limit)
+		messages := make([]map[string]interface{}, 0, len(page.Messages))
+		for i, m := range page.Messages {
+			reasoning := ""
+			if includeReasoning {
+				reasoning, _ = m.Metadata["reasoning_content"].(string)
+				reasoning = strings.TrimSpace(reasoning)
+			}
+			// 跳过只有工具调用而没有文本内容的 assistant 消息（需要返回思考内容时保留）
+			if m.Role == "assistant" && strings.TrimSpace(m.Content) == "" && len(m.ToolCalls) > 0 && reasoning == "" {
 				continue
 			}
 
 			msg := map[string]interface{}{
-				"role": m.Role, "content": m.Content, "timestamp": m.Timestamp,
+				"role": m.Role, "content": m.Content, "timestamp": m.Timestamp, "index": page.Start + i,
+			}
+			if reasoning != "" {
+				msg["reasoning"] = reasoning
 			}
 			if len(m.Media) > 0 {
 				msg["media"] = m.Media
@@ -1371,12 +1950,19 @@ func (h *Handler) registerAgentMethods() {
 			if len(m.ToolCalls) > 0 {
 				msg["tool_calls"] = m.ToolCalls
 			}
-			if len(m.Metadata) > 0 {
-				msg["metadata"] = m.Metadata
+			if meta := historyMetadata(m.Metadata); len(meta) > 0 {
+				msg["metadata"] = meta
+			}
+			// 流式检查点写入、运行未正常结束（如进程崩溃）的回复
+			if session.IsIncomplete(m) {
+				msg["incomplete"] = true
 			}
 			messages = append(messages, msg)
 		}
-		out := map[string]interface{}{"sessionKey": sessionKey, "messages": messages}
+		out := map[string]interface{}{"sessionKey": sessionKey, "messages": messages, "hasMore": page.HasMore, "nextCursor": nil}
+		if page.HasMore {
+			out["nextCursor"] = page.NextCursor
+		}
 		if v, ok := sess.Metadata["thinkingLevel"]; ok && v != nil {
 			out["thinkingLevel"] = v
 		}
@@ -1394,15 +1980,30 @@ func (h *Handler) registerAgentMethods() {
 		if sessionKey == "" {
 			return nil, fmt.Errorf("sessionKey or session_key is required")
 		}
-		// 当前无 run 追踪：返回与 OpenClaw 一致的响应结构，aborted 恒为 false
+		// 中止该会话所有排队中与执行中的 Run；响应结构与 OpenClaw 一致
+		runIds := []string{}
+		if h.runAborter != nil {
+			if aborted := h.runAborter(resolveGatewaySessionKey(sessionKey)); len(aborted) > 0 {
+				runIds = aborted
+			}
+		}
 		return map[string]interface{}{
 			"ok":      true,
-			"aborted": false,
-			"runIds":  []string{},
+			"aborted": len(runIds) > 0,
+			"runIds":  runIds,
 		}, nil
 	})
 
-	// sessions.list - 列出会话（与 OpenClaw 一致：key/kind/label/displayName/sessionId/updatedAt/spawnedBy、过滤 includeGlobal/includeUnknown/label/spawnedBy/agentId/activeMinutes、按 updatedAt 倒序）
+	// chat.events.since - 断线重连后按 sessionKey + sinceSeq 补齐期间广播的 agent 事件
+	h.registry.Register("chat.events.since", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.eventsSince(params)
+	})
+
+	// chat.subscribe / chat.unsubscribe - 按 sessionKey 订阅 agent 事件（工具执行进度、助手增量）
+	h.registry.Register("chat.subscribe", h.chatSubscribe)
+	h.registry.Register("chat.unsubscribe", h.chatUnsubscribe)
+
+	// sessions.list - 列出会话（与 OpenClaw 一致：key/kind/label/displayName/sessionId/updatedAt/spawnedBy/channel/accountId、过滤 includeGlobal/includeUnknown/label/spawnedBy/agentId/channel/activeMinutes、按 updatedAt 倒序）
 	h.registry.Register("sessions.list", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		keys, err := h.sessionMgr.List()
 		if err != nil {
@@ -1435,6 +2036,24 @@ func (h *Handler) registerAgentMethods() {
 		if v, ok := params["includeUnknown"].(bool); ok {
 			includeUnknown = v
 		}
+		// includeArchived：同时列出已归档会话（行内 archived 为 true）
+		archivedKeys := make(map[string]bool)
+		if v, _ := params["includeArchived"].(bool); v {
+			archived, err := h.sessionMgr.ListArchived()
+			if err != nil {
+				return nil, fmt.Errorf("failed to list archived sessions: %w", err)
+			}
+			active := make(map[string]bool, len(keys))
+			for _, k := range keys {
+				active[k] = true
+			}
+			for _, k := range archived {
+				if !active[k] {
+					archivedKeys[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
 		filterLabel := ""
 		if v, ok := params["label"].(string); ok {
 			filterLabel = strings.TrimSpace(v)
@@ -1447,6 +2066,7 @@ func (h *Handler) registerAgentMethods() {
 		if v, ok := params["agentId"].(string); ok {
 			filterAgentId = strings.TrimSpace(v)
 		}
+		filterChannel := getString(params, "channel")
 		activeMinutes := 0
 		if v, ok := params["activeMinutes"]; ok {
 			switch n := v.(type) {
@@ -1476,7 +2096,12 @@ func (h *Handler) registerAgentMethods() {
 					continue
 				}
 			}
-			sess, err := h.getSession(key)
+			var sess *session.Session
+			if archivedKeys[key] {
+				sess, err = h.sessionMgr.LoadArchived(key)
+			} else {
+				sess, err = h.getSession(key)
+			}
 			if err != nil {
 				continue
 			}
@@ -1492,6 +2117,9 @@ func (h *Handler) registerAgentMethods() {
 					continue
 				}
 			}
+			if filterChannel != "" && !sess.MatchesOriginChannel(filterChannel) {
+				continue
+			}
 			updatedAtMs := sess.UpdatedAt.UnixMilli()
 			if cutoffMs > 0 && updatedAtMs < cutoffMs {
 				continue
@@ -1517,6 +2145,12 @@ func (h *Handler) registerAgentMethods() {
 			if v, ok := sess.Metadata["spawnedBy"]; ok && v != nil {
 				row["spawnedBy"] = v
 			}
+			if channel, accountID := sess.Origin(); channel != "" {
+				row["channel"] = channel
+				if accountID != "" {
+					row["accountId"] = accountID
+				}
+			}
 			if v, ok := sess.Metadata["thinkingLevel"]; ok && v != nil {
 				row["thinkingLevel"] = v
 			}
@@ -1526,6 +2160,9 @@ func (h *Handler) registerAgentMethods() {
 			if v, ok := sess.Metadata["reasoningLevel"]; ok && v != nil {
 				row["reasoningLevel"] = v
 			}
+			if archivedKeys[key] {
+				row["archived"] = true
+			}
 			sessions = append(sessions, row)
 		}
 
@@ -1547,7 +2184,7 @@ func (h *Handler) registerAgentMethods() {
 		}, nil
 	})
 
-	// sessions.patch - 按 key 更新会话元数据（与 OpenClaw 一致：label, thinkingLevel, verboseLevel, reasoningLevel, model, spawnedBy 仅子会话, deleteTranscript）
+	// sessions.patch - 按 key 更新会话元数据（与 OpenClaw 一致：label, thinkingLevel, verboseLevel, reasoningLevel, model, spawnedBy 仅子会话, deleteTranscript；debugPrompts 仅 control 角色）
 	h.registry.Register("sessions.patch", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		key, ok := params["key"].(string)
 		if !ok || key == "" {
@@ -1560,17 +2197,8 @@ func (h *Handler) registerAgentMethods() {
 		}
 		if v, ok := params["label"]; ok && v != nil {
 			newLabel := strings.TrimSpace(fmt.Sprintf("%v", v))
-			allKeys, _ := h.sessionMgr.List()
-			for _, k := range allKeys {
-				if k == canonicalKey {
-					continue
-				}
-				other, _ := h.sessionMgr.GetOrCreate(k)
-				if other.Metadata != nil {
-					if lab, _ := other.Metadata["label"].(string); strings.TrimSpace(lab) == newLabel {
-						return nil, fmt.Errorf("label already in use: %s", newLabel)
-					}
-				}
+			if owner, found := h.sessionMgr.FindByLabel(newLabel); found && owner != canonicalKey {
+				return nil, fmt.Errorf("label already in use: %s", newLabel)
 			}
 		}
 		if v, ok := params["spawnedBy"]; ok {
@@ -1591,10 +2219,24 @@ func (h *Handler) registerAgentMethods() {
 				}
 			}
 		}
+		// debugPrompts：记录该会话完整 prompt（含用户内容），仅以 token 认证的 control 角色或 admin 设备可开启
+		debugPrompts, hasDebugPrompts := params["debugPrompts"].(bool)
+		if hasDebugPrompts && debugPrompts {
+			if auth, ok := h.callerAuth(sessionID); !ok || !auth.privileged() {
+				return nil, fmt.Errorf("debugPrompts requires control role or admin scope")
+			}
+		}
 		updates := make(map[string]interface{})
 		if v, ok := params["label"]; ok {
 			updates["label"] = v
 		}
+		if hasDebugPrompts {
+			if debugPrompts {
+				updates[session.MetadataDebugPrompts] = true
+			} else {
+				updates[session.MetadataDebugPrompts] = nil
+			}
+		}
 		if v, ok := params["thinkingLevel"]; ok {
 			updates["thinkingLevel"] = v
 		}
@@ -1605,7 +2247,7 @@ func (h *Handler) registerAgentMethods() {
 			updates["reasoningLevel"] = v
 		}
 		if v, ok := params["model"]; ok {
-			updates["modelOverride"] = v
+			updates[session.MetadataModelOverride] = v
 		}
 		if v, ok := params["spawnedBy"]; ok {
 			updates["spawnedBy"] = v
@@ -1621,7 +2263,7 @@ func (h *Handler) registerAgentMethods() {
 			"sessionId": canonicalKey,
 			"updatedAt": sess.UpdatedAt.UnixMilli(),
 		}
-		for _, k := range []string{"label", "thinkingLevel", "verboseLevel", "reasoningLevel", "modelOverride", "spawnedBy"} {
+		for _, k := range []string{"label", "thinkingLevel", "verboseLevel", "reasoningLevel", session.MetadataModelOverride, "spawnedBy", session.MetadataDebugPrompts} {
 			if v, ok := sess.Metadata[k]; ok && v != nil {
 				entry[k] = v
 			}
@@ -1632,7 +2274,7 @@ func (h *Handler) registerAgentMethods() {
 		}, nil
 	})
 
-	// sessions.delete - 按 key 删除会话（含 transcript）；key 支持 "main" 等别名，会解析为规范 key
+	// sessions.delete - 按 key 删除会话（含 transcript 与归档记录）；key 支持 "main" 等别名，会解析为规范 key
 	h.registry.Register("sessions.delete", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		key, ok := params["key"].(string)
 		if !ok || key == "" {
@@ -1645,6 +2287,82 @@ func (h *Handler) registerAgentMethods() {
 		return map[string]interface{}{"ok": true, "key": canonicalKey}, nil
 	})
 
+	// sessions.archive - 归档会话：标记 archived 并移入 archive/ 子目录，sessions.list 默认不再列出，记录完整保留
+	h.registry.Register("sessions.archive", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		key, ok := params["key"].(string)
+		if !ok || key == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "key parameter is required")
+		}
+		canonicalKey := resolveGatewaySessionKey(key)
+		// 运行结束时会保存会话，归档运行中的会话会丢失其结果
+		if h.sessionRuns != nil {
+			if runIDs := h.sessionRuns(canonicalKey); len(runIDs) > 0 {
+				return nil, NewRPCError(ErrorInvalidRequest, "session %s has active runs (%s); wait for them or call chat.abort first", canonicalKey, strings.Join(runIDs, ", "))
+			}
+		}
+		sess, err := h.sessionMgr.Archive(canonicalKey)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, NewRPCError(ErrorNotFound, "session not found: %s", canonicalKey)
+			}
+			return nil, fmt.Errorf("failed to archive session: %w", err)
+		}
+		return map[string]interface{}{
+			"ok": true, "key": canonicalKey,
+			"archivedAt": sess.GetMetadata(session.MetadataArchivedAt),
+		}, nil
+	})
+
+	// sessions.unarchive - 将归档会话原样恢复为活动会话
+	h.registry.Register("sessions.unarchive", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		key, ok := params["key"].(string)
+		if !ok || key == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "key parameter is required")
+		}
+		canonicalKey := resolveGatewaySessionKey(key)
+		sess, err := h.sessionMgr.Unarchive(canonicalKey)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, NewRPCError(ErrorNotFound, "archived session not found: %s", canonicalKey)
+			}
+			if errors.Is(err, session.ErrSessionExists) {
+				return nil, NewRPCError(ErrorInvalidRequest, "session %s already exists; delete or archive it first", canonicalKey)
+			}
+			if errors.Is(err, session.ErrLabelInUse) {
+				return nil, NewRPCError(ErrorInvalidRequest, "%v", err)
+			}
+			return nil, fmt.Errorf("failed to unarchive session: %w", err)
+		}
+		return map[string]interface{}{
+			"ok": true, "key": canonicalKey,
+			"messageCount": len(sess.Messages),
+			"updatedAt":    sess.UpdatedAt.UnixMilli(),
+		}, nil
+	})
+
+	// sessions.diff - 比较两个会话或两次运行：按位置对齐的对话 + 最后一条 assistant 消息的行级差异（hunks）
+	h.registry.Register("sessions.diff", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.diffSessions(params)
+	})
+
+	// sessions.export - 导出完整对话（json：原始消息与元数据；markdown：按角色与时间渲染），redactTools 省略工具调用参数
+	h.registry.Register("sessions.export", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.exportSession(params)
+	})
+
+	// sessions.merge - 将 sourceKey 的消息合并到 targetKey（strategy: append | interleave-by-time），可选 deleteSource 删除源会话
+	h.registry.Register("sessions.merge", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		sourceKey := resolveGatewaySessionKey(getString(params, "sourceKey"))
+		targetKey := resolveGatewaySessionKey(getString(params, "targetKey"))
+		if sourceKey == "" || targetKey == "" {
+			return nil, fmt.Errorf("sourceKey and targetKey parameters are required")
+		}
+		if h.sessionMerger == nil {
+			return nil, fmt.Errorf("sessions.merge is not available")
+		}
+		return h.sessionMerger(sourceKey, targetKey, getString(params, "strategy"), getBool(params, "deleteSource", false))
+	})
+
 	// sessions.get - 获取会话详情（与 OpenClaw 一致：key/sessionId、messages、entry 元数据）
 	h.registry.Register("sessions.get", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		key, ok := params["key"].(string)
@@ -1652,9 +2370,17 @@ func (h *Handler) registerAgentMethods() {
 			return nil, fmt.Errorf("key parameter is required")
 		}
 
-		sess, err := h.getSession(key)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get session: %w", err)
+		// 活动会话不存在时读取归档会话（只读，不会因此创建空白会话）
+		var sess *session.Session
+		canonical := resolveGatewaySessionKey(key)
+		if canonical != "" && !h.sessionMgr.Exists(canonical) {
+			sess, _ = h.sessionMgr.LoadArchived(canonical)
+		}
+		if sess == nil {
+			var err error
+			if sess, err = h.getSession(key); err != nil {
+				return nil, fmt.Errorf("failed to get session: %w", err)
+			}
 		}
 
 		entry := map[string]interface{}{
@@ -1679,6 +2405,31 @@ func (h *Handler) registerAgentMethods() {
 		}, nil
 	})
 
+	// sessions.media.get - 按 mediaRef 读取外置的会话媒体（session.media_inline_max_bytes 开启时消息中只保留引用）
+	h.registry.Register("sessions.media.get", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		ref, _ := params["ref"].(string)
+		if ref == "" {
+			ref, _ = params["mediaRef"].(string)
+		}
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return nil, fmt.Errorf("ref parameter is required")
+		}
+		data, err := h.sessionMgr.MediaStore().Get(ref)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("media not found: %s", ref)
+			}
+			return nil, fmt.Errorf("failed to read media: %w", err)
+		}
+		return map[string]interface{}{
+			"ref":      ref,
+			"mimeType": http.DetectContentType(data),
+			"size":     len(data),
+			"base64":   base64.StdEncoding.EncodeToString(data),
+		}, nil
+	})
+
 	// sessions.resolve - 由 key / sessionId / label 解析为规范 sessionKey（与 OpenClaw 一致）
 	h.registry.Register("sessions.resolve", func(sessionID string, params map[string]interface{}) (interface{}, error) {
 		key, _ := params["key"].(string)
@@ -1738,18 +2489,8 @@ func (h *Handler) registerAgentMethods() {
 			}
 			return nil, fmt.Errorf("no session found: %s", sessionId)
 		}
-		keys, err := h.sessionMgr.List()
-		if err != nil {
-			return nil, err
-		}
-		for _, k := range keys {
-			sess, err := h.getSession(k)
-			if err != nil {
-				continue
-			}
-			if lab, _ := sess.Metadata["label"].(string); strings.TrimSpace(lab) == label {
-				return map[string]interface{}{"ok": true, "key": sess.Key}, nil
-			}
+		if k, found := h.sessionMgr.FindByLabel(label); found {
+			return map[string]interface{}{"ok": true, "key": k}, nil
 		}
 		return nil, fmt.Errorf("no session found for label: %s", label)
 	})
@@ -1773,40 +2514,49 @@ func (h *Handler) registerAgentMethods() {
 			"key":    key,
 		}, nil
 	})
+
+	// sessions.touch - 会话保活：仅刷新 updatedAt，重置空闲计时，不添加消息
+	h.registry.Register("sessions.touch", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		key, ok := params["key"].(string)
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "key parameter is required")
+		}
+		canonical := resolveGatewaySessionKey(key)
+		sess, err := h.sessionMgr.Touch(canonical, h.sessionPolicy)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, NewRPCError(ErrorNotFound, "session not found: %s", canonical)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to touch session: %w", err)
+		}
+		return map[string]interface{}{
+			"ok":        true,
+			"key":       canonical,
+			"updatedAt": sess.UpdatedAt.UnixMilli(),
+		}, nil
+	})
 }
 
 // registerChannelMethods 注册 Channel 方法
 func (h *Handler) registerChannelMethods() {
 	// channels.status - 获取通道状态；未传 channel 时返回全量 ChannelsStatusSnapshot
 	h.registry.Register("channels.status", func(sessionID string, params map[string]interface{}) (interface{}, error) {
-		channelNames := h.channelMgr.List()
-		name, hasName := params["channel"].(string)
-		if hasName && name != "" {
-			channelNames = []string{name}
-		}
+		return h.channelsStatus(params)
+	})
 
-		channelsMap := make(map[string]interface{})
-		channelAccounts := make(map[string]interface{})
-		channelDefaultAccountId := make(map[string]string)
-		for _, n := range channelNames {
-			status, err := h.channelMgr.Status(n)
-			if err != nil {
-				channelsMap[n] = map[string]interface{}{"error": err.Error()}
-				continue
-			}
-			channelsMap[n] = status
-			channelAccounts[n] = []interface{}{}
-			channelDefaultAccountId[n] = ""
-		}
+	// channels.send.test - 经正常出站分发发送一条测试消息并等待投递结果，用于诊断通道能否送达
+	h.registry.Register("channels.send.test", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.testChannelSend(params)
+	})
 
-		return map[string]interface{}{
-			"ts":                      time.Now().UnixMilli(),
-			"channelOrder":            channelNames,
-			"channelLabels":           map[string]string{},
-			"channels":                channelsMap,
-			"channelAccounts":        channelAccounts,
-			"channelDefaultAccountId": channelDefaultAccountId,
-		}, nil
+	// channels.deadletter.list - 列出重试后仍投递失败的出站消息（含失败原因、尝试次数与时间）
+	h.registry.Register("channels.deadletter.list", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.deadLetterList(params)
+	})
+
+	// channels.deadletter.retry - 将死信重新放回出站队列
+	h.registry.Register("channels.deadletter.retry", func(sessionID string, params map[string]interface{}) (interface{}, error) {
+		return h.deadLetterRetry(params)
 	})
 
 	// channels.list - 列出所有通道
@@ -1881,11 +2631,27 @@ func (h *Handler) registerChannelMethods() {
 		}
 		message, _ := params["message"].(string)
 
-		// 使用前端传入的 idempotencyKey 作为 runId（与 openclaw 一致）
+		// 前端传入的 idempotencyKey 仅在本会话内保证幂等；实际 runId 按 sessionKey 命名空间派生，
+		// 不同会话使用相同 key 时不会串到对方的运行与事件
 		idempotencyKey, _ := params["idempotencyKey"].(string)
 		if idempotencyKey == "" {
 			idempotencyKey = uuid.New().String()
 		}
+		runID := session.RunID(sessionKey, idempotencyKey)
+
+		// steer: true 且该会话有正在执行的 Run 时，消息注入该 Run 并在其中处理（由 Run 结束时写入会话）；
+		// 否则按普通消息排入该会话的下一次运行
+		steer, _ := params["steer"].(bool)
+		atts, _ := params["attachments"].([]interface{})
+		if steer && len(atts) == 0 && h.runSteerer != nil {
+			if activeRunID, ok := h.runSteerer(sessionKey, message); ok {
+				return map[string]interface{}{
+					"runId":  activeRunID,
+					"status": "steered",
+					"steer":  "interrupted",
+				}, nil
+			}
+		}
 
 		sess, err := h.getSession(sessionKey)
 		if err != nil {
@@ -1893,17 +2659,15 @@ func (h *Handler) registerChannelMethods() {
 		}
 
 		media := make([]session.Media, 0)
-		if atts, ok := params["attachments"].([]interface{}); ok {
-			for _, a := range atts {
-				att, _ := a.(map[string]interface{})
-				if att == nil {
-					continue
-				}
-				mimeType, _ := att["mimeType"].(string)
-				content, _ := att["content"].(string)
-				if content != "" {
-					media = append(media, session.Media{Type: "image", Base64: content, MimeType: mimeType})
-				}
+		for _, a := range atts {
+			att, _ := a.(map[string]interface{})
+			if att == nil {
+				continue
+			}
+			mimeType, _ := att["mimeType"].(string)
+			content, _ := att["content"].(string)
+			if content != "" {
+				media = append(media, session.Media{Type: "image", Base64: content, MimeType: mimeType})
 			}
 		}
 
@@ -1923,7 +2687,7 @@ func (h *Handler) registerChannelMethods() {
 			busMedia = append(busMedia, bus.Media{Type: m.Type, Base64: m.Base64, MimeType: m.MimeType})
 		}
 		msg := &bus.InboundMessage{
-			ID:        idempotencyKey, // 使用 idempotencyKey 作为消息 ID，确保与前端 runId 一致
+			ID:        runID, // 与返回的 runId 一致，前端据此匹配 chat/agent 事件
 			Channel:   "websocket",
 			SenderID:  sessionID,
 			ChatID:    sessionKey,
@@ -1931,15 +2695,26 @@ func (h *Handler) registerChannelMethods() {
 			Media:     busMedia,
 			Timestamp: time.Now(),
 		}
+		// 连接握手声明了 locale 时随消息传给 agent，运行失败的提示按该语言发送
+		if h.connInfoProvider != nil {
+			if info, ok := h.connInfoProvider.ConnectionInfo(sessionID); ok && info.Locale != "" {
+				msg.Metadata = map[string]interface{}{"locale": info.Locale}
+			}
+		}
 		if err := h.bus.PublishInbound(context.Background(), msg); err != nil {
 			return nil, fmt.Errorf("failed to publish message: %w", err)
 		}
 
-		// 返回与 openclaw 一致的格式：{runId, status: "started"}
-		return map[string]interface{}{
-			"runId":  idempotencyKey,
-			"status": "started",
-		}, nil
+		// 返回与 openclaw 一致的格式：{runId, status: "started"}，附带原始 idempotencyKey
+		result := map[string]interface{}{
+			"runId":          runID,
+			"idempotencyKey": idempotencyKey,
+			"status":         "started",
+		}
+		if steer {
+			result["steer"] = "queued"
+		}
+		return result, nil
 	})
 }
 
@@ -0,0 +1,10 @@
+//go:build windows
+
+package gateway
+
+import "os"
+
+// fileInode Windows 下 FileInfo 不带文件索引，只能依靠路径与大小识别替换
+func fileInode(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
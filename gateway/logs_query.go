@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// logQueryMaxScanBytes logs.query 单次调用最多扫描的字节数，超过后返回 cursor 由客户端继续
+const logQueryMaxScanBytes = 8 * 1024 * 1024
+
+// logQueryMaxLineBytes 单行保留的最大字节数，超出部分被丢弃（该行按截断后的内容匹配）
+const logQueryMaxLineBytes = 1024 * 1024
+
+// ansiPattern 控制台编码日志中的颜色转义（CapitalColorLevelEncoder）
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// logLevelRank 日志级别顺序，level 过滤返回不低于该级别的条目
+var logLevelRank = map[string]int{
+	"debug": 0, "info": 1, "warn": 2, "error": 3, "dpanic": 4, "panic": 5, "fatal": 6,
+}
+
+// logQuery logs.query 的过滤条件；空字段不过滤
+type logQuery struct {
+	Level      string
+	SessionKey string
+	RunID      string
+	Method     string
+	Contains   string
+}
+
+// logEntry 解析后的日志条目；Raw 为 true 表示无法解析，Msg 为原始行
+type logEntry struct {
+	TS     string
+	Level  string
+	Caller string
+	Msg    string
+	Fields map[string]interface{}
+	Raw    bool
+}
+
+func (e *logEntry) toMap() map[string]interface{} {
+	fields := e.Fields
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	out := map[string]interface{}{"ts": e.TS, "level": e.Level, "msg": e.Msg, "fields": fields}
+	if e.Caller != "" {
+		out["caller"] = e.Caller
+	}
+	if e.Raw {
+		out["raw"] = true
+	}
+	return out
+}
+
+// parseLogLine 解析一行日志：JSON 编码（ts/level/msg 或 T/L/M）或控制台编码（时间\t级别\t位置\t消息\t{字段}）
+func parseLogLine(line string) (logEntry, bool) {
+	line = strings.TrimSpace(ansiPattern.ReplaceAllString(line, ""))
+	if strings.HasPrefix(line, "{") {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err == nil {
+			entry := logEntry{Fields: map[string]interface{}{}}
+			for k, v := range obj {
+				s, _ := v.(string)
+				switch k {
+				case "ts", "T", "time":
+					entry.TS = fmt.Sprint(v)
+				case "level", "L":
+					entry.Level = strings.ToLower(s)
+				case "msg", "M", "message":
+					entry.Msg = s
+				case "caller", "C":
+					entry.Caller = s
+				default:
+					entry.Fields[k] = v
+				}
+			}
+			if _, ok := logLevelRank[entry.Level]; ok {
+				return entry, true
+			}
+		}
+		return logEntry{}, false
+	}
+
+	head, fields := line, map[string]interface{}{}
+	if i := strings.LastIndex(line, "\t{"); i >= 0 {
+		if err := json.Unmarshal([]byte(line[i+1:]), &fields); err == nil {
+			head = line[:i]
+		} else {
+			fields = map[string]interface{}{}
+		}
+	}
+	parts := strings.SplitN(head, "\t", 4)
+	if len(parts) < 3 {
+		return logEntry{}, false
+	}
+	level := strings.ToLower(strings.TrimSpace(parts[1]))
+	if _, ok := logLevelRank[level]; !ok {
+		return logEntry{}, false
+	}
+	entry := logEntry{TS: parts[0], Level: level, Fields: fields}
+	if len(parts) == 4 {
+		entry.Caller, entry.Msg = parts[2], parts[3]
+	} else {
+		entry.Msg = parts[2]
+	}
+	return entry, true
+}
+
+// fieldString 取字段值（兼容 snake_case 与 camelCase 键名）
+func fieldString(fields map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// matches 判断条目是否满足过滤条件。无法解析的行按原文子串匹配 sessionKey / runId / method / contains，指定 level 时排除
+func (q *logQuery) matches(entry *logEntry, line string) bool {
+	if q.Contains != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(q.Contains)) {
+		return false
+	}
+	if entry.Raw {
+		if q.Level != "" {
+			return false
+		}
+		for _, v := range []string{q.SessionKey, q.RunID, q.Method} {
+			if v != "" && !strings.Contains(line, v) {
+				return false
+			}
+		}
+		return true
+	}
+	if q.Level != "" && logLevelRank[entry.Level] < logLevelRank[q.Level] {
+		return false
+	}
+	if q.SessionKey != "" && fieldString(entry.Fields, "session_key", "sessionKey") != q.SessionKey {
+		return false
+	}
+	if q.RunID != "" && fieldString(entry.Fields, "run_id", "runId") != q.RunID {
+		return false
+	}
+	if q.Method != "" && fieldString(entry.Fields, "method") != q.Method {
+		return false
+	}
+	return true
+}
+
+// queryLogFile logs.query 的实现：从 cursor（字节偏移）向后扫描，收集至多 limit 条匹配条目；
+// 返回的 cursor 为已扫描位置，done 表示已读到文件末尾
+func queryLogFile(logPath string, q logQuery, cursor int64, limit int) (map[string]interface{}, error) {
+	result := func(entries []map[string]interface{}, cursor int64, done bool) map[string]interface{} {
+		if entries == nil {
+			entries = []map[string]interface{}{}
+		}
+		return map[string]interface{}{"file": logPath, "entries": entries, "cursor": cursor, "done": done}
+	}
+	if logPath == "" {
+		return result(nil, 0, true), nil
+	}
+	file, err := os.Open(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result(nil, 0, true), nil
+		}
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && cursor > info.Size() {
+		cursor = 0 // 文件被截断或替换，从头开始
+	}
+	if _, err := file.Seek(cursor, 0); err != nil {
+		return nil, fmt.Errorf("failed to seek log file: %w", err)
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var entries []map[string]interface{}
+	offset := cursor
+	for len(entries) < limit && offset-cursor < logQueryMaxScanBytes {
+		raw, n, complete, err := readLogLine(reader, logQueryMaxLineBytes)
+		if err != nil {
+			return nil, fmt.Errorf("error reading log file: %w", err)
+		}
+		if !complete {
+			// 末尾无换行的行可能仍在写入，不推进 cursor，下次从行首重读
+			return result(entries, offset, true), nil
+		}
+		offset += n
+		line := strings.TrimSpace(ansiPattern.ReplaceAllString(string(raw), ""))
+		if line == "" {
+			continue
+		}
+		entry, ok := parseLogLine(line)
+		if !ok {
+			entry = logEntry{Msg: line, Raw: true}
+		}
+		if q.matches(&entry, line) {
+			entries = append(entries, entry.toMap())
+		}
+	}
+	return result(entries, offset, false), nil
+}
+
+// readLogLine 读取一行（不含换行符），只保留前 max 字节；n 为实际消耗的字节数（含换行符）。
+// complete 为 false 表示读到文件末尾仍未遇到换行符
+func readLogLine(r *bufio.Reader, max int) (line []byte, n int64, complete bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		n += int64(len(chunk))
+		if room := max - len(line); room > 0 {
+			keep := chunk
+			if len(keep) > room {
+				keep = keep[:room]
+			}
+			line = append(line, keep...)
+		}
+		switch err {
+		case nil:
+			return bytes.TrimSuffix(line, []byte("\n")), n, true, nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			return nil, n, false, nil
+		default:
+			return nil, n, false, err
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLogFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "goclaw.log")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func entryMsgs(t *testing.T, res map[string]interface{}) []string {
+	t.Helper()
+	var msgs []string
+	for _, e := range res["entries"].([]map[string]interface{}) {
+		msgs = append(msgs, e["msg"].(string))
+	}
+	return msgs
+}
+
+func TestQueryLogFileFilters(t *testing.T) {
+	path := writeLogFile(t, strings.Join([]string{
+		`{"level":"info","ts":"t1","msg":"run started","session_key":"s1","run_id":"r1"}`,
+		`{"level":"error","ts":"t2","msg":"run failed","session_key":"s2","run_id":"r2"}`,
+		"2026-01-01T00:00:00\tWARN\tagent/manager.go:10\tslow run\t{\"sessionKey\":\"s1\"}",
+		"panic: not a log line",
+		"",
+	}, "\n"))
+
+	cases := []struct {
+		name string
+		q    logQuery
+		want []string
+	}{
+		{"all", logQuery{}, []string{"run started", "run failed", "slow run", "panic: not a log line"}},
+		{"level", logQuery{Level: "warn"}, []string{"run failed", "slow run"}},
+		{"session", logQuery{SessionKey: "s1"}, []string{"run started", "slow run"}},
+		{"run", logQuery{RunID: "r2"}, []string{"run failed"}},
+		{"contains", logQuery{Contains: "PANIC"}, []string{"panic: not a log line"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := queryLogFile(path, tc.q, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entryMsgs(t, res); strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Fatalf("msgs = %q, want %q", got, tc.want)
+			}
+			if res["done"] != true {
+				t.Fatalf("done = %v", res["done"])
+			}
+		})
+	}
+}
+
+func TestQueryLogFileCursorPagination(t *testing.T) {
+	content := `{"level":"info","msg":"a"}` + "\n" + `{"level":"info","msg":"b"}` + "\n" + `{"level":"info","msg":"c"}` + "\n"
+	path := writeLogFile(t, content)
+
+	var got []string
+	var cursor int64
+	for i := 0; i < 5; i++ {
+		res, err := queryLogFile(path, logQuery{}, cursor, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, entryMsgs(t, res)...)
+		cursor = res["cursor"].(int64)
+		if res["done"] == true {
+			break
+		}
+	}
+	if strings.Join(got, "") != "abc" {
+		t.Fatalf("msgs = %q", got)
+	}
+	if cursor != int64(len(content)) {
+		t.Fatalf("cursor = %d, want %d", cursor, len(content))
+	}
+}
+
+func TestQueryLogFileTrailingPartialLine(t *testing.T) {
+	full := `{"level":"info","msg":"a"}` + "\n"
+	path := writeLogFile(t, full+`{"level":"info","msg":"b`)
+
+	res, err := queryLogFile(path, logQuery{}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryMsgs(t, res); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("msgs = %q", got)
+	}
+	// 未写完的行不计入 cursor，补全后从行首读到
+	cursor := res["cursor"].(int64)
+	if cursor != int64(len(full)) {
+		t.Fatalf("cursor = %d, want %d", cursor, len(full))
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`"}` + "\n")
+	f.Close()
+
+	res, err = queryLogFile(path, logQuery{}, cursor, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryMsgs(t, res); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("msgs after completion = %q", got)
+	}
+}
+
+func TestQueryLogFileOverlongLine(t *testing.T) {
+	long := strings.Repeat("x", logQueryMaxLineBytes+100)
+	content := long + "\n" + `{"level":"info","msg":"after"}` + "\n"
+	path := writeLogFile(t, content)
+
+	res, err := queryLogFile(path, logQuery{}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := entryMsgs(t, res)
+	if len(got) != 2 || len(got[0]) != logQueryMaxLineBytes || got[1] != "after" {
+		t.Fatalf("got %d entries, first len %d", len(got), len(got[0]))
+	}
+	if res["cursor"].(int64) != int64(len(content)) {
+		t.Fatalf("cursor = %v, want %d", res["cursor"], len(content))
+	}
+}
+
+func TestQueryLogFileMissingAndTruncated(t *testing.T) {
+	res, err := queryLogFile(filepath.Join(t.TempDir(), "missing.log"), logQuery{}, 0, 10)
+	if err != nil || res["done"] != true || len(entryMsgs(t, res)) != 0 {
+		t.Fatalf("missing file: res=%v err=%v", res, err)
+	}
+
+	path := writeLogFile(t, `{"level":"info","msg":"a"}`+"\n")
+	res, err = queryLogFile(path, logQuery{}, 1<<20, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryMsgs(t, res); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("cursor past EOF should restart from the beginning, got %q", got)
+	}
+}
@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// logFileSignature 日志文件的标识（inode）；同一路径的文件被替换（logrotate、删除重建）后标识改变。
+// 无法获取 inode 的平台返回空串，只依靠路径与大小判断
+func logFileSignature(info os.FileInfo) string {
+	if ino, ok := fileInode(info); ok {
+		return strconv.FormatUint(ino, 10)
+	}
+	return ""
+}
+
+// tailLogFile logs.tail 的实现。cursor 为上次返回的字节偏移，prevFile / prevSignature 为上次返回的 file / signature。
+// 日志文件已切换（路径变化或 inode 变化）或当前文件小于 cursor 时返回 reset: true：
+// 按日期切换到新文件且旧文件仍在时，先读完旧文件 cursor 之后的行，再从新文件开头继续，跨天不丢行；否则从新文件末尾开始
+func tailLogFile(logPath string, cursor int64, prevFile, prevSignature string, limit, maxBytes int) (map[string]interface{}, error) {
+	empty := map[string]interface{}{
+		"file": logPath, "cursor": 0, "signature": "", "lines": []string{}, "truncated": false, "reset": true,
+	}
+	if logPath == "" {
+		return empty, nil
+	}
+	info, err := os.Stat(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return empty, nil
+		}
+		return nil, fmt.Errorf("failed to stat log file: %w", err)
+	}
+	size := info.Size()
+	signature := logFileSignature(info)
+	result := func(lines []string, cursor int64, truncated, reset bool) map[string]interface{} {
+		if lines == nil {
+			lines = []string{}
+		}
+		return map[string]interface{}{
+			"file": logPath, "cursor": cursor, "signature": signature, "lines": lines, "truncated": truncated, "reset": reset,
+		}
+	}
+
+	pathChanged := prevFile != "" && filepath.Clean(prevFile) != filepath.Clean(logPath)
+	replaced := prevSignature != "" && signature != "" && prevSignature != signature
+	if cursor > 0 && pathChanged && isRotatedLogFile(prevFile, logPath) {
+		// 旧文件剩余部分 + 新文件开头
+		old, oldNext, oldMore, err := readLogLines(prevFile, cursor, limit, maxBytes)
+		if err == nil {
+			used := int(oldNext - cursor)
+			if oldMore || len(old) >= limit || used >= maxBytes {
+				// 旧文件本次读不完（或已用满额度）：继续停留在旧文件，下次调用再接着读
+				oldSignature := ""
+				if oldInfo, err := os.Stat(prevFile); err == nil {
+					oldSignature = logFileSignature(oldInfo)
+				}
+				return map[string]interface{}{
+					"file": prevFile, "cursor": oldNext, "signature": oldSignature, "lines": old, "truncated": true, "reset": false,
+				}, nil
+			}
+			lines, next, more, err := readLogLines(logPath, 0, limit-len(old), maxBytes-used)
+			if err != nil {
+				return nil, err
+			}
+			return result(append(old, lines...), min(next, size), more, true), nil
+		}
+	}
+	if cursor == 0 || pathChanged || replaced || cursor > size {
+		lines, truncated, err := tailLogLines(logPath, size, limit, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return result(lines, size, truncated, true), nil
+	}
+
+	lines, next, more, err := readLogLines(logPath, cursor, limit, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return result(lines, min(next, size), more, false), nil
+}
+
+// isRotatedLogFile prevFile 是否为与 logPath 同目录的 goclaw*.log（客户端传入的路径只允许指向按日期切换前的日志文件）
+func isRotatedLogFile(prevFile, logPath string) bool {
+	prevFile = filepath.Clean(prevFile)
+	base := filepath.Base(prevFile)
+	return filepath.Dir(prevFile) == filepath.Dir(filepath.Clean(logPath)) &&
+		strings.HasPrefix(base, "goclaw") && strings.HasSuffix(base, ".log")
+}
+
+// tailLogLines 读取文件最后 maxBytes 字节中的最后 limit 行
+func tailLogLines(path string, size int64, limit, maxBytes int) ([]string, bool, error) {
+	if size == 0 {
+		return nil, false, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+	start := max(size-int64(maxBytes), 0)
+	_, _ = file.Seek(start, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var tail []string
+	for scanner.Scan() {
+		tail = append(tail, scanner.Text())
+		if len(tail) > limit {
+			tail = tail[1:]
+		}
+	}
+	return tail, start > 0, nil
+}
+
+// readLogLines 从 offset 开始读取至多 limit 行 / maxBytes 字节，返回新的偏移以及是否还有未读内容
+func readLogLines(path string, offset int64, limit, maxBytes int) ([]string, int64, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, offset, false, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+	_, _ = file.Seek(offset, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	readBytes := int64(0)
+	for len(lines) < limit && readBytes < int64(maxBytes) && scanner.Scan() {
+		line := scanner.Text()
+		lines = append(lines, line)
+		readBytes += int64(len(line)) + 1
+	}
+	more := readBytes >= int64(maxBytes) || (len(lines) >= limit && scanner.Scan())
+	return lines, offset + readBytes, more, nil
+}
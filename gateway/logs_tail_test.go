@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tailLines(t *testing.T, res map[string]interface{}) string {
+	t.Helper()
+	return strings.Join(res["lines"].([]string), ",")
+}
+
+func appendLog(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailLogFileIncremental(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goclaw-2026-01-01.log")
+	if res, err := tailLogFile(path, 0, "", "", 10, 1024); err != nil || res["reset"] != true || tailLines(t, res) != "" {
+		t.Fatalf("missing file: res=%v err=%v", res, err)
+	}
+
+	appendLog(t, path, "a\nb\nc\n")
+	res, err := tailLogFile(path, 0, "", "", 2, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 首次调用返回最后 limit 行
+	if tailLines(t, res) != "b,c" || res["reset"] != true || res["cursor"] != int64(6) {
+		t.Fatalf("first tail = %v", res)
+	}
+
+	appendLog(t, path, "d\n")
+	res, err = tailLogFile(path, res["cursor"].(int64), path, res["signature"].(string), 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "d" || res["reset"] != false || res["cursor"] != int64(8) {
+		t.Fatalf("incremental tail = %v", res)
+	}
+}
+
+func TestTailLogFileResetsWhenTruncatedOrReplaced(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "goclaw.log")
+	appendLog(t, path, "a\nb\nc\n")
+	first, err := tailLogFile(path, 0, "", "", 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 截断：cursor 超过文件大小
+	if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tailLogFile(path, first["cursor"].(int64), path, first["signature"].(string), 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "x" || res["reset"] != true {
+		t.Fatalf("after truncation = %v", res)
+	}
+
+	// 替换：同一路径换成另一个文件（inode 变化），即使更大也从新文件重新开始
+	sig := res["signature"].(string)
+	if sig == "" {
+		t.Skip("file inode not available on this platform")
+	}
+	other := filepath.Join(dir, "replacement")
+	appendLog(t, other, "new1\nnew2\nnew3\n")
+	if err := os.Rename(other, path); err != nil {
+		t.Fatal(err)
+	}
+	res, err = tailLogFile(path, res["cursor"].(int64), path, sig, 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "new1,new2,new3" || res["reset"] != true || res["signature"] == sig {
+		t.Fatalf("after replacement = %v", res)
+	}
+}
+
+func TestTailLogFileStitchesAcrossDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "goclaw-2026-01-01.log")
+	newPath := filepath.Join(dir, "goclaw-2026-01-02.log")
+	appendLog(t, oldPath, "a\n")
+	first, err := tailLogFile(oldPath, 0, "", "", 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 跨天前旧文件又写入一行，随后切换到新文件
+	appendLog(t, oldPath, "b\n")
+	appendLog(t, newPath, "c\n")
+
+	res, err := tailLogFile(newPath, first["cursor"].(int64), oldPath, first["signature"].(string), 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "b,c" || res["file"] != newPath || res["cursor"] != int64(2) || res["reset"] != true {
+		t.Fatalf("stitched tail = %v", res)
+	}
+}
+
+func TestTailLogFileStaysOnOldFileUntilDrained(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "goclaw-2026-01-01.log")
+	newPath := filepath.Join(dir, "goclaw-2026-01-02.log")
+	appendLog(t, oldPath, "a\nb\nc\nd\n")
+	appendLog(t, newPath, "e\n")
+
+	res, err := tailLogFile(newPath, 2, oldPath, "", 2, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "b,c" || res["file"] != oldPath || res["cursor"] != int64(6) || res["reset"] != false {
+		t.Fatalf("first page = %v", res)
+	}
+	res, err = tailLogFile(newPath, res["cursor"].(int64), oldPath, res["signature"].(string), 2, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "d,e" || res["file"] != newPath {
+		t.Fatalf("second page = %v", res)
+	}
+}
+
+func TestTailLogFileIgnoresUnrelatedPrevFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	appendLog(t, secret, "do not read\n")
+	path := filepath.Join(dir, "goclaw-2026-01-02.log")
+	appendLog(t, path, "x\n")
+
+	// 客户端传入的 file 不是同目录的 goclaw*.log 时不读取，只从当前文件重新开始
+	res, err := tailLogFile(path, 1, secret, "", 10, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tailLines(t, res) != "x" || res["file"] != path || res["reset"] != true {
+		t.Fatalf("tail = %v", res)
+	}
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// handleMetrics 以 Prometheus 文本格式导出运行指标（gateway.metrics.enabled）
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	metrics.Write(w)
+}
+
+// ErrMetricsListen 独立端口的 /metrics 无法监听（如端口被占用）时 Start 返回的错误
+var ErrMetricsListen = errors.New("gateway metrics listener unavailable")
+
+// startMetricsServer gateway.metrics.port 不为 0 时在独立端口上提供 /metrics（便于只对监控网络开放）；
+// 同步监听，Start 在启动 HTTP / WebSocket 服务器之前调用，端口被占用等错误以 ErrMetricsListen 返回
+func (s *Server) startMetricsServer() error {
+	cfg := s.config.Metrics
+	if !cfg.Enabled || cfg.Port == 0 {
+		if cfg.Enabled {
+			logger.Info("Metrics endpoint enabled", zap.String("addr", fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)), zap.String("path", "/metrics"))
+		}
+		return nil
+	}
+	host := cfg.Host
+	if host == "" {
+		host = s.config.Host
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", host, cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("%w: listen on %s: %v", ErrMetricsListen, srv.Addr, err)
+	}
+	s.metricsServer = srv
+	logger.Info("Metrics server started",
+		zap.String("addr", ln.Addr().String()),
+		zap.String("path", "/metrics"),
+	)
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server error", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestStartMetricsServerReportsBindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s := NewServer(&config.GatewayConfig{Metrics: config.MetricsConfig{Enabled: true, Host: "127.0.0.1", Port: port}}, nil, nil, nil)
+	if err := s.startMetricsServer(); err == nil {
+		_ = s.metricsServer.Close()
+		t.Fatal("startMetricsServer() succeeded on a port that is already in use")
+	}
+
+	// Start 在启动 HTTP 服务器与后台任务之前失败，调用方据此退出
+	err = s.Start(context.Background())
+	if !errors.Is(err, ErrMetricsListen) {
+		t.Fatalf("Start() error = %v, want ErrMetricsListen", err)
+	}
+	if s.IsRunning() || s.server != nil {
+		t.Fatal("Start() left the gateway partially started")
+	}
+}
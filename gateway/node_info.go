@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"slices"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/diskspace"
+)
+
+// SetAppVersion 设置 goclaw 版本号（由 cli 注入），node.list 中展示
+func (h *Handler) SetAppVersion(version string) {
+	h.appVersion = version
+}
+
+// SetToolNamesProvider 设置已注册工具名称的来源（由 agent ToolRegistry 提供），node.list 中展示
+func (h *Handler) SetToolNamesProvider(provider func() []string) {
+	h.toolNamesProvider = provider
+}
+
+// localNodeInfo 本网关节点的能力与健康状况：capabilities 为能力标识列表（channel:<name>、browser、memory、tools），
+// 任一已配置通道未运行或磁盘空间不足时 status 为 degraded
+func (h *Handler) localNodeInfo() map[string]interface{} {
+	status := "ok"
+	capabilities := make([]string, 0)
+
+	channelNames := h.channelMgr.List()
+	slices.Sort(channelNames)
+	channelsInfo := make([]map[string]interface{}, 0, len(channelNames))
+	for _, name := range channelNames {
+		connected := true
+		if ch, ok := h.channelMgr.Get(name); ok {
+			if r, ok := ch.(interface{ IsRunning() bool }); ok {
+				connected = r.IsRunning()
+			}
+		}
+		if connected {
+			capabilities = append(capabilities, "channel:"+name)
+		} else {
+			status = "degraded"
+		}
+		channelsInfo = append(channelsInfo, map[string]interface{}{"name": name, "connected": connected})
+	}
+
+	var toolNames []string
+	if h.toolNamesProvider != nil {
+		toolNames = h.toolNamesProvider()
+		slices.Sort(toolNames)
+	}
+	browser := false
+	if cfg := config.Get(); cfg != nil {
+		browser = cfg.Tools.Browser.Enabled
+	}
+	memory := slices.Contains(toolNames, "memory_search")
+	if browser {
+		capabilities = append(capabilities, "browser")
+	}
+	if memory {
+		capabilities = append(capabilities, "memory")
+	}
+	if len(toolNames) > 0 {
+		capabilities = append(capabilities, "tools")
+	}
+
+	if mon := diskspace.Default(); mon != nil {
+		if level := mon.Status().Level; level == diskspace.LevelLow || level == diskspace.LevelCritical {
+			status = "degraded"
+		}
+	}
+
+	provider, degraded := h.providerStatus()
+	if degraded {
+		status = "degraded"
+	}
+
+	version := h.appVersion
+	if version == "" {
+		version = "dev"
+	}
+	return map[string]interface{}{
+		"id":              "local",
+		"status":          status,
+		"uptimeMs":        time.Since(h.startedAt).Milliseconds(),
+		"version":         version,
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    capabilities,
+		"channels":        channelsInfo,
+		"browser":         browser,
+		"memory":          memory,
+		"tools":           toolNames,
+		"provider":        provider,
+	}
+}
+
+// providerStatus 模型提供商可用性：state 为 ok / degraded（不可用，新运行立即报错）/ error（探测失败但不影响运行）/ unknown（尚未探测）
+func (h *Handler) providerStatus() (map[string]interface{}, bool) {
+	if h.providerHealth == nil {
+		return nil, false
+	}
+	result, degraded := h.providerHealth()
+	if result.CheckedAt == 0 {
+		return map[string]interface{}{"state": "unknown"}, false
+	}
+	state := "ok"
+	switch {
+	case degraded:
+		state = "degraded"
+	case !result.OK():
+		state = "error"
+	}
+	status := map[string]interface{}{
+		"state":     state,
+		"model":     result.Model,
+		"latencyMs": result.LatencyMs,
+		"checkedAt": result.CheckedAt,
+	}
+	if result.Reason != "" {
+		status["reason"] = result.Reason
+	}
+	if result.Error != "" {
+		status["error"] = result.Error
+	}
+	return status, degraded
+}
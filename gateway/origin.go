@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// checkOrigin WebSocket 升级时的 Origin 校验：未携带 Origin 的非浏览器客户端总是允许；
+// 配置了 gateway.websocket.allowed_origins 时按其匹配，否则仅允许与请求 Host 同源或来自 localhost 的页面
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := strings.TrimSpace(r.Header.Get("Origin"))
+	if origin == "" {
+		return true
+	}
+	if allowed := s.websocketConfig().AllowedOrigins; len(allowed) > 0 {
+		return originAllowed(origin, allowed)
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host) || isLocalHostname(u.Hostname())
+}
+
+// originAllowed origin 是否匹配任一配置项：精确匹配（忽略大小写与末尾 /），或 path.Match 通配（如 https://*.example.com），"*" 允许全部
+func originAllowed(origin string, allowed []string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pattern), "/"))
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if ok, _ := path.Match(pattern, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalHostname 是否为本机地址（localhost、127.0.0.0/8、::1）
+func isLocalHostname(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestCheckOriginDefaults(t *testing.T) {
+	s := NewServer(&config.GatewayConfig{}, nil, nil, nil)
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true}, // 非浏览器客户端
+		{"https://gw.example.com", true},
+		{"https://GW.example.com", true},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1:5173", true},
+		{"http://[::1]:8080", true},
+		{"null", false},
+		{"https://evil.example.com", false},
+		{"https://gw.example.com.evil.com", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://gw.example.com/ws", nil)
+		r.Host = "gw.example.com"
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := s.checkOrigin(r); got != tt.want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOriginConfigured(t *testing.T) {
+	cfg := &config.GatewayConfig{}
+	cfg.WebSocket.AllowedOrigins = []string{"https://app.example.com/", "https://*.example.org"}
+	s := NewServer(cfg, nil, nil, nil)
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM/", true},
+		{"https://ui.example.org", true},
+		{"http://ui.example.org", false},
+		{"https://example.org", false},
+		{"https://gw.example.com", false}, // 配置后不再放行同源
+		{"http://localhost:3000", false},  // 配置后不再放行 localhost
+		{"null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://gw.example.com/ws", nil)
+		r.Header.Set("Origin", tt.origin)
+		if got := s.checkOrigin(r); got != tt.want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestOriginAllowedWildcard(t *testing.T) {
+	if !originAllowed("null", []string{"*"}) {
+		t.Error(`"*" should allow every origin`)
+	}
+	if !originAllowed("https://a.b.example.com", []string{"https://*.example.com"}) {
+		t.Error("wildcard should match a nested subdomain")
+	}
+	if originAllowed("https://example.com", []string{"https://*.example.com"}) {
+		t.Error("wildcard subdomain pattern should not match the bare domain")
+	}
+}
+
+func TestConfigReloadKeepsAllowedOrigins(t *testing.T) {
+	oldCfg := &config.Config{}
+	newCfg := &config.Config{}
+	newCfg.Gateway.WebSocket.AllowedOrigins = []string{"https://app.example.com"}
+	s := NewServer(&oldCfg.Gateway, nil, nil, nil)
+	if err := s.HandleConfigReload(oldCfg, newCfg); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "http://gw.example.com/ws", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	if !s.checkOrigin(r) {
+		t.Error("allowed_origins lost after config reload")
+	}
+	if s.websocketConfig().PingInterval == 0 {
+		t.Error("defaults not applied after config reload")
+	}
+}
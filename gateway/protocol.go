@@ -50,8 +50,19 @@ const (
 	ErrorMethodNotFound = -32601
 	ErrorInvalidParams  = -32602
 	ErrorInternalError  = -32603
+	ErrorNotFound       = -32004 // 请求的资源（配置路径等）不存在
 )
 
+// Error 实现 error：方法处理器返回 *RPCError 时按其 Code 响应
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// NewRPCError 创建带错误码的方法错误
+func NewRPCError(code int, format string, args ...interface{}) *RPCError {
+	return &RPCError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
 // NewErrorResponse 创建错误响应
 func NewErrorResponse(id string, code int, message string) *JSONRPCResponse {
 	return &JSONRPCResponse{
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// maxProviderTestTimeout providers.test 的 timeoutMs 上限
+const maxProviderTestTimeout = 60 * time.Second
+
+// testProvider providers.test：{provider, apiKey, baseURL, model, timeoutMs} 用给定凭据发起一次极小调用，返回 {ok, latencyMs, error}；
+// 未传的 apiKey / baseURL 使用当前配置（baseURL 与配置不同时须同时传 apiKey），不写入配置；timeoutMs 上限 60s
+func (h *Handler) testProvider(params map[string]interface{}) (interface{}, error) {
+	req := providers.ProbeRequest{
+		Provider: getString(params, "provider"),
+		APIKey:   getString(params, "apiKey"),
+		BaseURL:  getString(params, "baseURL"),
+		Model:    getString(params, "model"),
+	}
+	if req.BaseURL == "" {
+		req.BaseURL = getString(params, "baseUrl")
+	}
+	if strings.TrimSpace(req.Provider) == "" && strings.TrimSpace(req.Model) == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "provider or model is required")
+	}
+	timeout := providers.DefaultPreflightTimeout
+	if ms, ok := params["timeoutMs"].(float64); ok && ms > 0 {
+		timeout = min(time.Duration(ms)*time.Millisecond, maxProviderTestTimeout)
+	}
+	result, err := providers.ProbeProvider(context.Background(), config.Get(), req, timeout)
+	if err != nil {
+		return nil, NewRPCError(ErrorInvalidParams, "%v", err)
+	}
+	out := map[string]interface{}{
+		"ok":        result.OK(),
+		"latencyMs": result.LatencyMs,
+		"model":     result.Model,
+	}
+	if !result.OK() {
+		out["error"] = result.Error
+		if result.Reason != "" {
+			out["reason"] = result.Reason
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"maps"
+	"net/http"
+	"sort"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+// 组件状态（/health 与 /ready 的 components）
+const (
+	componentOK       = "ok"
+	componentDegraded = "degraded" // 部分不可用，不影响就绪
+	componentDown     = "down"     // 关键组件不可用，/ready 返回 503
+	componentUnknown  = "unknown"
+)
+
+// componentsStatus 各组件状态：消息总线、Agent 数、通道运行情况、模型提供商（最近一次缓存的探测结果）与配置校验；
+// 只读取已有状态，不发起网络调用。critical 为不可用的关键组件名（channels 不是关键组件）
+func (s *Server) componentsStatus() (components map[string]interface{}, critical []string) {
+	components = map[string]interface{}{
+		"bus":      s.busStatus(),
+		"agents":   s.agentsStatus(),
+		"channels": s.channelsReadiness(),
+		"provider": s.providerReadiness(),
+		"config":   s.configReadiness(),
+	}
+	for name, c := range components {
+		if c.(map[string]interface{})["status"] == componentDown {
+			critical = append(critical, name)
+		}
+	}
+	sort.Strings(critical)
+	return components, critical
+}
+
+// overallStatus 汇总组件状态：有关键组件不可用时为 down，有组件降级时为 degraded
+func overallStatus(components map[string]interface{}, critical []string) string {
+	if len(critical) > 0 {
+		return componentDown
+	}
+	for _, c := range components {
+		if c.(map[string]interface{})["status"] == componentDegraded {
+			return componentDegraded
+		}
+	}
+	return componentOK
+}
+
+func (s *Server) busStatus() map[string]interface{} {
+	if s.bus == nil || s.bus.IsClosed() {
+		return map[string]interface{}{"status": componentDown}
+	}
+	return map[string]interface{}{
+		"status":   componentOK,
+		"inbound":  s.bus.InboundCount(),
+		"outbound": s.bus.OutboundCount(),
+	}
+}
+
+// agentsStatus 已加载的 Agent 数；未注入 agentLister 时为 unknown，没有任何 Agent 时为 down
+func (s *Server) agentsStatus() map[string]interface{} {
+	if s.handler == nil || s.handler.agentLister == nil {
+		return map[string]interface{}{"status": componentUnknown}
+	}
+	ids := s.handler.agentLister()
+	status := componentOK
+	if len(ids) == 0 {
+		status = componentDown
+	}
+	return map[string]interface{}{"status": status, "count": len(ids)}
+}
+
+// channelsReadiness 已注册通道中运行中的数量；有通道未运行时为 degraded（列出其名称）
+func (s *Server) channelsReadiness() map[string]interface{} {
+	if s.channelMgr == nil {
+		return map[string]interface{}{"status": componentUnknown}
+	}
+	names := s.channelMgr.List()
+	sort.Strings(names)
+	notRunning := make([]string, 0)
+	for _, name := range names {
+		ch, ok := s.channelMgr.Get(name)
+		if !ok {
+			continue
+		}
+		if r, ok := ch.(interface{ IsRunning() bool }); ok && !r.IsRunning() {
+			notRunning = append(notRunning, name)
+		}
+	}
+	status := componentOK
+	if len(notRunning) > 0 {
+		status = componentDegraded
+	}
+	return map[string]interface{}{
+		"status":     status,
+		"registered": len(names),
+		"running":    len(names) - len(notRunning),
+		"notRunning": notRunning,
+	}
+}
+
+// providerReadiness 模型提供商最近一次探测结果（见 Handler.providerStatus）；不可用（degraded）时为 down
+func (s *Server) providerReadiness() map[string]interface{} {
+	if s.handler == nil {
+		return map[string]interface{}{"status": componentUnknown}
+	}
+	provider, unavailable := s.handler.providerStatus()
+	if provider == nil {
+		return map[string]interface{}{"status": componentUnknown}
+	}
+	status := componentOK
+	switch {
+	case unavailable:
+		status = componentDown
+	case provider["state"] == "error":
+		status = componentDegraded
+	case provider["state"] == "unknown":
+		status = componentUnknown
+	}
+	provider["status"] = status
+	return provider
+}
+
+// preflightSuperseded 启动预检之后是否已有更新的提供商探测结果；有时以 provider 组件为准，
+// 提供商恢复后 /ready 不再因启动时的失败返回 503
+func (s *Server) preflightSuperseded(pf *providers.PreflightResult) bool {
+	if s.handler == nil || s.handler.providerHealth == nil {
+		return false
+	}
+	latest, _ := s.handler.providerHealth()
+	return latest.CheckedAt > pf.CheckedAt
+}
+
+// configCheck 针对某个配置对象的校验结果缓存
+type configCheck struct {
+	cfg    *config.Config
+	result map[string]interface{}
+}
+
+// configReadiness 当前配置的校验结果；存在 error 级问题时为 down（warning 只计数）。
+// 结果按配置对象缓存，仅在配置加载或热重载替换后重新校验
+func (s *Server) configReadiness() map[string]interface{} {
+	cfg := config.Get()
+	if cfg == nil {
+		return map[string]interface{}{"status": componentUnknown}
+	}
+	if c := s.configCheck.Load(); c != nil && c.cfg == cfg {
+		return maps.Clone(c.result)
+	}
+	errs := make([]config.ValidationIssue, 0)
+	warnings := 0
+	for _, issue := range config.ValidateIssues(cfg) {
+		if issue.Severity == config.IssueSeverityError {
+			errs = append(errs, issue)
+		} else {
+			warnings++
+		}
+	}
+	result := map[string]interface{}{"status": componentOK, "warnings": warnings}
+	if len(errs) > 0 {
+		result["status"] = componentDown
+		result["errors"] = errs
+	}
+	s.configCheck.Store(&configCheck{cfg: cfg, result: result})
+	return maps.Clone(result)
+}
+
+// publicCounts 未认证请求可见的各组件计数字段，其余细节（错误内容、通道名、提供商信息等）仅认证请求可见
+var publicCounts = map[string][]string{
+	"bus":      {"inbound", "outbound"},
+	"agents":   {"count"},
+	"channels": {"registered", "running"},
+	"config":   {"warnings"},
+}
+
+// publicComponents 只保留各组件的 status 与计数
+func publicComponents(components map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(components))
+	for name, c := range components {
+		detail := c.(map[string]interface{})
+		public := map[string]interface{}{"status": detail["status"]}
+		for _, key := range publicCounts[name] {
+			if v, ok := detail[key]; ok {
+				public[key] = v
+			}
+		}
+		out[name] = public
+	}
+	return out
+}
+
+// healthDetailsAllowed /health 与 /ready 的组件细节只返回给携带有效 token（auth_token 或已配对设备 token）的请求
+func (s *Server) healthDetailsAllowed(r *http.Request) bool {
+	_, _, ok := s.authenticateToken(requestToken(r))
+	return ok
+}
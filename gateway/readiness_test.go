@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smallnest/goclaw/bus"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+)
+
+func readyResponse(t *testing.T, s *Server, token string) map[string]interface{} {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.handleReady(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status code = %d, want 503", w.Code)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestReadyHidesDetailsFromUnauthenticatedCallers(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{}) // 空配置存在 error 级校验问题
+
+	s := NewServer(&config.GatewayConfig{}, nil, nil, nil)
+	s.SetWebSocketConfig(newWebSocketConfig(&config.WebSocketConfig{AuthToken: "secret"}))
+
+	for _, token := range []string{"", "wrong"} {
+		body := readyResponse(t, s, token)
+		if _, ok := body["critical"]; ok {
+			t.Errorf("token %q: critical exposed to unauthenticated caller", token)
+		}
+		cfg := body["components"].(map[string]interface{})["config"].(map[string]interface{})
+		if cfg["status"] != componentDown {
+			t.Errorf("token %q: config status = %v, want down", token, cfg["status"])
+		}
+		if _, ok := cfg["errors"]; ok {
+			t.Errorf("token %q: config errors exposed to unauthenticated caller", token)
+		}
+	}
+
+	body := readyResponse(t, s, "secret")
+	if _, ok := body["critical"]; !ok {
+		t.Error("critical missing for authenticated caller")
+	}
+	cfg := body["components"].(map[string]interface{})["config"].(map[string]interface{})
+	if _, ok := cfg["errors"]; !ok {
+		t.Error("config errors missing for authenticated caller")
+	}
+}
+
+func TestConfigReadinessCachedPerConfig(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	first := &config.Config{}
+	config.Set(first)
+
+	s := NewServer(&config.GatewayConfig{}, nil, nil, nil)
+	if got := s.configReadiness()["status"]; got != componentDown {
+		t.Fatalf("status = %v, want down", got)
+	}
+	if c := s.configCheck.Load(); c == nil || c.cfg != first {
+		t.Fatal("validation result not cached for the current config")
+	}
+
+	second := &config.Config{}
+	config.Set(second)
+	s.configReadiness()
+	if c := s.configCheck.Load(); c == nil || c.cfg != second {
+		t.Error("config not revalidated after it was replaced")
+	}
+}
+
+func TestReadyNewerProbeSupersedesFailedPreflight(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	cfg := &config.Config{}
+	config.Set(cfg)
+
+	messageBus := bus.NewMessageBus(1)
+	defer messageBus.Close()
+	s := NewServer(&config.GatewayConfig{}, messageBus, nil, nil)
+	s.handler.agentLister = func() []string { return []string{"main"} }
+	s.configCheck.Store(&configCheck{cfg: cfg, result: map[string]interface{}{"status": componentOK}})
+
+	failed := providers.PreflightResult{Status: providers.PreflightStatusError, Reason: "network_error", Error: "dial tcp: connection refused", CheckedAt: 100}
+	s.SetPreflightResult(failed)
+	latest := failed
+	s.handler.SetProviderHealthProvider(func() (providers.PreflightResult, bool) {
+		return latest, !latest.OK()
+	})
+
+	ready := func() int {
+		w := httptest.NewRecorder()
+		s.handleReady(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return w.Code
+	}
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("failed preflight: status code = %d, want 503", code)
+	}
+
+	// 之后的探测成功：不再因启动预检失败返回 503
+	latest = providers.PreflightResult{Status: providers.PreflightStatusOK, CheckedAt: 200}
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("recovered provider: status code = %d, want 200", code)
+	}
+}
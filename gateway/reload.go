@@ -14,20 +14,7 @@ func (s *Server) HandleConfigReload(oldCfg, newCfg *config.Config) error {
 	if configChanged(&oldCfg.Gateway.WebSocket, &newCfg.Gateway.WebSocket) {
 		logger.Info("WebSocket config changed, updating...")
 
-		wsConfig := &WebSocketConfig{
-			Host:           newCfg.Gateway.WebSocket.Host,
-			Port:           newCfg.Gateway.WebSocket.Port,
-			Path:           newCfg.Gateway.WebSocket.Path,
-			EnableAuth:     newCfg.Gateway.WebSocket.EnableAuth,
-			AuthToken:      newCfg.Gateway.WebSocket.AuthToken,
-			PingInterval:   newCfg.Gateway.WebSocket.PingInterval,
-			PongTimeout:    newCfg.Gateway.WebSocket.PongTimeout,
-			ReadTimeout:    newCfg.Gateway.WebSocket.ReadTimeout,
-			WriteTimeout:   newCfg.Gateway.WebSocket.WriteTimeout,
-			MaxMessageSize: 10 * 1024 * 1024, // 10MB
-		}
-
-		s.SetWebSocketConfig(wsConfig)
+		s.SetWebSocketConfig(newWebSocketConfig(&newCfg.Gateway.WebSocket))
 		logger.Info("WebSocket config updated")
 	}
 
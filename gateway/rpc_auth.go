@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// AuthContext 连接的鉴权上下文，HandleRequest 据此判断能否调用方法
+type AuthContext struct {
+	Authenticated bool     `json:"authenticated"`
+	Role          string   `json:"role"`
+	Scopes        []string `json:"scopes"`
+}
+
+// Allows 判断能否调用方法：公开方法（connect、health）始终允许，其余按 methodScopes 与授予的 scopes 判断
+func (a AuthContext) Allows(method string) bool {
+	return scopesAllow(a.Scopes, method)
+}
+
+// anonymousAuth 网关未开启鉴权（enable_auth=false）时的连接：视为受信任的本地访问，拥有全部权限
+var anonymousAuth = AuthContext{Role: connRoleAnonymous, Scopes: []string{scopeAdmin}}
+
+// authContext 返回连接当前的鉴权上下文
+func (c *Connection) authContext() AuthContext {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	return AuthContext{Authenticated: c.authenticated, Role: c.role, Scopes: c.scopes}
+}
+
+// revokedAuth 设备 token 被吊销或轮换后发起该操作的连接：只能调用公开方法，可用新 token 重新 connect
+var revokedAuth = AuthContext{Role: connRoleAnonymous}
+
+// DeviceConnectionRevoker 设备 token 被吊销或轮换后处理以旧 token 认证的在线连接（由 Server 实现）
+type DeviceConnectionRevoker interface {
+	RevokeDeviceConnections(deviceID, role, callerID string) int
+}
+
+// SetDeviceConnectionRevoker 设置设备连接吊销处理（由 Server 注入）
+func (h *Handler) SetDeviceConnectionRevoker(r DeviceConnectionRevoker) {
+	h.deviceRevoker = r
+}
+
+// revokeDeviceConnections device.token.revoke / rotate 后使以旧 token 认证的连接失效；未注入时（非 WebSocket 调用）不处理
+func (h *Handler) revokeDeviceConnections(deviceID, role, callerID string) {
+	if h.deviceRevoker == nil {
+		return
+	}
+	if n := h.deviceRevoker.RevokeDeviceConnections(deviceID, role, callerID); n > 0 {
+		logger.Info("Revoked live device connections",
+			zap.String("device_id", deviceID),
+			zap.String("role", role),
+			zap.Int("connections", n))
+	}
+}
+
+// setAuth 替换连接的鉴权上下文（握手时按 token 重新认证）；device 为 token 所属的配对记录，非设备 token 时为 nil
+func (c *Connection) setAuth(a AuthContext, device *PairedDevice) {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	c.authenticated = a.Authenticated
+	c.role = a.Role
+	c.scopes = a.Scopes
+	c.deviceRole = ""
+	if device != nil {
+		c.deviceID = device.DeviceID
+		c.deviceRole = device.Role
+	}
+}
+
+// authenticatedByDevice 连接是否以该设备（deviceId + 配对角色）的 token 认证
+func (c *Connection) authenticatedByDevice(deviceID, role string) bool {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	return c.role == connRoleDevice && c.deviceID == deviceID && c.deviceRole == role
+}
+
+// RevokeDeviceConnections 关闭以该设备旧 token 认证的连接；发起操作的连接（callerID）降为未认证而不关闭，
+// 以便收到本次响应并用新 token 重新 connect。返回受影响的连接数
+func (s *Server) RevokeDeviceConnections(deviceID, role, callerID string) int {
+	var affected []*Connection
+	s.connectionsMu.RLock()
+	for _, conn := range s.connections {
+		if conn.authenticatedByDevice(deviceID, role) {
+			affected = append(affected, conn)
+		}
+	}
+	s.connectionsMu.RUnlock()
+	for _, conn := range affected {
+		if conn.ID == callerID {
+			conn.setAuth(revokedAuth, nil)
+			continue
+		}
+		conn.connLog().Info("Closing connection authenticated by a revoked device token")
+		_ = conn.Close()
+	}
+	return len(affected)
+}
+
+// authenticateToken 校验 token：网关 auth_token 获得完整权限（control），已配对设备 token 获得该设备的 scopes
+func (s *Server) authenticateToken(token string) (AuthContext, *PairedDevice, bool) {
+	if token == "" {
+		return AuthContext{}, nil, false
+	}
+	// 使用恒定时间比较防止时序攻击
+	if s.authToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1 {
+		return AuthContext{Authenticated: true, Role: connRoleControl, Scopes: []string{scopeAdmin}}, nil, true
+	}
+	if d, ok := s.handler.devicesStore.Authenticate(token); ok {
+		return AuthContext{Authenticated: true, Role: connRoleDevice, Scopes: d.GrantedScopes()}, &d, true
+	}
+	return AuthContext{}, nil, false
+}
+
+// applyConnectAuth connect 握手携带 auth.token 时按该 token 重新确定连接权限（如 UI 以设备 token 限定自身权限）；
+// token 无效时返回错误，连接保持原有权限。未开启鉴权（enable_auth=false）时忽略 auth.token
+func (s *Server) applyConnectAuth(conn *Connection, req *JSONRPCRequest) error {
+	if req.Method != "connect" {
+		return nil
+	}
+	if cfg := s.websocketConfig(); cfg == nil || !cfg.EnableAuth {
+		return nil
+	}
+	auth, _ := req.Params["auth"].(map[string]interface{})
+	token, _ := auth["token"].(string)
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil
+	}
+	a, device, ok := s.authenticateToken(token)
+	if !ok {
+		conn.connLog().Warn("Rejected connect handshake with invalid token")
+		return fmt.Errorf("unauthorized: invalid auth token")
+	}
+	conn.setAuth(a, device)
+	conn.connLog().Info("Connection authenticated by handshake",
+		zap.String("role", a.Role),
+		zap.Strings("scopes", a.Scopes))
+	return nil
+}
+
+// authorize 按连接的鉴权上下文判断能否调用方法；未注入连接信息（非 WebSocket 调用）时不限制
+func (h *Handler) authorize(connID, method string) bool {
+	if h.connInfoProvider == nil {
+		return true
+	}
+	info, ok := h.connInfoProvider.ConnectionInfo(connID)
+	if !ok {
+		return requiredScope(method) == ""
+	}
+	return AuthContext{Authenticated: info.Authenticated, Role: info.Role, Scopes: info.Scopes}.Allows(method)
+}
+
+// callerAuth 返回调用连接的鉴权上下文；未注入连接信息或连接不存在时返回 false
+func (h *Handler) callerAuth(connID string) (AuthContext, bool) {
+	if h.connInfoProvider == nil {
+		return AuthContext{}, false
+	}
+	info, ok := h.connInfoProvider.ConnectionInfo(connID)
+	if !ok {
+		return AuthContext{}, false
+	}
+	return AuthContext{Authenticated: info.Authenticated, Role: info.Role, Scopes: info.Scopes}, true
+}
+
+// privileged 是否为以 token 认证的 control 角色或 admin 设备；未开启鉴权的匿名连接不算
+func (a AuthContext) privileged() bool {
+	return a.Authenticated && (a.Role == connRoleControl || slices.Contains(a.Scopes, scopeAdmin))
+}
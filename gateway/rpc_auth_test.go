@@ -0,0 +1,258 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/session"
+)
+
+// fakeConnInfo 按连接 ID 返回固定的连接元数据
+type fakeConnInfo map[string]ConnectionInfo
+
+func (f fakeConnInfo) ConnectionInfo(id string) (ConnectionInfo, bool) {
+	info, ok := f[id]
+	return info, ok
+}
+
+func testConnections() fakeConnInfo {
+	return fakeConnInfo{
+		"control":   {ID: "control", Authenticated: true, Role: connRoleControl, Scopes: []string{scopeAdmin}},
+		"admin":     {ID: "admin", Authenticated: true, Role: connRoleDevice, Scopes: []string{scopeRead, scopeAdmin}},
+		"logs":      {ID: "logs", Authenticated: true, Role: connRoleDevice, Scopes: []string{scopeRead, scopeLogs}},
+		"chat":      {ID: "chat", Authenticated: true, Role: connRoleDevice, Scopes: []string{scopeRead, scopeChat}},
+		"anonymous": {ID: "anonymous", Role: connRoleAnonymous, Scopes: []string{scopeAdmin}},
+	}
+}
+
+func TestSessionsPatchDebugPromptsRequiresPrivilegedConnection(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil, sessMgr, nil)
+	h.SetConnectionInfoProvider(testConnections())
+	patch := func(conn string) *JSONRPCResponse {
+		return h.HandleRequest(conn, &JSONRPCRequest{ID: conn, Method: "sessions.patch", Params: map[string]interface{}{
+			"key":          "agent:main:main",
+			"debugPrompts": true,
+		}})
+	}
+	for _, conn := range []string{"chat", "anonymous"} {
+		if resp := patch(conn); resp.Error == nil {
+			t.Errorf("%s connection enabled debugPrompts", conn)
+		}
+	}
+	for _, conn := range []string{"control", "admin"} {
+		if resp := patch(conn); resp.Error != nil {
+			t.Errorf("%s connection rejected: %+v", conn, resp.Error)
+		}
+	}
+}
+
+func TestAuthorizeByConnectionScopes(t *testing.T) {
+	h := NewHandler(nil, nil, nil)
+	revoked := testConnections()
+	revoked["revoked"] = ConnectionInfo{ID: "revoked", Role: revokedAuth.Role}
+	h.SetConnectionInfoProvider(revoked)
+
+	tests := []struct {
+		conn, method string
+		want         bool
+	}{
+		{"chat", "chat.send", true},
+		{"chat", "sessions.list", true},
+		{"chat", "config.apply", false},
+		{"chat", "device.token.revoke", false},
+		{"logs", "logs.tail", true},
+		{"logs", "chat.send", false},
+		{"admin", "config.apply", true},
+		{"admin", "device.token.rotate", true},
+		{"control", "update.run", true},
+		{"anonymous", "device.pair.approve", true},
+		{"revoked", "connect", true},
+		{"revoked", "sessions.list", false},
+		{"missing", "health", true},
+		{"missing", "sessions.list", false},
+	}
+	for _, tt := range tests {
+		if got := h.authorize(tt.conn, tt.method); got != tt.want {
+			t.Errorf("authorize(%s, %s) = %v, want %v", tt.conn, tt.method, got, tt.want)
+		}
+	}
+
+	// 未注入连接信息（非 WebSocket 调用）时不限制
+	if !NewHandler(nil, nil, nil).authorize("any", "config.apply") {
+		t.Error("authorize without connection info should allow")
+	}
+}
+
+// newAuthServer 开启鉴权的网关与一个已配对设备（scopes: read, chat），返回设备 token
+func newAuthServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	s := NewServer(&config.GatewayConfig{}, nil, nil, nil)
+	s.SetWebSocketConfig(newWebSocketConfig(&config.WebSocketConfig{EnableAuth: true, AuthToken: "gateway-secret"}))
+	s.handler.devicesStore = newDevicesStore(filepath.Join(t.TempDir(), "devices.json"))
+	token, err := s.handler.devicesStore.Approve("req-1", "laptop", "operator", []string{scopeRead, scopeChat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, token
+}
+
+func connectRequest(token string) *JSONRPCRequest {
+	return &JSONRPCRequest{ID: "1", Method: "connect", Params: map[string]interface{}{
+		"auth": map[string]interface{}{"token": token},
+	}}
+}
+
+func TestConnectHandshakeReauthenticates(t *testing.T) {
+	s, deviceToken := newAuthServer(t)
+	conn := &Connection{ID: "c"}
+	conn.setAuth(AuthContext{Authenticated: true, Role: connRoleControl, Scopes: []string{scopeAdmin}}, nil)
+
+	// 无效 token 被拒绝，连接保持原有权限
+	if err := s.applyConnectAuth(conn, connectRequest("bogus")); err == nil {
+		t.Fatal("invalid handshake token accepted")
+	}
+	if got := conn.authContext(); got.Role != connRoleControl {
+		t.Fatalf("auth after rejected handshake = %+v", got)
+	}
+
+	// 设备 token 将连接限定为该设备的权限
+	if err := s.applyConnectAuth(conn, connectRequest(deviceToken)); err != nil {
+		t.Fatal(err)
+	}
+	got := conn.authContext()
+	if got.Role != connRoleDevice || got.Allows("config.apply") || !got.Allows("chat.send") {
+		t.Fatalf("auth after device handshake = %+v", got)
+	}
+	if !conn.authenticatedByDevice("laptop", "operator") {
+		t.Fatal("connection not attributed to the paired device")
+	}
+
+	// 未开启鉴权时忽略 auth.token
+	s.SetWebSocketConfig(newWebSocketConfig(&config.WebSocketConfig{}))
+	anon := &Connection{ID: "anon"}
+	anon.setAuth(anonymousAuth, nil)
+	if err := s.applyConnectAuth(anon, connectRequest("bogus")); err != nil {
+		t.Fatalf("token checked with auth disabled: %v", err)
+	}
+	if err := s.applyConnectAuth(anon, connectRequest(deviceToken)); err != nil {
+		t.Fatal(err)
+	}
+	if got := anon.authContext(); got.Role != connRoleAnonymous || !got.Allows("config.apply") {
+		t.Fatalf("auth with auth disabled = %+v", got)
+	}
+}
+
+func TestDeviceTokenRevokeClosesLiveConnections(t *testing.T) {
+	s, deviceToken := newAuthServer(t)
+	s.handler.SetConnectionInfoProvider(s)
+	s.handler.SetDeviceConnectionRevoker(s)
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer srv.Close()
+
+	dial := func(token string) *websocket.Conn {
+		t.Helper()
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/?token="+token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var welcome map[string]interface{}
+		if err := ws.ReadJSON(&welcome); err != nil {
+			t.Fatal(err)
+		}
+		return ws
+	}
+	device := dial(deviceToken)
+	defer device.Close()
+	control := dial("gateway-secret")
+	defer control.Close()
+
+	var controlID string
+	s.connectionsMu.RLock()
+	for id, conn := range s.connections {
+		if conn.authContext().Role == connRoleControl {
+			controlID = id
+		}
+	}
+	s.connectionsMu.RUnlock()
+
+	resp := s.handler.HandleRequest(controlID, &JSONRPCRequest{ID: "r", Method: "device.token.revoke", Params: map[string]interface{}{
+		"deviceId": "laptop", "role": "operator",
+	}})
+	if resp.Error != nil {
+		t.Fatalf("revoke: %+v", resp.Error)
+	}
+
+	// 以被吊销 token 认证的连接被关闭
+	_ = device.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := device.ReadMessage(); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Fatal("device connection still open after revoke")
+			}
+			break
+		}
+	}
+	if _, ok := s.ConnectionInfo(controlID); !ok {
+		t.Fatal("control connection closed by device revoke")
+	}
+}
+
+func TestDeviceTokenRotateDowngradesCaller(t *testing.T) {
+	s, deviceToken := newAuthServer(t)
+	conn := &Connection{ID: "self"}
+	if err := s.applyConnectAuth(conn, connectRequest(deviceToken)); err != nil {
+		t.Fatal(err)
+	}
+	s.addConnection(conn)
+
+	// 发起轮换的连接不关闭（须收到新 token），但旧 token 的权限失效
+	if n := s.RevokeDeviceConnections("laptop", "operator", "self"); n != 1 {
+		t.Fatalf("affected connections = %d, want 1", n)
+	}
+	got := conn.authContext()
+	if got.Authenticated || got.Allows("sessions.list") || !got.Allows("connect") {
+		t.Fatalf("caller auth after rotate = %+v", got)
+	}
+	if conn.authenticatedByDevice("laptop", "operator") {
+		t.Fatal("caller still attributed to the rotated token")
+	}
+}
+
+func TestConnectSnapshotSectionsFollowAuthorization(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil, sessMgr, nil)
+	conns := testConnections()
+	conns["revoked"] = ConnectionInfo{ID: "revoked", Role: revokedAuth.Role}
+	h.SetConnectionInfoProvider(conns)
+
+	snapshot := func(conn string) map[string]interface{} {
+		resp := h.HandleRequest(conn, &JSONRPCRequest{ID: conn, Method: "connect", Params: map[string]interface{}{"snapshot": "sessions"}})
+		if resp.Error != nil {
+			t.Fatalf("%s connect: %+v", conn, resp.Error)
+		}
+		return resp.Result.(map[string]interface{})["snapshot"].(map[string]interface{})
+	}
+	if _, ok := snapshot("chat")["sessions"]; !ok {
+		t.Error("read-scoped connection missing sessions snapshot")
+	}
+	// 无 read 权限的连接不能借 connect snapshot 读取 sessions.list
+	for _, conn := range []string{"revoked", "missing"} {
+		if _, ok := snapshot(conn)["sessions"]; ok {
+			t.Errorf("%s connection received sessions snapshot", conn)
+		}
+	}
+}
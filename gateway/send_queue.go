@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// defaultSendQueueHighWater 每个连接发送队列的默认高水位（帧数）
+const defaultSendQueueHighWater = 256
+
+// sendQueueHardFactor 队列达到高水位的该倍数仍无法合并时，视为客户端已卡死并断开连接（重连后可用 chat.events.since 补齐）
+const sendQueueHardFactor = 4
+
+// coalesceLogInterval 同一连接合并日志的最小间隔
+const coalesceLogInterval = 10 * time.Second
+
+// outboundFrame 待发送的一帧；coalesceKey 非空的帧是内容为累积文本的流式增量（chat delta / agent assistant），
+// 队列积压时同一 key 只保留最新一帧
+type outboundFrame struct {
+	data        []byte
+	coalesceKey string
+}
+
+// sendQueue 连接的有界发送队列：广播方只入队不阻塞，由连接的写协程逐帧发送。
+// 超过高水位后流式增量按 key 合并，生命周期、工具等其他帧照常入队，不丢弃
+type sendQueue struct {
+	mu        sync.Mutex
+	frames    []outboundFrame
+	highWater int
+	notify    chan struct{}
+	closed    bool
+
+	coalesced       int64 // 累计合并掉的帧数
+	coalescedLogged int64
+	lastCoalesceLog time.Time
+}
+
+func newSendQueue(highWater int) *sendQueue {
+	if highWater <= 0 {
+		highWater = defaultSendQueueHighWater
+	}
+	return &sendQueue{highWater: highWater, notify: make(chan struct{}, 1)}
+}
+
+// push 入队；返回 false 表示队列已超过硬上限（或已关闭），调用方应断开连接
+func (q *sendQueue) push(f outboundFrame) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if len(q.frames) >= q.highWater && f.coalesceKey != "" {
+		// 去掉同一 key 尚未发送的旧增量，新增量放在队尾，保持与其他事件的先后顺序
+		for i := len(q.frames) - 1; i >= 0; i-- {
+			if q.frames[i].coalesceKey == f.coalesceKey {
+				q.frames = append(q.frames[:i], q.frames[i+1:]...)
+				q.coalesced++
+				break
+			}
+		}
+	}
+	if len(q.frames) >= q.highWater*sendQueueHardFactor {
+		return false
+	}
+	q.frames = append(q.frames, f)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// take 取出全部待发送帧；队列为空时阻塞，关闭后返回 nil
+func (q *sendQueue) take() []outboundFrame {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil
+		}
+		if len(q.frames) > 0 {
+			frames := q.frames
+			q.frames = nil
+			q.mu.Unlock()
+			return frames
+		}
+		q.mu.Unlock()
+		<-q.notify
+	}
+}
+
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.frames = nil
+	close(q.notify)
+}
+
+// stats 返回当前排队帧数与累计合并数
+func (q *sendQueue) stats() (queued int, coalesced int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.frames), q.coalesced
+}
+
+// coalesceReport 距上次日志已超过 coalesceLogInterval 且有新的合并时返回本次应记录的新增合并数
+func (q *sendQueue) coalesceReport(now time.Time) (delta int64, queued int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.coalesced == q.coalescedLogged || now.Sub(q.lastCoalesceLog) < coalesceLogInterval {
+		return 0, 0, false
+	}
+	delta = q.coalesced - q.coalescedLogged
+	q.coalescedLogged = q.coalesced
+	q.lastCoalesceLog = now
+	return delta, len(q.frames), true
+}
+
+// Enqueue 将一帧放入连接的发送队列（广播用，不阻塞）；积压超过硬上限时断开该连接
+func (c *Connection) Enqueue(data []byte, coalesceKey string) {
+	if c.queue == nil {
+		_ = c.SendMessage(websocket.TextMessage, data)
+		return
+	}
+	if !c.queue.push(outboundFrame{data: data, coalesceKey: coalesceKey}) {
+		queued, coalesced := c.queue.stats()
+		c.connLog().Warn("Disconnecting slow WebSocket client: send queue full",
+			zap.Int("queued", queued),
+			zap.Int64("coalesced_total", coalesced))
+		c.queue.close()
+		_ = c.Conn.Close()
+		return
+	}
+	if delta, queued, ok := c.queue.coalesceReport(time.Now()); ok {
+		c.connLog().Warn("Coalesced streaming events for slow WebSocket client",
+			zap.Int64("coalesced", delta),
+			zap.Int("queued", queued))
+	}
+}
+
+// writeLoop 连接的写协程：按入队顺序发送，写失败或队列关闭时退出
+func (c *Connection) writeLoop() {
+	for {
+		frames := c.queue.take()
+		if frames == nil {
+			return
+		}
+		for _, f := range frames {
+			if err := c.SendMessage(websocket.TextMessage, f.data); err != nil {
+				c.connLog().Debug("WebSocket write failed, stopping writer", zap.Error(err))
+				c.queue.close()
+				return
+			}
+		}
+	}
+}
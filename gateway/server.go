@@ -2,7 +2,6 @@ package gateway
 
 import (
 	"context"
-	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,20 +20,14 @@ import (
 	"github.com/smallnest/goclaw/bus"
 	"github.com/smallnest/goclaw/channels"
 	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/diskspace"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/metrics"
+	"github.com/smallnest/goclaw/providers"
 	"github.com/smallnest/goclaw/session"
 	"go.uber.org/zap"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// 在生产环境中应该检查 Origin
-		return true
-	},
-}
-
 // Server HTTP 网关服务器
 type Server struct {
 	config        *config.GatewayConfig
@@ -52,7 +45,13 @@ type Server struct {
 	enableAuth      bool
 	authToken       string
 	broadcastSeq    atomic.Uint64
+	eventReplay     *bus.ReplayBuffer // 最近广播的 agent 事件，供 chat.events.since 重放
 	lastHeartbeatMs atomic.Int64
+	preflight       atomic.Pointer[providers.PreflightResult]
+	configCheck     atomic.Pointer[configCheck] // 最近一次配置校验结果，配置变化时重新校验
+	metricsServer   *http.Server // gateway.metrics.port 独立端口时的 /metrics 服务器
+	tlsCerts        *certReloader // enable_tls 时 HTTP / WebSocket 服务器使用的证书
+	upgrader        websocket.Upgrader
 }
 
 // WebSocketConfig WebSocket 配置
@@ -67,64 +66,87 @@ type WebSocketConfig struct {
 	ReadTimeout    time.Duration
 	WriteTimeout   time.Duration
 	MaxMessageSize int64
-	// TLS 配置
+	SendQueueSize  int // 每连接发送队列高水位（帧数），超过后合并流式增量
+	// TLS 配置（gateway.websocket.enable_tls / cert_file / key_file）：开启后 HTTP 与 WebSocket 服务器均使用 HTTPS / WSS
 	EnableTLS bool
 	CertFile  string
 	KeyFile   string
+	// 允许建立 WebSocket 连接的 Origin（gateway.websocket.allowed_origins），为空时仅允许同源与 localhost
+	AllowedOrigins []string
 }
 
-// NewServer 创建网关服务器
-func NewServer(cfg *config.GatewayConfig, messageBus *bus.MessageBus, channelMgr *channels.Manager, sessionMgr *session.Manager) *Server {
-	// 从配置文件获取 WebSocket 设置，如果未配置则使用默认值
-	wsPort := cfg.WebSocket.Port
+// newWebSocketConfig 由配置文件的 gateway.websocket 生成 WebSocket 配置，未配置项使用默认值
+func newWebSocketConfig(cfg *config.WebSocketConfig) *WebSocketConfig {
+	wsPort := cfg.Port
 	if wsPort == 0 {
 		wsPort = 28789 // 默认端口
 	}
-	wsHost := cfg.WebSocket.Host
+	wsHost := cfg.Host
 	if wsHost == "" {
 		wsHost = "0.0.0.0" // 默认监听地址
 	}
-	wsPath := cfg.WebSocket.Path
+	wsPath := cfg.Path
 	if wsPath == "" {
 		wsPath = "/ws" // 默认路径
 	}
-	pingInterval := cfg.WebSocket.PingInterval
+	pingInterval := cfg.PingInterval
 	if pingInterval == 0 {
 		pingInterval = 30 * time.Second
 	}
-	pongTimeout := cfg.WebSocket.PongTimeout
+	pongTimeout := cfg.PongTimeout
 	if pongTimeout == 0 {
 		pongTimeout = 60 * time.Second
 	}
-	readTimeout := cfg.WebSocket.ReadTimeout
+	readTimeout := cfg.ReadTimeout
 	if readTimeout == 0 {
 		readTimeout = 60 * time.Second
 	}
-	writeTimeout := cfg.WebSocket.WriteTimeout
+	writeTimeout := cfg.WriteTimeout
 	if writeTimeout == 0 {
 		writeTimeout = 10 * time.Second
 	}
+	sendQueueSize := cfg.SendQueueSize
+	if sendQueueSize == 0 {
+		sendQueueSize = defaultSendQueueHighWater
+	}
 
-	return &Server{
-		config: cfg,
-		wsConfig: &WebSocketConfig{
-			Host:           wsHost,
-			Port:           wsPort,
-			Path:           wsPath,
-			EnableAuth:     cfg.WebSocket.EnableAuth,
-			AuthToken:      cfg.WebSocket.AuthToken,
-			PingInterval:   pingInterval,
-			PongTimeout:    pongTimeout,
-			ReadTimeout:    readTimeout,
-			WriteTimeout:   writeTimeout,
-			MaxMessageSize: 10 * 1024 * 1024, // 10MB
-		},
+	return &WebSocketConfig{
+		Host:           wsHost,
+		Port:           wsPort,
+		Path:           wsPath,
+		EnableAuth:     cfg.EnableAuth,
+		AuthToken:      cfg.AuthToken,
+		PingInterval:   pingInterval,
+		PongTimeout:    pongTimeout,
+		ReadTimeout:    readTimeout,
+		WriteTimeout:   writeTimeout,
+		MaxMessageSize: 10 * 1024 * 1024, // 10MB
+		SendQueueSize:  sendQueueSize,
+		EnableTLS:      cfg.EnableTLS,
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		AllowedOrigins: cfg.AllowedOrigins,
+	}
+}
+
+// NewServer 创建网关服务器
+func NewServer(cfg *config.GatewayConfig, messageBus *bus.MessageBus, channelMgr *channels.Manager, sessionMgr *session.Manager) *Server {
+	s := &Server{
+		config:      cfg,
+		wsConfig:    newWebSocketConfig(&cfg.WebSocket),
 		bus:         messageBus,
 		channelMgr:  channelMgr,
 		sessionMgr:  sessionMgr,
 		handler:     NewHandler(messageBus, sessionMgr, channelMgr),
 		connections: make(map[string]*Connection),
+		eventReplay: bus.NewReplayBuffer(0, 0),
 	}
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
+	return s
 }
 
 // SetWebSocketConfig 设置 WebSocket 配置
@@ -136,6 +158,13 @@ func (s *Server) SetWebSocketConfig(cfg *WebSocketConfig) {
 	s.authToken = cfg.AuthToken
 }
 
+// websocketConfig 返回当前 WebSocket 配置（配置重载时会被 SetWebSocketConfig 整体替换）
+func (s *Server) websocketConfig() *WebSocketConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.wsConfig
+}
+
 // SetSessionResetPolicy 设置会话重置策略（与 OpenClaw 对齐）；由调用方根据 config.session.reset 注入
 func (s *Server) SetSessionResetPolicy(policy *session.ResetPolicy) {
 	s.handler.SetSessionResetPolicy(policy)
@@ -148,12 +177,34 @@ func (s *Server) Start(ctx context.Context) error {
 		s.mu.Unlock()
 		return fmt.Errorf("server already running")
 	}
+	// enable_tls：HTTP 与 WebSocket 服务器均以 TLS 提供服务，证书不可用时直接失败，而不是退回明文
+	if s.wsConfig.EnableTLS {
+		certs, err := newCertReloader(s.wsConfig.CertFile, s.wsConfig.KeyFile)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.tlsCerts = certs
+	}
+	// 先绑定独立端口的 /metrics：端口被占用时在启动任何服务器与后台任务之前失败
+	if err := s.startMetricsServer(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
 	s.running = true
 	s.mu.Unlock()
 
+	if s.wsConfig.EnableAuth && len(s.wsConfig.AllowedOrigins) == 0 {
+		logger.Warn("WebSocket auth is enabled but gateway.websocket.allowed_origins is not set; only same-host and localhost origins are accepted")
+	}
+
 	s.lastHeartbeatMs.Store(time.Now().UnixMilli())
 	// 注入 presence 与 lastHeartbeat 供 RPC 使用
 	s.handler.SetPresenceProvider(s)
+	s.handler.SetConnectionInfoProvider(s)
+	s.handler.SetEventSubscriber(s)
+	s.handler.SetDeviceConnectionRevoker(s)
+	s.handler.SetEventReplayBuffer(s.eventReplay)
 	s.handler.SetLastHeartbeat(func() int64 { return s.lastHeartbeatMs.Load() })
 
 	// 启动 HTTP 服务器
@@ -170,6 +221,8 @@ func (s *Server) Start(ctx context.Context) error {
 	go s.broadcastOutbound(ctx)
 	// 启动 Agent 事件广播（与 OpenClaw 一致：lifecycle/tool/assistant 供 UI 显示进度）
 	go s.broadcastAgentEvents(ctx)
+	// 启动 cron 任务执行
+	go s.handler.cronRunner.Run(ctx)
 
 	// 监听上下文取消
 	go func() {
@@ -187,6 +240,7 @@ func (s *Server) startHTTPServer(ctx context.Context) error {
 
 	// 健康检查端点
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/ready", s.handleReady)
 
 	// Channels API 端点
 	mux.HandleFunc("/api/channels", s.handleChannelsAPI)
@@ -200,6 +254,14 @@ func (s *Server) startHTTPServer(ctx context.Context) error {
 	// WebSocket 端点（如果使用同一端口）
 	mux.HandleFunc(s.wsConfig.Path, s.handleWebSocket)
 
+	// pprof 调试端点（gateway.pprof.enabled 时启用，需认证）
+	s.registerPprofHandlers(mux)
+
+	// Prometheus 指标（gateway.metrics.enabled 且未配置独立端口时）
+	if s.config.Metrics.Enabled && s.config.Metrics.Port == 0 {
+		mux.HandleFunc("/metrics", s.handleMetrics)
+	}
+
 	// 提供 Control UI
 	if err := s.ServeControlUI(mux); err != nil {
 		logger.Warn("Failed to serve Control UI", zap.Error(err))
@@ -217,9 +279,10 @@ func (s *Server) startHTTPServer(ctx context.Context) error {
 	go func() {
 		logger.Info("HTTP gateway server started",
 			zap.String("addr", s.server.Addr),
+			zap.Bool("tls", s.tlsCerts != nil),
 		)
 
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.listenAndServe(s.server); err != nil && err != http.ErrServerClosed {
 			logger.Error("HTTP gateway server error", zap.Error(err))
 		}
 	}()
@@ -243,6 +306,7 @@ func (s *Server) startWebSocketServer(ctx context.Context) error {
 
 	// 健康检查端点
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/ready", s.handleReady)
 
 	// Channels API 端点
 	mux.HandleFunc("/api/channels", s.handleChannelsAPI)
@@ -260,9 +324,10 @@ func (s *Server) startWebSocketServer(ctx context.Context) error {
 		logger.Info("WebSocket gateway server started",
 			zap.String("addr", s.wsServer.Addr),
 			zap.String("path", s.wsConfig.Path),
+			zap.Bool("tls", s.tlsCerts != nil),
 		)
 
-		if err := s.wsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.listenAndServe(s.wsServer); err != nil && err != http.ErrServerClosed {
 			logger.Error("WebSocket gateway server error", zap.Error(err))
 		}
 	}()
@@ -303,6 +368,16 @@ func (s *Server) Stop() error {
 		}
 	}
 
+	// 停止 /metrics 服务器
+	if s.metricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			logger.Error("Failed to shutdown metrics server", zap.Error(err))
+		}
+	}
+
 	logger.Info("Gateway server stopped")
 	return nil
 }
@@ -316,6 +391,7 @@ func (s *Server) closeAllConnections() {
 		conn.Close()
 		delete(s.connections, id)
 	}
+	metrics.WebSocketConnections.Set(0)
 }
 
 // addConnection 添加连接
@@ -323,6 +399,7 @@ func (s *Server) addConnection(conn *Connection) {
 	s.connectionsMu.Lock()
 	defer s.connectionsMu.Unlock()
 	s.connections[conn.ID] = conn
+	metrics.WebSocketConnections.Set(float64(len(s.connections)))
 }
 
 // removeConnection 移除连接
@@ -330,6 +407,7 @@ func (s *Server) removeConnection(id string) {
 	s.connectionsMu.Lock()
 	defer s.connectionsMu.Unlock()
 	delete(s.connections, id)
+	metrics.WebSocketConnections.Set(float64(len(s.connections)))
 }
 
 // _getConnection 获取连接 (未使用，保留供将来使用)
@@ -374,13 +452,89 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 存活检查：进程在即返回 200（components 仅供参考，组件故障不影响状态码，就绪判断见 /ready）
+	components, _ := s.componentsStatus()
+	detailed := s.healthDetailsAllowed(r)
+	if !detailed {
+		components = publicComponents(components)
+	}
+	result := map[string]interface{}{
+		"status":     "ok",
+		"time":       time.Now().Unix(),
+		"components": components,
+	}
+	code := http.StatusOK
+	// /health?deep：附带数据目录磁盘空间；不足时 degraded，严重不足时 503
+	if _, deep := r.URL.Query()["deep"]; deep {
+		if mon := diskspace.Default(); mon != nil {
+			disk := mon.Check()
+			if detailed {
+				result["disk"] = disk
+			}
+			switch disk.Level {
+			case diskspace.LevelLow:
+				result["status"] = "degraded"
+			case diskspace.LevelCritical:
+				result["status"] = "degraded"
+				code = http.StatusServiceUnavailable
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(code)
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "ok",
-		"time":   time.Now().Unix(),
-	})
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// SetPreflightResult 记录启动预检（providers.preflight）结果，失败时 /ready 返回 503
+func (s *Server) SetPreflightResult(result providers.PreflightResult) {
+	s.preflight.Store(&result)
+}
+
+// handleReady 就绪检查：关键组件（消息总线、Agent、模型提供商、配置）不可用或启动预检失败（且之后没有更新的探测结果）时返回 503，
+// critical 列出不可用的组件（仅认证请求可见）；通道未运行只降级为 degraded，仍返回 200
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	components, critical := s.componentsStatus()
+	detailed := s.healthDetailsAllowed(r)
+	if !detailed {
+		components = publicComponents(components)
+	}
+	result := map[string]interface{}{
+		"status":     "ready",
+		"time":       time.Now().Unix(),
+		"components": components,
+	}
+	code := http.StatusOK
+	switch {
+	case len(critical) > 0:
+		result["status"] = "not_ready"
+		if detailed {
+			result["critical"] = critical
+		}
+		code = http.StatusServiceUnavailable
+	case overallStatus(components, critical) == componentDegraded:
+		result["status"] = "degraded"
+	}
+	if pf := s.preflight.Load(); pf != nil {
+		if detailed {
+			result["preflight"] = pf
+		}
+		if !pf.OK() && !s.preflightSuperseded(pf) {
+			result["status"] = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(result)
 }
 
 // handleFeishuWebhook 飞书 webhook 处理器
@@ -464,14 +618,28 @@ func (s *Server) handleGenericWebhook(w http.ResponseWriter, r *http.Request) {
 
 // handleWebSocket WebSocket 连接处理器
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// 检查认证
-	if s.wsConfig.EnableAuth && !s.authenticateWebSocket(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	// 拒绝不允许的 Origin，防止其他网站在用户浏览器中连接网关（CSWSH）
+	if !s.checkOrigin(r) {
+		logger.Warn("Rejected WebSocket connection from disallowed origin",
+			zap.String("origin", r.Header.Get("Origin")),
+			zap.String("remote_addr", r.RemoteAddr))
+		http.Error(w, "Forbidden: origin not allowed", http.StatusForbidden)
 		return
 	}
 
+	// 检查认证：连接的权限由升级时的 token 决定（auth_token 为完整权限，设备 token 为其 scopes）
+	auth, device := anonymousAuth, (*PairedDevice)(nil)
+	if s.wsConfig.EnableAuth {
+		a, d, ok := s.authenticateToken(requestToken(r))
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		auth, device = a, d
+	}
+
 	// 升级到 WebSocket
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Error("Failed to upgrade to WebSocket", zap.Error(err))
 		return
@@ -480,13 +648,14 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// 创建连接对象（仅生成连接 ID，不创建聊天会话；聊天会话由前端 sessionKey + chat.send/chat.history 触发 GetOrCreate）
 	connection := NewConnection(conn, s.wsConfig)
 	connectionID := connection.ID
+	connection.attachRequest(r)
+	connection.setAuth(auth, device)
 
 	// 添加到连接管理
 	s.addConnection(connection)
 
-	logger.Info("WebSocket connection established",
-		zap.String("connection_id", connectionID),
-		zap.String("remote_addr", r.RemoteAddr),
+	connection.connLog().Info("WebSocket connection established",
+		zap.String("user_agent", r.UserAgent()),
 	)
 
 	// 发送欢迎消息（Params 中 session_id 为连接标识，与聊天 sessionKey 无关，保留字段名兼容前端）
@@ -500,15 +669,16 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = connection.SendJSON(welcome)
 
-	// 启动心跳
+	// 启动心跳与广播写协程
 	go connection.heartbeat()
+	go connection.writeLoop()
 
 	// 处理消息
 	go s.handleWebSocketMessages(connection)
 }
 
-// authenticateWebSocket 验证 WebSocket 连接
-func (s *Server) authenticateWebSocket(r *http.Request) bool {
+// requestToken 从查询参数 token 或 Authorization: Bearer 头中取出 token
+func requestToken(r *http.Request) string {
 	// 从查询参数获取 token
 	token := r.URL.Query().Get("token")
 	if token == "" {
@@ -521,13 +691,7 @@ func (s *Server) authenticateWebSocket(r *http.Request) bool {
 			}
 		}
 	}
-
-	if token == "" {
-		return false
-	}
-
-	// 使用恒定时间比较防止时序攻击
-	return subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1
+	return token
 }
 
 // handleWebSocketMessages 处理 WebSocket 消息（conn.ID 为连接 ID，与聊天 sessionKey 无关）
@@ -536,7 +700,7 @@ func (s *Server) handleWebSocketMessages(conn *Connection) {
 		conn.Close()
 		s.removeConnection(conn.ID)
 		// 关闭原因已在上方 err != nil 分支按 reason 打出，此处仅作连接移除后的兜底日志（无 reason）
-		logger.Debug("WebSocket connection removed", zap.String("connection_id", conn.ID))
+		conn.connLog().Debug("WebSocket connection removed")
 	}()
 
 	for {
@@ -551,13 +715,11 @@ func (s *Server) handleWebSocketMessages(conn *Connection) {
 				reason = "client_close"
 			}
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
-				logger.Info("WebSocket connection closed",
-					zap.String("connection_id", conn.ID),
+				conn.connLog().Info("WebSocket connection closed",
 					zap.String("reason", reason),
 					zap.Error(err))
 			} else {
-				logger.Info("WebSocket connection closed",
-					zap.String("connection_id", conn.ID),
+				conn.connLog().Info("WebSocket connection closed",
 					zap.String("reason", reason))
 			}
 			break
@@ -571,20 +733,24 @@ func (s *Server) handleWebSocketMessages(conn *Connection) {
 		// 解析请求（支持前端 type:"req" 与 JSON-RPC 2.0）
 		req, err := ParseGatewayRequest(data)
 		if err != nil {
-			logger.Error("Failed to parse WebSocket message",
-				zap.String("connection_id", conn.ID),
+			conn.connLog().Error("Failed to parse WebSocket message",
 				zap.Error(err))
 			errorResp := NewGatewayErrorFrame("", "PARSE_ERROR", "Parse error", nil)
 			_ = conn.SendJSON(errorResp)
 			continue
 		}
 
-		logger.Debug("WebSocket request",
-			zap.String("connection_id", conn.ID),
+		conn.connLog().Debug("WebSocket request",
 			zap.String("method", req.Method),
 		)
+		conn.touch(req)
 
 		s.lastHeartbeatMs.Store(time.Now().UnixMilli())
+		// connect 握手可携带 auth.token 重新认证
+		if err := s.applyConnectAuth(conn, req); err != nil {
+			_ = conn.SendJSON(NewGatewayErrorFrame(req.ID, "INVALID_REQUEST", err.Error(), nil))
+			continue
+		}
 		// 处理请求
 		resp := s.handler.HandleRequest(conn.ID, req)
 
@@ -599,14 +765,16 @@ func (s *Server) handleWebSocketMessages(conn *Connection) {
 				code = "INVALID_PARAMS"
 			case ErrorInvalidRequest:
 				code = "INVALID_REQUEST"
+			case ErrorNotFound:
+				code = "NOT_FOUND"
 			}
 			frame = NewGatewayErrorFrame(req.ID, code, resp.Error.Message, nil)
 		} else {
 			frame = NewGatewaySuccess(req.ID, resp.Result)
 		}
 		if err := conn.SendJSON(frame); err != nil {
-			logger.Error("Failed to send WebSocket response",
-				zap.String("connection_id", conn.ID),
+			conn.connLog().Error("Failed to send WebSocket response",
+				zap.String("method", req.Method),
 				zap.Error(err))
 		}
 	}
@@ -700,6 +868,15 @@ func (s *Server) broadcastOutbound(ctx context.Context) {
 					"timestamp": tsMs,
 				},
 			}
+			// 运行失败时附带结构化错误（errorCode、限流时的 retryAfterSeconds），客户端可据此自动退避重试
+			if state == "error" {
+				payload["errorMessage"] = msg.Content
+				for _, key := range []string{"errorCode", "retryAfterSeconds"} {
+					if v, ok := msg.Metadata[key]; ok {
+						payload[key] = v
+					}
+				}
+			}
 			eventFrame := map[string]interface{}{
 				"type":    "event",
 				"event":  "chat",
@@ -712,13 +889,14 @@ func (s *Server) broadcastOutbound(ctx context.Context) {
 				continue
 			}
 
+			// 入队由各连接的写协程发送，慢客户端不阻塞广播；delta 内容为累积文本，积压时同一 run 只保留最新一帧
+			coalesceKey := ""
+			if state == "delta" {
+				coalesceKey = "chat:" + msg.ID
+			}
 			s.connectionsMu.RLock()
 			for _, conn := range s.connections {
-				if err := conn.SendMessage(websocket.TextMessage, notif); err != nil {
-					logger.Error("Failed to broadcast chat event",
-						zap.String("connection_id", conn.ID),
-						zap.Error(err))
-				}
+				conn.Enqueue(notif, coalesceKey)
 			}
 			s.connectionsMu.RUnlock()
 		}
@@ -741,19 +919,23 @@ func (s *Server) broadcastAgentEvents(ctx context.Context) {
 			if payload == nil {
 				continue
 			}
-			eventFrame := map[string]interface{}{
-				"type":    "event",
-				"event":  "agent",
-				"payload": payload,
-			}
-			notif, err := json.Marshal(eventFrame)
+			seq := s.broadcastSeq.Add(1)
+			s.eventReplay.Add(resolveGatewaySessionKey(payload.SessionKey), seq, payload)
+			notif, err := json.Marshal(agentEventFrame(seq, payload))
 			if err != nil {
 				logger.Error("Failed to marshal agent event", zap.Error(err))
 				continue
 			}
+			coalesceKey := ""
+			if payload.Stream == bus.AgentStreamAssistant {
+				coalesceKey = "agent:assistant:" + payload.RunId
+			}
+			sessionKey := resolveGatewaySessionKey(payload.SessionKey)
 			s.connectionsMu.RLock()
 			for _, conn := range s.connections {
-				_ = conn.SendMessage(websocket.TextMessage, notif)
+				if conn.wantsAgentEvent(sessionKey) {
+					conn.Enqueue(notif, coalesceKey)
+				}
 			}
 			s.connectionsMu.RUnlock()
 		}
@@ -770,6 +952,26 @@ type Connection struct {
 	pingInterval time.Duration
 	pongTimeout  time.Duration
 	mu           sync.Mutex
+
+	// 连接元数据（connection.info），由 infoMu 保护
+	infoMu        sync.Mutex
+	remoteAddr    string
+	forwardedFor  string
+	userAgent     string
+	authenticated bool
+	role          string
+	scopes        []string // role 为 device 时的权限范围
+	deviceID      string
+	deviceRole    string // 以设备 token 认证时该 token 的配对角色，用于吊销 / 轮换后定位连接
+	client        string
+	locale        string           // connect 握手携带的语言偏好（Accept-Language 形式），用于本地化系统消息
+	sessionKeys   map[string]int64 // sessionKey -> 最近使用时间（Unix 毫秒）
+	subscriptions map[string]bool  // chat.subscribe 订阅的 sessionKey；nil 表示未订阅，接收全部 agent 事件
+	lastActivity  int64
+	requests      int64
+	log           *zap.Logger // 带 connection_id / remote_addr 的 logger
+
+	queue *sendQueue // 广播事件发送队列，由 writeLoop 消费
 }
 
 // NewConnection 创建连接
@@ -780,6 +982,7 @@ func NewConnection(ws *websocket.Conn, cfg *WebSocketConfig) *Connection {
 		CreatedAt:    time.Now(),
 		pingInterval: cfg.PingInterval,
 		pongTimeout:  cfg.PongTimeout,
+		queue:        newSendQueue(cfg.SendQueueSize),
 	}
 }
 
@@ -876,6 +1079,9 @@ func (s *Server) handleChannelsAPI(w http.ResponseWriter, r *http.Request) {
 
 // Close 关闭连接
 func (c *Connection) Close() error {
+	if c.queue != nil {
+		c.queue.close()
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"strings"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+// diffSessions 实现 sessions.diff：比较两个会话（keyA / keyB）或两次运行（runA / runB，keyB 缺省为 keyA）的对话，
+// 返回按位置对齐的消息与最后一条 assistant 消息的行级差异
+func (h *Handler) diffSessions(params map[string]interface{}) (interface{}, error) {
+	keyA := strings.TrimSpace(getString(params, "keyA"))
+	keyB := strings.TrimSpace(getString(params, "keyB"))
+	runA := strings.TrimSpace(getString(params, "runA"))
+	runB := strings.TrimSpace(getString(params, "runB"))
+	if keyA == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "keyA is required")
+	}
+	if keyB == "" {
+		if runA == "" || runB == "" {
+			return nil, NewRPCError(ErrorInvalidParams, "keyB is required unless comparing runA and runB")
+		}
+		keyB = keyA
+	}
+
+	msgsA, err := h.diffSide(keyA, runA)
+	if err != nil {
+		return nil, err
+	}
+	msgsB, err := h.diffSide(keyB, runB)
+	if err != nil {
+		return nil, err
+	}
+	diff := session.DiffTranscripts(msgsA, msgsB)
+	return map[string]interface{}{
+		"keyA":       resolveGatewaySessionKey(keyA),
+		"keyB":       resolveGatewaySessionKey(keyB),
+		"runA":       runA,
+		"runB":       runB,
+		"divergedAt": diff.DivergedAt,
+		"rows":       diff.Rows,
+		"finalA":     diff.FinalA,
+		"finalB":     diff.FinalB,
+		"hunks":      diff.Hunks,
+	}, nil
+}
+
+// diffSide 只读读取一侧的消息（不重置不新鲜的会话，活动会话不存在时读取归档会话）；指定 runID 时只保留该次运行写入的消息
+func (h *Handler) diffSide(key, runID string) ([]session.Message, error) {
+	sess, err := h.lookupSession(key)
+	if err != nil {
+		return nil, err
+	}
+	msgs := sess.GetHistory(-1)
+	if runID == "" {
+		return msgs, nil
+	}
+	msgs = session.MessagesForRun(msgs, runID)
+	if len(msgs) == 0 {
+		return nil, NewRPCError(ErrorNotFound, "run %s not found in session %s", runID, key)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,31 @@
+package gateway
+
+import "testing"
+
+func TestSessionsDiffIsReadOnly(t *testing.T) {
+	const key = "agent:main:stale"
+	h, sessMgr := newStaleSessionHandler(t, key)
+	diff := func(keyB string) *JSONRPCResponse {
+		return h.HandleRequest("c", &JSONRPCRequest{ID: "1", Method: "sessions.diff", Params: map[string]interface{}{"keyA": key, "keyB": keyB}})
+	}
+
+	if resp := diff("agent:main:missing"); resp.Error == nil || resp.Error.Code != ErrorNotFound {
+		t.Fatalf("diff against unknown key = %+v, want not found", resp.Error)
+	}
+	if sessMgr.Exists("agent:main:missing") {
+		t.Fatal("diff created a session for an unknown key")
+	}
+
+	// 与自身比较：不新鲜的会话不应在读取时被重置
+	resp := diff(key)
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if got := resp.Result.(map[string]interface{})["divergedAt"]; got != 2 {
+		t.Fatalf("divergedAt = %v, want identical transcripts", got)
+	}
+	sess, err := sessMgr.Lookup(key)
+	if err != nil || len(sess.GetHistory(0)) != 2 {
+		t.Fatalf("stale session reset by sessions.diff: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package gateway
+
+import (
+	"strings"
+)
+
+// exportSession sessions.export：{key, format: json | markdown, redactTools} 导出完整对话；
+// json 返回原始消息与会话元数据，markdown 返回渲染后的文本。只读：不重置不新鲜的会话，活动会话不存在时读取归档会话
+func (h *Handler) exportSession(params map[string]interface{}) (interface{}, error) {
+	key := strings.TrimSpace(getString(params, "key"))
+	if key == "" {
+		return nil, NewRPCError(ErrorInvalidParams, "key is required")
+	}
+	format := strings.ToLower(strings.TrimSpace(getString(params, "format")))
+	if format == "" {
+		format = "json"
+	}
+	if format == "md" {
+		format = "markdown"
+	}
+	if format != "json" && format != "markdown" {
+		return nil, NewRPCError(ErrorInvalidParams, "format must be json or markdown, got %q", format)
+	}
+	sess, err := h.lookupSession(key)
+	if err != nil {
+		return nil, err
+	}
+	transcript := sess.Transcript(getBool(params, "redactTools", false))
+	out := map[string]interface{}{
+		"key":          transcript.Key,
+		"format":       format,
+		"messageCount": len(transcript.Messages),
+	}
+	if format == "markdown" {
+		out["content"] = transcript.RenderMarkdown()
+	} else {
+		out["transcript"] = transcript
+	}
+	return out, nil
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+// newStaleSessionHandler 返回一个带空闲重置策略的 Handler，其会话管理器从磁盘读取已不新鲜的 key 会话
+func newStaleSessionHandler(t *testing.T, key string) (*Handler, *session.Manager) {
+	t.Helper()
+	dir := t.TempDir()
+	writer, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := writer.GetOrCreate(key)
+	sess.AddMessage(session.Message{Role: "user", Content: "hello", Timestamp: time.Now().Add(-3 * time.Hour)})
+	sess.AddMessage(session.Message{Role: "assistant", Content: "hi", Timestamp: time.Now().Add(-3 * time.Hour)})
+	sess.UpdatedAt = time.Now().Add(-3 * time.Hour)
+	if err := writer.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	sessMgr, err := session.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil, sessMgr, nil)
+	h.SetSessionResetPolicy(&session.ResetPolicy{Mode: session.ResetModeIdle, IdleMinutes: 30})
+	return h, sessMgr
+}
+
+func TestSessionsExportIsReadOnly(t *testing.T) {
+	const key = "agent:main:stale"
+	h, sessMgr := newStaleSessionHandler(t, key)
+	export := func(key string) *JSONRPCResponse {
+		return h.HandleRequest("c", &JSONRPCRequest{ID: "1", Method: "sessions.export", Params: map[string]interface{}{"key": key}})
+	}
+
+	resp := export(key)
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if n := resp.Result.(map[string]interface{})["messageCount"]; n != 2 {
+		t.Fatalf("messageCount = %v, want the stale history exported as is", n)
+	}
+
+	if resp := export("agent:main:missing"); resp.Error == nil || resp.Error.Code != ErrorNotFound {
+		t.Fatalf("export unknown key = %+v, want not found", resp.Error)
+	}
+	if sessMgr.Exists("agent:main:missing") {
+		t.Fatal("export created a session for an unknown key")
+	}
+
+	if _, err := sessMgr.Archive(key); err != nil {
+		t.Fatal(err)
+	}
+	if resp := export(key); resp.Error != nil || resp.Result.(map[string]interface{})["messageCount"] != 2 {
+		t.Fatalf("export archived session = %+v, %+v", resp.Result, resp.Error)
+	}
+}
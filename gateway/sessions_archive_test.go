@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestSessionsArchiveRejectsActiveRuns(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "agent:main:busy"
+	sess, _ := sessMgr.GetOrCreate(key)
+	if err := sessMgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil, sessMgr, nil)
+	runs := []string{"run-1"}
+	h.SetSessionRunLister(func(sessionKey string) []string {
+		if sessionKey == key {
+			return runs
+		}
+		return nil
+	})
+
+	archive := func() *JSONRPCResponse {
+		return h.HandleRequest("c", &JSONRPCRequest{ID: "1", Method: "sessions.archive", Params: map[string]interface{}{"key": key}})
+	}
+	if resp := archive(); resp.Error == nil || resp.Error.Code != ErrorInvalidRequest {
+		t.Fatalf("archive with an active run = %+v, want invalid request", resp.Error)
+	}
+	if !sessMgr.Exists(key) {
+		t.Fatal("session archived despite the active run")
+	}
+
+	runs = nil
+	if resp := archive(); resp.Error != nil {
+		t.Fatalf("archive after the run finished: %+v", resp.Error)
+	}
+	if sessMgr.Exists(key) {
+		t.Fatal("session still active after archive")
+	}
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+func TestSessionsTouchOnlyExistingSessions(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil, sessMgr, nil)
+	touch := func(key string) *JSONRPCResponse {
+		return h.HandleRequest("c", &JSONRPCRequest{ID: "1", Method: "sessions.touch", Params: map[string]interface{}{"key": key}})
+	}
+
+	// 未知 key 返回 not_found，且不会因此创建会话
+	if resp := touch("agent:main:unknown"); resp.Error == nil || resp.Error.Code != ErrorNotFound {
+		t.Fatalf("touch unknown = %+v, want not found", resp.Error)
+	}
+	if sessMgr.Exists("agent:main:unknown") {
+		t.Fatal("sessions.touch created the unknown session")
+	}
+	if resp := touch(" "); resp.Error == nil || resp.Error.Code != ErrorInvalidParams {
+		t.Fatalf("touch without key = %+v, want invalid params", resp.Error)
+	}
+
+	const key = "agent:main:kept"
+	sess, _ := sessMgr.GetOrCreate(key)
+	if err := sessMgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	resp := touch(key)
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if res := resp.Result.(map[string]interface{}); res["key"] != key || res["ok"] != true {
+		t.Fatalf("touch = %+v", res)
+	}
+
+	// 归档会话不会被 touch 复活为活动会话
+	if _, err := sessMgr.Archive(key); err != nil {
+		t.Fatal(err)
+	}
+	if resp := touch(key); resp.Error == nil || resp.Error.Code != ErrorNotFound {
+		t.Fatalf("touch archived = %+v, want not found", resp.Error)
+	}
+	if sessMgr.Exists(key) {
+		t.Fatal("sessions.touch recreated the archived session")
+	}
+}
@@ -5,14 +5,23 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
-// SkillOverlay 单个技能的覆盖配置（enabled / apiKey）
+// skillInstallTimeout skills.install 下载/克隆与解包的总超时
+const skillInstallTimeout = 2 * time.Minute
+
+// SkillOverlay 单个技能的覆盖配置（enabled / apiKey）；Enabled 为 nil 表示未设置（默认启用）
 type SkillOverlay struct {
-	Enabled bool   `json:"enabled,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
 	APIKey  string `json:"apiKey,omitempty"`
 }
 
+// IsEnabled 技能是否启用（未设置时默认启用）
+func (o SkillOverlay) IsEnabled() bool {
+	return o.Enabled == nil || *o.Enabled
+}
+
 type skillsStore struct {
 	path string
 	mu   sync.RWMutex
@@ -23,6 +32,12 @@ func defaultSkillsOverlayPath() string {
 	return filepath.Join(home, ".goclaw", "skills-overlay.json")
 }
 
+// managedSkillsDir 受网关管理的技能目录（~/.goclaw/skills），skills.install 安装到这里
+func managedSkillsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".goclaw", "skills")
+}
+
 func newSkillsStore(path string) *skillsStore {
 	if path == "" {
 		path = defaultSkillsOverlayPath()
@@ -51,6 +66,11 @@ func (s *skillsStore) Load() (map[string]SkillOverlay, error) {
 	return out, nil
 }
 
+// LoadSkillOverlays 读取默认位置（~/.goclaw/skills-overlay.json）的技能覆盖配置，供 CLI 在网关未运行时使用
+func LoadSkillOverlays() (map[string]SkillOverlay, error) {
+	return newSkillsStore("").Load()
+}
+
 func (s *skillsStore) Save(overlays map[string]SkillOverlay) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -76,7 +96,8 @@ func (s *skillsStore) UpdateSkill(key string, enabled *bool, apiKey *string) err
 	}
 	cur := overlays[key]
 	if enabled != nil {
-		cur.Enabled = *enabled
+		v := *enabled
+		cur.Enabled = &v
 	}
 	if apiKey != nil {
 		cur.APIKey = *apiKey
@@ -84,3 +105,16 @@ func (s *skillsStore) UpdateSkill(key string, enabled *bool, apiKey *string) err
 	overlays[key] = cur
 	return s.Save(overlays)
 }
+
+// RemoveSkill 删除技能的 overlay 条目
+func (s *skillsStore) RemoveSkill(key string) error {
+	overlays, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := overlays[key]; !ok {
+		return nil
+	}
+	delete(overlays, key)
+	return s.Save(overlays)
+}
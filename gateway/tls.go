@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrTLSCertificate 开启 TLS 但证书或私钥缺失、不可读时 Start 返回的错误
+var ErrTLSCertificate = errors.New("gateway TLS certificate unavailable")
+
+// certCheckInterval 检查证书文件是否更新的最小间隔（握手时按需检查）
+const certCheckInterval = 10 * time.Second
+
+// certReloader 提供 TLS 证书，证书或私钥文件更新（如证书轮换）后自动重新加载；加载失败时继续使用旧证书
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader 加载证书与私钥；路径为空或加载失败时返回 ErrTLSCertificate
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	certFile, keyFile = strings.TrimSpace(certFile), strings.TrimSpace(keyFile)
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("%w: gateway.websocket.enable_tls requires cert_file and key_file", ErrTLSCertificate)
+	}
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTLSCertificate, err)
+	}
+	return r, nil
+}
+
+// reload 读取文件修改时间并重新加载证书（调用方持有 mu 或尚未共享）
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("cert_file: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("key_file: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load %s / %s: %w", r.certFile, r.keyFile, err)
+	}
+	r.cert = &cert
+	r.certMod = certInfo.ModTime()
+	r.keyMod = keyInfo.ModTime()
+	return nil
+}
+
+// GetCertificate 供 tls.Config 使用；距上次检查超过 certCheckInterval 且文件有更新时重新加载
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) >= certCheckInterval {
+		r.lastCheck = time.Now()
+		if r.changed() {
+			if err := r.reload(); err != nil {
+				// 证书与私钥可能尚未同时写完，保留旧证书，下次握手再试
+				logger.Warn("Failed to reload TLS certificate, keeping the previous one", zap.Error(err))
+			} else {
+				logger.Info("TLS certificate reloaded", zap.String("cert_file", r.certFile))
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// changed 证书或私钥文件的修改时间是否变化
+func (r *certReloader) changed() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(r.certMod) || !keyInfo.ModTime().Equal(r.keyMod)
+}
+
+// listenAndServe 按 gateway.websocket.enable_tls 以 HTTPS 或 HTTP 启动服务器
+func (s *Server) listenAndServe(srv *http.Server) error {
+	if s.tlsCerts == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: s.tlsCerts.GetCertificate,
+	}
+	return srv.ListenAndServeTLS("", "")
+}
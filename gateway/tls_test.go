@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedPair 生成以 commonName 为主题的自签名证书与私钥并写入 certFile / keyFile
+func writeSelfSignedPair(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedCommonName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloaderLoadsInitialPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSignedPair(t, certFile, keyFile, "first")
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader() error = %v", err)
+	}
+	if cn := servedCommonName(t, r); cn != "first" {
+		t.Errorf("served certificate CN = %q, want first", cn)
+	}
+}
+
+func TestCertReloaderRejectsMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSignedPair(t, certFile, keyFile, "first")
+
+	for _, tc := range []struct{ name, cert, key string }{
+		{"empty paths", "", " "},
+		{"missing cert", filepath.Join(dir, "nope.pem"), keyFile},
+		{"missing key", certFile, filepath.Join(dir, "nope.pem")},
+		{"mismatched pair", keyFile, certFile},
+	} {
+		if _, err := newCertReloader(tc.cert, tc.key); !errors.Is(err, ErrTLSCertificate) {
+			t.Errorf("%s: error = %v, want ErrTLSCertificate", tc.name, err)
+		}
+	}
+}
+
+func TestCertReloaderPicksUpRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSignedPair(t, certFile, keyFile, "first")
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate 写入新证书并推后修改时间（避免文件系统时间精度导致 mtime 不变），再让下次握手跳过检查间隔
+	rotate := func(write func()) {
+		write()
+		future := time.Now().Add(time.Minute)
+		for _, f := range []string{certFile, keyFile} {
+			if err := os.Chtimes(f, future, future); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.mu.Lock()
+		r.lastCheck = time.Time{}
+		r.mu.Unlock()
+	}
+
+	rotate(func() { writeSelfSignedPair(t, certFile, keyFile, "second") })
+	if cn := servedCommonName(t, r); cn != "second" {
+		t.Fatalf("served certificate CN after rotation = %q, want second", cn)
+	}
+
+	// 检查间隔内不重新加载
+	writeSelfSignedPair(t, certFile, keyFile, "third")
+	if cn := servedCommonName(t, r); cn != "second" {
+		t.Errorf("certificate reloaded within the check interval: CN = %q", cn)
+	}
+
+	// 新文件无法加载时继续使用旧证书
+	rotate(func() {
+		if err := os.WriteFile(keyFile, []byte("not a key"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if cn := servedCommonName(t, r); cn != "second" {
+		t.Errorf("served certificate CN after a broken rotation = %q, want second", cn)
+	}
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/providers"
+	"github.com/smallnest/goclaw/session"
+)
+
+// unknownUsageBucket 价格表中没有的模型 / 无法判断的提供商归入该分组
+const unknownUsageBucket = "unknown"
+
+// usageCostBucket usage.cost 中按模型或提供商汇总的一组
+type usageCostBucket struct {
+	InputTokens  int64    `json:"inputTokens"`
+	OutputTokens int64    `json:"outputTokens"`
+	TotalTokens  int64    `json:"totalTokens"`
+	Cost         float64  `json:"cost"`
+	Sessions     int      `json:"sessions"`
+	Models       []string `json:"models,omitempty"` // unknown 分组中的原始模型名
+}
+
+func (b *usageCostBucket) add(est session.HistoryTokenEstimate, cost float64) {
+	b.InputTokens += est.Input
+	b.OutputTokens += est.Output
+	b.TotalTokens += est.Total
+	b.Cost += cost
+	b.Sessions++
+}
+
+// parseUsageDateRange 解析 startDate / endDate（YYYY-MM-DD，本地时区，endDate 含当天），未传时对应边界为零值
+func parseUsageDateRange(params map[string]interface{}) (start, end time.Time, err error) {
+	if s := getString(params, "startDate"); s != "" {
+		if start, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			return start, end, fmt.Errorf("invalid startDate %q: expected YYYY-MM-DD", s)
+		}
+	}
+	if s := getString(params, "endDate"); s != "" {
+		if end, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			return start, end, fmt.Errorf("invalid endDate %q: expected YYYY-MM-DD", s)
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// sessionUsageModel 会话使用的模型：会话覆盖（modelOverride）> 所属 agent 的模型 > 默认模型
+func sessionUsageModel(cfg *config.Config, sess *session.Session) string {
+	if model, _ := sess.GetMetadata(session.MetadataModelOverride).(string); model != "" {
+		return model
+	}
+	if cfg == nil {
+		return ""
+	}
+	if agentID, _, ok := session.ParseAgentSessionKey(sess.Key); ok {
+		for _, a := range cfg.Agents.List {
+			if a.ID == agentID && a.Model != "" {
+				return a.Model
+			}
+		}
+	}
+	return cfg.Agents.Defaults.Model
+}
+
+// computeUsageCost 遍历会话，按消息估算 token（可按消息时间过滤）乘以模型单价汇总费用
+func (h *Handler) computeUsageCost(start, end time.Time) (map[string]interface{}, error) {
+	keys, err := h.sessionMgr.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	cfg := config.Get()
+	var overrides map[string]config.ModelPricing
+	if cfg != nil {
+		overrides = cfg.Providers.Pricing
+	}
+	pricing := providers.NewPricingTable(overrides)
+
+	var total float64
+	var tokens session.HistoryTokenEstimate
+	var recorded session.TokenUsage
+	byModel := make(map[string]*usageCostBucket)
+	byProvider := make(map[string]*usageCostBucket)
+	bucket := func(m map[string]*usageCostBucket, name string) *usageCostBucket {
+		if m[name] == nil {
+			m[name] = &usageCostBucket{}
+		}
+		return m[name]
+	}
+
+	for _, key := range keys {
+		sess, err := h.getSession(key)
+		if err != nil {
+			continue
+		}
+		if usage, ok := sess.TokenUsage(); ok {
+			recorded.Add(usage)
+		}
+		history := sess.GetHistory(-1)
+		if !start.IsZero() || !end.IsZero() {
+			filtered := history[:0:0]
+			for _, msg := range history {
+				if (!start.IsZero() && msg.Timestamp.Before(start)) || (!end.IsZero() && !msg.Timestamp.Before(end)) {
+					continue
+				}
+				filtered = append(filtered, msg)
+			}
+			history = filtered
+		}
+		if len(history) == 0 {
+			continue
+		}
+		est := session.EstimateHistoryTokens(history)
+		tokens.Input += est.Input
+		tokens.Output += est.Output
+		tokens.Total += est.Total
+
+		model := sessionUsageModel(cfg, sess)
+		provider := providers.ProviderNameForModel(model)
+		if provider == "" {
+			provider = unknownUsageBucket
+		}
+		var cost float64
+		if price, name, ok := pricing.Lookup(model); ok {
+			cost = providers.TokenCost(price, est.Input, est.Output)
+			bucket(byModel, name).add(est, cost)
+		} else {
+			b := bucket(byModel, unknownUsageBucket)
+			b.add(est, 0)
+			if model == "" {
+				model = "(none)"
+			}
+			if !slices.Contains(b.Models, model) {
+				b.Models = append(b.Models, model)
+			}
+		}
+		bucket(byProvider, provider).add(est, cost)
+		total += cost
+	}
+
+	return map[string]interface{}{
+		"total":           total,
+		"currency":        "USD",
+		"byModel":         byModel,
+		"byProvider":      byProvider,
+		"estimatedTokens": tokens,
+		"tokens":          recorded, // 会话记录的调用用量（不受日期过滤影响）
+	}, nil
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/session"
+)
+
+// usageTimeseriesPoint sessions.usage.timeseries 的一个时间桶
+type usageTimeseriesPoint struct {
+	Ts              int64 `json:"ts"` // 桶起始时间（毫秒，本地时区对齐）
+	MessageCount    int   `json:"messageCount"`
+	EstimatedTokens int64 `json:"estimatedTokens"`
+}
+
+// usageBucketStart 返回 t 所在桶（hour / day / week，周从周一开始）的起始时间
+func usageBucketStart(t time.Time, interval string) time.Time {
+	t = t.Local()
+	switch interval {
+	case "hour":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// parseUsageInterval 解析 interval 参数，缺省为 day
+func parseUsageInterval(params map[string]interface{}) (string, error) {
+	interval := strings.ToLower(strings.TrimSpace(getString(params, "interval")))
+	switch interval {
+	case "":
+		return "day", nil
+	case "hour", "day", "week":
+		return interval, nil
+	default:
+		return "", fmt.Errorf("invalid interval %q: expected hour, day or week", interval)
+	}
+}
+
+// computeUsageTimeseries 按消息时间戳把会话消息分桶统计；key 为空时汇总所有会话
+func (h *Handler) computeUsageTimeseries(key, interval string) ([]usageTimeseriesPoint, error) {
+	keys := []string{key}
+	if key == "" {
+		var err error
+		if keys, err = h.sessionMgr.List(); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+	}
+
+	buckets := make(map[int64]*usageTimeseriesPoint)
+	for _, k := range keys {
+		sess, err := h.getSession(k)
+		if err != nil {
+			if key != "" {
+				return nil, err
+			}
+			continue
+		}
+		for _, msg := range sess.GetHistory(-1) {
+			if msg.Timestamp.IsZero() {
+				continue
+			}
+			ts := usageBucketStart(msg.Timestamp, interval).UnixMilli()
+			p := buckets[ts]
+			if p == nil {
+				p = &usageTimeseriesPoint{Ts: ts}
+				buckets[ts] = p
+			}
+			p.MessageCount++
+			p.EstimatedTokens += session.EstimateHistoryTokens([]session.Message{msg}).Total
+		}
+	}
+
+	points := make([]usageTimeseriesPoint, 0, len(buckets))
+	for _, p := range buckets {
+		points = append(points, *p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Ts < points[j].Ts })
+	return points, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 )
 
@@ -78,6 +79,15 @@ func EnsureBuiltinSkills() error {
 	return nil
 }
 
+// IsBuiltinSkill 技能是否为内置技能（EnsureBuiltinSkills 会在启动时恢复缺失的内置技能）
+func IsBuiltinSkill(name string) bool {
+	if name == "" || name == "." {
+		return false
+	}
+	info, err := fs.Stat(builtinSkillsFS, path.Join("builtin_skills", name))
+	return err == nil && info.IsDir()
+}
+
 // copySingleSkill 复制单个技能
 func copySingleSkill(skillName, dstDir string) error {
 	srcDir := filepath.Join("builtin_skills", skillName)
@@ -0,0 +1,169 @@
+// Package diskspace 定期检查 goclaw 数据目录所在卷的剩余空间（会话、日志、记忆都写在这里）。
+// 低于 gateway.min_free_disk_mb 时告警；低于其 1/4 视为严重不足，可配置暂停新的运行，避免写入失败损坏文件。
+package diskspace
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// 空间状态
+const (
+	LevelOK       = "ok"
+	LevelLow      = "low"      // 低于阈值
+	LevelCritical = "critical" // 低于阈值的 1/4
+	LevelUnknown  = "unknown"  // 读取失败或尚未检查
+)
+
+// DefaultMinFreeMB 默认告警阈值（MB）
+const DefaultMinFreeMB = 500
+
+// DefaultInterval 默认检查间隔
+const DefaultInterval = time.Minute
+
+// ErrDiskCritical 剩余空间严重不足且配置了暂停新运行
+var ErrDiskCritical = errors.New("disk space critically low; new runs are paused")
+
+// statFn 读取卷空间，测试中可替换
+var statFn = statDisk
+
+// Status 一次检查的结果
+type Status struct {
+	Path      string `json:"path"`
+	Level     string `json:"level"`
+	FreeMB    uint64 `json:"freeMb"`
+	TotalMB   uint64 `json:"totalMb"`
+	MinFreeMB int    `json:"minFreeMb"`
+	CheckedAt int64  `json:"checkedAt"` // Unix 毫秒
+	Error     string `json:"error,omitempty"`
+}
+
+// Monitor 数据目录剩余空间监控
+type Monitor struct {
+	path      string
+	minFreeMB int
+	pauseRuns bool
+	interval  time.Duration
+
+	mu   sync.RWMutex
+	last Status
+}
+
+// NewMonitor 创建监控；minFreeMB <= 0 时使用 DefaultMinFreeMB，pauseRuns 为 true 时严重不足会拒绝新运行
+func NewMonitor(path string, minFreeMB int, pauseRuns bool) *Monitor {
+	if minFreeMB <= 0 {
+		minFreeMB = DefaultMinFreeMB
+	}
+	return &Monitor{
+		path:      path,
+		minFreeMB: minFreeMB,
+		pauseRuns: pauseRuns,
+		interval:  DefaultInterval,
+		last:      Status{Path: path, Level: LevelUnknown, MinFreeMB: minFreeMB},
+	}
+}
+
+// Start 立即检查一次，之后按间隔定期检查，直到 ctx 取消
+func (m *Monitor) Start(ctx context.Context) {
+	m.Check()
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Check 立即检查并更新状态；级别变化时记录日志（变差为 Warn/Error，恢复为 Info）
+func (m *Monitor) Check() Status {
+	st := Status{Path: m.path, MinFreeMB: m.minFreeMB, CheckedAt: time.Now().UnixMilli()}
+	free, total, err := statFn(m.path)
+	if err != nil {
+		st.Level = LevelUnknown
+		st.Error = err.Error()
+	} else {
+		st.FreeMB = free / (1024 * 1024)
+		st.TotalMB = total / (1024 * 1024)
+		switch {
+		case st.FreeMB < uint64(m.minFreeMB)/4:
+			st.Level = LevelCritical
+		case st.FreeMB < uint64(m.minFreeMB):
+			st.Level = LevelLow
+		default:
+			st.Level = LevelOK
+		}
+	}
+
+	m.mu.Lock()
+	prev := m.last.Level
+	m.last = st
+	m.mu.Unlock()
+
+	if st.Level != prev {
+		fields := []zap.Field{
+			zap.String("path", st.Path),
+			zap.Uint64("free_mb", st.FreeMB),
+			zap.Int("min_free_mb", st.MinFreeMB),
+		}
+		switch st.Level {
+		case LevelCritical:
+			logger.Error("DISK SPACE CRITICALLY LOW: sessions, logs and memory may fail to save", append(fields, zap.Bool("runs_paused", m.pauseRuns))...)
+		case LevelLow:
+			logger.Warn("Disk space low in goclaw data dir", fields...)
+		case LevelUnknown:
+			logger.Warn("Failed to check disk space", zap.String("path", st.Path), zap.String("error", st.Error))
+		default:
+			if prev != LevelUnknown {
+				logger.Info("Disk space back to normal", fields...)
+			}
+		}
+	}
+	return st
+}
+
+// Status 返回最近一次检查结果
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.last
+}
+
+// AllowRun 严重不足且配置了暂停时返回 ErrDiskCritical
+func (m *Monitor) AllowRun() error {
+	if m.pauseRuns && m.Status().Level == LevelCritical {
+		return ErrDiskCritical
+	}
+	return nil
+}
+
+var defaultMonitor atomic.Pointer[Monitor]
+
+// SetDefault 设置进程级监控（gateway 启动时设置，供 status/health 与 agent 运行前检查共用）
+func SetDefault(m *Monitor) {
+	defaultMonitor.Store(m)
+}
+
+// Default 返回进程级监控，未设置时为 nil
+func Default() *Monitor {
+	return defaultMonitor.Load()
+}
+
+// AllowRun 使用进程级监控检查是否允许新运行；未设置监控时总是允许
+func AllowRun() error {
+	if m := Default(); m != nil {
+		return m.AllowRun()
+	}
+	return nil
+}
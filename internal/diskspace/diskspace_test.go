@@ -0,0 +1,57 @@
+package diskspace
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMonitorReportsLowAndPausesRunsWhenCritical(t *testing.T) {
+	const mb = 1024 * 1024
+	free := uint64(2000 * mb)
+	orig := statFn
+	statFn = func(path string) (uint64, uint64, error) { return free, 100000 * mb, nil }
+	defer func() { statFn = orig }()
+
+	m := NewMonitor("/data/.goclaw", 1000, true)
+	if st := m.Status(); st.Level != LevelUnknown {
+		t.Fatalf("status before first check = %q, want unknown", st.Level)
+	}
+	if st := m.Check(); st.Level != LevelOK || st.FreeMB != 2000 || st.TotalMB != 100000 {
+		t.Fatalf("Check() = %+v, want ok with 2000MB free", st)
+	}
+
+	free = 600 * mb
+	if st := m.Check(); st.Level != LevelLow {
+		t.Fatalf("Check() level = %q, want low", st.Level)
+	}
+	if err := m.AllowRun(); err != nil {
+		t.Errorf("low (not critical) space should not pause runs: %v", err)
+	}
+
+	free = 100 * mb
+	if st := m.Check(); st.Level != LevelCritical {
+		t.Fatalf("Check() level = %q, want critical", st.Level)
+	}
+	if err := m.AllowRun(); !errors.Is(err, ErrDiskCritical) {
+		t.Errorf("AllowRun() = %v, want ErrDiskCritical", err)
+	}
+
+	// 未开启暂停时只告警
+	if err := NewMonitor("/data/.goclaw", 1000, false).AllowRun(); err != nil {
+		t.Errorf("AllowRun() without pause = %v", err)
+	}
+
+	SetDefault(m)
+	defer SetDefault(nil)
+	if err := AllowRun(); !errors.Is(err, ErrDiskCritical) {
+		t.Errorf("package AllowRun() = %v, want ErrDiskCritical", err)
+	}
+
+	statFn = func(path string) (uint64, uint64, error) { return 0, 0, errors.New("no such volume") }
+	if st := m.Check(); st.Level != LevelUnknown || st.Error == "" {
+		t.Errorf("stat failure should report unknown with error: %+v", st)
+	}
+	if err := m.AllowRun(); err != nil {
+		t.Errorf("unknown level should not pause runs: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package diskspace
+
+import "golang.org/x/sys/unix"
+
+// statDisk 返回 path 所在卷的可用字节数与总字节数
+func statDisk(path string) (free, total uint64, err error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), uint64(st.Blocks) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package diskspace
+
+import "golang.org/x/sys/windows"
+
+// statDisk 返回 path 所在卷的可用字节数与总字节数
+func statDisk(path string) (free, total uint64, err error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var avail, totalBytes, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &avail, &totalBytes, &totalFree); err != nil {
+		return 0, 0, err
+	}
+	return avail, totalBytes, nil
+}
@@ -0,0 +1,256 @@
+// Package i18n 系统生成消息（限流提示、上下文溢出、欢迎语等）的多语言文案。
+// 语言由 gateway.locale 决定（en / zh），也可按连接指定语言（TFor，如 connect 握手的 locale）；
+// 未配置或不支持的语言回退到英文。
+package i18n
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// 支持的语言
+const (
+	LocaleEN = "en"
+	LocaleZH = "zh"
+)
+
+// Key 消息目录中的文案键
+type Key string
+
+// 系统消息文案键
+const (
+	RunBudgetExceeded   Key = "run.budget_exceeded"
+	RunRateLimitedRetry Key = "run.rate_limited_retry" // 参数：等待秒数
+	RunRateLimited      Key = "run.rate_limited"
+	RunContextOverflow  Key = "run.context_overflow"
+	RunEmptyReply       Key = "run.empty_reply"
+	RunDiskCritical     Key = "run.disk_critical"
+	RunToolErrorStop    Key = "run.tool_error_stop"
+	RunModelNoVision    Key = "run.model_no_vision"
+	RunModelCapability  Key = "run.model_capability"
+	RunAborted          Key = "run.aborted"
+	RunTimeout          Key = "run.timeout"
+	RunProviderDown     Key = "run.provider_unavailable"
+	ChannelWelcome      Key = "channel.welcome"
+	ChannelStatus       Key = "channel.status" // 参数：在线状态文案
+	ChannelOnline       Key = "channel.online"
+	ChannelOffline      Key = "channel.offline"
+	ChannelStatusDenied Key = "channel.status_denied"
+	CommandNew          Key = "command.new"
+	CommandClear        Key = "command.clear"
+	CommandModelSet     Key = "command.model_set" // 参数：模型名
+	CommandModelReset   Key = "command.model_reset"
+	CommandModelCurrent Key = "command.model_current" // 参数：当前模型、命令前缀
+	CommandHelp         Key = "command.help"
+	CommandHelpNew      Key = "command.help_new"
+	CommandHelpModel    Key = "command.help_model"
+	CommandHelpClear    Key = "command.help_clear"
+	CommandHelpHelp     Key = "command.help_help"
+	SessionResetIdle    Key = "session.reset_idle"
+	SessionResetDaily   Key = "session.reset_daily"
+)
+
+// message 一条文案：def 为文案的原有语言，仅在缺少英文文案时使用
+type message struct {
+	def  string
+	text map[string]string
+}
+
+var catalog = map[Key]message{
+	RunBudgetExceeded: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "今日 token 预算已用完，请明天再试（或调整 providers.daily_token_budget）。",
+		LocaleEN: "Today's token budget is used up. Try again tomorrow (or raise providers.daily_token_budget).",
+	}},
+	RunRateLimitedRetry: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "请求过于频繁或模型暂时限流，请 %d 秒后再试。",
+		LocaleEN: "Too many requests or the model is rate limited. Please try again in %d seconds.",
+	}},
+	RunRateLimited: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "请求过于频繁或模型暂时限流，请稍后再试。",
+		LocaleEN: "Too many requests or the model is rate limited. Please try again later.",
+	}},
+	RunContextOverflow: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "对话内容超出模型上下文长度，截断与压缩后仍无法继续，请重置会话后再试。",
+		LocaleEN: "The conversation exceeds the model's context window even after trimming and compaction. Reset the session and try again.",
+	}},
+	RunEmptyReply: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "LLM 返回了空回复，请稍后重试或检查模型与代理配置（若为 9router/心流可尝试 model_request_interval_seconds 或更换模型）",
+		LocaleEN: "The LLM returned an empty reply. Retry later or check the model and proxy settings (for 9router/iFlow try model_request_interval_seconds or another model)",
+	}},
+	RunDiskCritical: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "服务器磁盘空间严重不足，已暂停新的对话以免丢失数据，请联系管理员清理空间。",
+		LocaleEN: "The server is critically low on disk space. New runs are paused to avoid losing data; ask the administrator to free up space.",
+	}},
+	RunToolErrorStop: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "工具 %[1]s 连续 %[2]d 次执行失败，已停止本次运行：%[3]s",
+		LocaleEN: "Stopped the run after tool %[1]s failed %[2]d time(s) in a row: %[3]s",
+	}},
+	RunModelNoVision: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "当前模型 %s 不支持图片，请发送文字，或在 agents.defaults.capability_models.vision 中配置支持图片的模型。",
+		LocaleEN: "The current model %s doesn't support images. Send text instead, or configure an image-capable model in agents.defaults.capability_models.vision.",
+	}},
+	RunModelCapability: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "当前模型 %[1]s 不支持 %[2]s，请在 agents.defaults.capability_models.%[2]s 中配置支持该能力的模型。",
+		LocaleEN: "The current model %[1]s doesn't support %[2]s. Configure a capable model in agents.defaults.capability_models.%[2]s.",
+	}},
+	RunAborted: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "本次运行已中止。",
+		LocaleEN: "The run was aborted.",
+	}},
+	RunTimeout: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "模型响应超时，请稍后重试（或调大 agents.defaults.run_timeout_seconds）。",
+		LocaleEN: "The model took too long to respond. Please try again later (or raise agents.defaults.run_timeout_seconds).",
+	}},
+	RunProviderDown: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "模型服务暂时不可用，请稍后再试。",
+		LocaleEN: "The model service is temporarily unavailable. Please try again later.",
+	}},
+	ChannelWelcome: {def: LocaleEN, text: map[string]string{
+		LocaleZH: "👋 欢迎使用 goclaw!\n\n我可以帮助你完成各种任务。发送 /help 查看可用命令。",
+		LocaleEN: "👋 Welcome to goclaw!\n\nI can help you with various tasks. Send /help to see available commands.",
+	}},
+	ChannelStatus: {def: LocaleEN, text: map[string]string{
+		LocaleZH: "✅ goclaw 运行中\n\n通道状态: %s",
+		LocaleEN: "✅ goclaw is running\n\nChannel status: %s",
+	}},
+	ChannelOnline: {def: LocaleEN, text: map[string]string{
+		LocaleZH: "🟢 在线",
+		LocaleEN: "🟢 Online",
+	}},
+	ChannelOffline: {def: LocaleEN, text: map[string]string{
+		LocaleZH: "🔴 离线",
+		LocaleEN: "🔴 Offline",
+	}},
+	ChannelStatusDenied: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "你无权查看状态",
+		LocaleEN: "You are not allowed to view the status",
+	}},
+	CommandNew: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "已开启新会话，之前的对话记录与会话设置已清空。",
+		LocaleEN: "Started a new session. Previous history and session settings were cleared.",
+	}},
+	CommandClear: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "已清空对话记录（会话设置保留）。",
+		LocaleEN: "Cleared the conversation history (session settings kept).",
+	}},
+	CommandModelSet: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "本会话模型已切换为 %s。",
+		LocaleEN: "This session now uses model %s.",
+	}},
+	CommandModelReset: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "本会话已恢复默认模型。",
+		LocaleEN: "This session is back on the default model.",
+	}},
+	CommandModelCurrent: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "当前模型：%[1]s\n发送 %[2]smodel <模型名> 切换，%[2]smodel default 恢复默认。",
+		LocaleEN: "Current model: %[1]s\nSend %[2]smodel <name> to switch, %[2]smodel default to revert.",
+	}},
+	CommandHelp: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "可用命令：",
+		LocaleEN: "Available commands:",
+	}},
+	CommandHelpNew: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "开启新会话（清空记录与会话设置）",
+		LocaleEN: "start a new session (clears history and session settings)",
+	}},
+	CommandHelpModel: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "查看或切换本会话使用的模型",
+		LocaleEN: "show or switch the model used by this session",
+	}},
+	CommandHelpClear: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "清空对话记录，保留会话设置",
+		LocaleEN: "clear the conversation history, keep session settings",
+	}},
+	CommandHelpHelp: {def: LocaleZH, text: map[string]string{
+		LocaleZH: "显示本帮助",
+		LocaleEN: "show this help",
+	}},
+	SessionResetIdle: {def: LocaleEN, text: map[string]string{
+		LocaleZH: "之前的对话因长时间无活动已重置。",
+		LocaleEN: "Previous conversation was reset due to inactivity.",
+	}},
+	SessionResetDaily: {def: LocaleEN, text: map[string]string{
+		LocaleZH: "之前的对话已按每日会话重置清空。",
+		LocaleEN: "Previous conversation was reset by the daily session reset.",
+	}},
+}
+
+// Normalize 将 zh-CN / en_US 等规范为支持的语言；不支持或为空时返回空字符串
+func Normalize(locale string) string {
+	l := strings.ToLower(strings.TrimSpace(locale))
+	switch {
+	case strings.HasPrefix(l, LocaleZH):
+		return LocaleZH
+	case strings.HasPrefix(l, LocaleEN):
+		return LocaleEN
+	}
+	return ""
+}
+
+// Locale 返回当前配置的语言（gateway.locale），未配置时为空字符串
+func Locale() string {
+	cfg := config.Get()
+	if cfg == nil {
+		return ""
+	}
+	return Normalize(cfg.Gateway.Locale)
+}
+
+// T 按当前语言渲染文案；未配置语言时使用英文
+func T(key Key, args ...interface{}) string {
+	return TWithDefault("", key, args...)
+}
+
+// TWithDefault 同 T，但未配置语言时使用 def（用于同一文案在不同渠道原本语言不同的情况）
+func TWithDefault(def string, key Key, args ...interface{}) string {
+	locale := Locale()
+	if locale == "" {
+		locale = def
+	}
+	return render(locale, key, args...)
+}
+
+// TFor 按指定语言渲染文案（如连接握手携带的 locale，可为 Accept-Language 形式 "fr-FR, zh;q=0.8"）；
+// hint 为空时同 T，均不支持时使用英文
+func TFor(hint string, key Key, args ...interface{}) string {
+	if strings.TrimSpace(hint) == "" {
+		return T(key, args...)
+	}
+	locale := Negotiate(hint)
+	if locale == "" {
+		locale = LocaleEN
+	}
+	return render(locale, key, args...)
+}
+
+// Negotiate 从 Accept-Language 形式的列表中按顺序（忽略 q 值）选出第一个支持的语言，没有时返回空字符串
+func Negotiate(hint string) string {
+	for _, part := range strings.Split(hint, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if locale := Normalize(tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// render 按 locale 渲染；locale 为空或不支持时使用英文，缺少英文文案时回退到原有语言
+func render(locale string, key Key, args ...interface{}) string {
+	msg, ok := catalog[key]
+	if !ok {
+		return string(key)
+	}
+	text, ok := msg.text[locale]
+	if !ok {
+		text, ok = msg.text[LocaleEN]
+	}
+	if !ok {
+		text = msg.text[msg.def]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// dailyFileLayout 按日期切换的日志文件名中的日期格式
+const dailyFileLayout = "2006-01-02"
+
+// dailyFile 按日期写入 dir/goclaw-2006-01-02.log：跨天后的第一次写入切换到新日期的文件，长时间运行的进程也按天分文件
+type dailyFile struct {
+	mu   sync.Mutex
+	dir  string
+	day  string
+	file *os.File
+	now  func() time.Time
+}
+
+func newDailyFile(dir string) (*dailyFile, error) {
+	d := &dailyFile{dir: dir, now: time.Now}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.rotateLocked(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DailyFilePath 返回 dir 下 t 当天的日志文件路径
+func DailyFilePath(dir string, t time.Time) string {
+	return filepath.Join(dir, "goclaw-"+t.Format(dailyFileLayout)+".log")
+}
+
+// rotateLocked 日期变化时关闭旧文件并打开（追加）当天的文件
+func (d *dailyFile) rotateLocked() error {
+	now := d.now()
+	day := now.Format(dailyFileLayout)
+	if d.file != nil && day == d.day {
+		return nil
+	}
+	f, err := os.OpenFile(DailyFilePath(d.dir, now), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if d.file != nil {
+		_ = d.file.Close()
+	}
+	d.file, d.day = f, day
+	return nil
+}
+
+func (d *dailyFile) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.rotateLocked(); err != nil && d.file == nil {
+		return 0, err
+	}
+	// 新文件打开失败时继续写旧文件，不丢日志
+	return d.file.Write(p)
+}
+
+func (d *dailyFile) Sync() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Sync()
+}
+
+// Path 当前写入的文件路径
+func (d *dailyFile) Path() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return ""
+	}
+	return d.file.Name()
+}
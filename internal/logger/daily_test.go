@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDailyFileRotatesAtDayBoundary(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 23, 59, 0, 0, time.Local)
+	d := &dailyFile{dir: dir, now: func() time.Time { return now }}
+
+	if _, err := d.Write([]byte("before midnight\n")); err != nil {
+		t.Fatal(err)
+	}
+	first := d.Path()
+	if first != DailyFilePath(dir, now) {
+		t.Fatalf("path = %q, want %q", first, DailyFilePath(dir, now))
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := d.Write([]byte("after midnight\n")); err != nil {
+		t.Fatal(err)
+	}
+	if d.Path() != DailyFilePath(dir, now) || d.Path() == first {
+		t.Fatalf("path after midnight = %q", d.Path())
+	}
+	_ = d.Sync()
+
+	for path, want := range map[string]string{first: "before midnight\n", d.Path(): "after midnight\n"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("%s = %q, want %q", path, data, want)
+		}
+	}
+}
@@ -15,6 +15,8 @@ var (
 	logMutex    sync.RWMutex
 	once        sync.Once
 	initialized bool
+	filePath    string     // InitWithFile 写入的日志文件，空表示只输出到 stdout/stderr
+	daily       *dailyFile // InitWithDailyFile 按日期切换的日志文件
 )
 
 // Init 初始化日志 (线程安全)，仅输出到 stdout/stderr
@@ -28,13 +30,22 @@ func Init(level string, development bool) error {
 func InitWithFile(level string, development bool, logFile string) error {
 	var initErr error
 	once.Do(func() {
-		initErr = doInit(level, development, logFile)
+		initErr = doInit(level, development, logFile, "")
+	})
+	return initErr
+}
+
+// InitWithDailyFile 初始化日志并同时写入 dir/goclaw-2006-01-02.log (线程安全)；跨天后自动切换到新日期的文件
+func InitWithDailyFile(level string, development bool, dir string) error {
+	var initErr error
+	once.Do(func() {
+		initErr = doInit(level, development, "", dir)
 	})
 	return initErr
 }
 
 // doInit 执行实际的日志初始化
-func doInit(level string, development bool, logFile string) error {
+func doInit(level string, development bool, logFile, dailyDir string) error {
 	// 解析日志级别
 	var zapLevel zapcore.Level
 	switch level {
@@ -83,8 +94,20 @@ func doInit(level string, development bool, logFile string) error {
 		ErrorOutputPaths: errorOutputPaths,
 	}
 
+	opts := []zap.Option{zap.AddCallerSkip(1)}
+	var dailyOut *dailyFile
+	if dailyDir != "" {
+		d, err := newDailyFile(dailyDir)
+		if err != nil {
+			return err
+		}
+		dailyOut = d
+		fileCore := zapcore.NewCore(zapcore.NewConsoleEncoder(config.EncoderConfig), d, config.Level)
+		opts = append(opts, zap.WrapCore(func(c zapcore.Core) zapcore.Core { return zapcore.NewTee(c, fileCore) }))
+	}
+
 	// 创建 logger
-	newLog, err := config.Build(zap.AddCallerSkip(1))
+	newLog, err := config.Build(opts...)
 	if err != nil {
 		return err
 	}
@@ -94,6 +117,8 @@ func doInit(level string, development bool, logFile string) error {
 	log = newLog
 	sugar = log.Sugar()
 	initialized = true
+	filePath = logFile
+	daily = dailyOut
 	logMutex.Unlock()
 
 	return nil
@@ -135,6 +160,16 @@ func S() *zap.SugaredLogger {
 	return sugar
 }
 
+// FilePath 返回当前写入的日志文件路径，未写文件时为空
+func FilePath() string {
+	logMutex.RLock()
+	defer logMutex.RUnlock()
+	if daily != nil {
+		return daily.Path()
+	}
+	return filePath
+}
+
 // Sync 同步日志 (线程安全)
 func Sync() error {
 	logMutex.RLock()
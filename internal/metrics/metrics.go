@@ -0,0 +1,286 @@
+// Package metrics 进程内的运行指标（WebSocket 连接、消息、Agent 运行、LLM 调用等），
+// 以 Prometheus 文本格式由 gateway /metrics 导出（gateway.metrics.enabled）。
+// 指标始终在内存中累计，开销仅为一次加锁的加法；未启用时不对外暴露。
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// LLMLatencyBuckets LLM 调用耗时直方图的桶（秒）
+var LLMLatencyBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120}
+
+// 导出的指标
+var (
+	WebSocketConnections = NewGauge("goclaw_websocket_connections", "Active WebSocket connections.")
+	MessagesInbound      = NewCounter("goclaw_messages_inbound_total", "Inbound messages published to the bus.", "channel")
+	MessagesOutbound     = NewCounter("goclaw_messages_outbound_total", "Outbound messages published to the bus, excluding stream deltas.", "channel")
+	RunsStarted          = NewCounter("goclaw_agent_runs_started_total", "Agent runs started.")
+	RunsCompleted        = NewCounter("goclaw_agent_runs_completed_total", "Agent runs that completed without error.")
+	RunsFailed           = NewCounter("goclaw_agent_runs_failed_total", "Agent runs that ended with an error.", "error_code")
+	LLMCallDuration      = NewHistogram("goclaw_llm_call_duration_seconds", "LLM call latency.", LLMLatencyBuckets, "model", "status")
+	LLMTokens            = NewCounter("goclaw_llm_tokens_total", "LLM tokens used; estimated is true when the provider reported no usage.", "kind", "estimated")
+	RateLimitRetries     = NewCounter("goclaw_rate_limit_retries_total", "LLM calls retried after a rate limit.")
+)
+
+// collector 一个指标族，按注册顺序导出
+type collector interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	registry = append(registry, c)
+	registryMu.Unlock()
+}
+
+// Write 以 Prometheus 文本格式写出全部指标
+func Write(w io.Writer) {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// family 指标族公共部分：名称、说明、标签名与按标签值保存的序列
+type family struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+}
+
+// key 将标签值编码为序列键；标签值个数与标签名不一致时 panic（属于调用方编程错误）
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs 将序列键还原为 {a="x",b="y"}，extra 追加在最后（如直方图的 le）
+func (f *family) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(f.labels) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, f.labels[i]+`="`+escapeLabel(v)+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (f *family) header(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, typ)
+}
+
+// Counter 单调递增的计数器（可带标签）
+type Counter struct {
+	family
+	values map[string]float64
+}
+
+// NewCounter 创建并注册计数器
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: family{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc 按标签值加 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 按标签值增加 v（v 为负时忽略）
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	c.values[k] += v
+	c.mu.Unlock()
+}
+
+// Value 返回标签值对应的当前计数
+func (c *Counter) Value(labelValues ...string) float64 {
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[k]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.labels) == 0 {
+		fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(c.values[""]))
+		return
+	}
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(k), formatFloat(c.values[k]))
+	}
+}
+
+// Gauge 可增可减的当前值（不带标签）
+type Gauge struct {
+	family
+	value float64
+}
+
+// NewGauge 创建并注册 gauge
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{family: family{name: name, help: help}}
+	register(g)
+	return g
+}
+
+// Set 设为 v
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Add 增加 v（可为负）
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+// Inc 加 1
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec 减 1
+func (g *Gauge) Dec() { g.Add(-1) }
+
+// Value 返回当前值
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.Value()))
+}
+
+// Histogram 按桶累计观测值的分布（可带标签）
+type Histogram struct {
+	family
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // 各桶（非累计）计数，最后一个为 +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram 创建并注册直方图，buckets 为升序的上界
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		family:  family{name: name, help: help, labels: labels},
+		buckets: append([]float64(nil), buckets...),
+		series:  make(map[string]*histogramSeries),
+	}
+	sort.Float64s(h.buckets)
+	register(h)
+	return h
+}
+
+// Observe 按标签值记录一次观测
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets)+1)}
+		h.series[k] = s
+	}
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+// Count 返回标签值对应的观测次数
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[k]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(k, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(k, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(k), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(k), s.count)
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel 按文本格式转义标签值中的反斜杠、双引号与换行
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheusText(t *testing.T) {
+	c := NewCounter("test_messages_total", "Test messages.", "channel")
+	c.Inc("telegram")
+	c.Add(2, `we"b`)
+	g := NewGauge("test_connections", "Test connections.")
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	h := NewHistogram("test_latency_seconds", "Test latency.", []float64{1, 5}, "model")
+	h.Observe(0.5, "gpt-4o")
+	h.Observe(5, "gpt-4o")
+	h.Observe(30, "gpt-4o")
+
+	var out strings.Builder
+	Write(&out)
+	text := out.String()
+	for _, want := range []string{
+		"# TYPE test_messages_total counter\n",
+		`test_messages_total{channel="telegram"} 1` + "\n",
+		`test_messages_total{channel="we\"b"} 2` + "\n",
+		"# TYPE test_connections gauge\ntest_connections 1\n",
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{model="gpt-4o",le="1"} 1` + "\n",
+		`test_latency_seconds_bucket{model="gpt-4o",le="5"} 2` + "\n",
+		`test_latency_seconds_bucket{model="gpt-4o",le="+Inf"} 3` + "\n",
+		`test_latency_seconds_sum{model="gpt-4o"} 35.5` + "\n",
+		`test_latency_seconds_count{model="gpt-4o"} 3` + "\n",
+		"# TYPE goclaw_agent_runs_started_total counter\ngoclaw_agent_runs_started_total 0\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q\n%s", want, text)
+		}
+	}
+}
+
+func TestCounterRejectsWrongLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for missing label value")
+		}
+	}()
+	MessagesInbound.Inc()
+}
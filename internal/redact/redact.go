@@ -0,0 +1,109 @@
+// Package redact 日志、调试输出与配置变更历史中的密钥脱敏。
+// 字段名、工具参数名与请求头共用同一套敏感名称规则，自由文本另按常见密钥格式匹配。
+package redact
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Placeholder 替代敏感值的占位
+const Placeholder = "[REDACTED]"
+
+// sensitiveSuffixes 名称（小写，- 视为 _）以这些片段结尾时视为敏感；按后缀而非子串匹配，max_tokens 等不受影响
+var sensitiveSuffixes = []string{
+	"token", "secret", "password", "passwd", "api_key", "apikey", "aes_key", "encrypt_key",
+	"authorization", "credential", "credentials", "cookie",
+}
+
+// 自由文本中需要脱敏的内容
+var (
+	secretAssignPattern = regexp.MustCompile(`(?i)((?:api[_-]?key|apikey|secret|token|password|passwd)["']?\s*[:=]\s*["']?)[^\s"',}]+`)
+	bearerPattern       = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]+`)
+	secretTokenPattern  = regexp.MustCompile(`\b(?:sk|xox[abp]|ghp|gho|AKIA)[-_A-Za-z0-9]{12,}`)
+)
+
+// IsSensitiveKey 字段名、参数名或请求头名是否可能携带密钥
+func IsSensitiveKey(name string) bool {
+	key := strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value 非空取值替换为 Placeholder，空值保留以便区分设置与清除
+func Value(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+	return Placeholder
+}
+
+// Map 返回 m 的脱敏副本：敏感字段或 extra 中声明的字段（不区分大小写）的值替换为 Placeholder，嵌套 map/数组递归处理。
+// 原 map 不会被修改
+func Map(m map[string]interface{}, extra ...string) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	return Tree(m, extra...).(map[string]interface{})
+}
+
+// Tree 同 Map，作用于任意 JSON 子树
+func Tree(v interface{}, extra ...string) interface{} {
+	declared := make(map[string]bool, len(extra))
+	for _, k := range extra {
+		declared[strings.ToLower(k)] = true
+	}
+	return tree(v, declared)
+}
+
+func tree(v interface{}, declared map[string]bool) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(tv))
+		for k, child := range tv {
+			if declared[strings.ToLower(k)] || IsSensitiveKey(k) {
+				m[k] = Value(child)
+			} else {
+				m[k] = tree(child, declared)
+			}
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(tv))
+		for i, child := range tv {
+			list[i] = tree(child, declared)
+		}
+		return list
+	}
+	return v
+}
+
+// Headers 返回用于日志的请求头副本，敏感请求头的值替换为 Placeholder
+func Headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name := range h {
+		if IsSensitiveKey(name) {
+			out[name] = Placeholder
+		} else {
+			out[name] = h.Get(name)
+		}
+	}
+	return out
+}
+
+// Text 对自由文本脱敏：key=value 形式的密钥、Bearer token、常见 key 前缀以及显式给出的密钥值（至少 4 个字符）
+func Text(text string, known []string) string {
+	for _, secret := range known {
+		if len(secret) >= 4 {
+			text = strings.ReplaceAll(text, secret, Placeholder)
+		}
+	}
+	text = secretAssignPattern.ReplaceAllString(text, "${1}"+Placeholder)
+	text = bearerPattern.ReplaceAllString(text, "${1}"+Placeholder)
+	return secretTokenPattern.ReplaceAllString(text, Placeholder)
+}
@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	for _, name := range []string{"token", "api_key", "apiKey", "apiToken", "client_secret", "encoding_aes_key", "Authorization", "X-Api-Key", "Set-Cookie", "passwd"} {
+		if !IsSensitiveKey(name) {
+			t.Errorf("IsSensitiveKey(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"max_tokens", "enabled", "model", "X-Title", "keyword"} {
+		if IsSensitiveKey(name) {
+			t.Errorf("IsSensitiveKey(%q) = true", name)
+		}
+	}
+}
+
+func TestMapRedactsNestedAndDeclaredFields(t *testing.T) {
+	args := map[string]interface{}{
+		"payload":  "body",
+		"apiToken": "t",
+		"empty":    map[string]interface{}{"password": ""},
+		"auth":     map[string]interface{}{"password": "p", "user": "u"},
+		"list":     []interface{}{map[string]interface{}{"secret": "s"}},
+	}
+	out := Map(args, "Payload")
+	if out["payload"] != Placeholder || out["apiToken"] != Placeholder {
+		t.Fatalf("top-level fields not redacted: %v", out)
+	}
+	auth := out["auth"].(map[string]interface{})
+	if auth["password"] != Placeholder || auth["user"] != "u" {
+		t.Fatalf("nested map = %v", auth)
+	}
+	if out["list"].([]interface{})[0].(map[string]interface{})["secret"] != Placeholder {
+		t.Fatalf("array element not redacted: %v", out["list"])
+	}
+	// 空值保留，便于区分设置与清除
+	if out["empty"].(map[string]interface{})["password"] != "" {
+		t.Fatalf("empty value should be kept: %v", out["empty"])
+	}
+	if args["apiToken"] != "t" || args["auth"].(map[string]interface{})["password"] != "p" {
+		t.Fatal("input was modified")
+	}
+	if Map(nil) != nil {
+		t.Fatal("Map(nil) should be nil")
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Api-Key", "secret")
+	h.Set("Authorization", "Bearer x")
+	h.Set("X-Title", "goclaw")
+	out := Headers(h)
+	if out["X-Api-Key"] != Placeholder || out["Authorization"] != Placeholder {
+		t.Fatalf("sensitive headers not redacted: %v", out)
+	}
+	if out["X-Title"] != "goclaw" {
+		t.Fatalf("plain header redacted: %v", out)
+	}
+}
+
+func TestText(t *testing.T) {
+	in := `{"api_key": "abc123", "auth": "Bearer eyJhbGci.x", "k": "sk-abcdefghijklmnop", "note": "uses hunter22"}`
+	out := Text(in, []string{"hunter22", "abc"})
+	want := `{"api_key": "[REDACTED]", "auth": "Bearer [REDACTED]", "k": "[REDACTED]", "note": "uses [REDACTED]"}`
+	if out != want {
+		t.Fatalf("Text() =\n%s\nwant\n%s", out, want)
+	}
+}
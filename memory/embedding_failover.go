@@ -3,6 +3,9 @@ package memory
 import (
 	"fmt"
 	"sync"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
 )
 
 // FailoverProvider 按顺序尝试多个 EmbeddingProvider，主提供商失败时自动切换备用（与 OpenClaw createEmbeddingProvider fallback 对齐）
@@ -19,17 +22,27 @@ type FailoverProviderOption struct {
 	Name     string
 }
 
-// NewFailoverProvider 创建故障转移 Provider，第一个为主，其余为备用
+// NewFailoverProvider 创建故障转移 Provider，第一个为主，其余为备用；
+// 维度与主 Provider 不同的备用会被跳过，否则切换后写入的向量与已有索引维度不一致
 func NewFailoverProvider(primary EmbeddingProvider, primaryName string, fallbacks ...FailoverProviderOption) *FailoverProvider {
 	providers := make([]EmbeddingProvider, 0, 1+len(fallbacks))
 	names := make([]string, 0, 1+len(fallbacks))
 	providers = append(providers, primary)
 	names = append(names, primaryName)
 	for _, f := range fallbacks {
-		if f.Provider != nil {
-			providers = append(providers, f.Provider)
-			names = append(names, f.Name)
+		if f.Provider == nil {
+			continue
+		}
+		if dim := f.Provider.Dimension(); dim != primary.Dimension() {
+			logger.Warn("Memory embedding fallback skipped: dimension differs from primary",
+				zap.String("primary", primaryName),
+				zap.Int("primary_dimension", primary.Dimension()),
+				zap.String("fallback", f.Name),
+				zap.Int("fallback_dimension", dim))
+			continue
 		}
+		providers = append(providers, f.Provider)
+		names = append(names, f.Name)
 	}
 	return &FailoverProvider{
 		providers: providers,
@@ -99,12 +112,15 @@ func (f *FailoverProvider) Dimension() int {
 	return f.providers[0].Dimension()
 }
 
-// MaxBatchSize 返回当前活跃 Provider 的最大批大小
+// MaxBatchSize 返回所有 Provider 中最小的批大小，保证切换到备用时批次仍合法
 func (f *FailoverProvider) MaxBatchSize() int {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	if f.active < len(f.providers) {
-		return f.providers[f.active].MaxBatchSize()
+	size := 0
+	for _, p := range f.providers {
+		if n := p.MaxBatchSize(); n > 0 && (size == 0 || n < size) {
+			size = n
+		}
 	}
-	return f.providers[0].MaxBatchSize()
+	return size
 }
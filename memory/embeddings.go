@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -126,73 +128,39 @@ func (p *OpenAIProvider) EmbedBatch(texts []string) ([][]float32, error) {
 	}
 
 	url := fmt.Sprintf("%s/embeddings", p.config.BaseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(reqJSON))
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", p.config.APIKey)}
+	body, err := postEmbeddingRequest(p.httpClient, url, headers, reqJSON, p.config.MaxRetries, func(status int, body []byte) error {
+		var errResp openAIErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+			return fmt.Errorf("API error: %s", errResp.Error.Message)
+		}
+		return fmt.Errorf("API returned status %d: %s", status, string(body))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
-
-	var lastErr error
-	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-
-		resp, err := p.httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			continue
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response: %w", err)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			var errResp openAIErrorResponse
-			if err := json.Unmarshal(body, &errResp); err == nil {
-				lastErr = fmt.Errorf("API error: %s", errResp.Error.Message)
-			} else {
-				lastErr = fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-			}
-
-			// Retry on rate limit or server errors
-			if resp.StatusCode == 429 || resp.StatusCode >= 500 {
-				continue
-			}
-			return nil, lastErr
-		}
+	var respBody openAIEmbeddingResponse
+	if err := json.Unmarshal(body, &respBody); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
 
-		var respBody openAIEmbeddingResponse
-		if err := json.Unmarshal(body, &respBody); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-		}
-
-		// Extract embeddings in order
-		result := make([][]float32, len(texts))
-		for _, item := range respBody.Data {
-			if item.Index >= 0 && item.Index < len(result) {
-				result[item.Index] = item.Embedding
-			}
+	// Extract embeddings in order
+	result := make([][]float32, len(texts))
+	for _, item := range respBody.Data {
+		if item.Index >= 0 && item.Index < len(result) {
+			result[item.Index] = item.Embedding
 		}
+	}
 
-		// Verify all embeddings were returned
-		for i, emb := range result {
-			if emb == nil {
-				return nil, fmt.Errorf("missing embedding at index %d", i)
-			}
+	// Verify all embeddings were returned
+	for i, emb := range result {
+		if emb == nil {
+			return nil, fmt.Errorf("missing embedding at index %d", i)
 		}
-
-		return result, nil
 	}
 
-	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
+	return result, nil
 }
 
 // Dimension returns the dimension of embeddings
@@ -206,39 +174,155 @@ func (p *OpenAIProvider) MaxBatchSize() int {
 	return 2048
 }
 
+// GeminiConfig configures the Gemini embedding provider
+type GeminiConfig struct {
+	APIKey     string
+	BaseURL    string
+	Model      string
+	Timeout    time.Duration
+	MaxRetries int
+}
+
+// DefaultGeminiConfig returns default Gemini configuration
+func DefaultGeminiConfig(apiKey string) GeminiConfig {
+	return GeminiConfig{
+		APIKey:     apiKey,
+		BaseURL:    "https://generativelanguage.googleapis.com/v1beta",
+		Model:      "text-embedding-004",
+		Timeout:    30 * time.Second,
+		MaxRetries: 3,
+	}
+}
+
+// geminiEmbedRequest is one entry of a batchEmbedContents request
+type geminiEmbedRequest struct {
+	Model   string `json:"model"`
+	Content struct {
+		Parts []struct {
+			Text string `json:"text"`
+		} `json:"parts"`
+	} `json:"content"`
+}
+
+// geminiBatchEmbedResponse is the response from batchEmbedContents
+type geminiBatchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// geminiErrorResponse represents an error from Gemini API
+type geminiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
 // GeminiProvider implements EmbeddingProvider using Google's Gemini API
-// This is a placeholder for future implementation
 type GeminiProvider struct {
-	apiKey    string
-	model     string
-	dimension int
+	config     GeminiConfig
+	httpClient *http.Client
+	dimension  int
 }
 
 // NewGeminiProvider creates a new Gemini embedding provider
-func NewGeminiProvider(apiKey, model string) (*GeminiProvider, error) {
-	if apiKey == "" {
+func NewGeminiProvider(config GeminiConfig) (*GeminiProvider, error) {
+	if config.APIKey == "" {
 		return nil, fmt.Errorf("API key is required")
 	}
-	if model == "" {
-		model = "embedding-001"
+	if config.BaseURL == "" {
+		config.BaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	config.Model = strings.TrimPrefix(config.Model, "models/")
+	if config.Model == "" {
+		config.Model = "text-embedding-004"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+
+	// text-embedding-004 / embedding-001 均为 768 维；gemini-embedding-001 默认 3072 维
+	dimension := 768
+	if config.Model == "gemini-embedding-001" {
+		dimension = 3072
 	}
+
 	return &GeminiProvider{
-		apiKey:    apiKey,
-		model:     model,
-		dimension: 768, // Gemini embedding dimension
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		dimension:  dimension,
 	}, nil
 }
 
 // Embed generates a single embedding
 func (p *GeminiProvider) Embed(text string) ([]float32, error) {
-	// TODO: Implement Gemini embedding API call
-	return nil, fmt.Errorf("Gemini provider not yet implemented")
+	embeddings, err := p.EmbedBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return embeddings[0], nil
 }
 
-// EmbedBatch generates multiple embeddings in one call
+// EmbedBatch generates multiple embeddings in one call (batchEmbedContents)
 func (p *GeminiProvider) EmbedBatch(texts []string) ([][]float32, error) {
-	// TODO: Implement Gemini batch embedding API call
-	return nil, fmt.Errorf("Gemini provider not yet implemented")
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+	if len(texts) > p.MaxBatchSize() {
+		return nil, fmt.Errorf("batch size %d exceeds maximum %d", len(texts), p.MaxBatchSize())
+	}
+
+	model := "models/" + p.config.Model
+	requests := make([]geminiEmbedRequest, len(texts))
+	for i, text := range texts {
+		requests[i].Model = model
+		requests[i].Content.Parts = []struct {
+			Text string `json:"text"`
+		}{{Text: text}}
+	}
+	reqJSON, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s:batchEmbedContents", p.config.BaseURL, model)
+	headers := map[string]string{"x-goog-api-key": p.config.APIKey}
+	body, err := postEmbeddingRequest(p.httpClient, url, headers, reqJSON, p.config.MaxRetries, func(status int, body []byte) error {
+		var errResp geminiErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+			return fmt.Errorf("API error: %s", errResp.Error.Message)
+		}
+		return fmt.Errorf("API returned status %d: %s", status, string(body))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody geminiBatchEmbedResponse
+	if err := json.Unmarshal(body, &respBody); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(respBody.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(respBody.Embeddings))
+	}
+
+	result := make([][]float32, len(texts))
+	for i, emb := range respBody.Embeddings {
+		if len(emb.Values) == 0 {
+			return nil, fmt.Errorf("missing embedding at index %d", i)
+		}
+		result[i] = emb.Values
+	}
+	return result, nil
 }
 
 // Dimension returns the dimension of embeddings
@@ -248,5 +332,66 @@ func (p *GeminiProvider) Dimension() int {
 
 // MaxBatchSize returns the maximum batch size
 func (p *GeminiProvider) MaxBatchSize() int {
-	return 100 // Gemini typically supports smaller batches
+	// batchEmbedContents accepts at most 100 requests per call
+	return 100
+}
+
+// embeddingRetrySleep 重试等待，测试中可替换
+var embeddingRetrySleep = time.Sleep
+
+// maxEmbeddingRetryDelay 单次重试等待上限（含 Retry-After）
+const maxEmbeddingRetryDelay = 60 * time.Second
+
+// postEmbeddingRequest 发送 JSON POST 并返回 200 响应体；429 / 5xx / 网络错误按 Retry-After 或指数退避重试。
+// 每次重试都重新构造请求，避免复用已读完的 body。
+func postEmbeddingRequest(client *http.Client, url string, headers map[string]string, payload []byte, maxRetries int, parseErr func(status int, body []byte) error) ([]byte, error) {
+	var lastErr error
+	var delay time.Duration
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if delay <= 0 {
+				delay = time.Duration(1<<(attempt-1)) * time.Second
+			}
+			embeddingRetrySleep(min(delay, maxEmbeddingRetryDelay))
+			delay = 0
+		}
+
+		req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("request failed: %w", err)
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response: %w", err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+		lastErr = parseErr(resp.StatusCode, body)
+
+		// Retry on rate limit or server errors
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs > 0 {
+				delay = time.Duration(secs) * time.Second
+			}
+			continue
+		}
+		return nil, lastErr
+	}
+
+	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
 }
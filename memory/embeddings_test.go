@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder 固定维度的假嵌入 provider，err 非空时所有调用失败
+type fakeEmbedder struct {
+	dim     int
+	batch   int
+	err     error
+	batches [][]string
+}
+
+func (f *fakeEmbedder) Embed(text string) ([]float32, error) {
+	embs, err := f.EmbedBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embs[0], nil
+}
+
+func (f *fakeEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+	f.batches = append(f.batches, texts)
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, f.dim)
+		v[0] = float32(len(t))
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (f *fakeEmbedder) Dimension() int    { return f.dim }
+func (f *fakeEmbedder) MaxBatchSize() int { return f.batch }
+
+func TestFailoverProviderFallsBackOnPrimaryError(t *testing.T) {
+	primary := &fakeEmbedder{dim: 4, batch: 2048, err: errors.New("rate limited")}
+	mismatched := &fakeEmbedder{dim: 8, batch: 1}
+	fallback := &fakeEmbedder{dim: 4, batch: 2}
+	p := NewFailoverProvider(primary, "openai",
+		FailoverProviderOption{Provider: mismatched, Name: "gemini"},
+		FailoverProviderOption{Provider: fallback, Name: "local"})
+
+	if got := p.MaxBatchSize(); got != 2 {
+		t.Fatalf("MaxBatchSize() = %d, want smallest batch 2", got)
+	}
+
+	m := &MemoryManager{provider: p}
+	embs, err := m.embedBatchWithFallback(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("embedBatchWithFallback() error = %v", err)
+	}
+	if len(embs) != 3 || embs[2][0] != 3 {
+		t.Fatalf("unexpected embeddings: %v", embs)
+	}
+	if len(fallback.batches) != 2 || len(fallback.batches[0]) != 2 {
+		t.Errorf("fallback batches = %v, want chunks of 2", fallback.batches)
+	}
+	// 维度不同的备用不参与故障转移，避免写入维度不一致的向量
+	if len(mismatched.batches) != 0 || p.Dimension() != 4 {
+		t.Errorf("mismatched fallback used: batches = %v, dimension = %d", mismatched.batches, p.Dimension())
+	}
+
+	fallback.err = errors.New("down")
+	if _, err := p.EmbedBatch([]string{"x"}); err == nil {
+		t.Error("expected error when all providers fail")
+	}
+}
+
+func TestMemoryManagerRequiresEmbeddingProvider(t *testing.T) {
+	m := &MemoryManager{}
+	if _, err := m.AddMemory(context.Background(), "hello", MemorySourceLongTerm, MemoryTypeFact, MemoryMetadata{}); !errors.Is(err, ErrNoEmbeddingProvider) {
+		t.Errorf("AddMemory() error = %v, want ErrNoEmbeddingProvider", err)
+	}
+	if err := m.Update(context.Background(), &VectorEmbedding{Text: "hello"}); !errors.Is(err, ErrNoEmbeddingProvider) {
+		t.Errorf("Update() error = %v, want ErrNoEmbeddingProvider", err)
+	}
+}
+
+func TestGeminiProviderBatchEmbedRetriesRateLimit(t *testing.T) {
+	var slept []time.Duration
+	embeddingRetrySleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { embeddingRetrySleep = time.Sleep }()
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/models/text-embedding-004:batchEmbedContents" || r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		var req struct {
+			Requests []geminiEmbedRequest `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request (attempt %d): %v", calls, err)
+		}
+		if calls == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`)
+			return
+		}
+		if len(req.Requests) != 2 || req.Requests[1].Model != "models/text-embedding-004" || req.Requests[1].Content.Parts[0].Text != "world" {
+			t.Errorf("unexpected requests: %+v", req.Requests)
+		}
+		fmt.Fprint(w, `{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultGeminiConfig("test-key")
+	cfg.BaseURL = srv.URL
+	p, err := NewGeminiProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embs, err := p.EmbedBatch([]string{"hello", "world"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if len(embs) != 2 || embs[1][1] != 0.4 {
+		t.Errorf("unexpected embeddings: %v", embs)
+	}
+	if calls != 2 || len(slept) != 1 || slept[0] != 2*time.Second {
+		t.Errorf("calls = %d slept = %v, want one Retry-After wait of 2s", calls, slept)
+	}
+}
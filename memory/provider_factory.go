@@ -1,14 +1,20 @@
 package memory
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
 )
 
+// ErrNoEmbeddingProvider 需要生成向量但未配置嵌入 provider（memory.builtin.embedding.provider）
+var ErrNoEmbeddingProvider = errors.New("no embedding provider configured (set memory.builtin.embedding.provider)")
+
 // NewEmbeddingProviderFromConfig 根据配置创建嵌入 Provider；支持主 provider + 备用（与 OpenClaw createEmbeddingProvider fallback 对齐）
 // 若 embedCfg 为 nil 或 provider 名为空，返回 (nil, nil)。
 func NewEmbeddingProviderFromConfig(cfg *config.Config, embedCfg *config.BuiltinEmbeddingConfig) (EmbeddingProvider, error) {
@@ -20,12 +26,16 @@ func NewEmbeddingProviderFromConfig(cfg *config.Config, embedCfg *config.Builtin
 		return nil, err
 	}
 	fallbackName := strings.TrimSpace(strings.ToLower(embedCfg.Fallback))
-	if fallbackName == "" || fallbackName == embedCfg.Provider {
+	if fallbackName == "" || fallbackName == primaryName {
 		return primary, nil
 	}
 	fallback, _, err := createProviderByName(cfg, fallbackName)
 	if err != nil {
 		// 备用创建失败时仍返回主 provider
+		logger.Warn("Memory embedding fallback unavailable, using primary only",
+			zap.String("primary", primaryName),
+			zap.String("fallback", fallbackName),
+			zap.Error(err))
 		return primary, nil
 	}
 	return NewFailoverProvider(primary, primaryName, FailoverProviderOption{Provider: fallback, Name: fallbackName}), nil
@@ -60,6 +70,29 @@ func createProviderByName(cfg *config.Config, name string) (EmbeddingProvider, s
 			return nil, "", err
 		}
 		return p, "openai", nil
+	case "gemini", "google":
+		apiKey := ""
+		if cfg != nil {
+			apiKey = strings.TrimSpace(cfg.Providers.Gemini.APIKey)
+		}
+		if apiKey == "" {
+			apiKey = strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
+		}
+		if apiKey == "" {
+			apiKey = strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
+		}
+		if apiKey == "" {
+			return nil, "", fmt.Errorf("gemini api_key required for memory embedding (set in config or GEMINI_API_KEY)")
+		}
+		c := DefaultGeminiConfig(apiKey)
+		if cfg != nil && cfg.Providers.Gemini.BaseURL != "" {
+			c.BaseURL = strings.TrimSuffix(cfg.Providers.Gemini.BaseURL, "/")
+		}
+		p, err := NewGeminiProvider(c)
+		if err != nil {
+			return nil, "", err
+		}
+		return p, "gemini", nil
 	default:
 		return nil, "", fmt.Errorf("unsupported memory embedding provider: %q", name)
 	}
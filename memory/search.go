@@ -63,7 +63,7 @@ func (m *MemoryManager) AddMemory(ctx context.Context, text string, source Memor
 	default:
 	}
 	if m.provider == nil {
-		return nil, fmt.Errorf("embedding provider is required for AddMemory")
+		return nil, ErrNoEmbeddingProvider
 	}
 
 	m.mu.Lock()
@@ -229,7 +229,7 @@ func (m *MemoryManager) Search(ctx context.Context, query string, opts SearchOpt
 		if sqlStore, ok := m.store.(*SQLiteStore); ok {
 			return sqlStore.SearchByTextQuery(query, opts)
 		}
-		return nil, fmt.Errorf("embedding not configured; set memory.builtin.embedding or OPENAI_API_KEY for semantic search")
+		return nil, fmt.Errorf("semantic search unavailable: %w", ErrNoEmbeddingProvider)
 	}
 
 	// Generate query embedding
@@ -288,6 +288,9 @@ func (m *MemoryManager) Update(ctx context.Context, ve *VectorEmbedding) error {
 
 	// Re-generate embedding if text changed
 	if len(ve.Vector) == 0 {
+		if m.provider == nil {
+			return ErrNoEmbeddingProvider
+		}
 		embedding, err := m.provider.Embed(ve.Text)
 		if err != nil {
 			return fmt.Errorf("failed to generate embedding: %w", err)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/tmc/langchaingo/llms"
@@ -11,15 +13,24 @@ import (
 	"go.uber.org/zap"
 )
 
-// AnthropicProvider Anthropic 提供商
+// AnthropicProvider Anthropic 提供商：非流式经由 langchaingo，流式直接调用 Messages API（SSE）
 type AnthropicProvider struct {
-	llm       llms.Model
-	model     string
-	maxTokens int
+	llm              llms.Model
+	apiKey           string
+	baseURL          string
+	model            string
+	maxTokens        int
+	streamingEnabled bool
+	client           *http.Client
 }
 
-// NewAnthropicProvider 创建 Anthropic 提供商
+// NewAnthropicProvider 创建 Anthropic 提供商（启用流式）
 func NewAnthropicProvider(apiKey, baseURL, model string, maxTokens int) (*AnthropicProvider, error) {
+	return NewAnthropicProviderWithStreaming(apiKey, baseURL, model, maxTokens, true)
+}
+
+// NewAnthropicProviderWithStreaming 创建 Anthropic 提供商；streaming 为 false 时 SupportsStreaming 返回 false，调用方回退到 Chat
+func NewAnthropicProviderWithStreaming(apiKey, baseURL, model string, maxTokens int, streaming bool) (*AnthropicProvider, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
 	}
@@ -34,12 +45,9 @@ func NewAnthropicProvider(apiKey, baseURL, model string, maxTokens int) (*Anthro
 	}
 
 	if baseURL != "" {
-		// Note: langchaingo's anthropic package might not support WithBaseURL yet,
-		// but we should check if it's available or use a custom client if needed.
-		// For now, we'll try to use it if it exists.
-		// Actually, let's check langchaingo's anthropic option.
-		_ = baseURL // TODO: implement custom base URL support
-		// nolint:staticcheck,emptybranch
+		opts = append(opts, anthropic.WithBaseURL(baseURL))
+	} else {
+		baseURL = defaultAnthropicBaseURL
 	}
 
 	llm, err := anthropic.New(opts...)
@@ -48,9 +56,13 @@ func NewAnthropicProvider(apiKey, baseURL, model string, maxTokens int) (*Anthro
 	}
 
 	return &AnthropicProvider{
-		llm:       llm,
-		model:     model,
-		maxTokens: maxTokens,
+		llm:              llm,
+		apiKey:           apiKey,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		model:            model,
+		maxTokens:        maxTokens,
+		streamingEnabled: streaming,
+		client:           &http.Client{},
 	}, nil
 }
 
@@ -123,11 +135,18 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 
 	// 调用 LLM
 	var llmOpts []llms.CallOption
-	if opts.Temperature > 0 {
-		llmOpts = append(llmOpts, llms.WithTemperature(float64(opts.Temperature)))
-	}
-	if opts.MaxTokens > 0 {
-		llmOpts = append(llmOpts, llms.WithMaxTokens(int(opts.MaxTokens)))
+	if budget := anthropicThinkingBudget(messages, opts); budget > 0 {
+		// 开启思考时不能设置 temperature
+		llmOpts = append(llmOpts,
+			llms.WithThinkingBudget(budget),
+			llms.WithMaxTokens(anthropicMaxTokensForThinking(opts.MaxTokens, budget)))
+	} else {
+		if opts.Temperature > 0 {
+			llmOpts = append(llmOpts, llms.WithTemperature(float64(opts.Temperature)))
+		}
+		if opts.MaxTokens > 0 {
+			llmOpts = append(llmOpts, llms.WithMaxTokens(int(opts.MaxTokens)))
+		}
 	}
 
 	// 如果有工具，添加工具选项
@@ -194,7 +213,7 @@ func (p *AnthropicProvider) Close() error {
 	return nil
 }
 
-// SupportsStreaming returns whether streaming is supported (currently false for Anthropic via langchaingo).
+// SupportsStreaming returns whether streaming is enabled for this provider.
 func (p *AnthropicProvider) SupportsStreaming() bool {
-	return false // langchaingo 的 Anthropic 实现暂不支持流式
+	return p.streamingEnabled
 }
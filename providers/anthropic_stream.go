@@ -0,0 +1,399 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultAnthropicBaseURL Anthropic API 默认地址
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	// anthropicAPIVersion Messages API 版本头
+	anthropicAPIVersion = "2023-06-01"
+	// anthropicDefaultMaxTokens Messages API 要求必填 max_tokens，未配置时使用该值
+	anthropicDefaultMaxTokens = 4096
+)
+
+// Anthropic Messages API 请求/事件结构（仅包含用到的字段）
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+	Stream      bool               `json:"stream"`
+}
+
+// anthropicThinking 扩展思考配置（budget_tokens 须小于 max_tokens）
+type anthropicThinking struct {
+	Type         string `json:"type"` // enabled
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"` // user / assistant
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type      string                 `json:"type"`
+	Text      string                 `json:"text,omitempty"`
+	Source    *anthropicImageSource  `json:"source,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Input     map[string]interface{} `json:"input,omitempty"`
+	ToolUseID string                 `json:"tool_use_id,omitempty"`
+	Content   string                 `json:"content,omitempty"`
+	Thinking  string                 `json:"thinking,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64 / url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicStreamEvent SSE data 中的事件；不同 type 只填充部分字段
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	ContentBlock *anthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStream 通过 Messages API（stream=true）流式输出：text_delta 逐段回调，
+// tool_use 与 thinking 块累积后随 Done 一并返回
+func (p *AnthropicProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, callback StreamCallback, options ...ChatOption) error {
+	opts := &ChatOptions{
+		Model:       p.model,
+		Temperature: 0.7,
+		MaxTokens:   p.maxTokens,
+		Stream:      true,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	body, err := p.doStreamRequest(ctx, buildAnthropicRequest(messages, tools, opts))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var acc anthropicAccumulator
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to decode anthropic stream event: %w", err)
+		}
+		delta, err := acc.add(&event)
+		if err != nil {
+			return err
+		}
+		if delta != "" {
+			callback(StreamChunk{Content: delta})
+		}
+		if event.Type == "message_stop" {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream error: %w", err)
+	}
+
+	final := StreamChunk{
+		Content:          acc.content.String(),
+		Done:             true,
+		ToolCalls:        acc.toolCalls(),
+		ReasoningContent: acc.reasoning.String(),
+	}
+	if acc.usage.InputTokens > 0 || acc.usage.OutputTokens > 0 {
+		final.Usage = &Usage{
+			PromptTokens:     acc.usage.InputTokens,
+			CompletionTokens: acc.usage.OutputTokens,
+			TotalTokens:      acc.usage.InputTokens + acc.usage.OutputTokens,
+		}
+	}
+	callback(final)
+	return nil
+}
+
+// doStreamRequest 发送流式请求并返回响应体；非 2xx 时把状态码与响应体带入错误，便于限流等错误分类
+func (p *AnthropicProvider) doStreamRequest(ctx context.Context, req *anthropicRequest) (io.ReadCloser, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode anthropic request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	logger.Debug("Anthropic stream request",
+		zap.String("model", req.Model),
+		zap.Int("messages", len(req.Messages)),
+		zap.Int("tools", len(req.Tools)))
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("anthropic API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
+
+// buildAnthropicRequest 将通用消息转换为 Messages API 请求：
+// system 合并到顶层 system，tool 结果以 user 角色的 tool_result 发送，相邻同角色内容合并
+func buildAnthropicRequest(messages []Message, tools []ToolDefinition, opts *ChatOptions) *anthropicRequest {
+	req := &anthropicRequest{
+		Model:     opts.Model,
+		Messages:  make([]anthropicMessage, 0, len(messages)),
+		MaxTokens: opts.MaxTokens,
+		Stream:    true,
+	}
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = anthropicDefaultMaxTokens
+	}
+	if budget := anthropicThinkingBudget(messages, opts); budget > 0 {
+		// 开启思考时不能设置 temperature
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		req.MaxTokens = anthropicMaxTokensForThinking(req.MaxTokens, budget)
+	} else if opts.Temperature > 0 {
+		t := opts.Temperature
+		req.Temperature = &t
+	}
+
+	var system []string
+	for _, msg := range messages {
+		var role string
+		var blocks []anthropicContentBlock
+		switch msg.Role {
+		case "system":
+			if strings.TrimSpace(msg.Content) != "" {
+				system = append(system, msg.Content)
+			}
+			continue
+		case "assistant":
+			role = "assistant"
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := tc.Params
+				if input == nil {
+					input = map[string]interface{}{}
+				}
+				blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: input})
+			}
+		case "tool":
+			role = "user"
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		default:
+			role = "user"
+			for _, img := range msg.Images {
+				if src := anthropicImageSourceFor(img); src != nil {
+					blocks = append(blocks, anthropicContentBlock{Type: "image", Source: src})
+				}
+			}
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			continue
+		}
+		req.Messages = append(req.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	for _, tool := range tools {
+		schema := tool.Parameters
+		if len(schema) == 0 {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
+	}
+	return req
+}
+
+// anthropicImageSourceFor 将 data URL、http(s) URL 或裸 base64 图片转换为 image source
+func anthropicImageSourceFor(img string) *anthropicImageSource {
+	if strings.HasPrefix(img, "data:") {
+		meta, data, ok := strings.Cut(strings.TrimPrefix(img, "data:"), ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil
+		}
+		return &anthropicImageSource{Type: "base64", MediaType: strings.TrimSuffix(meta, ";base64"), Data: data}
+	}
+	if strings.HasPrefix(img, "http://") || strings.HasPrefix(img, "https://") {
+		return &anthropicImageSource{Type: "url", URL: img}
+	}
+	head, err := base64.StdEncoding.DecodeString(img[:min(len(img), 64)/4*4])
+	if err != nil {
+		return nil
+	}
+	return &anthropicImageSource{Type: "base64", MediaType: http.DetectContentType(head), Data: img}
+}
+
+// anthropicToolBlock 流式中的 tool_use 块：input 以 input_json_delta 分段到达
+type anthropicToolBlock struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// anthropicAccumulator 累积流式事件：文本、思考内容、tool_use 块与用量
+type anthropicAccumulator struct {
+	content   strings.Builder
+	reasoning strings.Builder
+	tools     map[int]*anthropicToolBlock // content block index -> tool_use
+	usage     anthropicUsage
+}
+
+// add 处理一个事件，返回其中新增的可见文本
+func (a *anthropicAccumulator) add(event *anthropicStreamEvent) (string, error) {
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			a.usage.InputTokens = event.Message.Usage.InputTokens
+		}
+	case "content_block_start":
+		block := event.ContentBlock
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "tool_use":
+			if a.tools == nil {
+				a.tools = make(map[int]*anthropicToolBlock)
+			}
+			a.tools[event.Index] = &anthropicToolBlock{id: block.ID, name: block.Name}
+		case "text":
+			if block.Text != "" {
+				a.content.WriteString(block.Text)
+				return block.Text, nil
+			}
+		case "thinking":
+			a.reasoning.WriteString(block.Thinking)
+		}
+	case "content_block_delta":
+		if event.Delta == nil {
+			break
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			a.content.WriteString(event.Delta.Text)
+			return event.Delta.Text, nil
+		case "input_json_delta":
+			if tb := a.tools[event.Index]; tb != nil {
+				tb.args.WriteString(event.Delta.PartialJSON)
+			}
+		case "thinking_delta":
+			a.reasoning.WriteString(event.Delta.Thinking)
+		}
+	case "message_delta":
+		if event.Usage != nil {
+			a.usage.OutputTokens = event.Usage.OutputTokens
+		}
+	case "error":
+		if event.Error != nil {
+			return "", fmt.Errorf("anthropic stream error (%s): %s", event.Error.Type, event.Error.Message)
+		}
+		return "", fmt.Errorf("anthropic stream error")
+	}
+	return "", nil
+}
+
+// toolCalls 按块顺序返回累积的工具调用；参数解析失败的调用记录日志后跳过
+func (a *anthropicAccumulator) toolCalls() []ToolCall {
+	if len(a.tools) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(a.tools))
+	for idx := range a.tools {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	calls := make([]ToolCall, 0, len(indexes))
+	for _, idx := range indexes {
+		tb := a.tools[idx]
+		params := map[string]interface{}{}
+		if raw := strings.TrimSpace(tb.args.String()); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &params); err != nil {
+				logger.Error("Failed to unmarshal tool arguments",
+					zap.String("tool", tb.name),
+					zap.String("id", tb.id),
+					zap.Error(err))
+				continue
+			}
+		}
+		calls = append(calls, ToolCall{ID: tb.id, Name: tb.name, Params: params})
+	}
+	return calls
+}
@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicChatStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":21,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the file. "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Use read_file."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"check."}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"path\": \"REA"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"DME.md\"}"}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	}
+	var req anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" || r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("unexpected request %s headers %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(ev), &typ)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewAnthropicProvider("test-key", srv.URL, "claude-test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.SupportsStreaming() {
+		t.Fatal("streaming should be enabled by default")
+	}
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "What does the README say?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_0", Name: "list_dir", Params: map[string]interface{}{"path": "."}}}},
+		{Role: "tool", ToolCallID: "toolu_0", Content: "README.md"},
+	}
+	tools := []ToolDefinition{{Name: "read_file", Description: "Read a file", Parameters: map[string]interface{}{"type": "object"}}}
+
+	var deltas []string
+	var final StreamChunk
+	err = p.ChatStream(context.Background(), messages, tools, func(chunk StreamChunk) {
+		if chunk.Done {
+			final = chunk
+			return
+		}
+		deltas = append(deltas, chunk.Content)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+
+	if strings.Join(deltas, "|") != "Let me |check." {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if final.Content != "Let me check." || final.ReasoningContent != "Need the file. Use read_file." {
+		t.Errorf("final content = %q reasoning = %q", final.Content, final.ReasoningContent)
+	}
+	if len(final.ToolCalls) != 1 || final.ToolCalls[0].ID != "toolu_1" || final.ToolCalls[0].Params["path"] != "README.md" {
+		t.Errorf("tool calls = %+v", final.ToolCalls)
+	}
+	if final.Usage == nil || final.Usage.PromptTokens != 21 || final.Usage.CompletionTokens != 9 {
+		t.Errorf("usage = %+v", final.Usage)
+	}
+
+	if !req.Stream || req.System != "You are helpful." || req.MaxTokens != anthropicDefaultMaxTokens {
+		t.Errorf("request stream = %v system = %q max_tokens = %d", req.Stream, req.System, req.MaxTokens)
+	}
+	if len(req.Messages) != 3 || req.Messages[1].Content[0].Type != "tool_use" || req.Messages[2].Content[0].ToolUseID != "toolu_0" {
+		t.Errorf("unexpected messages: %+v", req.Messages)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("unexpected tools: %+v", req.Tools)
+	}
+}
+
+func TestAnthropicChatStreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewAnthropicProviderWithStreaming("test-key", srv.URL, "claude-test", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SupportsStreaming() {
+		t.Error("streaming should be disabled")
+	}
+	err = p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, func(StreamChunk) {})
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+}
@@ -73,6 +73,7 @@ type ChatOptions struct {
 	Temperature float64
 	MaxTokens   int
 	Stream      bool
+	Thinking    string // 思考等级（ThinkingLevel*），空表示不设置，由提供商按默认处理
 }
 
 // WithModel 设置模型
@@ -103,6 +104,13 @@ func WithStream(stream bool) ChatOption {
 	}
 }
 
+// WithThinkingLevel 设置思考等级；不支持的提供商忽略
+func WithThinkingLevel(level string) ChatOption {
+	return func(o *ChatOptions) {
+		o.Thinking = NormalizeThinkingLevel(level)
+	}
+}
+
 // ConvertToLangChainMessages 转换为 LangChain 消息格式
 func ConvertToLangChainMessages(messages []Message) []llms.MessageContent {
 	result := make([]llms.MessageContent, len(messages))
@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// globalCallLimit 进程级 LLM 调用并发上限（providers.max_concurrent_calls）：主 agent 与子 agent lane、
+// 摘要等所有调用共用同一个信号量，多 agent 时不会同时压垮上游
+var globalCallLimit struct {
+	mu    sync.Mutex
+	limit int
+	sem   *semaphore.Weighted
+}
+
+// callSlotKey context 标记：调用方已持有全局名额，内层 provider 包装不再重复占用（避免 limit=1 时自锁）
+type callSlotKey struct{}
+
+// SetMaxConcurrentCalls 设置全局 LLM 调用并发上限，0 表示不限制。上限变化时换用新的信号量，已占用的名额仍释放回旧信号量
+func SetMaxConcurrentCalls(limit int) {
+	globalCallLimit.mu.Lock()
+	defer globalCallLimit.mu.Unlock()
+	if limit < 0 {
+		limit = 0
+	}
+	if limit == globalCallLimit.limit {
+		return
+	}
+	globalCallLimit.limit = limit
+	globalCallLimit.sem = nil
+	if limit > 0 {
+		globalCallLimit.sem = semaphore.NewWeighted(int64(limit))
+	}
+}
+
+// AcquireCallSlot 占用一个全局 LLM 调用名额，返回带占用标记的 ctx 与释放函数；未限制或 ctx 已持有名额时不占用。
+// ctx 取消时放弃等待并返回 ctx.Err()
+func AcquireCallSlot(ctx context.Context) (context.Context, func(), error) {
+	if ctx.Value(callSlotKey{}) != nil {
+		return ctx, func() {}, nil
+	}
+	globalCallLimit.mu.Lock()
+	sem := globalCallLimit.sem
+	globalCallLimit.mu.Unlock()
+	if sem == nil {
+		return ctx, func() {}, nil
+	}
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return ctx, func() {}, err
+	}
+	var once sync.Once
+	return context.WithValue(ctx, callSlotKey{}, true), func() { once.Do(func() { sem.Release(1) }) }, nil
+}
+
+// GlobalCallLimitProvider 在全局名额内调用内层 Provider；调用方（如 orchestrator）已占用名额时直接透传
+type GlobalCallLimitProvider struct {
+	inner Provider
+}
+
+// Chat 实现 Provider
+func (p *GlobalCallLimitProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	ctx, release, err := AcquireCallSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.inner.Chat(ctx, messages, tools, options...)
+}
+
+// ChatWithTools 实现 Provider
+func (p *GlobalCallLimitProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	ctx, release, err := AcquireCallSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.inner.ChatWithTools(ctx, messages, tools, options...)
+}
+
+// Close 转发到内层
+func (p *GlobalCallLimitProvider) Close() error {
+	return p.inner.Close()
+}
+
+// SupportsStreaming 转发到内层
+func (p *GlobalCallLimitProvider) SupportsStreaming() bool {
+	return p.inner.SupportsStreaming()
+}
+
+// globalLimitStreamingProvider 在全局名额内实现 StreamingProvider（仅当 inner 为 StreamingProvider 时使用）
+type globalLimitStreamingProvider struct {
+	*GlobalCallLimitProvider
+}
+
+var _ StreamingProvider = (*globalLimitStreamingProvider)(nil)
+
+// ChatStream 占用名额后调用内层 ChatStream，流结束后释放
+func (p *globalLimitStreamingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, callback StreamCallback, options ...ChatOption) error {
+	ctx, release, err := AcquireCallSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return p.inner.(StreamingProvider).ChatStream(ctx, messages, tools, callback, options...)
+}
+
+// WrapProviderWithGlobalCallLimit 用全局并发名额包装 Provider；若 inner 实现 StreamingProvider 则返回也实现 StreamingProvider
+func WrapProviderWithGlobalCallLimit(inner Provider) Provider {
+	limited := &GlobalCallLimitProvider{inner: inner}
+	if _, ok := inner.(StreamingProvider); ok {
+		return &globalLimitStreamingProvider{GlobalCallLimitProvider: limited}
+	}
+	return limited
+}
@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowProvider 记录同时处理中的调用峰值
+type slowProvider struct {
+	mockProvider
+	active, peak atomic.Int32
+}
+
+func (p *slowProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return &Response{Content: "ok"}, nil
+}
+
+func TestGlobalCallLimitSharedAcrossProviders(t *testing.T) {
+	SetMaxConcurrentCalls(2)
+	t.Cleanup(func() { SetMaxConcurrentCalls(0) })
+
+	// 两个 provider（如主 agent 与子 agent 使用的实例）共用同一全局名额
+	inner := &slowProvider{}
+	a, b := WrapProviderWithGlobalCallLimit(inner), WrapProviderWithGlobalCallLimit(inner)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		p := a
+		if i%2 == 1 {
+			p = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Chat(context.Background(), nil, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := inner.peak.Load(); got != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", got)
+	}
+}
+
+func TestAcquireCallSlotHonorsContextAndNesting(t *testing.T) {
+	SetMaxConcurrentCalls(1)
+	t.Cleanup(func() { SetMaxConcurrentCalls(0) })
+
+	held, release, err := AcquireCallSlot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 已持有名额的 ctx 经过包装的 provider 时不再占用，limit=1 也不会自锁
+	if _, err := WrapProviderWithGlobalCallLimit(&slowProvider{}).Chat(held, nil, nil); err != nil {
+		t.Fatalf("nested call: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := AcquireCallSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline while slot is held, got %v", err)
+	}
+
+	release()
+	release() // 重复释放无副作用
+	_, release2, err := AcquireCallSlot(context.Background())
+	if err != nil {
+		t.Fatalf("slot not released: %v", err)
+	}
+	release2()
+}
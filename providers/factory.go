@@ -19,9 +19,11 @@ const (
 	ProviderTypeOpenRouter ProviderType = "openrouter"
 	ProviderTypeMoonshot   ProviderType = "moonshot" // Kimi 月之暗面，OpenAI 兼容 API
 	ProviderTypeRouter9    ProviderType = "9router"  // 9router 本地代理，OpenAI 兼容 API
+	ProviderTypeGemini     ProviderType = "gemini"   // Google Gemini generateContent API
 )
 
-// NewProvider 创建提供商（支持故障转移和配置轮换）。若配置了 providers.max_concurrent_calls > 0 则包一层全局并发限制，多 agent 时避免同时请求模型接口导致卡死。
+// NewProvider 创建提供商（支持故障转移和配置轮换）。providers.max_concurrent_calls 设置进程级并发上限，
+// 所有 agent / 子 agent 共用同一信号量，多 agent 时避免同时请求模型接口导致卡死。
 func NewProvider(cfg *config.Config) (Provider, error) {
 	var inner Provider
 	var err error
@@ -33,10 +35,12 @@ func NewProvider(cfg *config.Config) (Provider, error) {
 	if err != nil {
 		return nil, err
 	}
-	return WrapProviderWithConcurrencyLimit(inner, cfg.Providers.MaxConcurrentCalls), nil
+	DefaultUsageTracker().Configure(cfg.Providers.DailyTokenBudget, DefaultUsagePersistPath())
+	SetMaxConcurrentCalls(cfg.Providers.MaxConcurrentCalls)
+	return WrapProviderWithGlobalCallLimit(inner), nil
 }
 
-// NewSimpleProvider 创建单一提供商
+// NewSimpleProvider 创建单一提供商（带 token 用量统计）
 func NewSimpleProvider(cfg *config.Config) (Provider, error) {
 	// 确定使用哪个提供商
 	providerType, model, err := determineProvider(cfg)
@@ -47,13 +51,22 @@ func NewSimpleProvider(cfg *config.Config) (Provider, error) {
 		zap.String("provider", string(providerType)),
 		zap.String("model", model))
 
+	prov, err := newSimpleProviderByType(cfg, providerType, model)
+	if err != nil {
+		return nil, err
+	}
+	return WrapProviderWithUsageTracking(prov, string(providerType), model, nil), nil
+}
+
+// newSimpleProviderByType 按已解析的提供商类型与模型创建提供商
+func newSimpleProviderByType(cfg *config.Config, providerType ProviderType, model string) (Provider, error) {
 	switch providerType {
 	case ProviderTypeOpenAI:
 		streaming := true
 		if cfg.Providers.OpenAI.Streaming != nil {
 			streaming = *cfg.Providers.OpenAI.Streaming
 		}
-		return NewOpenAIProviderWithStreaming(
+		prov, err := NewOpenAIProviderWithStreaming(
 			cfg.Providers.OpenAI.APIKey,
 			cfg.Providers.OpenAI.BaseURL,
 			model,
@@ -61,14 +74,22 @@ func NewSimpleProvider(cfg *config.Config) (Provider, error) {
 			cfg.Providers.OpenAI.ExtraBody,
 			streaming,
 		)
+		if err != nil {
+			return nil, err
+		}
+		return prov.WithHeaders(cfg.Providers.OpenAI.Headers), nil
 	case ProviderTypeAnthropic:
-		return NewAnthropicProvider(cfg.Providers.Anthropic.APIKey, cfg.Providers.Anthropic.BaseURL, model, cfg.Agents.Defaults.MaxTokens)
+		streaming := true
+		if cfg.Providers.Anthropic.Streaming != nil {
+			streaming = *cfg.Providers.Anthropic.Streaming
+		}
+		return NewAnthropicProviderWithStreaming(cfg.Providers.Anthropic.APIKey, cfg.Providers.Anthropic.BaseURL, model, cfg.Agents.Defaults.MaxTokens, streaming)
 	case ProviderTypeOpenRouter:
 		streaming := true
 		if cfg.Providers.OpenRouter.Streaming != nil {
 			streaming = *cfg.Providers.OpenRouter.Streaming
 		}
-		return NewOpenRouterProviderWithStreaming(cfg.Providers.OpenRouter.APIKey, cfg.Providers.OpenRouter.BaseURL, model, cfg.Agents.Defaults.MaxTokens, streaming)
+		return NewOpenRouterProviderWithHeaders(cfg.Providers.OpenRouter.APIKey, cfg.Providers.OpenRouter.BaseURL, model, cfg.Agents.Defaults.MaxTokens, streaming, cfg.Providers.OpenRouter.Headers)
 	case ProviderTypeMoonshot:
 		baseURL := cfg.Providers.Moonshot.BaseURL
 		if baseURL == "" {
@@ -109,6 +130,12 @@ func NewSimpleProvider(cfg *config.Config) (Provider, error) {
 			streaming,
 			skipTools,
 		)
+	case ProviderTypeGemini:
+		streaming := true
+		if cfg.Providers.Gemini.Streaming != nil {
+			streaming = *cfg.Providers.Gemini.Streaming
+		}
+		return NewGeminiProvider(cfg.Providers.Gemini.APIKey, cfg.Providers.Gemini.BaseURL, model, cfg.Agents.Defaults.MaxTokens, streaming)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
@@ -131,23 +158,11 @@ func NewRotationProviderFromConfig(cfg *config.Config) (Provider, error) {
 		cfg.Providers.Failover.DefaultCooldown,
 		errorClassifier,
 	)
+	rotation.SetCircuitBreaker(cfg.Providers.Failover.CircuitBreaker.FailureThreshold, cfg.Providers.Failover.CircuitBreaker.Timeout)
 
 	// 添加所有配置
 	for _, profileCfg := range cfg.Providers.Profiles {
-		streaming, extraBody := resolveStreamingAndExtraBodyForProfile(cfg, profileCfg.Provider, profileCfg.Streaming, profileCfg.ExtraBody)
-		skipTools := ProviderType(profileCfg.Provider) == ProviderTypeRouter9 &&
-			cfg.Providers.Router9.ToolsEnabled != nil && !*cfg.Providers.Router9.ToolsEnabled
-
-		prov, err := createProviderByTypeWithStreaming(
-			profileCfg.Provider,
-			profileCfg.APIKey,
-			profileCfg.BaseURL,
-			cfg.Agents.Defaults.Model,
-			cfg.Agents.Defaults.MaxTokens,
-			extraBody,
-			streaming,
-			skipTools,
-		)
+		prov, err := newProfileProvider(cfg, profileCfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create provider for profile %s: %w", profileCfg.Name, err)
 		}
@@ -162,29 +177,36 @@ func NewRotationProviderFromConfig(cfg *config.Config) (Provider, error) {
 
 	// 如果只有一个配置，返回第一个提供商
 	if len(cfg.Providers.Profiles) == 1 {
-		p := cfg.Providers.Profiles[0]
-		streaming, extraBody := resolveStreamingAndExtraBodyForProfile(cfg, p.Provider, p.Streaming, p.ExtraBody)
-		skipTools := ProviderType(p.Provider) == ProviderTypeRouter9 &&
-			cfg.Providers.Router9.ToolsEnabled != nil && !*cfg.Providers.Router9.ToolsEnabled
-		prov, err := createProviderByTypeWithStreaming(
-			p.Provider,
-			p.APIKey,
-			p.BaseURL,
-			cfg.Agents.Defaults.Model,
-			cfg.Agents.Defaults.MaxTokens,
-			extraBody,
-			streaming,
-			skipTools,
-		)
-		if err != nil {
-			return nil, err
-		}
-		return prov, nil
+		return newProfileProvider(cfg, cfg.Providers.Profiles[0])
 	}
 
 	return rotation, nil
 }
 
+// newProfileProvider 按 profile 创建提供商：带 token 用量统计，max_concurrent > 0 时再包一层该 profile 独立的并发限制
+// （与 providers.max_concurrent_calls 全局限制叠加生效）
+func newProfileProvider(cfg *config.Config, p config.ProviderProfileConfig) (Provider, error) {
+	streaming, extraBody := resolveStreamingAndExtraBodyForProfile(cfg, p.Provider, p.Streaming, p.ExtraBody)
+	skipTools := ProviderType(p.Provider) == ProviderTypeRouter9 &&
+		cfg.Providers.Router9.ToolsEnabled != nil && !*cfg.Providers.Router9.ToolsEnabled
+	prov, err := createProviderByTypeWithStreaming(
+		p.Provider,
+		p.APIKey,
+		p.BaseURL,
+		cfg.Agents.Defaults.Model,
+		cfg.Agents.Defaults.MaxTokens,
+		extraBody,
+		p.Headers,
+		streaming,
+		skipTools,
+	)
+	if err != nil {
+		return nil, err
+	}
+	prov = WrapProviderWithUsageTracking(prov, p.Provider, cfg.Agents.Defaults.Model, nil)
+	return WrapProviderWithConcurrencyLimit(prov, p.MaxConcurrent), nil
+}
+
 // resolveStreamingAndExtraBodyForProfile 解析 profile 的流式与 extra_body：当 profile 未指定时，9router 回退到 providers.9router 配置。
 func resolveStreamingAndExtraBodyForProfile(cfg *config.Config, providerType string, profileStreaming *bool, profileExtraBody map[string]interface{}) (streaming bool, extraBody map[string]interface{}) {
 	if ProviderType(providerType) == ProviderTypeRouter9 {
@@ -212,23 +234,26 @@ func resolveStreamingAndExtraBodyForProfile(cfg *config.Config, providerType str
 
 // createProviderByType 根据类型创建提供商
 func createProviderByType(providerType, apiKey, baseURL, model string, maxTokens int, extraBody map[string]interface{}) (Provider, error) {
-	return createProviderByTypeWithStreaming(providerType, apiKey, baseURL, model, maxTokens, extraBody, true)
+	return createProviderByTypeWithStreaming(providerType, apiKey, baseURL, model, maxTokens, extraBody, nil, true)
 }
 
-// createProviderByTypeWithStreaming 根据类型创建提供商（带流式配置）。optSkipTools 仅用于 9router：传 true 时不向 API 传 tools。
-func createProviderByTypeWithStreaming(providerType, apiKey, baseURL, model string, maxTokens int, extraBody map[string]interface{}, streaming bool, optSkipTools ...bool) (Provider, error) {
+// createProviderByTypeWithStreaming 根据类型创建提供商（带流式配置）。headers 附加到 openai 兼容与 openrouter 的每个请求；
+// optSkipTools 仅用于 9router：传 true 时不向 API 传 tools。
+func createProviderByTypeWithStreaming(providerType, apiKey, baseURL, model string, maxTokens int, extraBody map[string]interface{}, headers map[string]string, streaming bool, optSkipTools ...bool) (Provider, error) {
 	skipTools := len(optSkipTools) > 0 && optSkipTools[0]
 	switch ProviderType(providerType) {
-	case ProviderTypeOpenAI:
-		return NewOpenAIProviderWithStreaming(apiKey, baseURL, model, maxTokens, extraBody, streaming)
+	case ProviderTypeOpenAI, ProviderTypeMoonshot, ProviderTypeRouter9:
+		prov, err := NewOpenAIProviderWithStreaming(apiKey, baseURL, model, maxTokens, extraBody, streaming, skipTools)
+		if err != nil {
+			return nil, err
+		}
+		return prov.WithHeaders(headers), nil
 	case ProviderTypeAnthropic:
-		return NewAnthropicProvider(apiKey, baseURL, model, maxTokens)
+		return NewAnthropicProviderWithStreaming(apiKey, baseURL, model, maxTokens, streaming)
 	case ProviderTypeOpenRouter:
-		return NewOpenRouterProviderWithStreaming(apiKey, baseURL, model, maxTokens, streaming)
-	case ProviderTypeMoonshot:
-		return NewOpenAIProviderWithStreaming(apiKey, baseURL, model, maxTokens, extraBody, streaming)
-	case ProviderTypeRouter9:
-		return NewOpenAIProviderWithStreaming(apiKey, baseURL, model, maxTokens, extraBody, streaming, skipTools)
+		return NewOpenRouterProviderWithHeaders(apiKey, baseURL, model, maxTokens, streaming, headers)
+	case ProviderTypeGemini:
+		return NewGeminiProvider(apiKey, baseURL, model, maxTokens, streaming)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
@@ -239,27 +264,8 @@ func determineProvider(cfg *config.Config) (ProviderType, string, error) {
 	model := cfg.Agents.Defaults.Model
 
 	// 检查模型名称前缀
-	if strings.HasPrefix(model, "openrouter:") {
-		return ProviderTypeOpenRouter, strings.TrimPrefix(model, "openrouter:"), nil
-	}
-
-	if strings.HasPrefix(model, "anthropic:") || strings.HasPrefix(model, "claude-") {
-		return ProviderTypeAnthropic, model, nil
-	}
-
-	if strings.HasPrefix(model, "openai:") || strings.HasPrefix(model, "gpt-") {
-		return ProviderTypeOpenAI, model, nil
-	}
-
-	if strings.HasPrefix(model, "moonshot:") {
-		return ProviderTypeMoonshot, strings.TrimPrefix(model, "moonshot:"), nil
-	}
-	if strings.HasPrefix(model, "kimi-") {
-		return ProviderTypeMoonshot, model, nil
-	}
-
-	if strings.HasPrefix(model, "9router:") {
-		return ProviderTypeRouter9, strings.TrimPrefix(model, "9router:"), nil
+	if providerType, name, ok := providerTypeFromModel(model); ok {
+		return providerType, name, nil
 	}
 
 	// 根据可用的 API key 决定
@@ -283,5 +289,73 @@ func determineProvider(cfg *config.Config) (ProviderType, string, error) {
 		return ProviderTypeMoonshot, model, nil
 	}
 
+	if cfg.Providers.Gemini.APIKey != "" {
+		return ProviderTypeGemini, model, nil
+	}
+
 	return "", "", fmt.Errorf("no LLM provider API key configured")
 }
+
+// providerTypeFromModel 按模型名前缀（如 openrouter:、claude-、gemini-）推断提供商，返回去掉路由前缀后的模型名
+func providerTypeFromModel(model string) (ProviderType, string, bool) {
+	if strings.HasPrefix(model, "openrouter:") {
+		return ProviderTypeOpenRouter, strings.TrimPrefix(model, "openrouter:"), true
+	}
+
+	if strings.HasPrefix(model, "anthropic:") {
+		return ProviderTypeAnthropic, strings.TrimPrefix(model, "anthropic:"), true
+	}
+	if strings.HasPrefix(model, "claude-") {
+		return ProviderTypeAnthropic, model, true
+	}
+
+	if strings.HasPrefix(model, "openai:") {
+		return ProviderTypeOpenAI, strings.TrimPrefix(model, "openai:"), true
+	}
+	if strings.HasPrefix(model, "gpt-") {
+		return ProviderTypeOpenAI, model, true
+	}
+
+	if strings.HasPrefix(model, "moonshot:") {
+		return ProviderTypeMoonshot, strings.TrimPrefix(model, "moonshot:"), true
+	}
+	if strings.HasPrefix(model, "kimi-") {
+		return ProviderTypeMoonshot, model, true
+	}
+
+	if strings.HasPrefix(model, "9router:") {
+		return ProviderTypeRouter9, strings.TrimPrefix(model, "9router:"), true
+	}
+
+	if strings.HasPrefix(model, "gemini:") {
+		return ProviderTypeGemini, strings.TrimPrefix(model, "gemini:"), true
+	}
+	if strings.HasPrefix(model, "gemini-") {
+		return ProviderTypeGemini, model, true
+	}
+	return "", model, false
+}
+
+// ResolveProviderName 按 NewProvider 的规则确定模型由哪个提供商处理：模型名前缀优先，否则按已配置的 API key；
+// 无法确定时返回空字符串
+func ResolveProviderName(cfg *config.Config, model string) string {
+	if providerType, _, ok := providerTypeFromModel(model); ok {
+		return string(providerType)
+	}
+	if cfg == nil {
+		return ""
+	}
+	resolveCfg := *cfg
+	resolveCfg.Agents.Defaults.Model = model
+	providerType, _, err := determineProvider(&resolveCfg)
+	if err != nil {
+		return ""
+	}
+	return string(providerType)
+}
+
+// ProviderNameForModel 按模型名前缀推断提供商名称，无法判断时返回空字符串
+func ProviderNameForModel(model string) string {
+	providerType, _, _ := providerTypeFromModel(model)
+	return string(providerType)
+}
@@ -30,6 +30,10 @@ func (m *mockProvider) Close() error {
 	return nil
 }
 
+func (m *mockProvider) SupportsStreaming() bool {
+	return false
+}
+
 func TestNewFailoverProvider(t *testing.T) {
 	primary := &mockProvider{}
 	fallback := &mockProvider{}
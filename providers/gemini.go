@@ -0,0 +1,472 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// defaultGeminiBaseURL Gemini API 默认地址（v1beta 支持 function calling 与 systemInstruction）
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiProvider Google Gemini 提供商，直接调用 generateContent / streamGenerateContent REST API
+type GeminiProvider struct {
+	apiKey           string
+	baseURL          string
+	model            string
+	maxTokens        int
+	streamingEnabled bool
+	client           *http.Client
+}
+
+// NewGeminiProvider 创建 Gemini 提供商；baseURL 为空时使用官方地址
+func NewGeminiProvider(apiKey, baseURL, model string, maxTokens int, streaming bool) (*GeminiProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+	if model == "" {
+		model = "gemini-2.0-flash"
+	}
+	return &GeminiProvider{
+		apiKey:           apiKey,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		model:            model,
+		maxTokens:        maxTokens,
+		streamingEnabled: streaming,
+		client:           &http.Client{},
+	}, nil
+}
+
+// Gemini REST API 请求/响应结构（仅包含用到的字段）
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"` // user / model；systemInstruction 不带 role
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64              `json:"temperature,omitempty"`
+	MaxOutputTokens int                   `json:"maxOutputTokens,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget int `json:"thinkingBudget"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+// Chat 聊天
+func (p *GeminiProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	opts := p.chatOptions(options)
+	body, err := p.doRequest(ctx, opts.Model, "generateContent", buildGeminiRequest(messages, tools, opts))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp geminiResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode gemini response: %w", err)
+	}
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("gemini blocked the prompt: %s", resp.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("gemini returned no candidates")
+	}
+
+	var acc geminiAccumulator
+	acc.add(resp.Candidates[0].Content.Parts)
+	response := &Response{
+		Content:          acc.content.String(),
+		ReasoningContent: acc.reasoning.String(),
+		ToolCalls:        acc.toolCalls,
+		FinishReason:     geminiFinishReason(resp.Candidates[0].FinishReason, len(acc.toolCalls) > 0),
+	}
+	if u := resp.UsageMetadata; u != nil {
+		response.Usage = Usage{
+			PromptTokens:     u.PromptTokenCount,
+			CompletionTokens: u.CandidatesTokenCount,
+			TotalTokens:      u.TotalTokenCount,
+		}
+	}
+	return response, nil
+}
+
+// ChatWithTools 聊天（带工具）
+func (p *GeminiProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	return p.Chat(ctx, messages, tools, options...)
+}
+
+// ChatStream 通过 streamGenerateContent（SSE）流式输出；函数调用在结束时随 Done 一并返回
+func (p *GeminiProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, callback StreamCallback, options ...ChatOption) error {
+	opts := p.chatOptions(options)
+	body, err := p.doRequest(ctx, opts.Model, "streamGenerateContent?alt=sse", buildGeminiRequest(messages, tools, opts))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var acc geminiAccumulator
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode gemini stream chunk: %w", err)
+		}
+		if len(chunk.Candidates) == 0 {
+			continue
+		}
+		if delta := acc.add(chunk.Candidates[0].Content.Parts); delta != "" {
+			callback(StreamChunk{Content: delta})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream error: %w", err)
+	}
+
+	callback(StreamChunk{
+		Content:   acc.content.String(),
+		Done:      true,
+		ToolCalls: acc.toolCalls,
+	})
+	return nil
+}
+
+// Close 关闭连接
+func (p *GeminiProvider) Close() error {
+	return nil
+}
+
+// SupportsStreaming returns whether streaming is enabled for this provider.
+func (p *GeminiProvider) SupportsStreaming() bool {
+	return p.streamingEnabled
+}
+
+// chatOptions 合并默认选项与调用方选项
+func (p *GeminiProvider) chatOptions(options []ChatOption) *ChatOptions {
+	opts := &ChatOptions{
+		Model:     p.model,
+		MaxTokens: p.maxTokens,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	opts.Model = strings.TrimPrefix(strings.TrimPrefix(opts.Model, "gemini:"), "models/")
+	return opts
+}
+
+// doRequest 发送请求并返回响应体；非 2xx 时把状态码与响应体带入错误，便于限流等错误分类
+func (p *GeminiProvider) doRequest(ctx context.Context, model, method string, req *geminiRequest) (io.ReadCloser, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode gemini request: %w", err)
+	}
+	url := fmt.Sprintf("%s/models/%s:%s", p.baseURL, model, method)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	logger.Debug("Gemini request",
+		zap.String("model", model),
+		zap.String("method", method),
+		zap.Int("contents", len(req.Contents)),
+		zap.Int("tools", len(req.Tools)))
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("gemini request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("gemini API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
+
+// buildGeminiRequest 将通用消息转换为 Gemini 请求：
+// system 合并到 systemInstruction，assistant 映射为 model，tool 结果以 user 角色的 functionResponse 发送，相邻同角色内容合并
+func buildGeminiRequest(messages []Message, tools []ToolDefinition, opts *ChatOptions) *geminiRequest {
+	req := &geminiRequest{Contents: make([]geminiContent, 0, len(messages))}
+
+	// tool 消息可能缺少 ToolName，通过 assistant 的 tool call ID 反查函数名
+	toolNames := make(map[string]string)
+	var systemParts []geminiPart
+
+	for _, msg := range messages {
+		var role string
+		var parts []geminiPart
+		switch msg.Role {
+		case "system":
+			if strings.TrimSpace(msg.Content) != "" {
+				systemParts = append(systemParts, geminiPart{Text: msg.Content})
+			}
+			continue
+		case "assistant":
+			role = "model"
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				toolNames[tc.ID] = tc.Name
+				args := tc.Params
+				if args == nil {
+					args = map[string]interface{}{}
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: tc.Name, Args: args}})
+			}
+		case "tool":
+			role = "user"
+			name := msg.ToolName
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     name,
+				Response: map[string]interface{}{"content": msg.Content},
+			}})
+		default:
+			role = "user"
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, img := range msg.Images {
+				if blob := geminiImageBlob(img); blob != nil {
+					parts = append(parts, geminiPart{InlineData: blob})
+				}
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if n := len(req.Contents); n > 0 && req.Contents[n-1].Role == role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, parts...)
+			continue
+		}
+		req.Contents = append(req.Contents, geminiContent{Role: role, Parts: parts})
+	}
+
+	if len(systemParts) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: systemParts}
+	}
+
+	if len(tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(tools))
+		for _, tool := range tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  geminiSchema(tool.Parameters),
+			})
+		}
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+
+	if opts != nil && (opts.Temperature > 0 || opts.MaxTokens > 0 || thinkingBudget(opts.Thinking) > 0) {
+		gc := &geminiGenerationConfig{MaxOutputTokens: opts.MaxTokens}
+		if opts.Temperature > 0 {
+			t := opts.Temperature
+			gc.Temperature = &t
+		}
+		if budget := thinkingBudget(opts.Thinking); budget > 0 {
+			gc.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget}
+		}
+		req.GenerationConfig = gc
+	}
+	return req
+}
+
+// geminiSchemaKeys Gemini functionDeclarations.parameters 支持的 OpenAPI schema 字段，其余（如 additionalProperties、$schema）会被拒绝
+var geminiSchemaKeys = map[string]bool{
+	"type": true, "format": true, "description": true, "nullable": true, "enum": true,
+	"properties": true, "required": true, "items": true, "minItems": true, "maxItems": true,
+	"minimum": true, "maximum": true,
+}
+
+// geminiSchema 清理 JSON Schema 中 Gemini 不支持的字段；无属性的 object 返回 nil（Gemini 不接受空 properties）
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	if len(schema) == 0 {
+		return nil
+	}
+	if t, _ := schema["type"].(string); t == "object" {
+		if props, _ := schema["properties"].(map[string]interface{}); len(props) == 0 {
+			return nil
+		}
+	}
+	return sanitizeGeminiSchema(schema)
+}
+
+func sanitizeGeminiSchema(schema map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		if !geminiSchemaKeys[k] {
+			continue
+		}
+		switch k {
+		case "properties":
+			props, _ := v.(map[string]interface{})
+			cleaned := make(map[string]interface{}, len(props))
+			for name, prop := range props {
+				if m, ok := prop.(map[string]interface{}); ok {
+					cleaned[name] = sanitizeGeminiSchema(m)
+				}
+			}
+			out[k] = cleaned
+		case "items":
+			if m, ok := v.(map[string]interface{}); ok {
+				out[k] = sanitizeGeminiSchema(m)
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// geminiImageBlob 将 data URL 或裸 base64 图片转换为 inlineData；http(s) URL 不支持内联，忽略
+func geminiImageBlob(img string) *geminiBlob {
+	if strings.HasPrefix(img, "data:") {
+		meta, data, ok := strings.Cut(strings.TrimPrefix(img, "data:"), ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil
+		}
+		return &geminiBlob{MimeType: strings.TrimSuffix(meta, ";base64"), Data: data}
+	}
+	if strings.HasPrefix(img, "http://") || strings.HasPrefix(img, "https://") {
+		logger.Debug("Gemini: skipping remote image URL (inline data only)", zap.String("url", img))
+		return nil
+	}
+	head, err := base64.StdEncoding.DecodeString(img[:min(len(img), 64)/4*4])
+	if err != nil {
+		return nil
+	}
+	return &geminiBlob{MimeType: http.DetectContentType(head), Data: img}
+}
+
+// geminiAccumulator 累积响应 parts：文本、思考内容与函数调用
+type geminiAccumulator struct {
+	content   strings.Builder
+	reasoning strings.Builder
+	toolCalls []ToolCall
+}
+
+// add 处理一组 parts，返回其中新增的可见文本
+func (a *geminiAccumulator) add(parts []geminiPart) string {
+	var delta strings.Builder
+	for _, part := range parts {
+		switch {
+		case part.FunctionCall != nil:
+			id := part.FunctionCall.ID
+			if id == "" {
+				// Gemini 的函数调用通常不带 ID，生成一个供 tool 结果回传时匹配
+				id = "call_" + uuid.New().String()
+			}
+			params := part.FunctionCall.Args
+			if params == nil {
+				params = map[string]interface{}{}
+			}
+			a.toolCalls = append(a.toolCalls, ToolCall{ID: id, Name: part.FunctionCall.Name, Params: params})
+		case part.Thought:
+			a.reasoning.WriteString(part.Text)
+		case part.Text != "":
+			a.content.WriteString(part.Text)
+			delta.WriteString(part.Text)
+		}
+	}
+	return delta.String()
+}
+
+// geminiFinishReason 将 Gemini finishReason 映射为通用值
+func geminiFinishReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "", "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	default:
+		return strings.ToLower(reason)
+	}
+}
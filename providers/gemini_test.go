@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGeminiToolCallRoundTrip(t *testing.T) {
+	var requests []geminiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing api key header")
+		}
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"read_file","args":{"path":"README.md"}}}]},"finishReason":"STOP"}],
+				"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":3,"totalTokenCount":15}}`)
+			return
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"The README says hello."}]},"finishReason":"STOP"}]}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewGeminiProvider("test-key", srv.URL, "gemini:gemini-2.0-flash", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := []ToolDefinition{{
+		Name:        "read_file",
+		Description: "Read a file",
+		Parameters: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+			"properties":           map[string]interface{}{"path": map[string]interface{}{"type": "string"}},
+			"required":             []interface{}{"path"},
+		},
+	}}
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "What does the README say?"},
+	}
+
+	resp, err := p.Chat(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "read_file" || resp.ToolCalls[0].Params["path"] != "README.md" || resp.ToolCalls[0].ID == "" {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.FinishReason != "tool_calls" || resp.Usage.TotalTokens != 15 {
+		t.Errorf("finish = %q usage = %+v", resp.FinishReason, resp.Usage)
+	}
+
+	first := requests[0]
+	if first.SystemInstruction == nil || first.SystemInstruction.Parts[0].Text != "You are helpful." {
+		t.Errorf("system instruction not set: %+v", first.SystemInstruction)
+	}
+	if len(first.Contents) != 1 || first.Contents[0].Role != "user" {
+		t.Errorf("unexpected contents: %+v", first.Contents)
+	}
+	decl := first.Tools[0].FunctionDeclarations[0]
+	if _, ok := decl.Parameters["additionalProperties"]; ok || decl.Name != "read_file" {
+		t.Errorf("function declaration not sanitized: %+v", decl)
+	}
+
+	// 回传工具结果（tool 消息不带 ToolName，按 tool call ID 反查）
+	call := resp.ToolCalls[0]
+	messages = append(messages,
+		Message{Role: "assistant", ToolCalls: []ToolCall{call}},
+		Message{Role: "tool", ToolCallID: call.ID, Content: "hello"},
+	)
+	resp, err = p.Chat(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatalf("second Chat() error = %v", err)
+	}
+	if resp.Content != "The README says hello." || len(resp.ToolCalls) != 0 || resp.FinishReason != "stop" {
+		t.Errorf("unexpected final response: %+v", resp)
+	}
+
+	second := requests[1].Contents
+	if len(second) != 3 || second[1].Role != "model" || second[2].Role != "user" {
+		t.Fatalf("unexpected roles in second request: %+v", second)
+	}
+	fc := second[1].Parts[0].FunctionCall
+	if fc == nil || fc.Name != "read_file" || fc.Args["path"] != "README.md" {
+		t.Errorf("function call not round-tripped: %+v", second[1].Parts)
+	}
+	fr := second[2].Parts[0].FunctionResponse
+	if fr == nil || fr.Name != "read_file" || fr.Response["content"] != "hello" {
+		t.Errorf("function response not sent: %+v", second[2].Parts)
+	}
+}
+
+func TestGeminiChatStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected stream url %s", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Let me \"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"check.\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"functionCall\":{\"id\":\"fc-1\",\"name\":\"exec\",\"args\":{\"command\":\"ls\"}}}]},\"finishReason\":\"STOP\"}]}\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewGeminiProvider("test-key", srv.URL, "gemini-2.5-flash", 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deltas []string
+	var final StreamChunk
+	err = p.ChatStream(context.Background(), []Message{{Role: "user", Content: "list files"}}, nil, func(chunk StreamChunk) {
+		if chunk.Done {
+			final = chunk
+			return
+		}
+		deltas = append(deltas, chunk.Content)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if strings.Join(deltas, "") != "Let me check." || final.Content != "Let me check." {
+		t.Errorf("deltas = %q final = %q", deltas, final.Content)
+	}
+	if len(final.ToolCalls) != 1 || final.ToolCalls[0].ID != "fc-1" || final.ToolCalls[0].Params["command"] != "ls" {
+		t.Errorf("unexpected streamed tool calls: %+v", final.ToolCalls)
+	}
+}
@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"net/http"
+)
+
+// DefaultOpenRouterHeaders OpenRouter 推荐的归因请求头（用于排行榜与部分功能），可被 providers.openrouter.headers 覆盖
+func DefaultOpenRouterHeaders() map[string]string {
+	return map[string]string{
+		"HTTP-Referer": "https://github.com/smallnest/goclaw",
+		"X-Title":      "goclaw",
+	}
+}
+
+// mergeHeaders 按顺序合并请求头，后者覆盖前者（名称不区分大小写）；值为空表示删除该头
+func mergeHeaders(layers ...map[string]string) http.Header {
+	h := make(http.Header)
+	for _, layer := range layers {
+		for name, value := range layer {
+			if value == "" {
+				h.Del(name)
+				continue
+			}
+			h.Set(name, value)
+		}
+	}
+	return h
+}
+
+// headerTransport 为每个请求附加固定请求头
+type headerTransport struct {
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// newHeaderHTTPClient 创建附加请求头的 HTTP 客户端；无请求头时返回 nil，调用方使用默认客户端
+func newHeaderHTTPClient(headers http.Header) *http.Client {
+	if len(headers) == 0 {
+		return nil
+	}
+	return &http.Client{Transport: &headerTransport{headers: headers}}
+}
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const fakeCompletion = `{"id":"c1","object":"chat.completion","created":1,"model":"m",
+	"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}],
+	"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`
+
+func captureHeadersServer(t *testing.T, got *http.Header) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, fakeCompletion)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAIProviderSendsConfiguredHeaders(t *testing.T) {
+	var got http.Header
+	srv := captureHeadersServer(t, &got)
+
+	prov, err := createProviderByTypeWithStreaming("openai", "sk-test-key", srv.URL, "gpt-4o", 0, nil,
+		map[string]string{"x-proxy-auth": "corp-secret", "X-Team": "infra"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prov.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("X-Proxy-Auth") != "corp-secret" || got.Get("X-Team") != "infra" {
+		t.Fatalf("configured headers missing: %v", got)
+	}
+	if got.Get("Authorization") != "Bearer sk-test-key" {
+		t.Fatalf("authorization header changed: %q", got.Get("Authorization"))
+	}
+}
+
+func TestOpenRouterProviderSendsDefaultAndConfiguredHeaders(t *testing.T) {
+	var got http.Header
+	srv := captureHeadersServer(t, &got)
+
+	prov, err := NewOpenRouterProviderWithHeaders("sk-or-test", srv.URL, "openai/gpt-4o", 0, false,
+		map[string]string{"x-title": "my-bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prov.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("HTTP-Referer") != DefaultOpenRouterHeaders()["HTTP-Referer"] {
+		t.Fatalf("default HTTP-Referer missing: %v", got)
+	}
+	if got.Get("X-Title") != "my-bot" {
+		t.Fatalf("configured X-Title should override default, got %q", got.Get("X-Title"))
+	}
+}
@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// concurrencyServer 记录同时处理中的请求峰值
+func concurrencyServer(t *testing.T, peak *int32) *httptest.Server {
+	t.Helper()
+	var active int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(40 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, fakeCompletion)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProfileMaxConcurrentBoundsEachProviderIndependently(t *testing.T) {
+	var peakA, peakB int32
+	srvA := concurrencyServer(t, &peakA)
+	srvB := concurrencyServer(t, &peakB)
+	streaming := false
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Model: "gpt-4o"}}}
+	profiles := []config.ProviderProfileConfig{
+		{Name: "openai", Provider: "openai", APIKey: "sk-a", BaseURL: srvA.URL, Streaming: &streaming, MaxConcurrent: 2},
+		{Name: "local-proxy", Provider: "openai", APIKey: "sk-b", BaseURL: srvB.URL, Streaming: &streaming, MaxConcurrent: 1},
+	}
+
+	const calls = 6
+	var wg sync.WaitGroup
+	for _, p := range profiles {
+		prov, err := newProfileProvider(cfg, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < calls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := prov.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if peakA != 2 {
+		t.Fatalf("openai profile peak concurrency = %d, want 2", peakA)
+	}
+	if peakB != 1 {
+		t.Fatalf("local-proxy profile peak concurrency = %d, want 1", peakB)
+	}
+}
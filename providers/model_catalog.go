@@ -0,0 +1,239 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// ModelCatalogTTL 提供商 /models 结果的缓存时间
+const ModelCatalogTTL = 5 * time.Minute
+
+// modelCatalogTimeout 单个提供商 /models 请求的超时
+const modelCatalogTimeout = 15 * time.Second
+
+// ModelInfo models.list 中的一个模型
+type ModelInfo struct {
+	ID            string `json:"id"`
+	Provider      string `json:"provider,omitempty"`
+	ContextWindow int    `json:"contextWindow,omitempty"` // 上下文窗口 token 数，0 表示未知
+}
+
+// modelsEndpoint 一个 OpenAI 兼容的 /models 端点
+type modelsEndpoint struct {
+	provider ProviderType
+	baseURL  string
+	apiKey   string
+	headers  map[string]string
+}
+
+type modelCatalogEntry struct {
+	models    []ModelInfo
+	fetchedAt time.Time
+}
+
+var (
+	modelCatalogMu    sync.Mutex
+	modelCatalogCache = make(map[string]modelCatalogEntry)
+)
+
+// modelsEndpoints 已配置的 OpenAI 兼容提供商（openai / openrouter / moonshot / 9router）的 /models 端点；
+// anthropic 与 gemini 的模型列表接口格式不同，不在此列
+func modelsEndpoints(cfg *config.Config) []modelsEndpoint {
+	var out []modelsEndpoint
+	p := cfg.Providers
+	if p.OpenAI.APIKey != "" {
+		out = append(out, modelsEndpoint{ProviderTypeOpenAI, orDefault(p.OpenAI.BaseURL, "https://api.openai.com/v1"), p.OpenAI.APIKey, p.OpenAI.Headers})
+	}
+	if p.OpenRouter.APIKey != "" {
+		out = append(out, modelsEndpoint{ProviderTypeOpenRouter, orDefault(p.OpenRouter.BaseURL, "https://openrouter.ai/api/v1"), p.OpenRouter.APIKey, p.OpenRouter.Headers})
+	}
+	if p.Moonshot.APIKey != "" {
+		out = append(out, modelsEndpoint{ProviderTypeMoonshot, orDefault(p.Moonshot.BaseURL, "https://api.moonshot.cn/v1"), p.Moonshot.APIKey, nil})
+	}
+	if p.Router9.APIKey != "" || p.Router9.BaseURL != "" {
+		out = append(out, modelsEndpoint{ProviderTypeRouter9, orDefault(p.Router9.BaseURL, "http://localhost:20128/v1"), orDefault(p.Router9.APIKey, "sk_9router"), nil})
+	}
+	return out
+}
+
+func orDefault(v, def string) string {
+	if strings.TrimSpace(v) == "" {
+		return def
+	}
+	return v
+}
+
+// FetchModelCatalog 查询已配置提供商的 /models 端点，返回模型及上下文窗口（响应含 context_length / context_window 时）；
+// 结果按端点缓存 ModelCatalogTTL。单个提供商失败不影响其他提供商，失败原因按提供商名返回
+func FetchModelCatalog(ctx context.Context, cfg *config.Config) ([]ModelInfo, map[string]string) {
+	if cfg == nil {
+		return nil, nil
+	}
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		models []ModelInfo
+		errs   map[string]string
+	)
+	for _, ep := range modelsEndpoints(cfg) {
+		wg.Add(1)
+		go func(ep modelsEndpoint) {
+			defer wg.Done()
+			list, err := cachedEndpointModels(ctx, ep)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if errs == nil {
+					errs = make(map[string]string)
+				}
+				errs[string(ep.provider)] = err.Error()
+				return
+			}
+			models = append(models, list...)
+		}(ep)
+	}
+	wg.Wait()
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Provider != models[j].Provider {
+			return models[i].Provider < models[j].Provider
+		}
+		return models[i].ID < models[j].ID
+	})
+	return models, errs
+}
+
+// CatalogHasModel 已缓存的提供商模型目录（models.list refresh 获取过）中是否有该模型；只读缓存，不发起请求
+func CatalogHasModel(model string) bool {
+	model = strings.TrimSpace(model)
+	if _, rest, ok := strings.Cut(model, ":"); ok {
+		model = rest
+	}
+	modelCatalogMu.Lock()
+	defer modelCatalogMu.Unlock()
+	for _, entry := range modelCatalogCache {
+		for _, m := range entry.models {
+			if m.ID == model {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func cachedEndpointModels(ctx context.Context, ep modelsEndpoint) ([]ModelInfo, error) {
+	key := string(ep.provider) + "|" + ep.baseURL + "|" + ep.apiKey
+	modelCatalogMu.Lock()
+	entry, ok := modelCatalogCache[key]
+	modelCatalogMu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < ModelCatalogTTL {
+		return entry.models, nil
+	}
+	models, err := fetchEndpointModels(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+	modelCatalogMu.Lock()
+	modelCatalogCache[key] = modelCatalogEntry{models: models, fetchedAt: time.Now()}
+	modelCatalogMu.Unlock()
+	return models, nil
+}
+
+// fetchEndpointModels GET {baseURL}/models，解析 OpenAI 兼容的 {data: [{id, context_length | context_window}]}
+func fetchEndpointModels(ctx context.Context, ep modelsEndpoint) ([]ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, modelCatalogTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(ep.baseURL, "/")+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+ep.apiKey)
+	for k, v := range ep.headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /models: %s: %s", resp.Status, strings.TrimSpace(string(body[:min(len(body), 300)])))
+	}
+	var payload struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			ContextWindow int    `json:"context_window"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decode /models response: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(payload.Data))
+	for _, m := range payload.Data {
+		if m.ID == "" {
+			continue
+		}
+		models = append(models, ModelInfo{
+			ID:            m.ID,
+			Provider:      string(ep.provider),
+			ContextWindow: max(m.ContextLength, m.ContextWindow),
+		})
+	}
+	return models, nil
+}
+
+// RoutableModelID 返回可直接写入 agents.*.model 的模型 ID：按名称已能路由到该提供商时原样返回（如 gpt-4o、claude-*），
+// 否则加上提供商前缀（如 openrouter:openai/gpt-4o、moonshot:moonshot-v1-8k）
+func RoutableModelID(provider, id string) string {
+	if provider == "" {
+		return id
+	}
+	if providerType, name, ok := providerTypeFromModel(id); ok && string(providerType) == provider && name == id {
+		return id
+	}
+	return provider + ":" + id
+}
+
+// MergeModelCatalog 合并配置中引用的模型与提供商返回的模型：配置中的模型在前并保留原写法（如 openrouter:xxx），
+// 能在目录中找到时补上上下文窗口；目录中其余模型按原顺序追加，ID 经 RoutableModelID 带上提供商前缀
+func MergeModelCatalog(configured []string, catalog []ModelInfo) []ModelInfo {
+	out := make([]ModelInfo, 0, len(configured)+len(catalog))
+	used := make([]bool, len(catalog))
+	for _, id := range configured {
+		providerType, name, _ := providerTypeFromModel(id)
+		info := ModelInfo{ID: id, Provider: string(providerType)}
+		for i, m := range catalog {
+			if m.ID != name || (providerType != "" && m.Provider != string(providerType)) {
+				continue
+			}
+			if info.Provider == "" {
+				info.Provider = m.Provider
+			}
+			info.ContextWindow = m.ContextWindow
+			used[i] = true
+		}
+		out = append(out, info)
+	}
+	for i, m := range catalog {
+		if !used[i] {
+			m.ID = RoutableModelID(m.Provider, m.ID)
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestFetchModelCatalogCachesAndMerges(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/api/v1/models" || r.Header.Get("Authorization") != "Bearer sk-or" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"anthropic/claude-sonnet-4","context_length":200000},{"id":"openai/gpt-4o","context_length":128000}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Providers.OpenRouter.APIKey = "sk-or"
+	cfg.Providers.OpenRouter.BaseURL = srv.URL + "/api/v1"
+
+	for range 2 {
+		catalog, errs := FetchModelCatalog(context.Background(), cfg)
+		if len(errs) != 0 || len(catalog) != 2 {
+			t.Fatalf("catalog = %+v, errs = %v", catalog, errs)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("/models requested %d times, want 1 (cached)", calls.Load())
+	}
+
+	catalog, _ := FetchModelCatalog(context.Background(), cfg)
+	merged := MergeModelCatalog([]string{"openrouter:anthropic/claude-sonnet-4", "claude-3-5-haiku"}, catalog)
+	want := []ModelInfo{
+		{ID: "openrouter:anthropic/claude-sonnet-4", Provider: "openrouter", ContextWindow: 200000},
+		{ID: "claude-3-5-haiku", Provider: "anthropic"},
+		{ID: "openrouter:openai/gpt-4o", Provider: "openrouter", ContextWindow: 128000},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("merged = %+v", merged)
+	}
+	for i := range want {
+		if merged[i] != want[i] {
+			t.Errorf("merged[%d] = %+v, want %+v", i, merged[i], want[i])
+		}
+	}
+
+	// 目录中追加的模型 ID 须能按前缀路由回返回它的提供商
+	for _, m := range merged {
+		if providerType, _, ok := providerTypeFromModel(m.ID); !ok || string(providerType) != m.Provider {
+			t.Errorf("model %q routes to %q, want %q", m.ID, providerType, m.Provider)
+		}
+	}
+
+	cfg.Providers.OpenRouter.APIKey = "sk-bad"
+	if _, errs := FetchModelCatalog(context.Background(), cfg); errs["openrouter"] == "" {
+		t.Fatal("expected an error for the rejected key")
+	}
+}
+
+func TestRoutableModelID(t *testing.T) {
+	tests := []struct {
+		provider, id, want string
+	}{
+		{"openai", "gpt-4o", "gpt-4o"},
+		{"openai", "o3-mini", "openai:o3-mini"},
+		{"openrouter", "openai/gpt-4o", "openrouter:openai/gpt-4o"},
+		{"openrouter", "gpt-4o", "openrouter:gpt-4o"},
+		{"moonshot", "moonshot-v1-8k", "moonshot:moonshot-v1-8k"},
+		{"moonshot", "kimi-k2", "kimi-k2"},
+		{"9router", "claude-sonnet-4", "9router:claude-sonnet-4"},
+		{"", "custom-model", "custom-model"},
+	}
+	for _, tt := range tests {
+		got := RoutableModelID(tt.provider, tt.id)
+		if got != tt.want {
+			t.Errorf("RoutableModelID(%q, %q) = %q, want %q", tt.provider, tt.id, got, tt.want)
+			continue
+		}
+		if tt.provider == "" {
+			continue
+		}
+		providerType, name, ok := providerTypeFromModel(got)
+		if !ok || string(providerType) != tt.provider || name != tt.id {
+			t.Errorf("%q routes to (%q, %q), want (%q, %q)", got, providerType, name, tt.provider, tt.id)
+		}
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/shared"
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/redact"
 	"go.uber.org/zap"
 )
 
@@ -20,9 +22,10 @@ type OpenAIProvider struct {
 	baseURL           string
 	maxTokens         int
 	extraBody         map[string]interface{}
-	streamingEnabled  bool // 是否启用流式输出
-	router9Compatible bool // 9router 兼容模式
-	skipTools         bool // 9router 下为 true 时不传 tools，用于排查 406
+	streamingEnabled  bool        // 是否启用流式输出
+	router9Compatible bool        // 9router 兼容模式
+	skipTools         bool        // 9router 下为 true 时不传 tools，用于排查 406
+	headers           http.Header // 附加到每个请求的自定义请求头
 }
 
 // NewOpenAIProvider creates an OpenAI provider.
@@ -76,6 +79,26 @@ func NewOpenAIProviderWithStreaming(apiKey, baseURL, model string, maxTokens int
 	}, nil
 }
 
+// WithHeaders 设置附加到每个请求的自定义请求头（如 OpenRouter 归因头、企业代理鉴权头）
+func (p *OpenAIProvider) WithHeaders(headers map[string]string) *OpenAIProvider {
+	p.headers = mergeHeaders(headers)
+	if len(p.headers) > 0 {
+		logger.Debug("Provider custom request headers",
+			zap.String("base_url", p.baseURL),
+			zap.Any("headers", redact.Headers(p.headers)))
+	}
+	return p
+}
+
+// headerOptions 将自定义请求头转换为请求选项
+func (p *OpenAIProvider) headerOptions() []option.RequestOption {
+	opts := make([]option.RequestOption, 0, len(p.headers))
+	for name := range p.headers {
+		opts = append(opts, option.WithHeader(name, p.headers.Get(name)))
+	}
+	return opts
+}
+
 // SupportsStreaming returns whether streaming is enabled for this provider.
 func (p *OpenAIProvider) SupportsStreaming() bool {
 	return p.streamingEnabled
@@ -133,7 +156,9 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []T
 			zap.Int("max_tokens_value", opts.MaxTokens))
 	} else {
 		reqOpts = append(p.extraBodyOptions(), assistantReasoningOptions(messages)...)
+		reqOpts = append(reqOpts, reasoningEffortOptions(opts)...)
 	}
+	reqOpts = append(reqOpts, p.headerOptions()...)
 
 	completion, err := p.client.Chat.Completions.New(ctx, req, reqOpts...)
 	if err != nil {
@@ -398,6 +423,15 @@ func copyExtraBody(src map[string]interface{}) map[string]interface{} {
 	return dst
 }
 
+// reasoningEffortOptions 按思考等级设置 reasoning_effort，覆盖 extra_body 中的同名字段
+func reasoningEffortOptions(opts *ChatOptions) []option.RequestOption {
+	effort := openAIReasoningEffort(opts.Thinking)
+	if effort == "" {
+		return nil
+	}
+	return []option.RequestOption{option.WithJSONSet("reasoning_effort", effort)}
+}
+
 func (p *OpenAIProvider) extraBodyOptions() []option.RequestOption {
 	if len(p.extraBody) == 0 {
 		return nil
@@ -482,6 +516,13 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 		}
 	} else {
 		reqOpts = append(p.extraBodyOptions(), assistantReasoningOptions(messages)...)
+		reqOpts = append(reqOpts, reasoningEffortOptions(opts)...)
+	}
+	reqOpts = append(reqOpts, p.headerOptions()...)
+	// 请求在流末尾附带用量（choices 为空的最后一个 chunk）；9router 兼容模式不发送额外参数
+	includeUsage := !p.router9Compatible
+	if includeUsage {
+		req.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
 	}
 
 	stream := p.client.Chat.Completions.NewStreaming(ctx, req, reqOpts...)
@@ -489,9 +530,17 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 	// 累积工具调用
 	toolCallsMap := make(map[int]*ToolCall)
 	var content strings.Builder
+	var usage *Usage
 
 	for stream.Next() {
 		chunk := stream.Current()
+		if u := chunk.Usage; u.TotalTokens > 0 || u.PromptTokens > 0 || u.CompletionTokens > 0 {
+			usage = &Usage{
+				PromptTokens:     int(u.PromptTokens),
+				CompletionTokens: int(u.CompletionTokens),
+				TotalTokens:      int(u.TotalTokens),
+			}
+		}
 		if len(chunk.Choices) == 0 {
 			continue
 		}
@@ -527,8 +576,8 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 			}
 		}
 
-		// 检查是否完成
-		if chunk.Choices[0].FinishReason != "" {
+		// 检查是否完成；请求了用量时用量在之后的 chunk 中，继续读到流结束
+		if chunk.Choices[0].FinishReason != "" && !includeUsage {
 			break
 		}
 	}
@@ -561,6 +610,7 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, too
 		Content:   content.String(),
 		Done:      true,
 		ToolCalls: toolCalls,
+		Usage:     usage,
 	})
 
 	return nil
@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIChatStreamReportsUsage(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"he"}}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"llo"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenAIProvider("sk-test", srv.URL, "gpt-4o", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var final StreamChunk
+	err = p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, func(c StreamChunk) {
+		if c.Done {
+			final = c
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, _ := body["stream_options"].(map[string]interface{})
+	if opts["include_usage"] != true {
+		t.Errorf("stream_options = %v, want include_usage", body["stream_options"])
+	}
+	if final.Content != "hello" {
+		t.Errorf("final content = %q", final.Content)
+	}
+	if final.Usage == nil || *final.Usage != (Usage{PromptTokens: 7, CompletionTokens: 2, TotalTokens: 9}) {
+		t.Fatalf("final usage = %+v, want 7/2/9", final.Usage)
+	}
+}
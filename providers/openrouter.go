@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/smallnest/goclaw/internal/logger"
+	"github.com/smallnest/goclaw/internal/redact"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
 	"go.uber.org/zap"
@@ -26,6 +27,11 @@ func NewOpenRouterProvider(apiKey, baseURL, model string, maxTokens int) (*OpenR
 
 // NewOpenRouterProviderWithStreaming 创建 OpenRouter 提供商并指定是否启用流式（预留，当前未实现流式）
 func NewOpenRouterProviderWithStreaming(apiKey, baseURL, model string, maxTokens int, streaming bool) (*OpenRouterProvider, error) {
+	return NewOpenRouterProviderWithHeaders(apiKey, baseURL, model, maxTokens, streaming, nil)
+}
+
+// NewOpenRouterProviderWithHeaders 创建 OpenRouter 提供商；headers 合并到默认的 HTTP-Referer/X-Title 之上并附加到每个请求
+func NewOpenRouterProviderWithHeaders(apiKey, baseURL, model string, maxTokens int, streaming bool, headers map[string]string) (*OpenRouterProvider, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
 	}
@@ -38,11 +44,17 @@ func NewOpenRouterProviderWithStreaming(apiKey, baseURL, model string, maxTokens
 		baseURL = "https://openrouter.ai/api/v1"
 	}
 
-	llm, err := openai.New(
+	llmOpts := []openai.Option{
 		openai.WithToken(apiKey),
 		openai.WithModel(model),
 		openai.WithBaseURL(baseURL),
-	)
+	}
+	merged := mergeHeaders(DefaultOpenRouterHeaders(), headers)
+	if client := newHeaderHTTPClient(merged); client != nil {
+		llmOpts = append(llmOpts, openai.WithHTTPClient(client))
+		logger.Debug("OpenRouter request headers", zap.Any("headers", redact.Headers(merged)))
+	}
+	llm, err := openai.New(llmOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/smallnest/goclaw/types"
+)
+
+// DefaultPreflightTimeout 启动预检的默认超时
+const DefaultPreflightTimeout = 20 * time.Second
+
+// 预检状态
+const (
+	PreflightStatusOK    = "ok"
+	PreflightStatusError = "error"
+)
+
+// PreflightResult 启动预检结果（providers.preflight），供日志与 /ready 展示
+type PreflightResult struct {
+	Status    string `json:"status"`
+	Model     string `json:"model,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+	Reason    string `json:"reason,omitempty"` // auth / model_not_found / network_error 等
+	Error     string `json:"error,omitempty"`
+	CheckedAt int64  `json:"checkedAt"`
+}
+
+// OK 预检是否成功
+func (r PreflightResult) OK() bool {
+	return r.Status == PreflightStatusOK
+}
+
+// Preflight 以默认模型发起一次极小的调用，提前暴露密钥错误、模型不存在、网络不可达等配置问题；
+// 同时预热连接（DNS / TLS）
+func Preflight(ctx context.Context, p Provider, model string, timeout time.Duration) PreflightResult {
+	if timeout <= 0 {
+		timeout = DefaultPreflightTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.Chat(ctx, []Message{{Role: "user", Content: "ping"}}, nil, WithMaxTokens(8))
+	result := PreflightResult{
+		Status:    PreflightStatusOK,
+		Model:     model,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: time.Now().UnixMilli(),
+	}
+	if err != nil {
+		reason := types.NewSimpleErrorClassifier().ClassifyError(err)
+		result.Status = PreflightStatusError
+		result.Reason = string(reason)
+		result.Error = fmt.Sprintf("%s: %v", preflightReasonText(reason), err)
+	}
+	return result
+}
+
+// preflightReasonText 预检失败原因的简短说明
+func preflightReasonText(reason types.FailoverReason) string {
+	switch reason {
+	case types.FailoverReasonAuth:
+		return "invalid API key"
+	case types.FailoverReasonModelNotFound:
+		return "unknown model"
+	case types.FailoverReasonNetworkError, types.FailoverReasonTimeout:
+		return "provider unreachable"
+	case types.FailoverReasonBilling:
+		return "billing problem"
+	case types.FailoverReasonRateLimit:
+		return "rate limited"
+	default:
+		return "provider call failed"
+	}
+}
@@ -0,0 +1,30 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPreflightReportsClassifiedErrors(t *testing.T) {
+	ok := Preflight(context.Background(), &mockProvider{response: &Response{Content: "pong"}}, "gpt-4o", 0)
+	if !ok.OK() || ok.Model != "gpt-4o" || ok.Error != "" {
+		t.Fatalf("unexpected result: %+v", ok)
+	}
+
+	tests := []struct {
+		err    error
+		reason string
+		text   string
+	}{
+		{errors.New("401 Unauthorized: invalid api key"), "auth", "invalid API key"},
+		{errors.New("dial tcp: lookup api.example.com: no such host"), "network_error", "provider unreachable"},
+	}
+	for _, tt := range tests {
+		res := Preflight(context.Background(), &mockProvider{shouldFail: true, failError: tt.err}, "gpt-4o", 0)
+		if res.OK() || res.Reason != tt.reason || !strings.HasPrefix(res.Error, tt.text) {
+			t.Errorf("Preflight(%v) = %+v, want reason %q", tt.err, res, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package providers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/smallnest/goclaw/config"
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// pricingJSON 内置模型价格表（美元 / 1K tokens），可通过 providers.pricing 覆盖或补充
+//
+//go:embed pricing.json
+var pricingJSON []byte
+
+var (
+	defaultPricingOnce sync.Once
+	defaultPricing     PricingTable
+)
+
+// PricingTable 模型 id（小写）-> 单价
+type PricingTable map[string]config.ModelPricing
+
+// NewPricingTable 返回内置价格表叠加配置覆盖后的副本
+func NewPricingTable(overrides map[string]config.ModelPricing) PricingTable {
+	defaultPricingOnce.Do(func() {
+		if err := json.Unmarshal(pricingJSON, &defaultPricing); err != nil {
+			logger.Error("Failed to parse built-in pricing table", zap.Error(err))
+		}
+	})
+	table := make(PricingTable, len(defaultPricing)+len(overrides))
+	for model, price := range defaultPricing {
+		table[strings.ToLower(model)] = price
+	}
+	for model, price := range overrides {
+		table[strings.ToLower(model)] = price
+	}
+	return table
+}
+
+// Lookup 查找模型单价，返回命中的价格表条目名。依次尝试完整模型名、去掉路由前缀（openrouter: 等）与厂商前缀（anthropic/ 等）后的名称；
+// 名称须与条目完全相同或仅多出日期后缀（如 claude-3-opus-20240229 命中 claude-3-opus），
+// 不做任意前缀匹配，避免 o1-mini 之类的不同模型按 o1 计价
+func (t PricingTable) Lookup(model string) (config.ModelPricing, string, bool) {
+	candidates := pricingCandidates(model)
+	for _, name := range candidates {
+		if price, ok := t[name]; ok {
+			return price, name, true
+		}
+	}
+	for _, name := range candidates {
+		for key, price := range t {
+			if isDatedVariant(name, key) {
+				return price, key, true
+			}
+		}
+	}
+	return config.ModelPricing{}, "", false
+}
+
+// HasModel 判断价格表是否收录该模型（匹配规则同 Lookup）；gpt-4o-evil 之类的任意扩展不算收录
+func (t PricingTable) HasModel(model string) bool {
+	_, _, ok := t.Lookup(model)
+	return ok
+}
+
+// pricingCandidates 价格表查找的候选名：完整模型名、去掉路由前缀（openrouter: 等）与厂商前缀（anthropic/ 等）后的名称
+func pricingCandidates(model string) []string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return nil
+	}
+	candidates := []string{model}
+	if _, rest, ok := strings.Cut(model, ":"); ok {
+		candidates = append(candidates, rest)
+	}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		candidates = append(candidates, model[i+1:])
+	}
+	return candidates
+}
+
+// modelDateSuffix 模型快照的日期后缀：20240229、2024-08-06、0613（旧 OpenAI 快照）或别名 latest
+var modelDateSuffix = regexp.MustCompile(`^(\d{8}|\d{4}-\d{2}-\d{2}|\d{4}|latest)$`)
+
+// isDatedVariant 判断 name 是否为 key 加 "-" 或 "@"（Vertex）分隔的日期后缀
+func isDatedVariant(name, key string) bool {
+	rest, ok := strings.CutPrefix(name, key)
+	if !ok || rest == "" || (rest[0] != '-' && rest[0] != '@') {
+		return false
+	}
+	return modelDateSuffix.MatchString(rest[1:])
+}
+
+// TokenCost 按单价计算费用（美元）
+func TokenCost(price config.ModelPricing, inputTokens, outputTokens int64) float64 {
+	return float64(inputTokens)/1000*price.Input + float64(outputTokens)/1000*price.Output
+}
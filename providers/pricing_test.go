@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestPricingTableLookup(t *testing.T) {
+	table := NewPricingTable(map[string]config.ModelPricing{
+		"My-Model": {Input: 1, Output: 2},
+		"gpt-4o":   {Input: 0.5, Output: 0.5},
+	})
+
+	tests := []struct {
+		model string
+		want  string
+		ok    bool
+	}{
+		{"gpt-4o", "gpt-4o", true},
+		{"gpt-4o-mini", "gpt-4o-mini", true},
+		{"claude-3-opus-20240229", "claude-3-opus", true},
+		{"gpt-4o-2024-08-06", "gpt-4o", true},
+		{"o1-mini", "o1-mini", true},
+		{"o1-mini-2024-09-12", "o1-mini", true},
+		{"claude-opus-4-1-20250805", "claude-opus-4-1", true},
+		{"gpt-4o-audio-preview", "", false},
+		{"openrouter:anthropic/claude-opus-4-5", "claude-opus-4-5", true},
+		{"my-model", "my-model", true},
+		{"totally-unknown", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		_, name, ok := table.Lookup(tt.model)
+		if ok != tt.ok || name != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.model, name, ok, tt.want, tt.ok)
+		}
+	}
+
+	if price, _, _ := table.Lookup("gpt-4o"); price.Input != 0.5 {
+		t.Errorf("override not applied: %+v", price)
+	}
+	if price, _, _ := NewPricingTable(nil).Lookup("gpt-4o"); price.Input == 0.5 {
+		t.Errorf("override leaked into default table: %+v", price)
+	}
+}
+
+func TestTokenCost(t *testing.T) {
+	got := TokenCost(config.ModelPricing{Input: 0.003, Output: 0.015}, 2000, 1000)
+	if math.Abs(got-0.021) > 1e-9 {
+		t.Errorf("TokenCost() = %v, want 0.021", got)
+	}
+}
+
+func TestPricingTableHasModel(t *testing.T) {
+	table := NewPricingTable(nil)
+	for model, want := range map[string]bool{
+		"gpt-4o":                             true,
+		"GPT-4o-2024-08-06":                  true,
+		"claude-3-opus-20240229":             true,
+		"claude-3-opus@20240229":             true,
+		"claude-3-5-sonnet-latest":           true,
+		"openrouter:anthropic/claude-opus-4": true,
+		"gpt-4o-evil":                        false,
+		"o1-anything":                        false,
+		"claude-3-opus-x-20240229":           false,
+		"":                                   false,
+	} {
+		if got := table.HasModel(model); got != want {
+			t.Errorf("HasModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// ProbeRequest providers.test 的参数；APIKey / BaseURL 为空时使用配置中该提供商的值
+type ProbeRequest struct {
+	Provider string
+	APIKey   string
+	BaseURL  string
+	Model    string
+}
+
+// ProbeProvider 用给定的凭据创建提供商（与正式运行相同的构造、鉴权与请求头逻辑）并发起一次极小调用，
+// 用于保存配置前校验 API key / base URL；不修改 cfg。参数无效（未知提供商、缺少模型）时返回错误
+func ProbeProvider(ctx context.Context, cfg *config.Config, req ProbeRequest, timeout time.Duration) (PreflightResult, error) {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	providerType := ProviderType(strings.ToLower(strings.TrimSpace(req.Provider)))
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		// 未指定模型时使用默认模型（须属于同一提供商）
+		if defType, defModel, ok := providerTypeFromModel(cfg.Agents.Defaults.Model); ok && (providerType == "" || defType == providerType) {
+			providerType, model = defType, defModel
+		}
+	} else if inferred, name, ok := providerTypeFromModel(model); ok && (providerType == "" || inferred == providerType) {
+		providerType, model = inferred, name
+	}
+	if providerType == "" {
+		return PreflightResult{}, fmt.Errorf("provider is required (cannot infer it from model %q)", req.Model)
+	}
+	if model == "" {
+		return PreflightResult{}, fmt.Errorf("model is required for provider %s", providerType)
+	}
+
+	probeCfg := *cfg
+	apiKey, baseURL := strings.TrimSpace(req.APIKey), strings.TrimSpace(req.BaseURL)
+	override := func(key, url *string) error {
+		// 已保存的 key 只发往已配置的 base URL，否则调用方可借自定义 baseURL 取走 key
+		if apiKey == "" && baseURL != "" && !sameBaseURL(baseURL, *url) {
+			return fmt.Errorf("apiKey is required when baseURL differs from the configured base URL")
+		}
+		if apiKey != "" {
+			*key = apiKey
+		}
+		if baseURL != "" {
+			*url = baseURL
+		}
+		return nil
+	}
+	var err error
+	switch providerType {
+	case ProviderTypeOpenAI:
+		err = override(&probeCfg.Providers.OpenAI.APIKey, &probeCfg.Providers.OpenAI.BaseURL)
+	case ProviderTypeAnthropic:
+		err = override(&probeCfg.Providers.Anthropic.APIKey, &probeCfg.Providers.Anthropic.BaseURL)
+	case ProviderTypeOpenRouter:
+		err = override(&probeCfg.Providers.OpenRouter.APIKey, &probeCfg.Providers.OpenRouter.BaseURL)
+	case ProviderTypeMoonshot:
+		err = override(&probeCfg.Providers.Moonshot.APIKey, &probeCfg.Providers.Moonshot.BaseURL)
+	case ProviderTypeRouter9:
+		err = override(&probeCfg.Providers.Router9.APIKey, &probeCfg.Providers.Router9.BaseURL)
+	case ProviderTypeGemini:
+		err = override(&probeCfg.Providers.Gemini.APIKey, &probeCfg.Providers.Gemini.BaseURL)
+	default:
+		return PreflightResult{}, fmt.Errorf("unsupported provider type: %s", providerType)
+	}
+	if err != nil {
+		return PreflightResult{}, err
+	}
+
+	p, err := newSimpleProviderByType(&probeCfg, providerType, model)
+	if err != nil {
+		return PreflightResult{
+			Status:    PreflightStatusError,
+			Model:     model,
+			Error:     err.Error(),
+			CheckedAt: time.Now().UnixMilli(),
+		}, nil
+	}
+	defer p.Close()
+	return Preflight(ctx, p, model, timeout), nil
+}
+
+// sameBaseURL 两个 base URL 是否相同（忽略大小写与末尾 /）
+func sameBaseURL(a, b string) bool {
+	return strings.EqualFold(strings.TrimRight(strings.TrimSpace(a), "/"), strings.TrimRight(strings.TrimSpace(b), "/"))
+}
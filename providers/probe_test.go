@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestProbeProviderUsesOverridesWithoutTouchingConfig(t *testing.T) {
+	var gotAuth atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Providers.OpenAI.APIKey = "sk-saved"
+	cfg.Providers.OpenAI.BaseURL = "https://api.openai.com/v1"
+
+	res, err := ProbeProvider(context.Background(), cfg, ProbeRequest{
+		Provider: "openai",
+		APIKey:   "sk-candidate",
+		BaseURL:  srv.URL,
+		Model:    "gpt-4o-mini",
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OK() || res.Reason != "auth" {
+		t.Fatalf("probe result = %+v, want auth failure", res)
+	}
+	if auth, _ := gotAuth.Load().(string); auth != "Bearer sk-candidate" {
+		t.Fatalf("Authorization = %q, want candidate key", auth)
+	}
+	if cfg.Providers.OpenAI.APIKey != "sk-saved" || cfg.Providers.OpenAI.BaseURL != "https://api.openai.com/v1" {
+		t.Fatalf("config modified: %+v", cfg.Providers.OpenAI)
+	}
+
+	if _, err := ProbeProvider(context.Background(), cfg, ProbeRequest{Provider: "openai", BaseURL: srv.URL, Model: "gpt-4o-mini"}, time.Second); err == nil {
+		t.Fatal("saved key must not be sent to a different base URL")
+	}
+	if _, err := ProbeProvider(context.Background(), cfg, ProbeRequest{Provider: "acme", Model: "x"}, time.Second); err == nil {
+		t.Fatal("unknown provider should be rejected")
+	}
+	if _, err := ProbeProvider(context.Background(), cfg, ProbeRequest{Provider: "openai"}, time.Second); err == nil {
+		t.Fatal("missing model should be rejected")
+	}
+}
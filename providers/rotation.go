@@ -7,7 +7,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/smallnest/goclaw/internal/logger"
 	"github.com/smallnest/goclaw/types"
+	"go.uber.org/zap"
 )
 
 // RotationStrategy 轮换策略
@@ -30,6 +32,7 @@ type ProviderProfile struct {
 	Priority      int
 	CooldownUntil time.Time
 	RequestCount  int64
+	breaker       *CircuitBreaker // 连续失败达到阈值后跳过该配置，超时后再试；nil 表示未启用
 	mu            sync.Mutex
 }
 
@@ -40,7 +43,10 @@ type RotationProvider struct {
 	currentIndex    int
 	errorClassifier types.ErrorClassifier
 	defaultCooldown time.Duration
-	mu              sync.RWMutex
+	// 每个配置独立的断路器参数（failover.circuit_breaker），threshold<=0 表示不启用
+	circuitThreshold int
+	circuitTimeout   time.Duration
+	mu               sync.RWMutex
 }
 
 // NewRotationProvider 创建轮换提供商
@@ -64,9 +70,36 @@ func (p *RotationProvider) AddProfile(name string, provider Provider, apiKey str
 		Provider: provider,
 		APIKey:   apiKey,
 		Priority: priority,
+		breaker:  p.newBreaker(),
 	}
 }
 
+// SetCircuitBreaker 为每个配置启用断路器：连续 threshold 次可故障转移的错误后跳过该配置，timeout 后再尝试；
+// threshold<=0 关闭断路器
+func (p *RotationProvider) SetCircuitBreaker(threshold int, timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.circuitThreshold = threshold
+	p.circuitTimeout = timeout
+	for _, profile := range p.profiles {
+		profile.mu.Lock()
+		profile.breaker = p.newBreaker()
+		profile.mu.Unlock()
+	}
+}
+
+// newBreaker 按当前断路器参数创建断路器，未启用时返回 nil；调用方需持有 p.mu
+func (p *RotationProvider) newBreaker() *CircuitBreaker {
+	if p.circuitThreshold <= 0 {
+		return nil
+	}
+	timeout := p.circuitTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	return NewCircuitBreaker(p.circuitThreshold, timeout)
+}
+
 // RemoveProfile 移除配置
 func (p *RotationProvider) RemoveProfile(name string) {
 	p.mu.Lock()
@@ -82,31 +115,56 @@ func (p *RotationProvider) GetProfile(name string) (*ProviderProfile, bool) {
 	return profile, ok
 }
 
-// Chat 聊天（带配置轮换）
+// Chat 聊天（带配置轮换）：按策略选择配置，遇到限流、5xx 等可故障转移的错误时冷却该配置并改用下一个，
+// 直到成功或所有可用配置都已尝试
 func (p *RotationProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
-	// 获取下一个可用的配置
-	profile := p.getNextProfile()
-	if profile == nil {
-		return nil, fmt.Errorf("no available provider profile")
-	}
+	tried := make(map[string]bool)
+	var lastErr error
+	for {
+		// 获取下一个可用的配置
+		profile := p.getNextProfile(tried)
+		if profile == nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("all provider profiles failed: %w", lastErr)
+			}
+			return nil, fmt.Errorf("no available provider profile")
+		}
+		tried[profile.Name] = true
+		logger.Debug("Using provider profile", zap.String("profile", profile.Name))
+
+		// 调用提供商
+		response, err := profile.Provider.Chat(ctx, messages, tools, options...)
+		if err == nil {
+			profile.mu.Lock()
+			profile.RequestCount++
+			breaker := profile.breaker
+			profile.mu.Unlock()
+			if breaker != nil {
+				breaker.RecordSuccess()
+			}
+			return response, nil
+		}
 
-	// 调用提供商
-	response, err := profile.Provider.Chat(ctx, messages, tools, options...)
-	if err != nil {
 		// 检查错误类型
 		reason := p.errorClassifier.ClassifyError(err)
 		if p.shouldSetCooldown(reason) {
 			p.setCooldown(profile.Name)
 		}
-		return nil, err
+		if !p.shouldFailover(reason) || ctx.Err() != nil {
+			return nil, err
+		}
+		profile.mu.Lock()
+		breaker := profile.breaker
+		profile.mu.Unlock()
+		if breaker != nil {
+			breaker.RecordFailure()
+		}
+		logger.Warn("Provider profile failed, trying next profile",
+			zap.String("profile", profile.Name),
+			zap.String("reason", string(reason)),
+			zap.Error(err))
+		lastErr = err
 	}
-
-	// 增加请求计数
-	profile.mu.Lock()
-	profile.RequestCount++
-	profile.mu.Unlock()
-
-	return response, nil
 }
 
 // ChatWithTools 聊天（带工具，支持配置轮换）
@@ -114,18 +172,22 @@ func (p *RotationProvider) ChatWithTools(ctx context.Context, messages []Message
 	return p.Chat(ctx, messages, tools, options...)
 }
 
-// getNextProfile 获取下一个可用的配置
-func (p *RotationProvider) getNextProfile() *ProviderProfile {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+// getNextProfile 获取下一个可用的配置，跳过 exclude 中本次调用已尝试过的配置
+func (p *RotationProvider) getNextProfile(exclude map[string]bool) *ProviderProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
 	now := time.Now()
 	available := make([]*ProviderProfile, 0, len(p.profiles))
 
-	// 筛选可用的配置（不在冷却期）
+	// 筛选可用的配置（不在冷却期、断路器未打开）
 	for _, profile := range p.profiles {
+		if exclude[profile.Name] {
+			continue
+		}
 		profile.mu.Lock()
-		if profile.CooldownUntil.IsZero() || now.After(profile.CooldownUntil) {
+		if (profile.CooldownUntil.IsZero() || now.After(profile.CooldownUntil)) &&
+			(profile.breaker == nil || profile.breaker.AllowRequest()) {
 			available = append(available, profile)
 		}
 		profile.mu.Unlock()
@@ -219,7 +281,19 @@ func (p *RotationProvider) setCooldown(profileName string) {
 // shouldSetCooldown 判断是否应该设置冷却
 func (p *RotationProvider) shouldSetCooldown(reason types.FailoverReason) bool {
 	switch reason {
-	case types.FailoverReasonAuth, types.FailoverReasonRateLimit, types.FailoverReasonBilling:
+	case types.FailoverReasonAuth, types.FailoverReasonRateLimit, types.FailoverReasonBilling, types.FailoverReasonServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+// shouldFailover 判断是否应改用下一个配置重试：与具体配置（密钥、额度、后端）相关的错误；
+// 上下文溢出、参数错误等换配置也无济于事，直接返回
+func (p *RotationProvider) shouldFailover(reason types.FailoverReason) bool {
+	switch reason {
+	case types.FailoverReasonAuth, types.FailoverReasonRateLimit, types.FailoverReasonBilling,
+		types.FailoverReasonServerError, types.FailoverReasonNetworkError, types.FailoverReasonTimeout:
 		return true
 	default:
 		return false
@@ -259,7 +333,7 @@ func (p *RotationProvider) Close() error {
 
 // SupportsStreaming returns whether the current profile supports streaming.
 func (p *RotationProvider) SupportsStreaming() bool {
-	profile := p.getNextProfile()
+	profile := p.getNextProfile(nil)
 	if profile == nil {
 		return false
 	}
@@ -294,11 +368,15 @@ func (p *RotationProvider) GetProfileStatus(name string) (map[string]interface{}
 	now := time.Now()
 	isInCooldown := !profile.CooldownUntil.IsZero() && now.Before(profile.CooldownUntil)
 
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"name":           profile.Name,
 		"priority":       profile.Priority,
 		"request_count":  profile.RequestCount,
 		"in_cooldown":    isInCooldown,
 		"cooldown_until": profile.CooldownUntil,
-	}, nil
+	}
+	if profile.breaker != nil {
+		status["circuit"] = profile.breaker.GetState().String()
+	}
+	return status, nil
 }
@@ -262,3 +262,41 @@ func TestRotationProviderClose(t *testing.T) {
 		t.Fatalf("Expected no error on close, got %v", err)
 	}
 }
+
+func TestRotationProviderFailoverAndCircuitBreaker(t *testing.T) {
+	classifier := types.NewSimpleErrorClassifier()
+	rp := NewRotationProvider(RotationStrategyRoundRobin, time.Millisecond, classifier)
+	rp.SetCircuitBreaker(2, time.Hour)
+
+	failing := &mockProvider{shouldFail: true, failError: errors.New("502 bad gateway")}
+	working := &mockProvider{response: &Response{Content: "ok"}}
+	rp.AddProfile("broken", failing, "key1", 1)
+	rp.AddProfile("healthy", working, "key2", 2)
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		resp, err := rp.Chat(ctx, nil, nil)
+		if err != nil {
+			t.Fatalf("call %d: expected failover to healthy profile, got %v", i, err)
+		}
+		if resp.Content != "ok" {
+			t.Fatalf("call %d: unexpected response %q", i, resp.Content)
+		}
+		time.Sleep(2 * time.Millisecond) // 等待冷却结束，只由断路器决定是否跳过
+	}
+
+	status, err := rp.GetProfileStatus("broken")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status["circuit"] != CircuitStateOpen.String() {
+		t.Errorf("Expected broken profile circuit to be open, got %v", status["circuit"])
+	}
+
+	// 不可故障转移的错误直接返回
+	rp.RemoveProfile("healthy")
+	rp.AddProfile("bad-request", &mockProvider{shouldFail: true, failError: errors.New("invalid request format")}, "key3", 3)
+	if _, err := rp.Chat(ctx, nil, nil); err == nil {
+		t.Error("Expected error when only non-failover profiles remain")
+	}
+}
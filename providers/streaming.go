@@ -9,14 +9,16 @@ import (
 
 // StreamChunk represents a chunk of streaming response
 type StreamChunk struct {
-	Content     string     `json:"content"`
-	Done        bool       `json:"done"`
-	ToolCall    *ToolCall  `json:"tool_call,omitempty"`
-	ToolCalls   []ToolCall `json:"tool_calls,omitempty"` // 完成时的所有工具调用
-	ThinkingTag string     `json:"thinking,omitempty"`
-	IsThinking  bool       `json:"is_thinking,omitempty"`
-	IsFinal     bool       `json:"is_final,omitempty"`
-	Error       error      `json:"error,omitempty"`
+	Content          string     `json:"content"`
+	Done             bool       `json:"done"`
+	ToolCall         *ToolCall  `json:"tool_call,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`        // 完成时的所有工具调用
+	ReasoningContent string     `json:"reasoning_content,omitempty"` // 完成时累积的思考内容（如 Anthropic thinking 块）
+	ThinkingTag      string     `json:"thinking,omitempty"`
+	IsThinking       bool       `json:"is_thinking,omitempty"`
+	IsFinal          bool       `json:"is_final,omitempty"`
+	Error            error      `json:"error,omitempty"`
+	Usage            *Usage     `json:"usage,omitempty"` // 完成时 provider 上报的用量（可选，未上报时由调用方估算）
 }
 
 // StreamCallback is called for each chunk in a streaming response
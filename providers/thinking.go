@@ -0,0 +1,75 @@
+package providers
+
+import "strings"
+
+// 思考等级（会话元数据 thinkingLevel / reasoningLevel）
+const (
+	ThinkingLevelOff     = "off"
+	ThinkingLevelMinimal = "minimal"
+	ThinkingLevelLow     = "low"
+	ThinkingLevelMedium  = "medium"
+	ThinkingLevelHigh    = "high"
+	ThinkingLevelXHigh   = "xhigh"
+)
+
+// thinkingBudgets 各思考等级对应的思考 token 预算（Anthropic budget_tokens / Gemini thinkingBudget）
+var thinkingBudgets = map[string]int{
+	ThinkingLevelMinimal: 1024,
+	ThinkingLevelLow:     2048,
+	ThinkingLevelMedium:  8192,
+	ThinkingLevelHigh:    16384,
+	ThinkingLevelXHigh:   32768,
+}
+
+// NormalizeThinkingLevel 规范化思考等级：on 视为 medium，none/disabled 视为 off，max 视为 xhigh；无法识别时返回空
+func NormalizeThinkingLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case ThinkingLevelOff, "none", "disabled", "false":
+		return ThinkingLevelOff
+	case "on", "true", "enabled":
+		return ThinkingLevelMedium
+	case "max":
+		return ThinkingLevelXHigh
+	case ThinkingLevelMinimal, ThinkingLevelLow, ThinkingLevelMedium, ThinkingLevelHigh, ThinkingLevelXHigh:
+		return level
+	}
+	return ""
+}
+
+// thinkingBudget 思考等级对应的 token 预算；off 或未设置返回 0
+func thinkingBudget(level string) int {
+	return thinkingBudgets[level]
+}
+
+// openAIReasoningEffort 思考等级对应的 reasoning_effort；off 或未设置返回空（不发送，沿用模型默认）
+func openAIReasoningEffort(level string) string {
+	switch level {
+	case ThinkingLevelMinimal, ThinkingLevelLow, ThinkingLevelMedium, ThinkingLevelHigh:
+		return level
+	case ThinkingLevelXHigh:
+		return ThinkingLevelHigh
+	}
+	return ""
+}
+
+// anthropicThinkingBudget 本次请求的 Anthropic 思考预算：仅在新一轮回复开始时开启。
+// 工具循环中的后续请求（最后一条为工具结果）需回传带签名的 thinking 块，这里不保存签名，因此不开启
+func anthropicThinkingBudget(messages []Message, opts *ChatOptions) int {
+	if opts == nil {
+		return 0
+	}
+	budget := thinkingBudget(opts.Thinking)
+	if budget == 0 || (len(messages) > 0 && messages[len(messages)-1].Role == "tool") {
+		return 0
+	}
+	return budget
+}
+
+// anthropicMaxTokensForThinking max_tokens 须大于思考预算，不足时在预算之上追加默认输出额度
+func anthropicMaxTokensForThinking(maxTokens, budget int) int {
+	if maxTokens <= budget {
+		return budget + anthropicDefaultMaxTokens
+	}
+	return maxTokens
+}
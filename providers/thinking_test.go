@@ -0,0 +1,40 @@
+package providers
+
+import "testing"
+
+func TestThinkingLevelRequestOptions(t *testing.T) {
+	opts := &ChatOptions{Model: "claude-sonnet-4-5", Temperature: 0.7, MaxTokens: 4096}
+	WithThinkingLevel("High")(opts)
+	if opts.Thinking != ThinkingLevelHigh {
+		t.Fatalf("Thinking = %q", opts.Thinking)
+	}
+
+	msgs := []Message{{Role: "user", Content: "hi"}}
+	req := buildAnthropicRequest(msgs, nil, opts)
+	if req.Thinking == nil || req.Thinking.BudgetTokens != 16384 || req.Temperature != nil || req.MaxTokens <= 16384 {
+		t.Fatalf("anthropic request = %+v, thinking = %+v", req, req.Thinking)
+	}
+
+	// 工具循环中的后续请求不开启思考
+	msgs = append(msgs,
+		Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "t1", Name: "read_file"}}},
+		Message{Role: "tool", ToolCallID: "t1", Content: "ok"})
+	if req := buildAnthropicRequest(msgs, nil, opts); req.Thinking != nil || req.MaxTokens != 4096 {
+		t.Fatalf("tool continuation request = %+v", req)
+	}
+
+	gemini := buildGeminiRequest(msgs[:1], nil, &ChatOptions{Thinking: ThinkingLevelLow})
+	if gemini.GenerationConfig == nil || gemini.GenerationConfig.ThinkingConfig == nil || gemini.GenerationConfig.ThinkingConfig.ThinkingBudget != 2048 {
+		t.Fatalf("gemini generationConfig = %+v", gemini.GenerationConfig)
+	}
+
+	if got := openAIReasoningEffort(NormalizeThinkingLevel("xhigh")); got != "high" {
+		t.Fatalf("effort(xhigh) = %q", got)
+	}
+	if got := openAIReasoningEffort(NormalizeThinkingLevel("off")); got != "" {
+		t.Fatalf("effort(off) = %q", got)
+	}
+	if NormalizeThinkingLevel("bogus") != "" {
+		t.Fatal("unknown level should normalize to empty")
+	}
+}
@@ -0,0 +1,367 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+// ErrDailyTokenBudgetExceeded 当日 token 用量已达 providers.daily_token_budget
+var ErrDailyTokenBudgetExceeded = errors.New("daily token budget reached")
+
+// usagePersistDelay 记录用量后延迟写盘的时间，期间的多次调用合并为一次写入
+const usagePersistDelay = 2 * time.Second
+
+// UsageCounter 单个 provider/model 的累计用量
+type UsageCounter struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	Calls            int64  `json:"calls"`
+	EstimatedCalls   int64  `json:"estimatedCalls"` // 响应未带 usage、按字符估算的调用次数
+	PromptTokens     int64  `json:"promptTokens"`
+	CompletionTokens int64  `json:"completionTokens"`
+	TotalTokens      int64  `json:"totalTokens"`
+}
+
+// dailyUsageFile 持久化的当日用量（重启后预算仍然生效）
+type dailyUsageFile struct {
+	Date        string `json:"date"`
+	TotalTokens int64  `json:"totalTokens"`
+}
+
+// UsageTracker 统计启动以来各 provider/model 的 token 用量，并按日执行可选的软预算
+type UsageTracker struct {
+	mu           sync.Mutex
+	startedAt    time.Time
+	counters     map[string]*UsageCounter // provider/model -> counter
+	day          string
+	dailyTokens  int64
+	dailyBudget  int64
+	persistPath  string
+	dirty        bool        // 当日用量有未写盘的变化
+	persistTimer *time.Timer // 已安排的延迟写盘
+	writeMu      sync.Mutex  // 串行化写盘（定时写入与 Flush）
+	now          func() time.Time
+}
+
+// NewUsageTracker 创建用量统计
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		startedAt: time.Now(),
+		counters:  make(map[string]*UsageCounter),
+		now:       time.Now,
+	}
+}
+
+var defaultUsageTracker = NewUsageTracker()
+
+// DefaultUsageTracker 返回进程级用量统计（provider 调用路径与 status/usage.live 共用）
+func DefaultUsageTracker() *UsageTracker {
+	return defaultUsageTracker
+}
+
+// DefaultUsagePersistPath 当日用量持久化文件路径（数据目录下 data/usage_daily.json）；无法确定数据目录时返回空，即不持久化
+func DefaultUsagePersistPath() string {
+	dataDir, err := config.GetDefaultDataDir()
+	if err != nil {
+		return ""
+	}
+	return config.GetUsageDailyPath(dataDir)
+}
+
+// Configure 设置每日预算（<=0 表示不限制）与持久化路径（空表示不持久化）；若持久化文件为当日记录则恢复当日用量
+func (t *UsageTracker) Configure(dailyBudget int64, persistPath string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dailyBudget = dailyBudget
+	t.persistPath = persistPath
+	if persistPath == "" {
+		return
+	}
+	data, err := os.ReadFile(persistPath)
+	if err != nil {
+		return
+	}
+	var f dailyUsageFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return
+	}
+	t.rollDayLocked()
+	if f.Date == t.day && f.TotalTokens > t.dailyTokens {
+		t.dailyTokens = f.TotalTokens
+	}
+}
+
+// rollDayLocked 跨日时清零当日用量
+func (t *UsageTracker) rollDayLocked() {
+	today := t.now().Format("2006-01-02")
+	if t.day != today {
+		t.day = today
+		t.dailyTokens = 0
+	}
+}
+
+// Record 记录一次调用的用量；estimated 表示 usage 为估算值
+func (t *UsageTracker) Record(provider, model string, usage Usage, estimated bool) {
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+
+	t.mu.Lock()
+	key := provider + "/" + model
+	c, ok := t.counters[key]
+	if !ok {
+		c = &UsageCounter{Provider: provider, Model: model}
+		t.counters[key] = c
+	}
+	c.Calls++
+	if estimated {
+		c.EstimatedCalls++
+	}
+	c.PromptTokens += int64(usage.PromptTokens)
+	c.CompletionTokens += int64(usage.CompletionTokens)
+	c.TotalTokens += int64(total)
+
+	t.rollDayLocked()
+	t.dailyTokens += int64(total)
+	if t.persistPath != "" {
+		t.dirty = true
+		if t.persistTimer == nil {
+			t.persistTimer = time.AfterFunc(usagePersistDelay, func() { _ = t.Flush() })
+		}
+	}
+	t.mu.Unlock()
+}
+
+// Flush 立即写入未落盘的当日用量（退出前调用，避免丢失最后 usagePersistDelay 内的记录）
+func (t *UsageTracker) Flush() error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	t.mu.Lock()
+	if t.persistTimer != nil {
+		t.persistTimer.Stop()
+		t.persistTimer = nil
+	}
+	if !t.dirty || t.persistPath == "" {
+		t.mu.Unlock()
+		return nil
+	}
+	t.dirty = false
+	path := t.persistPath
+	snapshot := dailyUsageFile{Date: t.day, TotalTokens: t.dailyTokens}
+	t.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return writeUsageFile(path, data)
+}
+
+// writeUsageFile 先写临时文件再 rename，避免进程中断时留下半截文件
+func writeUsageFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// CheckBudget 当日用量达到预算时返回 ErrDailyTokenBudgetExceeded；跨日自动重置
+func (t *UsageTracker) CheckBudget() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollDayLocked()
+	if t.dailyBudget > 0 && t.dailyTokens >= t.dailyBudget {
+		return ErrDailyTokenBudgetExceeded
+	}
+	return nil
+}
+
+// Counters 返回各 provider/model 的用量副本（按 provider、model 排序）
+func (t *UsageTracker) Counters() []UsageCounter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := make([]UsageCounter, 0, len(t.counters))
+	for _, c := range t.counters {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Provider != list[j].Provider {
+			return list[i].Provider < list[j].Provider
+		}
+		return list[i].Model < list[j].Model
+	})
+	return list
+}
+
+// Snapshot 返回供 status / usage.live 使用的用量快照
+func (t *UsageTracker) Snapshot() map[string]interface{} {
+	counters := t.Counters()
+	var total int64
+	for _, c := range counters {
+		total += c.TotalTokens
+	}
+
+	t.mu.Lock()
+	t.rollDayLocked()
+	daily := map[string]interface{}{
+		"date":        t.day,
+		"totalTokens": t.dailyTokens,
+		"budget":      t.dailyBudget,
+		"exceeded":    t.dailyBudget > 0 && t.dailyTokens >= t.dailyBudget,
+	}
+	startedAt := t.startedAt.UnixMilli()
+	t.mu.Unlock()
+
+	return map[string]interface{}{
+		"since":       startedAt,
+		"totalTokens": total,
+		"providers":   counters,
+		"daily":       daily,
+	}
+}
+
+// estimateUsage 响应未带 usage 时按字符数粗略估算（约 4 字符 / token）
+func estimateUsage(messages []Message, completion string) Usage {
+	promptChars := 0
+	for _, m := range messages {
+		promptChars += len(m.Content)
+	}
+	u := Usage{
+		PromptTokens:     promptChars / 4,
+		CompletionTokens: len(completion) / 4,
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+// UsageTrackingProvider 包装 Provider，在调用路径上累计 token 用量
+type UsageTrackingProvider struct {
+	inner   Provider
+	name    string
+	model   string
+	tracker *UsageTracker
+}
+
+// WrapProviderWithUsageTracking 用用量统计包装 Provider；若 inner 实现 StreamingProvider 则返回也实现 StreamingProvider
+func WrapProviderWithUsageTracking(inner Provider, name, model string, tracker *UsageTracker) Provider {
+	if tracker == nil {
+		tracker = defaultUsageTracker
+	}
+	wrapped := &UsageTrackingProvider{inner: inner, name: name, model: model, tracker: tracker}
+	if _, ok := inner.(StreamingProvider); ok {
+		return &usageStreamingProvider{UsageTrackingProvider: wrapped}
+	}
+	return wrapped
+}
+
+func (p *UsageTrackingProvider) resolveModel(options []ChatOption) string {
+	opts := &ChatOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	if opts.Model != "" {
+		return opts.Model
+	}
+	return p.model
+}
+
+func (p *UsageTrackingProvider) record(messages []Message, options []ChatOption, resp *Response) {
+	if resp == nil {
+		return
+	}
+	usage, estimated := ResolveUsage(messages, resp.Content, &resp.Usage)
+	p.tracker.Record(p.name, p.resolveModel(options), usage, estimated)
+}
+
+// ResolveUsage 优先使用 provider 上报的用量；reported 为空或全零时按字符估算，estimated 为 true
+func ResolveUsage(messages []Message, completion string, reported *Usage) (usage Usage, estimated bool) {
+	if reported == nil || (reported.TotalTokens == 0 && reported.PromptTokens == 0 && reported.CompletionTokens == 0) {
+		return estimateUsage(messages, completion), true
+	}
+	usage = *reported
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage, false
+}
+
+// Chat 实现 Provider
+func (p *UsageTrackingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	resp, err := p.inner.Chat(ctx, messages, tools, options...)
+	if err == nil {
+		p.record(messages, options, resp)
+	}
+	return resp, err
+}
+
+// ChatWithTools 实现 Provider
+func (p *UsageTrackingProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, options ...ChatOption) (*Response, error) {
+	resp, err := p.inner.ChatWithTools(ctx, messages, tools, options...)
+	if err == nil {
+		p.record(messages, options, resp)
+	}
+	return resp, err
+}
+
+// Close 写入未落盘的当日用量后转发到内层
+func (p *UsageTrackingProvider) Close() error {
+	_ = p.tracker.Flush()
+	return p.inner.Close()
+}
+
+// SupportsStreaming 转发到内层
+func (p *UsageTrackingProvider) SupportsStreaming() bool {
+	return p.inner.SupportsStreaming()
+}
+
+// usageStreamingProvider 流式调用结束后统计用量：优先用完成 chunk 上报的 usage，否则按累计内容估算
+type usageStreamingProvider struct {
+	*UsageTrackingProvider
+}
+
+var _ StreamingProvider = (*usageStreamingProvider)(nil)
+
+// ChatStream 调用内层 ChatStream 并统计用量
+func (p *usageStreamingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, callback StreamCallback, options ...ChatOption) error {
+	var content []byte
+	var reported *Usage
+	err := p.inner.(StreamingProvider).ChatStream(ctx, messages, tools, func(chunk StreamChunk) {
+		content = append(content, chunk.Content...)
+		if chunk.Usage != nil {
+			reported = chunk.Usage
+		}
+		callback(chunk)
+	}, options...)
+	if err == nil {
+		usage, estimated := ResolveUsage(messages, string(content), reported)
+		p.tracker.Record(p.name, p.resolveModel(options), usage, estimated)
+	}
+	return err
+}
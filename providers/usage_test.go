@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageTrackingProviderRecordsUsage(t *testing.T) {
+	tracker := NewUsageTracker()
+	inner := &mockProvider{response: &Response{Content: "ok", Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}}
+	p := WrapProviderWithUsageTracking(inner, "openai", "gpt-4o", tracker)
+
+	if _, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if _, err := p.Chat(context.Background(), nil, nil, WithModel("gpt-4o-mini")); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	counters := tracker.Counters()
+	if len(counters) != 2 {
+		t.Fatalf("counters = %d, want 2", len(counters))
+	}
+	if counters[0].Model != "gpt-4o" || counters[0].TotalTokens != 15 || counters[0].EstimatedCalls != 0 {
+		t.Errorf("unexpected counter: %+v", counters[0])
+	}
+}
+
+func TestUsageTrackingProviderEstimatesMissingUsage(t *testing.T) {
+	tracker := NewUsageTracker()
+	inner := &mockProvider{response: &Response{Content: "12345678"}}
+	p := WrapProviderWithUsageTracking(inner, "anthropic", "claude", tracker)
+
+	if _, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "abcdefghabcdefgh"}}, nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	c := tracker.Counters()[0]
+	if c.EstimatedCalls != 1 || c.TotalTokens != 6 {
+		t.Errorf("estimated counter = %+v, want 1 estimated call and 6 tokens", c)
+	}
+}
+
+func TestUsageTrackerDailyBudgetBlocksAfterLimit(t *testing.T) {
+	tracker := NewUsageTracker()
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	tracker.Configure(100, "")
+
+	inner := &mockProvider{response: &Response{Content: "ok", Usage: Usage{TotalTokens: 60}}}
+	p := WrapProviderWithUsageTracking(inner, "openai", "gpt-4o", tracker)
+
+	if err := tracker.CheckBudget(); err != nil {
+		t.Fatalf("CheckBudget() before usage = %v, want nil", err)
+	}
+	_, _ = p.Chat(context.Background(), nil, nil)
+	if err := tracker.CheckBudget(); err != nil {
+		t.Fatalf("CheckBudget() under budget = %v, want nil", err)
+	}
+	_, _ = p.Chat(context.Background(), nil, nil)
+	if err := tracker.CheckBudget(); !errors.Is(err, ErrDailyTokenBudgetExceeded) {
+		t.Fatalf("CheckBudget() over budget = %v, want ErrDailyTokenBudgetExceeded", err)
+	}
+
+	// 次日自动恢复
+	now = now.Add(24 * time.Hour)
+	if err := tracker.CheckBudget(); err != nil {
+		t.Errorf("CheckBudget() next day = %v, want nil", err)
+	}
+}
+
+func TestUsageTrackerPersistsDailyUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage_daily.json")
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	first := NewUsageTracker()
+	first.now = func() time.Time { return now }
+	first.Configure(100, path)
+	first.Record("openai", "gpt-4o", Usage{TotalTokens: 120}, false)
+	if err := first.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewUsageTracker()
+	restarted.now = func() time.Time { return now }
+	restarted.Configure(100, path)
+	if err := restarted.CheckBudget(); !errors.Is(err, ErrDailyTokenBudgetExceeded) {
+		t.Errorf("CheckBudget() after restart = %v, want ErrDailyTokenBudgetExceeded", err)
+	}
+}
+
+func TestUsageTrackerBatchesPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage_daily.json")
+	tracker := NewUsageTracker()
+	tracker.Configure(0, path)
+	for i := 0; i < 10; i++ {
+		tracker.Record("openai", "gpt-4o", Usage{TotalTokens: 10}, false)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("usage written synchronously on Record (stat err = %v)", err)
+	}
+
+	if err := tracker.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f dailyUsageFile
+	if err := json.Unmarshal(data, &f); err != nil || f.TotalTokens != 100 {
+		t.Fatalf("persisted usage = %s (err %v), want 100 tokens", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("temp files left behind: %v", entries)
+	}
+}
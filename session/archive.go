@@ -0,0 +1,150 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 归档会话的元数据
+const (
+	MetadataArchived   = "archived"   // 会话已归档（true）
+	MetadataArchivedAt = "archivedAt" // 归档时间（毫秒）
+)
+
+// archiveDirName 归档会话所在的子目录（<baseDir>/archive），List 不会列出其中的会话
+const archiveDirName = "archive"
+
+var (
+	// ErrSessionExists 恢复归档时同 key 的活动会话已存在
+	ErrSessionExists = errors.New("active session already exists")
+	// ErrLabelInUse 恢复归档时其标签已被其他活动会话使用
+	ErrLabelInUse = errors.New("session label already in use")
+	// ErrSessionArchived 会话已归档，仍持有旧对象的调用方（如归档前开始的运行）不能再保存它
+	ErrSessionArchived = errors.New("session is archived")
+)
+
+// archivePath 获取归档会话文件路径
+func (m *Manager) archivePath(key string) string {
+	return filepath.Join(m.baseDir, archiveDirName, KeyToSafeFilename(key)+".jsonl")
+}
+
+// Exists 判断活动会话是否存在（内存缓存或磁盘），不会创建会话
+func (m *Manager) Exists(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.sessions[key]; ok {
+		return true
+	}
+	_, err := os.Stat(m.sessionPath(key))
+	return err == nil
+}
+
+// Archive 归档会话：在元数据中标记 archived 后将记录原样移入 archive/ 子目录，并从缓存与标签索引中移除。
+// 会话不存在时返回的错误满足 os.IsNotExist
+func (m *Manager) Archive(key string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sess, ok := m.sessions[key]
+	if !ok {
+		var err error
+		if sess, err = m.load(key); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(m.baseDir, archiveDirName), 0755); err != nil {
+		return nil, err
+	}
+
+	sess.saveMu.Lock()
+	defer sess.saveMu.Unlock()
+	sess.mu.Lock()
+	if sess.Metadata == nil {
+		sess.Metadata = make(map[string]interface{})
+	}
+	sess.Metadata[MetadataArchived] = true
+	sess.Metadata[MetadataArchivedAt] = time.Now().UnixMilli()
+	sess.mu.Unlock()
+	if err := writeSessionFile(sess, m.archivePath(key)); err != nil {
+		return nil, fmt.Errorf("write archive: %w", err)
+	}
+	if err := os.Remove(m.sessionPath(key)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	delete(m.sessions, key)
+	m.labels.update(key, "")
+	return sess, nil
+}
+
+// Unarchive 将归档会话原样恢复为活动会话（清除 archived 标记）；同 key 的活动会话已存在时返回 ErrSessionExists，
+// 标签已被其他活动会话使用时返回 ErrLabelInUse
+func (m *Manager) Unarchive(key string) (*Session, error) {
+	// 标签索引查询会读取会话列表，须在持有 m.mu 之前完成
+	if header, ok := readSessionHeader(m.archivePath(key)); ok {
+		if label := sessionLabel(header.Metadata); label != "" {
+			if owner, found := m.FindByLabel(label); found && owner != key {
+				return nil, fmt.Errorf("%w: %q is used by %s", ErrLabelInUse, label, owner)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[key]; ok {
+		return nil, ErrSessionExists
+	}
+	if _, err := os.Stat(m.sessionPath(key)); err == nil {
+		return nil, ErrSessionExists
+	}
+	sess, err := loadSessionFile(key, m.archivePath(key))
+	if err != nil {
+		return nil, err
+	}
+	delete(sess.Metadata, MetadataArchived)
+	delete(sess.Metadata, MetadataArchivedAt)
+	sess.media = m.media
+	if err := writeSessionFile(sess, m.sessionPath(key)); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(m.archivePath(key)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	m.sessions[key] = sess
+	m.labels.update(key, sessionLabel(sess.Metadata))
+	return sess, nil
+}
+
+// LoadArchived 只读加载归档会话（不加入缓存）；不存在时返回的错误满足 os.IsNotExist
+func (m *Manager) LoadArchived(key string) (*Session, error) {
+	sess, err := loadSessionFile(key, m.archivePath(key))
+	if err != nil {
+		return nil, err
+	}
+	sess.media = m.media
+	return sess, nil
+}
+
+// ListArchived 列出所有归档会话的 key
+func (m *Manager) ListArchived() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.Path(), archiveDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !strings.EqualFold(ext, ".jsonl") {
+			continue
+		}
+		keys = append(keys, SafeFilenameToKey(strings.TrimSuffix(entry.Name(), ext)))
+	}
+	return keys, nil
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestArchiveAndUnarchive(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	key := "agent:main:done"
+	sess, _ := mgr.GetOrCreate(key)
+	sess.AddMessage(Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+	sess.AddMessage(Message{Role: "assistant", Content: "hi", Timestamp: time.Now()})
+	sess.PatchMetadata(map[string]interface{}{MetadataLabel: "finished"})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, err := mgr.Archive(key); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if keys, _ := mgr.List(); len(keys) != 0 {
+		t.Fatalf("List() after archive = %v", keys)
+	}
+	if mgr.Exists(key) {
+		t.Fatal("archived session should not exist as active")
+	}
+	if _, ok := mgr.FindByLabel("finished"); ok {
+		t.Fatal("archived session still indexed by label")
+	}
+	if archived, _ := mgr.ListArchived(); len(archived) != 1 || archived[0] != key {
+		t.Fatalf("ListArchived() = %v", archived)
+	}
+	got, err := mgr.LoadArchived(key)
+	if err != nil {
+		t.Fatalf("LoadArchived() error = %v", err)
+	}
+	if len(got.Messages) != 2 || got.Metadata[MetadataArchived] != true {
+		t.Fatalf("archived session = %+v", got)
+	}
+
+	restored, err := mgr.Unarchive(key)
+	if err != nil {
+		t.Fatalf("Unarchive() error = %v", err)
+	}
+	if len(restored.Messages) != 2 || restored.Metadata[MetadataArchived] != nil || sessionLabel(restored.Metadata) != "finished" {
+		t.Fatalf("restored session = %+v", restored)
+	}
+	if k, ok := mgr.FindByLabel("finished"); !ok || k != key {
+		t.Fatalf("FindByLabel() after unarchive = %q, %v", k, ok)
+	}
+	if _, err := mgr.LoadArchived(key); !os.IsNotExist(err) {
+		t.Fatalf("archive file should be removed, err = %v", err)
+	}
+
+	// 同 key 活动会话存在时不能恢复；不存在的会话无法归档
+	if _, err := mgr.Archive(key); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if _, err := mgr.GetOrCreate(key); err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	if _, err := mgr.Unarchive(key); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("Unarchive() over active session error = %v", err)
+	}
+	if _, err := mgr.Archive("agent:main:missing"); !os.IsNotExist(err) {
+		t.Fatalf("Archive(missing) error = %v", err)
+	}
+}
+
+func TestArchiveGuardsAgainstStaleWritersAndLabelClashes(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	key := "agent:main:old"
+	sess, _ := mgr.GetOrCreate(key)
+	sess.AddMessage(Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+	sess.PatchMetadata(map[string]interface{}{MetadataLabel: "report"})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Archive(key); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	// 归档前取得会话的调用方（如运行中的 Run）保存时不能重新生成活动会话
+	sess.AddMessage(Message{Role: "assistant", Content: "late reply", Timestamp: time.Now()})
+	if err := mgr.Save(sess); !errors.Is(err, ErrSessionArchived) {
+		t.Fatalf("Save() of archived session error = %v", err)
+	}
+	if mgr.Exists(key) {
+		t.Fatal("saving an archived session recreated the active file")
+	}
+
+	// 标签已被其他活动会话占用时不能恢复
+	other, _ := mgr.GetOrCreate("agent:main:new")
+	other.PatchMetadata(map[string]interface{}{MetadataLabel: "report"})
+	if err := mgr.Save(other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Unarchive(key); !errors.Is(err, ErrLabelInUse) {
+		t.Fatalf("Unarchive() with label clash error = %v", err)
+	}
+
+	// 删除会话同时删除归档记录
+	if err := mgr.Delete(key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := mgr.LoadArchived(key); !os.IsNotExist(err) {
+		t.Fatalf("archived record left after Delete(), err = %v", err)
+	}
+}
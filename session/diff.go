@@ -0,0 +1,228 @@
+package session
+
+import "strings"
+
+// MetadataRunID 消息元数据：写入该消息的运行 runId（sessions.diff 按 run 比较时使用）
+const MetadataRunID = "runId"
+
+// diffContextLines 文本差异每个 hunk 前后保留的相同行数
+const diffContextLines = 3
+
+// diffMaxCells 行级 LCS 的表格上限（行数乘积），超出时整体视为替换，避免超长回复占用大量内存
+const diffMaxCells = 4_000_000
+
+// TranscriptRow 对齐后的一行：同一位置上两侧的消息，一侧缺失时为 nil
+type TranscriptRow struct {
+	Index int      `json:"index"`
+	A     *Message `json:"a,omitempty"`
+	B     *Message `json:"b,omitempty"`
+	Same  bool     `json:"same"`
+}
+
+// DiffLine 文本差异中的一行，Op 为 " "（相同）、"-"（仅 A）、"+"（仅 B）
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffHunk 连续的差异块，行号从 1 开始（与 unified diff 一致）
+type DiffHunk struct {
+	AStart int        `json:"aStart"`
+	ALines int        `json:"aLines"`
+	BStart int        `json:"bStart"`
+	BLines int        `json:"bLines"`
+	Lines  []DiffLine `json:"lines"`
+}
+
+// TranscriptDiff 两段对话的比较结果
+type TranscriptDiff struct {
+	DivergedAt int             `json:"divergedAt"` // 第一条不同消息的下标；完全相同时为消息数
+	Rows       []TranscriptRow `json:"rows"`
+	FinalA     string          `json:"finalA"` // A 最后一条 assistant 消息
+	FinalB     string          `json:"finalB"`
+	Hunks      []DiffHunk      `json:"hunks"` // FinalA -> FinalB 的行级差异
+}
+
+// MessagesForRun 返回由 runID 写入的消息
+func MessagesForRun(msgs []Message, runID string) []Message {
+	var out []Message
+	for _, msg := range msgs {
+		if id, _ := msg.Metadata[MetadataRunID].(string); id == runID {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// DiffTranscripts 按位置对齐两段对话（fork 出的会话共享前缀，从 DivergedAt 开始分叉），并比较最后一条 assistant 消息
+func DiffTranscripts(a, b []Message) TranscriptDiff {
+	d := TranscriptDiff{DivergedAt: -1}
+	n := max(len(a), len(b))
+	d.Rows = make([]TranscriptRow, 0, n)
+	for i := 0; i < n; i++ {
+		row := TranscriptRow{Index: i}
+		if i < len(a) {
+			row.A = &a[i]
+		}
+		if i < len(b) {
+			row.B = &b[i]
+		}
+		row.Same = row.A != nil && row.B != nil && sameMessage(*row.A, *row.B)
+		if !row.Same && d.DivergedAt < 0 {
+			d.DivergedAt = i
+		}
+		d.Rows = append(d.Rows, row)
+	}
+	if d.DivergedAt < 0 {
+		d.DivergedAt = n
+	}
+	d.FinalA = lastAssistantContent(a)
+	d.FinalB = lastAssistantContent(b)
+	d.Hunks = DiffLines(d.FinalA, d.FinalB)
+	return d
+}
+
+// sameMessage 角色、内容与工具调用相同即视为同一条消息（时间戳与元数据不参与比较）
+func sameMessage(a, b Message) bool {
+	if a.Role != b.Role || a.Content != b.Content || a.ToolCallID != b.ToolCallID || len(a.ToolCalls) != len(b.ToolCalls) {
+		return false
+	}
+	for i := range a.ToolCalls {
+		if a.ToolCalls[i].ID != b.ToolCalls[i].ID || a.ToolCalls[i].Name != b.ToolCalls[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func lastAssistantContent(msgs []Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "assistant" && !IsIncomplete(msgs[i]) {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
+
+// DiffLines 计算 a -> b 的行级差异（LCS），按 diffContextLines 行上下文合并为 hunk；两者相同时返回 nil
+func DiffLines(a, b string) []DiffHunk {
+	if a == b {
+		return nil
+	}
+	ops := diffLineOps(splitDiffLines(a), splitDiffLines(b))
+
+	var hunks []DiffHunk
+	var cur *DiffHunk
+	aLine, bLine := 1, 1
+	lastChange := -1
+	for i, op := range ops {
+		if op.Op != " " {
+			if cur == nil || i-lastChange > 2*diffContextLines {
+				if cur != nil {
+					hunks = append(hunks, closeHunk(*cur, ops, lastChange))
+				}
+				start := max(0, i-diffContextLines)
+				cur = &DiffHunk{AStart: aLine, BStart: bLine}
+				// 回退补上前置上下文行
+				for j := start; j < i; j++ {
+					cur.Lines = append(cur.Lines, ops[j])
+				}
+				cur.AStart -= i - start
+				cur.BStart -= i - start
+			} else {
+				for j := lastChange + 1; j < i; j++ {
+					cur.Lines = append(cur.Lines, ops[j])
+				}
+			}
+			cur.Lines = append(cur.Lines, op)
+			lastChange = i
+		}
+		switch op.Op {
+		case " ":
+			aLine++
+			bLine++
+		case "-":
+			aLine++
+		case "+":
+			bLine++
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, closeHunk(*cur, ops, lastChange))
+	}
+	return hunks
+}
+
+// closeHunk 补上最后一处改动之后的上下文行，并统计 hunk 两侧的行数
+func closeHunk(h DiffHunk, ops []DiffLine, lastChange int) DiffHunk {
+	for j := lastChange + 1; j < len(ops) && j <= lastChange+diffContextLines; j++ {
+		h.Lines = append(h.Lines, ops[j])
+	}
+	for _, l := range h.Lines {
+		if l.Op != "+" {
+			h.ALines++
+		}
+		if l.Op != "-" {
+			h.BLines++
+		}
+	}
+	return h
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLineOps 以最长公共子序列生成逐行操作序列
+func diffLineOps(a, b []string) []DiffLine {
+	if len(a)*len(b) > diffMaxCells {
+		ops := make([]DiffLine, 0, len(a)+len(b))
+		for _, l := range a {
+			ops = append(ops, DiffLine{Op: "-", Text: l})
+		}
+		for _, l := range b {
+			ops = append(ops, DiffLine{Op: "+", Text: l})
+		}
+		return ops
+	}
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的 LCS 长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	ops := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffLine{Op: " ", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			ops = append(ops, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffLine{Op: "+", Text: b[j]})
+	}
+	return ops
+}
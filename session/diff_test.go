@@ -0,0 +1,74 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffForkedSessions(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := mgr.GetOrCreate("agent:main:base")
+	base.AddMessage(Message{Role: "user", Content: "write a haiku", Timestamp: time.Now()})
+	base.AddMessage(Message{Role: "assistant", Content: "old pond\nfrog jumps in\nsplash", Timestamp: time.Now()})
+
+	// fork：复制共同前缀后各自继续
+	fork, _ := mgr.GetOrCreate("agent:main:fork")
+	fork.SetMessages(base.GetHistory(-1))
+
+	base.AddMessage(Message{Role: "user", Content: "make it about autumn", Timestamp: time.Now(),
+		Metadata: map[string]interface{}{MetadataRunID: "run-a"}})
+	base.AddMessage(Message{Role: "assistant", Content: "autumn moon\nfrog jumps in\nsilence", Timestamp: time.Now(),
+		Metadata: map[string]interface{}{MetadataRunID: "run-a"}})
+	fork.AddMessage(Message{Role: "user", Content: "make it about winter", Timestamp: time.Now(),
+		Metadata: map[string]interface{}{MetadataRunID: "run-b"}})
+	fork.AddMessage(Message{Role: "assistant", Content: "winter wind\nfrog jumps in\nsilence\nsnow", Timestamp: time.Now(),
+		Metadata: map[string]interface{}{MetadataRunID: "run-b"}})
+
+	d := DiffTranscripts(base.GetHistory(-1), fork.GetHistory(-1))
+	if d.DivergedAt != 2 || len(d.Rows) != 4 || !d.Rows[1].Same || d.Rows[2].Same {
+		t.Fatalf("unexpected alignment: divergedAt=%d rows=%+v", d.DivergedAt, d.Rows)
+	}
+	if d.FinalA != "autumn moon\nfrog jumps in\nsilence" {
+		t.Fatalf("FinalA = %q", d.FinalA)
+	}
+
+	if len(d.Hunks) != 1 {
+		t.Fatalf("hunks = %+v", d.Hunks)
+	}
+	h := d.Hunks[0]
+	want := []DiffLine{
+		{"-", "autumn moon"}, {"+", "winter wind"}, {" ", "frog jumps in"}, {" ", "silence"}, {"+", "snow"},
+	}
+	if h.AStart != 1 || h.ALines != 3 || h.BStart != 1 || h.BLines != 4 || len(h.Lines) != len(want) {
+		t.Fatalf("unexpected hunk: %+v", h)
+	}
+	for i, l := range want {
+		if h.Lines[i] != l {
+			t.Errorf("line %d = %+v, want %+v", i, h.Lines[i], l)
+		}
+	}
+
+	// 按 run 比较：只取各自 run 写入的消息
+	runDiff := DiffTranscripts(MessagesForRun(base.GetHistory(-1), "run-a"), MessagesForRun(fork.GetHistory(-1), "run-b"))
+	if runDiff.DivergedAt != 0 || len(runDiff.Rows) != 2 || len(runDiff.Hunks) != 1 {
+		t.Fatalf("unexpected run diff: %+v", runDiff)
+	}
+}
+
+func TestDiffLinesSeparatesDistantChanges(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	b := "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\nELEVEN\n12"
+	hunks := DiffLines(a, b)
+	if len(hunks) != 2 {
+		t.Fatalf("hunks = %+v", hunks)
+	}
+	if hunks[0].AStart != 1 || hunks[0].ALines != 5 || hunks[1].AStart != 8 || hunks[1].ALines != 5 {
+		t.Fatalf("unexpected hunk ranges: %+v", hunks)
+	}
+	if DiffLines(a, a) != nil {
+		t.Fatal("identical text should have no hunks")
+	}
+}
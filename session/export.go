@@ -0,0 +1,161 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Transcript sessions.export 导出的完整对话：消息原样保留，附带会话元数据
+type Transcript struct {
+	Key       string                 `json:"key"`
+	CreatedAt time.Time              `json:"createdAt"`
+	UpdatedAt time.Time              `json:"updatedAt"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Messages  []Message              `json:"messages"`
+}
+
+// Transcript 返回会话当前内容的副本；redactTools 为 true 时去掉工具调用参数（可能含密钥等敏感信息）
+func (s *Session) Transcript(redactTools bool) Transcript {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := Transcript{
+		Key:       s.Key,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		Messages:  make([]Message, len(s.Messages)),
+	}
+	if len(s.Metadata) > 0 {
+		t.Metadata = make(map[string]interface{}, len(s.Metadata))
+		for k, v := range s.Metadata {
+			t.Metadata[k] = v
+		}
+	}
+	copy(t.Messages, s.Messages)
+	if redactTools {
+		for i := range t.Messages {
+			if len(t.Messages[i].ToolCalls) == 0 {
+				continue
+			}
+			calls := make([]ToolCall, len(t.Messages[i].ToolCalls))
+			for j, tc := range t.Messages[i].ToolCalls {
+				calls[j] = ToolCall{ID: tc.ID, Name: tc.Name}
+			}
+			t.Messages[i].ToolCalls = calls
+		}
+	}
+	return t
+}
+
+// RenderMarkdown 将对话渲染为 Markdown：每条消息一个带角色与时间的标题，工具调用参数与工具结果放在代码块中。
+// 参数为 nil 的工具调用（导出时 redactTools）标注为已省略
+func (t Transcript) RenderMarkdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", t.Key)
+	if label, _ := t.Metadata["label"].(string); strings.TrimSpace(label) != "" {
+		fmt.Fprintf(&b, "- Label: %s\n", strings.TrimSpace(label))
+	}
+	if !t.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "- Created: %s\n", t.CreatedAt.Format(time.RFC3339))
+	}
+	if !t.UpdatedAt.IsZero() {
+		fmt.Fprintf(&b, "- Updated: %s\n", t.UpdatedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "- Messages: %d\n", len(t.Messages))
+
+	for _, msg := range t.Messages {
+		b.WriteString("\n## ")
+		b.WriteString(markdownRoleHeader(msg))
+		if !msg.Timestamp.IsZero() {
+			b.WriteString(" · ")
+			b.WriteString(msg.Timestamp.Format(time.RFC3339))
+		}
+		b.WriteString("\n\n")
+
+		content := strings.TrimSpace(msg.Content)
+		switch {
+		case msg.Role == "tool":
+			if content != "" {
+				writeFencedBlock(&b, "", content)
+			}
+		case content != "":
+			b.WriteString(content)
+			b.WriteString("\n\n")
+		}
+		for _, m := range msg.Media {
+			ref := m.URL
+			if ref == "" {
+				ref = m.Ref
+			}
+			if ref == "" {
+				ref = "(inline)"
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n", m.Type, ref)
+		}
+		if len(msg.Media) > 0 {
+			b.WriteString("\n")
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&b, "**Tool call** `%s`", tc.Name)
+			if tc.ID != "" {
+				fmt.Fprintf(&b, " (%s)", tc.ID)
+			}
+			b.WriteString("\n\n")
+			if tc.Params == nil {
+				b.WriteString("_Arguments omitted._\n\n")
+				continue
+			}
+			args, err := json.MarshalIndent(tc.Params, "", "  ")
+			if err != nil {
+				args = []byte(fmt.Sprintf("%v", tc.Params))
+			}
+			writeFencedBlock(&b, "json", string(args))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// markdownRoleHeader 消息标题中的角色：工具结果附带工具名或 tool_call_id
+func markdownRoleHeader(msg Message) string {
+	switch msg.Role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	case "tool":
+		if name, _ := msg.Metadata["tool_name"].(string); name != "" {
+			return fmt.Sprintf("Tool result `%s`", name)
+		}
+		if msg.ToolCallID != "" {
+			return fmt.Sprintf("Tool result (%s)", msg.ToolCallID)
+		}
+		return "Tool result"
+	default:
+		return msg.Role
+	}
+}
+
+// writeFencedBlock 写入代码块；围栏比内容中最长的连续反引号多一个，避免内容提前结束代码块
+func writeFencedBlock(b *strings.Builder, lang, text string) {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	b.WriteString(fence)
+	b.WriteString(lang)
+	b.WriteString("\n")
+	b.WriteString(strings.TrimRight(text, "\n"))
+	b.WriteString("\n")
+	b.WriteString(fence)
+	b.WriteString("\n\n")
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranscriptRenderMarkdown(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	sess := &Session{
+		Key:       "agent:main:main",
+		CreatedAt: ts,
+		UpdatedAt: ts,
+		Metadata:  map[string]interface{}{"label": "Deploy help"},
+		Messages: []Message{
+			{Role: "user", Content: "run the deploy", Timestamp: ts},
+			{Role: "assistant", Timestamp: ts, ToolCalls: []ToolCall{
+				{ID: "call_1", Name: "exec", Params: map[string]interface{}{"command": "deploy --token s3cret"}},
+			}},
+			{Role: "tool", Content: "output with ``` fence", ToolCallID: "call_1", Timestamp: ts,
+				Metadata: map[string]interface{}{"tool_name": "exec"}},
+			{Role: "assistant", Content: "Deployed.", Timestamp: ts},
+		},
+	}
+
+	md := sess.Transcript(false).RenderMarkdown()
+	for _, want := range []string{
+		"# Session agent:main:main",
+		"- Label: Deploy help",
+		"## User · 2026-03-01T09:30:00Z\n\nrun the deploy",
+		"**Tool call** `exec` (call_1)\n\n```json\n",
+		`"command": "deploy --token s3cret"`,
+		"## Tool result `exec`",
+		"````\noutput with ``` fence\n````",
+		"## Assistant · 2026-03-01T09:30:00Z\n\nDeployed.",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+
+	redacted := sess.Transcript(true)
+	if redacted.Messages[1].ToolCalls[0].Params != nil || redacted.Messages[1].ToolCalls[0].Name != "exec" {
+		t.Fatalf("redacted tool call = %+v", redacted.Messages[1].ToolCalls[0])
+	}
+	if sess.Messages[1].ToolCalls[0].Params == nil {
+		t.Fatal("redaction must not modify the session")
+	}
+	md = redacted.RenderMarkdown()
+	if strings.Contains(md, "s3cret") || !strings.Contains(md, "_Arguments omitted._") {
+		t.Fatalf("redacted markdown leaks arguments:\n%s", md)
+	}
+}
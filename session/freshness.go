@@ -18,6 +18,10 @@ type ResetPolicy struct {
 	Mode        ResetMode
 	AtHour      int // 0-23，daily 时在当日该小时重置
 	IdleMinutes int // idle 时多少分钟无活动视为不新鲜
+	// Location daily 重置时刻所在时区，nil 为服务器本地时区
+	Location *time.Location
+	// NotifyOnReset 按策略重置时在新会话开头写入一条系统提示
+	NotifyOnReset bool
 }
 
 // EvaluateSessionFreshness 根据策略判断会话是否仍为“新鲜”。
@@ -30,15 +34,18 @@ func EvaluateSessionFreshness(updatedAt, now time.Time, policy ResetPolicy) bool
 	}
 	if policy.Mode == ResetModeDaily && policy.AtHour >= 0 && policy.AtHour <= 23 {
 		// 计算“上次重置点”：今天或昨天 policy.AtHour:00
-		lastReset := lastDailyResetAt(now, policy.AtHour)
+		lastReset := lastDailyResetAt(now, policy.AtHour, policy.Location)
 		return updatedAt.After(lastReset) || !updatedAt.Before(lastReset)
 	}
 	// 无有效策略时默认视为新鲜
 	return true
 }
 
-// lastDailyResetAt 返回在 now 之前最近一次 atHour 时刻（0:00 为该小时）
-func lastDailyResetAt(now time.Time, atHour int) time.Time {
+// lastDailyResetAt 返回在 now 之前最近一次 loc 时区的 atHour 时刻（0:00 为该小时）；loc 为 nil 时使用 now 所在时区
+func lastDailyResetAt(now time.Time, atHour int, loc *time.Location) time.Time {
+	if loc != nil {
+		now = now.In(loc)
+	}
 	y, m, d := now.Date()
 	reset := time.Date(y, m, d, atHour, 0, 0, 0, now.Location())
 	if now.Before(reset) {
@@ -49,9 +56,11 @@ func lastDailyResetAt(now time.Time, atHour int) time.Time {
 
 // PolicyFromConfig 从 config 的 SessionResetConfig 转为 ResetPolicy（避免 session 包依赖 config）
 type SessionResetConfigLike struct {
-	Mode        string
-	AtHour      int
-	IdleMinutes int
+	Mode          string
+	AtHour        int
+	IdleMinutes   int
+	NotifyOnReset bool
+	Timezone      string // IANA 时区名，空为服务器本地时区
 }
 
 // ToResetPolicy 将配置转为 ResetPolicy
@@ -70,5 +79,12 @@ func ToResetPolicy(c *SessionResetConfigLike) ResetPolicy {
 	if atHour < 0 || atHour > 23 {
 		atHour = 4
 	}
-	return ResetPolicy{Mode: mode, AtHour: atHour, IdleMinutes: c.IdleMinutes}
+	policy := ResetPolicy{Mode: mode, AtHour: atHour, IdleMinutes: c.IdleMinutes, NotifyOnReset: c.NotifyOnReset}
+	// 时区已在加载配置时校验，这里解析失败时回退到本地时区
+	if tz := strings.TrimSpace(c.Timezone); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			policy.Location = loc
+		}
+	}
+	return policy
 }
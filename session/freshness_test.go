@@ -0,0 +1,31 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyResetInTimezone(t *testing.T) {
+	policy := ToResetPolicy(&SessionResetConfigLike{Mode: "daily", AtHour: 4, Timezone: "Asia/Tokyo"})
+	if policy.Location == nil || policy.Location.String() != "Asia/Tokyo" {
+		t.Fatalf("location = %v", policy.Location)
+	}
+
+	// 04:00 Asia/Tokyo (UTC+9) = 19:00 UTC of the previous day
+	resetAt := time.Date(2026, 3, 9, 19, 0, 0, 0, time.UTC)
+	if got := lastDailyResetAt(resetAt.Add(time.Minute), policy.AtHour, policy.Location); !got.Equal(resetAt) {
+		t.Fatalf("last reset = %s, want %s", got.UTC(), resetAt)
+	}
+
+	updated := resetAt.Add(-time.Minute)
+	if !EvaluateSessionFreshness(updated, resetAt.Add(-time.Second), policy) {
+		t.Error("session should still be fresh just before 04:00 Tokyo")
+	}
+	if EvaluateSessionFreshness(updated, resetAt.Add(time.Second), policy) {
+		t.Error("session should be stale just after 04:00 Tokyo")
+	}
+	// 04:00 UTC is 13:00 Tokyo: no reset in between
+	if !EvaluateSessionFreshness(resetAt.Add(time.Hour), time.Date(2026, 3, 10, 4, 30, 0, 0, time.UTC), policy) {
+		t.Error("04:00 server (UTC) time must not trigger a Tokyo reset")
+	}
+}
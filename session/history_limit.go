@@ -0,0 +1,194 @@
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// MetadataHistorySummary 消息元数据：该消息为超出历史上限时对最早若干轮的摘要
+	MetadataHistorySummary = "historySummary"
+	// MetadataCompactedMessages 消息元数据：摘要所替代的消息数
+	MetadataCompactedMessages = "compactedMessages"
+	// SummaryMessagePrefix 摘要消息内容前缀（会话历史上限与上下文压缩共用）
+	SummaryMessagePrefix = "[Previous conversation summary]: "
+)
+
+// HistoryCompactor 将即将被裁掉的最早若干轮消息压缩为一段摘要；返回错误或空摘要时这些消息直接丢弃
+type HistoryCompactor func(dropped []Message) (summary string, err error)
+
+// SetHistoryLimits 设置单个会话落盘的消息数 / 字节数上限（session.max_messages / session.max_bytes），<=0 表示不限制；
+// Save 时超出上限会裁掉最早的若干轮
+func (m *Manager) SetHistoryLimits(maxMessages int, maxBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxMessages = maxMessages
+	m.maxBytes = maxBytes
+}
+
+// SetHistoryCompactor 设置裁剪时的摘要函数（在后台执行，不阻塞 Save）；未设置时超出上限的轮次直接丢弃
+func (m *Manager) SetHistoryCompactor(fn HistoryCompactor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compactor = fn
+}
+
+// enforceHistoryLimits 会话超出上限时按轮次（user 消息开始的一段，tool 调用与结果不会被拆开）裁掉最早的部分，
+// 至少保留最后一轮。未设置摘要函数时立即裁剪；设置了摘要函数时在后台摘要（可能需要数分钟的 LLM 调用，不阻塞 Save），
+// 完成后以一条 user 角色的摘要消息替代被裁掉的部分并重新落盘（发给模型时 system 消息会被丢弃，与 agent.CompactWithSummary 一致），期间会话暂时超出上限。调用方需持有 saveMu
+func (m *Manager) enforceHistoryLimits(sess *Session) {
+	m.mu.RLock()
+	maxMessages, maxBytes, compactor := m.maxMessages, m.maxBytes, m.compactor
+	m.mu.RUnlock()
+	if maxMessages <= 0 && maxBytes <= 0 {
+		return
+	}
+
+	sess.mu.RLock()
+	msgs := make([]Message, len(sess.Messages))
+	copy(msgs, sess.Messages)
+	sess.mu.RUnlock()
+	cut := historyCut(msgs, maxMessages, maxBytes, compactor != nil)
+	if cut == 0 {
+		return
+	}
+	if compactor == nil {
+		m.applyHistoryCut(sess, msgs[:cut], nil)
+		return
+	}
+
+	m.mu.Lock()
+	if m.compacting[sess.Key] {
+		m.mu.Unlock()
+		return
+	}
+	m.compacting[sess.Key] = true
+	m.compactions.Add(1)
+	m.mu.Unlock()
+
+	dropped := msgs[:cut]
+	go func() {
+		defer m.compactions.Done()
+		defer func() {
+			m.mu.Lock()
+			delete(m.compacting, sess.Key)
+			m.mu.Unlock()
+		}()
+
+		var head []Message
+		summary, err := compactor(dropped)
+		if err != nil {
+			logger.Warn("Session history summarization failed, dropping oldest turns",
+				zap.String("key", sess.Key), zap.Int("dropped", cut), zap.Error(err))
+		} else if summary != "" {
+			head = []Message{{
+				Role:      "user",
+				Content:   SummaryMessagePrefix + summary,
+				Timestamp: time.Now(),
+				Metadata:  map[string]interface{}{MetadataHistorySummary: true, MetadataCompactedMessages: cut},
+			}}
+		}
+
+		sess.saveMu.Lock()
+		defer sess.saveMu.Unlock()
+		m.mu.RLock()
+		current := m.sessions[sess.Key] == sess
+		m.mu.RUnlock()
+		// 摘要期间会话被删除、归档或重新加载时放弃本次裁剪
+		if !current || !m.applyHistoryCut(sess, dropped, head) {
+			return
+		}
+		if err := writeSessionFile(sess, m.sessionPath(sess.Key)); err != nil {
+			logger.Warn("Failed to save compacted session", zap.String("key", sess.Key), zap.Error(err))
+		}
+	}()
+}
+
+// WaitHistoryCompaction 等待后台历史摘要完成并落盘（一次性命令退出前调用）
+func (m *Manager) WaitHistoryCompaction() {
+	m.compactions.Wait()
+}
+
+// historyCut 返回需要裁掉的最早消息数（按轮次边界，至少保留最后一轮），未超出上限时返回 0；
+// withSummary 时为摘要消息预留一条
+func historyCut(msgs []Message, maxMessages int, maxBytes int64, withSummary bool) int {
+	if len(msgs) == 0 {
+		return 0
+	}
+	sizes := make([]int64, len(msgs))
+	var total int64
+	for i, msg := range msgs {
+		sizes[i] = messageSize(msg)
+		total += sizes[i]
+	}
+	reserve := 0
+	if withSummary {
+		reserve = 1
+	}
+	exceeded := func(count int, bytes int64) bool {
+		return (maxMessages > 0 && count+reserve > maxMessages) || (maxBytes > 0 && bytes > maxBytes)
+	}
+	if !exceeded(len(msgs)-reserve, total) {
+		return 0
+	}
+
+	cut := 0
+	for _, start := range turnStarts(msgs)[1:] {
+		if !exceeded(len(msgs)-cut, total) {
+			break
+		}
+		for ; cut < start; cut++ {
+			total -= sizes[cut]
+		}
+	}
+	return cut
+}
+
+// applyHistoryCut 以 head 替换会话开头的 dropped 消息；会话开头已不是 dropped（期间被重置或改写）时放弃并返回 false
+func (m *Manager) applyHistoryCut(sess *Session, dropped, head []Message) bool {
+	cut := len(dropped)
+	same := func(a, b Message) bool { return sameMessage(a, b) && a.Timestamp.Equal(b.Timestamp) }
+	sess.mu.Lock()
+	if len(sess.Messages) < cut || !same(sess.Messages[0], dropped[0]) || !same(sess.Messages[cut-1], dropped[cut-1]) {
+		sess.mu.Unlock()
+		return false
+	}
+	sess.Messages = append(head, sess.Messages[cut:]...)
+	remaining := len(sess.Messages)
+	sess.mu.Unlock()
+
+	m.mu.RLock()
+	maxMessages, maxBytes := m.maxMessages, m.maxBytes
+	m.mu.RUnlock()
+	logger.Info("Session history exceeded limit, compacted oldest turns",
+		zap.String("key", sess.Key),
+		zap.Int("dropped", cut),
+		zap.Bool("summarized", len(head) > 0),
+		zap.Int("remaining", remaining),
+		zap.Int("max_messages", maxMessages),
+		zap.Int64("max_bytes", maxBytes))
+	return true
+}
+
+// turnStarts 返回各轮起始下标（首条消息总是一轮的开始）
+func turnStarts(msgs []Message) []int {
+	var starts []int
+	for i, msg := range msgs {
+		if i == 0 || msg.Role == "user" {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// messageSize 消息在会话文件中的大致字节数
+func messageSize(msg Message) int64 {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return int64(len(msg.Content))
+	}
+	return int64(len(data)) + 1
+}
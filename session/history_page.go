@@ -0,0 +1,91 @@
+package session
+
+import "time"
+
+// HistoryCursor 分页游标：消息下标（Index >= 0）或时间戳（At 非零）；两者都未设置时表示无游标
+type HistoryCursor struct {
+	Index int
+	At    time.Time
+}
+
+// NoCursor 未设置的游标
+var NoCursor = HistoryCursor{Index: -1}
+
+// IsSet 游标是否已设置
+func (c HistoryCursor) IsSet() bool {
+	return c.Index >= 0 || !c.At.IsZero()
+}
+
+// HistoryPage 一页历史消息；Start 为首条消息在会话中的下标
+type HistoryPage struct {
+	Messages []Message
+	Start    int
+	// HasMore 翻页方向上是否还有消息；NextCursor 为继续翻页时传入的下标（before 翻页为本页首条，after 翻页为本页末条）
+	HasMore    bool
+	NextCursor int
+}
+
+// HistoryPage 按游标分页读取历史：before 返回游标之前的最多 limit 条（向更早翻页），after 返回游标之后的最多 limit 条；
+// 两者都未设置时与 GetHistory 一致返回最近 limit 条。时间戳游标按消息时间定位（before 不含该时刻及之后，after 不含该时刻及之前）
+func (s *Session) HistoryPage(before, after HistoryCursor, limit int) HistoryPage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := len(s.Messages)
+	if limit <= 0 {
+		limit = n
+	}
+	var start, end int
+	page := HistoryPage{}
+	if after.IsSet() && !before.IsSet() {
+		start = s.afterIndexLocked(after)
+		end = min(n, start+limit)
+		page.HasMore = end < n
+		page.NextCursor = end - 1
+	} else {
+		end = n
+		if before.IsSet() {
+			end = s.beforeIndexLocked(before)
+		}
+		if after.IsSet() {
+			start = s.afterIndexLocked(after)
+		}
+		floor := start
+		start = max(floor, end-limit)
+		if start > end {
+			start = end
+		}
+		page.HasMore = start > floor
+		page.NextCursor = start
+	}
+	page.Start = start
+	page.Messages = make([]Message, end-start)
+	copy(page.Messages, s.Messages[start:end])
+	return page
+}
+
+// beforeIndexLocked before 游标对应的结束下标（不含）
+func (s *Session) beforeIndexLocked(c HistoryCursor) int {
+	if c.Index >= 0 {
+		return min(c.Index, len(s.Messages))
+	}
+	for i, msg := range s.Messages {
+		if !msg.Timestamp.Before(c.At) {
+			return i
+		}
+	}
+	return len(s.Messages)
+}
+
+// afterIndexLocked after 游标对应的起始下标（含）
+func (s *Session) afterIndexLocked(c HistoryCursor) int {
+	if c.Index >= 0 {
+		return min(c.Index+1, len(s.Messages))
+	}
+	for i, msg := range s.Messages {
+		if msg.Timestamp.After(c.At) {
+			return i
+		}
+	}
+	return len(s.Messages)
+}
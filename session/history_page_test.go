@@ -0,0 +1,63 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHistoryPageCursors(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sess := &Session{}
+	for i := range 10 {
+		sess.Messages = append(sess.Messages, Message{Role: "user", Content: fmt.Sprint(i), Timestamp: base.Add(time.Duration(i) * time.Minute)})
+	}
+	contents := func(p HistoryPage) string {
+		out := ""
+		for _, m := range p.Messages {
+			out += m.Content
+		}
+		return out
+	}
+
+	// 无游标：最近 limit 条，与 GetHistory 一致
+	p := sess.HistoryPage(NoCursor, NoCursor, 4)
+	if contents(p) != "6789" || p.Start != 6 || !p.HasMore || p.NextCursor != 6 {
+		t.Fatalf("latest page = %q start=%d hasMore=%v next=%d", contents(p), p.Start, p.HasMore, p.NextCursor)
+	}
+	// 向更早翻页直到开头
+	p = sess.HistoryPage(HistoryCursor{Index: p.NextCursor}, NoCursor, 4)
+	if contents(p) != "2345" || !p.HasMore || p.NextCursor != 2 {
+		t.Fatalf("older page = %q hasMore=%v next=%d", contents(p), p.HasMore, p.NextCursor)
+	}
+	p = sess.HistoryPage(HistoryCursor{Index: p.NextCursor}, NoCursor, 4)
+	if contents(p) != "01" || p.HasMore {
+		t.Fatalf("oldest page = %q hasMore=%v", contents(p), p.HasMore)
+	}
+
+	// after 向后翻页
+	p = sess.HistoryPage(NoCursor, HistoryCursor{Index: 1}, 3)
+	if contents(p) != "234" || !p.HasMore || p.NextCursor != 4 {
+		t.Fatalf("after page = %q hasMore=%v next=%d", contents(p), p.HasMore, p.NextCursor)
+	}
+	p = sess.HistoryPage(NoCursor, HistoryCursor{Index: 7}, 3)
+	if contents(p) != "89" || p.HasMore {
+		t.Fatalf("last after page = %q hasMore=%v", contents(p), p.HasMore)
+	}
+
+	// 时间戳游标
+	p = sess.HistoryPage(HistoryCursor{Index: -1, At: base.Add(5 * time.Minute)}, NoCursor, 2)
+	if contents(p) != "34" {
+		t.Fatalf("before timestamp page = %q", contents(p))
+	}
+	p = sess.HistoryPage(NoCursor, HistoryCursor{Index: -1, At: base.Add(5 * time.Minute)}, 2)
+	if contents(p) != "67" {
+		t.Fatalf("after timestamp page = %q", contents(p))
+	}
+
+	// before 与 after 同时给出：区间内最近 limit 条
+	p = sess.HistoryPage(HistoryCursor{Index: 8}, HistoryCursor{Index: 2}, 3)
+	if contents(p) != "567" || !p.HasMore || p.NextCursor != 5 {
+		t.Fatalf("range page = %q hasMore=%v next=%d", contents(p), p.HasMore, p.NextCursor)
+	}
+}
@@ -0,0 +1,144 @@
+package session
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/smallnest/goclaw/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultIdleSweepInterval 空闲重置巡检的默认间隔
+const DefaultIdleSweepInterval = 5 * time.Minute
+
+// StartIdleSweeper 启动后台巡检：按 interval（<=0 时为 DefaultIdleSweepInterval）定期执行 SweepIdle，ctx 取消后退出。
+// 策略每轮从 SetResetPolicy 读取（未设置或非 idle 模式时空转），调用方应始终启动巡检，之后热更新启用的 idle 策略同样生效；
+// 巡检间隔在启动时确定，修改后需重启
+func (m *Manager) StartIdleSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultIdleSweepInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := m.SweepIdle(now); err != nil {
+					logger.Warn("Idle session sweep failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// SweepIdle 在 idle 模式下重置超过空闲窗口的会话（与访问时重置的效果相同），返回被重置的会话 key。
+// 未设置策略或非 idle 模式时不做任何事；已为空白（或仅有重置提示）的会话不会重复重置
+func (m *Manager) SweepIdle(now time.Time) ([]string, error) {
+	m.mu.RLock()
+	policy := m.resetPolicy
+	m.mu.RUnlock()
+	if policy == nil || policy.Mode != ResetModeIdle || policy.IdleMinutes <= 0 {
+		return nil, nil
+	}
+
+	keys, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	var reset []string
+	for _, key := range keys {
+		if updatedAt, ok := m.lastActivity(key); !ok || EvaluateSessionFreshness(updatedAt, now, *policy) {
+			continue
+		}
+		lastActivity, ok, err := m.resetIdleSession(key, now, policy)
+		if err != nil {
+			logger.Warn("Idle sweep: failed to reset session", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		logger.Info("Session reset by idle sweeper",
+			zap.String("key", key),
+			zap.Int("idle_minutes", policy.IdleMinutes),
+			zap.Time("last_activity", lastActivity))
+		reset = append(reset, key)
+	}
+	return reset, nil
+}
+
+// resetIdleSession 复核并重置单个空闲会话，返回重置前的最后活动时间与是否重置。
+// 未缓存的会话在持有 m.mu 时直接读写文件，不加入缓存（巡检不应让所有过期会话常驻内存），期间并发访问会等待重置完成
+func (m *Manager) resetIdleSession(key string, now time.Time, policy *ResetPolicy) (time.Time, bool, error) {
+	m.mu.Lock()
+	sess, cached := m.sessions[key]
+	if !cached {
+		defer m.mu.Unlock()
+		loaded, err := m.load(key)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return time.Time{}, false, nil
+			}
+			return time.Time{}, false, err
+		}
+		lastActivity := loaded.UpdatedAt
+		if EvaluateSessionFreshness(lastActivity, now, *policy) || !hasConversation(loaded.Messages) {
+			return lastActivity, false, nil
+		}
+		resetForPolicy(loaded, policy)
+		loaded.saveMu.Lock()
+		defer loaded.saveMu.Unlock()
+		if err := writeSessionFile(loaded, m.sessionPath(key)); err != nil {
+			return lastActivity, false, err
+		}
+		return lastActivity, true, nil
+	}
+	m.mu.Unlock()
+
+	// 加锁后复核：巡检期间会话可能刚被访问
+	sess.mu.Lock()
+	lastActivity := sess.UpdatedAt
+	if EvaluateSessionFreshness(lastActivity, now, *policy) || !hasConversation(sess.Messages) {
+		sess.mu.Unlock()
+		return lastActivity, false, nil
+	}
+	resetForPolicy(sess, policy)
+	sess.mu.Unlock()
+
+	if err := m.Save(sess); err != nil {
+		return lastActivity, false, err
+	}
+	return lastActivity, true, nil
+}
+
+// lastActivity 返回会话最后更新时间：优先取缓存，否则只读会话文件的元数据首行
+func (m *Manager) lastActivity(key string) (time.Time, bool) {
+	m.mu.RLock()
+	sess, ok := m.sessions[key]
+	m.mu.RUnlock()
+	if ok {
+		sess.mu.RLock()
+		defer sess.mu.RUnlock()
+		return sess.UpdatedAt, true
+	}
+
+	header, ok := readSessionHeader(m.sessionPath(key))
+	if !ok || header.UpdatedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return header.UpdatedAt, true
+}
+
+// hasConversation 判断消息中是否有重置提示以外的内容
+func hasConversation(msgs []Message) bool {
+	for _, msg := range msgs {
+		if notice, _ := msg.Metadata[MetadataResetNotice].(bool); !notice {
+			return true
+		}
+	}
+	return false
+}
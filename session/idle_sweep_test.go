@@ -0,0 +1,74 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSweepIdleResetsStaleSessions(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	stale := time.Now().Add(-2 * time.Hour)
+	for _, key := range []string{"agent:main:stale", "agent:main:fresh"} {
+		sess, _ := writer.GetOrCreateWithPolicy(key, nil)
+		sess.AddMessage(Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+		if key == "agent:main:stale" {
+			sess.UpdatedAt = stale
+		}
+		if err := writer.Save(sess); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// 新的 Manager 模拟重启后会话只在磁盘上
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if got, _ := mgr.SweepIdle(time.Now()); len(got) != 0 {
+		t.Fatalf("SweepIdle() without policy reset %v", got)
+	}
+	mgr.SetResetPolicy(&ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30, NotifyOnReset: true})
+
+	got, err := mgr.SweepIdle(time.Now())
+	if err != nil {
+		t.Fatalf("SweepIdle() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != "agent:main:stale" {
+		t.Fatalf("SweepIdle() = %v, want [agent:main:stale]", got)
+	}
+
+	mgr.mu.RLock()
+	cachedCount := len(mgr.sessions)
+	mgr.mu.RUnlock()
+	if cachedCount != 0 {
+		t.Errorf("sweeper cached %d sessions it only reset on disk", cachedCount)
+	}
+
+	reloaded, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	sess, err := reloaded.GetOrCreateWithPolicy("agent:main:stale", nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	if hasConversation(sess.Messages) || len(sess.Messages) != 1 {
+		t.Fatalf("stale session not reset on disk: %+v", sess.Messages)
+	}
+	if sess.Metadata[MetadataLastResetMode] != string(ResetModeIdle) {
+		t.Errorf("lastResetMode = %v", sess.Metadata[MetadataLastResetMode])
+	}
+	fresh, _ := reloaded.GetOrCreateWithPolicy("agent:main:fresh", nil)
+	if len(fresh.Messages) != 1 || !hasConversation(fresh.Messages) {
+		t.Errorf("fresh session should be untouched: %+v", fresh.Messages)
+	}
+
+	// 已重置的会话只剩重置提示，再次过期也不会重复重置
+	if got, _ := mgr.SweepIdle(time.Now().Add(3 * time.Hour)); len(got) != 1 || got[0] != "agent:main:fresh" {
+		t.Fatalf("second SweepIdle() = %v, want [agent:main:fresh]", got)
+	}
+}
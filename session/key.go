@@ -1,6 +1,8 @@
 package session
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 )
 
@@ -195,3 +197,10 @@ func SafeFilenameToKey(filename string) string {
 	// 其他格式：将下划线替换为冒号
 	return strings.ReplaceAll(filename, "_", ":")
 }
+
+// RunID 由 sessionKey 与客户端 idempotencyKey 派生内部 runId：同一会话内相同 key 得到相同 runId（幂等），
+// 不同会话即使 key 相同也不会冲突，避免客户端复用他人的 key 劫持其他会话的运行与事件
+func RunID(sessionKey, idempotencyKey string) string {
+	sum := sha256.Sum256([]byte(sessionKey + "\x00" + idempotencyKey))
+	return "run-" + hex.EncodeToString(sum[:16])
+}
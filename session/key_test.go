@@ -0,0 +1,22 @@
+package session
+
+import "testing"
+
+func TestRunIDNamespacedBySessionKey(t *testing.T) {
+	const key = "client-key-1"
+	a := RunID("agent:main:main", key)
+	b := RunID("agent:main:other", key)
+	if a == b {
+		t.Fatalf("same idempotencyKey in two sessions must give distinct run ids, got %q", a)
+	}
+	if again := RunID("agent:main:main", key); again != a {
+		t.Fatalf("run id not stable within a session: %q != %q", again, a)
+	}
+	if a == key || b == key {
+		t.Fatalf("run id must not expose the raw client key")
+	}
+	// 拼接边界不可混淆
+	if RunID("a:b", "c") == RunID("a", "b:c") {
+		t.Fatalf("run ids collide across key boundaries")
+	}
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetadataLabel 会话元数据：用户设置的标签（sessions.patch），同一标签只能属于一个会话
+const MetadataLabel = "label"
+
+// labelIndex 标签 → 会话 key 的索引，首次查询时从会话文件的元数据行构建，之后由 Save/Delete 维护
+type labelIndex struct {
+	mu      sync.Mutex
+	loaded  bool
+	byLabel map[string]map[string]bool // 历史数据可能存在重复标签，按集合保存
+	byKey   map[string]string
+}
+
+func newLabelIndex() *labelIndex {
+	return &labelIndex{
+		byLabel: make(map[string]map[string]bool),
+		byKey:   make(map[string]string),
+	}
+}
+
+// setLocked 更新 key 的标签；label 为空表示移除
+func (idx *labelIndex) setLocked(key, label string) {
+	if old, ok := idx.byKey[key]; ok {
+		if old == label {
+			return
+		}
+		delete(idx.byLabel[old], key)
+		if len(idx.byLabel[old]) == 0 {
+			delete(idx.byLabel, old)
+		}
+		delete(idx.byKey, key)
+	}
+	if label == "" {
+		return
+	}
+	idx.byKey[key] = label
+	if idx.byLabel[label] == nil {
+		idx.byLabel[label] = make(map[string]bool)
+	}
+	idx.byLabel[label][key] = true
+}
+
+// update 会话落盘或删除后同步索引；索引尚未构建时无需维护（构建时会读取磁盘）
+func (idx *labelIndex) update(key, label string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.loaded {
+		idx.setLocked(key, label)
+	}
+}
+
+// sessionLabel 返回元数据中规范化（去空白）后的标签
+func sessionLabel(metadata map[string]interface{}) string {
+	label, _ := metadata[MetadataLabel].(string)
+	return strings.TrimSpace(label)
+}
+
+// FindByLabel 按标签查找会话 key（标签比较前去除首尾空白）；不会加载或创建会话。
+// 若历史数据中多个会话使用同一标签，返回字典序最小的 key
+func (m *Manager) FindByLabel(label string) (string, bool) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", false
+	}
+	idx := m.labels
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.loaded {
+		if err := m.buildLabelIndexLocked(); err != nil {
+			return "", false
+		}
+	}
+	keys := idx.byLabel[label]
+	if len(keys) == 0 {
+		return "", false
+	}
+	owners := make([]string, 0, len(keys))
+	for k := range keys {
+		owners = append(owners, k)
+	}
+	return slices.Min(owners), true
+}
+
+// buildLabelIndexLocked 扫描会话文件的元数据行构建索引（调用方持有 m.labels.mu）
+func (m *Manager) buildLabelIndexLocked() error {
+	keys, err := m.List()
+	if err != nil {
+		return err
+	}
+	idx := m.labels
+	for _, key := range keys {
+		if header, ok := readSessionHeader(m.sessionPath(key)); ok {
+			idx.setLocked(key, sessionLabel(header.Metadata))
+		}
+	}
+	idx.loaded = true
+	return nil
+}
+
+// sessionHeader 会话文件首行（元数据行）的内容
+type sessionHeader struct {
+	Type      string                 `json:"_type"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// readSessionHeader 只读取会话文件的元数据行，避免为读取标签或更新时间解析整份记录
+func readSessionHeader(path string) (sessionHeader, bool) {
+	var header sessionHeader
+	file, err := os.Open(path)
+	if err != nil {
+		return header, false
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return header, false
+	}
+	if json.Unmarshal(line, &header) != nil || header.Type != "metadata" {
+		return header, false
+	}
+	return header, true
+}
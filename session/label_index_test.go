@@ -0,0 +1,52 @@
+package session
+
+import "testing"
+
+func TestFindByLabel(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	for key, label := range map[string]string{"agent:main:a": " work ", "agent:main:b": "home", "agent:main:c": ""} {
+		sess, _ := writer.GetOrCreate(key)
+		sess.PatchMetadata(map[string]interface{}{MetadataLabel: label})
+		if err := writer.Save(sess); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if key, ok := mgr.FindByLabel("work"); !ok || key != "agent:main:a" {
+		t.Fatalf("FindByLabel(work) = %q, %v", key, ok)
+	}
+	if _, ok := mgr.FindByLabel(""); ok {
+		t.Fatal("empty label should not match")
+	}
+	if n := len(mgr.sessions); n != 0 {
+		t.Fatalf("FindByLabel loaded %d sessions into cache", n)
+	}
+
+	// Save 后索引随标签变化
+	sess, _ := mgr.GetOrCreate("agent:main:a")
+	sess.PatchMetadata(map[string]interface{}{MetadataLabel: "office"})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, ok := mgr.FindByLabel("work"); ok {
+		t.Fatal("old label still indexed after relabel")
+	}
+	if key, ok := mgr.FindByLabel("office"); !ok || key != "agent:main:a" {
+		t.Fatalf("FindByLabel(office) = %q, %v", key, ok)
+	}
+
+	if err := mgr.Delete("agent:main:b"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := mgr.FindByLabel("home"); ok {
+		t.Fatal("deleted session still indexed")
+	}
+}
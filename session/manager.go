@@ -2,11 +2,14 @@ package session
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/smallnest/goclaw/internal/i18n"
 )
 
 // DefaultIdleMinutes 默认空闲分钟数（idle 模式）
@@ -17,10 +20,11 @@ const DefaultResetAtHour = 4
 
 // Media 媒体文件
 type Media struct {
-	Type     string `json:"type"`             // image, video, audio, document
-	URL      string `json:"url"`              // 文件URL
-	Base64   string `json:"base64,omitempty"` // Base64编码内容
-	MimeType string `json:"mimetype"`         // MIME类型
+	Type     string `json:"type"`               // image, video, audio, document
+	URL      string `json:"url"`                // 文件URL
+	Base64   string `json:"base64,omitempty"`   // Base64编码内容
+	MimeType string `json:"mimetype"`           // MIME类型
+	Ref      string `json:"mediaRef,omitempty"` // 外置存储引用（sha256:...），此时 Base64 为空，见 MediaStore
 }
 
 // ToolCall 工具调用
@@ -49,10 +53,16 @@ type Session struct {
 	UpdatedAt time.Time              `json:"updated_at"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	mu        sync.RWMutex
+	saveMu    sync.Mutex  // 串行化同一会话的落盘（运行中的流式检查点与运行结束时的保存可能并发）
+	media     *MediaStore // 由 Manager 注入；超过阈值的媒体在 AddMessage 时外置
 }
 
 // AddMessage 添加消息
 func (s *Session) AddMessage(msg Message) {
+	if s.media != nil {
+		s.media.externalize(&msg)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -79,6 +89,24 @@ func (s *Session) GetHistory(maxMessages int) []Message {
 	return result
 }
 
+// GetHydratedHistory 同 GetHistory，但外置媒体按 mediaRef 回填 base64；构造发给模型的消息时使用
+func (s *Session) GetHydratedHistory(maxMessages int) []Message {
+	history := s.GetHistory(maxMessages)
+	if s.media == nil {
+		return history
+	}
+	return s.media.hydrateMessages(history)
+}
+
+// SetMessages 替换全部消息（如修复格式错误的历史后写回）
+func (s *Session) SetMessages(msgs []Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Messages = msgs
+	s.UpdatedAt = time.Now()
+}
+
 // Clear 清空消息
 func (s *Session) Clear() {
 	s.mu.Lock()
@@ -88,6 +116,26 @@ func (s *Session) Clear() {
 	s.UpdatedAt = time.Now()
 }
 
+// MetadataDebugPrompts 会话元数据：为 true 时记录该会话每次运行发给 LLM 的完整 prompt
+const MetadataDebugPrompts = "debugPrompts"
+
+// MetadataModelOverride 会话元数据：本会话使用的模型（sessions.patch model / 入站命令 /model）
+const MetadataModelOverride = "modelOverride"
+
+// 会话元数据：思考等级（off/minimal/low/medium/high/xhigh）、回复详略（off/on/full）与推理等级（thinkingLevel 未设置时作为思考等级）
+const (
+	MetadataThinkingLevel  = "thinkingLevel"
+	MetadataVerboseLevel   = "verboseLevel"
+	MetadataReasoningLevel = "reasoningLevel"
+)
+
+// GetMetadata 读取单个元数据字段
+func (s *Session) GetMetadata(key string) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Metadata[key]
+}
+
 // PatchMetadata 更新会话元数据字段（如 label, thinkingLevel, verboseLevel, reasoningLevel）
 func (s *Session) PatchMetadata(updates map[string]interface{}) {
 	s.mu.Lock()
@@ -106,12 +154,26 @@ func (s *Session) PatchMetadata(updates map[string]interface{}) {
 	s.UpdatedAt = time.Now()
 }
 
+// Touch 仅刷新 UpdatedAt（不添加消息），用于保活以重置空闲计时
+func (s *Session) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.UpdatedAt = time.Now()
+}
+
 // Manager 会话管理器
 type Manager struct {
 	sessions    map[string]*Session
 	mu          sync.RWMutex
 	baseDir     string
-	resetPolicy *ResetPolicy // 可选：与 OpenClaw 对齐，不新鲜会话自动重置
+	resetPolicy *ResetPolicy     // 可选：与 OpenClaw 对齐，不新鲜会话自动重置
+	media       *MediaStore      // 外置媒体存储（<baseDir>/media）
+	maxMessages int              // 单个会话落盘的消息数上限，<=0 不限制
+	maxBytes    int64            // 单个会话落盘的字节数上限，<=0 不限制
+	compactor   HistoryCompactor // 超出上限时对被裁掉轮次的摘要，可为 nil
+	labels      *labelIndex      // 标签 → 会话 key 索引（FindByLabel）
+	compacting  map[string]bool  // 正在后台摘要的会话 key，避免同一会话重复摘要
+	compactions sync.WaitGroup   // 进行中的后台摘要，见 WaitHistoryCompaction
 }
 
 // NewManager 创建会话管理器
@@ -122,11 +184,61 @@ func NewManager(baseDir string) (*Manager, error) {
 	}
 
 	return &Manager{
-		sessions: make(map[string]*Session),
-		baseDir:  baseDir,
+		sessions:   make(map[string]*Session),
+		baseDir:    baseDir,
+		media:      NewMediaStore(filepath.Join(baseDir, "media")),
+		labels:     newLabelIndex(),
+		compacting: make(map[string]bool),
 	}, nil
 }
 
+// SetMediaInlineMax 设置会话内联媒体（base64）的最大字节数，超过则外置到媒体存储；<=0 表示不外置
+func (m *Manager) SetMediaInlineMax(n int) {
+	m.media.SetInlineMax(n)
+}
+
+// MediaStore 返回会话媒体存储（供 sessions.media.get 按 mediaRef 读取）
+func (m *Manager) MediaStore() *MediaStore {
+	return m.media
+}
+
+// HydrateMedia 返回消息副本，其中外置媒体按 mediaRef 回填 base64（发给模型等需要完整内容时使用）
+func (m *Manager) HydrateMedia(msgs []Message) []Message {
+	return m.media.hydrateMessages(msgs)
+}
+
+// MigrateInlineMedia 将已有会话中超过阈值的内联媒体外置并重写会话文件（需先 SetMediaInlineMax）；返回外置的媒体数
+func (m *Manager) MigrateInlineMedia() (int, error) {
+	if m.media.inlineMax.Load() <= 0 {
+		return 0, fmt.Errorf("media inline max is not set")
+	}
+	keys, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, key := range keys {
+		sess, err := m.GetOrCreateWithPolicy(key, nil)
+		if err != nil {
+			return total, fmt.Errorf("load session %s: %w", key, err)
+		}
+		sess.mu.Lock()
+		moved := 0
+		for i := range sess.Messages {
+			moved += m.media.externalize(&sess.Messages[i])
+		}
+		sess.mu.Unlock()
+		if moved == 0 {
+			continue
+		}
+		if err := m.Save(sess); err != nil {
+			return total, fmt.Errorf("save session %s: %w", key, err)
+		}
+		total += moved
+	}
+	return total, nil
+}
+
 // SetResetPolicy 设置会话重置策略；之后 GetOrCreate 将按策略判定是否重置（TUI/agent 与 Gateway 同一套策略）
 func (m *Manager) SetResetPolicy(policy *ResetPolicy) {
 	m.mu.Lock()
@@ -151,12 +263,7 @@ func (m *Manager) GetOrCreateWithPolicy(key string, policy *ResetPolicy) (*Sessi
 	if sess, ok := m.sessions[key]; ok {
 		if policy != nil && !EvaluateSessionFreshness(sess.UpdatedAt, time.Now(), *policy) {
 			// 不新鲜：重置为空白会话，保留 key
-			sess.Messages = []Message{}
-			sess.CreatedAt = time.Now()
-			sess.UpdatedAt = time.Now()
-			if sess.Metadata == nil {
-				sess.Metadata = make(map[string]interface{})
-			}
+			resetForPolicy(sess, policy)
 		}
 		return sess, nil
 	}
@@ -177,26 +284,107 @@ func (m *Manager) GetOrCreateWithPolicy(key string, policy *ResetPolicy) (*Sessi
 		}
 	} else if policy != nil && !EvaluateSessionFreshness(sess.UpdatedAt, time.Now(), *policy) {
 		// 已加载但不新鲜：重置为空白会话
-		sess.Messages = []Message{}
-		sess.CreatedAt = time.Now()
-		sess.UpdatedAt = time.Now()
-		if sess.Metadata == nil {
-			sess.Metadata = make(map[string]interface{})
-		}
+		resetForPolicy(sess, policy)
 	}
+	sess.media = m.media
 
 	// 添加到缓存
 	m.sessions[key] = sess
 	return sess, nil
 }
 
+// Lookup 只读获取会话：优先取缓存，否则从磁盘加载（不加入缓存、不按重置策略重置）；
+// 会话不存在时返回的错误满足 os.IsNotExist。用于汇总、统计等不应创建或修改会话的场景
+func (m *Manager) Lookup(key string) (*Session, error) {
+	m.mu.RLock()
+	sess, ok := m.sessions[key]
+	m.mu.RUnlock()
+	if ok {
+		return sess, nil
+	}
+	sess, err := m.load(key)
+	if err != nil {
+		return nil, err
+	}
+	sess.media = m.media
+	return sess, nil
+}
+
+// 按策略重置时写入新会话的提示（session.reset.notify_on_reset）
+const (
+	MetadataLastResetAt   = "lastResetAt"   // 最近一次按策略重置的时间（毫秒）
+	MetadataLastResetMode = "lastResetMode" // 最近一次按策略重置的模式（idle / daily）
+	MetadataResetNotice   = "resetNotice"   // 消息元数据：该系统消息为重置提示
+)
+
+// resetNotices 各重置模式的提示文案（按 gateway.locale 输出）
+var resetNotices = map[ResetMode]i18n.Key{
+	ResetModeIdle:  i18n.SessionResetIdle,
+	ResetModeDaily: i18n.SessionResetDaily,
+}
+
+// resetForPolicy 将不新鲜的会话重置为空白会话（保留 key 与元数据）；policy.NotifyOnReset 时在开头写入一条系统提示，
+// 并在元数据中记录重置时间与模式，供 UI 提示用户
+func resetForPolicy(sess *Session, policy *ResetPolicy) {
+	now := time.Now()
+	sess.Messages = []Message{}
+	sess.CreatedAt = now
+	sess.UpdatedAt = now
+	if sess.Metadata == nil {
+		sess.Metadata = make(map[string]interface{})
+	}
+	if !policy.NotifyOnReset {
+		return
+	}
+	sess.Metadata[MetadataLastResetAt] = now.UnixMilli()
+	sess.Metadata[MetadataLastResetMode] = string(policy.Mode)
+	sess.Messages = append(sess.Messages, Message{
+		Role:      "system",
+		Content:   i18n.T(resetNotices[policy.Mode]),
+		Timestamp: now,
+		Metadata:  map[string]interface{}{MetadataResetNotice: true},
+	})
+}
+
+// Touch 会话保活：按策略获取会话（已不新鲜的会话照常重置，不会被“续命”），刷新 UpdatedAt 并落盘。
+// 只作用于已存在的活动会话，不存在（含已归档）时返回满足 errors.Is(err, os.ErrNotExist) 的错误，不会创建
+func (m *Manager) Touch(key string, policy *ResetPolicy) (*Session, error) {
+	if !m.Exists(key) {
+		return nil, fmt.Errorf("%w: session %s", os.ErrNotExist, key)
+	}
+	sess, err := m.GetOrCreateWithPolicy(key, policy)
+	if err != nil {
+		return nil, err
+	}
+	sess.Touch()
+	if err := m.Save(sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
 // Save 保存会话
 func (m *Manager) Save(session *Session) error {
+	session.saveMu.Lock()
+	defer session.saveMu.Unlock()
+	if archived, _ := session.GetMetadata(MetadataArchived).(bool); archived {
+		return fmt.Errorf("%w: %s", ErrSessionArchived, session.Key)
+	}
+	m.enforceHistoryLimits(session)
+	if err := writeSessionFile(session, m.sessionPath(session.Key)); err != nil {
+		return err
+	}
 	session.mu.RLock()
-	defer session.mu.RUnlock()
+	label := sessionLabel(session.Metadata)
+	session.mu.RUnlock()
+	m.labels.update(session.Key, label)
+	return nil
+}
 
-	// 确定文件路径
-	filePath := m.sessionPath(session.Key)
+// writeSessionFile 将会话写入 filePath（先写临时文件再重命名）；调用方需持有 session.saveMu
+func writeSessionFile(session *Session, filePath string) error {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
 
 	// 创建临时文件
 	tmpPath := filePath + ".tmp"
@@ -253,7 +441,7 @@ func (m *Manager) Save(session *Session) error {
 	return closeAndRename()
 }
 
-// Delete 删除会话
+// Delete 删除会话（活动与归档的记录都会删除）
 func (m *Manager) Delete(key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -262,10 +450,12 @@ func (m *Manager) Delete(key string) error {
 	delete(m.sessions, key)
 
 	// 删除文件
-	filePath := m.sessionPath(key)
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-		return err
+	for _, filePath := range []string{m.sessionPath(key), m.archivePath(key)} {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
+	m.labels.update(key, "")
 
 	return nil
 }
@@ -331,8 +521,11 @@ func (m *Manager) List() ([]string, error) {
 
 // load 从磁盘加载会话
 func (m *Manager) load(key string) (*Session, error) {
-	filePath := m.sessionPath(key)
+	return loadSessionFile(key, m.sessionPath(key))
+}
 
+// loadSessionFile 解析会话文件
+func loadSessionFile(key, filePath string) (*Session, error) {
 	// 打开文件
 	file, err := os.Open(filePath)
 	if err != nil {
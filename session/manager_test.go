@@ -0,0 +1,303 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/goclaw/config"
+)
+
+func TestManagerTouchKeepsSessionFreshUnderIdlePolicy(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	policy := &ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30}
+
+	sess, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	sess.AddMessage(Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+	// 模拟 25 分钟无消息
+	sess.UpdatedAt = time.Now().Add(-25 * time.Minute)
+
+	if _, err := mgr.Touch("agent:main:main", policy); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+
+	// 再过 25 分钟（距最后一条消息共 50 分钟）：因 touch 过，仍在空闲窗口内
+	later := time.Now().Add(25 * time.Minute)
+	if !EvaluateSessionFreshness(sess.UpdatedAt, later, *policy) {
+		t.Fatal("touched session should still be fresh under idle policy")
+	}
+
+	got, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	if len(got.Messages) != 1 {
+		t.Errorf("touch should not add or drop messages, got %d", len(got.Messages))
+	}
+
+	// touch 需经 Save 落盘：新 Manager 从磁盘加载到的 updated_at 同样新鲜
+	reloaded, err := NewManager(mgr.Path())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	fromDisk, err := reloaded.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	if len(fromDisk.Messages) != 1 {
+		t.Errorf("reloaded session messages = %d, want 1", len(fromDisk.Messages))
+	}
+}
+
+func TestManagerTouchDoesNotReviveStaleSession(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	policy := &ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30}
+
+	sess, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	sess.AddMessage(Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+	sess.UpdatedAt = time.Now().Add(-45 * time.Minute)
+
+	touched, err := mgr.Touch("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	if len(touched.Messages) != 0 {
+		t.Errorf("stale session should be reset on touch, got %d messages", len(touched.Messages))
+	}
+}
+
+func TestPolicyResetWritesNoticeWhenEnabled(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	policy := &ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30, NotifyOnReset: true}
+
+	sess, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	sess.AddMessage(Message{Role: "user", Content: "remember the plan", Timestamp: time.Now()})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	sess.UpdatedAt = time.Now().Add(-45 * time.Minute)
+
+	reset, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	if len(reset.Messages) != 1 {
+		t.Fatalf("reset session messages = %+v, want only the reset notice", reset.Messages)
+	}
+	notice := reset.Messages[0]
+	if notice.Role != "system" || notice.Content != "Previous conversation was reset due to inactivity." || notice.Metadata[MetadataResetNotice] != true {
+		t.Fatalf("notice = %+v", notice)
+	}
+	if reset.GetMetadata(MetadataLastResetMode) != "idle" || reset.GetMetadata(MetadataLastResetAt) == nil {
+		t.Fatalf("reset metadata = %+v", reset.Metadata)
+	}
+
+	// 未开启时重置仍为空白会话
+	reset.UpdatedAt = time.Now().Add(-45 * time.Minute)
+	silent, err := mgr.GetOrCreateWithPolicy("agent:main:main", &ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30})
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	if len(silent.Messages) != 0 {
+		t.Fatalf("silent reset messages = %+v, want none", silent.Messages)
+	}
+}
+
+func TestSaveCompactsSessionExceedingHistoryLimit(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	mgr.SetHistoryLimits(6, 0)
+	var summarized []Message
+	mgr.SetHistoryCompactor(func(dropped []Message) (string, error) {
+		summarized = dropped
+		return "user asked about the weather twice", nil
+	})
+
+	sess, err := mgr.GetOrCreate("agent:main:main")
+	if err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("call-%d", i)
+		sess.AddMessage(Message{Role: "user", Content: fmt.Sprintf("question %d", i), Timestamp: time.Now()})
+		sess.AddMessage(Message{Role: "assistant", ToolCalls: []ToolCall{{ID: id, Name: "weather"}}, Timestamp: time.Now()})
+		sess.AddMessage(Message{Role: "tool", ToolCallID: id, Content: "sunny", Timestamp: time.Now()})
+		sess.AddMessage(Message{Role: "assistant", Content: fmt.Sprintf("answer %d", i), Timestamp: time.Now()})
+	}
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	mgr.WaitHistoryCompaction()
+
+	if len(summarized) != 8 || summarized[0].Content != "question 0" {
+		t.Fatalf("summarized %d messages, want the two oldest turns (8 messages)", len(summarized))
+	}
+	reloaded, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	onDisk, err := reloaded.GetOrCreate("agent:main:main")
+	if err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	if len(onDisk.Messages) != 5 {
+		t.Fatalf("saved messages = %d, want summary + last turn (5)", len(onDisk.Messages))
+	}
+	summary := onDisk.Messages[0]
+	if summary.Role != "user" || summary.Metadata[MetadataHistorySummary] != true ||
+		!strings.HasPrefix(summary.Content, SummaryMessagePrefix) || !strings.Contains(summary.Content, "weather twice") {
+		t.Fatalf("first message = %+v, want history summary", summary)
+	}
+	if onDisk.Messages[1].Content != "question 2" || onDisk.Messages[3].ToolCallID != "call-2" {
+		t.Fatalf("kept messages = %+v, want the newest turn intact", onDisk.Messages[1:])
+	}
+}
+
+func TestSaveDoesNotWaitForHistorySummary(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	mgr.SetHistoryLimits(4, 0)
+	release := make(chan struct{})
+	mgr.SetHistoryCompactor(func(dropped []Message) (string, error) {
+		<-release
+		return "earlier turns", nil
+	})
+
+	sess, _ := mgr.GetOrCreate("agent:main:main")
+	for i := 0; i < 3; i++ {
+		sess.AddMessage(Message{Role: "user", Content: fmt.Sprintf("q%d", i), Timestamp: time.Now()})
+		sess.AddMessage(Message{Role: "assistant", Content: fmt.Sprintf("a%d", i), Timestamp: time.Now()})
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- mgr.Save(sess) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		close(release)
+		t.Fatal("Save() blocked on the history summarizer")
+	}
+
+	// 摘要期间追加的消息保留在裁剪结果中
+	sess.AddMessage(Message{Role: "user", Content: "q3", Timestamp: time.Now()})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	close(release)
+	mgr.WaitHistoryCompaction()
+
+	msgs := sess.GetHistory(-1)
+	if len(msgs) == 0 || msgs[0].Metadata[MetadataHistorySummary] != true {
+		t.Fatalf("first message = %+v, want history summary", msgs)
+	}
+	if last := msgs[len(msgs)-1]; last.Content != "q3" {
+		t.Fatalf("last message = %+v, want the message added during summarization", last)
+	}
+}
+
+func TestLookupDoesNotCreateOrResetSessions(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, err := mgr.Lookup("agent:main:missing"); !os.IsNotExist(err) {
+		t.Fatalf("Lookup(missing) error = %v, want not exist", err)
+	}
+	if mgr.Exists("agent:main:missing") {
+		t.Fatal("Lookup created a session")
+	}
+
+	sess, _ := mgr.GetOrCreate("agent:main:main")
+	sess.AddMessage(Message{Role: "user", Content: "hello", Timestamp: time.Now()})
+	sess.UpdatedAt = time.Now().Add(-45 * time.Minute)
+	if err := mgr.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	mgr.mu.Lock()
+	delete(mgr.sessions, "agent:main:main")
+	mgr.mu.Unlock()
+	mgr.SetResetPolicy(&ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30})
+
+	got, err := mgr.Lookup("agent:main:main")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if len(got.Messages) != 1 {
+		t.Errorf("Lookup reset a stale session, got %d messages", len(got.Messages))
+	}
+	mgr.mu.RLock()
+	_, cached := mgr.sessions["agent:main:main"]
+	mgr.mu.RUnlock()
+	if cached {
+		t.Error("Lookup added the session to the cache")
+	}
+}
+
+func TestManagerTouchDoesNotCreateMissingSession(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, err := mgr.Touch("agent:main:missing", nil); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Touch() error = %v, want os.ErrNotExist", err)
+	}
+	if mgr.Exists("agent:main:missing") {
+		t.Fatal("Touch() created the missing session")
+	}
+}
+
+func TestPolicyResetNoticeFollowsGatewayLocale(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Set(&config.Config{Gateway: config.GatewayConfig{Locale: "zh"}})
+
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	policy := &ResetPolicy{Mode: ResetModeIdle, IdleMinutes: 30, NotifyOnReset: true}
+	sess, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	sess.AddMessage(Message{Role: "user", Content: "hi", Timestamp: time.Now()})
+	sess.UpdatedAt = time.Now().Add(-45 * time.Minute)
+
+	reset, err := mgr.GetOrCreateWithPolicy("agent:main:main", policy)
+	if err != nil {
+		t.Fatalf("GetOrCreateWithPolicy() error = %v", err)
+	}
+	if len(reset.Messages) != 1 || reset.Messages[0].Content != "之前的对话因长时间无活动已重置。" {
+		t.Fatalf("reset notice = %+v, want the zh text", reset.Messages)
+	}
+}
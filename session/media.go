@@ -0,0 +1,163 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// mediaRefPrefix 媒体引用前缀（内容寻址：sha256 of raw bytes）
+const mediaRefPrefix = "sha256:"
+
+// MediaStore 会话媒体的内容寻址存储：超过阈值的 base64 媒体写入 <dir>/<hash[:2]>/<hash>，会话中仅保留 mediaRef
+type MediaStore struct {
+	dir       string
+	inlineMax atomic.Int64 // 内联 base64 的最大字节数，0 表示不外置
+}
+
+// NewMediaStore 创建媒体存储（目录按需创建）
+func NewMediaStore(dir string) *MediaStore {
+	return &MediaStore{dir: dir}
+}
+
+// Dir 返回存储目录
+func (s *MediaStore) Dir() string {
+	return s.dir
+}
+
+// SetInlineMax 设置内联 base64 的最大字节数；超过则外置，<=0 表示全部内联（默认）
+func (s *MediaStore) SetInlineMax(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.inlineMax.Store(int64(n))
+}
+
+// Put 写入原始字节并返回引用；相同内容只存一份
+func (s *MediaStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := s.blobPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return mediaRefPrefix + hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return mediaRefPrefix + hash, nil
+}
+
+// Get 按引用读取原始字节
+func (s *MediaStore) Get(ref string) ([]byte, error) {
+	hash, ok := parseMediaRef(ref)
+	if !ok {
+		return nil, fmt.Errorf("invalid media ref: %q", ref)
+	}
+	return os.ReadFile(s.blobPath(hash))
+}
+
+// externalize 将消息中超过阈值的 base64 媒体写入存储，替换为 mediaRef；写入失败时保留内联
+func (s *MediaStore) externalize(msg *Message) int {
+	limit := int(s.inlineMax.Load())
+	if limit <= 0 || len(msg.Media) == 0 {
+		return 0
+	}
+	moved := 0
+	var media []Media
+	for i, m := range msg.Media {
+		if m.Ref != "" || len(m.Base64) <= limit {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(m.Base64)
+		if err != nil {
+			continue
+		}
+		ref, err := s.Put(data)
+		if err != nil {
+			continue
+		}
+		if media == nil {
+			// 复制一份，避免修改调用方共享的切片
+			media = append([]Media(nil), msg.Media...)
+		}
+		media[i].Ref = ref
+		media[i].Base64 = ""
+		moved++
+	}
+	if media != nil {
+		msg.Media = media
+	}
+	return moved
+}
+
+// hydrateMessages 返回消息副本，其中外置媒体按 mediaRef 回填 base64
+func (s *MediaStore) hydrateMessages(msgs []Message) []Message {
+	out := make([]Message, len(msgs))
+	for i, msg := range msgs {
+		if len(msg.Media) > 0 {
+			msg.Media = s.hydrate(msg.Media)
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// hydrate 为带 mediaRef 的媒体回填 base64；读取失败的条目保持原样
+func (s *MediaStore) hydrate(media []Media) []Media {
+	var out []Media
+	for i, m := range media {
+		if m.Ref == "" || m.Base64 != "" {
+			continue
+		}
+		data, err := s.Get(m.Ref)
+		if err != nil {
+			continue
+		}
+		if out == nil {
+			out = append([]Media(nil), media...)
+		}
+		out[i].Base64 = base64.StdEncoding.EncodeToString(data)
+	}
+	if out == nil {
+		return media
+	}
+	return out
+}
+
+func (s *MediaStore) blobPath(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// parseMediaRef 校验 sha256:<64 hex> 格式（防止路径穿越）
+func parseMediaRef(ref string) (string, bool) {
+	hash, ok := strings.CutPrefix(ref, mediaRefPrefix)
+	if !ok || len(hash) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return strings.ToLower(hash), true
+}
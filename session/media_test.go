@@ -0,0 +1,108 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManagerExternalizesMediaRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	mgr.SetMediaInlineMax(16)
+
+	image := bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 64)
+	encoded := base64.StdEncoding.EncodeToString(image)
+	media := []Media{
+		{Type: "image", Base64: encoded, MimeType: "image/png"},
+		{Type: "image", Base64: "aGk=", MimeType: "image/png"}, // 未超过阈值，保持内联
+	}
+
+	sess, err := mgr.GetOrCreate("agent:main:main")
+	if err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	sess.AddMessage(Message{Role: "user", Content: "look", Media: media, Timestamp: time.Now()})
+	if media[0].Base64 != encoded {
+		t.Fatal("AddMessage must not mutate the caller's media slice")
+	}
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	stored := sess.Messages[0].Media
+	if stored[0].Base64 != "" || !strings.HasPrefix(stored[0].Ref, "sha256:") {
+		t.Fatalf("large media not externalized: %+v", stored[0])
+	}
+	if stored[1].Ref != "" || stored[1].Base64 != "aGk=" {
+		t.Errorf("small media should stay inline: %+v", stored[1])
+	}
+	raw, err := os.ReadFile(mgr.sessionPath(sess.Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), encoded) || !strings.Contains(string(raw), `"mediaRef":"sha256:`) {
+		t.Errorf("session file should hold only the reference:\n%s", raw)
+	}
+
+	// 新 Manager 从磁盘加载后按引用回填
+	reloaded, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	fromDisk, err := reloaded.GetOrCreate(sess.Key)
+	if err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	hydrated := reloaded.HydrateMedia(fromDisk.GetHistory(0))
+	if got := hydrated[0].Media[0]; got.Base64 != encoded || got.MimeType != "image/png" {
+		t.Errorf("hydrated media mismatch: %+v", got)
+	}
+	if fromDisk.Messages[0].Media[0].Base64 != "" {
+		t.Error("HydrateMedia must not modify the session in place")
+	}
+	if got := fromDisk.GetHydratedHistory(0)[0].Media[0]; got.Base64 != encoded {
+		t.Errorf("GetHydratedHistory media mismatch: %+v", got)
+	}
+	data, err := reloaded.MediaStore().Get(stored[0].Ref)
+	if err != nil || !bytes.Equal(data, image) {
+		t.Errorf("MediaStore.Get() = %d bytes, err %v", len(data), err)
+	}
+	if _, err := reloaded.MediaStore().Get("sha256:../../etc/passwd"); err == nil {
+		t.Error("expected error for malformed ref")
+	}
+}
+
+func TestManagerMigrateInlineMedia(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), 128))
+	sess, _ := mgr.GetOrCreate("agent:main:legacy")
+	sess.AddMessage(Message{Role: "user", Media: []Media{{Type: "image", Base64: encoded}}, Timestamp: time.Now()})
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	migrator, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	migrator.SetMediaInlineMax(16)
+	moved, err := migrator.MigrateInlineMedia()
+	if err != nil || moved != 1 {
+		t.Fatalf("MigrateInlineMedia() = %d, %v", moved, err)
+	}
+	raw, _ := os.ReadFile(migrator.sessionPath("agent:main:legacy"))
+	if strings.Contains(string(raw), encoded) {
+		t.Error("migrated session file still contains inline media")
+	}
+}
@@ -0,0 +1,45 @@
+package session
+
+import "strings"
+
+// 会话来源元数据：创建会话的入站消息所属渠道与账号（DM 折叠到主会话时以首个渠道为准）
+const (
+	MetadataOriginChannel   = "channel"
+	MetadataOriginAccountID = "accountId"
+)
+
+// RecordOrigin 首次写入会话来源渠道与账号，已记录时不覆盖；返回是否有改动（调用方据此决定是否保存）
+func (s *Session) RecordOrigin(channel, accountID string) bool {
+	channel = strings.TrimSpace(channel)
+	if channel == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, _ := s.Metadata[MetadataOriginChannel].(string); existing != "" {
+		return false
+	}
+	if s.Metadata == nil {
+		s.Metadata = make(map[string]interface{})
+	}
+	s.Metadata[MetadataOriginChannel] = channel
+	if accountID = strings.TrimSpace(accountID); accountID != "" {
+		s.Metadata[MetadataOriginAccountID] = accountID
+	}
+	return true
+}
+
+// Origin 返回会话来源渠道与账号，未记录时为空
+func (s *Session) Origin() (channel, accountID string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	channel, _ = s.Metadata[MetadataOriginChannel].(string)
+	accountID, _ = s.Metadata[MetadataOriginAccountID].(string)
+	return channel, accountID
+}
+
+// MatchesOriginChannel 会话来源渠道是否为 channel（不区分大小写）
+func (s *Session) MatchesOriginChannel(channel string) bool {
+	origin, _ := s.Origin()
+	return origin != "" && strings.EqualFold(origin, strings.TrimSpace(channel))
+}
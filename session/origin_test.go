@@ -0,0 +1,65 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSessionsFilterableByOriginChannel(t *testing.T) {
+	dir := t.TempDir()
+	mgr, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	origins := map[string][2]string{
+		"agent:main:telegram:group:1":     {"telegram", ""},
+		"agent:main:feishu:bot-a:group:2": {"feishu", "bot-a"},
+		"agent:main:telegram:group:3":     {"Telegram", ""},
+		"agent:main:main":                 {"websocket", ""},
+	}
+	for key, o := range origins {
+		sess, err := mgr.GetOrCreate(key)
+		if err != nil {
+			t.Fatalf("GetOrCreate(%q) error = %v", key, err)
+		}
+		if !sess.RecordOrigin(o[0], o[1]) {
+			t.Fatalf("RecordOrigin(%q) reported no change", key)
+		}
+		if sess.RecordOrigin("qq", "") {
+			t.Fatalf("RecordOrigin should not overwrite an existing origin")
+		}
+		if err := mgr.Save(sess); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// 重新加载，确认来源已持久化
+	reloaded, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	keys, err := reloaded.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var telegram []string
+	for _, key := range keys {
+		sess, err := reloaded.GetOrCreate(key)
+		if err != nil {
+			t.Fatalf("GetOrCreate(%q) error = %v", key, err)
+		}
+		if sess.MatchesOriginChannel("telegram") {
+			telegram = append(telegram, key)
+		}
+	}
+	slices.Sort(telegram)
+	want := []string{"agent:main:telegram:group:1", "agent:main:telegram:group:3"}
+	if !slices.Equal(telegram, want) {
+		t.Errorf("telegram sessions = %v, want %v", telegram, want)
+	}
+
+	sess, _ := reloaded.GetOrCreate("agent:main:feishu:bot-a:group:2")
+	if ch, acct := sess.Origin(); ch != "feishu" || acct != "bot-a" {
+		t.Errorf("Origin() = %q, %q", ch, acct)
+	}
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"maps"
+	"time"
+)
+
+// 流式输出过程中周期性写入的临时 assistant 消息（write-ahead transcript）及其尚未落盘的用户消息：
+// 运行结束时移除并由最终消息替代；进程崩溃时保留在历史中，assistant 消息标记为未完成
+const (
+	MetadataProvisionalRunID = "provisional_run_id" // 写入该临时消息的 runId
+	MetadataIncomplete       = "incomplete"         // 为 true 表示回复未完整生成
+)
+
+// IsIncomplete 判断消息是否为未完成的临时消息
+func IsIncomplete(msg Message) bool {
+	v, _ := msg.Metadata[MetadataIncomplete].(bool)
+	return v
+}
+
+// UpsertProvisional 写入或更新 runID 对应的临时 assistant 消息内容
+func (s *Session) UpsertProvisional(runID, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.provisionalIndexLocked(runID); i >= 0 {
+		s.Messages[i].Content = content
+	} else {
+		s.Messages = append(s.Messages, Message{
+			Role:      "assistant",
+			Content:   content,
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				MetadataProvisionalRunID: runID,
+				MetadataIncomplete:       true,
+			},
+		})
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// AddProvisionalPrompt 写入 runID 对应的用户消息（本轮输入尚未随运行结果落盘时），保证临时回复之前总有对应的提问；
+// 与临时回复一起由 RemoveProvisional 移除
+func (s *Session) AddProvisionalPrompt(runID string, prompt Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metadata := make(map[string]interface{}, len(prompt.Metadata)+1)
+	maps.Copy(metadata, prompt.Metadata)
+	metadata[MetadataProvisionalRunID] = runID
+	prompt.Metadata = metadata
+	s.Messages = append(s.Messages, prompt)
+	s.UpdatedAt = time.Now()
+}
+
+// RemoveProvisional 移除 runID 对应的临时消息（含临时写入的用户消息），返回是否存在
+func (s *Session) RemoveProvisional(runID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.Messages[:0:0]
+	for _, msg := range s.Messages {
+		if id, _ := msg.Metadata[MetadataProvisionalRunID].(string); id != runID {
+			kept = append(kept, msg)
+		}
+	}
+	if len(kept) == len(s.Messages) {
+		return false
+	}
+	s.Messages = kept
+	s.UpdatedAt = time.Now()
+	return true
+}
+
+func (s *Session) provisionalIndexLocked(runID string) int {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if id, _ := s.Messages[i].Metadata[MetadataProvisionalRunID].(string); id == runID && IsIncomplete(s.Messages[i]) {
+			return i
+		}
+	}
+	return -1
+}
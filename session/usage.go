@@ -0,0 +1,125 @@
+package session
+
+import "encoding/json"
+
+const (
+	// MetadataTokenUsage 会话元数据：会话累计 token 用量（TokenUsage）
+	MetadataTokenUsage = "tokenUsage"
+	// MetadataLastRunUsage 会话元数据：最近一次运行的 token 用量（TokenUsage，含 runId）
+	MetadataLastRunUsage = "lastRunUsage"
+)
+
+// TokenUsage 会话或单次运行的 token 用量。EstimatedCalls 为 provider 未上报 usage、按字符估算的调用次数
+type TokenUsage struct {
+	RunID            string `json:"runId,omitempty"`
+	Calls            int64  `json:"calls"`
+	EstimatedCalls   int64  `json:"estimatedCalls"`
+	PromptTokens     int64  `json:"promptTokens"`
+	CompletionTokens int64  `json:"completionTokens"`
+	TotalTokens      int64  `json:"totalTokens"`
+}
+
+// Estimated 全部调用都没有 provider 上报的用量时为 true
+func (u TokenUsage) Estimated() bool {
+	return u.Calls > 0 && u.EstimatedCalls == u.Calls
+}
+
+// Add 累加另一段用量（RunID 不累加）
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.Calls += other.Calls
+	u.EstimatedCalls += other.EstimatedCalls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// TokenUsage 读取会话累计用量；未记录过时 ok 为 false
+func (s *Session) TokenUsage() (usage TokenUsage, ok bool) {
+	return decodeTokenUsage(s.GetMetadata(MetadataTokenUsage))
+}
+
+// AddTokenUsage 将一次运行的用量累加到会话元数据，并记录为最近一次运行的用量
+func (s *Session) AddTokenUsage(run TokenUsage) {
+	if run.Calls == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Metadata == nil {
+		s.Metadata = make(map[string]interface{})
+	}
+	total, _ := decodeTokenUsage(s.Metadata[MetadataTokenUsage])
+	total.RunID = ""
+	total.Add(run)
+	s.Metadata[MetadataTokenUsage] = total
+	s.Metadata[MetadataLastRunUsage] = run
+}
+
+// decodeTokenUsage 元数据从磁盘加载后为 map，内存中为 TokenUsage，统一经 JSON 转换
+func decodeTokenUsage(v interface{}) (TokenUsage, bool) {
+	var usage TokenUsage
+	if v == nil {
+		return usage, false
+	}
+	if u, ok := v.(TokenUsage); ok {
+		return u, true
+	}
+	data, err := json.Marshal(v)
+	if err != nil || json.Unmarshal(data, &usage) != nil {
+		return TokenUsage{}, false
+	}
+	return usage, true
+}
+
+// historyCharsPerToken 历史 token 估算的每 token 字节数（与 agent.CharsPerTokenEstimate 一致）
+const historyCharsPerToken = 4
+
+// HistoryTokenEstimate 按会话历史内容估算的 token 数。Input 为发给模型的部分（user / tool / system），Output 为模型生成的部分（assistant）
+type HistoryTokenEstimate struct {
+	Input  int64            `json:"inputTokens"`
+	Output int64            `json:"outputTokens"`
+	Total  int64            `json:"totalTokens"`
+	ByRole map[string]int64 `json:"byRole"`
+}
+
+// EstimateHistoryTokens 按字节长度估算消息的 token 数：正文、tool 调用名称与参数、tool 结果及推理内容均计入；空历史返回零值
+func EstimateHistoryTokens(msgs []Message) HistoryTokenEstimate {
+	est := HistoryTokenEstimate{ByRole: make(map[string]int64)}
+	for _, msg := range msgs {
+		n := estimateTextTokens(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			n += estimateTextTokens(tc.Name)
+			if len(tc.Params) > 0 {
+				if data, err := json.Marshal(tc.Params); err == nil {
+					n += estimateTextTokens(string(data))
+				}
+			}
+		}
+		if reasoning, ok := msg.Metadata["reasoning_content"].(string); ok {
+			n += estimateTextTokens(reasoning)
+		}
+		role := msg.Role
+		if role == "" {
+			role = "user"
+		}
+		est.ByRole[role] += n
+		if role == "assistant" {
+			est.Output += n
+		} else {
+			est.Input += n
+		}
+		est.Total += n
+	}
+	return est
+}
+
+func estimateTextTokens(s string) int64 {
+	if s == "" {
+		return 0
+	}
+	n := int64(len(s) / historyCharsPerToken)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,29 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateHistoryTokensSplitsByRole(t *testing.T) {
+	if est := EstimateHistoryTokens(nil); est.Total != 0 || est.Input != 0 || est.Output != 0 {
+		t.Fatalf("empty history estimate = %+v, want zero", est)
+	}
+
+	msgs := []Message{
+		{Role: "user", Content: strings.Repeat("a", 400)},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "read_file", Params: map[string]interface{}{"path": strings.Repeat("p", 80)}}}},
+		{Role: "tool", ToolCallID: "c1", Content: strings.Repeat("x", 4000)},
+		{Role: "assistant", Content: strings.Repeat("b", 200)},
+	}
+	est := EstimateHistoryTokens(msgs)
+	if est.ByRole["user"] != 100 || est.ByRole["tool"] != 1000 {
+		t.Fatalf("byRole = %+v, want user 100 and tool 1000", est.ByRole)
+	}
+	if est.ByRole["assistant"] <= 50 {
+		t.Fatalf("assistant tokens = %d, want tool-call arguments counted", est.ByRole["assistant"])
+	}
+	if est.Input != 1100 || est.Output != est.ByRole["assistant"] || est.Total != est.Input+est.Output {
+		t.Fatalf("estimate = %+v, want input from user/tool and output from assistant", est)
+	}
+}
@@ -0,0 +1,66 @@
+package skills
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SkillManifestFile is the manifest file expected in every installed skill directory.
+const SkillManifestFile = "SKILL.md"
+
+// InstalledSkill describes one skill directory under the managed skills dir
+// (~/.goclaw/skills). Error is set when the manifest is missing or malformed.
+type InstalledSkill struct {
+	Key         string `json:"key"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Path        string `json:"path"`
+	Error       string `json:"error,omitempty"`
+	Content     string `json:"-"`
+}
+
+// Malformed reports whether the skill's manifest could not be parsed.
+func (s InstalledSkill) Malformed() bool {
+	return s.Error != ""
+}
+
+// ScanInstalledSkills parses the manifest of every skill directory in dir,
+// sorted by key. A missing dir yields no skills; unreadable or malformed
+// manifests are reported per skill instead of failing the whole scan.
+func ScanInstalledSkills(dir string) ([]InstalledSkill, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read skills dir %s: %w", dir, err)
+	}
+
+	var result []InstalledSkill
+	for _, e := range entries {
+		if !e.IsDir() || e.Name()[0] == '.' {
+			continue
+		}
+		skillDir := filepath.Join(dir, e.Name())
+		installed := InstalledSkill{Key: e.Name(), Path: skillDir}
+		manifest := filepath.Join(skillDir, SkillManifestFile)
+		if _, statErr := os.Stat(manifest); statErr != nil {
+			installed.Error = fmt.Sprintf("%s not found", SkillManifestFile)
+			result = append(result, installed)
+			continue
+		}
+		skill, loadErr := loadSkillFromFile(manifest, "managed")
+		if loadErr != nil {
+			installed.Error = loadErr.Error()
+		} else {
+			installed.Name = skill.Name
+			installed.Description = skill.Description
+			installed.Content = skill.Content
+		}
+		result = append(result, installed)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
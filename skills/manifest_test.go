@@ -0,0 +1,43 @@
+package skills
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanInstalledSkillsFlagsMalformedManifests(t *testing.T) {
+	dir := t.TempDir()
+	write := func(key, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, key), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if content != "" {
+			if err := os.WriteFile(filepath.Join(dir, key, SkillManifestFile), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("weather", "---\nname: weather\ndescription: Get the weather\n---\nUse curl.")
+	write("broken", "no frontmatter here")
+	write("empty", "")
+
+	got, err := ScanInstalledSkills(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Key != "broken" || got[1].Key != "empty" || got[2].Key != "weather" {
+		t.Fatalf("skills = %+v", got)
+	}
+	if !got[0].Malformed() || !got[1].Malformed() {
+		t.Errorf("broken/empty manifests should be flagged: %+v", got[:2])
+	}
+	if w := got[2]; w.Malformed() || w.Name != "weather" || w.Description != "Get the weather" {
+		t.Errorf("weather = %+v", w)
+	}
+
+	if none, err := ScanInstalledSkills(filepath.Join(dir, "missing")); err != nil || len(none) != 0 {
+		t.Errorf("missing dir = %v, %v", none, err)
+	}
+}
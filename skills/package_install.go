@@ -0,0 +1,375 @@
+package skills
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SkillJSONManifestFile is an alternative manifest; it is converted to SKILL.md on install.
+const SkillJSONManifestFile = "skill.json"
+
+// Limits applied while fetching and unpacking a skill package.
+const (
+	maxSkillDownloadBytes = 50 << 20
+	maxSkillUnpackedBytes = 200 << 20
+)
+
+// ErrSkillExists is returned when the target skill directory is already present.
+var ErrSkillExists = errors.New("skill already installed")
+
+var skillKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidSkillKey reports whether key can be used as a directory name under the skills dir.
+func ValidSkillKey(key string) bool {
+	return skillKeyPattern.MatchString(key) && !strings.Contains(key, "..")
+}
+
+// InstallSkillPackage installs the skill at source into dir/<key>. source may be
+// a local directory or archive, a git URL (*.git, git@..., git+https://...), or
+// an http(s) URL of a .tar.gz/.tgz/.tar/.zip archive. The package is staged in a
+// hidden directory under dir and only moved into place once its manifest
+// (SKILL.md, or skill.json converted to SKILL.md) validates, so a failed install
+// leaves nothing behind.
+func InstallSkillPackage(ctx context.Context, dir, key, source string) (InstalledSkill, error) {
+	source = strings.TrimSpace(source)
+	if !ValidSkillKey(key) {
+		return InstalledSkill{}, fmt.Errorf("invalid skill key %q", key)
+	}
+	if source == "" {
+		return InstalledSkill{}, fmt.Errorf("source is required")
+	}
+	target := filepath.Join(dir, key)
+	if _, err := os.Stat(target); err == nil {
+		return InstalledSkill{}, fmt.Errorf("%w: %s", ErrSkillExists, key)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return InstalledSkill{}, fmt.Errorf("failed to create skills dir: %w", err)
+	}
+	staging, err := os.MkdirTemp(dir, ".install-"+key+"-")
+	if err != nil {
+		return InstalledSkill{}, fmt.Errorf("failed to create staging dir: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := fetchSkillSource(ctx, source, staging); err != nil {
+		return InstalledSkill{}, err
+	}
+	root, err := skillPackageRoot(staging)
+	if err != nil {
+		return InstalledSkill{}, err
+	}
+	_ = os.RemoveAll(filepath.Join(root, ".git"))
+	skill, err := loadSkillFromFile(filepath.Join(root, SkillManifestFile), "managed")
+	if err != nil {
+		return InstalledSkill{}, fmt.Errorf("invalid %s: %w", SkillManifestFile, err)
+	}
+	if strings.TrimSpace(skill.Frontmatter["name"]) == "" {
+		// Without a name in the manifest, scans fall back to the directory name.
+		skill.Name = key
+	}
+	if err := os.Rename(root, target); err != nil {
+		return InstalledSkill{}, fmt.Errorf("failed to move skill into place: %w", err)
+	}
+	_ = os.Chmod(target, 0755) // MkdirTemp creates the staging dir with 0700.
+	return InstalledSkill{
+		Key:         key,
+		Name:        skill.Name,
+		Description: skill.Description,
+		Path:        target,
+		Content:     skill.Content,
+	}, nil
+}
+
+// isGitSource reports whether source should be cloned with git.
+func isGitSource(source string) bool {
+	return strings.HasPrefix(source, "git@") || strings.HasPrefix(source, "git+") ||
+		strings.HasPrefix(source, "git://") || strings.HasSuffix(source, ".git")
+}
+
+// fetchSkillSource copies, clones, or downloads and unpacks source into dest.
+func fetchSkillSource(ctx context.Context, source, dest string) error {
+	switch {
+	case isGitSource(source):
+		url := strings.TrimPrefix(source, "git+")
+		cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--", url, filepath.Join(dest, "repo"))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git clone %s failed: %w: %s", url, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		data, err := downloadSkillArchive(ctx, source)
+		if err != nil {
+			return err
+		}
+		return unpackSkillArchive(source, data, dest)
+	}
+
+	path := ResolveUserPath(source)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("skill source %s: %w", source, err)
+	}
+	if info.IsDir() {
+		return copySkillDir(path, filepath.Join(dest, filepath.Base(path)))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	return unpackSkillArchive(path, data, dest)
+}
+
+func downloadSkillArchive(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid skill URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSkillDownloadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if len(data) > maxSkillDownloadBytes {
+		return nil, fmt.Errorf("skill archive %s exceeds %d MB", url, maxSkillDownloadBytes>>20)
+	}
+	return data, nil
+}
+
+// unpackSkillArchive extracts a tar, tar.gz, or zip archive (detected by name, then content).
+func unpackSkillArchive(name string, data []byte, dest string) error {
+	lower := strings.ToLower(name)
+	if i := strings.IndexAny(lower, "?#"); i >= 0 {
+		lower = lower[:i]
+	}
+	switch {
+	case strings.HasSuffix(lower, ".zip") || bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return unpackZip(data, dest)
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") || bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("invalid gzip archive: %w", err)
+		}
+		defer gz.Close()
+		return unpackTar(gz, dest)
+	case strings.HasSuffix(lower, ".tar"):
+		return unpackTar(bytes.NewReader(data), dest)
+	}
+	return fmt.Errorf("unsupported skill archive %s (expected .tar.gz, .tgz, .tar or .zip)", name)
+}
+
+// safeArchivePath resolves an archive entry under dest, rejecting absolute paths and "..".
+func safeArchivePath(dest, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q escapes the skill directory", name)
+	}
+	return filepath.Join(dest, clean), nil
+}
+
+func unpackTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		path, err := safeArchivePath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			total += hdr.Size
+			if total > maxSkillUnpackedBytes {
+				return fmt.Errorf("skill archive exceeds %d MB unpacked", maxSkillUnpackedBytes>>20)
+			}
+			if err := writeSkillFile(path, tr, os.FileMode(hdr.Mode)&0755|0644); err != nil {
+				return err
+			}
+		}
+		// Symlinks and other entry types are skipped so nothing points outside the skill dir.
+	}
+}
+
+func unpackZip(data []byte, dest string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	var total uint64
+	for _, f := range zr.File {
+		path, err := safeArchivePath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		total += f.UncompressedSize64
+		if total > maxSkillUnpackedBytes {
+			return fmt.Errorf("skill archive exceeds %d MB unpacked", maxSkillUnpackedBytes>>20)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("invalid zip entry %s: %w", f.Name, err)
+		}
+		err = writeSkillFile(path, rc, f.Mode()&0755|0644)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSkillFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, io.LimitReader(r, maxSkillUnpackedBytes)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copySkillDir copies a local skill directory, skipping .git and symlinks.
+func copySkillDir(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dest, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeSkillFile(target, f, info.Mode().Perm())
+	})
+}
+
+// skillPackageRoot finds the directory holding the manifest: the staging dir
+// itself or, as with GitHub archives and clones, its single top-level directory.
+// A skill.json manifest is converted to SKILL.md.
+func skillPackageRoot(staging string) (string, error) {
+	root := staging
+	for range 2 {
+		if hasManifest(root) {
+			break
+		}
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return "", err
+		}
+		var dirs []string
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, e.Name())
+			}
+		}
+		if len(dirs) != 1 || len(entries) != 1 {
+			break
+		}
+		root = filepath.Join(root, dirs[0])
+	}
+	if _, err := os.Stat(filepath.Join(root, SkillManifestFile)); err == nil {
+		return root, nil
+	}
+	if _, err := os.Stat(filepath.Join(root, SkillJSONManifestFile)); err == nil {
+		if err := convertSkillJSON(root); err != nil {
+			return "", err
+		}
+		return root, nil
+	}
+	return "", fmt.Errorf("skill package has no %s or %s", SkillManifestFile, SkillJSONManifestFile)
+}
+
+func hasManifest(dir string) bool {
+	for _, name := range []string{SkillManifestFile, SkillJSONManifestFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// convertSkillJSON writes SKILL.md from skill.json ({name, description, instructions|content}).
+func convertSkillJSON(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, SkillJSONManifestFile))
+	if err != nil {
+		return err
+	}
+	var manifest struct {
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		Instructions string `json:"instructions"`
+		Content      string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid %s: %w", SkillJSONManifestFile, err)
+	}
+	if strings.TrimSpace(manifest.Name) == "" {
+		return fmt.Errorf("invalid %s: name is required", SkillJSONManifestFile)
+	}
+	body := manifest.Instructions
+	if body == "" {
+		body = manifest.Content
+	}
+	name, _ := json.Marshal(manifest.Name)
+	desc, _ := json.Marshal(manifest.Description)
+	md := fmt.Sprintf("---\nname: %s\ndescription: %s\n---\n\n%s\n", name, desc, body)
+	return os.WriteFile(filepath.Join(dir, SkillManifestFile), []byte(md), 0644)
+}
@@ -0,0 +1,120 @@
+package skills
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSkillManifest = "---\nname: weather\ndescription: Get the weather\n---\nUse curl."
+
+func TestInstallSkillPackageFromDirectory(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, SkillManifestFile), []byte(testSkillManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	got, err := InstallSkillPackage(context.Background(), dir, "weather", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != "weather" || got.Name != "weather" || got.Description != "Get the weather" {
+		t.Fatalf("installed = %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "weather", SkillManifestFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InstallSkillPackage(context.Background(), dir, "weather", src); !errors.Is(err, ErrSkillExists) {
+		t.Fatalf("reinstall err = %v", err)
+	}
+}
+
+func TestInstallSkillPackageFromTarball(t *testing.T) {
+	files := map[string]string{
+		"weather-1.0/skill.json":     `{"name":"weather","description":"Get the weather","instructions":"Use curl."}`,
+		"weather-1.0/scripts/run.sh": "#!/bin/sh\ncurl wttr.in\n",
+		"weather-1.0/../../evil.txt": "nope",
+	}
+	archive := gzipTarball(t, files)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, err := InstallSkillPackage(context.Background(), dir, "weather", srv.URL+"/weather.tgz"); err == nil {
+		t.Fatal("archive with path traversal should be rejected")
+	}
+	assertNoInstallLeftovers(t, dir)
+
+	delete(files, "weather-1.0/../../evil.txt")
+	archive = gzipTarball(t, files)
+	got, err := InstallSkillPackage(context.Background(), dir, "weather", srv.URL+"/weather.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "weather" || got.Description != "Get the weather" {
+		t.Fatalf("installed = %+v", got)
+	}
+	for _, name := range []string{SkillManifestFile, "scripts/run.sh"} {
+		if _, err := os.Stat(filepath.Join(dir, "weather", name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+}
+
+func TestInstallSkillPackageRejectsBadManifest(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, SkillManifestFile), []byte("no frontmatter here"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := InstallSkillPackage(context.Background(), dir, "broken", src); err == nil {
+		t.Fatal("expected manifest validation error")
+	}
+	if _, err := InstallSkillPackage(context.Background(), dir, "../etc", src); err == nil {
+		t.Fatal("expected invalid key error")
+	}
+	assertNoInstallLeftovers(t, dir)
+}
+
+func assertNoInstallLeftovers(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("failed install left %d entries, first %q", len(entries), entries[0].Name())
+	}
+}
+
+func gzipTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -24,6 +24,8 @@ const (
 	FailoverReasonServerError FailoverReason = "server_error"
 	// FailoverReasonNetworkError 网络错误
 	FailoverReasonNetworkError FailoverReason = "network_error"
+	// FailoverReasonModelNotFound 模型不存在或不可用（模型名拼写错误、已下线）
+	FailoverReasonModelNotFound FailoverReason = "model_not_found"
 	// FailoverReasonUnknown 未知错误
 	FailoverReasonUnknown FailoverReason = "unknown"
 )
@@ -57,6 +59,7 @@ type SimpleErrorClassifier struct {
 	serverErrorPatterns  []string
 	networkErrorPatterns []string
 	contextOverflowPatterns []string
+	modelNotFoundPatterns   []string
 }
 
 // NewSimpleErrorClassifier 创建简单错误分类器
@@ -94,6 +97,11 @@ func NewSimpleErrorClassifier() *SimpleErrorClassifier {
 			"context length", "maximum context", "token limit",
 			"context_length_exceeded", "tokens exceed",
 		},
+		modelNotFoundPatterns: []string{
+			"model_not_found", "model not found", "unknown model", "invalid model",
+			"no such model", "not a valid model",
+			"model does not exist", "is not found for api version",
+		},
 	}
 }
 
@@ -109,6 +117,9 @@ func (c *SimpleErrorClassifier) ClassifyError(err error) FailoverReason {
 	if c.matchesAny(errMsg, c.contextOverflowPatterns) {
 		return FailoverReasonContextOverflow
 	}
+	if c.matchesAny(errMsg, c.modelNotFoundPatterns) || isModelDoesNotExist(errMsg) {
+		return FailoverReasonModelNotFound
+	}
 	if c.matchesAny(errMsg, c.authPatterns) {
 		return FailoverReasonAuth
 	}
@@ -140,6 +151,25 @@ func (c *SimpleErrorClassifier) IsFailoverError(err error) bool {
 	return reason != FailoverReasonUnknown
 }
 
+// modelNotFoundRes 含模型名、无法用固定片段匹配的“模型不存在”错误（errMsg 已转小写）：
+// OpenAI 风格的 "The model `xxx` does not exist"；Anthropic 的 not_found_error 仅在 message 指向 model 时算模型不存在；
+// OpenRouter 的 "No endpoints found for <model>"（"No endpoints found that support tool use" 等能力不匹配不算）
+var modelNotFoundRes = []*regexp.Regexp{
+	regexp.MustCompile("model\\s+[`'\"]?[^\\s`'\"]+[`'\"]?\\s+does not exist"),
+	regexp.MustCompile(`not_found_error"?\s*,\s*"message"\s*:\s*"model:`),
+	regexp.MustCompile(`no endpoints found for [^\s.]`),
+}
+
+// isModelDoesNotExist 检查是否为“模型不存在”类错误（含模型名，无法用固定片段匹配）
+func isModelDoesNotExist(errMsg string) bool {
+	for _, re := range modelNotFoundRes {
+		if re.MatchString(errMsg) {
+			return true
+		}
+	}
+	return false
+}
+
 // matchesAny 检查错误消息是否匹配任何模式
 func (c *SimpleErrorClassifier) matchesAny(errMsg string, patterns []string) bool {
 	for _, pattern := range patterns {
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyModelNotFound(t *testing.T) {
+	classifier := NewSimpleErrorClassifier()
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"error, status code: 404, message: The model `gpt-9` does not exist or you do not have access to it.", true},
+		{`POST "https://api.anthropic.com/v1/messages": 404 Not Found {"type":"error","error":{"type":"not_found_error","message":"model: claude-9"}}`, true},
+		{`404 {"error":{"message":"No endpoints found for openai/gpt-9.","code":404}}`, true},
+		{`404 {"type":"error","error":{"type":"not_found_error","message":"File not found: file_abc"}}`, false},
+		{`404 {"error":{"message":"No endpoints found that support tool use. To learn more about provider routing, visit: https://openrouter.ai/docs/provider-routing","code":404}}`, false},
+		{"No endpoints found matching your data policy", false},
+	}
+	for _, tt := range tests {
+		got := classifier.ClassifyError(errors.New(tt.msg)) == FailoverReasonModelNotFound
+		if got != tt.want {
+			t.Errorf("ClassifyError(%q) model not found = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
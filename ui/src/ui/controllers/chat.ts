@@ -27,6 +27,22 @@ export type ChatEventPayload = {
   errorMessage?: string;
 };
 
+/**
+ * Mirrors session.RunID on the gateway: the effective runId is namespaced by
+ * sessionKey so the same idempotencyKey in two sessions never collides.
+ * Returns null outside secure contexts (no crypto.subtle); callers then adopt
+ * the runId returned by chat.send.
+ */
+async function deriveRunId(sessionKey: string, idempotencyKey: string): Promise<string | null> {
+  if (typeof crypto === "undefined" || !crypto.subtle) {
+    return null;
+  }
+  const data = new TextEncoder().encode(`${sessionKey}\u0000${idempotencyKey}`);
+  const digest = new Uint8Array(await crypto.subtle.digest("SHA-256", data));
+  const hex = Array.from(digest.subarray(0, 16), (b) => b.toString(16).padStart(2, "0")).join("");
+  return `run-${hex}`;
+}
+
 export async function loadChatHistory(state: ChatState) {
   if (!state.client || !state.connected) {
     return;
@@ -100,7 +116,9 @@ export async function sendChatMessage(
 
   state.chatSending = true;
   state.lastError = null;
-  const runId = generateUUID();
+  const idempotencyKey = generateUUID();
+  const derivedRunId = await deriveRunId(state.sessionKey, idempotencyKey).catch(() => null);
+  const runId = derivedRunId ?? idempotencyKey;
   state.chatRunId = runId;
   state.chatStream = "";
   state.chatStreamStartedAt = now;
@@ -123,13 +141,20 @@ export async function sendChatMessage(
     : undefined;
 
   try {
-    await state.client.request("chat.send", {
+    const res = await state.client.request<{ runId?: string }>("chat.send", {
       sessionKey: state.sessionKey,
       message: msg,
       deliver: false,
-      idempotencyKey: runId,
+      idempotencyKey,
       attachments: apiAttachments,
     });
+    const serverRunId = res?.runId;
+    if (serverRunId && serverRunId !== runId) {
+      if (state.chatRunId === runId) {
+        state.chatRunId = serverRunId;
+      }
+      return serverRunId;
+    }
     return runId;
   } catch (err) {
     const error = String(err);